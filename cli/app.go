@@ -107,6 +107,7 @@ type advancedAppServices interface {
 	removeUpdateState()
 	passwordPersistenceStrategy() passwordpersist.Strategy
 	getPasswordFromFlags(ctx context.Context, isCreate, allowPersistent bool) (string, error)
+	hasPasswordFromFlags() bool
 	optionsFromFlags(ctx context.Context) *repo.Options
 	runAppWithContext(command *kingpin.CmdClause, callback func(ctx context.Context) error) error
 }
@@ -159,6 +160,8 @@ type App struct {
 	repository  commandRepository
 	logs        commandLogs
 
+	notification commandNotification
+
 	// testability hooks
 	testonlyIgnoreMissingRequiredFeatures bool
 
@@ -297,6 +300,7 @@ func (c *App) setup(app *kingpin.Application) {
 	c.policy.setup(c, app)
 	c.mount.setup(c, app)
 	c.maintenance.setup(c, app)
+	c.notification.setup(c, app)
 	c.repository.setup(c, app)
 }
 
@@ -2,16 +2,20 @@
 
 type commandServerACL struct {
 	add    commandACLAdd
+	check  commandACLCheck
 	delete commandACLDelete
 	enable commandACLEnable
 	list   commandACLList
+	roles  commandACLRoles
 }
 
 func (c *commandServerACL) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("acl", "Manager server access control list entries")
 
 	c.add.setup(svc, cmd)
+	c.check.setup(svc, cmd)
 	c.delete.setup(svc, cmd)
 	c.enable.setup(svc, cmd)
 	c.list.setup(svc, cmd)
+	c.roles.setup(svc, cmd)
 }
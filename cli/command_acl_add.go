@@ -14,28 +14,58 @@ type commandACLAdd struct {
 	user   string
 	target string
 	level  string
+	role   string
 }
 
 func (c *commandACLAdd) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("add", "Add ACL entry")
-	cmd.Flag("user", "User the ACL targets").Required().StringVar(&c.user)
-	cmd.Flag("target", "Manifests targeted by the rule (type:T,key1:value1,...,keyN:valueN)").Required().StringVar(&c.target)
-	cmd.Flag("access", "Access the user gets to subject").Required().EnumVar(&c.level, acl.SupportedAccessLevels()...)
+	cmd.Flag("user", "User the ACL targets (user@host, possibly including wildcards, or group:name)").Required().StringVar(&c.user)
+	cmd.Flag("target", "Manifests targeted by the rule (type:T,key1:value1,...,keyN:valueN)").StringVar(&c.target)
+	cmd.Flag("access", "Access the user gets to subject").EnumVar(&c.level, acl.SupportedAccessLevels()...)
+	cmd.Flag("role", "Grant all rules of a predefined role instead of a single target").EnumVar(&c.role, acl.SupportedRoles()...)
 	cmd.Action(svc.repositoryWriterAction(c.run))
 }
 
-func (c *commandACLAdd) run(ctx context.Context, rep repo.RepositoryWriter) error {
+func parseTargetRule(target string) (acl.TargetRule, error) {
 	r := acl.TargetRule{}
 
-	for _, v := range strings.Split(c.target, ",") {
+	for _, v := range strings.Split(target, ",") {
 		parts := strings.SplitN(v, "=", 2) //nolint:gomnd
 		if len(parts) != 2 {               //nolint:gomnd
-			return errors.Errorf("invalid target labels %q, must be key=value", v)
+			return nil, errors.Errorf("invalid target labels %q, must be key=value", v)
 		}
 
 		r[parts[0]] = parts[1]
 	}
 
+	return r, nil
+}
+
+func (c *commandACLAdd) run(ctx context.Context, rep repo.RepositoryWriter) error {
+	if c.role != "" {
+		if c.target != "" || c.level != "" {
+			return errors.Errorf("--role cannot be combined with --target or --access")
+		}
+
+		entries, err := acl.GrantRole(ctx, rep, c.user, c.role)
+		if err != nil {
+			return errors.Wrap(err, "error granting role")
+		}
+
+		log(ctx).Infof("Added %v ACL entries granting role %q to %v.", len(entries), c.role, c.user)
+
+		return nil
+	}
+
+	if c.target == "" || c.level == "" {
+		return errors.Errorf("either --role or both --target and --access must be specified")
+	}
+
+	r, err := parseTargetRule(c.target)
+	if err != nil {
+		return err
+	}
+
 	al, err := acl.ParseAccessLevel(c.level)
 	if err != nil {
 		return errors.Wrap(err, "invalid access level")
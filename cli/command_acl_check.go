@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/internal/auth"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+type commandACLCheck struct {
+	user         string
+	groups       []string
+	manifestType string
+	labels       []string
+	lint         bool
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandACLCheck) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("check", "Explain the access level of a user to a target or find problems with ACL entries")
+	cmd.Flag("user", "User whose access is checked (user@host)").StringVar(&c.user)
+	cmd.Flag("group", "Additional group the user is a member of, such as OpenID Connect group").StringsVar(&c.groups)
+	cmd.Flag("manifest-type", "Type of the target (snapshot, policy, user, group, acl or content)").StringVar(&c.manifestType)
+	cmd.Flag("label", "Label of the target (key=value)").StringsVar(&c.labels)
+	cmd.Flag("lint", "Find redundant or overly broad ACL entries instead").BoolVar(&c.lint)
+
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.repositoryReaderAction(c.run))
+}
+
+func (c *commandACLCheck) run(ctx context.Context, rep repo.Repository) error {
+	if c.lint {
+		return c.runLint(ctx, rep)
+	}
+
+	if c.user == "" || c.manifestType == "" {
+		return errors.Errorf("--user and --manifest-type must be specified unless --lint is used")
+	}
+
+	target := map[string]string{}
+
+	for _, l := range c.labels {
+		r, err := parseTargetRule(l)
+		if err != nil {
+			return err
+		}
+
+		for k, v := range r {
+			target[k] = v
+		}
+	}
+
+	target[manifest.TypeLabelKey] = c.manifestType
+
+	ex, err := auth.ExplainAccess(ctx, rep, c.user, c.groups, target)
+	if err != nil {
+		return errors.Wrap(err, "unable to check access")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(&serverapi.ACLCheckResponse{
+			User:       ex.User,
+			Groups:     ex.Groups,
+			Target:     ex.Target,
+			ACLEnabled: ex.ACLEnabled,
+			Access:     ex.Access,
+			Entries:    aclEntriesForJSON(ex.Entries),
+		}))
+
+		return nil
+	}
+
+	c.out.printStdout("User:   %v\n", ex.User)
+	c.out.printStdout("Groups: %v\n", ex.Groups)
+	c.out.printStdout("Target: %v\n", acl.TargetRule(ex.Target))
+	c.out.printStdout("Access: %v\n", ex.Access)
+
+	if !ex.ACLEnabled {
+		c.out.printStdout("\nACLs are not enabled, legacy permissions apply.\n")
+		return nil
+	}
+
+	if len(ex.Entries) == 0 {
+		c.out.printStdout("\nNo ACL entries apply to the user and target.\n")
+		return nil
+	}
+
+	c.out.printStdout("\nApplicable ACL entries (* marks entries which grant the access):\n")
+
+	for _, e := range ex.Entries {
+		marker := " "
+		if e.Access == ex.Access {
+			marker = "*"
+		}
+
+		c.out.printStdout("%v %v\n", marker, formatACLEntry(e))
+	}
+
+	return nil
+}
+
+func (c *commandACLCheck) runLint(ctx context.Context, rep repo.Repository) error {
+	entries, err := acl.LoadEntries(ctx, rep, nil)
+	if err != nil {
+		return errors.Wrap(err, "error loading ACL entries")
+	}
+
+	findings := acl.Lint(entries)
+
+	if c.jo.jsonOutput {
+		resp := &serverapi.ACLLintResponse{
+			Findings: []serverapi.ACLLintFinding{},
+		}
+
+		for _, f := range findings {
+			resp.Findings = append(resp.Findings, serverapi.ACLLintFinding{
+				Entry:   serverapi.ACLEntry{ID: f.Entry.ManifestID, Entry: f.Entry},
+				Kind:    f.Kind,
+				Message: f.Message,
+			})
+		}
+
+		c.out.printStdout("%s\n", c.jo.jsonBytes(resp))
+
+		return nil
+	}
+
+	if len(findings) == 0 {
+		c.out.printStdout("No problems found.\n")
+		return nil
+	}
+
+	for _, f := range findings {
+		c.out.printStdout("%v: %v: %v\n", f.Kind, formatACLEntry(f.Entry), f.Message)
+	}
+
+	return nil
+}
+
+func aclEntriesForJSON(entries []*acl.Entry) []serverapi.ACLEntry {
+	result := []serverapi.ACLEntry{}
+
+	for _, e := range entries {
+		result = append(result, serverapi.ACLEntry{ID: e.ManifestID, Entry: e})
+	}
+
+	return result
+}
@@ -0,0 +1,73 @@
+package cli_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestACLCheck(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer env.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	// legacy permissions
+	out := env.RunAndExpectSuccess(t, "server", "acl", "check", "--user", "alice@laptop", "--manifest-type", "snapshot", "--label", "username=alice", "--label", "hostname=laptop")
+	require.Contains(t, out, "Access: FULL")
+	require.Contains(t, out, "ACLs are not enabled, legacy permissions apply.")
+
+	env.RunAndExpectSuccess(t, "server", "acl", "enable")
+	env.RunAndExpectSuccess(t, "server", "acl", "add", "--user", "*@laptop", "--target", "type=snapshot,hostname=laptop", "--access", "READ")
+	env.RunAndExpectSuccess(t, "server", "acl", "add", "--user", "alice@laptop", "--target", "type=snapshot,hostname=laptop", "--access", "READ")
+	env.RunAndExpectSuccess(t, "server", "acl", "add", "--user", "*@*", "--target", "type=acl", "--access", "FULL")
+
+	var resp serverapi.ACLCheckResponse
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "server", "acl", "check", "--user", "alice@laptop", "--json",
+		"--manifest-type", "snapshot", "--label", "username=bob", "--label", "hostname=laptop"), &resp)
+	require.Equal(t, "READ", resp.Access.String())
+	require.Len(t, resp.Entries, 2)
+
+	out = env.RunAndExpectSuccess(t, "server", "acl", "check", "--user", "alice@laptop", "--manifest-type", "snapshot", "--label", "username=alice,hostname=laptop")
+	require.Contains(t, out, "Access: FULL")
+	require.Len(t, linesWithPrefix(out, "* "), 1)
+	require.Len(t, linesWithPrefix(out, "  id:"), 2)
+
+	out = env.RunAndExpectSuccess(t, "server", "acl", "check", "--user", "alice@laptop", "--manifest-type", "user")
+	require.Contains(t, out, "No ACL entries apply to the user and target.")
+
+	env.RunAndExpectFailure(t, "server", "acl", "check", "--user", "alice@laptop")
+	env.RunAndExpectFailure(t, "server", "acl", "check", "--user", "alice", "--manifest-type", "snapshot")
+
+	var lint serverapi.ACLLintResponse
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "server", "acl", "check", "--lint", "--json"), &lint)
+	require.Len(t, lint.Findings, 2)
+	require.Equal(t, "redundant", lint.Findings[0].Kind)
+	require.Equal(t, "alice@laptop", lint.Findings[0].Entry.User)
+	require.Equal(t, "overly-broad", lint.Findings[1].Kind)
+	require.Equal(t, "*@*", lint.Findings[1].Entry.User)
+
+	out = env.RunAndExpectSuccess(t, "server", "acl", "check", "--lint")
+	require.Len(t, out, 2)
+}
+
+func linesWithPrefix(lines []string, prefix string) []string {
+	var result []string
+
+	for _, l := range lines {
+		if strings.HasPrefix(l, prefix) {
+			result = append(result, l)
+		}
+	}
+
+	return result
+}
@@ -2,21 +2,35 @@
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/internal/auth"
+	"github.com/kopia/kopia/internal/user"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
 )
 
 type commandACLList struct {
+	user    string
+	groups  []string
+	targets []string
+
 	jo  jsonOutput
 	out textOutput
 }
 
 func (c *commandACLList) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("list", "List ACL entries").Alias("ls")
+	cmd.Flag("user", "Only list entries which apply to the provided user@host and show their effective permissions").StringVar(&c.user)
+	cmd.Flag("group", "Additional group the user is a member of, such as OpenID Connect group (requires --user)").StringsVar(&c.groups)
+	cmd.Flag("target", "Target to evaluate effective permissions for (key1=value1,...,keyN=value), may use OWN_USER and OWN_HOST (requires --user)").StringsVar(&c.targets)
 
 	c.jo.setup(svc, cmd)
 	c.out.setup(svc)
@@ -24,6 +38,14 @@ func (c *commandACLList) setup(svc appServices, parent commandParent) {
 }
 
 func (c *commandACLList) run(ctx context.Context, rep repo.Repository) error {
+	if c.user != "" {
+		return c.listForUser(ctx, rep)
+	}
+
+	if len(c.groups) > 0 || len(c.targets) > 0 {
+		return errors.Errorf("--group and --target require --user")
+	}
+
 	var jl jsonList
 
 	jl.begin(&c.jo)
@@ -38,14 +60,144 @@ func (c *commandACLList) run(ctx context.Context, rep repo.Repository) error {
 		if c.jo.jsonOutput {
 			jl.emit(aclListItem{e.ManifestID, e})
 		} else {
-			c.out.printStdout("id:%v user:%v access:%v target:%v\n", e.ManifestID, e.User, e.Access, e.Target)
+			c.printEntry(e)
 		}
 	}
 
 	return nil
 }
 
+func (c *commandACLList) printEntry(e *acl.Entry) {
+	c.out.printStdout("%v\n", formatACLEntry(e))
+}
+
+func formatACLEntry(e *acl.Entry) string {
+	if e.Role != "" {
+		return fmt.Sprintf("id:%v user:%v access:%v target:%v role:%v", e.ManifestID, e.User, e.Access, e.Target, e.Role)
+	}
+
+	return fmt.Sprintf("id:%v user:%v access:%v target:%v", e.ManifestID, e.User, e.Access, e.Target)
+}
+
+// defaultEffectivePermissionTargets returns targets commonly accessed by the user.
+func defaultEffectivePermissionTargets(username, hostname string) []acl.TargetRule {
+	return []acl.TargetRule{
+		{manifest.TypeLabelKey: acl.ContentManifestType},
+		{manifest.TypeLabelKey: snapshot.ManifestType, snapshot.UsernameLabel: username, snapshot.HostnameLabel: hostname},
+		{manifest.TypeLabelKey: policy.ManifestType, policy.PolicyTypeLabel: policy.PolicyTypeGlobal},
+		{manifest.TypeLabelKey: policy.ManifestType, policy.PolicyTypeLabel: policy.PolicyTypeHost, policy.HostnameLabel: hostname},
+		{manifest.TypeLabelKey: policy.ManifestType, policy.PolicyTypeLabel: policy.PolicyTypeUser, policy.UsernameLabel: username, policy.HostnameLabel: hostname},
+		{manifest.TypeLabelKey: user.ManifestType, user.UsernameAtHostnameLabel: username + "@" + hostname},
+	}
+}
+
+func (c *commandACLList) effectivePermissionTargets(username, hostname string) ([]acl.TargetRule, error) {
+	if len(c.targets) == 0 {
+		return defaultEffectivePermissionTargets(username, hostname), nil
+	}
+
+	var result []acl.TargetRule
+
+	for _, t := range c.targets {
+		r, err := parseTargetRule(t)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range r {
+			v = strings.ReplaceAll(v, acl.OwnUser, username)
+			r[k] = strings.ReplaceAll(v, acl.OwnHost, hostname)
+		}
+
+		result = append(result, r)
+	}
+
+	return result, nil
+}
+
+func (c *commandACLList) listForUser(ctx context.Context, rep repo.Repository) error {
+	parts := strings.Split(c.user, "@")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" { //nolint:gomnd
+		return errors.Errorf("user must be 'username@hostname'")
+	}
+
+	username, hostname := parts[0], parts[1]
+
+	entries, err := acl.LoadEntries(ctx, rep, nil)
+	if err != nil {
+		return errors.Wrap(err, "error loading ACL entries")
+	}
+
+	groupMap, err := acl.LoadGroupMap(ctx, rep, nil)
+	if err != nil {
+		return errors.Wrap(err, "error loading groups")
+	}
+
+	groups := append(acl.GroupsForUser(groupMap, username, hostname), c.groups...)
+	sort.Strings(groups)
+
+	targets, err := c.effectivePermissionTargets(username, hostname)
+	if err != nil {
+		return err
+	}
+
+	// evaluate permissions the same way the server does, which includes falling back to legacy
+	// permissions when ACLs are not enabled.
+	ai := auth.DefaultAuthorizer().Authorize(auth.WithGroups(ctx, c.groups), rep, c.user)
+
+	result := aclUserPermissions{
+		User:   c.user,
+		Groups: groups,
+	}
+
+	for _, e := range acl.EntriesForUser(entries, username, hostname, groups...) {
+		result.Entries = append(result.Entries, aclListItem{e.ManifestID, e})
+	}
+
+	for _, t := range targets {
+		access := ai.ManifestAccessLevel(t)
+		if t[manifest.TypeLabelKey] == acl.ContentManifestType {
+			access = ai.ContentAccessLevel()
+		}
+
+		result.Permissions = append(result.Permissions, aclTargetAccess{t, access})
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(result))
+		return nil
+	}
+
+	for _, it := range result.Entries {
+		c.printEntry(it.Entry)
+	}
+
+	if len(entries) == 0 {
+		c.out.printStdout("ACLs are not enabled, legacy permissions apply.\n")
+	}
+
+	c.out.printStdout("\nEffective permissions of %v (groups: %v):\n", c.user, strings.Join(groups, ", "))
+
+	for _, p := range result.Permissions {
+		c.out.printStdout("  %v: %v\n", p.Target, p.Access)
+	}
+
+	return nil
+}
+
 type aclListItem struct {
 	ID manifest.ID `json:"id"`
 	*acl.Entry
 }
+
+type aclTargetAccess struct {
+	Target acl.TargetRule  `json:"target"`
+	Access acl.AccessLevel `json:"access"`
+}
+
+type aclUserPermissions struct {
+	User        string            `json:"user"`
+	Groups      []string          `json:"groups"`
+	Entries     []aclListItem     `json:"entries"`
+	Permissions []aclTargetAccess `json:"permissions"`
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/kopia/kopia/internal/acl"
+)
+
+type commandACLRoles struct {
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandACLRoles) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("roles", "List predefined roles which can be granted using 'acl add --role'")
+
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.noRepositoryAction(c.run))
+}
+
+func (c *commandACLRoles) run(ctx context.Context) error {
+	var jl jsonList
+
+	jl.begin(&c.jo)
+	defer jl.end()
+
+	for _, r := range acl.Roles() {
+		if c.jo.jsonOutput {
+			jl.emit(r)
+			continue
+		}
+
+		c.out.printStdout("%v: %v\n", r.Name, r.Description)
+
+		for _, rr := range r.Rules {
+			c.out.printStdout("  access:%v target:%v\n", rr.Access, rr.Target)
+		}
+	}
+
+	return nil
+}
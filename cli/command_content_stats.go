@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"sort"
 	"strconv"
 
 	"github.com/pkg/errors"
@@ -10,6 +11,8 @@
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/compression"
 	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
 )
 
 type commandContentStats struct {
@@ -86,6 +89,10 @@ func (c *commandContentStats) run(ctx context.Context, rep repo.DirectRepository
 		}
 	}
 
+	if err := c.printAutoCompressionStats(ctx, rep, sizeToString); err != nil {
+		return errors.Wrap(err, "error calculating automatic compression statistics")
+	}
+
 	if grandTotal.count == 0 {
 		return nil
 	}
@@ -109,6 +116,93 @@ func (c *commandContentStats) run(ctx context.Context, rep repo.DirectRepository
 	return nil
 }
 
+// autoCompressionTotals holds sizes of files uploaded using automatic compression, by selected compressor.
+type autoCompressionTotals struct {
+	none, fast, best int64
+}
+
+func (t *autoCompressionTotals) add(o *autoCompressionTotals) {
+	t.none += o.none
+	t.fast += o.fast
+	t.best += o.best
+}
+
+// printAutoCompressionStats prints statistics of compressors selected automatically, based on the latest
+// snapshot of each source, for each source and aggregated by the policy which selects automatic compression.
+func (c *commandContentStats) printAutoCompressionStats(ctx context.Context, rep repo.Repository, sizeToString func(int64) string) error {
+	sources, err := snapshot.ListSources(ctx, rep)
+	if err != nil {
+		return errors.Wrap(err, "error listing sources")
+	}
+
+	byPolicy := map[snapshot.SourceInfo]*autoCompressionTotals{}
+
+	for _, src := range sources {
+		snapshots, err := snapshot.ListSnapshots(ctx, rep, src)
+		if err != nil {
+			return errors.Wrapf(err, "error listing snapshots of %v", src)
+		}
+
+		if len(snapshots) == 0 {
+			continue
+		}
+
+		latest := snapshot.SortByTime(snapshots, true)[0].Stats
+		st := autoCompressionTotals{latest.AutoCompressionNoneSize, latest.AutoCompressionFastSize, latest.AutoCompressionBestSize}
+
+		if st.none+st.fast+st.best == 0 {
+			continue
+		}
+
+		if len(byPolicy) == 0 {
+			c.out.printStdout("Automatic Compression (latest snapshots):\n")
+		}
+
+		c.printAutoCompressionTotals(src.String(), &st, sizeToString)
+
+		_, def, _, err := policy.GetEffectivePolicy(ctx, rep, src)
+		if err != nil {
+			return errors.Wrapf(err, "error getting effective policy of %v", src)
+		}
+
+		// attribute the source to the policy which defines its compressor.
+		target := def.CompressionPolicy.CompressorName
+		if byPolicy[target] == nil {
+			byPolicy[target] = &autoCompressionTotals{}
+		}
+
+		byPolicy[target].add(&st)
+	}
+
+	if len(byPolicy) == 0 {
+		return nil
+	}
+
+	targets := make([]snapshot.SourceInfo, 0, len(byPolicy))
+	for t := range byPolicy {
+		targets = append(targets, t)
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].String() < targets[j].String()
+	})
+
+	c.out.printStdout("Automatic Compression by policy (latest snapshots):\n")
+
+	for _, t := range targets {
+		c.printAutoCompressionTotals(t.String(), byPolicy[t], sizeToString)
+	}
+
+	return nil
+}
+
+func (c *commandContentStats) printAutoCompressionTotals(name string, st *autoCompressionTotals, sizeToString func(int64) string) {
+	c.out.printStdout("  %v\n", name)
+	c.out.printStdout("    %-20v %v\n", "(uncompressed)", sizeToString(st.none))
+	c.out.printStdout("    %-20v %v\n", compression.AutoFastCompressor, sizeToString(st.fast))
+	c.out.printStdout("    %-20v %v\n", compression.AutoBestCompressor, sizeToString(st.best))
+}
+
 func (c *commandContentStats) calculateStats(ctx context.Context, rep repo.DirectRepository, sizeBuckets []uint32) (
 	grandTotal contentStatsTotals,
 	byCompressionTotal map[compression.HeaderID]*contentStatsTotals,
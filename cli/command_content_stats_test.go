@@ -0,0 +1,41 @@
+package cli_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestContentStatsAutoCompression(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	dir1 := testutil.TempDirectory(t)
+	dir2 := testutil.TempDirectory(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir1, "file1.txt"), bytes.Repeat([]byte("compressible "), 1000), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir2, "file2.txt"), bytes.Repeat([]byte("compressible "), 1000), 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "policy", "set", "--global", "--compression", "auto")
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir1)
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir2)
+
+	lines := env.RunAndExpectSuccess(t, "content", "stats")
+
+	// both sources are aggregated under the global policy which selects automatic compression.
+	require.Contains(t, lines, "Automatic Compression by policy (latest snapshots):")
+	require.Contains(t, lines, "  (global)")
+	require.Contains(t, lines, "    zstd                 26 KB")
+
+	// second snapshot of the source only has cached files, which are still accounted for.
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir1)
+
+	lines = env.RunAndExpectSuccess(t, "content", "stats")
+	require.Contains(t, lines, "    zstd                 26 KB")
+}
@@ -0,0 +1,17 @@
+package cli
+
+type commandServerGroup struct {
+	add    commandServerGroupAdd
+	delete commandServerGroupDelete
+	list   commandServerGroupList
+	member commandServerGroupMember
+}
+
+func (c *commandServerGroup) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("groups", "Manage groups of repository users").Alias("group")
+
+	c.add.setup(svc, cmd)
+	c.delete.setup(svc, cmd)
+	c.list.setup(svc, cmd)
+	c.member.setup(svc, cmd)
+}
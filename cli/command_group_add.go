@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/repo"
+)
+
+type commandServerGroupAdd struct {
+	name        string
+	description string
+	members     []string
+}
+
+func (c *commandServerGroupAdd) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("add", "Add new group").Alias("create")
+	cmd.Flag("description", "Group description").StringVar(&c.description)
+	cmd.Flag("member", "Group member (user@host, possibly including wildcards)").StringsVar(&c.members)
+	cmd.Arg("name", "Group name").Required().StringVar(&c.name)
+	cmd.Action(svc.repositoryWriterAction(c.run))
+}
+
+func (c *commandServerGroupAdd) run(ctx context.Context, rep repo.RepositoryWriter) error {
+	_, err := acl.GetGroup(ctx, rep, c.name)
+
+	switch {
+	case err == nil:
+		return errors.Errorf("group %q already exists", c.name)
+
+	case !errors.Is(err, acl.ErrGroupNotFound):
+		return errors.Wrap(err, "error getting group")
+	}
+
+	g := &acl.Group{
+		Name:        c.name,
+		Description: c.description,
+	}
+
+	if err := g.AddMembers(c.members...); err != nil {
+		return errors.Wrap(err, "invalid group member")
+	}
+
+	if err := acl.SetGroup(ctx, rep, g); err != nil {
+		return errors.Wrap(err, "error setting group")
+	}
+
+	log(ctx).Infof("Group %q added. Grant permissions to its members using 'kopia server acl add --user=%v%v'.", c.name, acl.GroupPrefix, c.name)
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/repo"
+)
+
+type commandServerGroupDelete struct {
+	name string
+}
+
+func (c *commandServerGroupDelete) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("delete", "Delete group").Alias("remove").Alias("rm")
+	cmd.Arg("name", "The group to delete.").Required().StringVar(&c.name)
+	cmd.Action(svc.repositoryWriterAction(c.run))
+}
+
+func (c *commandServerGroupDelete) run(ctx context.Context, rep repo.RepositoryWriter) error {
+	if err := acl.DeleteGroup(ctx, rep, c.name); err != nil {
+		return errors.Wrap(err, "error deleting group")
+	}
+
+	log(ctx).Infof("Group %q deleted. ACL entries granted to %v%v were not removed.", c.name, acl.GroupPrefix, c.name)
+
+	return nil
+}
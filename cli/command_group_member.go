@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/repo"
+)
+
+type commandServerGroupMember struct {
+	add    commandServerGroupMemberAddRemove
+	remove commandServerGroupMemberAddRemove
+}
+
+func (c *commandServerGroupMember) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("member", "Manage group members").Alias("members")
+
+	c.add.setup(svc, cmd, true)
+	c.remove.setup(svc, cmd, false)
+}
+
+type commandServerGroupMemberAddRemove struct {
+	group   string
+	members []string
+
+	isAdd bool
+}
+
+func (c *commandServerGroupMemberAddRemove) setup(svc appServices, parent commandParent, isAdd bool) {
+	var cmd *kingpin.CmdClause
+
+	c.isAdd = isAdd
+
+	if isAdd {
+		cmd = parent.Command("add", "Add members to a group")
+	} else {
+		cmd = parent.Command("remove", "Remove members from a group").Alias("rm").Alias("delete")
+	}
+
+	cmd.Arg("group", "Group name").Required().StringVar(&c.group)
+	cmd.Arg("member", "Group member (user@host, possibly including wildcards)").Required().StringsVar(&c.members)
+	cmd.Action(svc.repositoryWriterAction(c.run))
+}
+
+func (c *commandServerGroupMemberAddRemove) run(ctx context.Context, rep repo.RepositoryWriter) error {
+	g, err := acl.GetGroup(ctx, rep, c.group)
+	if err != nil {
+		return errors.Wrap(err, "error getting group")
+	}
+
+	if c.isAdd {
+		err = g.AddMembers(c.members...)
+	} else {
+		err = g.RemoveMembers(c.members...)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "unable to change group members")
+	}
+
+	return errors.Wrap(acl.SetGroup(ctx, rep, g), "error setting group")
+}
@@ -1,15 +1,19 @@
 package cli
 
 type commandMaintenance struct {
-	info commandMaintenanceInfo
-	run  commandMaintenanceRun
-	set  commandMaintenanceSet
+	history commandMaintenanceHistory
+	info    commandMaintenanceInfo
+	run     commandMaintenanceRun
+	set     commandMaintenanceSet
+	worker  commandMaintenanceWorker
 }
 
 func (c *commandMaintenance) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("maintenance", "Maintenance commands.").Hidden().Alias("gc")
 
+	c.history.setup(svc, cmd)
 	c.info.setup(svc, cmd)
 	c.run.setup(svc, cmd)
 	c.set.setup(svc, cmd)
+	c.worker.setup(svc, cmd)
 }
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/maintenance"
+)
+
+type commandMaintenanceHistory struct {
+	task    string
+	maxRuns int
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandMaintenanceHistory) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("history", "Display history of maintenance runs")
+	cmd.Flag("task", "Only show runs of the provided maintenance task").StringVar(&c.task)
+	cmd.Flag("max-runs", "Maximum number of runs to show (0 shows all)").Default("0").IntVar(&c.maxRuns)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.directRepositoryReadAction(c.run))
+}
+
+func (c *commandMaintenanceHistory) run(ctx context.Context, rep repo.DirectRepository) error {
+	history, err := maintenance.History(ctx, rep, maintenance.TaskType(c.task))
+	if err != nil {
+		return errors.Wrap(err, "unable to get maintenance history")
+	}
+
+	if c.maxRuns > 0 && len(history) > c.maxRuns {
+		history = history[0:c.maxRuns]
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(history))
+		return nil
+	}
+
+	for _, h := range history {
+		status := "SUCCESS"
+		if !h.Success {
+			status = "ERROR: " + h.Error
+		}
+
+		c.out.printStdout("%v %-30v %-10v %v%v\n",
+			formatTimestamp(h.Start),
+			h.Task,
+			h.End.Sub(h.Start).Truncate(time.Second),
+			status,
+			formatRunStats(h.Stats))
+	}
+
+	return nil
+}
+
+func formatRunStats(stats map[string]int64) string {
+	var keys []string
+
+	for k := range stats {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var sb strings.Builder
+
+	for _, k := range keys {
+		fmt.Fprintf(&sb, " %v=%v", k, stats[k])
+	}
+
+	return sb.String()
+}
@@ -2,6 +2,8 @@
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -9,6 +11,7 @@
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob/throttling"
 	"github.com/kopia/kopia/repo/maintenance"
 )
 
@@ -72,6 +75,94 @@ func (c *commandMaintenanceInfo) run(ctx context.Context, rep repo.DirectReposit
 		c.out.printStdout("Object Lock Extension: disabled\n")
 	}
 
+	if p.Partitions > 1 {
+		c.out.printStdout("Partitions: %v\n", p.Partitions)
+	} else {
+		c.out.printStdout("Partitions: disabled\n")
+	}
+
+	if pc := p.PackCompaction; pc.LiveDataThresholdPercent > 0 {
+		c.out.printStdout("Pack Compaction: packs with less than %v%% of live data", pc.LiveDataThresholdPercent)
+
+		if pc.MaxRewriteBytes > 0 {
+			c.out.printStdout(", up to %v per run", units.BytesString(pc.MaxRewriteBytes))
+		}
+
+		c.out.printStdout("\n")
+	} else {
+		c.out.printStdout("Pack Compaction: disabled\n")
+	}
+
+	if len(p.Windows) > 0 {
+		c.out.printStdout("Windows: %v\n", strings.Join(p.Windows, "; "))
+	} else {
+		c.out.printStdout("Windows: any time\n")
+	}
+
+	if p.MaxRuntime > 0 {
+		c.out.printStdout("Max Runtime: %v\n", p.MaxRuntime)
+	} else {
+		c.out.printStdout("Max Runtime: unlimited\n")
+	}
+
+	if len(p.TaskBudgets) > 0 {
+		c.out.printStdout("Task Budgets:\n")
+
+		var tasks []string
+
+		for task := range p.TaskBudgets {
+			tasks = append(tasks, string(task))
+		}
+
+		sort.Strings(tasks)
+
+		for _, task := range tasks {
+			c.out.printStdout("  %v:\n", task)
+			c.displayBudget(p.TaskBudgets[maintenance.TaskType(task)])
+		}
+	}
+
+	if len(p.Tasks) > 0 {
+		c.out.printStdout("Task Schedule:\n")
+
+		var tasks []string
+
+		for task := range p.Tasks {
+			tasks = append(tasks, string(task))
+		}
+
+		sort.Strings(tasks)
+
+		for _, task := range tasks {
+			switch tp := p.Tasks[maintenance.TaskType(task)]; {
+			case tp.Disabled:
+				c.out.printStdout("  %v: disabled\n", task)
+			case tp.Interval > 0:
+				c.out.printStdout("  %v: every %v\n", task, tp.Interval)
+			default:
+				c.out.printStdout("  %v: every cycle\n", task)
+			}
+		}
+	}
+
+	hr := p.HistoryRetention.OrDefault()
+
+	c.out.printStdout("History Retention:\n")
+	c.out.printStdout("  max count per task: %v\n", hr.MaxCount)
+	c.out.printStdout("  max age:            %v\n", hr.MaxAge)
+
+	if ir := s.InterruptedRun; ir != nil {
+		c.out.printStdout("Interrupted %v maintenance started at %v will be resumed.\n", ir.Mode, formatTimestamp(ir.Start))
+	}
+
+	if sp := s.Scrub; sp != nil {
+		if sp.LastBlobID != "" {
+			c.out.printStdout("Scrub started at %v will continue after %v.\n", formatTimestamp(sp.CycleStart), sp.LastBlobID)
+		} else if !sp.LastCycleEnd.IsZero() {
+			c.out.printStdout("Last scrub completed at %v.\n", formatTimestamp(sp.LastCycleEnd))
+		}
+	}
+
 	c.out.printStdout("Recent Maintenance Runs:\n")
 
 	for run, timings := range s.Runs {
@@ -109,3 +200,33 @@ func (c *commandMaintenanceInfo) displayCycleInfo(cp *maintenance.CycleParams, t
 		}
 	}
 }
+
+func (c *commandMaintenanceInfo) displayBudget(b throttling.Limits) {
+	if v := b.DownloadBytesPerSecond; v > 0 {
+		c.out.printStdout("    max download speed: %v\n", units.BytesPerSecondsString(v))
+	}
+
+	if v := b.UploadBytesPerSecond; v > 0 {
+		c.out.printStdout("    max upload speed:   %v\n", units.BytesPerSecondsString(v))
+	}
+
+	if v := b.ReadsPerSecond; v > 0 {
+		c.out.printStdout("    reads per second:   %v\n", v)
+	}
+
+	if v := b.WritesPerSecond; v > 0 {
+		c.out.printStdout("    writes per second:  %v\n", v)
+	}
+
+	if v := b.ListsPerSecond; v > 0 {
+		c.out.printStdout("    lists per second:   %v\n", v)
+	}
+
+	if v := b.ConcurrentReads; v > 0 {
+		c.out.printStdout("    concurrent reads:   %v\n", v)
+	}
+
+	if v := b.ConcurrentWrites; v > 0 {
+		c.out.printStdout("    concurrent writes:  %v\n", v)
+	}
+}
@@ -2,25 +2,34 @@
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/maintenance"
 	"github.com/kopia/kopia/snapshot/snapshotmaintenance"
 )
 
 type commandMaintenanceRun struct {
-	maintenanceRunFull  bool
-	maintenanceRunForce bool
-	safety              maintenance.SafetyParameters
+	maintenanceRunFull   bool
+	maintenanceRunForce  bool
+	maintenanceRunDryRun bool
+	safety               maintenance.SafetyParameters
+
+	jo  jsonOutput
+	out textOutput
 }
 
 func (c *commandMaintenanceRun) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("run", "Run repository maintenance")
 	cmd.Flag("full", "Full maintenance").BoolVar(&c.maintenanceRunFull)
 	cmd.Flag("force", "Run maintenance even if not owned (unsafe)").Hidden().BoolVar(&c.maintenanceRunForce)
+	cmd.Flag("dry-run", "Report changes maintenance would make without making them").BoolVar(&c.maintenanceRunDryRun)
 	safetyFlagVar(cmd, &c.safety)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
 
 	cmd.Action(svc.directRepositoryWriteAction(c.run))
 }
@@ -37,6 +46,51 @@ func (c *commandMaintenanceRun) run(ctx context.Context, rep repo.DirectReposito
 		mode = maintenance.ModeFull
 	}
 
+	if c.maintenanceRunDryRun {
+		return c.dryRun(ctx, rep, mode)
+	}
+
 	//nolint:wrapcheck
 	return snapshotmaintenance.Run(ctx, rep, mode, c.maintenanceRunForce, c.safety)
 }
+
+func (c *commandMaintenanceRun) dryRun(ctx context.Context, rep repo.DirectRepositoryWriter, mode maintenance.Mode) error {
+	report, err := snapshotmaintenance.DryRun(ctx, rep, mode, c.safety)
+	if err != nil {
+		return errors.Wrap(err, "error simulating maintenance")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(report))
+		return nil
+	}
+
+	c.out.printStdout("Dry run of %v maintenance, no changes have been made:\n\n", report.Mode)
+
+	for _, t := range report.Tasks {
+		c.out.printStdout("  %-28v %v\n", t.Task, describeTaskReport(t))
+	}
+
+	c.out.printStdout("\nProjected space reclamation: %v\n", units.BytesString(report.ProjectedReclaimedBytes))
+
+	return nil
+}
+
+func describeTaskReport(t maintenance.TaskReport) string {
+	if t.SkipReason != "" {
+		return "skipped: " + t.SkipReason
+	}
+
+	switch t.Action {
+	case maintenance.ActionMarkDeleted:
+		return fmt.Sprintf("would mark %v contents as deleted (%v)", t.Contents, units.BytesString(t.ContentBytes))
+	case maintenance.ActionRewrite:
+		return fmt.Sprintf("would rewrite %v contents (%v)", t.Contents, units.BytesString(t.ContentBytes))
+	case maintenance.ActionDrop:
+		return fmt.Sprintf("would drop %v deleted contents from indexes (%v)", t.Contents, units.BytesString(t.ContentBytes))
+	case maintenance.ActionDelete:
+		return fmt.Sprintf("would delete %v unreferenced blobs (%v)", t.Blobs, units.BytesString(t.BlobBytes))
+	default:
+		return t.Action
+	}
+}
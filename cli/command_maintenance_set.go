@@ -2,12 +2,14 @@
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob/throttling"
 	"github.com/kopia/kopia/repo/maintenance"
 )
 
@@ -25,6 +27,24 @@ type commandMaintenanceSet struct {
 	maxTotalRetainedLogSizeMB int64
 
 	extendObjectLocks []bool // optional boolean
+
+	partitions int
+
+	windows    string
+	maxRuntime time.Duration
+
+	budgetTask string
+	budget     commonThrottleSet
+
+	task         string
+	taskEnabled  []bool // optional boolean
+	taskInterval time.Duration
+
+	maxHistoryCount int
+	maxHistoryAge   time.Duration
+
+	compactPacksThresholdPercent int
+	compactPacksMaxRewriteMB     int64
 }
 
 func (c *commandMaintenanceSet) setup(svc appServices, parent commandParent) {
@@ -38,6 +58,13 @@ func (c *commandMaintenanceSet) setup(svc appServices, parent commandParent) {
 	c.maxRetainedLogCount = -1
 	c.maxRetainedLogAge = -1
 	c.maxTotalRetainedLogSizeMB = -1
+	c.partitions = -1
+	c.maxRuntime = -1
+	c.taskInterval = -1
+	c.maxHistoryCount = -1
+	c.maxHistoryAge = -1
+	c.compactPacksThresholdPercent = -1
+	c.compactPacksMaxRewriteMB = -1
 
 	cmd.Flag("owner", "Set maintenance owner user@hostname").StringVar(&c.maintenanceSetOwner)
 
@@ -54,6 +81,18 @@ func (c *commandMaintenanceSet) setup(svc appServices, parent commandParent) {
 	cmd.Flag("max-retained-log-age", "Set maximum age of log sessions to retain").DurationVar(&c.maxRetainedLogAge)
 	cmd.Flag("max-retained-log-size-mb", "Set maximum total size of log sessions").Int64Var(&c.maxTotalRetainedLogSizeMB)
 	cmd.Flag("extend-object-locks", "Extend retention period of locked objects as part of full maintenance.").BoolListVar(&c.extendObjectLocks)
+	cmd.Flag("partitions", "Split full content rewrite and snapshot GC into content ID partitions processed by maintenance workers (0 disables)").IntVar(&c.partitions)
+	cmd.Flag("window", "Semicolon-separated crontab-compatible expressions matching local times when automatic maintenance is allowed to run (or 'any')").StringVar(&c.windows)
+	cmd.Flag("max-runtime", "Maximum duration of a maintenance run, unfinished tasks are resumed during the next run (0 means unlimited)").DurationVar(&c.maxRuntime)
+	cmd.Flag("budget-task", "Maintenance task whose storage budget is set using throttling flags").StringVar(&c.budgetTask)
+	c.budget.setup(cmd)
+	cmd.Flag("task", "Maintenance task whose schedule is set using --enable-task and --task-interval").StringVar(&c.task)
+	cmd.Flag("enable-task", "Enable or disable the maintenance task").BoolListVar(&c.taskEnabled)
+	cmd.Flag("task-interval", "Minimum interval between successful runs of the maintenance task (0 runs it during every maintenance cycle)").DurationVar(&c.taskInterval)
+	cmd.Flag("max-history-count", "Set maximum number of runs of each maintenance task to keep in history").IntVar(&c.maxHistoryCount)
+	cmd.Flag("max-history-age", "Set maximum age of maintenance runs to keep in history").DurationVar(&c.maxHistoryAge)
+	cmd.Flag("compact-packs-threshold", "Compact packs with lower percentage of live data during full maintenance (0 disables)").IntVar(&c.compactPacksThresholdPercent)
+	cmd.Flag("compact-packs-max-rewrite-mb", "Set maximum amount of live data rewritten by a single pack compaction (0 means unlimited)").Int64Var(&c.compactPacksMaxRewriteMB)
 
 	cmd.Action(svc.directRepositoryWriteAction(c.run))
 }
@@ -140,6 +179,184 @@ func (c *commandMaintenanceSet) setMaintenanceObjectLockExtendFromFlags(ctx cont
 	}
 }
 
+func (c *commandMaintenanceSet) setMaintenancePartitionsFromFlags(ctx context.Context, p *maintenance.Params, changed *bool) {
+	if v := c.partitions; v != -1 {
+		p.Partitions = v
+		*changed = true
+
+		if v > 1 {
+			log(ctx).Infof("Partitioned maintenance enabled with %v partitions.", v)
+		} else {
+			log(ctx).Infof("Partitioned maintenance disabled.")
+		}
+	}
+}
+
+func (c *commandMaintenanceSet) setMaintenanceWindowsFromFlags(ctx context.Context, p *maintenance.Params, changed *bool) error {
+	if c.windows != "" {
+		var windows []string
+
+		if c.windows != "any" {
+			windows = splitCronExpressions(c.windows)
+		}
+
+		if err := maintenance.ValidateWindows(windows); err != nil {
+			return errors.Wrap(err, "invalid maintenance window")
+		}
+
+		p.Windows = windows
+		*changed = true
+
+		if len(windows) == 0 {
+			log(ctx).Infof("Maintenance allowed to run at any time.")
+		} else {
+			log(ctx).Infof("Maintenance allowed to run only within %v.", strings.Join(windows, "; "))
+		}
+	}
+
+	if v := c.maxRuntime; v != -1 {
+		p.MaxRuntime = v
+		*changed = true
+
+		if v > 0 {
+			log(ctx).Infof("Setting maximum maintenance runtime to %v.", v)
+		} else {
+			log(ctx).Infof("Maximum maintenance runtime is unlimited.")
+		}
+	}
+
+	return nil
+}
+
+func (c *commandMaintenanceSet) setMaintenanceBudgetFromFlags(ctx context.Context, p *maintenance.Params, changed *bool) error {
+	task := maintenance.TaskType(c.budgetTask)
+	budget := p.TaskBudgets[task]
+
+	var changeCount int
+
+	if err := c.budget.apply(ctx, &budget, &changeCount); err != nil {
+		return err
+	}
+
+	if changeCount == 0 {
+		return nil
+	}
+
+	if task == "" {
+		return errors.Errorf("--budget-task must be specified when setting maintenance budget")
+	}
+
+	if p.TaskBudgets == nil {
+		p.TaskBudgets = map[maintenance.TaskType]throttling.Limits{}
+	}
+
+	if budget == (throttling.Limits{}) {
+		delete(p.TaskBudgets, task)
+		log(ctx).Infof("Removed budget of %v.", task)
+	} else {
+		p.TaskBudgets[task] = budget
+	}
+
+	*changed = true
+
+	return nil
+}
+
+func (c *commandMaintenanceSet) setMaintenanceTaskFromFlags(ctx context.Context, p *maintenance.Params, changed *bool) error {
+	if len(c.taskEnabled) == 0 && c.taskInterval == -1 {
+		return nil
+	}
+
+	task := maintenance.TaskType(c.task)
+	if task == "" {
+		return errors.Errorf("--task must be specified when setting task schedule")
+	}
+
+	tp := p.Tasks[task]
+
+	// we use lists to distinguish between flag not set
+	// Zero elements == not set, more than zero - flag set, in which case we pick the last value
+	if len(c.taskEnabled) > 0 {
+		tp.Disabled = !c.taskEnabled[len(c.taskEnabled)-1]
+
+		if tp.Disabled {
+			log(ctx).Infof("Maintenance task %v disabled.", task)
+		} else {
+			log(ctx).Infof("Maintenance task %v enabled.", task)
+		}
+	}
+
+	if v := c.taskInterval; v != -1 {
+		tp.Interval = v
+
+		log(ctx).Infof("Interval for maintenance task %v set to %v.", task, v)
+	}
+
+	if p.Tasks == nil {
+		p.Tasks = map[maintenance.TaskType]maintenance.TaskParams{}
+	}
+
+	if tp == (maintenance.TaskParams{}) {
+		delete(p.Tasks, task)
+	} else {
+		p.Tasks[task] = tp
+	}
+
+	*changed = true
+
+	return nil
+}
+
+func (c *commandMaintenanceSet) setHistoryRetentionFromFlags(ctx context.Context, p *maintenance.Params, changed *bool) {
+	if v := c.maxHistoryCount; v != -1 {
+		hr := p.HistoryRetention.OrDefault()
+		hr.MaxCount = v
+		p.HistoryRetention = hr
+		*changed = true
+
+		log(ctx).Infof("Setting max maintenance history count to %v.", hr.MaxCount)
+	}
+
+	if v := c.maxHistoryAge; v != -1 {
+		hr := p.HistoryRetention.OrDefault()
+		hr.MaxAge = v
+		p.HistoryRetention = hr
+		*changed = true
+
+		log(ctx).Infof("Setting max maintenance history age to %v.", hr.MaxAge)
+	}
+}
+
+func (c *commandMaintenanceSet) setPackCompactionFromFlags(ctx context.Context, p *maintenance.Params, changed *bool) error {
+	if v := c.compactPacksThresholdPercent; v != -1 {
+		if v < 0 || v > 100 { //nolint:gomnd
+			return errors.Errorf("invalid pack compaction threshold: %v%%", v)
+		}
+
+		p.PackCompaction.LiveDataThresholdPercent = v
+		*changed = true
+
+		if v > 0 {
+			log(ctx).Infof("Packs with less than %v%% of live data will be compacted.", v)
+		} else {
+			log(ctx).Infof("Pack compaction disabled.")
+		}
+	}
+
+	if v := c.compactPacksMaxRewriteMB; v != -1 {
+		p.PackCompaction.MaxRewriteBytes = v << 20 //nolint:gomnd
+		*changed = true
+
+		if v > 0 {
+			log(ctx).Infof("Setting maximum amount of data rewritten by pack compaction to %v.", units.BytesString(p.PackCompaction.MaxRewriteBytes))
+		} else {
+			log(ctx).Infof("Amount of data rewritten by pack compaction is unlimited.")
+		}
+	}
+
+	return nil
+}
+
 func (c *commandMaintenanceSet) run(ctx context.Context, rep repo.DirectRepositoryWriter) error {
 	p, err := maintenance.GetParams(ctx, rep)
 	if err != nil {
@@ -158,6 +375,25 @@ func (c *commandMaintenanceSet) run(ctx context.Context, rep repo.DirectReposito
 	c.setMaintenanceEnabledAndIntervalFromFlags(ctx, &p.FullCycle, "full", c.maintenanceSetEnableFull, c.maintenanceSetFullFrequency, &changedParams)
 	c.setLogCleanupParametersFromFlags(ctx, p, &changedParams)
 	c.setMaintenanceObjectLockExtendFromFlags(ctx, p, &changedParams)
+	c.setMaintenancePartitionsFromFlags(ctx, p, &changedParams)
+
+	if err := c.setMaintenanceWindowsFromFlags(ctx, p, &changedParams); err != nil {
+		return err
+	}
+
+	if err := c.setMaintenanceBudgetFromFlags(ctx, p, &changedParams); err != nil {
+		return err
+	}
+
+	if err := c.setMaintenanceTaskFromFlags(ctx, p, &changedParams); err != nil {
+		return err
+	}
+
+	c.setHistoryRetentionFromFlags(ctx, p, &changedParams)
+
+	if err := c.setPackCompactionFromFlags(ctx, p, &changedParams); err != nil {
+		return err
+	}
 
 	if pauseDuration := c.maintenanceSetPauseQuick; pauseDuration != -1 {
 		s.NextQuickMaintenanceTime = rep.Time().Add(pauseDuration)
@@ -2,12 +2,15 @@
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/kopia/kopia/cli"
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/throttling"
+	"github.com/kopia/kopia/repo/maintenance"
 	"github.com/kopia/kopia/tests/testenv"
 )
 
@@ -38,6 +41,124 @@ func TestMaintenanceSetExtendObjectLocks(t *testing.T) {
 	require.False(t, mi.ExtendObjectLocks, "ExtendOjectLocks should be disabled.")
 }
 
+func TestMaintenanceSetPartitions(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	var mi cli.MaintenanceInfo
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.Zero(t, mi.Partitions)
+
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--partitions", "8")
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.Equal(t, 8, mi.Partitions)
+
+	e.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none")
+
+	// no partitioned maintenance in progress.
+	e.RunAndExpectSuccess(t, "maintenance", "worker")
+
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--partitions", "0")
+
+	mi = cli.MaintenanceInfo{}
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.Zero(t, mi.Partitions)
+}
+
+func TestMaintenanceSetWindowsAndBudgets(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	var mi cli.MaintenanceInfo
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--window", "* 1-4 * * *; * 22-23 * * 6", "--max-runtime", "2h")
+	e.RunAndExpectFailure(t, "maintenance", "set", "--window", "not-a-cron-expression")
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.Equal(t, []string{"* 1-4 * * *", "* 22-23 * * 6"}, mi.Windows)
+	require.Equal(t, 2*time.Hour, mi.MaxRuntime)
+
+	// throttling flags require a task.
+	e.RunAndExpectFailure(t, "maintenance", "set", "--upload-bytes-per-second", "1000")
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--budget-task", maintenance.TaskRewriteContentsFull, "--upload-bytes-per-second", "1000", "--concurrent-reads", "2")
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.Equal(t, map[maintenance.TaskType]throttling.Limits{
+		maintenance.TaskRewriteContentsFull: {UploadBytesPerSecond: 1000, ConcurrentReads: 2},
+	}, mi.TaskBudgets)
+
+	e.RunAndExpectSuccess(t, "maintenance", "info")
+
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--window", "any", "--max-runtime", "0",
+		"--budget-task", maintenance.TaskRewriteContentsFull, "--upload-bytes-per-second", "unlimited", "--concurrent-reads", "unlimited")
+
+	mi = cli.MaintenanceInfo{}
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.Empty(t, mi.Windows)
+	require.Zero(t, mi.MaxRuntime)
+	require.Empty(t, mi.TaskBudgets)
+}
+
+func TestMaintenanceSetTaskScheduleAndHistory(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	var (
+		mi      cli.MaintenanceInfo
+		history []maintenance.HistoryEntry
+	)
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	e.RunAndExpectFailure(t, "maintenance", "set", "--enable-task", "false")
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--task", maintenance.TaskSnapshotGarbageCollection, "--enable-task", "false",
+		"--max-history-count", "20")
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--task", maintenance.TaskCleanupLogs, "--task-interval", "24h")
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.Equal(t, map[maintenance.TaskType]maintenance.TaskParams{
+		maintenance.TaskSnapshotGarbageCollection: {Disabled: true},
+		maintenance.TaskCleanupLogs:               {Interval: 24 * time.Hour},
+	}, mi.Tasks)
+	require.Equal(t, 20, mi.HistoryRetention.MaxCount)
+
+	e.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none")
+	e.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none")
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "history", "--json", "--task", maintenance.TaskSnapshotGarbageCollection), &history)
+	require.Empty(t, history)
+
+	// second cleanup is not due yet.
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "history", "--json", "--task", maintenance.TaskCleanupLogs), &history)
+	require.Len(t, history, 1)
+	require.Contains(t, history[0].Stats, "deletedLogs")
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "history", "--json", "--max-runs", "3"), &history)
+	require.Len(t, history, 3)
+
+	e.RunAndExpectSuccess(t, "maintenance", "history")
+
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--task", maintenance.TaskSnapshotGarbageCollection, "--enable-task", "true")
+
+	mi = cli.MaintenanceInfo{}
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.Equal(t, map[maintenance.TaskType]maintenance.TaskParams{
+		maintenance.TaskCleanupLogs: {Interval: 24 * time.Hour},
+	}, mi.Tasks)
+}
+
 func (s *formatSpecificTestSuite) TestInvalidExtendRetainOptions(t *testing.T) {
 	var mi cli.MaintenanceInfo
 
@@ -81,3 +202,32 @@ func (s *formatSpecificTestSuite) TestInvalidExtendRetainOptions(t *testing.T) {
 	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "repo", "status", "--json"), &rs)
 	require.True(t, rs.BlobRetention.RetentionPeriod == 176400000000000, "retention-interval should be unchanged.")
 }
+
+func TestMaintenanceSetPackCompaction(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	var mi cli.MaintenanceInfo
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	e.RunAndExpectFailure(t, "maintenance", "set", "--compact-packs-threshold", "101")
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--compact-packs-threshold", "50", "--compact-packs-max-rewrite-mb", "100")
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.Equal(t, maintenance.PackCompactionParams{
+		LiveDataThresholdPercent: 50,
+		MaxRewriteBytes:          100 << 20,
+	}, mi.PackCompaction)
+
+	e.RunAndExpectSuccess(t, "maintenance", "info")
+	e.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none")
+
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--compact-packs-threshold", "0", "--compact-packs-max-rewrite-mb", "0")
+
+	mi = cli.MaintenanceInfo{}
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.Zero(t, mi.PackCompaction)
+}
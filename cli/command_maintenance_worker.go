@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot/snapshotmaintenance"
+)
+
+type commandMaintenanceWorker struct {
+	watch        bool
+	pollInterval time.Duration
+}
+
+func (c *commandMaintenanceWorker) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("worker", "Process partitions of maintenance run by the maintenance owner")
+	cmd.Flag("watch", "Keep waiting for new partitioned maintenance").BoolVar(&c.watch)
+	cmd.Flag("poll-interval", "Interval between checks for partitioned maintenance when watching").Default("1m").DurationVar(&c.pollInterval)
+
+	cmd.Action(svc.directRepositoryWriteAction(c.run))
+}
+
+func (c *commandMaintenanceWorker) run(ctx context.Context, rep repo.DirectRepositoryWriter) error {
+	for {
+		n, err := snapshotmaintenance.RunWorker(ctx, rep)
+		if err != nil {
+			return errors.Wrap(err, "maintenance worker error")
+		}
+
+		if n > 0 {
+			log(ctx).Infof("Processed %v maintenance partitions.", n)
+		}
+
+		if !c.watch {
+			if n == 0 {
+				log(ctx).Infof("No partitioned maintenance available.")
+			}
+
+			return nil
+		}
+
+		if !clock.SleepInterruptibly(ctx, c.pollInterval) {
+			return nil
+		}
+	}
+}
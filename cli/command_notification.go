@@ -0,0 +1,13 @@
+package cli
+
+type commandNotification struct {
+	profile  commandNotificationProfile
+	template commandNotificationTemplate
+}
+
+func (c *commandNotification) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("notification", "Commands to manage notifications about snapshot, maintenance and error events.").Alias("notifications")
+
+	c.profile.setup(svc, cmd)
+	c.template.setup(svc, cmd)
+}
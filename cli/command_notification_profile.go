@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/repo"
+)
+
+// notificationSeverityNames lists names of notification severities, from the least to the most severe.
+//
+//nolint:gochecknoglobals
+var notificationSeverityNames = []string{"verbose", "success", "report", "warning", "error"}
+
+type commandNotificationProfile struct {
+	add    commandNotificationProfileAdd
+	delete commandNotificationProfileDelete
+	list   commandNotificationProfileList
+	test   commandNotificationProfileTest
+}
+
+func (c *commandNotificationProfile) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("profile", "Manage notification profiles, which route notifications to their destinations.")
+
+	c.add.setup(svc, cmd)
+	c.delete.setup(svc, cmd)
+	c.list.setup(svc, cmd)
+	c.test.setup(svc, cmd)
+}
+
+type commandNotificationProfileDelete struct {
+	profileName string
+}
+
+func (c *commandNotificationProfileDelete) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("delete", "Delete notification profile").Alias("rm")
+	cmd.Flag("profile-name", "Profile name").Required().StringVar(&c.profileName)
+	cmd.Action(svc.repositoryWriterAction(c.run))
+}
+
+func (c *commandNotificationProfileDelete) run(ctx context.Context, rep repo.RepositoryWriter) error {
+	//nolint:wrapcheck
+	return notifyprofile.DeleteProfile(ctx, rep, c.profileName)
+}
+
+type notificationProfileListItem struct {
+	ProfileName string `json:"profile"`
+	Type        string `json:"type"`
+	MinSeverity string `json:"minSeverity"`
+	Summary     string `json:"summary"`
+}
+
+type commandNotificationProfileList struct {
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandNotificationProfileList) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("list", "List notification profiles").Alias("ls")
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.repositoryReaderAction(c.run))
+}
+
+func (c *commandNotificationProfileList) run(ctx context.Context, rep repo.Repository) error {
+	profiles, err := notifyprofile.ListProfiles(ctx, rep)
+	if err != nil {
+		return errors.Wrap(err, "unable to list notification profiles")
+	}
+
+	items := []notificationProfileListItem{}
+
+	for _, p := range profiles {
+		item := notificationProfileListItem{
+			ProfileName: p.ProfileName,
+			Type:        string(p.MethodConfig.Type),
+			MinSeverity: p.MinSeverity.String(),
+		}
+
+		if s, err := sender.GetSender(ctx, p.MethodConfig); err == nil {
+			item.Summary = s.Summary()
+		} else {
+			item.Summary = "invalid configuration: " + err.Error()
+		}
+
+		items = append(items, item)
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(items))
+		return nil
+	}
+
+	for _, it := range items {
+		c.out.printStdout("Profile %q Type %q Minimum Severity: %v\n  %v\n", it.ProfileName, it.Type, it.MinSeverity, it.Summary)
+	}
+
+	return nil
+}
+
+type commandNotificationProfileTest struct {
+	profileName string
+}
+
+func (c *commandNotificationProfileTest) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("test", "Send test notification using the profile")
+	cmd.Flag("profile-name", "Profile name").Required().StringVar(&c.profileName)
+	cmd.Action(svc.repositoryReaderAction(c.run))
+}
+
+func (c *commandNotificationProfileTest) run(ctx context.Context, rep repo.Repository) error {
+	p, err := notifyprofile.GetProfile(ctx, rep, c.profileName)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get notification profile %q", c.profileName)
+	}
+
+	return sendTestNotification(ctx, rep, p)
+}
+
+func sendTestNotification(ctx context.Context, rep repo.Repository, p *notifyprofile.Config) error {
+	msg, err := notification.RenderMessage(ctx, rep, notifytemplate.TestNotification, &notification.TestNotification{
+		ProfileName: p.ProfileName,
+	}, sender.SeverityReport)
+	if err != nil {
+		return errors.Wrap(err, "unable to render test notification")
+	}
+
+	log(ctx).Infof("Sending test notification using profile %q...", p.ProfileName)
+
+	//nolint:wrapcheck
+	return notification.SendTo(ctx, rep, p, msg)
+}
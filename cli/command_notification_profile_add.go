@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/command"
+	"github.com/kopia/kopia/notification/sender/email"
+	"github.com/kopia/kopia/notification/sender/webhook"
+	"github.com/kopia/kopia/repo"
+)
+
+type commandNotificationProfileAdd struct {
+	email   commandNotificationProfileAddEmail
+	webhook commandNotificationProfileAddWebhook
+	command commandNotificationProfileAddCommand
+}
+
+func (c *commandNotificationProfileAdd) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("add", "Add or replace notification profile")
+
+	c.email.setup(svc, cmd)
+	c.webhook.setup(svc, cmd)
+	c.command.setup(svc, cmd)
+}
+
+// notificationProfileFlags are common to all notification methods.
+type notificationProfileFlags struct {
+	profileName          string
+	minSeverity          string
+	sendTestNotification bool
+}
+
+func (c *notificationProfileFlags) setup(cmd *kingpin.CmdClause) {
+	cmd.Flag("profile-name", "Profile name").Required().StringVar(&c.profileName)
+	cmd.Flag("min-severity", "Minimum severity of notifications sent using the profile").Default("warning").EnumVar(&c.minSeverity, notificationSeverityNames...)
+	cmd.Flag("send-test-notification", "Send test notification after saving the profile").BoolVar(&c.sendTestNotification)
+}
+
+func (c *notificationProfileFlags) save(ctx context.Context, rep repo.RepositoryWriter, mc sender.MethodConfig) error {
+	severity, err := sender.ParseSeverity(c.minSeverity)
+	if err != nil {
+		return errors.Wrap(err, "invalid minimum severity")
+	}
+
+	// creating the sender validates its options and applies their defaults.
+	if _, err := sender.GetSender(ctx, mc); err != nil {
+		return errors.Wrap(err, "invalid notification profile")
+	}
+
+	p := &notifyprofile.Config{
+		ProfileName:  c.profileName,
+		MethodConfig: mc,
+		MinSeverity:  severity,
+	}
+
+	if c.sendTestNotification {
+		if err := sendTestNotification(ctx, rep, p); err != nil {
+			return errors.Wrap(err, "unable to send test notification, profile not saved")
+		}
+	}
+
+	log(ctx).Infof("Saving notification profile %q...", c.profileName)
+
+	//nolint:wrapcheck
+	return notifyprofile.SaveProfile(ctx, rep, p)
+}
+
+type commandNotificationProfileAddEmail struct {
+	notificationProfileFlags
+
+	opt email.Options
+}
+
+func (c *commandNotificationProfileAddEmail) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("email", "Send notifications by email using SMTP server")
+	c.notificationProfileFlags.setup(cmd)
+
+	cmd.Flag("smtp-server", "SMTP server").Required().StringVar(&c.opt.SMTPServer)
+	cmd.Flag("smtp-port", "SMTP port").Default("587").IntVar(&c.opt.SMTPPort)
+	cmd.Flag("smtp-identity", "SMTP identity").StringVar(&c.opt.SMTPIdentity)
+	cmd.Flag("smtp-username", "SMTP username").StringVar(&c.opt.SMTPUsername)
+	cmd.Flag("smtp-password", "SMTP password").Envar(svc.EnvName("KOPIA_SMTP_PASSWORD")).StringVar(&c.opt.SMTPPassword)
+	cmd.Flag("mail-from", "From address").Required().StringVar(&c.opt.From)
+	cmd.Flag("mail-to", "To address, multiple addresses can be separated by commas").Required().StringVar(&c.opt.To)
+
+	cmd.Action(svc.repositoryWriterAction(func(ctx context.Context, rep repo.RepositoryWriter) error {
+		return c.save(ctx, rep, sender.MethodConfig{Type: email.Method, Config: &c.opt})
+	}))
+}
+
+type commandNotificationProfileAddWebhook struct {
+	notificationProfileFlags
+
+	opt     webhook.Options
+	headers []string
+}
+
+func (c *commandNotificationProfileAddWebhook) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("webhook", "Send notifications as JSON payload of HTTP requests")
+	c.notificationProfileFlags.setup(cmd)
+
+	cmd.Flag("endpoint", "Webhook endpoint URL").Required().StringVar(&c.opt.Endpoint)
+	cmd.Flag("method", "HTTP method").Default("POST").StringVar(&c.opt.Method)
+	cmd.Flag("http-header", "HTTP header to send with each request, in the form 'Name: Value'").StringsVar(&c.headers)
+
+	cmd.Action(svc.repositoryWriterAction(func(ctx context.Context, rep repo.RepositoryWriter) error {
+		c.opt.Headers = map[string]string{}
+
+		for _, h := range c.headers {
+			k, v, ok := strings.Cut(h, ":")
+			if !ok {
+				return errors.Errorf("invalid HTTP header %q, must be 'Name: Value'", h)
+			}
+
+			c.opt.Headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+
+		return c.save(ctx, rep, sender.MethodConfig{Type: webhook.Method, Config: &c.opt})
+	}))
+}
+
+type commandNotificationProfileAddCommand struct {
+	notificationProfileFlags
+
+	opt command.Options
+}
+
+func (c *commandNotificationProfileAddCommand) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("command", "Send notifications by running a command, which receives the message body on standard input")
+	c.notificationProfileFlags.setup(cmd)
+
+	cmd.Flag("command", "Command to run").Required().StringVar(&c.opt.Command)
+	cmd.Flag("arg", "Command argument (can be repeated)").StringsVar(&c.opt.Arguments)
+	cmd.Flag("timeout-seconds", "Maximum time the command is allowed to run").IntVar(&c.opt.TimeoutSeconds)
+
+	cmd.Action(svc.repositoryWriterAction(func(ctx context.Context, rep repo.RepositoryWriter) error {
+		return c.save(ctx, rep, sender.MethodConfig{Type: command.Method, Config: &c.opt})
+	}))
+}
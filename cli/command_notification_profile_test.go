@@ -0,0 +1,133 @@
+package cli_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/notification/sender/webhook"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestNotificationProfile(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		payloads []webhook.Payload
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhook.Payload
+
+		if r.Header.Get("Authorization") != "Bearer some-token" || json.NewDecoder(r.Body).Decode(&p) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		payloads = append(payloads, p)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	received := func() []webhook.Payload {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]webhook.Payload(nil), payloads...)
+	}
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer env.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	// profile is not saved when the test notification fails.
+	env.RunAndExpectFailure(t, "notification", "profile", "add", "webhook", "--profile-name=hook", "--endpoint", srv.URL, "--send-test-notification")
+	require.Empty(t, received())
+
+	env.RunAndExpectSuccess(t, "notification", "profile", "add", "webhook", "--profile-name=hook", "--endpoint", srv.URL,
+		"--http-header", "Authorization: Bearer some-token", "--min-severity=success", "--send-test-notification")
+	require.Len(t, received(), 1)
+	require.True(t, strings.HasPrefix(received()[0].Subject, "Test notification from Kopia"), received()[0].Subject)
+	require.Equal(t, "report", received()[0].Severity)
+
+	env.RunAndExpectSuccess(t, "notification", "profile", "test", "--profile-name=hook")
+	require.Len(t, received(), 2)
+
+	env.RunAndExpectFailure(t, "notification", "profile", "test", "--profile-name=no-such-profile")
+	env.RunAndExpectFailure(t, "notification", "profile", "add", "email", "--profile-name=mail", "--smtp-server=localhost", "--mail-from=a@example.com", "--mail-to=,")
+
+	env.RunAndExpectSuccess(t, "notification", "profile", "add", "command", "--profile-name=cmd", "--command=some-command", "--min-severity=error")
+	env.RunAndExpectSuccess(t, "notification", "profile", "list")
+
+	var profiles []struct {
+		ProfileName string `json:"profile"`
+		Type        string `json:"type"`
+		MinSeverity string `json:"minSeverity"`
+		Summary     string `json:"summary"`
+	}
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "notification", "profile", "list", "--json"), &profiles)
+	require.Len(t, profiles, 2)
+	require.Equal(t, "cmd", profiles[0].ProfileName)
+	require.Equal(t, "command", profiles[0].Type)
+	require.Equal(t, "error", profiles[0].MinSeverity)
+	require.Equal(t, "Command: some-command", profiles[0].Summary)
+	require.Equal(t, "hook", profiles[1].ProfileName)
+	require.Equal(t, "webhook", profiles[1].Type)
+	require.Equal(t, "success", profiles[1].MinSeverity)
+
+	env.RunAndExpectSuccess(t, "notification", "profile", "delete", "--profile-name=cmd")
+	env.RunAndExpectFailure(t, "notification", "profile", "delete", "--profile-name=cmd")
+
+	// maintenance runs are reported to the profile accepting successes.
+	env.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none")
+
+	var maintenanceReports []webhook.Payload
+
+	for _, p := range received() {
+		if strings.Contains(p.Subject, "full maintenance completed") {
+			maintenanceReports = append(maintenanceReports, p)
+		}
+	}
+
+	require.Len(t, maintenanceReports, 1)
+	require.Equal(t, "success", maintenanceReports[0].Severity)
+}
+
+func TestNotificationTemplate(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer env.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	require.Contains(t, env.RunAndExpectSuccess(t, "notification", "template", "list"), "verify-error.txt")
+
+	original := env.RunAndExpectSuccess(t, "notification", "template", "show", "verify-error.txt")
+
+	templateFile := filepath.Join(t.TempDir(), "template.txt")
+	require.NoError(t, os.WriteFile(templateFile, []byte("Subject: custom subject\n\nbody\n"), 0o600))
+
+	env.RunAndExpectFailure(t, "notification", "template", "set", "verify-error.txt")
+	env.RunAndExpectFailure(t, "notification", "template", "set", "no-such-template.txt", "--from-file", templateFile)
+	env.RunAndExpectSuccess(t, "notification", "template", "set", "verify-error.txt", "--from-file", templateFile)
+
+	require.Contains(t, env.RunAndExpectSuccess(t, "notification", "template", "list"), "verify-error.txt (customized)")
+	require.Equal(t, []string{"Subject: custom subject", "", "body"}, env.RunAndExpectSuccess(t, "notification", "template", "show", "verify-error.txt"))
+	require.Equal(t, original, env.RunAndExpectSuccess(t, "notification", "template", "show", "verify-error.txt", "--original"))
+
+	env.RunAndExpectSuccess(t, "notification", "template", "reset", "verify-error.txt")
+	require.Equal(t, original, env.RunAndExpectSuccess(t, "notification", "template", "show", "verify-error.txt"))
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/repo"
+)
+
+type commandNotificationTemplate struct {
+	list  commandNotificationTemplateList
+	reset commandNotificationTemplateReset
+	set   commandNotificationTemplateSet
+	show  commandNotificationTemplateShow
+}
+
+func (c *commandNotificationTemplate) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("template", "Manage templates of notification messages.")
+
+	c.list.setup(svc, cmd)
+	c.reset.setup(svc, cmd)
+	c.set.setup(svc, cmd)
+	c.show.setup(svc, cmd)
+}
+
+type commandNotificationTemplateList struct {
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandNotificationTemplateList) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("list", "List notification templates").Alias("ls")
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.repositoryReaderAction(c.run))
+}
+
+func (c *commandNotificationTemplateList) run(ctx context.Context, rep repo.Repository) error {
+	infos, err := notifytemplate.ListTemplates(ctx, rep)
+	if err != nil {
+		return errors.Wrap(err, "unable to list notification templates")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(infos))
+		return nil
+	}
+
+	for _, i := range infos {
+		if i.IsCustomized {
+			c.out.printStdout("%v (customized)\n", i.Name)
+		} else {
+			c.out.printStdout("%v\n", i.Name)
+		}
+	}
+
+	return nil
+}
+
+type commandNotificationTemplateShow struct {
+	name     string
+	original bool
+
+	out textOutput
+}
+
+func (c *commandNotificationTemplateShow) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("show", "Show notification template")
+	cmd.Arg("name", "Template name").Required().StringVar(&c.name)
+	cmd.Flag("original", "Show the built-in template instead of its customized version").BoolVar(&c.original)
+	c.out.setup(svc)
+	cmd.Action(svc.repositoryReaderAction(c.run))
+}
+
+func (c *commandNotificationTemplateShow) run(ctx context.Context, rep repo.Repository) error {
+	var (
+		text string
+		err  error
+	)
+
+	if c.original {
+		text, err = notifytemplate.DefaultTemplate(c.name)
+	} else {
+		text, err = notifytemplate.GetTemplate(ctx, rep, c.name)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "unable to get notification template")
+	}
+
+	c.out.printStdout("%v", text)
+
+	return nil
+}
+
+type commandNotificationTemplateSet struct {
+	name      string
+	fromFile  string
+	fromStdin bool
+
+	svc appServices
+}
+
+func (c *commandNotificationTemplateSet) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("set", "Customize notification template")
+	cmd.Arg("name", "Template name").Required().StringVar(&c.name)
+	cmd.Flag("from-file", "Read template text from the provided file").StringVar(&c.fromFile)
+	cmd.Flag("from-stdin", "Read template text from standard input").BoolVar(&c.fromStdin)
+	c.svc = svc
+	cmd.Action(svc.repositoryWriterAction(c.run))
+}
+
+func (c *commandNotificationTemplateSet) run(ctx context.Context, rep repo.RepositoryWriter) error {
+	var (
+		b   []byte
+		err error
+	)
+
+	switch {
+	case c.fromFile != "":
+		b, err = os.ReadFile(c.fromFile)
+	case c.fromStdin:
+		b, err = io.ReadAll(c.svc.stdin())
+	default:
+		return errors.Errorf("must specify either --from-file or --from-stdin")
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "unable to read template text")
+	}
+
+	//nolint:wrapcheck
+	return notifytemplate.SetTemplate(ctx, rep, c.name, string(b))
+}
+
+type commandNotificationTemplateReset struct {
+	name string
+}
+
+func (c *commandNotificationTemplateReset) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("reset", "Restore built-in notification template")
+	cmd.Arg("name", "Template name").Required().StringVar(&c.name)
+	cmd.Action(svc.repositoryWriterAction(c.run))
+}
+
+func (c *commandNotificationTemplateReset) run(ctx context.Context, rep repo.RepositoryWriter) error {
+	//nolint:wrapcheck
+	return notifytemplate.ResetTemplate(ctx, rep, c.name)
+}
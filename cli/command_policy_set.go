@@ -24,6 +24,7 @@ type commandPolicySet struct {
 	policyLoggingFlags
 	policyRetentionFlags
 	policySchedulingFlags
+	policySplitterFlags
 	policyUploadFlags
 }
 
@@ -39,6 +40,7 @@ func (c *commandPolicySet) setup(svc appServices, parent commandParent) {
 	c.policyLoggingFlags.setup(cmd)
 	c.policyRetentionFlags.setup(cmd)
 	c.policySchedulingFlags.setup(cmd)
+	c.policySplitterFlags.setup(cmd)
 	c.policyUploadFlags.setup(cmd)
 
 	cmd.Action(svc.repositoryWriterAction(c.run))
@@ -104,6 +106,10 @@ func (c *commandPolicySet) setPolicyFromFlags(ctx context.Context, p *policy.Pol
 		return errors.Wrap(err, "compression policy")
 	}
 
+	if err := c.setSplitterPolicyFromFlags(ctx, &p.SplitterPolicy, changeCount); err != nil {
+		return errors.Wrap(err, "splitter policy")
+	}
+
 	if err := c.setSchedulingPolicyFromFlags(ctx, &p.SchedulingPolicy, changeCount); err != nil {
 		return errors.Wrap(err, "scheduling policy")
 	}
@@ -303,5 +309,5 @@ func supportedCompressionAlgorithms() []string {
 
 	sort.Strings(res)
 
-	return append([]string{inheritPolicyString, "none"}, res...)
+	return append([]string{inheritPolicyString, "none", string(compression.AutoName)}, res...)
 }
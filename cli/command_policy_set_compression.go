@@ -11,9 +11,10 @@
 )
 
 type policyCompressionFlags struct {
-	policySetCompressionAlgorithm string
-	policySetCompressionMinSize   string
-	policySetCompressionMaxSize   string
+	policySetCompressionAlgorithm  string
+	policySetCompressionMinSize    string
+	policySetCompressionMaxSize    string
+	policySetCompressionDictionary string
 
 	policySetAddOnlyCompress    []string
 	policySetRemoveOnlyCompress []string
@@ -29,6 +30,7 @@ func (c *policyCompressionFlags) setup(cmd *kingpin.CmdClause) {
 	cmd.Flag("compression", "Compression algorithm").EnumVar(&c.policySetCompressionAlgorithm, supportedCompressionAlgorithms()...)
 	cmd.Flag("compression-min-size", "Min size of file to attempt compression for").StringVar(&c.policySetCompressionMinSize)
 	cmd.Flag("compression-max-size", "Max size of file to attempt compression for").StringVar(&c.policySetCompressionMaxSize)
+	cmd.Flag("compression-dictionary", "Compress small files using dictionary trained for the source ('true', 'false', 'inherit')").EnumVar(&c.policySetCompressionDictionary, booleanEnumValues...)
 
 	// Files to only compress.
 	cmd.Flag("add-only-compress", "List of extensions to add to the only-compress list").PlaceHolder("PATTERN").StringsVar(&c.policySetAddOnlyCompress)
@@ -50,6 +52,10 @@ func (c *policyCompressionFlags) setCompressionPolicyFromFlags(ctx context.Conte
 		return errors.Wrap(err, "maximum file size subject to compression")
 	}
 
+	if err := applyPolicyBoolPtr(ctx, "compression dictionary", &p.UseDictionary, c.policySetCompressionDictionary, changeCount); err != nil {
+		return errors.Wrap(err, "compression dictionary")
+	}
+
 	if v := c.policySetCompressionAlgorithm; v != "" {
 		*changeCount++
 
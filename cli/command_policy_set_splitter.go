@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/kopia/kopia/repo/splitter"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+type policySplitterFlags struct {
+	policySetSplitterAlgorithm               string
+	policySetBlockAlignedSplitterAlgorithm   string
+	policySetArchiveMembersSplitterAlgorithm string
+
+	policySetAddBlockAligned     []string
+	policySetRemoveBlockAligned  []string
+	policySetClearBlockAligned   bool
+	policySetDisableBlockAligned bool
+
+	policySetAddArchiveMembers     []string
+	policySetRemoveArchiveMembers  []string
+	policySetClearArchiveMembers   bool
+	policySetDisableArchiveMembers bool
+}
+
+func (c *policySplitterFlags) setup(cmd *kingpin.CmdClause) {
+	algorithms := append([]string{inheritPolicyString}, splitter.SupportedAlgorithms()...)
+	archiveAlgorithms := append(append([]string{}, algorithms...), splitter.SupportedPolicyAlgorithms()...)
+
+	cmd.Flag("splitter", "Splitter algorithm for files (overrides repository default)").EnumVar(&c.policySetSplitterAlgorithm, algorithms...)
+	cmd.Flag("block-aligned-splitter", "Splitter algorithm for block-aligned files, such as VM disk images").EnumVar(&c.policySetBlockAlignedSplitterAlgorithm, algorithms...)
+	cmd.Flag("archive-splitter", "Splitter algorithm for ZIP-like archives").EnumVar(&c.policySetArchiveMembersSplitterAlgorithm, archiveAlgorithms...)
+
+	// Files to split using block-aligned splitter, such as VM disk images, the list replaces lists of parent policies.
+	cmd.Flag("add-block-aligned", "List of extensions to add to the block-aligned splitting list (replaces the list inherited from parent policies)").PlaceHolder("PATTERN").StringsVar(&c.policySetAddBlockAligned)
+	cmd.Flag("remove-block-aligned", "List of extensions to remove from the block-aligned splitting list").PlaceHolder("PATTERN").StringsVar(&c.policySetRemoveBlockAligned)
+	cmd.Flag("clear-block-aligned", "Clear list of extensions in the block-aligned splitting list and inherit it from the parent policy").BoolVar(&c.policySetClearBlockAligned)
+	cmd.Flag("disable-block-aligned", "Do not use block-aligned splitting for any files, regardless of parent policies").BoolVar(&c.policySetDisableBlockAligned)
+
+	// Files to split using archive splitter, such as ZIP-based formats, the list replaces lists of parent policies.
+	cmd.Flag("add-archive-members", "List of extensions to add to the archive member splitting list (replaces the list inherited from parent policies)").PlaceHolder("PATTERN").StringsVar(&c.policySetAddArchiveMembers)
+	cmd.Flag("remove-archive-members", "List of extensions to remove from the archive member splitting list").PlaceHolder("PATTERN").StringsVar(&c.policySetRemoveArchiveMembers)
+	cmd.Flag("clear-archive-members", "Clear list of extensions in the archive member splitting list and inherit it from the parent policy").BoolVar(&c.policySetClearArchiveMembers)
+	cmd.Flag("disable-archive-members", "Do not split any files at archive member boundaries, regardless of parent policies").BoolVar(&c.policySetDisableArchiveMembers)
+}
+
+func (c *policySplitterFlags) setSplitterPolicyFromFlags(ctx context.Context, p *policy.SplitterPolicy, changeCount *int) error {
+	applyPolicySplitterName(ctx, "splitter algorithm", &p.Algorithm, c.policySetSplitterAlgorithm, changeCount)
+	applyPolicySplitterName(ctx, "block-aligned splitter algorithm", &p.BlockAlignedAlgorithm, c.policySetBlockAlignedSplitterAlgorithm, changeCount)
+	applyPolicySplitterName(ctx, "archive splitter algorithm", &p.ArchiveMembersAlgorithm, c.policySetArchiveMembersSplitterAlgorithm, changeCount)
+
+	applyPolicySplitterExtensions(ctx, "block-aligned splitting extensions",
+		&p.BlockAligned, &p.NoParentBlockAligned, c.policySetAddBlockAligned, c.policySetRemoveBlockAligned, c.policySetClearBlockAligned, c.policySetDisableBlockAligned, changeCount)
+
+	applyPolicySplitterExtensions(ctx, "archive member splitting extensions",
+		&p.ArchiveMembers, &p.NoParentArchiveMembers, c.policySetAddArchiveMembers, c.policySetRemoveArchiveMembers, c.policySetClearArchiveMembers, c.policySetDisableArchiveMembers, changeCount)
+
+	return nil
+}
+
+// applyPolicySplitterExtensions applies changes to the list of extensions, which is inherited from the parent
+// policy when empty, unless it has been disabled.
+func applyPolicySplitterExtensions(ctx context.Context, desc string, val *[]string, noParent *bool, add, remove []string, clear, disable bool, changeCount *int) {
+	if disable {
+		log(ctx).Infof(" - disabling %v", desc)
+
+		*changeCount++
+
+		*val = nil
+		*noParent = true
+
+		return
+	}
+
+	if clear {
+		*noParent = false
+	}
+
+	applyPolicyStringList(ctx, desc, val, add, remove, clear, changeCount)
+}
+
+func applyPolicySplitterName(ctx context.Context, desc string, val *string, str string, changeCount *int) {
+	if str == "" {
+		// not changed
+		return
+	}
+
+	*changeCount++
+
+	if str == inheritPolicyString {
+		log(ctx).Infof(" - resetting %v to default value inherited from parent", desc)
+
+		*val = ""
+
+		return
+	}
+
+	log(ctx).Infof(" - setting %v to %v", desc, str)
+
+	*val = str
+}
@@ -0,0 +1,34 @@
+package cli_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestNewRepositorySplitterPolicy(t *testing.T) {
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	// global policy of a new repository holds splitter lists for disk images and archives.
+	lines := e.RunAndExpectSuccess(t, "policy", "show", "--global")
+	lines = compressSpaces(lines)
+	require.Contains(t, lines, " Block-aligned files: FIXED-1M (defined for this target)")
+	require.Contains(t, lines, " .vmdk (defined for this target)")
+	require.Contains(t, lines, " Archives split at member boundaries: ZIP-4M-BUZHASH (defined for this target)")
+	require.Contains(t, lines, " .docx (defined for this target)")
+
+	td := testutil.TempDirectory(t)
+
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--remove-block-aligned=.vmdk")
+
+	lines = e.RunAndExpectSuccess(t, "policy", "show", td)
+	lines = compressSpaces(lines)
+	require.Contains(t, lines, " .qcow2 inherited from (global)")
+	require.NotContains(t, lines, " .vmdk inherited from (global)")
+}
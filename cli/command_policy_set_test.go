@@ -440,3 +440,61 @@ func TestSetSchedulingPolicyFromFlags(t *testing.T) {
 func newOptionalBool(b policy.OptionalBool) *policy.OptionalBool {
 	return &b
 }
+
+func TestSetSplitterPolicyFromFlags(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	psf := policySplitterFlags{
+		policySetSplitterAlgorithm:             "DYNAMIC-4M-FASTCDC",
+		policySetBlockAlignedSplitterAlgorithm: "FIXED-4M",
+		policySetAddBlockAligned:               []string{".bin", ".iso"},
+		policySetRemoveArchiveMembers:          []string{".jar"},
+	}
+
+	p := &policy.SplitterPolicy{
+		ArchiveMembersAlgorithm: "ZIP-1M-BUZHASH",
+		ArchiveMembers:          []string{".jar", ".zip"},
+	}
+
+	changeCount := 0
+
+	require.NoError(t, psf.setSplitterPolicyFromFlags(ctx, p, &changeCount))
+	require.Equal(t, 5, changeCount)
+	require.Equal(t, &policy.SplitterPolicy{
+		Algorithm:               "DYNAMIC-4M-FASTCDC",
+		BlockAlignedAlgorithm:   "FIXED-4M",
+		BlockAligned:            []string{".bin", ".iso"},
+		ArchiveMembersAlgorithm: "ZIP-1M-BUZHASH",
+		ArchiveMembers:          []string{".zip"},
+	}, p)
+
+	psf = policySplitterFlags{
+		policySetSplitterAlgorithm:   inheritPolicyString,
+		policySetClearArchiveMembers: true,
+	}
+
+	changeCount = 0
+
+	require.NoError(t, psf.setSplitterPolicyFromFlags(ctx, p, &changeCount))
+	require.Equal(t, 2, changeCount)
+	require.Equal(t, "", p.Algorithm)
+	require.Empty(t, p.ArchiveMembers)
+
+	psf = policySplitterFlags{
+		policySetDisableBlockAligned: true,
+	}
+
+	changeCount = 0
+
+	require.NoError(t, psf.setSplitterPolicyFromFlags(ctx, p, &changeCount))
+	require.Equal(t, 1, changeCount)
+	require.Empty(t, p.BlockAligned)
+	require.True(t, p.NoParentBlockAligned)
+
+	psf = policySplitterFlags{
+		policySetClearBlockAligned: true,
+	}
+
+	require.NoError(t, psf.setSplitterPolicyFromFlags(ctx, p, &changeCount))
+	require.False(t, p.NoParentBlockAligned)
+}
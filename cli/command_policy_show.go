@@ -126,6 +126,8 @@ func printPolicy(out *textOutput, p *policy.Policy, def *policy.Definition) {
 	rows = append(rows, policyTableRow{})
 	rows = appendCompressionPolicyRows(rows, p, def)
 	rows = append(rows, policyTableRow{})
+	rows = appendSplitterPolicyRows(rows, p, def)
+	rows = append(rows, policyTableRow{})
 	rows = appendActionsPolicyRows(rows, p, def)
 	rows = append(rows, policyTableRow{})
 	rows = appendLoggingPolicyRows(rows, p, def)
@@ -371,9 +373,62 @@ func appendCompressionPolicyRows(rows []policyTableRow, p *policy.Policy, def *p
 		rows = append(rows, policyTableRow{"  Compress files of all sizes.", "", ""})
 	}
 
+	if p.CompressionPolicy.UseDictionary.OrDefault(false) {
+		rows = append(rows, policyTableRow{
+			"  Compress small files using dictionary:", "true",
+			definitionPointToString(p.Target(), def.CompressionPolicy.UseDictionary),
+		})
+	}
+
+	return rows
+}
+
+func appendSplitterPolicyRows(rows []policyTableRow, p *policy.Policy, def *policy.Definition) []policyTableRow {
+	rows = append(rows,
+		policyTableRow{"Splitter:", "", ""},
+		policyTableRow{"  Algorithm:", valueOrRepositoryDefault(p.SplitterPolicy.Algorithm), definitionPointToString(p.Target(), def.SplitterPolicy.Algorithm)})
+
+	if len(p.SplitterPolicy.BlockAligned) > 0 && p.SplitterPolicy.BlockAlignedAlgorithm != "" {
+		rows = append(rows, policyTableRow{
+			"  Block-aligned files:", p.SplitterPolicy.BlockAlignedAlgorithm,
+			definitionPointToString(p.Target(), def.SplitterPolicy.BlockAlignedAlgorithm),
+		})
+
+		for _, rule := range p.SplitterPolicy.BlockAligned {
+			rows = append(rows, policyTableRow{"    " + rule, "", definitionPointToString(p.Target(), def.SplitterPolicy.BlockAligned)})
+		}
+	}
+
+	if p.SplitterPolicy.NoParentBlockAligned && len(p.SplitterPolicy.BlockAligned) == 0 {
+		rows = append(rows, policyTableRow{"  Block-aligned files:", "(disabled)", definitionPointToString(p.Target(), def.SplitterPolicy.BlockAligned)})
+	}
+
+	if len(p.SplitterPolicy.ArchiveMembers) > 0 && p.SplitterPolicy.ArchiveMembersAlgorithm != "" {
+		rows = append(rows, policyTableRow{
+			"  Archives split at member boundaries:", p.SplitterPolicy.ArchiveMembersAlgorithm,
+			definitionPointToString(p.Target(), def.SplitterPolicy.ArchiveMembersAlgorithm),
+		})
+
+		for _, rule := range p.SplitterPolicy.ArchiveMembers {
+			rows = append(rows, policyTableRow{"    " + rule, "", definitionPointToString(p.Target(), def.SplitterPolicy.ArchiveMembers)})
+		}
+	}
+
+	if p.SplitterPolicy.NoParentArchiveMembers && len(p.SplitterPolicy.ArchiveMembers) == 0 {
+		rows = append(rows, policyTableRow{"  Archives split at member boundaries:", "(disabled)", definitionPointToString(p.Target(), def.SplitterPolicy.ArchiveMembers)})
+	}
+
 	return rows
 }
 
+func valueOrRepositoryDefault(s string) string {
+	if s == "" {
+		return "(repository default)"
+	}
+
+	return s
+}
+
 func appendActionsPolicyRows(rows []policyTableRow, p *policy.Policy, def *policy.Definition) []policyTableRow {
 	var anyActions bool
 
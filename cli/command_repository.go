@@ -4,10 +4,13 @@ type commandRepository struct {
 	connect          commandRepositoryConnect
 	create           commandRepositoryCreate
 	disconnect       commandRepositoryDisconnect
+	recover          commandRepositoryRecover
 	repair           commandRepositoryRepair
+	scrub            commandRepositoryScrub
 	setClient        commandRepositorySetClient
 	setParameters    commandRepositorySetParameters
 	changePassword   commandRepositoryChangePassword
+	stats            commandRepositoryStats
 	status           commandRepositoryStatus
 	syncTo           commandRepositorySyncTo
 	throttle         commandRepositoryThrottle
@@ -21,9 +24,12 @@ func (c *commandRepository) setup(svc advancedAppServices, parent commandParent)
 	c.connect.setup(svc, cmd)
 	c.create.setup(svc, cmd)
 	c.disconnect.setup(svc, cmd)
+	c.recover.setup(svc, cmd)
 	c.repair.setup(svc, cmd)
+	c.scrub.setup(svc, cmd)
 	c.setClient.setup(svc, cmd)
 	c.setParameters.setup(svc, cmd)
+	c.stats.setup(svc, cmd)
 	c.status.setup(svc, cmd)
 	c.syncTo.setup(svc, cmd)
 	c.throttle.setup(svc, cmd)
@@ -57,6 +57,7 @@ type connectOptions struct {
 	connectPermissiveCacheLoading bool
 	connectDescription            string
 	connectEnableActions          bool
+	connectEnableCommandNotify    bool
 
 	formatBlobCacheDuration time.Duration
 	disableFormatBlobCache  bool
@@ -76,6 +77,7 @@ func (c *connectOptions) setup(svc appServices, cmd *kingpin.CmdClause) {
 	cmd.Flag("permissive-cache-loading", "Do not fail when loading bad cache index entries.  Repository must be opened in read-only mode").Hidden().BoolVar(&c.connectPermissiveCacheLoading)
 	cmd.Flag("description", "Human-readable description of the repository").StringVar(&c.connectDescription)
 	cmd.Flag("enable-actions", "Allow snapshot actions").BoolVar(&c.connectEnableActions)
+	cmd.Flag("enable-command-notifications", "Allow notification profiles to run commands").BoolVar(&c.connectEnableCommandNotify)
 	cmd.Flag("repository-format-cache-duration", "Duration of kopia.repository format blob cache").Hidden().DurationVar(&c.formatBlobCacheDuration)
 	cmd.Flag("disable-repository-format-cache", "Disable caching of kopia.repository format blob").Hidden().BoolVar(&c.disableFormatBlobCache)
 }
@@ -97,13 +99,14 @@ func (c *connectOptions) toRepoConnectOptions() *repo.ConnectOptions {
 			MaxListCacheDuration:      content.DurationSeconds(c.connectMaxListCacheDuration.Seconds()),
 		},
 		ClientOptions: repo.ClientOptions{
-			Hostname:                c.connectHostname,
-			Username:                c.connectUsername,
-			ReadOnly:                c.connectReadonly,
-			PermissiveCacheLoading:  c.connectPermissiveCacheLoading,
-			Description:             c.connectDescription,
-			EnableActions:           c.connectEnableActions,
-			FormatBlobCacheDuration: c.getFormatBlobCacheDuration(),
+			Hostname:                   c.connectHostname,
+			Username:                   c.connectUsername,
+			ReadOnly:                   c.connectReadonly,
+			PermissiveCacheLoading:     c.connectPermissiveCacheLoading,
+			Description:                c.connectDescription,
+			EnableActions:              c.connectEnableActions,
+			EnableCommandNotifications: c.connectEnableCommandNotify,
+			FormatBlobCacheDuration:    c.getFormatBlobCacheDuration(),
 		},
 	}
 }
@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -16,6 +17,8 @@ type commandRepositoryConnectServer struct {
 	connectAPIServerURL             string
 	connectAPIServerCertFingerprint string
 	connectAPIServerUseGRPCAPI      bool
+	connectAPIServerClientCertFile  string
+	connectAPIServerClientKeyFile   string
 
 	svc advancedAppServices
 	out textOutput
@@ -30,6 +33,8 @@ func (c *commandRepositoryConnectServer) setup(svc advancedAppServices, parent c
 	cmd.Flag("url", "Server URL").Required().StringVar(&c.connectAPIServerURL)
 	cmd.Flag("server-cert-fingerprint", "Server certificate fingerprint").StringVar(&c.connectAPIServerCertFingerprint)
 	cmd.Flag("grpc", "Use GRPC API").Default("true").BoolVar(&c.connectAPIServerUseGRPCAPI)
+	cmd.Flag("client-cert-file", "PEM file with client certificate presented to the server").ExistingFileVar(&c.connectAPIServerClientCertFile)
+	cmd.Flag("client-key-file", "PEM file with private key of the client certificate").ExistingFileVar(&c.connectAPIServerClientKeyFile)
 	cmd.Action(svc.noRepositoryAction(c.run))
 }
 
@@ -40,6 +45,23 @@ func (c *commandRepositoryConnectServer) run(ctx context.Context) error {
 		DisableGRPC:                         !c.connectAPIServerUseGRPCAPI,
 	}
 
+	if (c.connectAPIServerClientCertFile == "") != (c.connectAPIServerClientKeyFile == "") {
+		return errors.Errorf("--client-cert-file and --client-key-file must be specified together")
+	}
+
+	if c.connectAPIServerClientCertFile != "" {
+		var err error
+
+		// the files are read whenever the repository is opened, so they can be renewed in place.
+		if as.ClientCertificateFile, err = filepath.Abs(c.connectAPIServerClientCertFile); err != nil {
+			return errors.Wrap(err, "unable to resolve client certificate path")
+		}
+
+		if as.ClientKeyFile, err = filepath.Abs(c.connectAPIServerClientKeyFile); err != nil {
+			return errors.Wrap(err, "unable to resolve client key path")
+		}
+	}
+
 	configFile := c.svc.repositoryConfigFileName()
 	opt := c.co.toRepoConnectOptions()
 
@@ -55,9 +77,16 @@ func (c *commandRepositoryConnectServer) run(ctx context.Context) error {
 
 	log(ctx).Infof("Connecting to server '%v' as '%v@%v'...", as.BaseURL, u, h)
 
-	pass, err := c.svc.getPasswordFromFlags(ctx, false, false)
-	if err != nil {
-		return errors.Wrap(err, "getting password")
+	var pass string
+
+	// users authenticated by client certificates don't need a password.
+	if as.ClientCertificateFile == "" || c.svc.hasPasswordFromFlags() {
+		var err error
+
+		pass, err = c.svc.getPasswordFromFlags(ctx, false, false)
+		if err != nil {
+			return errors.Wrap(err, "getting password")
+		}
 	}
 
 	if err := passwordpersist.OnSuccess(
@@ -170,14 +170,14 @@ func (c *commandRepositoryCreate) populateRepository(ctx context.Context, passwo
 	return repo.WriteSession(ctx, rep, repo.WriteSessionOptions{
 		Purpose: "populate repository",
 	}, func(ctx context.Context, w repo.RepositoryWriter) error {
-		if err := policy.SetPolicy(ctx, w, policy.GlobalPolicySourceInfo, policy.DefaultPolicy); err != nil {
+		if err := policy.SetPolicy(ctx, w, policy.GlobalPolicySourceInfo, policy.NewRepositoryGlobalPolicy); err != nil {
 			return errors.Wrap(err, "unable to set global policy")
 		}
 
 		var rows []policyTableRow
 
-		rows = appendRetentionPolicyRows(rows, policy.DefaultPolicy, &policy.Definition{})
-		rows = appendCompressionPolicyRows(rows, policy.DefaultPolicy, &policy.Definition{})
+		rows = appendRetentionPolicyRows(rows, policy.NewRepositoryGlobalPolicy, &policy.Definition{})
+		rows = appendCompressionPolicyRows(rows, policy.NewRepositoryGlobalPolicy, &policy.Definition{})
 
 		c.out.printStdout("%v\n", alignedPolicyTableRows(rows))
 
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotrecovery"
+)
+
+type commandRepositoryRecover struct {
+	recoverIndexes bool
+	parallel       int
+	host           string
+	username       string
+	path           string
+	commit         bool
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandRepositoryRecover) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("recover", "Recover indexes from pack blobs and create snapshots of directories which are not reachable from any snapshot.")
+	cmd.Flag("recover-indexes", "Rebuild indexes from all pack blobs").Default("true").BoolVar(&c.recoverIndexes)
+	cmd.Flag("parallel", "Recover parallelism").Default("8").IntVar(&c.parallel)
+	cmd.Flag("host", "Host name of recovered snapshots").Default("recovered").StringVar(&c.host)
+	cmd.Flag("username", "User name of recovered snapshots (defaults to current user)").StringVar(&c.username)
+	cmd.Flag("path", "Path of recovered snapshots").Default("/recovered").StringVar(&c.path)
+	cmd.Flag("commit", "Commit recovered indexes and snapshots").BoolVar(&c.commit)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.directRepositoryWriteAction(c.run))
+}
+
+func (c *commandRepositoryRecover) run(ctx context.Context, rep repo.DirectRepositoryWriter) error {
+	username := c.username
+	if username == "" {
+		username = rep.ClientOptions().Username
+	}
+
+	st, err := snapshotrecovery.Run(ctx, rep, snapshotrecovery.Options{
+		RecoverIndexes: c.recoverIndexes,
+		Parallel:       c.parallel,
+		Source: snapshot.SourceInfo{
+			Host:     c.host,
+			UserName: username,
+			Path:     c.path,
+		},
+		Commit: c.commit,
+	})
+	if err != nil {
+		return errors.Wrap(err, "recovery failed")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(st))
+		return nil
+	}
+
+	for _, m := range st.Snapshots {
+		c.out.printStdout("%v %v %v files %v\n",
+			formatTimestamp(m.StartTime.ToTime()),
+			m.RootObjectID(),
+			m.Stats.TotalFileCount,
+			units.BytesString(m.Stats.TotalFileSize))
+	}
+
+	switch {
+	case len(st.Snapshots) == 0:
+		log(ctx).Infof("No unreachable directories found.")
+	case c.commit:
+		log(ctx).Infof("Recovered %v snapshots of %v.", len(st.Snapshots), st.Snapshots[0].Source)
+	default:
+		log(ctx).Infof("Found %v directories to recover as snapshots, but not committed. Re-run with --commit", len(st.Snapshots))
+	}
+
+	return nil
+}
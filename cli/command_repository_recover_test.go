@@ -0,0 +1,55 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotrecovery"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestRepositoryRecover(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer env.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.txt"), []byte("hello"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "subdir", "file2.txt"), []byte("world"), 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	var man snapshot.Manifest
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "create", dir, "--json"), &man)
+	env.RunAndExpectSuccess(t, "snapshot", "delete", string(man.ID), "--delete")
+	env.RunAndVerifyOutputLineCount(t, 0, "snapshot", "list", "--all")
+
+	var st snapshotrecovery.Stats
+
+	// without --commit nothing is written.
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "recover", "--json"), &st)
+	require.Len(t, st.Snapshots, 1)
+	require.Equal(t, man.RootObjectID(), st.Snapshots[0].RootObjectID())
+	env.RunAndVerifyOutputLineCount(t, 0, "snapshot", "list", "--all")
+
+	env.RunAndExpectSuccess(t, "repo", "recover", "--commit", "--host", "myhost", "--path", "/lost")
+
+	var snaps []cli.SnapshotManifest
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "list", "--all", "--json"), &snaps)
+	require.Len(t, snaps, 1)
+	require.Equal(t, man.RootObjectID(), snaps[0].RootObjectID())
+	require.Equal(t, "myhost", snaps[0].Source.Host)
+	require.Equal(t, "/lost", snaps[0].Source.Path)
+
+	env.RunAndExpectSuccess(t, "snapshot", "restore", string(snaps[0].ID), testutil.TempDirectory(t))
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/maintenance"
+)
+
+type commandRepositoryScrub struct {
+	mirrorConfigFile string
+	maxSizeMB        int64
+	maxRuntime       time.Duration
+	dryRun           bool
+	force            bool
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandRepositoryScrub) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("scrub", "Verify all pack blobs and repair damaged contents, continuing where the previous scrub left off.")
+	cmd.Flag("mirror-config", "Path to the configuration file of a repository mirror used to restore missing or damaged pack blobs").StringVar(&c.mirrorConfigFile)
+	cmd.Flag("max-size-mb", "Maximum size of pack blobs scrubbed in a single run (0 means unlimited)").PlaceHolder("MB").Int64Var(&c.maxSizeMB)
+	cmd.Flag("max-runtime", "Maximum duration of a single run (0 means unlimited)").DurationVar(&c.maxRuntime)
+	cmd.Flag("dry-run", "Only report problems without repairing them").BoolVar(&c.dryRun)
+	cmd.Flag("force", "Scrub even if maintenance is not owned (unsafe)").Hidden().BoolVar(&c.force)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.directRepositoryWriteAction(c.run))
+}
+
+func (c *commandRepositoryScrub) run(ctx context.Context, rep repo.DirectRepositoryWriter) error {
+	opt := &maintenance.ScrubOptions{
+		MaxBytes: c.maxSizeMB << 20, //nolint:gomnd
+		DryRun:   c.dryRun,
+		Force:    c.force,
+	}
+
+	if c.maxRuntime > 0 {
+		opt.Deadline = rep.Time().Add(c.maxRuntime)
+	}
+
+	if c.mirrorConfigFile != "" {
+		mirror, err := c.connectToMirror(ctx)
+		if err != nil {
+			return err
+		}
+
+		defer mirror.Close(ctx) //nolint:errcheck
+
+		opt.Mirror = mirror
+	}
+
+	st, err := maintenance.Scrub(ctx, rep, opt)
+
+	if st != nil {
+		if c.jo.jsonOutput {
+			c.out.printStdout("%s\n", c.jo.jsonBytes(st))
+		} else {
+			c.out.printStdout("Scrubbed %v pack blobs (%v) containing %v contents.\n", st.ScrubbedBlobs, units.BytesString(st.ScrubbedBytes), st.VerifiedContents)
+			c.out.printStdout("Corrected contents: %v, restored pack blobs: %v, missing pack blobs: %v, invalid contents: %v\n",
+				st.CorrectedContents, st.RestoredBlobs, st.MissingBlobs, st.InvalidContents)
+		}
+	}
+
+	return errors.Wrap(err, "scrub failed")
+}
+
+func (c *commandRepositoryScrub) connectToMirror(ctx context.Context) (blob.Storage, error) {
+	cfg, err := repo.LoadConfigFromFile(c.mirrorConfigFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open mirror config")
+	}
+
+	if cfg.Storage == nil {
+		return nil, errors.Errorf("mirror config does not specify blob storage connection parameters")
+	}
+
+	st, err := blob.NewStorage(ctx, *cfg.Storage, false)
+
+	return st, errors.Wrap(err, "unable to connect to mirror storage")
+}
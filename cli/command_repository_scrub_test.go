@@ -0,0 +1,66 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/filesystem"
+	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestRepositoryScrub(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer env.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.txt"), bytes.Repeat([]byte{1, 2, 3, 4, 5}, 15000), 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir)
+
+	var st maintenance.ScrubStats
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "scrub", "--json"), &st)
+	require.Positive(t, st.ScrubbedBlobs)
+	require.True(t, st.CycleCompleted)
+
+	// create a mirror of the repository and its configuration file.
+	mirrorDir := testutil.TempDirectory(t)
+	env.RunAndExpectSuccess(t, "repo", "sync-to", "filesystem", "--path", mirrorDir)
+
+	mirrorConfig := filepath.Join(testutil.TempDirectory(t), "mirror.config")
+	cfg, err := json.Marshal(repo.LocalConfig{
+		Storage: &blob.ConnectionInfo{
+			Type:   "filesystem",
+			Config: &filesystem.Options{Path: mirrorDir},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(mirrorConfig, cfg, 0o600))
+
+	blobIDToDelete := strings.Split(env.RunAndExpectSuccess(t, "blob", "list", "--prefix=p")[0], " ")[0]
+	env.RunAndExpectSuccess(t, "blob", "delete", blobIDToDelete)
+
+	env.RunAndExpectFailure(t, "repo", "scrub")
+	env.RunAndExpectSuccess(t, "repo", "scrub", "--mirror-config", mirrorConfig, "--dry-run")
+	env.RunAndExpectFailure(t, "repo", "scrub")
+
+	st = maintenance.ScrubStats{}
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "scrub", "--mirror-config", mirrorConfig, "--json"), &st)
+	require.Equal(t, 1, st.RestoredBlobs)
+
+	env.RunAndExpectSuccess(t, "content", "verify", "--full")
+	env.RunAndExpectSuccess(t, "maintenance", "info")
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/snapshot/snapshotmaintenance"
+)
+
+type commandRepositoryStats struct {
+	history bool
+	raw     bool
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandRepositoryStats) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("stats", "Show repository statistics.")
+	cmd.Flag("history", "Show history of statistics recorded by full maintenance instead of computing current statistics").BoolVar(&c.history)
+	cmd.Flag("raw", "Raw numbers").Short('r').BoolVar(&c.raw)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.directRepositoryReadAction(c.run))
+}
+
+func (c *commandRepositoryStats) run(ctx context.Context, rep repo.DirectRepository) error {
+	if c.history {
+		return c.showHistory(ctx, rep)
+	}
+
+	st, err := maintenance.CollectStats(ctx, rep, snapshotmaintenance.SnapshotStats)
+	if err != nil {
+		return errors.Wrap(err, "error collecting statistics")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(st))
+		return nil
+	}
+
+	sizeToString := c.sizeToString()
+
+	c.out.printStdout("Blobs:\n")
+
+	var prefixes []blob.ID
+	for p := range st.Blobs {
+		prefixes = append(prefixes, p)
+	}
+
+	sort.Slice(prefixes, func(i, j int) bool { return prefixes[i] < prefixes[j] })
+
+	for _, p := range prefixes {
+		c.out.printStdout("  %-10v count: %v size: %v\n", p, st.Blobs[p].Count, sizeToString(st.Blobs[p].Bytes))
+	}
+
+	c.out.printStdout("  %-10v size: %v\n", "(total)", sizeToString(st.TotalBlobBytes()))
+	c.out.printStdout("Contents:          %v\n", st.ContentCount)
+	c.out.printStdout("Logical Size:      %v\n", sizeToString(st.LogicalBytes))
+	c.out.printStdout("Physical Size:     %v\n", sizeToString(st.PhysicalBytes))
+	c.out.printStdout("Compression Saved: %v\n", sizeToString(st.CompressionSavedBytes))
+	c.out.printStdout("Snapshots:         %v (total size %v)\n", st.Snapshots, sizeToString(st.SnapshotBytes))
+	c.out.printStdout("Dedupe Ratio:      %.2f\n", st.DedupeRatio)
+
+	if len(st.Sources) == 0 {
+		return nil
+	}
+
+	c.out.printStdout("Sources:\n")
+
+	var sources []string
+	for s := range st.Sources {
+		sources = append(sources, s)
+	}
+
+	sort.Strings(sources)
+
+	for _, s := range sources {
+		ss := st.Sources[s]
+		c.out.printStdout("  %v\n    snapshots: %v unique: %v shared: %v\n", s, ss.Snapshots, sizeToString(ss.UniqueBytes), sizeToString(ss.SharedBytes))
+	}
+
+	return nil
+}
+
+func (c *commandRepositoryStats) showHistory(ctx context.Context, rep repo.DirectRepository) error {
+	history, err := maintenance.StatsHistory(ctx, rep)
+	if err != nil {
+		return errors.Wrap(err, "unable to get statistics history")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(history))
+		return nil
+	}
+
+	if len(history) == 0 {
+		log(ctx).Infof("No statistics have been recorded yet, they are recorded during full maintenance.")
+		return nil
+	}
+
+	sizeToString := c.sizeToString()
+
+	c.out.printStdout("%-24v %12v %12v %12v %12v %8v %9v\n", "TIME", "BLOBS", "PHYSICAL", "LOGICAL", "SNAPSHOTS", "DEDUPE", "SOURCES")
+
+	for _, st := range history {
+		c.out.printStdout("%-24v %12v %12v %12v %12v %8.2f %9v\n",
+			formatTimestamp(st.Time),
+			sizeToString(st.TotalBlobBytes()),
+			sizeToString(st.PhysicalBytes),
+			sizeToString(st.LogicalBytes),
+			sizeToString(st.SnapshotBytes),
+			st.DedupeRatio,
+			len(st.Sources))
+	}
+
+	return nil
+}
+
+func (c *commandRepositoryStats) sizeToString() func(int64) string {
+	if c.raw {
+		return func(l int64) string {
+			return strconv.FormatInt(l, 10)
+		}
+	}
+
+	return units.BytesString
+}
@@ -0,0 +1,50 @@
+package cli_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestRepositoryStats(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer env.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "snapshot", "create", testutil.TempDirectory(t))
+	env.RunAndExpectSuccess(t, "repo", "stats")
+
+	var st maintenance.RepositoryStats
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "stats", "--json"), &st)
+	require.Equal(t, 1, st.Snapshots)
+	require.Len(t, st.Sources, 1)
+	require.Positive(t, st.ContentCount)
+
+	env.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none")
+	env.RunAndExpectSuccess(t, "repo", "stats", "--history")
+
+	var history []*maintenance.RepositoryStats
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "stats", "--history", "--json"), &history)
+	require.Len(t, history, 1)
+	require.Equal(t, 1, history[0].Snapshots)
+
+	// storage usage of sources is only recorded when its task is scheduled.
+	require.Empty(t, history[0].Sources)
+
+	env.RunAndExpectSuccess(t, "maintenance", "set", "--task", "source-storage-usage", "--task-interval", "24h")
+	env.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none")
+
+	history = nil
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "stats", "--history", "--json"), &history)
+	require.NotEmpty(t, history)
+	require.Len(t, history[len(history)-1].Sources, 1)
+}
@@ -9,6 +9,9 @@
 
 type commandServer struct {
 	acl      commandServerACL
+	audit    commandServerAudit
+	group    commandServerGroup
+	token    commandServerToken
 	user     commandServerUser
 	cancel   commandServerCancel
 	flush    commandServerFlush
@@ -38,6 +41,7 @@ type serverClientFlags struct {
 	serverAddress         string
 	serverUsername        string
 	serverPassword        string
+	serverAPIToken        string
 	serverCertFingerprint string
 }
 
@@ -47,6 +51,7 @@ func (c *serverClientFlags) setup(svc appServices, cmd *kingpin.CmdClause) {
 	cmd.Flag("address", "Address of the server to connect to").Envar(svc.EnvName("KOPIA_SERVER_ADDRESS")).Default("http://127.0.0.1:51515").StringVar(&c.serverAddress)
 	cmd.Flag("server-control-username", "Server control username").Envar(svc.EnvName("KOPIA_SERVER_USERNAME")).StringVar(&c.serverUsername)
 	cmd.Flag("server-control-password", "Server control password").PlaceHolder("PASSWORD").Envar(svc.EnvName("KOPIA_SERVER_PASSWORD")).StringVar(&c.serverPassword)
+	cmd.Flag("server-api-token", "API token used instead of server control username and password").PlaceHolder("TOKEN").Envar(svc.EnvName("KOPIA_SERVER_API_TOKEN")).StringVar(&c.serverAPIToken)
 
 	// aliases for backwards compat
 	cmd.Flag("server-username", "Server control username").Hidden().StringVar(&c.serverUsername)
@@ -60,6 +65,9 @@ func (c *commandServer) setup(svc advancedAppServices, parent commandParent) {
 
 	c.start.setup(svc, cmd)
 	c.acl.setup(svc, cmd)
+	c.audit.setup(svc, cmd)
+	c.group.setup(svc, cmd)
+	c.token.setup(svc, cmd)
 	c.user.setup(svc, cmd)
 
 	c.status.setup(svc, cmd)
@@ -83,6 +91,7 @@ func (c *serverClientFlags) serverAPIClientOptions() (apiclient.Options, error)
 		BaseURL:                             c.serverAddress,
 		Username:                            c.serverUsername,
 		Password:                            c.serverPassword,
+		BearerToken:                         c.serverAPIToken,
 		TrustedServerCertificateFingerprint: c.serverCertFingerprint,
 	}, nil
 }
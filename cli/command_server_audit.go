@@ -0,0 +1,10 @@
+package cli
+
+type commandServerAudit struct {
+	list commandServerAuditList
+}
+
+func (c *commandServerAudit) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("audit", "Inspect audit log of a running server")
+	c.list.setup(svc, cmd)
+}
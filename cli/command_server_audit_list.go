@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/auditlog"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/serverapi"
+)
+
+type commandServerAuditList struct {
+	sf serverClientFlags
+
+	user   string
+	action string
+	since  time.Duration
+	limit  int
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandServerAuditList) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("list", "List audit log entries").Alias("ls")
+	cmd.Flag("user", "Only show entries for the provided user or user@host").StringVar(&c.user)
+	cmd.Flag("action", "Only show entries for the provided action").StringVar(&c.action)
+	cmd.Flag("since", "Only show entries newer than the provided duration").DurationVar(&c.since)
+	cmd.Flag("limit", "Maximum number of most recent entries to show (0 = unlimited)").Default("100").IntVar(&c.limit)
+
+	c.sf.setup(svc, cmd)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+
+	cmd.Action(svc.serverAction(&c.sf, c.run))
+}
+
+func (c *commandServerAuditList) run(ctx context.Context, cli *apiclient.KopiaAPIClient) error {
+	q := url.Values{}
+
+	if c.user != "" {
+		q.Set("user", c.user)
+	}
+
+	if c.action != "" {
+		q.Set("action", c.action)
+	}
+
+	if c.since > 0 {
+		q.Set("since", clock.Now().Add(-c.since).UTC().Format(time.RFC3339))
+	}
+
+	q.Set("limit", strconv.Itoa(c.limit))
+
+	resp, err := serverapi.ListAuditLog(ctx, cli, q)
+	if err != nil {
+		return errors.Wrap(err, "unable to list audit log")
+	}
+
+	var jl jsonList
+
+	jl.begin(&c.jo)
+	defer jl.end()
+
+	for _, e := range resp.Entries {
+		if c.jo.jsonOutput {
+			jl.emit(e)
+			continue
+		}
+
+		user := e.UsernameAtHost()
+		if user == "" {
+			user = "-"
+		}
+
+		line := strings.Join([]string{
+			formatTimestamp(e.Time),
+			user,
+			e.Action,
+			string(e.Result),
+			formatAuditTarget(e),
+		}, " ")
+
+		if e.Error != "" {
+			line += " error: " + e.Error
+		}
+
+		c.out.printStdout("%v\n", strings.TrimSpace(line))
+	}
+
+	if resp.Malformed > 0 {
+		log(ctx).Warnf("Skipped %v malformed audit log entries.", resp.Malformed)
+	}
+
+	return nil
+}
+
+// formatAuditTarget returns target of the audit log entry as a sorted list of key=value pairs.
+func formatAuditTarget(e *auditlog.Entry) string {
+	var parts []string
+
+	for k, v := range e.Target {
+		parts = append(parts, k+"="+v)
+	}
+
+	sort.Strings(parts)
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,71 @@
+package cli_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/auditlog"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestServerAuditLog(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	auditLogFile := filepath.Join(testutil.TempDirectory(t), "audit.log")
+
+	serverStarted := make(chan struct{})
+	serverStopped := make(chan struct{})
+
+	var sp testutil.ServerParameters
+
+	go func() {
+		wait, _ := env.RunAndProcessStderr(t, sp.ProcessOutput,
+			"server", "start", "--insecure", "--random-server-control-password", "--address=127.0.0.1:0",
+			"--audit-log-file", auditLogFile)
+
+		close(serverStarted)
+
+		wait()
+
+		close(serverStopped)
+	}()
+
+	select {
+	case <-serverStarted:
+		t.Logf("server started on %v", sp.BaseURL)
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("server did not start in time")
+	}
+
+	env.RunAndExpectFailure(t, "server", "status", "--address", sp.BaseURL, "--server-control-password", "wrong-password")
+
+	lines := env.RunAndExpectSuccess(t, "server", "audit", "list", "--address", sp.BaseURL, "--server-control-password", sp.ServerControlPassword,
+		"--action", "login", "--since", "1h")
+	require.NotEmpty(t, lines)
+	require.True(t, strings.HasSuffix(lines[0], " server-control login denied method=password"), lines[0])
+
+	var entries []*auditlog.Entry
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "server", "audit", "list", "--address", sp.BaseURL, "--server-control-password", sp.ServerControlPassword,
+		"--action", "login", "--limit", "1", "--json"), &entries)
+	require.Len(t, entries, 1)
+	require.Equal(t, auditlog.ResultSuccess, entries[0].Result)
+
+	env.RunAndExpectSuccess(t, "server", "shutdown", "--address", sp.BaseURL, "--server-control-password", sp.ServerControlPassword)
+
+	select {
+	case <-serverStopped:
+		t.Logf("server shut down")
+
+	case <-time.After(15 * time.Second):
+		t.Fatalf("server did not shutdown in time")
+	}
+}
@@ -0,0 +1,96 @@
+package cli_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+type aclUserPermissions struct {
+	User    string   `json:"user"`
+	Groups  []string `json:"groups"`
+	Entries []struct {
+		ID     string         `json:"id"`
+		User   string         `json:"user"`
+		Target acl.TargetRule `json:"target"`
+		Access string         `json:"access"`
+		Role   string         `json:"role"`
+	} `json:"entries"`
+	Permissions []struct {
+		Target acl.TargetRule `json:"target"`
+		Access string         `json:"access"`
+	} `json:"permissions"`
+}
+
+func TestServerGroupsAndRoles(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer env.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	env.RunAndExpectSuccess(t, "server", "group", "add", "restorers", "--member", "bob@home")
+	env.RunAndExpectFailure(t, "server", "group", "add", "restorers")
+	env.RunAndExpectFailure(t, "server", "group", "add", "invalid", "--member", "bob")
+	env.RunAndExpectSuccess(t, "server", "group", "member", "add", "restorers", "*@office")
+	env.RunAndExpectFailure(t, "server", "group", "member", "add", "no-such-group", "bob@home")
+	env.RunAndExpectFailure(t, "server", "group", "member", "remove", "restorers", "alice@home")
+
+	var groups []*acl.Group
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "server", "group", "list", "--json"), &groups)
+	require.Len(t, groups, 1)
+	require.Equal(t, []string{"*@office", "bob@home"}, groups[0].Members)
+
+	require.Equal(t, []string{"restorers members:*@office,bob@home"}, env.RunAndExpectSuccess(t, "server", "group", "list"))
+
+	env.RunAndExpectSuccess(t, "server", "acl", "enable")
+	env.RunAndExpectSuccess(t, "server", "acl", "add", "--user", "group:restorers", "--role", "restore-operator")
+	env.RunAndExpectFailure(t, "server", "acl", "add", "--user", "group:restorers", "--role", "auditor", "--access", "READ")
+	env.RunAndExpectFailure(t, "server", "acl", "add", "--user", "group:restorers", "--target", "type=snapshot")
+
+	var perms aclUserPermissions
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "server", "acl", "list", "--user", "alice@office", "--json",
+		"--target", "type=snapshot,username=bob,hostname=home",
+		"--target", "type=snapshot,username=OWN_USER,hostname=OWN_HOST"), &perms)
+
+	require.Equal(t, []string{"restorers"}, perms.Groups)
+	require.Len(t, perms.Permissions, 2)
+	require.Equal(t, "READ", perms.Permissions[0].Access)
+	require.Equal(t, "FULL", perms.Permissions[1].Access)
+	require.Equal(t, "alice", perms.Permissions[1].Target["username"])
+
+	roleEntries := 0
+
+	for _, e := range perms.Entries {
+		if e.Role == acl.RoleRestoreOperator {
+			roleEntries++
+		}
+	}
+
+	require.Equal(t, 3, roleEntries)
+
+	env.RunAndExpectSuccess(t, "server", "group", "member", "remove", "restorers", "*@office")
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "server", "acl", "list", "--user", "alice@office", "--json",
+		"--target", "type=snapshot,username=bob,hostname=home"), &perms)
+	require.Empty(t, perms.Groups)
+	require.Equal(t, "NONE", perms.Permissions[0].Access)
+
+	// externally-provided groups.
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "server", "acl", "list", "--user", "alice@office", "--json", "--group", "restorers",
+		"--target", "type=snapshot,username=bob,hostname=home"), &perms)
+	require.Equal(t, "READ", perms.Permissions[0].Access)
+
+	env.RunAndExpectFailure(t, "server", "acl", "list", "--group", "restorers")
+
+	env.RunAndExpectSuccess(t, "server", "group", "delete", "restorers")
+	env.RunAndExpectFailure(t, "server", "group", "delete", "restorers")
+	env.RunAndExpectSuccess(t, "server", "acl", "roles")
+}
@@ -18,6 +18,7 @@
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	htpasswd "github.com/tg123/go-htpasswd"
 
+	"github.com/kopia/kopia/internal/auditlog"
 	"github.com/kopia/kopia/internal/auth"
 	"github.com/kopia/kopia/internal/ctxutil"
 	"github.com/kopia/kopia/internal/server"
@@ -59,6 +60,9 @@ type commandServerStart struct {
 	serverStartTLSGenerateCertValidDays int
 	serverStartTLSGenerateCertNames     []string
 	serverStartTLSPrintFullServerCert   bool
+	serverStartTLSClientCAFile          string
+	serverStartTLSRequireClientCert     bool
+	serverStartTLSClientCertIdentity    string
 	uiTitlePrefix                       string
 	uiPreferencesFile                   string
 	asyncRepoConnect                    bool
@@ -69,6 +73,20 @@ type commandServerStart struct {
 
 	disableCSRFTokenChecks bool // disable CSRF token checks - used for development/debugging only
 
+	oidcIssuer          string
+	oidcClientID        string
+	oidcClientSecret    string
+	oidcScopes          []string
+	oidcAudiences       []string
+	oidcUsernameClaim   string
+	oidcHostnameClaim   string
+	oidcDefaultHostname string
+	oidcGroupsClaim     string
+	oidcUIGroup         string
+	oidcRedirectURL     string
+
+	auditLogFile string
+
 	sf  serverFlags
 	svc advancedAppServices
 	out textOutput
@@ -106,6 +124,9 @@ func (c *commandServerStart) setup(svc advancedAppServices, parent commandParent
 	cmd.Flag("tls-generate-cert-valid-days", "How long should the TLS certificate be valid").Default("3650").Hidden().IntVar(&c.serverStartTLSGenerateCertValidDays)
 	cmd.Flag("tls-generate-cert-name", "Host names/IP addresses to generate TLS certificate for").Default("127.0.0.1").Hidden().StringsVar(&c.serverStartTLSGenerateCertNames)
 	cmd.Flag("tls-print-server-cert", "Print server certificate").Hidden().BoolVar(&c.serverStartTLSPrintFullServerCert)
+	cmd.Flag("tls-client-ca-file", "PEM file with certificate authorities issuing client certificates which authenticate users").ExistingFileVar(&c.serverStartTLSClientCAFile)
+	cmd.Flag("tls-require-client-cert", "Require all clients to present a certificate").BoolVar(&c.serverStartTLSRequireClientCert)
+	cmd.Flag("tls-client-cert-identity", "Field of client certificate holding username@hostname").Default(string(auth.ClientCertificateIdentityCommonName)).EnumVar(&c.serverStartTLSClientCertIdentity, auth.SupportedClientCertificateIdentitySources...)
 
 	cmd.Flag("async-repo-connect", "Connect to repository asynchronously").Hidden().BoolVar(&c.asyncRepoConnect)
 	cmd.Flag("ui-title-prefix", "UI title prefix").Hidden().Envar(svc.EnvName("KOPIA_UI_TITLE_PREFIX")).StringVar(&c.uiTitlePrefix)
@@ -116,6 +137,20 @@ func (c *commandServerStart) setup(svc advancedAppServices, parent commandParent
 
 	cmd.Flag("shutdown-grace-period", "Grace period for shutting down the server").Default("5s").DurationVar(&c.shutdownGracePeriod)
 
+	cmd.Flag("oidc-issuer", "URL of the OpenID Connect issuer used to authenticate users").Envar(svc.EnvName("KOPIA_OIDC_ISSUER")).StringVar(&c.oidcIssuer)
+	cmd.Flag("oidc-client-id", "OpenID Connect client ID").Envar(svc.EnvName("KOPIA_OIDC_CLIENT_ID")).StringVar(&c.oidcClientID)
+	cmd.Flag("oidc-client-secret", "OpenID Connect client secret").Envar(svc.EnvName("KOPIA_OIDC_CLIENT_SECRET")).StringVar(&c.oidcClientSecret)
+	cmd.Flag("oidc-scope", "OpenID Connect scopes requested when logging in to the UI").StringsVar(&c.oidcScopes)
+	cmd.Flag("oidc-audience", "Additional audiences accepted in bearer tokens").StringsVar(&c.oidcAudiences)
+	cmd.Flag("oidc-username-claim", "Token claim holding the username").Default("sub").StringVar(&c.oidcUsernameClaim)
+	cmd.Flag("oidc-hostname-claim", "Token claim holding the hostname").StringVar(&c.oidcHostnameClaim)
+	cmd.Flag("oidc-default-hostname", "Hostname of users authenticated using OpenID Connect when not specified in the token").Default("oidc").StringVar(&c.oidcDefaultHostname)
+	cmd.Flag("oidc-groups-claim", "Token claim holding the list of groups, which can be referred to in ACLs as 'group:<name>'").Default("groups").StringVar(&c.oidcGroupsClaim)
+	cmd.Flag("oidc-ui-group", "Members of this OpenID Connect group are allowed to access the UI").StringVar(&c.oidcUIGroup)
+	cmd.Flag("oidc-redirect-url", "Externally visible URL of the OpenID Connect callback ending with /api/v1/oidc/callback").StringVar(&c.oidcRedirectURL)
+
+	cmd.Flag("audit-log-file", "Append audit log of security-relevant operations to the provided file").Envar(svc.EnvName("KOPIA_AUDIT_LOG_FILE")).StringVar(&c.auditLogFile)
+
 	c.sf.setup(svc, cmd)
 	c.co.setup(svc, cmd)
 	c.svc = svc
@@ -125,16 +160,43 @@ func (c *commandServerStart) setup(svc advancedAppServices, parent commandParent
 }
 
 func (c *commandServerStart) serverStartOptions(ctx context.Context) (*server.Options, error) {
-	authn, err := c.getAuthenticator(ctx)
+	oidc, err := c.getOIDCAuthenticator(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to initialize OpenID Connect authentication")
+	}
+
+	authn, err := c.getAuthenticator(ctx, oidc)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to initialize authentication")
 	}
 
+	var clientCerts *auth.ClientCertificateAuthenticator
+
+	if c.serverStartTLSClientCAFile != "" {
+		clientCerts, err = auth.NewClientCertificateAuthenticator(auth.ClientCertificateIdentitySource(c.serverStartTLSClientCertIdentity))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to initialize client certificate authentication")
+		}
+
+		log(ctx).Infof("Server will allow users presenting client certificates issued by %v.", c.serverStartTLSClientCAFile)
+	}
+
 	uiPreferencesFile := c.uiPreferencesFile
 	if uiPreferencesFile == "" {
 		uiPreferencesFile = filepath.Join(filepath.Dir(c.svc.repositoryConfigFileName()), "ui-preferences.json")
 	}
 
+	var auditLog *auditlog.Log
+
+	if c.auditLogFile != "" {
+		auditLog, err = auditlog.Open(c.auditLogFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to open audit log")
+		}
+
+		log(ctx).Infof("Server will record audit log in %v.", c.auditLogFile)
+	}
+
 	return &server.Options{
 		ConfigFile:           c.svc.repositoryConfigFileName(),
 		ConnectOptions:       c.co.toRepoConnectOptions(),
@@ -151,6 +213,14 @@ func (c *commandServerStart) serverStartOptions(ctx context.Context) (*server.Op
 		UITitlePrefix:        c.uiTitlePrefix,
 
 		DisableCSRFTokenChecks: c.disableCSRFTokenChecks,
+
+		OIDC:            oidc,
+		OIDCUIGroup:     c.oidcUIGroup,
+		OIDCRedirectURL: c.oidcRedirectURL,
+
+		ClientCertificates: clientCerts,
+
+		AuditLog: auditLog,
 	}, nil
 }
 
@@ -178,6 +248,8 @@ func (c *commandServerStart) run(ctx context.Context) error {
 		return err
 	}
 
+	defer opts.AuditLog.Close() //nolint:errcheck
+
 	srv, err := server.New(ctx, opts)
 	if err != nil {
 		return errors.Wrap(err, "unable to initialize server")
@@ -195,6 +267,9 @@ func (c *commandServerStart) run(ctx context.Context) error {
 		},
 	}
 
+	// event streams are long-lived and would otherwise delay graceful shutdown.
+	httpServer.RegisterOnShutdown(srv.CloseEventStreams)
+
 	srv.OnShutdown = func(ctx context.Context) error {
 		ctx2, cancel := context.WithTimeout(ctx, c.shutdownGracePeriod)
 		defer cancel()
@@ -296,7 +371,26 @@ func stripProtocol(addr string) string {
 	return strings.TrimPrefix(strings.TrimPrefix(addr, "https://"), "http://")
 }
 
-func (c *commandServerStart) getAuthenticator(ctx context.Context) (auth.Authenticator, error) {
+func (c *commandServerStart) getOIDCAuthenticator(ctx context.Context) (*auth.OIDCAuthenticator, error) {
+	if c.oidcIssuer == "" {
+		return nil, nil
+	}
+
+	//nolint:wrapcheck
+	return auth.NewOIDCAuthenticator(ctx, auth.OIDCOptions{
+		Issuer:          c.oidcIssuer,
+		ClientID:        c.oidcClientID,
+		ClientSecret:    c.oidcClientSecret,
+		Scopes:          c.oidcScopes,
+		Audiences:       c.oidcAudiences,
+		UsernameClaim:   c.oidcUsernameClaim,
+		HostnameClaim:   c.oidcHostnameClaim,
+		DefaultHostname: c.oidcDefaultHostname,
+		GroupsClaim:     c.oidcGroupsClaim,
+	})
+}
+
+func (c *commandServerStart) getAuthenticator(ctx context.Context, oidc *auth.OIDCAuthenticator) (auth.Authenticator, error) {
 	var authenticators []auth.Authenticator
 
 	// handle passwords (UI and remote) from htpasswd file.
@@ -357,6 +451,13 @@ func (c *commandServerStart) getAuthenticator(ctx context.Context) (auth.Authent
 User accounts can be added using 'kopia server user add'.
 `)
 
+	// handle tokens issued by OpenID Connect provider
+	if oidc != nil {
+		log(ctx).Infof("Server will allow users authenticated by OpenID Connect issuer %v.", c.oidcIssuer)
+
+		authenticators = append(authenticators, oidc)
+	}
+
 	// handle user accounts stored in the repository
 	authenticators = append(authenticators, auth.AuthenticateRepositoryUsers())
 
@@ -81,11 +81,46 @@ func (c *commandServerStart) maybeGenerateTLS(ctx context.Context) error {
 	return nil
 }
 
+// maybeVerifyClientCertificates configures the server to verify client certificates issued by trusted certificate authorities.
+func (c *commandServerStart) maybeVerifyClientCertificates(httpServer *http.Server) error {
+	if c.serverStartTLSClientCAFile == "" {
+		if c.serverStartTLSRequireClientCert {
+			return errors.Errorf("--tls-require-client-cert requires --tls-client-ca-file")
+		}
+
+		return nil
+	}
+
+	pool, err := tlsutil.LoadCertificatePool(c.serverStartTLSClientCAFile)
+	if err != nil {
+		return errors.Wrap(err, "unable to load client certificate authorities")
+	}
+
+	if httpServer.TLSConfig == nil {
+		httpServer.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	httpServer.TLSConfig.ClientCAs = pool
+	httpServer.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+
+	if c.serverStartTLSRequireClientCert {
+		httpServer.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return nil
+}
+
 func (c *commandServerStart) startServerWithOptionalTLSAndListener(ctx context.Context, httpServer *http.Server, listener net.Listener) error {
 	if err := c.maybeGenerateTLS(ctx); err != nil {
 		return err
 	}
 
+	if err := c.maybeVerifyClientCertificates(httpServer); err != nil {
+		return err
+	}
+
 	switch {
 	case c.serverStartTLSCertFile != "" && c.serverStartTLSKeyFile != "":
 		// PEM files provided
@@ -101,13 +136,15 @@ func (c *commandServerStart) startServerWithOptionalTLSAndListener(ctx context.C
 			return errors.Wrap(err, "unable to generate server cert")
 		}
 
-		httpServer.TLSConfig = &tls.Config{
-			MinVersion: tls.VersionTLS13,
-			Certificates: []tls.Certificate{
-				{
-					Certificate: [][]byte{cert.Raw},
-					PrivateKey:  key,
-				},
+		if httpServer.TLSConfig == nil {
+			httpServer.TLSConfig = &tls.Config{}
+		}
+
+		httpServer.TLSConfig.MinVersion = tls.VersionTLS13
+		httpServer.TLSConfig.Certificates = []tls.Certificate{
+			{
+				Certificate: [][]byte{cert.Raw},
+				PrivateKey:  key,
 			},
 		}
 
@@ -135,6 +172,10 @@ func (c *commandServerStart) startServerWithOptionalTLSAndListener(ctx context.C
 			return errors.Errorf("TLS not configured. To start server without encryption pass --insecure")
 		}
 
+		if c.serverStartTLSClientCAFile != "" {
+			return errors.Errorf("client certificates require TLS")
+		}
+
 		fmt.Fprintf(c.out.stderr(), "SERVER ADDRESS: http://%v\n", httpServer.Addr)
 		c.showServerUIPrompt(ctx)
 
@@ -0,0 +1,57 @@
+package cli_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/apitoken"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestServerTokens(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer env.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	env.RunAndExpectFailure(t, "server", "token", "create")
+	env.RunAndExpectFailure(t, "server", "token", "create", "--scope", "no-such-scope")
+
+	out := env.RunAndExpectSuccess(t, "server", "token", "create", "--scope", "status", "--scope", "trigger-snapshot", "--description", "ci")
+	require.Len(t, out, 1)
+	require.True(t, apitoken.IsAPIToken(out[0]))
+
+	var created struct {
+		apitoken.Token
+		BearerToken string `json:"token"`
+	}
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "server", "token", "create", "--scope", "admin", "--expires-in", "0", "--json"), &created)
+	require.True(t, apitoken.IsAPIToken(created.BearerToken))
+	require.True(t, created.ExpiresTime.IsZero())
+
+	var tokens []*apitoken.Token
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "server", "token", "list", "--json"), &tokens)
+	require.Len(t, tokens, 2)
+	require.Equal(t, "ci", tokens[0].Description)
+	require.Equal(t, []apitoken.Scope{apitoken.ScopeStatus, apitoken.ScopeTriggerSnapshot}, tokens[0].Scopes)
+	require.False(t, tokens[0].ExpiresTime.IsZero())
+
+	lines := env.RunAndExpectSuccess(t, "server", "token", "list")
+	require.Len(t, lines, 2)
+	require.True(t, strings.HasPrefix(lines[0], tokens[0].ID+" scopes:status,trigger-snapshot "), lines[0])
+	require.Contains(t, lines[1], "expires:never last-used:never")
+
+	env.RunAndExpectSuccess(t, "server", "token", "revoke", tokens[0].ID)
+	env.RunAndExpectFailure(t, "server", "token", "revoke", tokens[0].ID)
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "server", "token", "list", "--json"), &tokens)
+	require.Len(t, tokens, 1)
+	require.Equal(t, created.ID, tokens[0].ID)
+}
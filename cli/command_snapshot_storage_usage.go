@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+type commandSnapshotStorageUsage struct {
+	sources          []string
+	includeSnapshots bool
+	raw              bool
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandSnapshotStorageUsage) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("storage-usage", "Show storage used exclusively by snapshots of each source, which would be freed by deleting them, and storage shared with other sources.")
+	cmd.Arg("source", "Only show usage of the provided sources").StringsVar(&c.sources)
+	cmd.Flag("snapshots", "Also show storage used exclusively by individual snapshots (slower)").BoolVar(&c.includeSnapshots)
+	cmd.Flag("raw", "Raw numbers").Short('r').BoolVar(&c.raw)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.repositoryReaderAction(c.run))
+}
+
+func (c *commandSnapshotStorageUsage) run(ctx context.Context, rep repo.Repository) error {
+	filter := map[snapshot.SourceInfo]bool{}
+
+	for _, s := range c.sources {
+		si, err := snapshot.ParseSourceInfo(s, rep.ClientOptions().Hostname, rep.ClientOptions().Username)
+		if err != nil {
+			return errors.Wrapf(err, "invalid source: '%s'", s)
+		}
+
+		filter[si] = true
+	}
+
+	ids, err := snapshot.ListSnapshotManifests(ctx, rep, nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to list snapshot manifests")
+	}
+
+	// usage must always be calculated for all snapshots, since any of them may share contents with the selected ones.
+	manifests, err := snapshot.LoadSnapshots(ctx, rep, ids)
+	if err != nil {
+		return errors.Wrap(err, "unable to load snapshot manifests")
+	}
+
+	usage, err := snapshotfs.CalculateSourceStorageUsage(ctx, rep, manifests, c.includeSnapshots)
+	if err != nil {
+		return errors.Wrap(err, "error calculating storage usage")
+	}
+
+	var result []*snapshotfs.SourceStorageUsage
+
+	for _, u := range usage {
+		if len(filter) == 0 || filter[u.Source] {
+			result = append(result, u)
+		}
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(result))
+		return nil
+	}
+
+	sizeToString := units.BytesString
+	if c.raw {
+		sizeToString = func(l int64) string {
+			return strconv.FormatInt(l, 10)
+		}
+	}
+
+	for _, u := range result {
+		c.out.printStdout("%v\n", u.Source)
+		c.out.printStdout("  %v snapshots, unique: %v (%v contents), shared: %v (%v contents)\n",
+			u.SnapshotCount,
+			sizeToString(u.UniqueBytes), u.UniqueContentCount,
+			sizeToString(u.SharedBytes), u.SharedContentCount)
+
+		for _, su := range u.Snapshots {
+			c.out.printStdout("  %v %v unique: %v (%v contents), shared: %v (%v contents)\n",
+				formatTimestamp(su.StartTime.ToTime()), su.ID,
+				sizeToString(su.UniqueBytes), su.UniqueContentCount,
+				sizeToString(su.SharedBytes), su.SharedContentCount)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestSnapshotStorageUsage(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer env.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	dir1 := testutil.TempDirectory(t)
+	dir2 := testutil.TempDirectory(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir1, "shared.txt"), []byte{1, 2, 3, 4, 5}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir2, "shared.txt"), []byte{1, 2, 3, 4, 5}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir2, "unique.txt"), []byte{6, 7, 8}, 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir1)
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir2)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir2, "unique2.txt"), []byte{9, 10, 11}, 0o600))
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir2)
+
+	env.RunAndExpectSuccess(t, "snapshot", "storage-usage")
+	env.RunAndExpectSuccess(t, "snapshot", "storage-usage", "--snapshots")
+
+	var usage []*snapshotfs.SourceStorageUsage
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "storage-usage", dir2, "--snapshots", "--json"), &usage)
+	require.Len(t, usage, 1)
+
+	u := usage[0]
+
+	require.Equal(t, 2, u.SnapshotCount)
+	// 2 root directories, unique.txt and unique2.txt
+	require.Equal(t, int64(4), u.UniqueContentCount)
+	// shared.txt
+	require.Equal(t, int64(1), u.SharedContentCount)
+
+	require.Len(t, u.Snapshots, 2)
+	// root directory, unique.txt and shared.txt are shared with the other snapshot or source
+	require.Equal(t, int64(1), u.Snapshots[0].UniqueContentCount)
+	require.Equal(t, int64(2), u.Snapshots[0].SharedContentCount)
+	// root directory and unique2.txt
+	require.Equal(t, int64(2), u.Snapshots[1].UniqueContentCount)
+	require.Equal(t, int64(2), u.Snapshots[1].SharedContentCount)
+}
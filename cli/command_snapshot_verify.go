@@ -7,6 +7,9 @@
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/notification/sender"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/manifest"
@@ -70,8 +73,7 @@ func (c *commandSnapshotVerify) run(ctx context.Context, rep repo.Repository) er
 
 	v := snapshotfs.NewVerifier(ctx, rep, opts)
 
-	//nolint:wrapcheck
-	return v.InParallel(ctx, func(tw *snapshotfs.TreeWalker) error {
+	err := v.InParallel(ctx, func(tw *snapshotfs.TreeWalker) error {
 		manifests, err := c.loadSourceManifests(ctx, rep, c.verifyCommandSources)
 		if err != nil {
 			return err
@@ -118,6 +120,15 @@ func (c *commandSnapshotVerify) run(ctx context.Context, rep repo.Repository) er
 
 		return nil
 	})
+	if err != nil {
+		notification.Send(ctx, rep, notifytemplate.VerifyError, &notification.VerifyError{
+			Sources: c.verifyCommandSources,
+			Error:   err.Error(),
+		}, sender.SeverityError)
+	}
+
+	//nolint:wrapcheck
+	return err
 }
 
 func (c *commandSnapshotVerify) loadSourceManifests(ctx context.Context, rep repo.Repository, sources []string) ([]*snapshot.Manifest, error) {
@@ -0,0 +1,15 @@
+package cli
+
+type commandServerToken struct {
+	create commandServerTokenCreate
+	list   commandServerTokenList
+	revoke commandServerTokenRevoke
+}
+
+func (c *commandServerToken) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("tokens", "Manage API tokens used to access the server API").Alias("token")
+
+	c.create.setup(svc, cmd)
+	c.list.setup(svc, cmd)
+	c.revoke.setup(svc, cmd)
+}
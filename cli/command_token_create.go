@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/apitoken"
+	"github.com/kopia/kopia/repo"
+)
+
+type commandServerTokenCreate struct {
+	description string
+	scopes      []string
+	expiresIn   time.Duration
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandServerTokenCreate) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("create", "Create new API token").Alias("add")
+	cmd.Flag("description", "Token description").StringVar(&c.description)
+	cmd.Flag("scope", "Token scope").Required().EnumsVar(&c.scopes, apitoken.SupportedScopes...)
+	cmd.Flag("expires-in", "Token lifetime (0 means the token never expires)").Default("2160h").DurationVar(&c.expiresIn)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.repositoryWriterAction(c.run))
+}
+
+type apiTokenCreateResult struct {
+	*apitoken.Token
+	BearerToken string `json:"token"`
+}
+
+func (c *commandServerTokenCreate) run(ctx context.Context, rep repo.RepositoryWriter) error {
+	var scopes []apitoken.Scope
+
+	for _, s := range c.scopes {
+		scopes = append(scopes, apitoken.Scope(s))
+	}
+
+	var expires time.Time
+	if c.expiresIn > 0 {
+		expires = rep.Time().Add(c.expiresIn)
+	}
+
+	t, tokenString, err := apitoken.Create(ctx, rep, c.description, scopes, expires)
+	if err != nil {
+		return errors.Wrap(err, "error creating API token")
+	}
+
+	if c.jo.jsonOutput {
+		c.out.printStdout("%s\n", c.jo.jsonBytes(apiTokenCreateResult{t, tokenString}))
+		return nil
+	}
+
+	log(ctx).Infof("Created API token %v, it will not be shown again. Use it as a bearer token or pass it using --server-api-token.", t.ID)
+
+	c.out.printStdout("%v\n", tokenString)
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/apitoken"
+	"github.com/kopia/kopia/repo"
+)
+
+type commandServerTokenList struct {
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandServerTokenList) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("list", "List API tokens").Alias("ls")
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.repositoryReaderAction(c.run))
+}
+
+func formatTokenTime(t time.Time, zeroValue string) string {
+	if t.IsZero() {
+		return zeroValue
+	}
+
+	return formatTimestamp(t)
+}
+
+func (c *commandServerTokenList) run(ctx context.Context, rep repo.Repository) error {
+	var jl jsonList
+
+	jl.begin(&c.jo)
+	defer jl.end()
+
+	tokens, err := apitoken.List(ctx, rep)
+	if err != nil {
+		return errors.Wrap(err, "error listing API tokens")
+	}
+
+	for _, t := range tokens {
+		if c.jo.jsonOutput {
+			jl.emit(t)
+			continue
+		}
+
+		var scopes []string
+		for _, s := range t.Scopes {
+			scopes = append(scopes, string(s))
+		}
+
+		expires := formatTokenTime(t.ExpiresTime, "never")
+		if t.IsExpired(rep.Time()) {
+			expires += " (expired)"
+		}
+
+		c.out.printStdout("%v scopes:%v created:%v expires:%v last-used:%v %v\n",
+			t.ID,
+			strings.Join(scopes, ","),
+			formatTimestamp(t.CreatedTime),
+			expires,
+			formatTokenTime(t.LastUsedTime, "never"),
+			t.Description)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/apitoken"
+	"github.com/kopia/kopia/repo"
+)
+
+type commandServerTokenRevoke struct {
+	id string
+}
+
+func (c *commandServerTokenRevoke) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("revoke", "Revoke API token").Alias("delete").Alias("rm")
+	cmd.Arg("id", "The identifier of the token to revoke.").Required().StringVar(&c.id)
+	cmd.Action(svc.repositoryWriterAction(c.run))
+}
+
+func (c *commandServerTokenRevoke) run(ctx context.Context, rep repo.RepositoryWriter) error {
+	if err := apitoken.Revoke(ctx, rep, c.id); err != nil {
+		return errors.Wrap(err, "error revoking API token")
+	}
+
+	log(ctx).Infof("API token %v revoked. Running servers need to be refreshed using 'kopia server refresh' to stop accepting it.", c.id)
+
+	return nil
+}
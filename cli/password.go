@@ -92,6 +92,11 @@ func (c *App) getPasswordFromFlags(ctx context.Context, isCreate, allowPersisten
 	return askForExistingRepositoryPassword(c.stdoutWriter)
 }
 
+// hasPasswordFromFlags determines whether the password was provided via --password flag or KOPIA_PASSWORD environment variable.
+func (c *App) hasPasswordFromFlags() bool {
+	return c.password != ""
+}
+
 // askPass presents a given prompt and asks the user for password.
 func askPass(out io.Writer, prompt string) (string, error) {
 	for i := 0; i < 5; i++ {
@@ -18,6 +18,7 @@
 	AccessLevelRead:   "READ",
 	AccessLevelAppend: "APPEND",
 	AccessLevelFull:   "FULL",
+	AccessLevelAdmin:  "ADMIN",
 }
 
 // stringToAccessLevel maps strings to supported access levels.
@@ -75,11 +76,12 @@ func (a *AccessLevel) UnmarshalJSON(b []byte) error {
 	AccessLevelRead   AccessLevel = 2 // permissions to view, but not change
 	AccessLevelAppend AccessLevel = 3 // permissions to view/add but not update/delete.
 	AccessLevelFull   AccessLevel = 4 // permission to view/add/update/delete.
+	AccessLevelAdmin  AccessLevel = 5 // full permissions plus management using server administration API.
 )
 
 // SupportedAccessLevels returns the list of supported access levels.
 func SupportedAccessLevels() []string {
-	return []string{"NONE", "READ", "APPEND", "FULL"}
+	return []string{"NONE", "READ", "APPEND", "FULL", "ADMIN"}
 }
 
 // ParseAccessLevel parses the provided string into an AccessLevel.
@@ -16,12 +16,14 @@ func TestAccessLevelJSONSerialization(t *testing.T) {
 		C acl.AccessLevel `json:"c"`
 		D acl.AccessLevel `json:"d"`
 		E acl.AccessLevel `json:"e"`
+		F acl.AccessLevel `json:"f"`
 	}
 
 	s1.B = acl.AccessLevelNone
 	s1.C = acl.AccessLevelRead
 	s1.D = acl.AccessLevelAppend
 	s1.E = acl.AccessLevelFull
+	s1.F = acl.AccessLevelAdmin
 
 	v, err := json.MarshalIndent(s1, "", "  ")
 	require.NoError(t, err)
@@ -31,7 +33,8 @@ func TestAccessLevelJSONSerialization(t *testing.T) {
   "b": "NONE",
   "c": "READ",
   "d": "APPEND",
-  "e": "FULL"
+  "e": "FULL",
+  "f": "ADMIN"
 }`
 
 	if diff := cmp.Diff(got, want); diff != "" {
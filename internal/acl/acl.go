@@ -23,11 +23,15 @@
 	OwnHost = "OWN_HOST"
 )
 
+// GroupPrefix is the prefix of ACL entry users which refer to all members of a group,
+// for example "group:admins".
+const GroupPrefix = "group:"
+
 // TargetRule specifies a list of key and values that must match labels on the target manifest.
 // The value can have two special placeholders - OWN_USER and OWN_VALUE representing the matched user
 // and host respectively if wildcards are being used.
 // Each target rule must have a type "type" key with a value corresponding to a manifest type
-// ("snapshot", "policy", "user", "acl"). A special type "content" gives access to contents.
+// ("snapshot", "policy", "user", "group", "acl"). A special type "content" gives access to contents.
 type TargetRule map[string]string
 
 func (r TargetRule) String() string {
@@ -64,9 +68,10 @@ func (r TargetRule) matches(target map[string]string, username, hostname string)
 // user certain level of access to a target.
 type Entry struct {
 	ManifestID manifest.ID `json:"-"`
-	User       string      `json:"user"`   // supports wildcards such as "*@*", "user@host", "*@host, user@*"
+	User       string      `json:"user"`   // supports wildcards such as "*@*", "user@host", "*@host, user@*" and groups "group:name"
 	Target     TargetRule  `json:"target"` // supports OwnUser and OwnHost in labels
 	Access     AccessLevel `json:"access,omitempty"`
+	Role       string      `json:"role,omitempty"` // name of the predefined role the entry was created from, if any
 }
 
 type valueValidatorFunc func(v string) error
@@ -113,7 +118,10 @@ func oneOf(allowed ...string) valueValidatorFunc {
 	user.ManifestType: {
 		user.UsernameAtHostnameLabel: nonEmptyString,
 	},
-	aclManifestType: {},
+	GroupManifestType: {
+		GroupNameLabel: nonEmptyString,
+	},
+	ManifestType: {},
 }
 
 // Validate validates entry.
@@ -122,8 +130,11 @@ func (e *Entry) Validate() error {
 		return errors.Errorf("nil acl")
 	}
 
-	parts := strings.Split(e.User, "@")
-	if len(parts) != 2 { //nolint:gomnd
+	if g := strings.TrimPrefix(e.User, GroupPrefix); g != e.User {
+		if g == "" || strings.Contains(g, "@") {
+			return errors.Errorf("invalid group name %q", g)
+		}
+	} else if parts := strings.Split(e.User, "@"); len(parts) != 2 { //nolint:gomnd
 		return errors.Errorf("user must be 'username@hostname' possibly including wildcards")
 	}
 
@@ -156,6 +167,12 @@ func (e *Entry) Validate() error {
 		return errors.Errorf("valid access level must be specified")
 	}
 
+	if e.Role != "" {
+		if _, err := GetRole(e.Role); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -11,9 +11,8 @@
 	"github.com/kopia/kopia/repo/manifest"
 )
 
-const (
-	aclManifestType = "acl"
-)
+// ManifestType is the type of the manifest used to store ACL entries.
+const ManifestType = "acl"
 
 func matchOrWildcard(rule, actual string) bool {
 	if rule == "*" {
@@ -23,7 +22,17 @@ func matchOrWildcard(rule, actual string) bool {
 	return rule == actual
 }
 
-func userMatches(rule, username, hostname string) bool {
+func userMatches(rule, username, hostname string, groups []string) bool {
+	if g := strings.TrimPrefix(rule, GroupPrefix); g != rule {
+		for _, ug := range groups {
+			if ug == g {
+				return true
+			}
+		}
+
+		return false
+	}
+
 	ruleParts := strings.Split(rule, "@")
 	if len(ruleParts) != 2 { //nolint:gomnd
 		return false
@@ -32,12 +41,12 @@ func userMatches(rule, username, hostname string) bool {
 	return matchOrWildcard(ruleParts[0], username) && matchOrWildcard(ruleParts[1], hostname)
 }
 
-// EntriesForUser computes the list of ACL entries matching the given user.
-func EntriesForUser(entries []*Entry, username, hostname string) []*Entry {
+// EntriesForUser computes the list of ACL entries matching the given user, who is a member of the provided groups.
+func EntriesForUser(entries []*Entry, username, hostname string, groups ...string) []*Entry {
 	result := []*Entry{}
 
 	for _, e := range entries {
-		if userMatches(e.User, username, hostname) {
+		if userMatches(e.User, username, hostname, groups) {
 			result = append(result, e)
 		}
 	}
@@ -48,10 +57,16 @@ func EntriesForUser(entries []*Entry, username, hostname string) []*Entry {
 // EffectivePermissions computes the effective access level for a given user@hostname to subject
 // for a given set of ACL Entries.
 func EffectivePermissions(username, hostname string, target map[string]string, entries []*Entry) AccessLevel {
+	return EffectivePermissionsWithGroups(username, hostname, nil, target, entries)
+}
+
+// EffectivePermissionsWithGroups computes the effective access level for a given user@hostname, who is
+// a member of the provided groups, to subject for a given set of ACL Entries.
+func EffectivePermissionsWithGroups(username, hostname string, groups []string, target map[string]string, entries []*Entry) AccessLevel {
 	highest := AccessLevelNone
 
 	for _, e := range entries {
-		if !userMatches(e.User, username, hostname) {
+		if !userMatches(e.User, username, hostname, groups) {
 			continue
 		}
 
@@ -74,7 +89,7 @@ func LoadEntries(ctx context.Context, rep repo.Repository, old []*Entry) ([]*Ent
 	}
 
 	entries, err := rep.FindManifests(ctx, map[string]string{
-		manifest.TypeLabelKey: aclManifestType,
+		manifest.TypeLabelKey: ManifestType,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "error listing ACL manifests")
@@ -115,7 +130,7 @@ func AddACL(ctx context.Context, w repo.RepositoryWriter, e *Entry) error {
 	}
 
 	manifestID, err := w.PutManifest(ctx, map[string]string{
-		manifest.TypeLabelKey: aclManifestType,
+		manifest.TypeLabelKey: ManifestType,
 	}, e)
 	if err != nil {
 		return errors.Wrap(err, "error writing manifest")
@@ -125,3 +140,21 @@ func AddACL(ctx context.Context, w repo.RepositoryWriter, e *Entry) error {
 
 	return nil
 }
+
+// GrantRole adds ACL entries which grant the predefined role to the provided user or group and returns them.
+func GrantRole(ctx context.Context, w repo.RepositoryWriter, user, roleName string) ([]*Entry, error) {
+	r, err := GetRole(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := r.Entries(user)
+
+	for _, e := range entries {
+		if err := AddACL(ctx, w, e); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
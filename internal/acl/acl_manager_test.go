@@ -149,6 +149,31 @@ func TestEffectivePermissions(t *testing.T) {
 	}
 }
 
+func TestEffectivePermissionsWithGroups(t *testing.T) {
+	entries := []*acl.Entry{
+		{
+			User:   "group:admins",
+			Target: acl.TargetRule{manifest.TypeLabelKey: snapshot.ManifestType},
+			Access: acl.AccessLevelFull,
+		},
+		{
+			User:   "group:auditors",
+			Target: acl.TargetRule{manifest.TypeLabelKey: snapshot.ManifestType},
+			Access: acl.AccessLevelRead,
+		},
+	}
+
+	target := map[string]string{manifest.TypeLabelKey: snapshot.ManifestType}
+
+	require.Equal(t, acl.AccessLevelNone, acl.EffectivePermissions(actualUser, actualHostname, target, entries))
+	require.Equal(t, acl.AccessLevelNone, acl.EffectivePermissionsWithGroups(actualUser, actualHostname, []string{"others"}, target, entries))
+	require.Equal(t, acl.AccessLevelRead, acl.EffectivePermissionsWithGroups(actualUser, actualHostname, []string{"auditors"}, target, entries))
+	require.Equal(t, acl.AccessLevelFull, acl.EffectivePermissionsWithGroups(actualUser, actualHostname, []string{"auditors", "admins"}, target, entries))
+
+	require.Empty(t, acl.EntriesForUser(entries, actualUser, actualHostname))
+	require.Len(t, acl.EntriesForUser(entries, actualUser, actualHostname, "admins"), 1)
+}
+
 func TestLoadEntries(t *testing.T) {
 	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
 
@@ -220,7 +245,7 @@ func TestACLEntryValidation(t *testing.T) {
 				},
 				Access: acl.AccessLevelFull,
 			},
-			WantErr: "invalid 'type' label, must be one of: acl, content, policy, snapshot, user",
+			WantErr: "invalid 'type' label, must be one of: acl, content, group, policy, snapshot, user",
 		},
 		{
 			Entry: &acl.Entry{
@@ -328,6 +353,26 @@ func TestACLEntryValidation(t *testing.T) {
 			},
 			WantErr: "user must be 'username@hostname' possibly including wildcards",
 		},
+		{
+			Entry: &acl.Entry{
+				User: "group:admins",
+				Target: acl.TargetRule{
+					"type": "snapshot",
+				},
+				Access: acl.AccessLevelFull,
+			},
+			WantErr: "",
+		},
+		{
+			Entry: &acl.Entry{
+				User: "group:",
+				Target: acl.TargetRule{
+					"type": "snapshot",
+				},
+				Access: acl.AccessLevelFull,
+			},
+			WantErr: `invalid group name ""`,
+		},
 		{
 			Entry: &acl.Entry{
 				User: "foo@bar",
@@ -0,0 +1,152 @@
+package acl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kopia/kopia/internal/user"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+// MatchingEntries returns ACL entries which apply to the given user@hostname, who is a member of the
+// provided groups, and the target, sorted by decreasing access level. The first entry (if any) determines
+// effective permissions.
+func MatchingEntries(username, hostname string, groups []string, target map[string]string, entries []*Entry) []*Entry {
+	result := []*Entry{}
+
+	for _, e := range entries {
+		if userMatches(e.User, username, hostname, groups) && e.Target.matches(target, username, hostname) {
+			result = append(result, e)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Access > result[j].Access
+	})
+
+	return result
+}
+
+// Kinds of lint findings.
+const (
+	LintRedundant   = "redundant"
+	LintOverlyBroad = "overly-broad"
+)
+
+// LintFinding describes a potential problem with an ACL entry.
+type LintFinding struct {
+	Entry   *Entry `json:"entry"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// sensitiveManifestTypes are manifest types which control access to the repository.
+//
+//nolint:gochecknoglobals
+var sensitiveManifestTypes = map[string]bool{
+	ManifestType:      true,
+	user.ManifestType: true,
+	GroupManifestType: true,
+}
+
+// Lint returns findings about redundant or overly broad ACL entries.
+func Lint(entries []*Entry) []*LintFinding {
+	var result []*LintFinding
+
+	for i, e := range entries {
+		if msg := overlyBroad(e); msg != "" {
+			result = append(result, &LintFinding{e, LintOverlyBroad, msg})
+		}
+
+		for j, other := range entries {
+			if i == j || !entryCovers(other, e) {
+				continue
+			}
+
+			// of two identical entries report only the latter one.
+			if entryCovers(e, other) && i < j {
+				continue
+			}
+
+			result = append(result, &LintFinding{e, LintRedundant, fmt.Sprintf("already granted by %v", other.ManifestID)})
+
+			break
+		}
+	}
+
+	return result
+}
+
+// entryCovers returns true if entry a grants at least the same access as entry b to all users and targets of b.
+func entryCovers(a, b *Entry) bool {
+	return a.Access >= b.Access && userCovers(a.User, b.User) && targetCovers(a.Target, b.Target)
+}
+
+// userCovers returns true if user rule a matches all users matched by user rule b.
+func userCovers(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	if strings.HasPrefix(a, GroupPrefix) {
+		return false
+	}
+
+	aParts := strings.Split(a, "@")
+	if len(aParts) != 2 { //nolint:gomnd
+		return false
+	}
+
+	if strings.HasPrefix(b, GroupPrefix) {
+		// group members can be arbitrary users.
+		return aParts[0] == "*" && aParts[1] == "*"
+	}
+
+	bParts := strings.Split(b, "@")
+	if len(bParts) != 2 { //nolint:gomnd
+		return false
+	}
+
+	return matchOrWildcard(aParts[0], bParts[0]) && matchOrWildcard(aParts[1], bParts[1])
+}
+
+// targetCovers returns true if target rule a matches all manifests matched by rule b.
+func targetCovers(a, b TargetRule) bool {
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func overlyBroad(e *Entry) string {
+	userParts := strings.Split(e.User, "@")
+	if len(userParts) != 2 || userParts[0] != "*" { //nolint:gomnd
+		// groups and rules for individual users are not considered broad.
+		return ""
+	}
+
+	allUsers := "all users"
+	if userParts[1] != "*" {
+		allUsers = "all users at " + userParts[1]
+	}
+
+	typ := e.Target[manifest.TypeLabelKey]
+
+	if sensitiveManifestTypes[typ] && e.Access > AccessLevelRead && len(e.Target) == 1 {
+		return fmt.Sprintf("allows %v to modify all %v manifests", allUsers, typ)
+	}
+
+	if e.Access >= AccessLevelFull && len(e.Target) == 1 {
+		if typ == ContentManifestType {
+			return fmt.Sprintf("allows %v to delete contents", allUsers)
+		}
+
+		return fmt.Sprintf("allows %v to delete all %v manifests", allUsers, typ)
+	}
+
+	return ""
+}
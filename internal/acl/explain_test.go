@@ -0,0 +1,84 @@
+package acl_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/internal/auth"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestMatchingEntries(t *testing.T) {
+	snapshotsRead := &acl.Entry{User: "*@*", Target: acl.TargetRule{manifest.TypeLabelKey: snapshot.ManifestType}, Access: acl.AccessLevelRead}
+	ownSnapshotsFull := &acl.Entry{
+		User: "*@*",
+		Target: acl.TargetRule{
+			manifest.TypeLabelKey:  snapshot.ManifestType,
+			snapshot.UsernameLabel: acl.OwnUser,
+		},
+		Access: acl.AccessLevelFull,
+	}
+	groupAppend := &acl.Entry{User: "group:ops", Target: acl.TargetRule{manifest.TypeLabelKey: snapshot.ManifestType}, Access: acl.AccessLevelAppend}
+	otherUser := &acl.Entry{User: "alice@*", Target: acl.TargetRule{manifest.TypeLabelKey: snapshot.ManifestType}, Access: acl.AccessLevelFull}
+
+	entries := []*acl.Entry{snapshotsRead, ownSnapshotsFull, groupAppend, otherUser}
+
+	own := map[string]string{manifest.TypeLabelKey: snapshot.ManifestType, snapshot.UsernameLabel: actualUser}
+	other := map[string]string{manifest.TypeLabelKey: snapshot.ManifestType, snapshot.UsernameLabel: "carol"}
+
+	require.Equal(t, []*acl.Entry{ownSnapshotsFull, snapshotsRead}, acl.MatchingEntries(actualUser, actualHostname, nil, own, entries))
+	require.Equal(t, []*acl.Entry{groupAppend, snapshotsRead}, acl.MatchingEntries(actualUser, actualHostname, []string{"ops"}, other, entries))
+	require.Empty(t, acl.MatchingEntries(actualUser, actualHostname, nil, map[string]string{manifest.TypeLabelKey: acl.ContentManifestType}, entries))
+}
+
+func TestLint(t *testing.T) {
+	require.Empty(t, acl.Lint(auth.DefaultACLs))
+
+	snapshotsRead := &acl.Entry{ManifestID: "a", User: "*@*", Target: acl.TargetRule{manifest.TypeLabelKey: snapshot.ManifestType}, Access: acl.AccessLevelRead}
+	bobHostSnapshots := &acl.Entry{
+		ManifestID: "b",
+		User:       actualUserAtHostname,
+		Target: acl.TargetRule{
+			manifest.TypeLabelKey:  snapshot.ManifestType,
+			snapshot.HostnameLabel: actualHostname,
+		},
+		Access: acl.AccessLevelRead,
+	}
+	bobHostSnapshotsFull := &acl.Entry{
+		ManifestID: "c",
+		User:       actualUser + "@*",
+		Target: acl.TargetRule{
+			manifest.TypeLabelKey:  snapshot.ManifestType,
+			snapshot.HostnameLabel: actualHostname,
+		},
+		Access: acl.AccessLevelFull,
+	}
+	duplicate := &acl.Entry{ManifestID: "d", User: "*@*", Target: acl.TargetRule{manifest.TypeLabelKey: snapshot.ManifestType}, Access: acl.AccessLevelRead}
+	groupRead := &acl.Entry{ManifestID: "e", User: "group:ops", Target: acl.TargetRule{manifest.TypeLabelKey: snapshot.ManifestType}, Access: acl.AccessLevelRead}
+	everybodyModifiesUsers := &acl.Entry{ManifestID: "f", User: "*@" + actualHostname, Target: acl.TargetRule{manifest.TypeLabelKey: "user"}, Access: acl.AccessLevelAppend}
+	everybodyDeletesPolicies := &acl.Entry{ManifestID: "g", User: "*@*", Target: acl.TargetRule{manifest.TypeLabelKey: "policy"}, Access: acl.AccessLevelFull}
+
+	findings := acl.Lint([]*acl.Entry{snapshotsRead, bobHostSnapshots, bobHostSnapshotsFull, duplicate, groupRead, everybodyModifiesUsers, everybodyDeletesPolicies})
+
+	type finding struct {
+		id   manifest.ID
+		kind string
+	}
+
+	var got []finding
+
+	for _, f := range findings {
+		got = append(got, finding{f.Entry.ManifestID, f.Kind})
+	}
+
+	require.Equal(t, []finding{
+		{"b", acl.LintRedundant},   // covered by "a" and "c"
+		{"d", acl.LintRedundant},   // duplicate of "a"
+		{"e", acl.LintRedundant},   // covered by "a"
+		{"f", acl.LintOverlyBroad}, // all users at a host can create user accounts
+		{"g", acl.LintOverlyBroad}, // all users can delete all policies
+	}, got)
+}
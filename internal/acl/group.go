@@ -0,0 +1,267 @@
+package acl
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+// GroupManifestType is the type of the manifest used to represent groups of users.
+const GroupManifestType = "group"
+
+// GroupNameLabel is the manifest label identifying groups by name.
+const GroupNameLabel = "group"
+
+// ErrGroupNotFound is returned to indicate that a group was not found in the repository.
+var ErrGroupNotFound = errors.New("group not found")
+
+// Group is a named set of users which can be granted access in ACL entries using "group:<name>".
+type Group struct {
+	ManifestID  manifest.ID `json:"-"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Members     []string    `json:"members"` // supports wildcards such as "*@host" and "user@*"
+}
+
+// HasMember returns true if the group has the provided member, matched literally.
+func (g *Group) HasMember(member string) bool {
+	for _, m := range g.Members {
+		if m == member {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddMembers adds the provided members to the group, ignoring existing members.
+func (g *Group) AddMembers(members ...string) error {
+	for _, m := range members {
+		if err := validateGroupMember(m); err != nil {
+			return err
+		}
+
+		if !g.HasMember(m) {
+			g.Members = append(g.Members, m)
+		}
+	}
+
+	sort.Strings(g.Members)
+
+	return nil
+}
+
+// RemoveMembers removes the provided members from the group.
+func (g *Group) RemoveMembers(members ...string) error {
+	for _, m := range members {
+		if !g.HasMember(m) {
+			return errors.Errorf("%q is not a member of group %q", m, g.Name)
+		}
+	}
+
+	var remaining []string
+
+	for _, m := range g.Members {
+		if !contains(members, m) {
+			remaining = append(remaining, m)
+		}
+	}
+
+	g.Members = remaining
+
+	return nil
+}
+
+// Validate validates the group.
+func (g *Group) Validate() error {
+	if err := ValidateGroupName(g.Name); err != nil {
+		return err
+	}
+
+	for _, m := range g.Members {
+		if err := validateGroupMember(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validGroupNameRegexp matches group names consisting of letters, digits or dashes, underscores or period characters.
+var validGroupNameRegexp = regexp.MustCompile(`^[A-Za-z0-9\-_.]+$`)
+
+// ValidateGroupName returns an error if the given group name is invalid.
+func ValidateGroupName(name string) error {
+	if name == "" {
+		return errors.Errorf("group name is required")
+	}
+
+	if !validGroupNameRegexp.MatchString(name) {
+		return errors.Errorf("invalid group name %q, must consist of letters, digits, '-', '_' or '.'", name)
+	}
+
+	return nil
+}
+
+func validateGroupMember(m string) error {
+	if parts := strings.Split(m, "@"); len(parts) != 2 || parts[0] == "" || parts[1] == "" { //nolint:gomnd
+		return errors.Errorf("invalid member %q, must be 'username@hostname' possibly including wildcards", m)
+	}
+
+	return nil
+}
+
+func contains(list []string, v string) bool {
+	for _, it := range list {
+		if it == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GroupsForUser returns the sorted names of groups which have the given user as a member.
+func GroupsForUser(groups map[string]*Group, username, hostname string) []string {
+	var result []string
+
+	for name, g := range groups {
+		for _, m := range g.Members {
+			if userMatches(m, username, hostname, nil) {
+				result = append(result, name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// LoadGroupMap returns the map of all groups in the repository by name, using old map as a cache.
+func LoadGroupMap(ctx context.Context, rep repo.Repository, old map[string]*Group) (map[string]*Group, error) {
+	if rep == nil {
+		return nil, nil
+	}
+
+	entries, err := rep.FindManifests(ctx, map[string]string{manifest.TypeLabelKey: GroupManifestType})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing group manifests")
+	}
+
+	result := map[string]*Group{}
+
+	for _, m := range manifest.DedupeEntryMetadataByLabel(entries, GroupNameLabel) {
+		name := m.Labels[GroupNameLabel]
+
+		// same group as before
+		if o := old[name]; o != nil && o.ManifestID == m.ID {
+			result[name] = o
+			continue
+		}
+
+		g := &Group{}
+		if _, err := rep.GetManifest(ctx, m.ID, g); err != nil {
+			return nil, errors.Wrapf(err, "error loading group manifest %v", name)
+		}
+
+		g.ManifestID = m.ID
+
+		result[name] = g
+	}
+
+	return result, nil
+}
+
+// ListGroups gets the list of all groups in the repository sorted by name.
+func ListGroups(ctx context.Context, rep repo.Repository) ([]*Group, error) {
+	groups, err := LoadGroupMap(ctx, rep, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Group
+
+	for _, g := range groups {
+		result = append(result, g)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// GetGroup returns the group with a given name.
+func GetGroup(ctx context.Context, rep repo.Repository, name string) (*Group, error) {
+	manifests, err := rep.FindManifests(ctx, map[string]string{
+		manifest.TypeLabelKey: GroupManifestType,
+		GroupNameLabel:        name,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error looking for group")
+	}
+
+	if len(manifests) == 0 {
+		return nil, errors.Wrap(ErrGroupNotFound, name)
+	}
+
+	g := &Group{}
+	if _, err := rep.GetManifest(ctx, manifest.PickLatestID(manifests), g); err != nil {
+		return nil, errors.Wrap(err, "error loading group")
+	}
+
+	g.ManifestID = manifest.PickLatestID(manifests)
+
+	return g, nil
+}
+
+// SetGroup creates or updates the group.
+func SetGroup(ctx context.Context, w repo.RepositoryWriter, g *Group) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+
+	id, err := w.ReplaceManifests(ctx, map[string]string{
+		manifest.TypeLabelKey: GroupManifestType,
+		GroupNameLabel:        g.Name,
+	}, g)
+	if err != nil {
+		return errors.Wrap(err, "error writing group")
+	}
+
+	g.ManifestID = id
+
+	return nil
+}
+
+// DeleteGroup removes the group with a given name.
+func DeleteGroup(ctx context.Context, w repo.RepositoryWriter, name string) error {
+	manifests, err := w.FindManifests(ctx, map[string]string{
+		manifest.TypeLabelKey: GroupManifestType,
+		GroupNameLabel:        name,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error looking for group")
+	}
+
+	if len(manifests) == 0 {
+		return errors.Wrap(ErrGroupNotFound, name)
+	}
+
+	for _, m := range manifests {
+		if err := w.DeleteManifest(ctx, m.ID); err != nil {
+			return errors.Wrapf(err, "error deleting group %v", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package acl_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/internal/repotesting"
+)
+
+func TestGroupMembers(t *testing.T) {
+	g := &acl.Group{Name: "ops"}
+
+	require.NoError(t, g.AddMembers("bob@home", "*@office", "bob@home"))
+	require.Equal(t, []string{"*@office", "bob@home"}, g.Members)
+
+	require.Error(t, g.AddMembers("bob"))
+	require.Error(t, g.AddMembers("@home"))
+	require.Error(t, g.RemoveMembers("alice@home"))
+
+	require.NoError(t, g.RemoveMembers("bob@home"))
+	require.Equal(t, []string{"*@office"}, g.Members)
+}
+
+func TestGroupsForUser(t *testing.T) {
+	groups := map[string]*acl.Group{
+		"office": {Name: "office", Members: []string{"*@" + anotherHostname}},
+		"bobs":   {Name: "bobs", Members: []string{actualUser + "@*"}},
+		"admins": {Name: "admins", Members: []string{"alice@" + actualHostname}},
+	}
+
+	require.Equal(t, []string{"bobs"}, acl.GroupsForUser(groups, actualUser, actualHostname))
+	require.Equal(t, []string{"bobs", "office"}, acl.GroupsForUser(groups, actualUser, anotherHostname))
+	require.Empty(t, acl.GroupsForUser(groups, "carol", actualHostname))
+}
+
+func TestGroupManager(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	_, err := acl.GetGroup(ctx, env.RepositoryWriter, "ops")
+	require.ErrorIs(t, err, acl.ErrGroupNotFound)
+
+	require.Error(t, acl.SetGroup(ctx, env.RepositoryWriter, &acl.Group{Name: "bad name"}))
+	require.Error(t, acl.SetGroup(ctx, env.RepositoryWriter, &acl.Group{Name: "ops", Members: []string{"bob"}}))
+
+	require.NoError(t, acl.SetGroup(ctx, env.RepositoryWriter, &acl.Group{Name: "ops", Members: []string{actualUserAtHostname}}))
+	require.NoError(t, acl.SetGroup(ctx, env.RepositoryWriter, &acl.Group{Name: "admins"}))
+
+	g, err := acl.GetGroup(ctx, env.RepositoryWriter, "ops")
+	require.NoError(t, err)
+	require.Equal(t, []string{actualUserAtHostname}, g.Members)
+
+	// replace existing group
+	require.NoError(t, g.AddMembers("*@"+anotherHostname))
+	require.NoError(t, acl.SetGroup(ctx, env.RepositoryWriter, g))
+
+	groups, err := acl.ListGroups(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	require.Equal(t, "admins", groups[0].Name)
+	require.Equal(t, "ops", groups[1].Name)
+	require.Equal(t, []string{"*@" + anotherHostname, actualUserAtHostname}, groups[1].Members)
+
+	require.NoError(t, acl.DeleteGroup(ctx, env.RepositoryWriter, "admins"))
+	require.ErrorIs(t, acl.DeleteGroup(ctx, env.RepositoryWriter, "admins"), acl.ErrGroupNotFound)
+
+	groups, err = acl.ListGroups(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+}
@@ -0,0 +1,152 @@
+package acl
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/user"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+// Names of predefined roles.
+const (
+	RoleBackupOnly      = "backup-only"
+	RoleRestoreOperator = "restore-operator"
+	RoleAuditor         = "auditor"
+	RoleAdmin           = "admin"
+)
+
+// RoleRule is a single rule granted by a role.
+type RoleRule struct {
+	Target TargetRule  `json:"target"`
+	Access AccessLevel `json:"access"`
+}
+
+// Role is a predefined set of rules which are granted together to a user or group.
+type Role struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Rules       []RoleRule `json:"rules"`
+}
+
+// Entries returns ACL entries granting the role to the provided user, which can be a group reference.
+func (r *Role) Entries(user string) []*Entry {
+	var result []*Entry
+
+	for _, rr := range r.Rules {
+		t := TargetRule{}
+		for k, v := range rr.Target {
+			t[k] = v
+		}
+
+		result = append(result, &Entry{
+			User:   user,
+			Target: t,
+			Access: rr.Access,
+			Role:   r.Name,
+		})
+	}
+
+	return result
+}
+
+func typeRule(typ string, labels ...string) TargetRule {
+	r := TargetRule{manifest.TypeLabelKey: typ}
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		r[labels[i]] = labels[i+1]
+	}
+
+	return r
+}
+
+// policyReadRules allow reading policies which apply to user's own snapshots.
+func policyReadRules() []RoleRule {
+	return []RoleRule{
+		{typeRule(policy.ManifestType, policy.PolicyTypeLabel, policy.PolicyTypeGlobal), AccessLevelRead},
+		{typeRule(policy.ManifestType, policy.PolicyTypeLabel, policy.PolicyTypeHost, policy.HostnameLabel, OwnHost), AccessLevelRead},
+		{typeRule(policy.ManifestType, policy.UsernameLabel, OwnUser, policy.HostnameLabel, OwnHost), AccessLevelRead},
+	}
+}
+
+//nolint:gochecknoglobals
+var predefinedRoles = map[string]*Role{
+	RoleBackupOnly: {
+		Name:        RoleBackupOnly,
+		Description: "Create snapshots of own sources, without the ability to delete snapshots or change policies.",
+		Rules: append([]RoleRule{
+			{typeRule(ContentManifestType), AccessLevelAppend},
+			{typeRule(snapshot.ManifestType, snapshot.UsernameLabel, OwnUser, snapshot.HostnameLabel, OwnHost), AccessLevelAppend},
+		}, policyReadRules()...),
+	},
+	RoleRestoreOperator: {
+		Name:        RoleRestoreOperator,
+		Description: "Browse and restore snapshots of all users.",
+		Rules: []RoleRule{
+			{typeRule(ContentManifestType), AccessLevelRead},
+			{typeRule(snapshot.ManifestType), AccessLevelRead},
+			{typeRule(policy.ManifestType), AccessLevelRead},
+		},
+	},
+	RoleAuditor: {
+		Name:        RoleAuditor,
+		Description: "Inspect snapshots, policies, users, groups and ACLs without access to file contents.",
+		Rules: []RoleRule{
+			{typeRule(snapshot.ManifestType), AccessLevelRead},
+			{typeRule(policy.ManifestType), AccessLevelRead},
+			{typeRule(user.ManifestType), AccessLevelRead},
+			{typeRule(GroupManifestType), AccessLevelRead},
+			{typeRule(ManifestType), AccessLevelRead},
+		},
+	},
+	RoleAdmin: {
+		Name:        RoleAdmin,
+		Description: "Full access to contents, snapshots and policies and administration of users, groups and ACLs.",
+		Rules: []RoleRule{
+			{typeRule(ContentManifestType), AccessLevelFull},
+			{typeRule(snapshot.ManifestType), AccessLevelFull},
+			{typeRule(policy.ManifestType), AccessLevelFull},
+			{typeRule(user.ManifestType), AccessLevelAdmin},
+			{typeRule(GroupManifestType), AccessLevelAdmin},
+			{typeRule(ManifestType), AccessLevelAdmin},
+		},
+	},
+}
+
+// SupportedRoles returns the sorted names of predefined roles.
+func SupportedRoles() []string {
+	var result []string
+
+	for k := range predefinedRoles {
+		result = append(result, k)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// Roles returns predefined roles sorted by name.
+func Roles() []*Role {
+	var result []*Role
+
+	for _, n := range SupportedRoles() {
+		result = append(result, predefinedRoles[n])
+	}
+
+	return result
+}
+
+// GetRole returns the predefined role with a given name.
+func GetRole(name string) (*Role, error) {
+	r := predefinedRoles[name]
+	if r == nil {
+		return nil, errors.Errorf("unknown role %q, must be one of: %v", name, strings.Join(SupportedRoles(), ", "))
+	}
+
+	return r, nil
+}
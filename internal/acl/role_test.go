@@ -0,0 +1,65 @@
+package acl_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestRoles(t *testing.T) {
+	require.Equal(t, []string{acl.RoleAdmin, acl.RoleAuditor, acl.RoleBackupOnly, acl.RoleRestoreOperator}, acl.SupportedRoles())
+
+	for _, r := range acl.Roles() {
+		for _, e := range r.Entries(acl.GroupPrefix + "ops") {
+			require.NoError(t, e.Validate(), r.Name)
+			require.Equal(t, r.Name, e.Role)
+		}
+	}
+
+	_, err := acl.GetRole("no-such-role")
+	require.ErrorContains(t, err, "must be one of")
+
+	require.Error(t, (&acl.Entry{
+		User:   actualUserAtHostname,
+		Target: acl.TargetRule{manifest.TypeLabelKey: snapshot.ManifestType},
+		Access: acl.AccessLevelRead,
+		Role:   "no-such-role",
+	}).Validate())
+}
+
+func TestGrantRole(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	added, err := acl.GrantRole(ctx, env.RepositoryWriter, actualUserAtHostname, acl.RoleBackupOnly)
+	require.NoError(t, err)
+
+	entries, err := acl.LoadEntries(ctx, env.RepositoryWriter, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, len(added))
+
+	ownSnapshot := map[string]string{
+		manifest.TypeLabelKey:  snapshot.ManifestType,
+		snapshot.UsernameLabel: actualUser,
+		snapshot.HostnameLabel: actualHostname,
+	}
+
+	otherSnapshot := map[string]string{
+		manifest.TypeLabelKey:  snapshot.ManifestType,
+		snapshot.UsernameLabel: "alice",
+		snapshot.HostnameLabel: actualHostname,
+	}
+
+	require.Equal(t, acl.AccessLevelAppend, acl.EffectivePermissions(actualUser, actualHostname, ownSnapshot, entries))
+	require.Equal(t, acl.AccessLevelNone, acl.EffectivePermissions(actualUser, actualHostname, otherSnapshot, entries))
+
+	_, err = acl.GrantRole(ctx, env.RepositoryWriter, "bob", acl.RoleAuditor)
+	require.Error(t, err)
+
+	_, err = acl.GrantRole(ctx, env.RepositoryWriter, actualUserAtHostname, "no-such-role")
+	require.Error(t, err)
+}
@@ -176,8 +176,15 @@ type Options struct {
 	Username string
 	Password string
 
+	// API token sent as a bearer token instead of username and password, optional.
+	BearerToken string
+
 	TrustedServerCertificateFingerprint string
 
+	// PEM files with client certificate and its private key presented to the server, optional.
+	ClientCertificateFile string
+	ClientKeyFile         string
+
 	LogRequests bool
 }
 
@@ -185,15 +192,22 @@ type Options struct {
 func NewKopiaAPIClient(options Options) (*KopiaAPIClient, error) {
 	var transport http.RoundTripper
 
-	// override transport which trusts only one certificate
-	if f := options.TrustedServerCertificateFingerprint; f != "" {
-		transport = tlsutil.TransportTrustingSingleCertificate(f)
+	// override transport which trusts only one certificate or presents client certificate
+	if options.TrustedServerCertificateFingerprint != "" || options.ClientCertificateFile != "" {
+		tc, err := tlsutil.ClientTLSConfig(options.TrustedServerCertificateFingerprint, options.ClientCertificateFile, options.ClientKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to set up TLS")
+		}
+
+		transport = tlsutil.TransportWithTLSConfig(tc)
 	} else {
 		transport = http.DefaultTransport
 	}
 
-	// wrap with a round-tripper that provides basic authentication
-	if options.Username != "" || options.Password != "" {
+	// wrap with a round-tripper that provides bearer token or basic authentication
+	if options.BearerToken != "" {
+		transport = bearerTokenTransport{transport, options.BearerToken}
+	} else if options.Username != "" || options.Password != "" {
 		transport = basicAuthTransport{transport, options.Username, options.Password}
 	}
 
@@ -229,6 +243,18 @@ func (t basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	return t.base.RoundTrip(req)
 }
 
+type bearerTokenTransport struct {
+	base  http.RoundTripper
+	token string
+}
+
+func (t bearerTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+t.token)
+
+	//nolint:wrapcheck
+	return t.base.RoundTrip(req)
+}
+
 type loggingTransport struct {
 	base http.RoundTripper
 }
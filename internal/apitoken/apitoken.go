@@ -0,0 +1,166 @@
+// Package apitoken manages API tokens which allow automation to access the server API.
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+// Scope determines which server API requests can be made using a token.
+type Scope string
+
+// Supported token scopes.
+const (
+	// ScopeStatus allows read-only requests, such as getting server status or listing sources.
+	ScopeStatus Scope = "status"
+
+	// ScopeTriggerSnapshot allows starting and canceling snapshots of existing sources.
+	ScopeTriggerSnapshot Scope = "trigger-snapshot"
+
+	// ScopeRestore allows restoring and mounting snapshots.
+	ScopeRestore Scope = "restore"
+
+	// ScopeAdmin allows all requests.
+	ScopeAdmin Scope = "admin"
+)
+
+// SupportedScopes lists supported token scopes.
+//
+//nolint:gochecknoglobals
+var SupportedScopes = []string{
+	string(ScopeStatus),
+	string(ScopeTriggerSnapshot),
+	string(ScopeRestore),
+	string(ScopeAdmin),
+}
+
+// tokenPrefix distinguishes API tokens from other bearer tokens, such as OpenID Connect ID tokens.
+const tokenPrefix = "kopia_"
+
+const (
+	tokenIDBytes     = 8
+	tokenSecretBytes = 32
+)
+
+// Token describes an API token. Only the hash of the token secret is stored.
+type Token struct {
+	ManifestID manifest.ID `json:"-"`
+
+	ID           string    `json:"id"`
+	Description  string    `json:"description,omitempty"`
+	Scopes       []Scope   `json:"scopes"`
+	SecretHash   []byte    `json:"secretHash"`
+	CreatedTime  time.Time `json:"created"`
+	ExpiresTime  time.Time `json:"expires,omitempty"` // zero means the token never expires
+	LastUsedTime time.Time `json:"lastUsed,omitempty"`
+}
+
+// HasScope determines whether the token grants the provided scope, admin tokens have all scopes.
+func (t *Token) HasScope(s Scope) bool {
+	for _, ts := range t.Scopes {
+		if ts == s || ts == ScopeAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsExpired determines whether the token has expired at the provided time.
+func (t *Token) IsExpired(now time.Time) bool {
+	return !t.ExpiresTime.IsZero() && !now.Before(t.ExpiresTime)
+}
+
+// isValidSecret determines whether the provided secret matches the token.
+func (t *Token) isValidSecret(secret string) bool {
+	return subtle.ConstantTimeCompare(hashSecret(secret), t.SecretHash) == 1
+}
+
+// ValidateScope returns an error if the provided scope is not supported.
+func ValidateScope(s Scope) error {
+	for _, v := range SupportedScopes {
+		if string(s) == v {
+			return nil
+		}
+	}
+
+	return errors.Errorf("unsupported scope %q, must be one of: %v", s, strings.Join(SupportedScopes, ", "))
+}
+
+// hashSecret returns the hash of a token secret. Secrets are random and long enough that
+// a fast hash is sufficient, which keeps verification of each request cheap.
+func hashSecret(secret string) []byte {
+	h := sha256.Sum256([]byte(secret))
+	return h[:]
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", errors.Wrap(err, "error generating random bytes")
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// newToken creates a new token with random identifier and returns it along with the bearer token string.
+func newToken(description string, scopes []Scope, now, expires time.Time) (*Token, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", errors.Errorf("at least one scope is required")
+	}
+
+	for _, s := range scopes {
+		if err := ValidateScope(s); err != nil {
+			return nil, "", err
+		}
+	}
+
+	id, err := randomHex(tokenIDBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := randomHex(tokenSecretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	t := &Token{
+		ID:          id,
+		Description: description,
+		Scopes:      scopes,
+		SecretHash:  hashSecret(secret),
+		CreatedTime: now,
+		ExpiresTime: expires,
+	}
+
+	return t, tokenPrefix + id + "_" + secret, nil
+}
+
+// validTokenRegexp matches bearer token strings returned by Create().
+var validTokenRegexp = regexp.MustCompile(`^kopia_([0-9a-f]+)_([0-9a-f]+)$`)
+
+// IsAPIToken determines whether the provided bearer token looks like an API token.
+func IsAPIToken(s string) bool {
+	return strings.HasPrefix(s, tokenPrefix)
+}
+
+// parseToken returns the token identifier and secret from a bearer token string.
+func parseToken(s string) (id, secret string, err error) {
+	m := validTokenRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return "", "", errors.Errorf("malformed API token")
+	}
+
+	return m[1], m[2], nil
+}
@@ -0,0 +1,165 @@
+package apitoken
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+// ManifestType is the type of the manifest used to represent API tokens.
+const ManifestType = "apitoken"
+
+// IDLabel is the manifest label identifying API tokens.
+const IDLabel = "token"
+
+var (
+	// ErrTokenNotFound is returned to indicate that a token was not found in the repository.
+	ErrTokenNotFound = errors.New("API token not found")
+
+	// ErrInvalidToken is returned when a bearer token is unknown, revoked, expired or does not match.
+	ErrInvalidToken = errors.New("invalid API token")
+)
+
+// Create creates a new API token and returns it along with the bearer token string, which is not stored
+// in the repository and can't be retrieved later.
+func Create(ctx context.Context, w repo.RepositoryWriter, description string, scopes []Scope, expires time.Time) (*Token, string, error) {
+	t, tokenString, err := newToken(description, scopes, w.Time(), expires)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := SetToken(ctx, w, t); err != nil {
+		return nil, "", err
+	}
+
+	return t, tokenString, nil
+}
+
+// SetToken creates or updates the API token.
+func SetToken(ctx context.Context, w repo.RepositoryWriter, t *Token) error {
+	id, err := w.ReplaceManifests(ctx, map[string]string{
+		manifest.TypeLabelKey: ManifestType,
+		IDLabel:               t.ID,
+	}, t)
+	if err != nil {
+		return errors.Wrap(err, "error writing API token")
+	}
+
+	t.ManifestID = id
+
+	return nil
+}
+
+// List returns all API tokens sorted by creation time.
+func List(ctx context.Context, rep repo.Repository) ([]*Token, error) {
+	entries, err := rep.FindManifests(ctx, map[string]string{manifest.TypeLabelKey: ManifestType})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing API tokens")
+	}
+
+	var result []*Token
+
+	for _, m := range manifest.DedupeEntryMetadataByLabel(entries, IDLabel) {
+		t := &Token{}
+		if _, err := rep.GetManifest(ctx, m.ID, t); err != nil {
+			return nil, errors.Wrapf(err, "error loading API token %v", m.Labels[IDLabel])
+		}
+
+		t.ManifestID = m.ID
+
+		result = append(result, t)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedTime.Before(result[j].CreatedTime)
+	})
+
+	return result, nil
+}
+
+// Get returns the API token with a given identifier.
+func Get(ctx context.Context, rep repo.Repository, id string) (*Token, error) {
+	manifests, err := rep.FindManifests(ctx, map[string]string{
+		manifest.TypeLabelKey: ManifestType,
+		IDLabel:               id,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error looking for API token")
+	}
+
+	if len(manifests) == 0 {
+		return nil, errors.Wrap(ErrTokenNotFound, id)
+	}
+
+	t := &Token{}
+	if _, err := rep.GetManifest(ctx, manifest.PickLatestID(manifests), t); err != nil {
+		return nil, errors.Wrap(err, "error loading API token")
+	}
+
+	t.ManifestID = manifest.PickLatestID(manifests)
+
+	return t, nil
+}
+
+// Revoke removes the API token with a given identifier.
+func Revoke(ctx context.Context, w repo.RepositoryWriter, id string) error {
+	manifests, err := w.FindManifests(ctx, map[string]string{
+		manifest.TypeLabelKey: ManifestType,
+		IDLabel:               id,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error looking for API token")
+	}
+
+	if len(manifests) == 0 {
+		return errors.Wrap(ErrTokenNotFound, id)
+	}
+
+	for _, m := range manifests {
+		if err := w.DeleteManifest(ctx, m.ID); err != nil {
+			return errors.Wrapf(err, "error deleting API token %v", id)
+		}
+	}
+
+	return nil
+}
+
+// Verify returns the API token matching the provided bearer token string if it's valid at a given time.
+// Tokens whose identifier matches more than one manifest are rejected, since it's not possible to tell
+// which one was legitimately written.
+func Verify(ctx context.Context, rep repo.Repository, tokenString string, now time.Time) (*Token, error) {
+	id, secret, err := parseToken(tokenString)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	manifests, err := rep.FindManifests(ctx, map[string]string{
+		manifest.TypeLabelKey: ManifestType,
+		IDLabel:               id,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error looking for API token")
+	}
+
+	if len(manifests) != 1 {
+		return nil, errors.Wrapf(ErrInvalidToken, "found %v manifests for API token %v", len(manifests), id)
+	}
+
+	t := &Token{}
+	if _, err := rep.GetManifest(ctx, manifests[0].ID, t); err != nil {
+		return nil, errors.Wrap(err, "error loading API token")
+	}
+
+	t.ManifestID = manifests[0].ID
+
+	if t.ID != id || !t.isValidSecret(secret) || t.IsExpired(now) {
+		return nil, ErrInvalidToken
+	}
+
+	return t, nil
+}
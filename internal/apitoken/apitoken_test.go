@@ -0,0 +1,81 @@
+package apitoken_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/apitoken"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+func TestAPITokens(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+	now := env.RepositoryWriter.Time()
+
+	_, _, err := apitoken.Create(ctx, env.RepositoryWriter, "no scopes", nil, time.Time{})
+	require.Error(t, err)
+
+	_, _, err = apitoken.Create(ctx, env.RepositoryWriter, "bad scope", []apitoken.Scope{"no-such-scope"}, time.Time{})
+	require.Error(t, err)
+
+	tok1, tokenString1, err := apitoken.Create(ctx, env.RepositoryWriter, "ci status", []apitoken.Scope{apitoken.ScopeStatus}, time.Time{})
+	require.NoError(t, err)
+	require.True(t, apitoken.IsAPIToken(tokenString1))
+
+	tok2, tokenString2, err := apitoken.Create(ctx, env.RepositoryWriter, "short-lived", []apitoken.Scope{apitoken.ScopeAdmin}, now.Add(time.Hour))
+	require.NoError(t, err)
+
+	tokens, err := apitoken.List(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+
+	v, err := apitoken.Verify(ctx, env.RepositoryWriter, tokenString1, now)
+	require.NoError(t, err)
+	require.Equal(t, tok1.ID, v.ID)
+	require.True(t, v.HasScope(apitoken.ScopeStatus))
+	require.False(t, v.HasScope(apitoken.ScopeRestore))
+
+	v, err = apitoken.Verify(ctx, env.RepositoryWriter, tokenString2, now)
+	require.NoError(t, err)
+	require.True(t, v.HasScope(apitoken.ScopeRestore))
+
+	// duplicate manifests for the same token are rejected.
+	dupID, err := env.RepositoryWriter.PutManifest(ctx, map[string]string{
+		manifest.TypeLabelKey: apitoken.ManifestType,
+		apitoken.IDLabel:      tok2.ID,
+	}, tok2)
+	require.NoError(t, err)
+
+	_, err = apitoken.Verify(ctx, env.RepositoryWriter, tokenString2, now)
+	require.ErrorIs(t, err, apitoken.ErrInvalidToken)
+
+	require.NoError(t, env.RepositoryWriter.DeleteManifest(ctx, dupID))
+
+	// expired
+	_, err = apitoken.Verify(ctx, env.RepositoryWriter, tokenString2, now.Add(2*time.Hour))
+	require.ErrorIs(t, err, apitoken.ErrInvalidToken)
+
+	// wrong secret
+	_, err = apitoken.Verify(ctx, env.RepositoryWriter, "kopia_"+tok2.ID+"_0123456789abcdef", now)
+	require.ErrorIs(t, err, apitoken.ErrInvalidToken)
+
+	_, err = apitoken.Verify(ctx, env.RepositoryWriter, "not-a-token", now)
+	require.ErrorIs(t, err, apitoken.ErrInvalidToken)
+
+	require.NoError(t, apitoken.Revoke(ctx, env.RepositoryWriter, tok1.ID))
+
+	_, err = apitoken.Verify(ctx, env.RepositoryWriter, tokenString1, now)
+	require.ErrorIs(t, err, apitoken.ErrInvalidToken)
+
+	err = apitoken.Revoke(ctx, env.RepositoryWriter, tok1.ID)
+	require.True(t, errors.Is(err, apitoken.ErrTokenNotFound))
+
+	tokens, err = apitoken.List(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, tok2.ID, tokens[0].ID)
+}
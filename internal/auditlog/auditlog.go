@@ -0,0 +1,312 @@
+// Package auditlog implements append-only log of security-relevant server operations.
+package auditlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo/logging"
+)
+
+var log = logging.Module("kopia/auditlog")
+
+// Result is the result of an audited operation.
+type Result string
+
+// Supported results.
+const (
+	ResultSuccess Result = "success"
+	ResultFailure Result = "failure"
+	ResultDenied  Result = "denied"
+)
+
+// Audited actions.
+const (
+	ActionLogin           = "login"
+	ActionManifestPut     = "manifest-put"
+	ActionManifestDelete  = "manifest-delete"
+	ActionSnapshotDelete  = "snapshot-delete"
+	ActionPolicySet       = "policy-set"
+	ActionPolicyDelete    = "policy-delete"
+	ActionUserAdd         = "user-add"
+	ActionUserSetPassword = "user-set-password"
+	ActionUserDelete      = "user-delete"
+	ActionACLAdd          = "acl-add"
+	ActionACLUpdate       = "acl-update"
+	ActionACLDelete       = "acl-delete"
+	ActionRestore         = "restore"
+	ActionMount           = "mount"
+	ActionUnmount         = "unmount"
+)
+
+// maxEntryLength is the maximum length of a single log entry accepted when reading the log.
+const maxEntryLength = 1 << 20
+
+// Entry is a single entry in the audit log.
+type Entry struct {
+	Time          time.Time         `json:"time"`
+	User          string            `json:"user"`
+	Host          string            `json:"host,omitempty"`
+	RemoteAddress string            `json:"remoteAddress,omitempty"`
+	Action        string            `json:"action"`
+	Target        map[string]string `json:"target,omitempty"`
+	Result        Result            `json:"result"`
+	Error         string            `json:"error,omitempty"`
+}
+
+// UsernameAtHost returns user@host of the entry, or just the user if the host is unknown.
+func (e *Entry) UsernameAtHost() string {
+	if e.Host == "" {
+		return e.User
+	}
+
+	return e.User + "@" + e.Host
+}
+
+// NewEntry returns a new entry for the action performed by the provided user (possibly 'username@hostname').
+// Empty target values are omitted.
+func NewEntry(now time.Time, usernameAtHost, action string, target map[string]string, result Result, err error) *Entry {
+	e := &Entry{
+		Time:   now.UTC(),
+		Action: action,
+		Result: result,
+	}
+
+	e.User, e.Host, _ = strings.Cut(usernameAtHost, "@")
+
+	for k, v := range target {
+		if v == "" {
+			continue
+		}
+
+		if e.Target == nil {
+			e.Target = map[string]string{}
+		}
+
+		e.Target[k] = v
+	}
+
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	return e
+}
+
+// Log is an append-only audit log stored in a local file as JSON lines.
+//
+// Entries are written as they are recorded, while syncing the file to disk happens in the background,
+// so that concurrently recorded entries share a single sync.
+type Log struct {
+	mu   sync.Mutex
+	f    *os.File
+	path string
+
+	// +checklocks:mu
+	closed bool
+
+	syncRequests chan struct{} // signals that entries have been written since the last sync
+	syncDone     chan struct{} // closed when the background sync goroutine exits
+}
+
+// Open opens the audit log file for appending, creating it if it does not exist.
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) //nolint:gomnd
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open audit log")
+	}
+
+	if err := terminateLastEntry(f); err != nil {
+		f.Close() //nolint:errcheck
+		return nil, err
+	}
+
+	l := &Log{
+		f:            f,
+		path:         path,
+		syncRequests: make(chan struct{}, 1),
+		syncDone:     make(chan struct{}),
+	}
+
+	go l.syncLoop()
+
+	return l, nil
+}
+
+// terminateLastEntry appends a newline if the last entry is incomplete, so that it does not
+// corrupt the next recorded entry.
+func terminateLastEntry(f *os.File) error {
+	st, err := f.Stat()
+	if err != nil {
+		return errors.Wrap(err, "unable to stat audit log")
+	}
+
+	if st.Size() == 0 {
+		return nil
+	}
+
+	var last [1]byte
+
+	rf, err := os.Open(f.Name())
+	if err != nil {
+		return errors.Wrap(err, "unable to open audit log")
+	}
+
+	defer rf.Close() //nolint:errcheck
+
+	if _, err := rf.ReadAt(last[:], st.Size()-1); err != nil {
+		return errors.Wrap(err, "unable to read audit log")
+	}
+
+	if last[0] == '\n' {
+		return nil
+	}
+
+	_, err = f.WriteString("\n")
+
+	return errors.Wrap(err, "unable to write audit log")
+}
+
+// syncLoop syncs the file after entries are written, entries written while a sync is in progress
+// are synced together afterwards.
+func (l *Log) syncLoop() {
+	defer close(l.syncDone)
+
+	for range l.syncRequests {
+		if err := l.f.Sync(); err != nil {
+			log(context.Background()).Errorf("unable to sync audit log: %v", err)
+		}
+	}
+}
+
+// Path returns the path of the audit log file.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Enabled returns true if the log is enabled, which makes it possible to use nil log.
+func (l *Log) Enabled() bool {
+	return l != nil
+}
+
+// Record appends the provided entry to the log, failures are logged but otherwise ignored.
+// Recording to nil log does nothing.
+func (l *Log) Record(ctx context.Context, e *Entry) {
+	if l == nil {
+		return
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		log(ctx).Errorf("unable to serialize audit log entry: %v", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		log(ctx).Errorf("unable to write audit log entry: log is closed")
+		return
+	}
+
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		log(ctx).Errorf("unable to write audit log entry: %v", err)
+		return
+	}
+
+	// request sync unless one is already pending.
+	select {
+	case l.syncRequests <- struct{}{}:
+	default:
+	}
+}
+
+// Close syncs and closes the log.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil
+	}
+
+	l.closed = true
+
+	close(l.syncRequests)
+	<-l.syncDone
+
+	if err := l.f.Sync(); err != nil {
+		l.f.Close() //nolint:errcheck
+		return errors.Wrap(err, "unable to sync audit log")
+	}
+
+	return errors.Wrap(l.f.Close(), "unable to close audit log")
+}
+
+// Filter selects entries returned by Read.
+type Filter struct {
+	User   string    // 'username' or 'username@hostname'
+	Action string    // action name
+	Since  time.Time // only entries at or after this time
+	Limit  int       // only the most recent entries, 0 means no limit
+}
+
+func (f Filter) matches(e *Entry) bool {
+	if f.User != "" && f.User != e.User && f.User != e.UsernameAtHost() {
+		return false
+	}
+
+	if f.Action != "" && f.Action != e.Action {
+		return false
+	}
+
+	return f.Since.IsZero() || !e.Time.Before(f.Since)
+}
+
+// Read returns entries matching the provided filter, oldest first, along with the number of malformed
+// entries that were skipped, such as a partially written last entry.
+func (l *Log) Read(f Filter) (entries []*Entry, malformed int, err error) {
+	rf, err := os.Open(l.path)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "unable to open audit log")
+	}
+
+	defer rf.Close() //nolint:errcheck
+
+	s := bufio.NewScanner(rf)
+	s.Buffer(nil, maxEntryLength)
+
+	for s.Scan() {
+		e := &Entry{}
+		if err := json.Unmarshal(s.Bytes(), e); err != nil {
+			malformed++
+			continue
+		}
+
+		if f.matches(e) {
+			entries = append(entries, e)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, 0, errors.Wrap(err, "error reading audit log")
+	}
+
+	if f.Limit > 0 && len(entries) > f.Limit {
+		entries = entries[len(entries)-f.Limit:]
+	}
+
+	return entries, malformed, nil
+}
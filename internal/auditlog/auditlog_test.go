@@ -0,0 +1,111 @@
+package auditlog_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/auditlog"
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+func TestAuditLog(t *testing.T) {
+	ctx := testlogging.Context(t)
+	fname := filepath.Join(t.TempDir(), "audit.log")
+	t0 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	l, err := auditlog.Open(fname)
+	require.NoError(t, err)
+
+	l.Record(ctx, auditlog.NewEntry(t0, "alice@laptop", auditlog.ActionLogin, nil, auditlog.ResultSuccess, nil))
+	l.Record(ctx, auditlog.NewEntry(t0.Add(time.Minute), "alice@laptop", auditlog.ActionPolicySet, map[string]string{
+		"username": "alice",
+		"hostname": "laptop",
+		"path":     "",
+	}, auditlog.ResultSuccess, nil))
+	l.Record(ctx, auditlog.NewEntry(t0.Add(2*time.Minute), "ui-user", auditlog.ActionRestore, nil, auditlog.ResultFailure, errors.New("some error")))
+	require.NoError(t, l.Close())
+
+	// reopening appends to the existing log.
+	l, err = auditlog.Open(fname)
+	require.NoError(t, err)
+
+	defer l.Close() //nolint:errcheck
+
+	l.Record(ctx, auditlog.NewEntry(t0.Add(3*time.Minute), "bob@desktop", auditlog.ActionLogin, nil, auditlog.ResultDenied, nil))
+
+	all, malformed, err := l.Read(auditlog.Filter{})
+	require.NoError(t, err)
+	require.Zero(t, malformed)
+	require.Len(t, all, 4)
+
+	require.Equal(t, "alice", all[1].User)
+	require.Equal(t, "laptop", all[1].Host)
+	require.Equal(t, map[string]string{"username": "alice", "hostname": "laptop"}, all[1].Target)
+	require.Equal(t, "ui-user", all[2].UsernameAtHost())
+	require.Equal(t, "some error", all[2].Error)
+
+	entries, _, err := l.Read(auditlog.Filter{User: "alice"})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	entries, _, err = l.Read(auditlog.Filter{User: "bob@desktop", Action: auditlog.ActionLogin})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, auditlog.ResultDenied, entries[0].Result)
+
+	entries, _, err = l.Read(auditlog.Filter{Since: t0.Add(time.Minute), Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, auditlog.ActionRestore, entries[0].Action)
+
+	b, err := os.ReadFile(fname)
+	require.NoError(t, err)
+	require.Len(t, strings.Split(strings.TrimSpace(string(b)), "\n"), 4)
+}
+
+func TestAuditLog_SkipsMalformedEntries(t *testing.T) {
+	ctx := testlogging.Context(t)
+	fname := filepath.Join(t.TempDir(), "audit.log")
+	t0 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	l, err := auditlog.Open(fname)
+	require.NoError(t, err)
+
+	l.Record(ctx, auditlog.NewEntry(t0, "alice@laptop", auditlog.ActionLogin, nil, auditlog.ResultSuccess, nil))
+	require.NoError(t, l.Close())
+
+	// simulate entries damaged by a crash or by editing the file.
+	f, err := os.OpenFile(fname, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString("not json\n{\"time\":\"2024-01-02T03:")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	l, err = auditlog.Open(fname)
+	require.NoError(t, err)
+
+	defer l.Close() //nolint:errcheck
+
+	// entries recorded after an incomplete entry are readable.
+	l.Record(ctx, auditlog.NewEntry(t0.Add(time.Minute), "bob@desktop", auditlog.ActionLogin, nil, auditlog.ResultSuccess, nil))
+
+	entries, malformed, err := l.Read(auditlog.Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "bob", entries[1].User)
+	require.Equal(t, 2, malformed)
+}
+
+func TestAuditLog_Nil(t *testing.T) {
+	var l *auditlog.Log
+
+	require.False(t, l.Enabled())
+	l.Record(testlogging.Context(t), auditlog.NewEntry(time.Now(), "alice", auditlog.ActionLogin, nil, auditlog.ResultSuccess, nil))
+	require.NoError(t, l.Close())
+}
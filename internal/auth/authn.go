@@ -20,6 +20,41 @@ type Authenticator interface {
 	Refresh(ctx context.Context) error
 }
 
+// GroupAuthenticator is implemented by authenticators whose credentials also identify groups of the user.
+type GroupAuthenticator interface {
+	// AuthenticateWithGroups verifies the provided credentials and returns groups they identify.
+	AuthenticateWithGroups(ctx context.Context, rep repo.Repository, username, password string) (groups []string, ok bool)
+}
+
+// Authenticate verifies the provided credentials using the authenticator and returns groups of the user
+// identified by the credentials, if the authenticator supports them.
+func Authenticate(ctx context.Context, a Authenticator, rep repo.Repository, username, password string) (groups []string, ok bool) {
+	if ga, ok := a.(GroupAuthenticator); ok {
+		return ga.AuthenticateWithGroups(ctx, rep, username, password)
+	}
+
+	return nil, a.IsValid(ctx, rep, username, password)
+}
+
+type groupsContextKey struct{}
+
+// WithGroups returns a context which carries the groups of the authenticated user, which are taken into
+// account when authorizing the user.
+func WithGroups(ctx context.Context, groups []string) context.Context {
+	if len(groups) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, groupsContextKey{}, groups)
+}
+
+// GroupsFromContext returns the groups of the authenticated user stored in the context.
+func GroupsFromContext(ctx context.Context) []string {
+	g, _ := ctx.Value(groupsContextKey{}).([]string)
+
+	return g
+}
+
 type singleUserAuthenticator struct {
 	expectedUsernameBytes []byte
 	expectedPasswordBytes []byte
@@ -51,6 +86,16 @@ func (c combinedAuthenticator) IsValid(ctx context.Context, rep repo.Repository,
 	return false
 }
 
+func (c combinedAuthenticator) AuthenticateWithGroups(ctx context.Context, rep repo.Repository, username, password string) (groups []string, ok bool) {
+	for _, a := range c {
+		if groups, ok := Authenticate(ctx, a, rep, username, password); ok {
+			return groups, true
+		}
+	}
+
+	return nil, false
+}
+
 func (c combinedAuthenticator) Refresh(ctx context.Context) error {
 	for _, a := range c {
 		if err := a.Refresh(ctx); err != nil {
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ClientCertificateIdentitySource specifies which field of the client certificate holds the user identity.
+type ClientCertificateIdentitySource string
+
+// Supported sources of client certificate identity.
+const (
+	// ClientCertificateIdentityCommonName uses subject common name, which must be 'username@hostname'.
+	ClientCertificateIdentityCommonName ClientCertificateIdentitySource = "cn"
+
+	// ClientCertificateIdentityEmail uses the first e-mail address in subject alternative names.
+	ClientCertificateIdentityEmail ClientCertificateIdentitySource = "email"
+
+	// ClientCertificateIdentityURI uses the first 'kopia:username@hostname' URI in subject alternative names.
+	ClientCertificateIdentityURI ClientCertificateIdentitySource = "uri"
+)
+
+// SupportedClientCertificateIdentitySources lists supported sources of client certificate identity.
+//
+//nolint:gochecknoglobals
+var SupportedClientCertificateIdentitySources = []string{
+	string(ClientCertificateIdentityCommonName),
+	string(ClientCertificateIdentityEmail),
+	string(ClientCertificateIdentityURI),
+}
+
+const clientCertificateURIScheme = "kopia"
+
+// ErrNoClientCertificate is returned when the connection did not present a verified client certificate.
+var ErrNoClientCertificate = errors.Errorf("no verified client certificate")
+
+// ClientCertificateAuthenticator maps TLS client certificates issued by trusted certificate authorities
+// to 'username@hostname' identities. Certificate chains are verified during TLS handshake.
+type ClientCertificateAuthenticator struct {
+	source ClientCertificateIdentitySource
+}
+
+// NewClientCertificateAuthenticator returns an authenticator which takes user identity from the provided
+// field of client certificates.
+func NewClientCertificateAuthenticator(source ClientCertificateIdentitySource) (*ClientCertificateAuthenticator, error) {
+	if source == "" {
+		source = ClientCertificateIdentityCommonName
+	}
+
+	for _, s := range SupportedClientCertificateIdentitySources {
+		if string(source) == s {
+			return &ClientCertificateAuthenticator{source}, nil
+		}
+	}
+
+	return nil, errors.Errorf("unsupported client certificate identity source %q, must be one of: %v", source, strings.Join(SupportedClientCertificateIdentitySources, ", "))
+}
+
+// HasClientCertificate determines whether the client has presented a certificate on the provided connection.
+func HasClientCertificate(cs *tls.ConnectionState) bool {
+	return cs != nil && len(cs.PeerCertificates) > 0
+}
+
+// Identity returns the 'username@hostname' identity of the verified client certificate presented on the provided connection.
+func (a *ClientCertificateAuthenticator) Identity(cs *tls.ConnectionState) (string, error) {
+	if cs == nil || len(cs.VerifiedChains) == 0 || len(cs.VerifiedChains[0]) == 0 {
+		return "", ErrNoClientCertificate
+	}
+
+	id := a.identityFromCertificate(cs.VerifiedChains[0][0])
+
+	if parts := strings.Split(id, "@"); len(parts) != 2 || parts[0] == "" || parts[1] == "" { //nolint:gomnd
+		return "", errors.Errorf("client certificate does not have a valid %v identity, must be 'username@hostname'", a.source)
+	}
+
+	return id, nil
+}
+
+func (a *ClientCertificateAuthenticator) identityFromCertificate(c *x509.Certificate) string {
+	switch a.source {
+	case ClientCertificateIdentityEmail:
+		if len(c.EmailAddresses) > 0 {
+			return c.EmailAddresses[0]
+		}
+
+	case ClientCertificateIdentityURI:
+		for _, u := range c.URIs {
+			if u.Scheme == clientCertificateURIScheme {
+				return u.Opaque
+			}
+		}
+
+	case ClientCertificateIdentityCommonName:
+		return c.Subject.CommonName
+	}
+
+	return ""
+}
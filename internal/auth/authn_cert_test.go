@@ -0,0 +1,76 @@
+package auth_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/auth"
+)
+
+func TestClientCertificateAuthenticator(t *testing.T) {
+	cert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "Alice@Laptop"},
+		EmailAddresses: []string{"bob@example.com"},
+		URIs:           []*url.URL{{Scheme: "https", Host: "example.com"}, {Scheme: "kopia", Opaque: "carol@desktop"}},
+	}
+
+	verified := &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+
+	unverified := &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+	}
+
+	cases := []struct {
+		source auth.ClientCertificateIdentitySource
+		want   string
+	}{
+		{"", "Alice@Laptop"},
+		{auth.ClientCertificateIdentityCommonName, "Alice@Laptop"},
+		{auth.ClientCertificateIdentityEmail, "bob@example.com"},
+		{auth.ClientCertificateIdentityURI, "carol@desktop"},
+	}
+
+	for _, tc := range cases {
+		a, err := auth.NewClientCertificateAuthenticator(tc.source)
+		require.NoError(t, err)
+
+		id, err := a.Identity(verified)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, id)
+
+		_, err = a.Identity(unverified)
+		require.ErrorIs(t, err, auth.ErrNoClientCertificate)
+
+		_, err = a.Identity(nil)
+		require.ErrorIs(t, err, auth.ErrNoClientCertificate)
+	}
+
+	require.True(t, auth.HasClientCertificate(unverified))
+	require.False(t, auth.HasClientCertificate(&tls.ConnectionState{}))
+	require.False(t, auth.HasClientCertificate(nil))
+
+	_, err := auth.NewClientCertificateAuthenticator("no-such-source")
+	require.Error(t, err)
+
+	// certificates without valid identity are rejected.
+	a, err := auth.NewClientCertificateAuthenticator(auth.ClientCertificateIdentityCommonName)
+	require.NoError(t, err)
+
+	for _, cn := range []string{"", "alice", "alice@", "@laptop", "a@b@c"} {
+		c := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+
+		_, err := a.Identity(&tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{c},
+			VerifiedChains:   [][]*x509.Certificate{{c}},
+		})
+		require.Error(t, err, cn)
+	}
+}
@@ -0,0 +1,556 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/repo"
+)
+
+const (
+	defaultOIDCUsernameClaim     = "sub"
+	defaultOIDCGroupsClaim       = "groups"
+	defaultOIDCHostname          = "oidc"
+	defaultOIDCKeysCacheDuration = time.Hour
+
+	// minimum time between fetches of the key set caused by tokens signed with unknown keys.
+	minOIDCKeysRefetchInterval = 10 * time.Second
+
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+)
+
+//nolint:gochecknoglobals
+var (
+	defaultOIDCScopes = []string{"openid", "profile", "email"}
+
+	supportedOIDCSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+)
+
+// OIDCOptions specifies parameters of the OpenID Connect authenticator.
+type OIDCOptions struct {
+	Issuer       string   // URL of the issuer, used for discovery and validated against 'iss' claim
+	ClientID     string   // client ID registered with the issuer, also accepted as token audience
+	ClientSecret string   // client secret, optional for public clients
+	Scopes       []string // scopes requested during authorization code flow
+	Audiences    []string // additional audiences accepted in bearer tokens
+
+	UsernameClaim   string // claim holding the username, defaults to 'sub'
+	HostnameClaim   string // claim holding the hostname, optional
+	DefaultHostname string // hostname used when HostnameClaim is not set or missing, defaults to 'oidc'
+	GroupsClaim     string // claim holding the list of groups, defaults to 'groups'
+
+	KeysCacheDuration time.Duration // how long to cache issuer signing keys, defaults to 1 hour
+	HTTPClient        *http.Client
+}
+
+// OIDCIdentity represents the identity established from a verified token.
+type OIDCIdentity struct {
+	UsernameAtHost string
+	Groups         []string
+	Expiry         time.Time
+}
+
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCAuthenticator authenticates users based on tokens issued by an OpenID Connect provider.
+// It verifies bearer tokens (also accepted as passwords) and implements the authorization code flow.
+type OIDCAuthenticator struct {
+	opt  OIDCOptions
+	meta oidcProviderMetadata
+
+	// fetchMutex serializes fetches of the key set, which happen without holding mu.
+	fetchMutex sync.Mutex
+
+	mu sync.Mutex
+	// +checklocks:mu
+	keys map[string]interface{}
+	// +checklocks:mu
+	keysFetchTime time.Time
+	// +checklocks:mu
+	keysStale bool
+}
+
+// NewOIDCAuthenticator returns an authenticator that accepts tokens issued by the provided OpenID Connect issuer.
+func NewOIDCAuthenticator(ctx context.Context, opt OIDCOptions) (*OIDCAuthenticator, error) {
+	if opt.Issuer == "" {
+		return nil, errors.Errorf("missing issuer")
+	}
+
+	if opt.ClientID == "" {
+		return nil, errors.Errorf("missing client ID")
+	}
+
+	if opt.UsernameClaim == "" {
+		opt.UsernameClaim = defaultOIDCUsernameClaim
+	}
+
+	if opt.GroupsClaim == "" {
+		opt.GroupsClaim = defaultOIDCGroupsClaim
+	}
+
+	if opt.DefaultHostname == "" {
+		opt.DefaultHostname = defaultOIDCHostname
+	}
+
+	if opt.KeysCacheDuration <= 0 {
+		opt.KeysCacheDuration = defaultOIDCKeysCacheDuration
+	}
+
+	if len(opt.Scopes) == 0 {
+		opt.Scopes = defaultOIDCScopes
+	}
+
+	if opt.HTTPClient == nil {
+		opt.HTTPClient = http.DefaultClient
+	}
+
+	a := &OIDCAuthenticator{
+		opt: opt,
+	}
+
+	if err := a.getJSON(ctx, strings.TrimSuffix(opt.Issuer, "/")+oidcDiscoveryPath, &a.meta); err != nil {
+		return nil, errors.Wrap(err, "unable to discover OpenID Connect provider configuration")
+	}
+
+	if strings.TrimSuffix(a.meta.Issuer, "/") != strings.TrimSuffix(opt.Issuer, "/") {
+		return nil, errors.Errorf("issuer mismatch: %q, expected %q", a.meta.Issuer, opt.Issuer)
+	}
+
+	if a.meta.JWKSURI == "" {
+		return nil, errors.Errorf("provider configuration does not include jwks_uri")
+	}
+
+	return a, nil
+}
+
+// IsValid implements Authenticator by treating the password as a token which must identify the provided user.
+func (a *OIDCAuthenticator) IsValid(ctx context.Context, rep repo.Repository, username, password string) bool {
+	_, ok := a.AuthenticateWithGroups(ctx, rep, username, password)
+
+	return ok
+}
+
+// AuthenticateWithGroups implements GroupAuthenticator by treating the password as a token which must
+// identify the provided user and returns the groups from the token.
+func (a *OIDCAuthenticator) AuthenticateWithGroups(ctx context.Context, _ repo.Repository, username, password string) (groups []string, ok bool) {
+	if strings.Count(password, ".") != 2 { //nolint:gomnd
+		// not a JWT, don't bother.
+		return nil, false
+	}
+
+	id, err := a.VerifyToken(ctx, password)
+	if err != nil {
+		log(ctx).Debugf("invalid OpenID Connect token for %v: %v", username, err)
+		return nil, false
+	}
+
+	if id.UsernameAtHost != username {
+		return nil, false
+	}
+
+	return id.Groups, true
+}
+
+// Refresh ensures the issuer signing keys are fetched again on next use.
+func (a *OIDCAuthenticator) Refresh(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.keysStale = true
+
+	return nil
+}
+
+// VerifyToken verifies the signature, issuer, audience and expiration of the provided token
+// and returns the identity it represents.
+func (a *OIDCAuthenticator) VerifyToken(ctx context.Context, rawToken string) (*OIDCIdentity, error) {
+	id, _, err := a.verifyToken(ctx, rawToken)
+
+	return id, err
+}
+
+func (a *OIDCAuthenticator) verifyToken(ctx context.Context, rawToken string) (*OIDCIdentity, jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+
+	if _, err := jwt.NewParser(jwt.WithValidMethods(supportedOIDCSigningMethods)).ParseWithClaims(rawToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		return a.verificationKey(ctx, kid)
+	}); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid token")
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, nil, errors.Errorf("token does not expire")
+	}
+
+	if !claims.VerifyIssuer(a.meta.Issuer, true) {
+		return nil, nil, errors.Errorf("unexpected token issuer")
+	}
+
+	if !a.hasValidAudience(claims) {
+		return nil, nil, errors.Errorf("unexpected token audience")
+	}
+
+	id, err := a.identityFromClaims(claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return id, claims, nil
+}
+
+func (a *OIDCAuthenticator) hasValidAudience(claims jwt.MapClaims) bool {
+	for _, aud := range append([]string{a.opt.ClientID}, a.opt.Audiences...) {
+		if claims.VerifyAudience(aud, true) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// identityFromClaims returns the identity of the token. Unless the hostname claim is configured,
+// all users are assigned the default hostname, so they can't be confused with other repository users.
+func (a *OIDCAuthenticator) identityFromClaims(claims jwt.MapClaims) (*OIDCIdentity, error) {
+	username, _ := claims[a.opt.UsernameClaim].(string)
+	if username == "" {
+		return nil, errors.Errorf("token does not have %q claim", a.opt.UsernameClaim)
+	}
+
+	// e-mail addresses are only trusted when verified by the issuer.
+	if a.opt.UsernameClaim == "email" {
+		if verified, _ := claims["email_verified"].(bool); !verified {
+			return nil, errors.Errorf("e-mail address %q is not verified", username)
+		}
+	}
+
+	hostname := a.opt.DefaultHostname
+
+	if a.opt.HostnameClaim != "" {
+		if h, _ := claims[a.opt.HostnameClaim].(string); h != "" {
+			hostname = h
+		}
+	}
+
+	// claims are escaped, so that a value which includes '@', such as an e-mail address,
+	// can't be used to impersonate users outside of the hostname assigned to OpenID Connect users.
+	usernameAtHost := url.QueryEscape(username) + "@" + url.QueryEscape(hostname)
+
+	id := &OIDCIdentity{
+		UsernameAtHost: usernameAtHost,
+	}
+
+	switch g := claims[a.opt.GroupsClaim].(type) {
+	case string:
+		id.Groups = []string{g}
+
+	case []interface{}:
+		for _, v := range g {
+			if s, ok := v.(string); ok {
+				id.Groups = append(id.Groups, s)
+			}
+		}
+	}
+
+	if exp, ok := claims["exp"].(float64); ok {
+		id.Expiry = time.Unix(int64(exp), 0)
+	}
+
+	return id, nil
+}
+
+// AuthCodeURL returns the URL of the issuer login page which redirects back to redirectURL
+// with the authorization code, using PKCE challenge derived from the provided code verifier.
+func (a *OIDCAuthenticator) AuthCodeURL(redirectURL, state, nonce, codeVerifier string) string {
+	challenge := sha256.Sum256([]byte(codeVerifier))
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {a.opt.ClientID},
+		"redirect_uri":          {redirectURL},
+		"scope":                 {strings.Join(a.opt.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(a.meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return a.meta.AuthorizationEndpoint + sep + q.Encode()
+}
+
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange exchanges the authorization code for tokens and returns the identity from the verified ID token.
+func (a *OIDCAuthenticator) Exchange(ctx context.Context, code, codeVerifier, redirectURL, nonce string) (*OIDCIdentity, error) {
+	if a.meta.TokenEndpoint == "" {
+		return nil, errors.Errorf("provider configuration does not include token_endpoint")
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"code_verifier": {codeVerifier},
+		"client_id":     {a.opt.ClientID},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create token request")
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if a.opt.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(a.opt.ClientID), url.QueryEscape(a.opt.ClientSecret))
+	}
+
+	resp, err := a.opt.HTTPClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "token request failed")
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	var tr oidcTokenResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return nil, errors.Wrapf(err, "unable to decode token response (HTTP %v)", resp.StatusCode)
+	}
+
+	if tr.Error != "" {
+		return nil, errors.Errorf("token request failed: %v %v", tr.Error, tr.ErrorDescription)
+	}
+
+	if resp.StatusCode != http.StatusOK || tr.IDToken == "" {
+		return nil, errors.Errorf("token response does not include ID token (HTTP %v)", resp.StatusCode)
+	}
+
+	id, claims, err := a.verifyToken(ctx, tr.IDToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid ID token")
+	}
+
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, errors.Errorf("ID token nonce mismatch")
+	}
+
+	return id, nil
+}
+
+func (a *OIDCAuthenticator) verificationKey(ctx context.Context, kid string) (interface{}, error) {
+	if k, fetch := a.cachedVerificationKey(kid, clock.Now()); !fetch {
+		return keyOrNotFound(k, kid)
+	}
+
+	a.fetchMutex.Lock()
+	defer a.fetchMutex.Unlock()
+
+	// the keys may have been fetched while waiting for the mutex.
+	now := clock.Now()
+
+	if k, fetch := a.cachedVerificationKey(kid, now); !fetch {
+		return keyOrNotFound(k, kid)
+	}
+
+	keys, err := a.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.keys = keys
+	a.keysFetchTime = now
+	a.keysStale = false
+
+	return keyOrNotFound(a.findKeyLocked(kid), kid)
+}
+
+// cachedVerificationKey returns the cached key with the provided ID, or indicates that the key set
+// should be fetched, because it's stale or the issuer may have rotated its keys.
+func (a *OIDCAuthenticator) cachedVerificationKey(kid string, now time.Time) (key interface{}, fetch bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.keys == nil || a.keysStale || now.After(a.keysFetchTime.Add(a.opt.KeysCacheDuration)) {
+		return nil, true
+	}
+
+	if k := a.findKeyLocked(kid); k != nil {
+		return k, false
+	}
+
+	// fetch keys signed with unknown keys again, but not too often.
+	return nil, now.After(a.keysFetchTime.Add(minOIDCKeysRefetchInterval))
+}
+
+func keyOrNotFound(k interface{}, kid string) (interface{}, error) {
+	if k == nil {
+		return nil, errors.Errorf("signing key %q not found", kid)
+	}
+
+	return k, nil
+}
+
+// +checklocks:a.mu
+func (a *OIDCAuthenticator) findKeyLocked(kid string) interface{} {
+	if kid == "" && len(a.keys) == 1 {
+		for _, k := range a.keys {
+			return k
+		}
+	}
+
+	return a.keys[kid]
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (a *OIDCAuthenticator) fetchKeys(ctx context.Context) (map[string]interface{}, error) {
+	var ks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+
+	if err := a.getJSON(ctx, a.meta.JWKSURI, &ks); err != nil {
+		return nil, errors.Wrap(err, "unable to fetch signing keys")
+	}
+
+	keys := map[string]interface{}{}
+
+	for _, k := range ks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		pk, err := k.publicKey()
+		if err != nil {
+			log(ctx).Debugf("ignoring signing key %q: %v", k.Kid, err)
+			continue
+		}
+
+		keys[k.Kid] = pk
+	}
+
+	return keys, nil
+}
+
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, errors.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid key parameter")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (a *OIDCAuthenticator) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return errors.Wrap(err, "unable to create request")
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := a.opt.HTTPClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get %v", u)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unable to get %v: HTTP %v", u, resp.StatusCode)
+	}
+
+	return errors.Wrapf(json.NewDecoder(resp.Body).Decode(v), "unable to decode %v", u)
+}
+
+var (
+	_ Authenticator      = (*OIDCAuthenticator)(nil)
+	_ GroupAuthenticator = (*OIDCAuthenticator)(nil)
+)
@@ -0,0 +1,256 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/auth"
+	"github.com/kopia/kopia/internal/oidctesting"
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+func newOIDCAuthenticator(t *testing.T, iss *oidctesting.Issuer, modify func(o *auth.OIDCOptions)) *auth.OIDCAuthenticator {
+	t.Helper()
+
+	opt := auth.OIDCOptions{
+		Issuer:       iss.URL,
+		ClientID:     oidctesting.ClientID,
+		ClientSecret: oidctesting.ClientSecret,
+	}
+
+	if modify != nil {
+		modify(&opt)
+	}
+
+	a, err := auth.NewOIDCAuthenticator(testlogging.Context(t), opt)
+	require.NoError(t, err)
+
+	return a
+}
+
+func TestOIDCAuthenticator_VerifyToken(t *testing.T) {
+	ctx := testlogging.Context(t)
+	iss := oidctesting.NewIssuer(t)
+	a := newOIDCAuthenticator(t, iss, func(o *auth.OIDCOptions) {
+		o.Audiences = []string{"kopia-api"}
+	})
+
+	id, err := a.VerifyToken(ctx, iss.Token(t, jwt.MapClaims{
+		"sub":    "alice",
+		"groups": []string{"admins", "backup"},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "alice@oidc", id.UsernameAtHost)
+	require.Equal(t, []string{"admins", "backup"}, id.Groups)
+
+	// usernames which include hostname can't impersonate users with other hostnames.
+	id, err = a.VerifyToken(ctx, iss.Token(t, jwt.MapClaims{"sub": "bob@laptop", "aud": "kopia-api"}))
+	require.NoError(t, err)
+	require.Equal(t, "bob%40laptop@oidc", id.UsernameAtHost)
+	require.Empty(t, id.Groups)
+
+	// preferred_username is not used by default, since it's neither unique nor stable.
+	id, err = a.VerifyToken(ctx, iss.Token(t, jwt.MapClaims{"sub": "1234", "preferred_username": "alice"}))
+	require.NoError(t, err)
+	require.Equal(t, "1234@oidc", id.UsernameAtHost)
+
+	cases := map[string]jwt.MapClaims{
+		"missing username": {},
+		"wrong audience":   {"sub": "alice", "aud": "other"},
+		"wrong issuer":     {"sub": "alice", "iss": "https://other"},
+		"expired":          {"sub": "alice", "exp": time.Now().Add(-time.Minute).Unix()},
+		"no expiration":    {"sub": "alice", "exp": nil},
+	}
+
+	for name, claims := range cases {
+		_, err := a.VerifyToken(ctx, iss.Token(t, claims))
+		require.Error(t, err, name)
+	}
+
+	// token signed by another issuer.
+	_, err = a.VerifyToken(ctx, oidctesting.NewIssuer(t).Token(t, jwt.MapClaims{"sub": "alice", "iss": iss.URL}))
+	require.Error(t, err)
+}
+
+func TestOIDCAuthenticator_Claims(t *testing.T) {
+	ctx := testlogging.Context(t)
+	iss := oidctesting.NewIssuer(t)
+	a := newOIDCAuthenticator(t, iss, func(o *auth.OIDCOptions) {
+		o.UsernameClaim = "sub"
+		o.HostnameClaim = "host"
+		o.DefaultHostname = "corp"
+		o.GroupsClaim = "roles"
+	})
+
+	id, err := a.VerifyToken(ctx, iss.Token(t, jwt.MapClaims{"sub": "alice", "host": "laptop", "roles": "admins"}))
+	require.NoError(t, err)
+	require.Equal(t, "alice@laptop", id.UsernameAtHost)
+	require.Equal(t, []string{"admins"}, id.Groups)
+
+	id, err = a.VerifyToken(ctx, iss.Token(t, jwt.MapClaims{"sub": "alice"}))
+	require.NoError(t, err)
+	require.Equal(t, "alice@corp", id.UsernameAtHost)
+
+	id, err = a.VerifyToken(ctx, iss.Token(t, jwt.MapClaims{"sub": "alice@example.com", "host": "x@laptop"}))
+	require.NoError(t, err)
+	require.Equal(t, "alice%40example.com@x%40laptop", id.UsernameAtHost)
+}
+
+func TestOIDCAuthenticator_EmailClaim(t *testing.T) {
+	ctx := testlogging.Context(t)
+	iss := oidctesting.NewIssuer(t)
+	a := newOIDCAuthenticator(t, iss, func(o *auth.OIDCOptions) {
+		o.UsernameClaim = "email"
+	})
+
+	id, err := a.VerifyToken(ctx, iss.Token(t, jwt.MapClaims{"email": "alice@example.com", "email_verified": true}))
+	require.NoError(t, err)
+	require.Equal(t, "alice%40example.com@oidc", id.UsernameAtHost)
+
+	_, err = a.VerifyToken(ctx, iss.Token(t, jwt.MapClaims{"email": "alice@example.com"}))
+	require.Error(t, err)
+
+	_, err = a.VerifyToken(ctx, iss.Token(t, jwt.MapClaims{"email": "alice@example.com", "email_verified": false}))
+	require.Error(t, err)
+}
+
+func TestOIDCAuthenticator_IsValid(t *testing.T) {
+	iss := oidctesting.NewIssuer(t)
+	a := newOIDCAuthenticator(t, iss, nil)
+
+	tok := iss.Token(t, jwt.MapClaims{"sub": "alice"})
+
+	verifyAuthenticator(t, a, "alice@oidc", tok, true)
+	verifyAuthenticator(t, a, "bob@oidc", tok, false)
+	verifyAuthenticator(t, a, "alice@oidc", "not-a-token", false)
+	verifyAuthenticator(t, a, "alice@oidc", "a.b.c", false)
+
+	verifyAuthenticator(t, auth.CombineAuthenticators(auth.AuthenticateSingleUser("bob@oidc", "pass"), a), "alice@oidc", tok, true)
+}
+
+func TestOIDCAuthenticator_Groups(t *testing.T) {
+	ctx := testlogging.Context(t)
+	iss := oidctesting.NewIssuer(t)
+	a := newOIDCAuthenticator(t, iss, nil)
+	combined := auth.CombineAuthenticators(auth.AuthenticateSingleUser("alice@oidc", "pass"), a)
+
+	tok := iss.Token(t, jwt.MapClaims{"sub": "alice", "groups": []string{"admins"}})
+
+	// groups are only returned along with the token which carries them.
+	groups, ok := auth.Authenticate(ctx, combined, nil, "alice@oidc", tok)
+	require.True(t, ok)
+	require.Equal(t, []string{"admins"}, groups)
+
+	groups, ok = auth.Authenticate(ctx, combined, nil, "alice@oidc", "pass")
+	require.True(t, ok)
+	require.Empty(t, groups)
+
+	_, ok = auth.Authenticate(ctx, combined, nil, "alice@oidc", "wrong")
+	require.False(t, ok)
+}
+
+func TestOIDCAuthenticator_KeysCache(t *testing.T) {
+	ctx := testlogging.Context(t)
+	iss := oidctesting.NewIssuer(t)
+	a := newOIDCAuthenticator(t, iss, nil)
+
+	for i := 0; i < 5; i++ {
+		_, err := a.VerifyToken(ctx, iss.Token(t, jwt.MapClaims{"sub": "alice"}))
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, 1, iss.KeysRequests())
+
+	// tokens signed with rotated key won't verify until the keys are refreshed.
+	iss.RotateKey(t)
+
+	_, err := a.VerifyToken(ctx, iss.Token(t, jwt.MapClaims{"sub": "alice"}))
+	require.Error(t, err)
+	require.Equal(t, 1, iss.KeysRequests())
+
+	require.NoError(t, a.Refresh(ctx))
+
+	_, err = a.VerifyToken(ctx, iss.Token(t, jwt.MapClaims{"sub": "alice"}))
+	require.NoError(t, err)
+	require.Equal(t, 2, iss.KeysRequests())
+}
+
+func TestOIDCAuthenticator_AuthorizationCodeFlow(t *testing.T) {
+	ctx := testlogging.Context(t)
+	iss := oidctesting.NewIssuer(t)
+	a := newOIDCAuthenticator(t, iss, nil)
+
+	iss.SetLoginClaims(jwt.MapClaims{
+		"sub":    "alice",
+		"groups": []string{"admins"},
+	})
+
+	const (
+		redirectURL = "https://kopia.example.com/api/v1/oidc/callback"
+		verifier    = "some-code-verifier-which-is-long-enough-0123456789"
+	)
+
+	cli := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := cli.Get(a.AuthCodeURL(redirectURL, "some-state", "some-nonce", verifier))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, "some-state", loc.Query().Get("state"))
+
+	code := loc.Query().Get("code")
+
+	// wrong nonce
+	_, err = a.Exchange(ctx, code, verifier, redirectURL, "other-nonce")
+	require.Error(t, err)
+
+	// codes can't be reused
+	_, err = a.Exchange(ctx, code, verifier, redirectURL, "some-nonce")
+	require.Error(t, err)
+
+	resp, err = cli.Get(a.AuthCodeURL(redirectURL, "some-state", "some-nonce", verifier))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	loc, err = url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	// wrong code verifier
+	_, err = a.Exchange(ctx, loc.Query().Get("code"), "wrong-verifier", redirectURL, "some-nonce")
+	require.Error(t, err)
+
+	resp, err = cli.Get(a.AuthCodeURL(redirectURL, "some-state", "some-nonce", verifier))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	loc, err = url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	id, err := a.Exchange(ctx, loc.Query().Get("code"), verifier, redirectURL, "some-nonce")
+	require.NoError(t, err)
+	require.Equal(t, "alice@oidc", id.UsernameAtHost)
+	require.Equal(t, []string{"admins"}, id.Groups)
+}
+
+func TestOIDCAuthenticator_InvalidIssuer(t *testing.T) {
+	ctx := testlogging.Context(t)
+	iss := oidctesting.NewIssuer(t)
+
+	_, err := auth.NewOIDCAuthenticator(ctx, auth.OIDCOptions{Issuer: iss.URL + "/other", ClientID: oidctesting.ClientID})
+	require.Error(t, err)
+
+	_, err = auth.NewOIDCAuthenticator(ctx, auth.OIDCOptions{Issuer: iss.URL})
+	require.ErrorContains(t, err, "missing client ID")
+}
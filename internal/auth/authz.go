@@ -26,6 +26,7 @@ type Authorizer interface {
 	AccessLevelRead   = acl.AccessLevelRead   // RO access
 	AccessLevelAppend = acl.AccessLevelAppend // RO + create new
 	AccessLevelFull   = acl.AccessLevelFull   // read/write/delete
+	AccessLevelAdmin  = acl.AccessLevelAdmin  // read/write/delete and server administration API
 )
 
 // AuthorizationInfo determines logged in user's access level.
@@ -97,10 +97,13 @@ type aclCache struct {
 	nextRefreshTime time.Time
 	// +checklocks:mu
 	aclEntries []*acl.Entry
+	// +checklocks:mu
+	groups map[string]*acl.Group
 }
 
 // Authorize returns authorization info based on ACLs stored in the repository falling back to legacy authorizer
-// if no ACL entries are defined.
+// if no ACL entries are defined. ACL entries granted to groups apply to groups stored in the context and
+// to groups defined in the repository.
 func (ac *aclCache) Authorize(ctx context.Context, rep repo.Repository, usernameAtHostname string) AuthorizationInfo {
 	ac.mu.Lock()
 	defer ac.mu.Unlock()
@@ -116,6 +119,7 @@ func (ac *aclCache) Authorize(ctx context.Context, rep repo.Repository, username
 	if rep == ac.lastRep {
 		// the server switched to another repository, discard cache.
 		ac.aclEntries = nil
+		ac.groups = nil
 		ac.lastRep = rep
 
 		// ensure ACL entries are reloaded below
@@ -132,13 +136,44 @@ func (ac *aclCache) Authorize(ctx context.Context, rep repo.Repository, username
 		} else {
 			ac.aclEntries = newMap
 		}
+
+		newGroups, err := acl.LoadGroupMap(ctx, rep, ac.groups)
+		if err != nil {
+			log(ctx).Errorf("unable to load groups: %v", err)
+		} else {
+			ac.groups = newGroups
+		}
 	}
 
 	if len(ac.aclEntries) == 0 {
 		return legacyAuthorizationInfo{usernameAtHostname}
 	}
 
-	return aclEntriesAuthorizer{acl.EntriesForUser(ac.aclEntries, u, h), u, h}
+	groups := mergeGroups(GroupsFromContext(ctx), acl.GroupsForUser(ac.groups, u, h))
+
+	return aclEntriesAuthorizer{acl.EntriesForUser(ac.aclEntries, u, h, groups...), u, h, groups}
+}
+
+// mergeGroups returns the union of the provided lists of group names.
+func mergeGroups(a, b []string) []string {
+	result := append([]string(nil), a...)
+
+	for _, g := range b {
+		found := false
+
+		for _, r := range result {
+			if r == g {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			result = append(result, g)
+		}
+	}
+
+	return result
 }
 
 func (ac *aclCache) Refresh(ctx context.Context) error {
@@ -155,14 +190,15 @@ type aclEntriesAuthorizer struct {
 	entries  []*acl.Entry
 	username string
 	hostname string
+	groups   []string
 }
 
 func (a aclEntriesAuthorizer) ContentAccessLevel() AccessLevel {
-	return acl.EffectivePermissions(a.username, a.hostname, ContentRule, a.entries)
+	return acl.EffectivePermissionsWithGroups(a.username, a.hostname, a.groups, ContentRule, a.entries)
 }
 
 func (a aclEntriesAuthorizer) ManifestAccessLevel(labels map[string]string) AccessLevel {
-	return acl.EffectivePermissions(a.username, a.hostname, labels, a.entries)
+	return acl.EffectivePermissionsWithGroups(a.username, a.hostname, a.groups, labels, a.entries)
 }
 
 // DefaultAuthorizer returns Authorizer that will fetch ACLs from the repository
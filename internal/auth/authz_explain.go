@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+// AccessExplanation describes how the access level of a user to a target was determined.
+type AccessExplanation struct {
+	User       string
+	Groups     []string
+	Target     map[string]string
+	ACLEnabled bool
+	Access     AccessLevel
+
+	// ACL entries which apply to the user and target, sorted by decreasing access level.
+	Entries []*acl.Entry
+}
+
+// ExplainAccess determines the access level of the user, who is a member of the provided external groups
+// (such as OpenID Connect groups) and groups stored in the repository, to the target
+// along with ACL entries that contributed to it.
+func ExplainAccess(ctx context.Context, rep repo.Repository, usernameAtHostname string, groups []string, target map[string]string) (*AccessExplanation, error) {
+	parts := strings.Split(usernameAtHostname, "@")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" { //nolint:gomnd
+		return nil, errors.Errorf("user must be 'username@hostname'")
+	}
+
+	if target[manifest.TypeLabelKey] == "" {
+		return nil, errors.Errorf("target must have a '%v' label", manifest.TypeLabelKey)
+	}
+
+	u, h := parts[0], parts[1]
+
+	entries, err := acl.LoadEntries(ctx, rep, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading ACL entries")
+	}
+
+	groupMap, err := acl.LoadGroupMap(ctx, rep, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading groups")
+	}
+
+	allGroups := mergeGroups(groups, acl.GroupsForUser(groupMap, u, h))
+	sort.Strings(allGroups)
+
+	ex := &AccessExplanation{
+		User:       usernameAtHostname,
+		Groups:     allGroups,
+		Target:     target,
+		ACLEnabled: len(entries) > 0,
+	}
+
+	if !ex.ACLEnabled {
+		ai := legacyAuthorizationInfo{usernameAtHostname}
+
+		if target[manifest.TypeLabelKey] == acl.ContentManifestType {
+			ex.Access = ai.ContentAccessLevel()
+		} else {
+			ex.Access = ai.ManifestAccessLevel(target)
+		}
+
+		return ex, nil
+	}
+
+	ex.Entries = acl.MatchingEntries(u, h, allGroups, target, entries)
+	ex.Access = AccessLevelNone
+
+	if len(ex.Entries) > 0 {
+		ex.Access = ex.Entries[0].Access
+	}
+
+	return ex, nil
+}
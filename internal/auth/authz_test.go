@@ -115,6 +115,38 @@ func TestDefaultAuthorizer_DefaultACLs(t *testing.T) {
 	verifyLegacyAuthorizer(ctx, t, env.Repository, auth.DefaultAuthorizer())
 }
 
+func TestDefaultAuthorizer_GroupACLs(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	for _, e := range auth.DefaultACLs {
+		require.NoError(t, acl.AddACL(ctx, env.RepositoryWriter, e))
+	}
+
+	require.NoError(t, acl.AddACL(ctx, env.RepositoryWriter, &acl.Entry{
+		User:   acl.GroupPrefix + "auditors",
+		Target: acl.TargetRule{"type": "snapshot"},
+		Access: acl.AccessLevelRead,
+	}))
+
+	a := auth.DefaultAuthorizer()
+
+	require.Equal(t, auth.AccessLevelNone, a.Authorize(ctx, env.RepositoryWriter, "alice@oidc").ManifestAccessLevel(fooAtBarSnapshot))
+	require.Equal(t, auth.AccessLevelNone, a.Authorize(auth.WithGroups(ctx, []string{"others"}), env.RepositoryWriter, "alice@oidc").ManifestAccessLevel(fooAtBarSnapshot))
+	require.Equal(t, auth.AccessLevelRead, a.Authorize(auth.WithGroups(ctx, []string{"auditors"}), env.RepositoryWriter, "alice@oidc").ManifestAccessLevel(fooAtBarSnapshot))
+
+	// groups stored in the repository.
+	require.NoError(t, acl.SetGroup(ctx, env.RepositoryWriter, &acl.Group{Name: "restorers", Members: []string{"*@office"}}))
+
+	_, err := acl.GrantRole(ctx, env.RepositoryWriter, acl.GroupPrefix+"restorers", acl.RoleRestoreOperator)
+	require.NoError(t, err)
+
+	require.NoError(t, a.Refresh(ctx))
+
+	require.Equal(t, auth.AccessLevelRead, a.Authorize(ctx, env.RepositoryWriter, "bob@office").ManifestAccessLevel(fooAtBarSnapshot))
+	require.Equal(t, auth.AccessLevelNone, a.Authorize(ctx, env.RepositoryWriter, "bob@home").ManifestAccessLevel(fooAtBarSnapshot))
+	require.Equal(t, auth.AccessLevelRead, a.Authorize(auth.WithGroups(ctx, []string{"auditors"}), env.RepositoryWriter, "bob@office").ManifestAccessLevel(fooAtBarSnapshot))
+}
+
 //nolint:thelper
 func verifyLegacyAuthorizer(ctx context.Context, t *testing.T, rep repo.Repository, authorizer auth.Authorizer) {
 	cases := []struct {
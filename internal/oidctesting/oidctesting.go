@@ -0,0 +1,268 @@
+// Package oidctesting provides a fake OpenID Connect issuer for testing.
+package oidctesting
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// Client credentials registered with the fake issuer.
+const (
+	ClientID     = "kopia-test-client"
+	ClientSecret = "kopia-test-secret"
+)
+
+const (
+	rsaKeyBits    = 2048
+	tokenValidity = time.Hour
+)
+
+type pendingCode struct {
+	claims        jwt.MapClaims
+	nonce         string
+	redirectURI   string
+	codeChallenge string
+}
+
+// Issuer is a fake OpenID Connect issuer which signs tokens with RSA keys, publishes them
+// and implements authorization code flow with PKCE, automatically approving all logins.
+type Issuer struct {
+	URL string
+
+	mu sync.Mutex
+	// +checklocks:mu
+	key *rsa.PrivateKey
+	// +checklocks:mu
+	keyID string
+	// +checklocks:mu
+	keysRequests int
+	// +checklocks:mu
+	loginClaims jwt.MapClaims
+	// +checklocks:mu
+	codes map[string]*pendingCode
+}
+
+// NewIssuer starts a fake issuer which is shut down when the test completes.
+func NewIssuer(t *testing.T) *Issuer {
+	t.Helper()
+
+	iss := &Issuer{
+		codes: map[string]*pendingCode{},
+	}
+
+	iss.RotateKey(t)
+
+	m := http.NewServeMux()
+	m.HandleFunc("/.well-known/openid-configuration", iss.handleDiscovery)
+	m.HandleFunc("/keys", iss.handleKeys)
+	m.HandleFunc("/authorize", iss.handleAuthorize)
+	m.HandleFunc("/token", iss.handleToken)
+
+	hs := httptest.NewServer(m)
+	t.Cleanup(hs.Close)
+
+	iss.URL = hs.URL
+
+	return iss
+}
+
+// RotateKey replaces the signing key of the issuer.
+func (iss *Issuer) RotateKey(t *testing.T) {
+	t.Helper()
+
+	k, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	require.NoError(t, err)
+
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	iss.key = k
+	iss.keyID = uuid.NewString()
+}
+
+// KeysRequests returns the number of times the issuer keys have been fetched.
+func (iss *Issuer) KeysRequests() int {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	return iss.keysRequests
+}
+
+// SetLoginClaims sets the claims of ID tokens issued to users logging in using authorization code flow.
+func (iss *Issuer) SetLoginClaims(claims jwt.MapClaims) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	iss.loginClaims = claims
+}
+
+// Token returns a token with the provided claims, which is issued by the issuer for the test client,
+// valid for one hour unless the claims specify otherwise.
+func (iss *Issuer) Token(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	c := jwt.MapClaims{
+		"iss": iss.URL,
+		"aud": ClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(tokenValidity).Unix(),
+	}
+
+	for k, v := range claims {
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+	}
+
+	s, err := iss.sign(c)
+	require.NoError(t, err)
+
+	return s
+}
+
+func (iss *Issuer) sign(claims jwt.MapClaims) (string, error) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = iss.keyID
+
+	//nolint:wrapcheck
+	return tok.SignedString(iss.key)
+}
+
+func (iss *Issuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{
+		"issuer":                 iss.URL,
+		"authorization_endpoint": iss.URL + "/authorize",
+		"token_endpoint":         iss.URL + "/token",
+		"jwks_uri":               iss.URL + "/keys",
+	})
+}
+
+func (iss *Issuer) handleKeys(w http.ResponseWriter, r *http.Request) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	iss.keysRequests++
+
+	writeJSON(w, map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kid": iss.keyID,
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(iss.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(iss.key.E)).Bytes()),
+			},
+		},
+	})
+}
+
+func (iss *Issuer) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if q.Get("client_id") != ClientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect URI", http.StatusBadRequest)
+		return
+	}
+
+	code := uuid.NewString()
+
+	iss.mu.Lock()
+	iss.codes[code] = &pendingCode{
+		claims:        iss.loginClaims,
+		nonce:         q.Get("nonce"),
+		redirectURI:   redirectURI.String(),
+		codeChallenge: q.Get("code_challenge"),
+	}
+	iss.mu.Unlock()
+
+	rq := redirectURI.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirectURI.RawQuery = rq.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (iss *Issuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if id, secret, ok := r.BasicAuth(); !ok || id != ClientID || secret != ClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJSON(w, map[string]string{"error": "invalid_client"})
+
+		return
+	}
+
+	code := r.PostFormValue("code")
+
+	iss.mu.Lock()
+	pc := iss.codes[code]
+	delete(iss.codes, code)
+	iss.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+
+	if pc == nil || r.PostFormValue("grant_type") != "authorization_code" ||
+		r.PostFormValue("redirect_uri") != pc.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != pc.codeChallenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   iss.URL,
+		"aud":   ClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(tokenValidity).Unix(),
+		"nonce": pc.nonce,
+	}
+
+	for k, v := range pc.claims {
+		claims[k] = v
+	}
+
+	idToken, err := iss.sign(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"access_token": uuid.NewString(),
+		"token_type":   "Bearer",
+		"expires_in":   int(tokenValidity.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/internal/auth"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+// aclManifestLabels are labels used to determine whether the user can read ACL entries.
+//
+//nolint:gochecknoglobals
+var aclManifestLabels = map[string]string{manifest.TypeLabelKey: acl.ManifestType}
+
+func toACLEntries(entries []*acl.Entry) []serverapi.ACLEntry {
+	result := []serverapi.ACLEntry{}
+
+	for _, e := range entries {
+		result = append(result, serverapi.ACLEntry{ID: e.ManifestID, Entry: e})
+	}
+
+	return result
+}
+
+func handleACLCheck(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	var req serverapi.ACLCheckRequest
+
+	if err := json.Unmarshal(rc.body, &req); err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "malformed request")
+	}
+
+	if req.User == "" {
+		req.User = rc.username
+	}
+
+	// users can always explain their own permissions, explaining permissions of others or supplying
+	// additional groups requires access to ACLs.
+	if (req.User != rc.username || len(req.Groups) > 0) && !hasManifestAccess(ctx, rc, aclManifestLabels, auth.AccessLevelRead) {
+		return nil, accessDeniedError()
+	}
+
+	groups := req.Groups
+
+	// permissions of the authenticated user are explained using the groups they authenticated with.
+	if req.User == rc.username {
+		groups = rc.groups
+	}
+
+	ex, err := auth.ExplainAccess(ctx, rc.rep, req.User, groups, req.Target)
+	if err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, err.Error())
+	}
+
+	return &serverapi.ACLCheckResponse{
+		User:       ex.User,
+		Groups:     ex.Groups,
+		Target:     ex.Target,
+		ACLEnabled: ex.ACLEnabled,
+		Access:     ex.Access,
+		Entries:    toACLEntries(ex.Entries),
+	}, nil
+}
+
+func handleACLLint(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	entries, err := acl.LoadEntries(ctx, rc.rep, nil)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	resp := &serverapi.ACLLintResponse{
+		Findings: []serverapi.ACLLintFinding{},
+	}
+
+	for _, f := range acl.Lint(entries) {
+		resp.Findings = append(resp.Findings, serverapi.ACLLintFinding{
+			Entry:   serverapi.ACLEntry{ID: f.Entry.ManifestID, Entry: f.Entry},
+			Kind:    f.Kind,
+			Message: f.Message,
+		})
+	}
+
+	return resp, nil
+}
+
+func requireACLReadAccess(ctx context.Context, rc requestContext) bool {
+	return hasManifestAccess(ctx, rc, aclManifestLabels, auth.AccessLevelRead)
+}
+
+func handleACLList(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	entries, err := acl.LoadEntries(ctx, rc.rep, nil)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	return &serverapi.ACLListResponse{
+		Entries: toACLEntries(entries),
+	}, nil
+}
+
+func parseAddACLRequest(rc requestContext) (*serverapi.AddACLRequest, *apiError) {
+	var req serverapi.AddACLRequest
+
+	if err := json.Unmarshal(rc.body, &req); err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "malformed request body")
+	}
+
+	return &req, nil
+}
+
+func handleACLAdd(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	req, aerr := parseAddACLRequest(rc)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	var added []*acl.Entry
+
+	if req.Role != "" {
+		if req.Target != nil || req.Access != 0 {
+			return nil, requestError(serverapi.ErrorMalformedRequest, "role cannot be combined with target or access")
+		}
+
+		r, err := acl.GetRole(req.Role)
+		if err != nil {
+			return nil, requestError(serverapi.ErrorMalformedRequest, err.Error())
+		}
+
+		added = r.Entries(req.User)
+	} else {
+		added = []*acl.Entry{{User: req.User, Target: req.Target, Access: req.Access}}
+	}
+
+	for _, e := range added {
+		if err := e.Validate(); err != nil {
+			return nil, requestError(serverapi.ErrorMalformedRequest, err.Error())
+		}
+	}
+
+	if aerr := modifyACLs(ctx, rc, "ACLAdd", func(ctx context.Context, w repo.RepositoryWriter) error {
+		for _, e := range added {
+			if err := acl.AddACL(ctx, w, e); err != nil {
+				return errors.Wrap(err, "error adding entry")
+			}
+		}
+
+		return nil
+	}); aerr != nil {
+		return nil, aerr
+	}
+
+	return &serverapi.ACLListResponse{
+		Entries: toACLEntries(added),
+	}, nil
+}
+
+// findACLEntry returns the ACL entry with the ID provided in the URL.
+func findACLEntry(ctx context.Context, rc requestContext) (*acl.Entry, *apiError) {
+	entries, err := acl.LoadEntries(ctx, rc.rep, nil)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	for _, e := range entries {
+		if string(e.ManifestID) == rc.muxVar("id") {
+			return e, nil
+		}
+	}
+
+	return nil, notFoundError("ACL entry not found")
+}
+
+// handleACLUpdate replaces the ACL entry with a new one, which gets a new ID.
+func handleACLUpdate(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	old, aerr := findACLEntry(ctx, rc)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	req, aerr := parseAddACLRequest(rc)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if req.Role != "" {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "role cannot be used when updating an entry")
+	}
+
+	e := &acl.Entry{User: req.User, Target: req.Target, Access: req.Access}
+	if err := e.Validate(); err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, err.Error())
+	}
+
+	if aerr := modifyACLs(ctx, rc, "ACLUpdate", func(ctx context.Context, w repo.RepositoryWriter) error {
+		if err := acl.AddACL(ctx, w, e); err != nil {
+			return errors.Wrap(err, "error adding entry")
+		}
+
+		return errors.Wrap(w.DeleteManifest(ctx, old.ManifestID), "error deleting old entry")
+	}); aerr != nil {
+		return nil, aerr
+	}
+
+	return &serverapi.ACLEntry{ID: e.ManifestID, Entry: e}, nil
+}
+
+func handleACLDelete(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	e, aerr := findACLEntry(ctx, rc)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if aerr := modifyACLs(ctx, rc, "ACLDelete", func(ctx context.Context, w repo.RepositoryWriter) error {
+		return errors.Wrap(w.DeleteManifest(ctx, e.ManifestID), "error deleting entry")
+	}); aerr != nil {
+		return nil, aerr
+	}
+
+	return &serverapi.Empty{}, nil
+}
+
+func modifyACLs(ctx context.Context, rc requestContext, purpose string, cb func(ctx context.Context, w repo.RepositoryWriter) error) *apiError {
+	if _, ok := rc.rep.(repo.RepositoryWriter); !ok {
+		return repositoryNotWritableError()
+	}
+
+	if err := repo.WriteSession(ctx, rc.rep, repo.WriteSessionOptions{Purpose: purpose}, cb); err != nil {
+		return internalServerError(err)
+	}
+
+	// make sure new entries take effect immediately.
+	_ = rc.srv.Refresh(ctx)
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/auth"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/server"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/servertesting"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestACLCheckAndLint(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	for _, e := range auth.DefaultACLs {
+		require.NoError(t, acl.AddACL(ctx, env.RepositoryWriter, e))
+	}
+
+	_, err := acl.GrantRole(ctx, env.RepositoryWriter, servertesting.TestUsername+"@"+servertesting.TestHostname, acl.RoleAuditor)
+	require.NoError(t, err)
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	srvInfo := servertesting.StartServerWithOptions(t, env, false, func(o *server.Options) {
+		o.Authorizer = auth.DefaultAuthorizer()
+		o.Authenticator = auth.CombineAuthenticators(o.Authenticator, auth.AuthenticateSingleUser("alice@laptop", "alice-pass"))
+	})
+
+	newClient := func(username, password string) *apiclient.KopiaAPIClient {
+		cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+			BaseURL:  srvInfo.BaseURL,
+			Username: username,
+			Password: password,
+		})
+		require.NoError(t, err)
+
+		return cli
+	}
+
+	alice := newClient("alice@laptop", "alice-pass")
+	auditor := newClient(servertesting.TestUsername+"@"+servertesting.TestHostname, servertesting.TestPassword)
+
+	aliceSnapshots := map[string]string{
+		manifest.TypeLabelKey:  snapshot.ManifestType,
+		snapshot.UsernameLabel: "alice",
+		snapshot.HostnameLabel: "laptop",
+	}
+
+	// users can check their own permissions.
+	resp, err := serverapi.CheckACL(ctx, alice, &serverapi.ACLCheckRequest{Target: aliceSnapshots})
+	require.NoError(t, err)
+	require.Equal(t, "alice@laptop", resp.User)
+	require.True(t, resp.ACLEnabled)
+	require.Equal(t, acl.AccessLevelFull, resp.Access)
+	require.Len(t, resp.Entries, 1)
+	require.NotEmpty(t, resp.Entries[0].ID)
+
+	// but not permissions of others.
+	_, err = serverapi.CheckACL(ctx, alice, &serverapi.ACLCheckRequest{User: "foo@bar", Target: aliceSnapshots})
+	require.Error(t, err)
+
+	// nor the effect of additional groups.
+	_, err = serverapi.CheckACL(ctx, alice, &serverapi.ACLCheckRequest{Groups: []string{"admins"}, Target: aliceSnapshots})
+	require.Error(t, err)
+
+	// additional groups of other users are used when explaining their permissions.
+	resp, err = serverapi.CheckACL(ctx, auditor, &serverapi.ACLCheckRequest{User: "alice@laptop", Groups: []string{"admins"}, Target: aliceSnapshots})
+	require.NoError(t, err)
+	require.Contains(t, resp.Groups, "admins")
+
+	// while the authenticated user is always explained using their own groups.
+	resp, err = serverapi.CheckACL(ctx, auditor, &serverapi.ACLCheckRequest{Groups: []string{"admins"}, Target: aliceSnapshots})
+	require.NoError(t, err)
+	require.NotContains(t, resp.Groups, "admins")
+
+	resp, err = serverapi.CheckACL(ctx, auditor, &serverapi.ACLCheckRequest{User: "alice@laptop", Target: map[string]string{
+		manifest.TypeLabelKey: acl.ContentManifestType,
+	}})
+	require.NoError(t, err)
+	require.Equal(t, acl.AccessLevelAppend, resp.Access)
+
+	resp, err = serverapi.CheckACL(ctx, auditor, &serverapi.ACLCheckRequest{Target: aliceSnapshots})
+	require.NoError(t, err)
+	require.Equal(t, acl.AccessLevelRead, resp.Access)
+	require.Len(t, resp.Entries, 1)
+	require.Equal(t, acl.RoleAuditor, resp.Entries[0].Role)
+
+	_, err = serverapi.CheckACL(ctx, auditor, &serverapi.ACLCheckRequest{Target: map[string]string{}})
+	require.Error(t, err)
+
+	lint, err := serverapi.LintACL(ctx, auditor)
+	require.NoError(t, err)
+	require.Empty(t, lint.Findings)
+
+	_, err = serverapi.LintACL(ctx, alice)
+	require.Error(t, err)
+}
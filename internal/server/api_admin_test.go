@@ -0,0 +1,211 @@
+package server_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/apitoken"
+	"github.com/kopia/kopia/internal/auth"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/server"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/servertesting"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+const (
+	testAdminUser     = "admin@corp"
+	testAdminPassword = "admin-pass"
+)
+
+// startAdminAPIServer starts the server and returns a factory of clients using basic authentication
+// and a client using an API token with the admin scope.
+func startAdminAPIServer(t *testing.T) (func(username, password string) *apiclient.KopiaAPIClient, *apiclient.KopiaAPIClient) {
+	t.Helper()
+
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	for _, e := range auth.DefaultACLs {
+		require.NoError(t, acl.AddACL(ctx, env.RepositoryWriter, e))
+	}
+
+	_, err := acl.GrantRole(ctx, env.RepositoryWriter, testAdminUser, acl.RoleAdmin)
+	require.NoError(t, err)
+
+	_, adminToken, err := apitoken.Create(ctx, env.RepositoryWriter, "admin", []apitoken.Scope{apitoken.ScopeAdmin}, time.Time{})
+	require.NoError(t, err)
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	srvInfo := servertesting.StartServerWithOptions(t, env, false, func(o *server.Options) {
+		o.Authorizer = auth.DefaultAuthorizer()
+		o.Authenticator = auth.CombineAuthenticators(
+			o.Authenticator,
+			auth.AuthenticateSingleUser(testAdminUser, testAdminPassword),
+			auth.AuthenticateRepositoryUsers(),
+		)
+	})
+
+	adminCli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:     srvInfo.BaseURL,
+		BearerToken: adminToken,
+	})
+	require.NoError(t, err)
+
+	return func(username, password string) *apiclient.KopiaAPIClient {
+		cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+			BaseURL:  srvInfo.BaseURL,
+			Username: username,
+			Password: password,
+		})
+		require.NoError(t, err)
+
+		return cli
+	}, adminCli
+}
+
+func canAccessRepository(t *testing.T, cli *apiclient.KopiaAPIClient) bool {
+	t.Helper()
+
+	var resp interface{}
+
+	return cli.Get(testlogging.Context(t), "repo/parameters", nil, &resp) == nil
+}
+
+func TestAdminAPI_Users(t *testing.T) {
+	ctx := testlogging.Context(t)
+	newClient, admin := startAdminAPIServer(t)
+
+	regular := newClient(servertesting.TestUsername+"@"+servertesting.TestHostname, servertesting.TestPassword)
+
+	_, err := serverapi.ListUsers(ctx, regular)
+	require.Error(t, err)
+
+	_, err = serverapi.AddUser(ctx, regular, &serverapi.AddUserRequest{Username: "bob@laptop", Password: "bob-pass"})
+	require.Error(t, err)
+
+	ui, err := serverapi.AddUser(ctx, admin, &serverapi.AddUserRequest{Username: "bob@laptop", Password: "bob-pass"})
+	require.NoError(t, err)
+	require.Equal(t, "bob@laptop", ui.Username)
+
+	_, err = serverapi.AddUser(ctx, admin, &serverapi.AddUserRequest{Username: "bob@laptop", Password: "bob-pass"})
+	require.ErrorContains(t, err, "409")
+
+	_, err = serverapi.AddUser(ctx, admin, &serverapi.AddUserRequest{Username: "Invalid", Password: "pass"})
+	require.Error(t, err)
+
+	require.True(t, canAccessRepository(t, newClient("bob@laptop", "bob-pass")))
+
+	users, err := serverapi.ListUsers(ctx, admin)
+	require.NoError(t, err)
+	require.Equal(t, []serverapi.UserInfo{{Username: "bob@laptop", PasswordHashVersion: 1}}, users.Users)
+
+	// password reset
+	require.NoError(t, serverapi.SetUserPassword(ctx, admin, "bob@laptop", "new-pass"))
+	require.False(t, canAccessRepository(t, newClient("bob@laptop", "bob-pass")))
+	require.True(t, canAccessRepository(t, newClient("bob@laptop", "new-pass")))
+
+	require.Error(t, serverapi.SetUserPassword(ctx, admin, "nobody@laptop", "new-pass"))
+
+	require.NoError(t, serverapi.DeleteUser(ctx, admin, "bob@laptop"))
+	require.False(t, canAccessRepository(t, newClient("bob@laptop", "new-pass")))
+	require.Error(t, serverapi.DeleteUser(ctx, admin, "bob@laptop"))
+}
+
+func TestAdminAPI_ACLs(t *testing.T) {
+	ctx := testlogging.Context(t)
+	newClient, admin := startAdminAPIServer(t)
+
+	regular := newClient(servertesting.TestUsername+"@"+servertesting.TestHostname, servertesting.TestPassword)
+
+	entries, err := serverapi.ListACLs(ctx, admin)
+	require.NoError(t, err)
+
+	adminRole, err := acl.GetRole(acl.RoleAdmin)
+	require.NoError(t, err)
+
+	initialCount := len(entries.Entries)
+	require.Equal(t, len(auth.DefaultACLs)+len(adminRole.Rules), initialCount)
+
+	_, err = serverapi.ListACLs(ctx, regular)
+	require.Error(t, err)
+
+	added, err := serverapi.AddACL(ctx, admin, &serverapi.AddACLRequest{
+		User:   "*@*",
+		Target: map[string]string{manifest.TypeLabelKey: "snapshot"},
+		Access: acl.AccessLevelRead,
+	})
+	require.NoError(t, err)
+	require.Len(t, added.Entries, 1)
+
+	added, err = serverapi.AddACL(ctx, admin, &serverapi.AddACLRequest{User: "group:ops", Role: acl.RoleRestoreOperator})
+	require.NoError(t, err)
+	require.Len(t, added.Entries, 3)
+
+	_, err = serverapi.AddACL(ctx, admin, &serverapi.AddACLRequest{User: "bad", Role: acl.RoleRestoreOperator})
+	require.Error(t, err)
+
+	_, err = serverapi.AddACL(ctx, admin, &serverapi.AddACLRequest{User: "*@*", Target: map[string]string{"type": "no-such-type"}, Access: acl.AccessLevelRead})
+	require.Error(t, err)
+
+	updated, err := serverapi.UpdateACL(ctx, admin, string(added.Entries[0].ID), &serverapi.AddACLRequest{
+		User:   "group:ops",
+		Target: added.Entries[0].Target,
+		Access: acl.AccessLevelFull,
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, added.Entries[0].ID, updated.ID)
+	require.Equal(t, acl.AccessLevelFull, updated.Access)
+
+	require.NoError(t, serverapi.DeleteACL(ctx, admin, string(updated.ID)))
+	require.Error(t, serverapi.DeleteACL(ctx, admin, string(updated.ID)))
+
+	entries, err = serverapi.ListACLs(ctx, admin)
+	require.NoError(t, err)
+	require.Len(t, entries.Entries, initialCount+3)
+
+	// regular users can't add entries.
+	_, err = serverapi.AddACL(ctx, regular, &serverapi.AddACLRequest{User: "*@*", Role: acl.RoleAdmin})
+	require.Error(t, err)
+}
+
+func TestAdminAPI_UIUserRequiresCSRFToken(t *testing.T) {
+	ctx := testlogging.Context(t)
+	newClient, _ := startAdminAPIServer(t)
+
+	ui := newClient(servertesting.TestUIUsername, servertesting.TestUIPassword)
+
+	_, err := serverapi.ListUsers(ctx, ui)
+	require.Error(t, err)
+
+	require.NoError(t, ui.FetchCSRFTokenForTesting(ctx))
+
+	_, err = serverapi.ListUsers(ctx, ui)
+	require.NoError(t, err)
+
+	_, err = serverapi.ListACLs(ctx, ui)
+	require.NoError(t, err)
+}
+
+func TestAdminAPI_NonUIAdminRequiresCSRFToken(t *testing.T) {
+	ctx := testlogging.Context(t)
+	newClient, _ := startAdminAPIServer(t)
+
+	// admin using basic authentication has no way of getting CSRF token, so all requests are rejected.
+	admin := newClient(testAdminUser, testAdminPassword)
+
+	_, err := serverapi.AddUser(ctx, admin, &serverapi.AddUserRequest{Username: "bob@laptop", Password: "bob-pass"})
+	require.ErrorContains(t, err, "401")
+
+	_, err = serverapi.AddACL(ctx, admin, &serverapi.AddACLRequest{User: "*@*", Role: acl.RoleAdmin})
+	require.ErrorContains(t, err, "401")
+
+	_, err = serverapi.ListUsers(ctx, admin)
+	require.ErrorContains(t, err, "401")
+
+	require.False(t, canAccessRepository(t, newClient("bob@laptop", "bob-pass")))
+}
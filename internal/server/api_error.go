@@ -23,6 +23,10 @@ func notFoundError(message string) *apiError {
 	return &apiError{http.StatusNotFound, serverapi.ErrorNotFound, message}
 }
 
+func alreadyExistsError(message string) *apiError {
+	return &apiError{http.StatusConflict, serverapi.ErrorAlreadyExists, message}
+}
+
 func accessDeniedError() *apiError {
 	return &apiError{http.StatusForbidden, serverapi.ErrorAccessDenied, "access is denied"}
 }
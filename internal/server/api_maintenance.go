@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/uitask"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/snapshot/snapshotmaintenance"
+)
+
+func maintenanceInfo(ctx context.Context, dr repo.DirectRepository) (*serverapi.MaintenanceInfoResponse, *apiError) {
+	p, err := maintenance.GetParams(ctx, dr)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	sched, err := maintenance.GetSchedule(ctx, dr)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	return &serverapi.MaintenanceInfoResponse{
+		Params:        p,
+		Schedule:      sched,
+		OwnedByServer: p.Owner == dr.ClientOptions().UsernameAtHost(),
+	}, nil
+}
+
+func handleMaintenanceInfo(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	dr, ok := rc.rep.(repo.DirectRepository)
+	if !ok {
+		return nil, requestError(serverapi.ErrorStorageConnection, "no direct storage connection")
+	}
+
+	return maintenanceInfo(ctx, dr)
+}
+
+func validateCycleParams(cp *maintenance.CycleParams) error {
+	if cp != nil && cp.Interval <= 0 {
+		return errors.Errorf("maintenance interval must be positive")
+	}
+
+	return nil
+}
+
+func handleMaintenanceSet(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	dr, ok := rc.rep.(repo.DirectRepository)
+	if !ok {
+		return nil, requestError(serverapi.ErrorStorageConnection, "no direct storage connection")
+	}
+
+	var req serverapi.SetMaintenanceRequest
+
+	if err := json.Unmarshal(rc.body, &req); err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "malformed request body")
+	}
+
+	if req.Owner == "me" {
+		req.Owner = dr.ClientOptions().UsernameAtHost()
+	}
+
+	if req.Owner != "" {
+		if parts := strings.Split(req.Owner, "@"); len(parts) != 2 || parts[0] == "" || parts[1] == "" { //nolint:gomnd
+			return nil, requestError(serverapi.ErrorMalformedRequest, "owner must be 'username@hostname' or 'me'")
+		}
+	}
+
+	for _, cp := range []*maintenance.CycleParams{req.QuickCycle, req.FullCycle} {
+		if err := validateCycleParams(cp); err != nil {
+			return nil, requestError(serverapi.ErrorMalformedRequest, err.Error())
+		}
+	}
+
+	if err := repo.DirectWriteSession(ctx, dr, repo.WriteSessionOptions{
+		Purpose: "MaintenanceSet",
+	}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
+		p, err := maintenance.GetParams(ctx, w)
+		if err != nil {
+			return errors.Wrap(err, "unable to get current parameters")
+		}
+
+		if req.Owner != "" {
+			p.Owner = req.Owner
+		}
+
+		if req.QuickCycle != nil {
+			p.QuickCycle = *req.QuickCycle
+		}
+
+		if req.FullCycle != nil {
+			p.FullCycle = *req.FullCycle
+		}
+
+		return errors.Wrap(maintenance.SetParams(ctx, w, p), "unable to set params")
+	}); err != nil {
+		return nil, internalServerError(err)
+	}
+
+	return maintenanceInfo(ctx, dr)
+}
+
+func handleMaintenanceRun(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	dr, ok := rc.rep.(repo.DirectRepository)
+	if !ok {
+		return nil, requestError(serverapi.ErrorStorageConnection, "no direct storage connection")
+	}
+
+	var req serverapi.RunMaintenanceRequest
+
+	if err := json.Unmarshal(rc.body, &req); err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "malformed request body")
+	}
+
+	p, err := maintenance.GetParams(ctx, dr)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	if p.Owner != dr.ClientOptions().UsernameAtHost() {
+		return nil, requestError(serverapi.ErrorMalformedRequest, maintenance.NotOwnedError{Owner: p.Owner}.Error())
+	}
+
+	mode := maintenance.ModeQuick
+	description := "Quick maintenance"
+
+	if req.Full {
+		mode = maintenance.ModeFull
+		description = "Full maintenance"
+	}
+
+	taskIDChan := make(chan string)
+
+	// launch a goroutine that will run the maintenance and can be observed in the Tasks UI.
+
+	//nolint:errcheck
+	go rc.srv.taskManager().Run(ctx, "Maintenance", description, func(ctx context.Context, ctrl uitask.Controller) error {
+		taskIDChan <- ctrl.CurrentTaskID()
+
+		//nolint:wrapcheck
+		return repo.DirectWriteSession(ctx, dr, repo.WriteSessionOptions{
+			Purpose: "MaintenanceRun",
+		}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
+			_, supportsEpochManager, err := w.ContentManager().EpochManager()
+			if err != nil {
+				return errors.Wrap(err, "EpochManager")
+			}
+
+			// repositories with epoch manager always run full maintenance, same as 'kopia maintenance run'.
+			if supportsEpochManager {
+				mode = maintenance.ModeFull
+			}
+
+			//nolint:wrapcheck
+			return snapshotmaintenance.Run(ctx, w, mode, false, maintenance.SafetyFull)
+		})
+	})
+
+	taskID := <-taskIDChan
+
+	task, ok := rc.srv.taskManager().GetTask(taskID)
+	if !ok {
+		return nil, internalServerError(errors.Errorf("task not found"))
+	}
+
+	return task, nil
+}
+
+func handleMaintenanceHistory(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	dr, ok := rc.rep.(repo.DirectRepository)
+	if !ok {
+		return nil, requestError(serverapi.ErrorStorageConnection, "no direct storage connection")
+	}
+
+	history, err := maintenance.History(ctx, dr, maintenance.TaskType(rc.queryParam("task")))
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	return &serverapi.MaintenanceHistoryResponse{
+		Runs: history,
+	}, nil
+}
+
+func handleRepoStats(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	dr, ok := rc.rep.(repo.DirectRepository)
+	if !ok {
+		return nil, requestError(serverapi.ErrorStorageConnection, "no direct storage connection")
+	}
+
+	history, err := maintenance.StatsHistory(ctx, dr)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	return &serverapi.RepositoryStatsResponse{
+		History: history,
+	}, nil
+}
@@ -0,0 +1,167 @@
+package server_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/servertesting"
+	"github.com/kopia/kopia/internal/uitask"
+	"github.com/kopia/kopia/repo/maintenance"
+)
+
+func TestMaintenanceHistory(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+	srvInfo := servertesting.StartServer(t, env, false)
+
+	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:                             srvInfo.BaseURL,
+		TrustedServerCertificateFingerprint: srvInfo.TrustedServerCertificateFingerprint,
+		Username:                            servertesting.TestUIUsername,
+		Password:                            servertesting.TestUIPassword,
+	})
+
+	require.NoError(t, err)
+
+	require.NoError(t, cli.FetchCSRFTokenForTesting(ctx))
+
+	resp, err := serverapi.GetMaintenanceHistory(ctx, cli, "")
+	require.NoError(t, err)
+	require.Empty(t, resp.Runs)
+
+	require.NoError(t, maintenance.ReportRunAndStats(ctx, env.RepositoryWriter, maintenance.TaskCleanupLogs, nil, func(ctx context.Context) (map[string]int64, error) {
+		return map[string]int64{"deletedLogs": 3}, nil
+	}))
+	require.NoError(t, maintenance.ReportRun(ctx, env.RepositoryWriter, maintenance.TaskIndexCompaction, nil, func(ctx context.Context) error {
+		return nil
+	}))
+
+	resp, err = serverapi.GetMaintenanceHistory(ctx, cli, "")
+	require.NoError(t, err)
+	require.Len(t, resp.Runs, 2)
+
+	resp, err = serverapi.GetMaintenanceHistory(ctx, cli, maintenance.TaskCleanupLogs)
+	require.NoError(t, err)
+	require.Len(t, resp.Runs, 1)
+	require.Equal(t, maintenance.TaskType(maintenance.TaskCleanupLogs), resp.Runs[0].Task)
+	require.True(t, resp.Runs[0].Success)
+	require.Equal(t, map[string]int64{"deletedLogs": 3}, resp.Runs[0].Stats)
+}
+
+func TestRepoStats(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+	srvInfo := servertesting.StartServer(t, env, false)
+
+	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:                             srvInfo.BaseURL,
+		TrustedServerCertificateFingerprint: srvInfo.TrustedServerCertificateFingerprint,
+		Username:                            servertesting.TestUIUsername,
+		Password:                            servertesting.TestUIPassword,
+	})
+
+	require.NoError(t, err)
+
+	require.NoError(t, cli.FetchCSRFTokenForTesting(ctx))
+
+	resp, err := serverapi.GetRepositoryStats(ctx, cli)
+	require.NoError(t, err)
+	require.Empty(t, resp.History)
+
+	require.NoError(t, maintenance.RecordStats(ctx, env.RepositoryWriter, nil, nil))
+
+	resp, err = serverapi.GetRepositoryStats(ctx, cli)
+	require.NoError(t, err)
+	require.Len(t, resp.History, 1)
+	require.Positive(t, resp.History[0].TotalBlobBytes())
+}
+
+func TestMaintenanceInfoAndSet(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+	srvInfo := servertesting.StartServer(t, env, false)
+
+	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:                             srvInfo.BaseURL,
+		TrustedServerCertificateFingerprint: srvInfo.TrustedServerCertificateFingerprint,
+		Username:                            servertesting.TestUIUsername,
+		Password:                            servertesting.TestUIPassword,
+	})
+
+	require.NoError(t, err)
+
+	require.NoError(t, cli.FetchCSRFTokenForTesting(ctx))
+
+	info, err := serverapi.GetMaintenanceInfo(ctx, cli)
+	require.NoError(t, err)
+	require.True(t, info.Params.QuickCycle.Enabled)
+
+	info, err = serverapi.SetMaintenanceParams(ctx, cli, &serverapi.SetMaintenanceRequest{
+		Owner:     "someone@else",
+		FullCycle: &maintenance.CycleParams{Enabled: false, Interval: 48 * time.Hour},
+	})
+	require.NoError(t, err)
+	require.False(t, info.OwnedByServer)
+	require.Equal(t, "someone@else", info.Params.Owner)
+	require.Equal(t, maintenance.CycleParams{Enabled: false, Interval: 48 * time.Hour}, info.Params.FullCycle)
+	require.True(t, info.Params.QuickCycle.Enabled)
+
+	// maintenance owned by another user can't be run by the server.
+	_, err = serverapi.RunMaintenance(ctx, cli, &serverapi.RunMaintenanceRequest{})
+	require.Error(t, err)
+
+	_, err = serverapi.SetMaintenanceParams(ctx, cli, &serverapi.SetMaintenanceRequest{Owner: "invalid"})
+	require.Error(t, err)
+
+	_, err = serverapi.SetMaintenanceParams(ctx, cli, &serverapi.SetMaintenanceRequest{
+		QuickCycle: &maintenance.CycleParams{Enabled: true},
+	})
+	require.Error(t, err)
+
+	info, err = serverapi.SetMaintenanceParams(ctx, cli, &serverapi.SetMaintenanceRequest{Owner: "me"})
+	require.NoError(t, err)
+	require.True(t, info.OwnedByServer)
+}
+
+func TestMaintenanceRun(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+	srvInfo := servertesting.StartServer(t, env, false)
+
+	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:                             srvInfo.BaseURL,
+		TrustedServerCertificateFingerprint: srvInfo.TrustedServerCertificateFingerprint,
+		Username:                            servertesting.TestUIUsername,
+		Password:                            servertesting.TestUIPassword,
+	})
+
+	require.NoError(t, err)
+
+	require.NoError(t, cli.FetchCSRFTokenForTesting(ctx))
+
+	// maintenance can only be run by the server when it's the owner.
+	_, err = serverapi.SetMaintenanceParams(ctx, cli, &serverapi.SetMaintenanceRequest{Owner: "me"})
+	require.NoError(t, err)
+
+	for _, full := range []bool{false, true} {
+		task, err := serverapi.RunMaintenance(ctx, cli, &serverapi.RunMaintenanceRequest{Full: full})
+		require.NoError(t, err)
+		require.Equal(t, "Maintenance", task.Kind)
+
+		require.Equal(t, uitask.StatusSuccess, waitForTask(t, cli, task.TaskID, 30*time.Second).Status)
+
+		logs, err := serverapi.GetTaskLogs(ctx, cli, task.TaskID)
+		require.NoError(t, err)
+		require.NotEmpty(t, logs.Logs)
+	}
+
+	resp, err := serverapi.GetMaintenanceHistory(ctx, cli, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Runs)
+
+	info, err := serverapi.GetMaintenanceInfo(ctx, cli)
+	require.NoError(t, err)
+	require.False(t, info.Schedule.NextFullMaintenanceTime.IsZero())
+}
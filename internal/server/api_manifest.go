@@ -6,6 +6,7 @@
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/auditlog"
 	"github.com/kopia/kopia/internal/auth"
 	"github.com/kopia/kopia/internal/remoterepoapi"
 	"github.com/kopia/kopia/internal/serverapi"
@@ -56,7 +57,10 @@ func handleManifestDelete(ctx context.Context, rc requestContext) (interface{},
 		return nil, internalServerError(err)
 	}
 
+	target := manifestAuditTarget(mid, em.Labels)
+
 	if !hasManifestAccess(ctx, rc, em.Labels, auth.AccessLevelFull) {
+		rc.audit(ctx, auditlog.ActionManifestDelete, target, accessDeniedError())
 		return nil, accessDeniedError()
 	}
 
@@ -66,9 +70,12 @@ func handleManifestDelete(ctx context.Context, rc requestContext) (interface{},
 	}
 
 	if err != nil {
+		rc.audit(ctx, auditlog.ActionManifestDelete, target, internalServerError(err))
 		return nil, internalServerError(err)
 	}
 
+	rc.audit(ctx, auditlog.ActionManifestDelete, target, nil)
+
 	return &serverapi.Empty{}, nil
 }
 
@@ -112,14 +119,28 @@ func handleManifestCreate(ctx context.Context, rc requestContext) (interface{},
 		return nil, requestError(serverapi.ErrorMalformedRequest, "malformed request")
 	}
 
+	audited := isAuditedManifest(req.Metadata.Labels)
+
 	if !hasManifestAccess(ctx, rc, req.Metadata.Labels, auth.AccessLevelAppend) {
+		if audited {
+			rc.audit(ctx, auditlog.ActionManifestPut, manifestAuditTarget("", req.Metadata.Labels), accessDeniedError())
+		}
+
 		return nil, accessDeniedError()
 	}
 
 	id, err := rw.PutManifest(ctx, req.Metadata.Labels, req.Payload)
 	if err != nil {
+		if audited {
+			rc.audit(ctx, auditlog.ActionManifestPut, manifestAuditTarget("", req.Metadata.Labels), internalServerError(err))
+		}
+
 		return nil, internalServerError(err)
 	}
 
+	if audited {
+		rc.audit(ctx, auditlog.ActionManifestPut, manifestAuditTarget(id, req.Metadata.Labels), nil)
+	}
+
 	return &manifest.EntryMetadata{ID: id}, nil
 }
@@ -158,7 +158,7 @@ func handleRepoCreate(ctx context.Context, rc requestContext) (interface{}, *api
 	if err := repo.WriteSession(ctx, newRepo, repo.WriteSessionOptions{
 		Purpose: "handleRepoCreate",
 	}, func(ctx context.Context, w repo.RepositoryWriter) error {
-		if err := policy.SetPolicy(ctx, w, policy.GlobalPolicySourceInfo, policy.DefaultPolicy); err != nil {
+		if err := policy.SetPolicy(ctx, w, policy.GlobalPolicySourceInfo, policy.NewRepositoryGlobalPolicy); err != nil {
 			return errors.Wrap(err, "set global policy")
 		}
 
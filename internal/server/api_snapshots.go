@@ -12,6 +12,7 @@
 	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
 )
 
 func handleListSnapshots(ctx context.Context, rc requestContext) (interface{}, *apiError) {
@@ -54,6 +55,27 @@ func handleListSnapshots(ctx context.Context, rc requestContext) (interface{}, *
 	return resp, nil
 }
 
+func handleSnapshotStorageUsage(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	manifestIDs, err := snapshot.ListSnapshotManifests(ctx, rc.rep, nil, nil)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	manifests, err := snapshot.LoadSnapshots(ctx, rc.rep, manifestIDs)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	usage, err := snapshotfs.CalculateSourceStorageUsage(ctx, rc.rep, manifests, rc.queryParam("snapshots") != "")
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	return &serverapi.StorageUsageResponse{
+		Sources: usage,
+	}, nil
+}
+
 func handleDeleteSnapshots(ctx context.Context, rc requestContext) (interface{}, *apiError) {
 	var req serverapi.DeleteSnapshotsRequest
 
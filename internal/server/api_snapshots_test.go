@@ -6,6 +6,7 @@
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/internal/apiclient"
 	"github.com/kopia/kopia/internal/mockfs"
 	"github.com/kopia/kopia/internal/repotesting"
@@ -256,3 +257,72 @@ func TestEditSnapshots(t *testing.T) {
 	require.EqualValues(t, []string{"pin2"}, updated[0].Pins)
 	require.EqualValues(t, newDesc2, updated[0].Description)
 }
+
+func TestSnapshotStorageUsage(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	si1 := env.LocalPathSourceInfo("/dummy/path")
+	si2 := env.LocalPathSourceInfo("/another/path")
+
+	require.NoError(t, repo.WriteSession(ctx, env.Repository, repo.WriteSessionOptions{Purpose: "Test"}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		u := snapshotfs.NewUploader(w)
+
+		dir1 := mockfs.NewDirectory()
+		dir1.AddFile("file1", []byte{1, 2, 3}, 0o644)
+		dir1.AddFile("shared", []byte{1, 2, 4}, 0o644)
+
+		dir2 := mockfs.NewDirectory()
+		dir2.AddFile("shared", []byte{1, 2, 4}, 0o644)
+
+		for _, s := range []struct {
+			dir fs.Directory
+			si  snapshot.SourceInfo
+		}{
+			{dir1, si1},
+			{dir1, si1},
+			{dir2, si2},
+		} {
+			man, err := u.Upload(ctx, s.dir, nil, s.si)
+			require.NoError(t, err)
+
+			_, err = snapshot.SaveSnapshot(ctx, w, man)
+			require.NoError(t, err)
+		}
+
+		return nil
+	}))
+
+	srvInfo := servertesting.StartServer(t, env, false)
+
+	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:                             srvInfo.BaseURL,
+		TrustedServerCertificateFingerprint: srvInfo.TrustedServerCertificateFingerprint,
+		Username:                            servertesting.TestUIUsername,
+		Password:                            servertesting.TestUIPassword,
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, cli.FetchCSRFTokenForTesting(ctx))
+
+	resp, err := serverapi.GetStorageUsage(ctx, cli, false)
+	require.NoError(t, err)
+	require.Len(t, resp.Sources, 2)
+
+	// sources are sorted by their string representation.
+	require.Equal(t, si2, resp.Sources[0].Source)
+	require.Equal(t, si1, resp.Sources[1].Source)
+	require.Equal(t, int64(1), resp.Sources[0].SharedContentCount)
+	require.Equal(t, int64(1), resp.Sources[1].SharedContentCount)
+	require.Equal(t, 2, resp.Sources[1].SnapshotCount)
+	require.Empty(t, resp.Sources[1].Snapshots)
+
+	resp, err = serverapi.GetStorageUsage(ctx, cli, true)
+	require.NoError(t, err)
+	require.Len(t, resp.Sources, 2)
+	require.Len(t, resp.Sources[1].Snapshots, 2)
+
+	// both snapshots of the first source are identical, so all of their contents are shared.
+	require.Zero(t, resp.Sources[1].Snapshots[0].UniqueContentCount)
+	require.Zero(t, resp.Sources[1].Snapshots[1].UniqueContentCount)
+	require.Equal(t, int64(3), resp.Sources[1].Snapshots[1].SharedContentCount)
+}
@@ -15,6 +15,9 @@
 	"github.com/kopia/kopia/internal/servertesting"
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/internal/uitask"
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/testsender"
 	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/snapshot/policy"
 )
@@ -157,3 +160,51 @@ func TestSourceRefreshesAfterPolicy(t *testing.T) {
 
 	require.True(t, match)
 }
+
+func TestSnapshotNotification(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	require.NoError(t, notifyprofile.SaveProfile(ctx, env.RepositoryWriter, &notifyprofile.Config{
+		ProfileName: "test",
+		MinSeverity: sender.SeveritySuccess,
+		MethodConfig: sender.MethodConfig{
+			Type:   testsender.Method,
+			Config: &testsender.Options{Name: t.Name()},
+		},
+	}))
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	srvInfo := servertesting.StartServer(t, env, false)
+
+	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:                             srvInfo.BaseURL,
+		TrustedServerCertificateFingerprint: srvInfo.TrustedServerCertificateFingerprint,
+		Username:                            servertesting.TestUIUsername,
+		Password:                            servertesting.TestUIPassword,
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, cli.FetchCSRFTokenForTesting(ctx))
+
+	dir := testutil.TempDirectory(t)
+	si := env.LocalPathSourceInfo(dir)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file-a"), []byte{1, 2}, 0o644))
+
+	mustCreateSource(t, cli, dir, &policy.Policy{})
+
+	_, err = serverapi.UploadSnapshots(ctx, cli, &si)
+	require.NoError(t, err)
+
+	deadline := clock.Now().Add(30 * time.Second)
+
+	for len(testsender.MessagesSentTo(t.Name())) == 0 && clock.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	msgs := testsender.MessagesSentTo(t.Name())
+	require.Len(t, msgs, 1)
+	require.Equal(t, sender.SeveritySuccess, msgs[0].Severity)
+	require.Contains(t, msgs[0].Subject, "Snapshot of "+si.String()+" completed")
+	require.Contains(t, msgs[0].Body, "Files:       1")
+}
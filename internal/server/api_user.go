@@ -2,8 +2,12 @@
 
 import (
 	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/user"
 	"github.com/kopia/kopia/repo"
 )
 
@@ -13,3 +17,156 @@ func handleCurrentUser(ctx context.Context, _ requestContext) (interface{}, *api
 		Hostname: repo.GetDefaultHostName(ctx),
 	}, nil
 }
+
+func toUserInfo(p *user.Profile) serverapi.UserInfo {
+	return serverapi.UserInfo{
+		Username:            p.Username,
+		PasswordHashVersion: p.PasswordHashVersion,
+	}
+}
+
+func handleUserList(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	profiles, err := user.ListUserProfiles(ctx, rc.rep)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	resp := &serverapi.UsersResponse{
+		Users: []serverapi.UserInfo{},
+	}
+
+	for _, p := range profiles {
+		resp.Users = append(resp.Users, toUserInfo(p))
+	}
+
+	return resp, nil
+}
+
+func handleUserGet(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	p, err := user.GetUserProfile(ctx, rc.rep, rc.muxVar("username"))
+	if errors.Is(err, user.ErrUserNotFound) {
+		return nil, notFoundError("user not found")
+	}
+
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	return toUserInfo(p), nil
+}
+
+func handleUserAdd(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	var req serverapi.AddUserRequest
+
+	if err := json.Unmarshal(rc.body, &req); err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "malformed request body")
+	}
+
+	if err := user.ValidateUsername(req.Username); err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, err.Error())
+	}
+
+	if req.Password == "" {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "password is required")
+	}
+
+	_, err := user.GetUserProfile(ctx, rc.rep, req.Username)
+
+	switch {
+	case err == nil:
+		return nil, alreadyExistsError("user already exists")
+
+	case !errors.Is(err, user.ErrUserNotFound):
+		return nil, internalServerError(err)
+	}
+
+	p := &user.Profile{Username: req.Username}
+
+	if err := p.SetPassword(req.Password); err != nil {
+		return nil, internalServerError(err)
+	}
+
+	if aerr := setUserProfile(ctx, rc, p, "UserAdd"); aerr != nil {
+		return nil, aerr
+	}
+
+	return toUserInfo(p), nil
+}
+
+func handleUserSetPassword(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	var req serverapi.SetUserPasswordRequest
+
+	if err := json.Unmarshal(rc.body, &req); err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "malformed request body")
+	}
+
+	if req.Password == "" {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "password is required")
+	}
+
+	p, err := user.GetUserProfile(ctx, rc.rep, rc.muxVar("username"))
+	if errors.Is(err, user.ErrUserNotFound) {
+		return nil, notFoundError("user not found")
+	}
+
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	if err := p.SetPassword(req.Password); err != nil {
+		return nil, internalServerError(err)
+	}
+
+	if aerr := setUserProfile(ctx, rc, p, "UserSetPassword"); aerr != nil {
+		return nil, aerr
+	}
+
+	return &serverapi.Empty{}, nil
+}
+
+func setUserProfile(ctx context.Context, rc requestContext, p *user.Profile, purpose string) *apiError {
+	if _, ok := rc.rep.(repo.RepositoryWriter); !ok {
+		return repositoryNotWritableError()
+	}
+
+	if err := repo.WriteSession(ctx, rc.rep, repo.WriteSessionOptions{
+		Purpose: purpose,
+	}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		return user.SetUserProfile(ctx, w, p)
+	}); err != nil {
+		return internalServerError(err)
+	}
+
+	// make sure the new password takes effect immediately.
+	_ = rc.srv.Refresh(ctx)
+
+	return nil
+}
+
+func handleUserDelete(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	username := rc.muxVar("username")
+
+	if _, err := user.GetUserProfile(ctx, rc.rep, username); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, notFoundError("user not found")
+		}
+
+		return nil, internalServerError(err)
+	}
+
+	if _, ok := rc.rep.(repo.RepositoryWriter); !ok {
+		return nil, repositoryNotWritableError()
+	}
+
+	if err := repo.WriteSession(ctx, rc.rep, repo.WriteSessionOptions{
+		Purpose: "UserDelete",
+	}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		return user.DeleteUserProfile(ctx, w, username)
+	}); err != nil {
+		return nil, internalServerError(err)
+	}
+
+	_ = rc.srv.Refresh(ctx)
+
+	return &serverapi.Empty{}, nil
+}
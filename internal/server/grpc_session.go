@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"net/http"
 	"runtime"
@@ -14,10 +15,12 @@
 	"golang.org/x/sync/semaphore"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 
+	"github.com/kopia/kopia/internal/auditlog"
 	"github.com/kopia/kopia/internal/auth"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/grpcapi"
@@ -50,24 +53,75 @@ func (s *Server) send(srv grpcapi.KopiaRepository_SessionServer, requestID int64
 	return nil
 }
 
-func (s *Server) authenticateGRPCSession(ctx context.Context, rep repo.Repository) (string, error) {
+func (s *Server) authenticateGRPCSession(ctx context.Context, rep repo.Repository) (username string, groups []string, err error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return "", status.Errorf(codes.PermissionDenied, "metadata not found in context")
+		return "", nil, status.Errorf(codes.PermissionDenied, "metadata not found in context")
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && s.options.ClientCertificates != nil {
+		if ti, ok := p.AuthInfo.(credentials.TLSInfo); ok && auth.HasClientCertificate(&ti.State) {
+			return s.authenticateGRPCClientCertificate(md, &ti.State)
+		}
+	}
+
+	if a := md.Get("authorization"); len(a) == 1 && s.options.OIDC != nil {
+		if token := strings.TrimPrefix(a[0], "Bearer "); token != a[0] {
+			id, err := s.options.OIDC.VerifyToken(ctx, token)
+			if err != nil {
+				return "", nil, status.Errorf(codes.PermissionDenied, "invalid bearer token")
+			}
+
+			return id.UsernameAtHost, id.Groups, nil
+		}
 	}
 
 	if u, h, p := md.Get("kopia-username"), md.Get("kopia-hostname"), md.Get("kopia-password"); len(u) == 1 && len(p) == 1 && len(h) == 1 {
 		username := u[0] + "@" + h[0]
 		password := p[0]
 
-		if s.authenticator.IsValid(ctx, rep, username, password) {
-			return username, nil
+		if groups, ok := auth.Authenticate(ctx, s.authenticator, rep, username, password); ok {
+			return username, groups, nil
 		}
 
-		return "", status.Errorf(codes.PermissionDenied, "access denied for %v", username)
+		return "", nil, status.Errorf(codes.PermissionDenied, "access denied for %v", username)
 	}
 
-	return "", status.Errorf(codes.PermissionDenied, "missing credentials")
+	return "", nil, status.Errorf(codes.PermissionDenied, "missing credentials")
+}
+
+func (s *Server) authenticateGRPCClientCertificate(md metadata.MD, cs *tls.ConnectionState) (username string, groups []string, err error) {
+	id, err := s.options.ClientCertificates.Identity(cs)
+	if err != nil {
+		return "", nil, status.Errorf(codes.PermissionDenied, "invalid client certificate")
+	}
+
+	// credentials sent along with the certificate must be for the same user.
+	if u, h := md.Get("kopia-username"), md.Get("kopia-hostname"); len(u) == 1 && len(h) == 1 && u[0]+"@"+h[0] != id {
+		return "", nil, status.Errorf(codes.PermissionDenied, "client certificate does not match %v@%v", u[0], h[0])
+	}
+
+	return id, nil, nil
+}
+
+// grpcSessionUsername returns username@hostname claimed by the client in session metadata, if any.
+func grpcSessionUsername(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if u, h := md.Get("kopia-username"), md.Get("kopia-hostname"); len(u) == 1 && len(h) == 1 {
+		return u[0] + "@" + h[0]
+	}
+
+	return ""
+}
+
+// grpcPeerAddress returns the address of the client, if known.
+func grpcPeerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+
+	return ""
 }
 
 // Session handles GRPC session from a repository client.
@@ -82,21 +136,27 @@ func (s *Server) Session(srv grpcapi.KopiaRepository_SessionServer) error {
 		return status.Errorf(codes.Unavailable, "not connected to a direct repository")
 	}
 
-	username, err := s.authenticateGRPCSession(ctx, dr)
+	username, groups, err := s.authenticateGRPCSession(ctx, dr)
 	if err != nil {
+		s.newAuditRecorder(grpcSessionUsername(ctx), grpcPeerAddress(ctx))(ctx, auditlog.ActionLogin, map[string]string{"method": loginMethodGRPC}, auditlog.ResultDenied, nil)
 		return err
 	}
 
-	authz := s.authorizer.Authorize(ctx, dr, username)
+	authz := s.authorizer.Authorize(auth.WithGroups(ctx, groups), dr, username)
 	if authz == nil {
 		authz = auth.NoAccess()
 	}
 
+	authz = hideServerOnlyManifests{authz}
+
 	p, ok := peer.FromContext(ctx)
 	if !ok {
 		return status.Errorf(codes.PermissionDenied, "peer not found in context")
 	}
 
+	audit := s.newAuditRecorder(username, p.Addr.String())
+	audit(ctx, auditlog.ActionLogin, map[string]string{"method": loginMethodGRPC}, auditlog.ResultSuccess, nil)
+
 	log(ctx).Infof("starting session for user %q from %v", username, p.Addr)
 	defer log(ctx).Infof("session ended for user %q from %v", username, p.Addr)
 
@@ -131,7 +191,7 @@ func (s *Server) Session(srv grpcapi.KopiaRepository_SessionServer) error {
 			go func() {
 				defer s.grpcServerState.sem.Release(1)
 
-				handleSessionRequest(ctx, dw, authz, req, func(resp *grpcapi.SessionResponse) {
+				handleSessionRequest(ctx, dw, authz, audit, req, func(resp *grpcapi.SessionResponse) {
 					if err := s.send(srv, req.RequestId, resp); err != nil {
 						select {
 						case lastErr <- err:
@@ -148,7 +208,7 @@ func (s *Server) Session(srv grpcapi.KopiaRepository_SessionServer) error {
 
 var tracer = otel.Tracer("kopia/grpc")
 
-func handleSessionRequest(ctx context.Context, dw repo.DirectRepositoryWriter, authz auth.AuthorizationInfo, req *grpcapi.SessionRequest, respond func(*grpcapi.SessionResponse)) {
+func handleSessionRequest(ctx context.Context, dw repo.DirectRepositoryWriter, authz auth.AuthorizationInfo, audit auditRecorder, req *grpcapi.SessionRequest, respond func(*grpcapi.SessionResponse)) {
 	if req.TraceContext != nil {
 		var tc propagation.TraceContext
 		ctx = tc.Extract(ctx, propagation.MapCarrier(req.TraceContext))
@@ -171,13 +231,13 @@ func handleSessionRequest(ctx context.Context, dw repo.DirectRepositoryWriter, a
 		respond(handleGetManifestRequest(ctx, dw, authz, inner.GetManifest))
 
 	case *grpcapi.SessionRequest_PutManifest:
-		respond(handlePutManifestRequest(ctx, dw, authz, inner.PutManifest))
+		respond(handlePutManifestRequest(ctx, dw, authz, audit, inner.PutManifest))
 
 	case *grpcapi.SessionRequest_FindManifests:
 		handleFindManifestsRequest(ctx, dw, authz, inner.FindManifests, respond)
 
 	case *grpcapi.SessionRequest_DeleteManifest:
-		respond(handleDeleteManifestRequest(ctx, dw, authz, inner.DeleteManifest))
+		respond(handleDeleteManifestRequest(ctx, dw, authz, audit, inner.DeleteManifest))
 
 	case *grpcapi.SessionRequest_PrefetchContents:
 		respond(handlePrefetchContentsRequest(ctx, dw, authz, inner.PrefetchContents))
@@ -322,19 +382,27 @@ func handleGetManifestRequest(ctx context.Context, dw repo.DirectRepositoryWrite
 	}
 }
 
-func handlePutManifestRequest(ctx context.Context, dw repo.DirectRepositoryWriter, authz auth.AuthorizationInfo, req *grpcapi.PutManifestRequest) *grpcapi.SessionResponse {
+func handlePutManifestRequest(ctx context.Context, dw repo.DirectRepositoryWriter, authz auth.AuthorizationInfo, audit auditRecorder, req *grpcapi.PutManifestRequest) *grpcapi.SessionResponse {
 	ctx, span := tracer.Start(ctx, "GRPCSession.PutManifest")
 	defer span.End()
 
+	if !isAuditedManifest(req.GetLabels()) {
+		audit = func(context.Context, string, map[string]string, auditlog.Result, error) {}
+	}
+
 	if authz.ManifestAccessLevel(req.GetLabels()) < auth.AccessLevelAppend {
+		audit(ctx, auditlog.ActionManifestPut, manifestAuditTarget("", req.GetLabels()), auditlog.ResultDenied, nil)
 		return accessDeniedResponse()
 	}
 
 	manifestID, err := dw.PutManifest(ctx, req.GetLabels(), json.RawMessage(req.GetJsonData()))
 	if err != nil {
+		audit(ctx, auditlog.ActionManifestPut, manifestAuditTarget("", req.GetLabels()), auditlog.ResultFailure, err)
 		return errorResponse(err)
 	}
 
+	audit(ctx, auditlog.ActionManifestPut, manifestAuditTarget(manifestID, req.GetLabels()), auditlog.ResultSuccess, nil)
+
 	return &grpcapi.SessionResponse{
 		Response: &grpcapi.SessionResponse_PutManifest{
 			PutManifest: &grpcapi.PutManifestResponse{
@@ -390,7 +458,7 @@ func handleFindManifestsRequest(ctx context.Context, dw repo.DirectRepositoryWri
 	})
 }
 
-func handleDeleteManifestRequest(ctx context.Context, dw repo.DirectRepositoryWriter, authz auth.AuthorizationInfo, req *grpcapi.DeleteManifestRequest) *grpcapi.SessionResponse {
+func handleDeleteManifestRequest(ctx context.Context, dw repo.DirectRepositoryWriter, authz auth.AuthorizationInfo, audit auditRecorder, req *grpcapi.DeleteManifestRequest) *grpcapi.SessionResponse {
 	ctx, span := tracer.Start(ctx, "GRPCSession.DeleteManifest")
 	defer span.End()
 
@@ -401,14 +469,20 @@ func handleDeleteManifestRequest(ctx context.Context, dw repo.DirectRepositoryWr
 		return errorResponse(err)
 	}
 
+	target := manifestAuditTarget(em.ID, em.Labels)
+
 	if authz.ManifestAccessLevel(em.Labels) < auth.AccessLevelFull {
+		audit(ctx, auditlog.ActionManifestDelete, target, auditlog.ResultDenied, nil)
 		return accessDeniedResponse()
 	}
 
 	if err := dw.DeleteManifest(ctx, manifest.ID(req.GetManifestId())); err != nil {
+		audit(ctx, auditlog.ActionManifestDelete, target, auditlog.ResultFailure, err)
 		return errorResponse(err)
 	}
 
+	audit(ctx, auditlog.ActionManifestDelete, target, auditlog.ResultSuccess, nil)
+
 	return &grpcapi.SessionResponse{
 		Response: &grpcapi.SessionResponse_DeleteManifest{
 			DeleteManifest: &grpcapi.DeleteManifestResponse{},
@@ -7,6 +7,8 @@
 
 	"github.com/gorilla/mux"
 
+	"github.com/kopia/kopia/internal/apitoken"
+	"github.com/kopia/kopia/internal/auditlog"
 	"github.com/kopia/kopia/internal/auth"
 	"github.com/kopia/kopia/internal/mount"
 	"github.com/kopia/kopia/internal/uitask"
@@ -18,8 +20,14 @@
 //nolint:interfacebloat
 type serverInterface interface {
 	deleteSourceManager(ctx context.Context, src snapshot.SourceInfo) bool
-	generateShortTermAuthCookie(username string, now time.Time) (string, error)
-	isAuthCookieValid(username, cookieValue string) bool
+	generateShortTermAuthCookie(username string, groups []string, now time.Time) (string, error)
+	authCookieGroups(username, cookieValue string) ([]string, bool)
+	validateCSRFToken(r *http.Request) bool
+	recordAudit(ctx context.Context, e *auditlog.Entry)
+	shouldRecordAPITokenUse(t *apitoken.Token, now time.Time) bool
+	subscribeEvents(types []string) *eventSubscriber
+	unsubscribeEvents(sub *eventSubscriber)
+	oidcSessionFromCookie(cookieValue string) (username string, groups []string, ok bool)
 	getAuthorizer() auth.Authorizer
 	getAuthenticator() auth.Authenticator
 	getOptions() *Options
@@ -45,6 +53,13 @@ type requestContext struct {
 	body []byte
 	rep  repo.Repository
 	srv  serverInterface
+
+	// authenticated user and their groups
+	username string
+	groups   []string
+
+	// API token used to authenticate the request, if any
+	apiToken *apitoken.Token
 }
 
 func (r *requestContext) muxVar(s string) string {
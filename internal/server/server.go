@@ -18,6 +18,9 @@
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/internal/apitoken"
+	"github.com/kopia/kopia/internal/auditlog"
 	"github.com/kopia/kopia/internal/auth"
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/ctxutil"
@@ -25,6 +28,10 @@
 	"github.com/kopia/kopia/internal/passwordpersist"
 	"github.com/kopia/kopia/internal/serverapi"
 	"github.com/kopia/kopia/internal/uitask"
+	"github.com/kopia/kopia/internal/user"
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/notification/sender"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/logging"
 	"github.com/kopia/kopia/repo/maintenance"
@@ -41,6 +48,11 @@
 	maxMaintenanceAttemptFrequency = 4 * time.Hour
 	sleepOnMaintenanceError        = 30 * time.Minute
 
+	// full maintenance which has not run for this long after it was due is reported as overdue,
+	// no more frequently than once per maintenanceOverdueNotifyInterval.
+	maintenanceOverdueThreshold      = 7 * 24 * time.Hour
+	maintenanceOverdueNotifyInterval = 24 * time.Hour
+
 	// retry initialization of repository starting at 1s doubling delay each time up to max 5 minutes
 	// (1s, 2s, 4s, 8s, 16s, 32s, 64s, 128s, 256s, 300s, which then stays at 300s...)
 	retryInitRepositorySleepOnError    = 1 * time.Second
@@ -96,6 +108,12 @@ type Server struct {
 	taskmgr              *uitask.Manager
 	authCookieSigningKey []byte
 
+	events eventBroker
+
+	apiTokenUseMutex sync.Mutex
+	// +checklocks:apiTokenUseMutex
+	apiTokenLastRecordedUse map[string]time.Time // last-used time of API tokens written by this server
+
 	grpcServerState
 }
 
@@ -109,16 +127,17 @@ func (s *Server) SetupHTMLUIAPIHandlers(m *mux.Router) {
 
 	// snapshots
 	m.HandleFunc("/api/v1/snapshots", s.handleUI(handleListSnapshots)).Methods(http.MethodGet)
-	m.HandleFunc("/api/v1/snapshots/delete", s.handleUI(handleDeleteSnapshots)).Methods(http.MethodPost)
+	m.HandleFunc("/api/v1/snapshots/delete", s.handleUI(audited(auditlog.ActionSnapshotDelete, auditDeleteSnapshotsFromBody, handleDeleteSnapshots))).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/snapshots/edit", s.handleUI(handleEditSnapshots)).Methods(http.MethodPost)
+	m.HandleFunc("/api/v1/snapshots/storage-usage", s.handleUI(handleSnapshotStorageUsage)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/policy", s.handleUI(handlePolicyGet)).Methods(http.MethodGet)
-	m.HandleFunc("/api/v1/policy", s.handleUI(handlePolicyPut)).Methods(http.MethodPut)
-	m.HandleFunc("/api/v1/policy", s.handleUI(handlePolicyDelete)).Methods(http.MethodDelete)
+	m.HandleFunc("/api/v1/policy", s.handleUI(audited(auditlog.ActionPolicySet, auditSourceFromURL, handlePolicyPut))).Methods(http.MethodPut)
+	m.HandleFunc("/api/v1/policy", s.handleUI(audited(auditlog.ActionPolicyDelete, auditSourceFromURL, handlePolicyDelete))).Methods(http.MethodDelete)
 	m.HandleFunc("/api/v1/policy/resolve", s.handleUI(handlePolicyResolve)).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/policies", s.handleUI(handlePolicyList)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/refresh", s.handleUI(handleRefresh)).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/objects/{objectID}", s.requireAuth(csrfTokenNotRequired, handleObjectGet)).Methods(http.MethodGet)
-	m.HandleFunc("/api/v1/restore", s.handleUI(handleRestore)).Methods(http.MethodPost)
+	m.HandleFunc("/api/v1/restore", s.handleUI(audited(auditlog.ActionRestore, auditRootFromBody, handleRestore))).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/estimate", s.handleUI(handleEstimate)).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/paths/resolve", s.handleUI(handlePathResolve)).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/cli", s.handleUI(handleCLIInfo)).Methods(http.MethodGet)
@@ -132,12 +151,28 @@ func (s *Server) SetupHTMLUIAPIHandlers(m *mux.Router) {
 	m.HandleFunc("/api/v1/repo/algorithms", s.handleUIPossiblyNotConnected(handleRepoSupportedAlgorithms)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/repo/throttle", s.handleUI(handleRepoGetThrottle)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/repo/throttle", s.handleUI(handleRepoSetThrottle)).Methods(http.MethodPut)
-
-	m.HandleFunc("/api/v1/mounts", s.handleUI(handleMountCreate)).Methods(http.MethodPost)
-	m.HandleFunc("/api/v1/mounts/{rootObjectID}", s.handleUI(handleMountDelete)).Methods(http.MethodDelete)
+	m.HandleFunc("/api/v1/repo/stats", s.handleUI(handleRepoStats)).Methods(http.MethodGet)
+	m.HandleFunc("/api/v1/maintenance", s.handleUI(handleMaintenanceInfo)).Methods(http.MethodGet)
+	m.HandleFunc("/api/v1/maintenance", s.handleUI(handleMaintenanceSet)).Methods(http.MethodPut)
+	m.HandleFunc("/api/v1/maintenance/run", s.handleUI(handleMaintenanceRun)).Methods(http.MethodPost)
+	m.HandleFunc("/api/v1/maintenance/history", s.handleUI(handleMaintenanceHistory)).Methods(http.MethodGet)
+
+	m.HandleFunc("/api/v1/mounts", s.handleUI(audited(auditlog.ActionMount, auditRootFromBody, handleMountCreate))).Methods(http.MethodPost)
+	m.HandleFunc("/api/v1/mounts/{rootObjectID}", s.handleUI(audited(auditlog.ActionUnmount, auditMuxVar("rootObjectID", "root"), handleMountDelete))).Methods(http.MethodDelete)
 	m.HandleFunc("/api/v1/mounts/{rootObjectID}", s.handleUI(handleMountGet)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/mounts", s.handleUI(handleMountList)).Methods(http.MethodGet)
 
+	m.HandleFunc("/api/v1/users", s.handleAdminAPI(user.ManifestType, handleUserList)).Methods(http.MethodGet)
+	m.HandleFunc("/api/v1/users", s.handleAdminAPI(user.ManifestType, audited(auditlog.ActionUserAdd, auditUserFromBody, handleUserAdd))).Methods(http.MethodPost)
+	m.HandleFunc("/api/v1/users/{username}", s.handleAdminAPI(user.ManifestType, handleUserGet)).Methods(http.MethodGet)
+	m.HandleFunc("/api/v1/users/{username}", s.handleAdminAPI(user.ManifestType, audited(auditlog.ActionUserSetPassword, auditMuxVar("username", "username"), handleUserSetPassword))).Methods(http.MethodPut)
+	m.HandleFunc("/api/v1/users/{username}", s.handleAdminAPI(user.ManifestType, audited(auditlog.ActionUserDelete, auditMuxVar("username", "username"), handleUserDelete))).Methods(http.MethodDelete)
+
+	m.HandleFunc("/api/v1/acls", s.handleAdminAPI(acl.ManifestType, handleACLList)).Methods(http.MethodGet)
+	m.HandleFunc("/api/v1/acls", s.handleAdminAPI(acl.ManifestType, audited(auditlog.ActionACLAdd, auditACLFromBody, handleACLAdd))).Methods(http.MethodPost)
+	m.HandleFunc("/api/v1/acls/{id}", s.handleAdminAPI(acl.ManifestType, audited(auditlog.ActionACLUpdate, auditMuxVar("id", "id"), handleACLUpdate))).Methods(http.MethodPut)
+	m.HandleFunc("/api/v1/acls/{id}", s.handleAdminAPI(acl.ManifestType, audited(auditlog.ActionACLDelete, auditMuxVar("id", "id"), handleACLDelete))).Methods(http.MethodDelete)
+
 	m.HandleFunc("/api/v1/current-user", s.handleUIPossiblyNotConnected(handleCurrentUser)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/ui-preferences", s.handleUIPossiblyNotConnected(handleGetUIPreferences)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/ui-preferences", s.handleUIPossiblyNotConnected(handleSetUIPreferences)).Methods(http.MethodPut)
@@ -147,6 +182,14 @@ func (s *Server) SetupHTMLUIAPIHandlers(m *mux.Router) {
 	m.HandleFunc("/api/v1/tasks/{taskID}", s.handleUIPossiblyNotConnected(handleTaskInfo)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/tasks/{taskID}/logs", s.handleUIPossiblyNotConnected(handleTaskLogs)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/tasks/{taskID}/cancel", s.handleUIPossiblyNotConnected(handleTaskCancel)).Methods(http.MethodPost)
+
+	// real-time events, streamed using Server-Sent Events.
+	m.HandleFunc("/api/v1/events", s.requireAuth(csrfTokenNotRequired, handleEvents)).Methods(http.MethodGet)
+
+	// OpenID Connect login, authentication is performed by the provider.
+	m.HandleFunc(oidcLoginPath, s.handleOIDCLogin).Methods(http.MethodGet)
+	m.HandleFunc(oidcCallbackPath, s.handleOIDCCallback).Methods(http.MethodGet)
+	m.HandleFunc(oidcLogoutPath, s.handleOIDCLogout).Methods(http.MethodGet, http.MethodPost)
 }
 
 // SetupRepositoryAPIHandlers registers HTTP repository API handlers.
@@ -163,6 +206,9 @@ func (s *Server) SetupRepositoryAPIHandlers(m *mux.Router) {
 	m.HandleFunc("/api/v1/manifests/{manifestID}", s.handleRepositoryAPI(handlerWillCheckAuthorization, handleManifestDelete)).Methods(http.MethodDelete)
 	m.HandleFunc("/api/v1/manifests", s.handleRepositoryAPI(handlerWillCheckAuthorization, handleManifestCreate)).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/manifests", s.handleRepositoryAPI(handlerWillCheckAuthorization, handleManifestList)).Methods(http.MethodGet)
+
+	m.HandleFunc("/api/v1/acls/check", s.handleRepositoryAPI(handlerWillCheckAuthorization, handleACLCheck)).Methods(http.MethodPost)
+	m.HandleFunc("/api/v1/acls/lint", s.handleRepositoryAPI(requireACLReadAccess, handleACLLint)).Methods(http.MethodGet)
 }
 
 // SetupControlAPIHandlers registers control API handlers.
@@ -179,14 +225,68 @@ func (s *Server) SetupControlAPIHandlers(m *mux.Router) {
 	m.HandleFunc("/api/v1/control/resume-source", s.handleServerControlAPI(handleResume)).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/control/throttle", s.handleServerControlAPI(handleRepoGetThrottle)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/control/throttle", s.handleServerControlAPI(handleRepoSetThrottle)).Methods(http.MethodPut)
+	m.HandleFunc("/api/v1/control/audit", s.handleServerControlAPIPossiblyNotConnected(handleAuditLogList)).Methods(http.MethodGet)
 }
 
-func isAuthenticated(rc requestContext) bool {
+func isAuthenticated(rc *requestContext) bool {
 	authn := rc.srv.getAuthenticator()
 	if authn == nil {
 		return true
 	}
 
+	if cc := rc.srv.getOptions().ClientCertificates; cc != nil && auth.HasClientCertificate(rc.req.TLS) {
+		id, err := cc.Identity(rc.req.TLS)
+		if err != nil {
+			log(rc.req.Context()).Debugf("invalid client certificate: %v", err)
+			rc.srv.recordAudit(rc.req.Context(), loginEntry(rc.req, "", loginMethodClientCertificate, auditlog.ResultDenied))
+			http.Error(rc.w, "Access denied.\n", http.StatusUnauthorized)
+
+			return false
+		}
+
+		// credentials sent along with the certificate must be for the same user.
+		if username, _, ok := rc.req.BasicAuth(); ok && username != id {
+			rc.srv.recordAudit(rc.req.Context(), loginEntry(rc.req, username, loginMethodClientCertificate, auditlog.ResultDenied))
+			http.Error(rc.w, "Client certificate does not match user.\n", http.StatusUnauthorized)
+			return false
+		}
+
+		rc.username = id
+		rc.srv.recordAudit(rc.req.Context(), loginEntry(rc.req, id, loginMethodClientCertificate, auditlog.ResultSuccess))
+
+		return true
+	}
+
+	if token, ok := bearerToken(rc.req); ok && apitoken.IsAPIToken(token) {
+		return authenticateAPIToken(rc, token)
+	}
+
+	if oidc := rc.srv.getOptions().OIDC; oidc != nil {
+		if token, ok := bearerToken(rc.req); ok {
+			id, err := oidc.VerifyToken(rc.req.Context(), token)
+			if err != nil {
+				log(rc.req.Context()).Debugf("invalid bearer token: %v", err)
+				rc.srv.recordAudit(rc.req.Context(), loginEntry(rc.req, "", loginMethodOIDC, auditlog.ResultDenied))
+				http.Error(rc.w, "Access denied.\n", http.StatusUnauthorized)
+
+				return false
+			}
+
+			rc.username, rc.groups = id.UsernameAtHost, id.Groups
+			rc.srv.recordAudit(rc.req.Context(), loginEntry(rc.req, id.UsernameAtHost, loginMethodOIDC, auditlog.ResultSuccess))
+
+			return true
+		}
+
+		if c, err := rc.req.Cookie(kopiaOIDCSessionCookie); err == nil && c != nil {
+			if username, groups, ok := rc.srv.oidcSessionFromCookie(c.Value); ok {
+				rc.username, rc.groups = username, groups
+
+				return true
+			}
+		}
+	}
+
 	username, password, ok := rc.req.BasicAuth()
 	if !ok {
 		rc.w.Header().Set("WWW-Authenticate", `Basic realm="Kopia"`)
@@ -196,23 +296,30 @@ func isAuthenticated(rc requestContext) bool {
 	}
 
 	if c, err := rc.req.Cookie(kopiaAuthCookie); err == nil && c != nil {
-		if rc.srv.isAuthCookieValid(username, c.Value) {
+		if groups, ok := rc.srv.authCookieGroups(username, c.Value); ok {
 			// found a short-term JWT cookie that matches given username, trust it.
 			// this avoids potentially expensive password hashing inside the authenticator.
+			rc.username, rc.groups = username, groups
+
 			return true
 		}
 	}
 
-	if !authn.IsValid(rc.req.Context(), rc.rep, username, password) {
+	groups, ok := auth.Authenticate(rc.req.Context(), authn, rc.rep, username, password)
+	if !ok {
+		rc.srv.recordAudit(rc.req.Context(), loginEntry(rc.req, username, loginMethodPassword, auditlog.ResultDenied))
 		rc.w.Header().Set("WWW-Authenticate", `Basic realm="Kopia"`)
 		http.Error(rc.w, "Access denied.\n", http.StatusUnauthorized)
 
 		return false
 	}
 
+	rc.username, rc.groups = username, groups
+	rc.srv.recordAudit(rc.req.Context(), loginEntry(rc.req, username, loginMethodPassword, auditlog.ResultSuccess))
+
 	now := clock.Now()
 
-	ac, err := rc.srv.generateShortTermAuthCookie(username, now)
+	ac, err := rc.srv.generateShortTermAuthCookie(username, groups, now)
 	if err != nil {
 		log(rc.req.Context()).Errorf("unable to generate short-term auth cookie: %v", err)
 	} else {
@@ -227,32 +334,48 @@ func isAuthenticated(rc requestContext) bool {
 	return true
 }
 
-func (s *Server) isAuthCookieValid(username, cookieValue string) bool {
-	tok, err := jwt.ParseWithClaims(cookieValue, &jwt.RegisteredClaims{}, func(t *jwt.Token) (interface{}, error) {
+// authCookieClaims are claims of the short-term auth cookie, which carries groups of the user
+// established when the cookie was issued.
+type authCookieClaims struct {
+	jwt.RegisteredClaims
+
+	Groups []string `json:"groups,omitempty"`
+}
+
+// authCookieGroups returns groups stored in the short-term auth cookie if it's valid for the provided user.
+func (s *Server) authCookieGroups(username, cookieValue string) ([]string, bool) {
+	tok, err := jwt.ParseWithClaims(cookieValue, &authCookieClaims{}, func(t *jwt.Token) (interface{}, error) {
 		return s.authCookieSigningKey, nil
 	})
 	if err != nil {
-		return false
+		return nil, false
 	}
 
-	sc, ok := tok.Claims.(*jwt.RegisteredClaims)
+	sc, ok := tok.Claims.(*authCookieClaims)
 	if !ok {
-		return false
+		return nil, false
 	}
 
-	return sc.Subject == username
+	if sc.Subject != username || !sc.VerifyAudience(kopiaAuthCookieAudience, true) {
+		return nil, false
+	}
+
+	return sc.Groups, true
 }
 
-func (s *Server) generateShortTermAuthCookie(username string, now time.Time) (string, error) {
+func (s *Server) generateShortTermAuthCookie(username string, groups []string, now time.Time) (string, error) {
 	//nolint:wrapcheck
-	return jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.RegisteredClaims{
-		Subject:   username,
-		NotBefore: jwt.NewNumericDate(now.Add(-time.Minute)),
-		ExpiresAt: jwt.NewNumericDate(now.Add(kopiaAuthCookieTTL)),
-		IssuedAt:  jwt.NewNumericDate(now),
-		Audience:  jwt.ClaimStrings{kopiaAuthCookieAudience},
-		ID:        uuid.New().String(),
-		Issuer:    kopiaAuthCookieIssuer,
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, &authCookieClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   username,
+			NotBefore: jwt.NewNumericDate(now.Add(-time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(kopiaAuthCookieTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Audience:  jwt.ClaimStrings{kopiaAuthCookieAudience},
+			ID:        uuid.New().String(),
+			Issuer:    kopiaAuthCookieIssuer,
+		},
+		Groups: groups,
 	}).SignedString(s.authCookieSigningKey)
 }
 
@@ -289,11 +412,19 @@ func (s *Server) requireAuth(checkCSRFToken csrfTokenOption, f func(ctx context.
 		rc := s.captureRequestContext(w, r)
 
 		//nolint:contextcheck
-		if !isAuthenticated(rc) {
+		if !isAuthenticated(&rc) {
+			return
+		}
+
+		// API tokens can only be used for routes allowed by their scopes, regardless of the authorization
+		// performed by the handler.
+		if rc.apiToken != nil && !hasAPITokenScope(rc) {
+			http.Error(w, "Access denied.\n", http.StatusForbidden)
 			return
 		}
 
-		if checkCSRFToken == csrfTokenRequired {
+		// API tokens are never sent automatically by browsers, so CSRF protection is not needed.
+		if checkCSRFToken == csrfTokenRequired && rc.apiToken == nil {
 			if !s.validateCSRFToken(r) {
 				http.Error(w, "Invalid or missing CSRF token.\n", http.StatusUnauthorized)
 				return
@@ -306,14 +437,12 @@ func (s *Server) requireAuth(checkCSRFToken csrfTokenOption, f func(ctx context.
 
 func httpAuthorizationInfo(ctx context.Context, rc requestContext) auth.AuthorizationInfo {
 	// authentication already done
-	userAtHost, _, _ := rc.req.BasicAuth()
-
-	authz := rc.srv.getAuthorizer().Authorize(ctx, rc.rep, userAtHost)
+	authz := rc.srv.getAuthorizer().Authorize(auth.WithGroups(ctx, rc.groups), rc.rep, rc.username)
 	if authz == nil {
 		authz = auth.NoAccess()
 	}
 
-	return authz
+	return hideServerOnlyManifests{authz}
 }
 
 type isAuthorizedFunc func(ctx context.Context, rc requestContext) bool
@@ -344,6 +473,19 @@ func (s *Server) handleRepositoryAPI(isAuthorized isAuthorizedFunc, f apiRequest
 	})
 }
 
+// handleAdminAPI handles server administration API, which is available to the UI user and
+// to users with ADMIN access to manifests of a given type. Requests not using API tokens
+// must include a valid CSRF token.
+func (s *Server) handleAdminAPI(manifestType string, f apiRequestFunc) http.HandlerFunc {
+	return s.handleRequestPossiblyNotConnected(requireAdminAccess(manifestType), csrfTokenRequired, func(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+		if rc.rep == nil {
+			return nil, requestError(serverapi.ErrorNotConnected, "not connected")
+		}
+
+		return f(ctx, rc)
+	})
+}
+
 func (s *Server) handleUI(f apiRequestFunc) http.HandlerFunc {
 	return s.handleRequestPossiblyNotConnected(requireUIUser, csrfTokenRequired, func(ctx context.Context, rc requestContext) (interface{}, *apiError) {
 		if rc.rep == nil {
@@ -611,9 +753,15 @@ func (s *Server) refreshPeriodically(ctx context.Context) {
 }
 
 func (s *Server) periodicMaintenance(ctx context.Context, rep repo.DirectRepository) {
+	var lastOverdueNotification time.Time
+
 	for {
 		now := clock.Now()
 
+		if now.Sub(lastOverdueNotification) >= maintenanceOverdueNotifyInterval && notifyIfMaintenanceOverdue(ctx, rep, now) {
+			lastOverdueNotification = now
+		}
+
 		// this will return time == now or in the past if the maintenance is currently runnable
 		// by the current user.
 		nextMaintenanceTime, err := maintenance.TimeToAttemptNextMaintenance(ctx, rep, now.Add(maxMaintenanceAttemptFrequency))
@@ -652,6 +800,39 @@ func (s *Server) periodicMaintenance(ctx context.Context, rep repo.DirectReposit
 	}
 }
 
+// notifyIfMaintenanceOverdue sends a warning notification when full maintenance has not run for a long
+// time after it was due, regardless of its owner, and returns true if it did.
+func notifyIfMaintenanceOverdue(ctx context.Context, rep repo.DirectRepository, now time.Time) bool {
+	p, err := maintenance.GetParams(ctx, rep)
+	if err != nil {
+		log(ctx).Debugw("unable to get maintenance parameters", "error", err)
+		return false
+	}
+
+	sched, err := maintenance.GetSchedule(ctx, rep)
+	if err != nil {
+		log(ctx).Debugw("unable to get maintenance schedule", "error", err)
+		return false
+	}
+
+	if !p.FullCycle.Enabled || sched.NextFullMaintenanceTime.IsZero() {
+		return false
+	}
+
+	overdue := now.Sub(sched.NextFullMaintenanceTime)
+	if overdue < maintenanceOverdueThreshold {
+		return false
+	}
+
+	notification.Send(ctx, rep, notifytemplate.MaintenanceOverdue, &notification.MaintenanceOverdue{
+		Owner:   p.Owner,
+		DueTime: sched.NextFullMaintenanceTime,
+		Overdue: overdue.Truncate(time.Hour),
+	}, sender.SeverityWarning)
+
+	return true
+}
+
 func periodicMaintenanceOnce(ctx context.Context, rep repo.Repository) error {
 	dr, ok := rep.(repo.DirectRepository)
 	if !ok {
@@ -815,8 +996,14 @@ func (s *Server) ServeStaticFiles(m *mux.Router, fs http.FileSystem) {
 
 		rc := s.captureRequestContext(w, r)
 
+		if s.options.OIDC != nil && !hasCredentials(r) {
+			// send users without credentials to the OpenID Connect provider to log in.
+			http.Redirect(w, r, oidcLoginPath, http.StatusFound)
+			return
+		}
+
 		//nolint:contextcheck
-		if !isAuthenticated(rc) {
+		if !isAuthenticated(&rc) {
 			return
 		}
 
@@ -866,6 +1053,14 @@ type Options struct {
 	ServerControlUser      string // name of the user allowed to access the server control API
 	DisableCSRFTokenChecks bool
 	UITitlePrefix          string
+
+	OIDC            *auth.OIDCAuthenticator // OpenID Connect authenticator, also included in Authenticator
+	OIDCUIGroup     string                  // members of this group are allowed to access the UI API
+	OIDCRedirectURL string                  // URL of the OpenID Connect callback, determined from request when empty
+
+	ClientCertificates *auth.ClientCertificateAuthenticator // authenticates users presenting verified TLS client certificates
+
+	AuditLog *auditlog.Log // records security-relevant operations, optional
 }
 
 // InitRepositoryFunc is a function that attempts to connect to/open repository.
@@ -1053,6 +1248,7 @@ func New(ctx context.Context, options *Options) (*Server, error) {
 	}
 
 	s.parallelSnapshotsChanged = sync.NewCond(&s.parallelSnapshotsMutex)
+	s.taskmgr.AddListener(s.publishTaskEvent)
 
 	return s, nil
 }
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kopia/kopia/internal/apitoken"
+	"github.com/kopia/kopia/internal/auditlog"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/ctxutil"
+	"github.com/kopia/kopia/repo"
+)
+
+// apiTokenLastUsedUpdateInterval limits how often last-used time of API tokens is written to the repository.
+const apiTokenLastUsedUpdateInterval = time.Hour
+
+// apiTokenUsernamePrefix is prepended to token identifiers to form the name of the authenticated user.
+const apiTokenUsernamePrefix = "apitoken:"
+
+// apiTokenScopes maps method and path template of each route that can be accessed using
+// API tokens to the required token scope. Routes which are not listed require ScopeAdmin,
+// so that newly added routes are never accessible to tokens with narrower scopes.
+//
+//nolint:gochecknoglobals
+var apiTokenScopes = map[string]apitoken.Scope{
+	"GET /api/v1/sources":                   apitoken.ScopeStatus,
+	"GET /api/v1/snapshots":                 apitoken.ScopeStatus,
+	"GET /api/v1/snapshots/storage-usage":   apitoken.ScopeStatus,
+	"GET /api/v1/policy":                    apitoken.ScopeStatus,
+	"POST /api/v1/policy/resolve":           apitoken.ScopeStatus,
+	"GET /api/v1/policies":                  apitoken.ScopeStatus,
+	"POST /api/v1/estimate":                 apitoken.ScopeStatus,
+	"POST /api/v1/paths/resolve":            apitoken.ScopeStatus,
+	"GET /api/v1/cli":                       apitoken.ScopeStatus,
+	"GET /api/v1/repo/status":               apitoken.ScopeStatus,
+	"GET /api/v1/repo/algorithms":           apitoken.ScopeStatus,
+	"GET /api/v1/repo/throttle":             apitoken.ScopeStatus,
+	"GET /api/v1/repo/stats":                apitoken.ScopeStatus,
+	"GET /api/v1/maintenance":               apitoken.ScopeStatus,
+	"GET /api/v1/maintenance/history":       apitoken.ScopeStatus,
+	"GET /api/v1/current-user":              apitoken.ScopeStatus,
+	"GET /api/v1/tasks-summary":             apitoken.ScopeStatus,
+	"GET /api/v1/tasks":                     apitoken.ScopeStatus,
+	"GET /api/v1/tasks/{taskID}":            apitoken.ScopeStatus,
+	"GET /api/v1/tasks/{taskID}/logs":       apitoken.ScopeStatus,
+	"GET /api/v1/events":                    apitoken.ScopeStatus,
+	"GET /api/v1/control/sources":           apitoken.ScopeStatus,
+	"GET /api/v1/control/status":            apitoken.ScopeStatus,
+	"GET /api/v1/control/throttle":          apitoken.ScopeStatus,
+	"POST /api/v1/control/trigger-snapshot": apitoken.ScopeTriggerSnapshot,
+	"POST /api/v1/control/cancel-snapshot":  apitoken.ScopeTriggerSnapshot,
+	"POST /api/v1/sources/upload":           apitoken.ScopeTriggerSnapshot,
+	"POST /api/v1/sources/cancel":           apitoken.ScopeTriggerSnapshot,
+	"POST /api/v1/restore":                  apitoken.ScopeRestore,
+	"GET /api/v1/mounts":                    apitoken.ScopeRestore,
+	"POST /api/v1/mounts":                   apitoken.ScopeRestore,
+	"GET /api/v1/mounts/{rootObjectID}":     apitoken.ScopeRestore,
+	"DELETE /api/v1/mounts/{rootObjectID}":  apitoken.ScopeRestore,
+	"GET /api/v1/objects/{objectID}":        apitoken.ScopeRestore,
+}
+
+// requiredAPITokenScope returns the token scope required to make the provided request.
+func requiredAPITokenScope(r *http.Request) apitoken.Scope {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return apitoken.ScopeAdmin
+	}
+
+	tmpl, err := route.GetPathTemplate()
+	if err != nil {
+		return apitoken.ScopeAdmin
+	}
+
+	if s, ok := apiTokenScopes[r.Method+" "+tmpl]; ok {
+		return s
+	}
+
+	return apitoken.ScopeAdmin
+}
+
+// authenticateAPIToken authenticates the request using API token stored in the repository.
+func authenticateAPIToken(rc *requestContext, tokenString string) bool {
+	ctx := rc.req.Context()
+
+	if rc.rep == nil {
+		http.Error(rc.w, "Access denied.\n", http.StatusUnauthorized)
+		return false
+	}
+
+	now := clock.Now()
+
+	t, err := apitoken.Verify(ctx, rc.rep, tokenString, now)
+	if err != nil {
+		log(ctx).Debugf("invalid API token: %v", err)
+		rc.srv.recordAudit(ctx, loginEntry(rc.req, "", loginMethodAPIToken, auditlog.ResultDenied))
+		http.Error(rc.w, "Access denied.\n", http.StatusUnauthorized)
+
+		return false
+	}
+
+	rc.username = apiTokenUsernamePrefix + t.ID
+	rc.apiToken = t
+
+	if rc.srv.shouldRecordAPITokenUse(t, now) {
+		rep := rc.rep
+
+		ctxutil.GoDetached(ctx, func(ctx context.Context) {
+			recordAPITokenUse(ctx, rep, t.ID, now)
+		})
+	}
+
+	return true
+}
+
+// shouldRecordAPITokenUse determines whether last-used time of the token needs to be written to
+// the repository. Writes which are in progress or not yet visible to the server are tracked in
+// memory, so that subsequent requests don't write it again.
+func (s *Server) shouldRecordAPITokenUse(t *apitoken.Token, now time.Time) bool {
+	s.apiTokenUseMutex.Lock()
+	defer s.apiTokenUseMutex.Unlock()
+
+	lastUsed := t.LastUsedTime
+	if recorded := s.apiTokenLastRecordedUse[t.ID]; recorded.After(lastUsed) {
+		lastUsed = recorded
+	}
+
+	if now.Sub(lastUsed) < apiTokenLastUsedUpdateInterval {
+		return false
+	}
+
+	if s.apiTokenLastRecordedUse == nil {
+		s.apiTokenLastRecordedUse = map[string]time.Time{}
+	}
+
+	s.apiTokenLastRecordedUse[t.ID] = now
+
+	return true
+}
+
+// recordAPITokenUse updates last-used time of the token in the background, failures are logged.
+// The update becomes visible to the server on next repository refresh.
+func recordAPITokenUse(ctx context.Context, rep repo.Repository, tokenID string, now time.Time) {
+	if err := repo.WriteSession(ctx, rep, repo.WriteSessionOptions{
+		Purpose: "APITokenUsed",
+	}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		// reload the token, so that a token revoked in the meantime is not written back.
+		t, err := apitoken.Get(ctx, w, tokenID)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		t.LastUsedTime = now
+
+		return apitoken.SetToken(ctx, w, t)
+	}); err != nil {
+		log(ctx).Errorf("unable to record use of API token %v: %v", tokenID, err)
+	}
+}
+
+// hasAPITokenScope determines whether the request was authenticated using API token with a scope required to make it.
+func hasAPITokenScope(rc requestContext) bool {
+	return rc.apiToken.HasScope(requiredAPITokenScope(rc.req))
+}
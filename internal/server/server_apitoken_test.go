@@ -0,0 +1,169 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/apitoken"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/servertesting"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob/throttling"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/snapshot"
+)
+
+func TestServer_APITokens(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	w := env.RepositoryWriter
+	now := w.Time()
+
+	statusToken, statusTokenString, err := apitoken.Create(ctx, w, "status", []apitoken.Scope{apitoken.ScopeStatus}, time.Time{})
+	require.NoError(t, err)
+
+	_, triggerTokenString, err := apitoken.Create(ctx, w, "trigger", []apitoken.Scope{apitoken.ScopeTriggerSnapshot}, time.Time{})
+	require.NoError(t, err)
+
+	_, adminTokenString, err := apitoken.Create(ctx, w, "admin", []apitoken.Scope{apitoken.ScopeAdmin}, time.Time{})
+	require.NoError(t, err)
+
+	_, expiredTokenString, err := apitoken.Create(ctx, w, "expired", []apitoken.Scope{apitoken.ScopeAdmin}, now.Add(-time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, w.Flush(ctx))
+
+	si := servertesting.StartServer(t, env, false)
+
+	newClient := func(token string) *apiclient.KopiaAPIClient {
+		cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+			BaseURL:     si.BaseURL,
+			BearerToken: token,
+		})
+		require.NoError(t, err)
+
+		return cli
+	}
+
+	// read-only token can get status of the server and sources, no CSRF token is needed.
+	statusCli := newClient(statusTokenString)
+
+	_, err = serverapi.Status(ctx, statusCli)
+	require.NoError(t, err)
+
+	_, err = serverapi.ListSources(ctx, statusCli, nil)
+	require.NoError(t, err)
+
+	_, err = serverapi.UploadSnapshots(ctx, statusCli, nil)
+	require.ErrorContains(t, err, "403")
+
+	require.ErrorContains(t, serverapi.SetThrottlingLimits(ctx, statusCli, throttling.Limits{}), "403")
+
+	_, err = serverapi.ListUsers(ctx, statusCli)
+	require.ErrorContains(t, err, "403")
+
+	// routes without a dedicated scope require admin scope.
+	var prefs serverapi.UIPreferences
+	require.ErrorContains(t, statusCli.Get(ctx, "ui-preferences", nil, &prefs), "403")
+
+	// repository API routes are subject to token scopes too.
+	require.ErrorContains(t, statusCli.Post(ctx, "flush", nil, nil), "403")
+
+	var params map[string]interface{}
+	require.ErrorContains(t, statusCli.Get(ctx, "repo/parameters", nil, &params), "403")
+
+	// last-used time is recorded in the background.
+	require.Eventually(t, func() bool {
+		require.NoError(t, env.Repository.Refresh(ctx))
+
+		tok, err := apitoken.Get(ctx, env.Repository, statusToken.ID)
+		require.NoError(t, err)
+
+		return !tok.LastUsedTime.IsZero()
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// trigger-snapshot token can only start snapshots.
+	triggerCli := newClient(triggerTokenString)
+
+	// there are no sources, but the request was authorized.
+	_, err = serverapi.UploadSnapshots(ctx, triggerCli, nil)
+	require.ErrorContains(t, err, "404")
+
+	_, err = serverapi.Status(ctx, triggerCli)
+	require.ErrorContains(t, err, "403")
+
+	// admin token can do everything.
+	adminCli := newClient(adminTokenString)
+
+	_, err = serverapi.Status(ctx, adminCli)
+	require.NoError(t, err)
+
+	require.NoError(t, serverapi.SetThrottlingLimits(ctx, adminCli, throttling.Limits{}))
+
+	_, err = serverapi.ListUsers(ctx, adminCli)
+	require.NoError(t, err)
+
+	require.NoError(t, adminCli.Post(ctx, "flush", nil, nil))
+
+	// expired, malformed and unknown tokens are rejected.
+	for _, tok := range []string{expiredTokenString, "kopia_0123_4567", "kopia_invalid"} {
+		_, err = serverapi.Status(ctx, newClient(tok))
+		require.ErrorContains(t, err, "401")
+	}
+}
+
+func TestServer_ServerOnlyManifestsCantBeAccessedByRepositoryClients(t *testing.T) {
+	for _, disableGRPC := range []bool{false, true} {
+		disableGRPC := disableGRPC
+
+		t.Run(fmt.Sprintf("disableGRPC=%v", disableGRPC), func(t *testing.T) {
+			ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+			_, _, err := apitoken.Create(ctx, env.RepositoryWriter, "existing", []apitoken.Scope{apitoken.ScopeAdmin}, time.Time{})
+			require.NoError(t, err)
+			require.NoError(t, notifyprofile.SaveProfile(ctx, env.RepositoryWriter, &notifyprofile.Config{ProfileName: "existing"}))
+			require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+			apiServerInfo := servertesting.StartServer(t, env, true)
+			apiServerInfo.DisableGRPC = disableGRPC
+
+			rep, err := servertesting.ConnectAndOpenAPIServer(t, ctx, apiServerInfo, repo.ClientOptions{
+				Username: servertesting.TestUsername,
+				Hostname: servertesting.TestHostname,
+			}, content.CachingOptions{
+				CacheDirectory: testutil.TempDirectory(t),
+			}, servertesting.TestPassword, &repo.Options{})
+			require.NoError(t, err)
+
+			defer rep.Close(ctx)
+
+			for _, labels := range []map[string]string{
+				{manifest.TypeLabelKey: apitoken.ManifestType, apitoken.IDLabel: "0123456789abcdef"},
+				{manifest.TypeLabelKey: notifyprofile.ManifestType, "profile": "planted"},
+			} {
+				// username and hostname labels would otherwise grant the user full access to the manifest.
+				labels[snapshot.UsernameLabel] = servertesting.TestUsername
+				labels[snapshot.HostnameLabel] = servertesting.TestHostname
+
+				err = repo.WriteSession(ctx, rep, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.RepositoryWriter) error {
+					_, err := w.PutManifest(ctx, labels, map[string]string{})
+					return err
+				})
+				require.Error(t, err)
+
+				found, err := rep.FindManifests(ctx, map[string]string{manifest.TypeLabelKey: labels[manifest.TypeLabelKey]})
+				require.NoError(t, err)
+				require.Empty(t, found)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/internal/apitoken"
+	"github.com/kopia/kopia/internal/auditlog"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/user"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+// Methods of authentication recorded in the audit log for logins.
+const (
+	loginMethodPassword          = "password"
+	loginMethodClientCertificate = "client-certificate"
+	loginMethodAPIToken          = "api-token"
+	loginMethodOIDC              = "oidc"
+	loginMethodGRPC              = "grpc"
+)
+
+// auditedManifestTypes lists types of manifests whose creation is recorded in the audit log,
+// deletion of manifests is always recorded.
+//
+//nolint:gochecknoglobals
+var auditedManifestTypes = map[string]bool{
+	policy.ManifestType:   true,
+	acl.ManifestType:      true,
+	acl.GroupManifestType: true,
+	user.ManifestType:     true,
+	apitoken.ManifestType: true,
+}
+
+// auditRecorder records the result of an operation performed by an authenticated user in the audit log.
+type auditRecorder func(ctx context.Context, action string, target map[string]string, result auditlog.Result, err error)
+
+func (s *Server) recordAudit(ctx context.Context, e *auditlog.Entry) {
+	s.options.AuditLog.Record(ctx, e)
+}
+
+// newAuditRecorder returns auditRecorder for the provided user connected from a given address.
+func (s *Server) newAuditRecorder(usernameAtHost, remoteAddress string) auditRecorder {
+	return func(ctx context.Context, action string, target map[string]string, result auditlog.Result, err error) {
+		e := auditlog.NewEntry(clock.Now(), usernameAtHost, action, target, result, err)
+		e.RemoteAddress = remoteAddress
+
+		s.recordAudit(ctx, e)
+	}
+}
+
+// loginEntry returns audit log entry describing successful or failed login of a given user.
+func loginEntry(r *http.Request, usernameAtHost, method string, result auditlog.Result) *auditlog.Entry {
+	e := auditlog.NewEntry(clock.Now(), usernameAtHost, auditlog.ActionLogin, map[string]string{"method": method}, result, nil)
+	e.RemoteAddress = r.RemoteAddr
+
+	return e
+}
+
+// audit records the result of an operation performed by the authenticated user in the audit log.
+func (r *requestContext) audit(ctx context.Context, action string, target map[string]string, aerr *apiError) {
+	e := auditlog.NewEntry(clock.Now(), r.username, action, target, auditlog.ResultSuccess, nil)
+	e.RemoteAddress = r.req.RemoteAddr
+
+	switch {
+	case aerr == nil:
+	case aerr.httpErrorCode == http.StatusForbidden:
+		e.Result = auditlog.ResultDenied
+	default:
+		e.Result = auditlog.ResultFailure
+		e.Error = aerr.message
+	}
+
+	r.srv.recordAudit(ctx, e)
+}
+
+// isAuditedManifest determines whether creation of manifest with given labels should be recorded in the audit log.
+func isAuditedManifest(labels map[string]string) bool {
+	return auditedManifestTypes[labels[manifest.TypeLabelKey]]
+}
+
+// audited returns a request handler which records the result of the provided handler in the audit log.
+func audited(action string, target func(rc requestContext) map[string]string, f apiRequestFunc) apiRequestFunc {
+	return func(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+		v, aerr := f(ctx, rc)
+
+		rc.audit(ctx, action, target(rc), aerr)
+
+		return v, aerr
+	}
+}
+
+// manifestAuditTarget returns audit target of manifest operations consisting of manifest labels and ID.
+func manifestAuditTarget(id manifest.ID, labels map[string]string) map[string]string {
+	result := map[string]string{"manifestID": string(id)}
+
+	for k, v := range labels {
+		result[k] = v
+	}
+
+	return result
+}
+
+func sourceTarget(si snapshot.SourceInfo) map[string]string {
+	return map[string]string{
+		snapshot.UsernameLabel: si.UserName,
+		snapshot.HostnameLabel: si.Host,
+		snapshot.PathLabel:     si.Path,
+	}
+}
+
+// auditSourceFromURL returns audit target of requests identifying snapshot source in the URL.
+func auditSourceFromURL(rc requestContext) map[string]string {
+	return sourceTarget(getSnapshotSourceFromURL(rc.req.URL))
+}
+
+// auditMuxVar returns a function which returns audit target of requests identifying the target in the URL path.
+func auditMuxVar(muxVar, targetKey string) func(rc requestContext) map[string]string {
+	return func(rc requestContext) map[string]string {
+		return map[string]string{targetKey: rc.muxVar(muxVar)}
+	}
+}
+
+// auditRootFromBody returns audit target of restore and mount requests.
+func auditRootFromBody(rc requestContext) map[string]string {
+	var req struct {
+		Root string `json:"root"`
+	}
+
+	json.Unmarshal(rc.body, &req) //nolint:errcheck
+
+	return map[string]string{"root": req.Root}
+}
+
+// auditUserFromBody returns audit target of requests adding users, without their passwords.
+func auditUserFromBody(rc requestContext) map[string]string {
+	var req serverapi.AddUserRequest
+
+	json.Unmarshal(rc.body, &req) //nolint:errcheck
+
+	return map[string]string{"username": req.Username}
+}
+
+// auditACLFromBody returns audit target of requests adding ACL entries.
+func auditACLFromBody(rc requestContext) map[string]string {
+	var req serverapi.AddACLRequest
+
+	json.Unmarshal(rc.body, &req) //nolint:errcheck
+
+	result := map[string]string{
+		"user": req.User,
+		"role": req.Role,
+	}
+
+	if len(req.Target) > 0 {
+		result["target"] = acl.TargetRule(req.Target).String()
+		result["access"] = req.Access.String()
+	}
+
+	return result
+}
+
+// auditDeleteSnapshotsFromBody returns audit target of requests deleting snapshots.
+func auditDeleteSnapshotsFromBody(rc requestContext) map[string]string {
+	var req serverapi.DeleteSnapshotsRequest
+
+	json.Unmarshal(rc.body, &req) //nolint:errcheck
+
+	var ids []string
+	for _, id := range req.SnapshotManifestIDs {
+		ids = append(ids, string(id))
+	}
+
+	result := sourceTarget(req.SourceInfo)
+	result["snapshots"] = strings.Join(ids, ",")
+
+	if req.DeleteSourceAndPolicy {
+		result["deleteSourceAndPolicy"] = "true"
+	}
+
+	return result
+}
+
+func handleAuditLogList(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	al := rc.srv.getOptions().AuditLog
+	if !al.Enabled() {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "audit log is not enabled")
+	}
+
+	f := auditlog.Filter{
+		User:   rc.queryParam("user"),
+		Action: rc.queryParam("action"),
+	}
+
+	if v := rc.queryParam("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, requestError(serverapi.ErrorMalformedRequest, "invalid 'since' time")
+		}
+
+		f.Since = t
+	}
+
+	if v := rc.queryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, requestError(serverapi.ErrorMalformedRequest, "invalid limit")
+		}
+
+		f.Limit = n
+	}
+
+	entries, malformed, err := al.Read(f)
+	if err != nil {
+		return nil, internalServerError(errors.Wrap(err, "unable to read audit log"))
+	}
+
+	if malformed > 0 {
+		log(ctx).Warnf("skipped %v malformed entries of audit log %v", malformed, al.Path())
+	}
+
+	return &serverapi.AuditLogResponse{Entries: entries, Malformed: malformed}, nil
+}
@@ -0,0 +1,141 @@
+package server_test
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/auditlog"
+	"github.com/kopia/kopia/internal/auth"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/server"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/servertesting"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+func TestServer_AuditLog(t *testing.T) {
+	const (
+		controlUsername = "server-control"
+		controlPassword = "control-password"
+	)
+
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	al, err := auditlog.Open(filepath.Join(testutil.TempDirectory(t), "audit.log"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { al.Close() }) //nolint:errcheck
+
+	si := servertesting.StartServerWithOptions(t, env, true, func(o *server.Options) {
+		o.AuditLog = al
+		o.ServerControlUser = controlUsername
+		o.Authenticator = auth.CombineAuthenticators(o.Authenticator, auth.AuthenticateSingleUser(controlUsername, controlPassword))
+	})
+
+	newClient := func(username, password string) *apiclient.KopiaAPIClient {
+		cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+			BaseURL:                             si.BaseURL,
+			TrustedServerCertificateFingerprint: si.TrustedServerCertificateFingerprint,
+			Username:                            username,
+			Password:                            password,
+		})
+		require.NoError(t, err)
+
+		return cli
+	}
+
+	// failed login.
+	_, err = serverapi.Status(ctx, newClient(controlUsername, "wrong-password"))
+	require.ErrorContains(t, err, "401")
+
+	// policy change made using the UI.
+	uiCli := newClient(servertesting.TestUIUsername, servertesting.TestUIPassword)
+	require.NoError(t, uiCli.FetchCSRFTokenForTesting(ctx))
+
+	src := env.LocalPathSourceInfo(testutil.TempDirectory(t))
+	require.NoError(t, serverapi.SetPolicy(ctx, uiCli, src, &policy.Policy{}))
+
+	// manifest changes made by a repository client.
+	rep, err := servertesting.ConnectAndOpenAPIServer(t, ctx, si, repo.ClientOptions{
+		Username: servertesting.TestUsername,
+		Hostname: servertesting.TestHostname,
+	}, content.CachingOptions{
+		CacheDirectory: testutil.TempDirectory(t),
+	}, servertesting.TestPassword, &repo.Options{})
+	require.NoError(t, err)
+
+	defer rep.Close(ctx)
+
+	var snapshotManifestID manifest.ID
+
+	require.NoError(t, repo.WriteSession(ctx, rep, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		clientSource := snapshot.SourceInfo{UserName: servertesting.TestUsername, Host: servertesting.TestHostname, Path: "/some/path"}
+
+		require.NoError(t, policy.SetPolicy(ctx, w, clientSource, &policy.Policy{}))
+
+		snapshotManifestID, err = w.PutManifest(ctx, map[string]string{
+			manifest.TypeLabelKey:  snapshot.ManifestType,
+			snapshot.UsernameLabel: servertesting.TestUsername,
+			snapshot.HostnameLabel: servertesting.TestHostname,
+			snapshot.PathLabel:     "/some/path",
+		}, map[string]string{})
+		require.NoError(t, err)
+
+		return w.DeleteManifest(ctx, snapshotManifestID)
+	}))
+
+	controlCli := newClient(controlUsername, controlPassword)
+
+	list := func(q url.Values) []*auditlog.Entry {
+		t.Helper()
+
+		resp, err := serverapi.ListAuditLog(ctx, controlCli, q)
+		require.NoError(t, err)
+
+		return resp.Entries
+	}
+
+	logins := list(url.Values{"action": {auditlog.ActionLogin}, "user": {controlUsername}})
+	require.NotEmpty(t, logins)
+	require.Equal(t, controlUsername, logins[0].User)
+	require.Equal(t, auditlog.ResultDenied, logins[0].Result)
+	require.Equal(t, "password", logins[0].Target["method"])
+
+	grpcLogins := list(url.Values{"action": {auditlog.ActionLogin}, "user": {servertesting.TestUsername + "@" + servertesting.TestHostname}})
+	require.NotEmpty(t, grpcLogins)
+	require.Equal(t, auditlog.ResultSuccess, grpcLogins[0].Result)
+	require.Equal(t, "grpc", grpcLogins[0].Target["method"])
+
+	policySet := list(url.Values{"action": {auditlog.ActionPolicySet}})
+	require.Len(t, policySet, 1)
+	require.Equal(t, servertesting.TestUIUsername, policySet[0].User)
+	require.Equal(t, auditlog.ResultSuccess, policySet[0].Result)
+	require.Equal(t, src.Path, policySet[0].Target[snapshot.PathLabel])
+
+	// only creation of policy manifest is recorded, snapshot manifest is not.
+	puts := list(url.Values{"action": {auditlog.ActionManifestPut}})
+	require.Len(t, puts, 1)
+	require.Equal(t, policy.ManifestType, puts[0].Target[manifest.TypeLabelKey])
+	require.Equal(t, servertesting.TestHostname, puts[0].Host)
+
+	deletes := list(url.Values{"action": {auditlog.ActionManifestDelete}})
+	require.Len(t, deletes, 1)
+	require.Equal(t, string(snapshotManifestID), deletes[0].Target["manifestID"])
+	require.Equal(t, auditlog.ResultSuccess, deletes[0].Result)
+
+	require.Len(t, list(url.Values{"limit": {"2"}}), 2)
+
+	// audit log is only available to the server control user.
+	_, err = serverapi.ListAuditLog(ctx, uiCli, nil)
+	require.ErrorContains(t, err, "403")
+}
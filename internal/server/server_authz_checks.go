@@ -10,7 +10,10 @@
 	"net/http"
 
 	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/apitoken"
 	"github.com/kopia/kopia/internal/auth"
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/repo/manifest"
 )
 
 // kopiaSessionCookie is the name of the session cookie that Kopia server will generate for all
@@ -59,18 +62,52 @@ func (s *Server) validateCSRFToken(r *http.Request) bool {
 	return false
 }
 
+// serverOnlyManifestTypes lists types of manifests which the server trusts to authenticate users or to
+// run commands. Remote clients can't read, write or delete them regardless of ACLs, since otherwise they
+// could plant manifests granting themselves additional privileges.
+//
+//nolint:gochecknoglobals
+var serverOnlyManifestTypes = map[string]bool{
+	apitoken.ManifestType:      true,
+	notifyprofile.ManifestType: true,
+}
+
+// hideServerOnlyManifests denies access to server-only manifests and delegates everything else.
+type hideServerOnlyManifests struct {
+	auth.AuthorizationInfo
+}
+
+func (h hideServerOnlyManifests) ManifestAccessLevel(labels map[string]string) auth.AccessLevel {
+	if serverOnlyManifestTypes[labels[manifest.TypeLabelKey]] {
+		return auth.AccessLevelNone
+	}
+
+	return h.AuthorizationInfo.ManifestAccessLevel(labels)
+}
+
 func requireUIUser(ctx context.Context, rc requestContext) bool {
 	if rc.srv.getAuthenticator() == nil {
 		return true
 	}
 
-	if rc.srv.getOptions().UIUser == "" {
-		return false
+	if rc.apiToken != nil {
+		return hasAPITokenScope(rc)
 	}
 
-	user, _, _ := rc.req.BasicAuth()
+	return isUIUser(rc.srv.getOptions(), rc.username, rc.groups)
+}
 
-	return user == rc.srv.getOptions().UIUser
+// isUIUser determines whether the provided user is allowed to access the UI API.
+func isUIUser(opts *Options, username string, groups []string) bool {
+	if opts.OIDCUIGroup != "" {
+		for _, g := range groups {
+			if g == opts.OIDCUIGroup {
+				return true
+			}
+		}
+	}
+
+	return opts.UIUser != "" && username == opts.UIUser
 }
 
 func requireServerControlUser(ctx context.Context, rc requestContext) bool {
@@ -78,13 +115,31 @@ func requireServerControlUser(ctx context.Context, rc requestContext) bool {
 		return true
 	}
 
+	if rc.apiToken != nil {
+		return hasAPITokenScope(rc)
+	}
+
 	if rc.srv.getOptions().ServerControlUser == "" {
 		return false
 	}
 
-	user, _, _ := rc.req.BasicAuth()
+	return rc.username == rc.srv.getOptions().ServerControlUser
+}
 
-	return user == rc.srv.getOptions().ServerControlUser
+// requireAdminAccess allows users with ADMIN access to manifests of a given type to use server
+// administration API. The UI user is always allowed and API tokens must have the admin scope.
+func requireAdminAccess(manifestType string) isAuthorizedFunc {
+	return func(ctx context.Context, rc requestContext) bool {
+		if rc.apiToken != nil {
+			return rc.apiToken.HasScope(apitoken.ScopeAdmin)
+		}
+
+		if rc.srv.getAuthenticator() == nil || isUIUser(rc.srv.getOptions(), rc.username, rc.groups) {
+			return true
+		}
+
+		return hasManifestAccess(ctx, rc, map[string]string{manifest.TypeLabelKey: manifestType}, auth.AccessLevelAdmin)
+	}
 }
 
 func anyAuthenticatedUser(ctx context.Context, _ requestContext) bool {
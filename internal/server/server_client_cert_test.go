@@ -0,0 +1,161 @@
+package server_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/auditlog"
+	"github.com/kopia/kopia/internal/auth"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/server"
+	"github.com/kopia/kopia/internal/servertesting"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert, key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	p := x509.NewCertPool()
+	p.AddCert(ca.cert)
+
+	return p
+}
+
+// issueClientCertificate issues client certificate for the provided common name and returns the names of PEM files
+// with the certificate and its key.
+func (ca *testCA) issueClientCertificate(t *testing.T, commonName string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certFile, keyFile
+}
+
+func TestServer_ClientCertificates(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	ca := newTestCA(t)
+	al, err := auditlog.Open(filepath.Join(testutil.TempDirectory(t), "audit.log"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { al.Close() }) //nolint:errcheck
+
+	si := servertesting.StartServerWithClientCertificates(t, env, ca.pool(), auth.ClientCertificateIdentityCommonName, func(o *server.Options) {
+		o.AuditLog = al
+	})
+
+	aliceCert, aliceKey := ca.issueClientCertificate(t, "alice@laptop")
+	otherCert, otherKey := newTestCA(t).issueClientCertificate(t, "alice@laptop")
+
+	connect := func(username, hostname, password, certFile, keyFile string, disableGRPC bool) error {
+		asi := *si
+		asi.DisableGRPC = disableGRPC
+		asi.ClientCertificateFile = certFile
+		asi.ClientKeyFile = keyFile
+
+		rep, err := servertesting.ConnectAndOpenAPIServer(t, ctx, &asi, repo.ClientOptions{
+			Username: username,
+			Hostname: hostname,
+		}, content.CachingOptions{}, password, &repo.Options{})
+		if err != nil {
+			return err
+		}
+
+		return rep.Close(ctx)
+	}
+
+	for _, disableGRPC := range []bool{false, true} {
+		// certificate identifies the user, no password is needed.
+		require.NoError(t, connect("alice", "laptop", "", aliceCert, aliceKey, disableGRPC))
+
+		// certificate issued to another user.
+		require.Error(t, connect("bob", "laptop", "", aliceCert, aliceKey, disableGRPC))
+
+		// certificate issued by untrusted authority.
+		require.Error(t, connect("alice", "laptop", "", otherCert, otherKey, disableGRPC))
+
+		// clients without certificates can still use passwords.
+		require.NoError(t, connect(servertesting.TestUsername, servertesting.TestHostname, servertesting.TestPassword, "", "", disableGRPC))
+	}
+
+	// both successful and denied logins using certificates are audited.
+	logins, _, err := al.Read(auditlog.Filter{Action: auditlog.ActionLogin})
+	require.NoError(t, err)
+
+	results := map[string]auditlog.Result{}
+
+	for _, e := range logins {
+		if e.Target["method"] == "client-certificate" {
+			results[e.UsernameAtHost()] = e.Result
+		}
+	}
+
+	require.Equal(t, map[string]auditlog.Result{
+		"alice@laptop": auditlog.ResultSuccess,
+		"bob@laptop":   auditlog.ResultDenied,
+	}, results)
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/uitask"
+)
+
+const (
+	// eventSubscriberBufferSize is the number of events buffered for each subscriber,
+	// events sent to subscribers which don't keep up are dropped.
+	eventSubscriberBufferSize = 1000
+
+	// eventStreamKeepAliveInterval is the interval between comments sent to idle event streams
+	// to keep connections through proxies alive.
+	eventStreamKeepAliveInterval = 30 * time.Second
+)
+
+//nolint:gochecknoglobals
+var uitaskEventTypes = map[uitask.EventType]string{
+	uitask.EventStarted:  serverapi.EventTaskStarted,
+	uitask.EventProgress: serverapi.EventTaskProgress,
+	uitask.EventLog:      serverapi.EventTaskLog,
+	uitask.EventFinished: serverapi.EventTaskFinished,
+}
+
+type eventWithID struct {
+	id int64
+	ev *serverapi.Event
+}
+
+// eventSubscriber receives events of selected types, or all events if no types are selected.
+type eventSubscriber struct {
+	types  map[string]bool
+	events chan eventWithID
+}
+
+// eventBroker delivers server events to all subscribers.
+type eventBroker struct {
+	mu sync.Mutex
+	// +checklocks:mu
+	nextEventID int64
+	// +checklocks:mu
+	subscribers map[*eventSubscriber]struct{}
+	// +checklocks:mu
+	closed bool
+}
+
+// publish sends the event to all interested subscribers without blocking.
+func (b *eventBroker) publish(ev *serverapi.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.subscribers) == 0 {
+		return
+	}
+
+	b.nextEventID++
+
+	for s := range b.subscribers {
+		if len(s.types) > 0 && !s.types[ev.Type] {
+			continue
+		}
+
+		select {
+		case s.events <- eventWithID{b.nextEventID, ev}:
+		default:
+		}
+	}
+}
+
+// subscribe returns a new subscriber, whose events channel is closed when the broker is closed.
+func (b *eventBroker) subscribe(types []string) *eventSubscriber {
+	s := &eventSubscriber{
+		types:  map[string]bool{},
+		events: make(chan eventWithID, eventSubscriberBufferSize),
+	}
+
+	for _, t := range types {
+		s.types[t] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(s.events)
+		return s
+	}
+
+	if b.subscribers == nil {
+		b.subscribers = map[*eventSubscriber]struct{}{}
+	}
+
+	b.subscribers[s] = struct{}{}
+
+	return s
+}
+
+func (b *eventBroker) unsubscribe(s *eventSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[s]; ok {
+		delete(b.subscribers, s)
+		close(s.events)
+	}
+}
+
+// close closes all current and future subscribers.
+func (b *eventBroker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+
+	for s := range b.subscribers {
+		close(s.events)
+	}
+
+	b.subscribers = nil
+}
+
+func (s *Server) publishEvent(ev *serverapi.Event) {
+	if ev.Time.IsZero() {
+		ev.Time = clock.Now()
+	}
+
+	s.events.publish(ev)
+}
+
+// publishTaskEvent publishes events about UI tasks.
+func (s *Server) publishTaskEvent(tev uitask.Event) {
+	ev := &serverapi.Event{
+		Type: uitaskEventTypes[tev.Type],
+	}
+
+	if tev.Type == uitask.EventLog {
+		ev.TaskID = tev.Task.TaskID
+		ev.LogLine = tev.LogLine
+	} else {
+		ti := tev.Task
+		ev.Task = &ti
+	}
+
+	s.publishEvent(ev)
+}
+
+// CloseEventStreams terminates all event streams, which would otherwise prevent graceful shutdown of HTTP server.
+func (s *Server) CloseEventStreams() {
+	s.events.close()
+}
+
+func (s *Server) subscribeEvents(types []string) *eventSubscriber {
+	return s.events.subscribe(types)
+}
+
+func (s *Server) unsubscribeEvents(sub *eventSubscriber) {
+	s.events.unsubscribe(sub)
+}
+
+// handleEvents streams server events to the client using Server-Sent Events
+// until the client disconnects or the server shuts down.
+func handleEvents(ctx context.Context, rc requestContext) {
+	if !requireUIUser(ctx, rc) && !requireServerControlUser(ctx, rc) {
+		http.Error(rc.w, "access denied", http.StatusForbidden)
+		return
+	}
+
+	var types []string
+
+	if t := rc.queryParam("types"); t != "" {
+		types = strings.Split(t, ",")
+	}
+
+	sub := rc.srv.subscribeEvents(types)
+	defer rc.srv.unsubscribeEvents(sub)
+
+	rc.w.Header().Set("Content-Type", "text/event-stream")
+	rc.w.Header().Set("Cache-Control", "no-cache")
+	rc.w.Header().Set("X-Accel-Buffering", "no")
+	rc.w.WriteHeader(http.StatusOK)
+
+	rctl := http.NewResponseController(rc.w) //nolint:bodyclose
+
+	if err := rctl.Flush(); err != nil {
+		log(ctx).Errorf("event stream does not support flushing: %v", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(eventStreamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(rc.w, ": keep-alive\n\n")
+
+		case e, ok := <-sub.events:
+			if !ok {
+				return
+			}
+
+			err = writeEvent(rc.w, e)
+		}
+
+		if err == nil {
+			err = rctl.Flush()
+		}
+
+		if err != nil {
+			log(ctx).Debugf("unable to write event: %v", err)
+			return
+		}
+	}
+}
+
+// writeEvent writes the event in the Server-Sent Events format.
+func writeEvent(w http.ResponseWriter, e eventWithID) error {
+	b, err := json.Marshal(e.ev)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	_, err = fmt.Fprintf(w, "id: %v\nevent: %v\ndata: %s\n\n", e.id, e.ev.Type, b)
+
+	return err //nolint:wrapcheck
+}
@@ -0,0 +1,153 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/servertesting"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/internal/uitask"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+// openEventStream connects to the server event stream and returns a channel receiving parsed events.
+func openEventStream(ctx context.Context, t *testing.T, baseURL, query string) <-chan *serverapi.Event {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/v1/events"+query, http.NoBody)
+	require.NoError(t, err)
+
+	req.SetBasicAuth(servertesting.TestUIUsername, servertesting.TestUIPassword)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	ch := make(chan *serverapi.Event, 1000)
+
+	go func() {
+		defer resp.Body.Close()
+		defer close(ch)
+
+		var eventType string
+
+		s := bufio.NewScanner(resp.Body)
+		s.Buffer(nil, 1<<20)
+
+		for s.Scan() {
+			line := s.Text()
+
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				eventType = strings.TrimPrefix(line, "event: ")
+
+			case strings.HasPrefix(line, "data: "):
+				ev := &serverapi.Event{}
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), ev); err != nil || ev.Type != eventType {
+					t.Errorf("invalid event %q of type %q: %v", line, eventType, err)
+					return
+				}
+
+				ch <- ev
+			}
+		}
+	}()
+
+	return ch
+}
+
+func TestServerEvents(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+	srvInfo := servertesting.StartServer(t, env, false)
+
+	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:  srvInfo.BaseURL,
+		Username: servertesting.TestUIUsername,
+		Password: servertesting.TestUIPassword,
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, cli.FetchCSRFTokenForTesting(ctx))
+
+	// event stream requires authentication.
+	resp, err := http.Get(srvInfo.BaseURL + "/api/v1/events") //nolint:noctx
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	all := openEventStream(streamCtx, t, srvInfo.BaseURL, "")
+	snapshotsOnly := openEventStream(streamCtx, t, srvInfo.BaseURL, "?types="+serverapi.EventSnapshotFinished)
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file-a"), []byte{1, 2, 3}, 0o644))
+
+	si := env.LocalPathSourceInfo(dir)
+
+	mustCreateSource(t, cli, dir, &policy.Policy{})
+
+	_, err = serverapi.UploadSnapshots(ctx, cli, &si)
+	require.NoError(t, err)
+
+	seen := map[string]*serverapi.Event{}
+	statuses := map[string]bool{}
+
+	timeout := time.After(30 * time.Second)
+
+	for seen[serverapi.EventSnapshotFinished] == nil || seen[serverapi.EventTaskFinished] == nil {
+		select {
+		case ev, ok := <-all:
+			require.True(t, ok, "event stream closed")
+
+			if ev.Type == serverapi.EventSourceStatus {
+				require.Equal(t, si, ev.Source.Source)
+				statuses[ev.Source.Status] = true
+			}
+
+			if ev.Task != nil && ev.Task.Kind != "Snapshot" {
+				continue
+			}
+
+			seen[ev.Type] = ev
+
+		case <-timeout:
+			t.Fatalf("snapshot events not received, got %v", seen)
+		}
+	}
+
+	require.True(t, statuses["UPLOADING"])
+	require.NotNil(t, seen[serverapi.EventTaskStarted])
+	require.Equal(t, uitask.StatusSuccess, seen[serverapi.EventTaskFinished].Task.Status)
+	require.Equal(t, seen[serverapi.EventTaskStarted].Task.TaskID, seen[serverapi.EventTaskFinished].Task.TaskID)
+
+	sn := seen[serverapi.EventSnapshotFinished].Snapshot
+	require.Equal(t, si, sn.Source)
+	require.NotEmpty(t, sn.SnapshotID)
+	require.Empty(t, sn.Error)
+	require.Equal(t, int32(1), sn.Stats.TotalFileCount)
+
+	// the filtered stream only receives snapshot events.
+	select {
+	case ev := <-snapshotsOnly:
+		require.Equal(t, serverapi.EventSnapshotFinished, ev.Type)
+		require.Equal(t, sn.SnapshotID, ev.Snapshot.SnapshotID)
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("snapshot event not received by filtered stream")
+	}
+}
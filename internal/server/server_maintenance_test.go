@@ -0,0 +1,50 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/testsender"
+	"github.com/kopia/kopia/repo/maintenance"
+)
+
+func TestNotifyIfMaintenanceOverdue(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	require.NoError(t, notifyprofile.SaveProfile(ctx, env.RepositoryWriter, &notifyprofile.Config{
+		ProfileName: "test",
+		MinSeverity: sender.SeverityWarning,
+		MethodConfig: sender.MethodConfig{
+			Type:   testsender.Method,
+			Config: &testsender.Options{Name: t.Name()},
+		},
+	}))
+
+	now := clock.Now()
+
+	// no maintenance has been scheduled yet.
+	require.False(t, notifyIfMaintenanceOverdue(ctx, env.RepositoryWriter, now))
+
+	require.NoError(t, maintenance.SetSchedule(ctx, env.RepositoryWriter, &maintenance.Schedule{
+		NextFullMaintenanceTime: now.Add(-maintenanceOverdueThreshold + time.Hour),
+	}))
+	require.False(t, notifyIfMaintenanceOverdue(ctx, env.RepositoryWriter, now))
+	require.Empty(t, testsender.MessagesSentTo(t.Name()))
+
+	require.NoError(t, maintenance.SetSchedule(ctx, env.RepositoryWriter, &maintenance.Schedule{
+		NextFullMaintenanceTime: now.Add(-maintenanceOverdueThreshold - time.Hour),
+	}))
+	require.True(t, notifyIfMaintenanceOverdue(ctx, env.RepositoryWriter, now))
+
+	msgs := testsender.MessagesSentTo(t.Name())
+	require.Len(t, msgs, 1)
+	require.Equal(t, sender.SeverityWarning, msgs[0].Severity)
+	require.Contains(t, msgs[0].Subject, "maintenance is overdue")
+	require.Contains(t, msgs[0].Body, "169h0m0s")
+}
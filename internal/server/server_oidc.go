@@ -0,0 +1,251 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+
+	"github.com/kopia/kopia/internal/auditlog"
+	"github.com/kopia/kopia/internal/clock"
+)
+
+const (
+	oidcLoginPath    = "/api/v1/oidc/login"
+	oidcCallbackPath = "/api/v1/oidc/callback"
+	oidcLogoutPath   = "/api/v1/oidc/logout"
+
+	// kopiaOIDCLoginCookie holds the state of OpenID Connect login in progress.
+	kopiaOIDCLoginCookie    = "Kopia-OIDC-Login"
+	kopiaOIDCLoginCookieTTL = 10 * time.Minute
+	kopiaOIDCLoginAudience  = "kopia-oidc-login"
+
+	// kopiaOIDCSessionCookie holds the identity of the user who has logged in using OpenID Connect.
+	kopiaOIDCSessionCookie    = "Kopia-OIDC-Session"
+	kopiaOIDCSessionCookieTTL = 8 * time.Hour
+	kopiaOIDCSessionAudience  = "kopia-oidc-session"
+
+	oidcRandomStringBytes = 32
+)
+
+type oidcLoginClaims struct {
+	jwt.RegisteredClaims
+
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"verifier"`
+}
+
+type signedCookieClaims interface {
+	jwt.Claims
+	VerifyAudience(cmp string, req bool) bool
+}
+
+type oidcSessionClaims struct {
+	jwt.RegisteredClaims
+
+	Groups []string `json:"groups,omitempty"`
+}
+
+// bearerToken returns the token from 'Authorization: Bearer <token>' header.
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+
+	h := r.Header.Get("Authorization")
+	if len(h) <= len(prefix) || !strings.EqualFold(h[0:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return h[len(prefix):], true
+}
+
+// hasCredentials determines whether the request includes any credentials, possibly invalid or expired.
+func hasCredentials(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return true
+	}
+
+	_, err := r.Cookie(kopiaOIDCSessionCookie)
+
+	return err == nil
+}
+
+func randomURLSafeString() string {
+	b := make([]byte, oidcRandomStringBytes)
+	io.ReadFull(rand.Reader, b) //nolint:errcheck
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (s *Server) oidcRedirectURL(r *http.Request) string {
+	if s.options.OIDCRedirectURL != "" {
+		return s.options.OIDCRedirectURL
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host + oidcCallbackPath
+}
+
+func (s *Server) setOIDCCookie(w http.ResponseWriter, r *http.Request, name, value string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	oidc := s.options.OIDC
+	if oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	now := clock.Now()
+
+	lc := &oidcLoginClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(kopiaOIDCLoginCookieTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Audience:  jwt.ClaimStrings{kopiaOIDCLoginAudience},
+			Issuer:    kopiaAuthCookieIssuer,
+		},
+		State:        randomURLSafeString(),
+		Nonce:        randomURLSafeString(),
+		CodeVerifier: randomURLSafeString(),
+	}
+
+	v, err := jwt.NewWithClaims(jwt.SigningMethodHS256, lc).SignedString(s.authCookieSigningKey)
+	if err != nil {
+		log(r.Context()).Errorf("unable to sign login cookie: %v", err)
+		http.Error(w, "Internal error.\n", http.StatusInternalServerError)
+
+		return
+	}
+
+	s.setOIDCCookie(w, r, kopiaOIDCLoginCookie, v, now.Add(kopiaOIDCLoginCookieTTL))
+
+	http.Redirect(w, r, oidc.AuthCodeURL(s.oidcRedirectURL(r), lc.State, lc.Nonce, lc.CodeVerifier), http.StatusFound)
+}
+
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	oidc := s.options.OIDC
+	if oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	if e := q.Get("error"); e != "" {
+		log(ctx).Errorf("OpenID Connect login failed: %v %v", e, q.Get("error_description"))
+		http.Error(w, "Login failed.\n", http.StatusUnauthorized)
+
+		return
+	}
+
+	c, err := r.Cookie(kopiaOIDCLoginCookie)
+	if err != nil {
+		http.Error(w, "Login session not found.\n", http.StatusBadRequest)
+		return
+	}
+
+	lc := &oidcLoginClaims{}
+
+	if !s.parseSignedCookie(c.Value, lc, kopiaOIDCLoginAudience) || lc.State != q.Get("state") {
+		http.Error(w, "Invalid login session.\n", http.StatusBadRequest)
+		return
+	}
+
+	s.setOIDCCookie(w, r, kopiaOIDCLoginCookie, "", time.Unix(0, 0))
+
+	id, err := oidc.Exchange(ctx, q.Get("code"), lc.CodeVerifier, s.oidcRedirectURL(r), lc.Nonce)
+	if err != nil {
+		log(ctx).Errorf("OpenID Connect login failed: %v", err)
+		s.recordAudit(ctx, loginEntry(r, "", loginMethodOIDC, auditlog.ResultFailure))
+		http.Error(w, "Login failed.\n", http.StatusUnauthorized)
+
+		return
+	}
+
+	if !isUIUser(&s.options, id.UsernameAtHost, id.Groups) {
+		log(ctx).Infof("user %v is not allowed to access the UI", id.UsernameAtHost)
+		s.recordAudit(ctx, loginEntry(r, id.UsernameAtHost, loginMethodOIDC, auditlog.ResultDenied))
+		http.Error(w, "UI Access denied.\n", http.StatusForbidden)
+
+		return
+	}
+
+	now := clock.Now()
+
+	v, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &oidcSessionClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   id.UsernameAtHost,
+			ExpiresAt: jwt.NewNumericDate(now.Add(kopiaOIDCSessionCookieTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Audience:  jwt.ClaimStrings{kopiaOIDCSessionAudience},
+			ID:        uuid.New().String(),
+			Issuer:    kopiaAuthCookieIssuer,
+		},
+		Groups: id.Groups,
+	}).SignedString(s.authCookieSigningKey)
+	if err != nil {
+		log(ctx).Errorf("unable to sign session cookie: %v", err)
+		http.Error(w, "Internal error.\n", http.StatusInternalServerError)
+
+		return
+	}
+
+	log(ctx).Infof("user %v logged in using OpenID Connect", id.UsernameAtHost)
+	s.recordAudit(ctx, loginEntry(r, id.UsernameAtHost, loginMethodOIDC, auditlog.ResultSuccess))
+
+	s.setOIDCCookie(w, r, kopiaOIDCSessionCookie, v, now.Add(kopiaOIDCSessionCookieTTL))
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (s *Server) handleOIDCLogout(w http.ResponseWriter, r *http.Request) {
+	if s.options.OIDC == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.setOIDCCookie(w, r, kopiaOIDCSessionCookie, "", time.Unix(0, 0))
+
+	http.Error(w, "Logged out.\n", http.StatusOK)
+}
+
+func (s *Server) oidcSessionFromCookie(cookieValue string) (username string, groups []string, ok bool) {
+	sc := &oidcSessionClaims{}
+
+	if !s.parseSignedCookie(cookieValue, sc, kopiaOIDCSessionAudience) || sc.Subject == "" {
+		return "", nil, false
+	}
+
+	return sc.Subject, sc.Groups, true
+}
+
+// parseSignedCookie parses the cookie value signed by the server for the provided audience.
+func (s *Server) parseSignedCookie(cookieValue string, claims signedCookieClaims, audience string) bool {
+	if _, err := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name})).ParseWithClaims(cookieValue, claims, func(t *jwt.Token) (interface{}, error) {
+		return s.authCookieSigningKey, nil
+	}); err != nil {
+		return false
+	}
+
+	return claims.VerifyAudience(audience, true)
+}
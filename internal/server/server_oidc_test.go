@@ -0,0 +1,186 @@
+package server_test
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/auditlog"
+	"github.com/kopia/kopia/internal/auth"
+	"github.com/kopia/kopia/internal/oidctesting"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/server"
+	"github.com/kopia/kopia/internal/servertesting"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+)
+
+const oidcUIGroup = "kopia-ui"
+
+func startOIDCServer(t *testing.T) (*oidctesting.Issuer, *repo.APIServerInfo, *auditlog.Log) {
+	t.Helper()
+
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+	iss := oidctesting.NewIssuer(t)
+
+	a, err := auth.NewOIDCAuthenticator(ctx, auth.OIDCOptions{
+		Issuer:       iss.URL,
+		ClientID:     oidctesting.ClientID,
+		ClientSecret: oidctesting.ClientSecret,
+	})
+	require.NoError(t, err)
+
+	al, err := auditlog.Open(filepath.Join(testutil.TempDirectory(t), "audit.log"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { al.Close() }) //nolint:errcheck
+
+	si := servertesting.StartServerWithOptions(t, env, true, func(o *server.Options) {
+		o.AuditLog = al
+		o.Authenticator = auth.CombineAuthenticators(o.Authenticator, a)
+		o.OIDC = a
+		o.OIDCUIGroup = oidcUIGroup
+	})
+
+	return iss, si, al
+}
+
+func newOIDCTestBrowser(t *testing.T) *http.Client {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	return &http.Client{
+		Jar: jar,
+		Transport: &http.Transport{
+			//nolint:gosec
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+}
+
+func TestOIDC_BearerToken(t *testing.T) {
+	iss, si, al := startOIDCServer(t)
+
+	get := func(token string) int {
+		req, err := http.NewRequestWithContext(testlogging.Context(t), http.MethodGet, si.BaseURL+"/api/v1/repo/parameters", http.NoBody)
+		require.NoError(t, err)
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := newOIDCTestBrowser(t).Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	require.Equal(t, http.StatusOK, get(iss.Token(t, jwt.MapClaims{"sub": "alice"})))
+	require.Equal(t, http.StatusUnauthorized, get(iss.Token(t, jwt.MapClaims{"sub": "alice", "aud": "other"})))
+	require.Equal(t, http.StatusUnauthorized, get("invalid"))
+	require.Equal(t, http.StatusUnauthorized, get(""))
+
+	// both successful and denied logins are audited.
+	logins, _, err := al.Read(auditlog.Filter{Action: auditlog.ActionLogin})
+	require.NoError(t, err)
+
+	var results []auditlog.Result
+
+	for _, e := range logins {
+		require.Equal(t, "oidc", e.Target["method"])
+
+		results = append(results, e.Result)
+	}
+
+	require.Equal(t, []auditlog.Result{auditlog.ResultSuccess, auditlog.ResultDenied, auditlog.ResultDenied}, results)
+	require.NotEmpty(t, logins[0].User)
+}
+
+func TestOIDC_AuthorizationCodeFlow(t *testing.T) {
+	iss, si, _ := startOIDCServer(t)
+
+	// members of UI group are allowed in.
+	iss.SetLoginClaims(jwt.MapClaims{"sub": "alice", "groups": []string{oidcUIGroup}})
+
+	browser := newOIDCTestBrowser(t)
+
+	resp, err := browser.Get(si.BaseURL + "/")
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	match := regexp.MustCompile(`<meta name="kopia-csrf-token" content="(.*)" />`).FindSubmatch(body)
+	require.NotNil(t, match)
+
+	getRepoStatus := func() int {
+		req, err := http.NewRequestWithContext(testlogging.Context(t), http.MethodGet, si.BaseURL+"/api/v1/repo/status", http.NoBody)
+		require.NoError(t, err)
+
+		req.Header.Set(apiclient.CSRFTokenHeader, string(match[1]))
+
+		resp, err := browser.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	// session cookie grants access to UI API.
+	require.Equal(t, http.StatusOK, getRepoStatus())
+
+	resp, err = browser.Get(si.BaseURL + "/api/v1/oidc/logout")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.Equal(t, http.StatusUnauthorized, getRepoStatus())
+
+	// other users are denied access.
+	iss.SetLoginClaims(jwt.MapClaims{"sub": "bob", "groups": []string{"others"}})
+
+	resp, err = newOIDCTestBrowser(t).Get(si.BaseURL + "/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestOIDC_RepositoryClient(t *testing.T) {
+	iss, si, _ := startOIDCServer(t)
+	ctx := testlogging.Context(t)
+
+	for _, disableGRPC := range []bool{false, true} {
+		si.DisableGRPC = disableGRPC
+
+		rep, err := servertesting.ConnectAndOpenAPIServer(t, ctx, si, repo.ClientOptions{
+			Username: "alice",
+			Hostname: "oidc",
+		}, content.CachingOptions{
+			CacheDirectory: testutil.TempDirectory(t),
+		}, iss.Token(t, jwt.MapClaims{"sub": "alice"}), &repo.Options{})
+		require.NoError(t, err)
+		require.NoError(t, rep.Close(ctx))
+
+		_, err = servertesting.ConnectAndOpenAPIServer(t, ctx, si, repo.ClientOptions{
+			Username: "bob",
+			Hostname: "oidc",
+		}, content.CachingOptions{}, iss.Token(t, jwt.MapClaims{"sub": "alice"}), &repo.Options{})
+		require.Error(t, err)
+	}
+}
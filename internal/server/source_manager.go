@@ -15,6 +15,9 @@
 	"github.com/kopia/kopia/internal/ctxutil"
 	"github.com/kopia/kopia/internal/serverapi"
 	"github.com/kopia/kopia/internal/uitask"
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/notification/sender"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/snapshot/policy"
@@ -99,9 +102,16 @@ func (s *sourceManager) Status() *serverapi.SourceStatus {
 
 func (s *sourceManager) setStatus(stat string) {
 	s.sourceMutex.Lock()
-	defer s.sourceMutex.Unlock()
-
+	changed := s.state != stat
 	s.state = stat
+	s.sourceMutex.Unlock()
+
+	if changed {
+		s.server.publishEvent(&serverapi.Event{
+			Type:   serverapi.EventSourceStatus,
+			Source: s.Status(),
+		})
+	}
 }
 
 func (s *sourceManager) isPaused() bool {
@@ -366,8 +376,11 @@ func (s *sourceManager) snapshotInternal(ctx context.Context, ctrl uitask.Contro
 	s.lastAttemptedSnapshotTime = fs.UTCTimestampFromTime(clock.Now())
 	s.sourceMutex.Unlock()
 
-	//nolint:wrapcheck
-	return repo.WriteSession(ctx, s.rep, repo.WriteSessionOptions{
+	startTime := clock.Now()
+
+	var createdManifest *snapshot.Manifest
+
+	err = repo.WriteSession(ctx, s.rep, repo.WriteSessionOptions{
 		Purpose: "Source Manager Uploader",
 		OnUpload: func(numBytes int64) {
 			// extra indirection to allow changing onUpload function later
@@ -407,6 +420,8 @@ func (s *sourceManager) snapshotInternal(ctx context.Context, ctrl uitask.Contro
 			return errors.Wrap(err, "upload error")
 		}
 
+		createdManifest = manifest
+
 		ignoreIdenticalSnapshot := policyTree.EffectivePolicy().RetentionPolicy.IgnoreIdenticalSnapshots.OrDefault(false)
 		if ignoreIdenticalSnapshot && len(manifestsSinceLastCompleteSnapshot) > 0 {
 			if manifestsSinceLastCompleteSnapshot[0].RootObjectID() == manifest.RootObjectID() {
@@ -427,6 +442,67 @@ func (s *sourceManager) snapshotInternal(ctx context.Context, ctrl uitask.Contro
 		log(ctx).Debugf("created snapshot %v", snapshotID)
 		return nil
 	})
+
+	s.sendSnapshotNotification(ctx, startTime, createdManifest, err)
+	s.publishSnapshotFinished(startTime, createdManifest, err)
+
+	//nolint:wrapcheck
+	return err
+}
+
+// sendSnapshotNotification notifies about the outcome of the snapshot, snapshots which completed
+// with ignored errors are reported as warnings.
+func (s *sourceManager) sendSnapshotNotification(ctx context.Context, startTime time.Time, man *snapshot.Manifest, err error) {
+	r := &notification.SnapshotReport{
+		Source:    s.src.String(),
+		StartTime: startTime,
+		EndTime:   clock.Now(),
+	}
+
+	severity := sender.SeveritySuccess
+
+	if man != nil {
+		r.SnapshotID = string(man.ID)
+		r.TotalFileCount = int64(man.Stats.TotalFileCount)
+		r.TotalFileSize = man.Stats.TotalFileSize
+		r.ErrorCount = int(man.Stats.ErrorCount)
+
+		if r.ErrorCount > 0 {
+			severity = sender.SeverityWarning
+		}
+	}
+
+	if err != nil {
+		r.Error = err.Error()
+		severity = sender.SeverityError
+	}
+
+	notification.Send(ctx, s.rep, notifytemplate.SnapshotReport, r, severity)
+}
+
+// publishSnapshotFinished publishes server event describing the outcome of the snapshot.
+func (s *sourceManager) publishSnapshotFinished(startTime time.Time, man *snapshot.Manifest, err error) {
+	ev := &serverapi.SnapshotFinishedEvent{
+		Source:    s.src,
+		StartTime: startTime,
+		EndTime:   clock.Now(),
+	}
+
+	if man != nil {
+		ev.SnapshotID = man.ID
+		stats := man.Stats
+		ev.Stats = &stats
+	}
+
+	if err != nil {
+		ev.Error = err.Error()
+	}
+
+	s.server.publishEvent(&serverapi.Event{
+		Type:     serverapi.EventSnapshotFinished,
+		Time:     ev.EndTime,
+		Snapshot: ev,
+	})
 }
 
 // +checklocksread:s.sourceMutex
@@ -3,6 +3,7 @@
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -10,6 +11,7 @@
 	"github.com/kopia/kopia/internal/apiclient"
 	"github.com/kopia/kopia/internal/uitask"
 	"github.com/kopia/kopia/repo/blob/throttling"
+	"github.com/kopia/kopia/repo/maintenance"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/snapshot/policy"
@@ -55,6 +57,16 @@ func GetTask(ctx context.Context, c *apiclient.KopiaAPIClient, taskID string) (*
 	return resp, nil
 }
 
+// GetTaskLogs returns the log of a given task.
+func GetTaskLogs(ctx context.Context, c *apiclient.KopiaAPIClient, taskID string) (*TaskLogResponse, error) {
+	resp := &TaskLogResponse{}
+	if err := c.Get(ctx, "tasks/"+taskID+"/logs", nil, resp); err != nil {
+		return nil, errors.Wrap(err, "GetTaskLogs")
+	}
+
+	return resp, nil
+}
+
 // UploadSnapshots triggers snapshot upload on matching snapshots.
 func UploadSnapshots(ctx context.Context, c *apiclient.KopiaAPIClient, match *snapshot.SourceInfo) (*MultipleSourceActionResponse, error) {
 	resp := &MultipleSourceActionResponse{}
@@ -164,6 +176,22 @@ func ListSnapshots(ctx context.Context, c *apiclient.KopiaAPIClient, src snapsho
 	return resp, nil
 }
 
+// GetStorageUsage returns storage used exclusively by snapshots of each source and optionally by individual snapshots.
+func GetStorageUsage(ctx context.Context, c *apiclient.KopiaAPIClient, includeSnapshots bool) (*StorageUsageResponse, error) {
+	resp := &StorageUsageResponse{}
+
+	u := "snapshots/storage-usage"
+	if includeSnapshots {
+		u += "?snapshots=1"
+	}
+
+	if err := c.Get(ctx, u, nil, resp); err != nil {
+		return nil, errors.Wrap(err, "GetStorageUsage")
+	}
+
+	return resp, nil
+}
+
 // ListPolicies lists the policies managed by the server for a given target filter.
 func ListPolicies(ctx context.Context, c *apiclient.KopiaAPIClient, match *snapshot.SourceInfo) (*PoliciesResponse, error) {
 	resp := &PoliciesResponse{}
@@ -209,6 +237,67 @@ func ListTasks(ctx context.Context, c *apiclient.KopiaAPIClient) (*TaskListRespo
 	return resp, nil
 }
 
+// GetMaintenanceHistory returns the history of maintenance runs of the provided task (or all tasks if empty).
+func GetMaintenanceHistory(ctx context.Context, c *apiclient.KopiaAPIClient, task maintenance.TaskType) (*MaintenanceHistoryResponse, error) {
+	resp := &MaintenanceHistoryResponse{}
+	if err := c.Get(ctx, "maintenance/history?task="+url.QueryEscape(string(task)), nil, resp); err != nil {
+		return nil, errors.Wrap(err, "GetMaintenanceHistory")
+	}
+
+	return resp, nil
+}
+
+// GetMaintenanceInfo returns maintenance parameters and schedule.
+func GetMaintenanceInfo(ctx context.Context, c *apiclient.KopiaAPIClient) (*MaintenanceInfoResponse, error) {
+	resp := &MaintenanceInfoResponse{}
+	if err := c.Get(ctx, "maintenance", nil, resp); err != nil {
+		return nil, errors.Wrap(err, "GetMaintenanceInfo")
+	}
+
+	return resp, nil
+}
+
+// SetMaintenanceParams changes maintenance parameters and returns the updated parameters and schedule.
+func SetMaintenanceParams(ctx context.Context, c *apiclient.KopiaAPIClient, req *SetMaintenanceRequest) (*MaintenanceInfoResponse, error) {
+	resp := &MaintenanceInfoResponse{}
+	if err := c.Put(ctx, "maintenance", req, resp); err != nil {
+		return nil, errors.Wrap(err, "SetMaintenanceParams")
+	}
+
+	return resp, nil
+}
+
+// RunMaintenance starts maintenance task.
+func RunMaintenance(ctx context.Context, c *apiclient.KopiaAPIClient, req *RunMaintenanceRequest) (*uitask.Info, error) {
+	resp := &uitask.Info{}
+	if err := c.Post(ctx, "maintenance/run", req, resp); err != nil {
+		return nil, errors.Wrap(err, "RunMaintenance")
+	}
+
+	return resp, nil
+}
+
+// ListAuditLog returns entries of the server audit log matching the provided query parameters
+// (user, action, since, limit).
+func ListAuditLog(ctx context.Context, c *apiclient.KopiaAPIClient, query url.Values) (*AuditLogResponse, error) {
+	resp := &AuditLogResponse{}
+	if err := c.Get(ctx, "control/audit?"+query.Encode(), nil, resp); err != nil {
+		return nil, errors.Wrap(err, "ListAuditLog")
+	}
+
+	return resp, nil
+}
+
+// GetRepositoryStats returns the history of repository statistics.
+func GetRepositoryStats(ctx context.Context, c *apiclient.KopiaAPIClient) (*RepositoryStatsResponse, error) {
+	resp := &RepositoryStatsResponse{}
+	if err := c.Get(ctx, "repo/stats", nil, resp); err != nil {
+		return nil, errors.Wrap(err, "GetRepositoryStats")
+	}
+
+	return resp, nil
+}
+
 // GetObject returns the object payload.
 func GetObject(ctx context.Context, c *apiclient.KopiaAPIClient, objectID string) ([]byte, error) {
 	var b []byte
@@ -244,3 +333,100 @@ func matchSourceParameters(match *snapshot.SourceInfo) string {
 
 	return "?" + strings.Join(clauses, "&")
 }
+
+// CheckACL explains the access level of a user to a target.
+func CheckACL(ctx context.Context, c *apiclient.KopiaAPIClient, req *ACLCheckRequest) (*ACLCheckResponse, error) {
+	resp := &ACLCheckResponse{}
+	if err := c.Post(ctx, "acls/check", req, resp); err != nil {
+		return nil, errors.Wrap(err, "CheckACL")
+	}
+
+	return resp, nil
+}
+
+// LintACL returns potential problems with ACL entries.
+func LintACL(ctx context.Context, c *apiclient.KopiaAPIClient) (*ACLLintResponse, error) {
+	resp := &ACLLintResponse{}
+	if err := c.Get(ctx, "acls/lint", nil, resp); err != nil {
+		return nil, errors.Wrap(err, "LintACL")
+	}
+
+	return resp, nil
+}
+
+// ListUsers lists repository users.
+func ListUsers(ctx context.Context, c *apiclient.KopiaAPIClient) (*UsersResponse, error) {
+	resp := &UsersResponse{}
+	if err := c.Get(ctx, "users", nil, resp); err != nil {
+		return nil, errors.Wrap(err, "ListUsers")
+	}
+
+	return resp, nil
+}
+
+// AddUser adds a repository user.
+func AddUser(ctx context.Context, c *apiclient.KopiaAPIClient, req *AddUserRequest) (*UserInfo, error) {
+	resp := &UserInfo{}
+	if err := c.Post(ctx, "users", req, resp); err != nil {
+		return nil, errors.Wrap(err, "AddUser")
+	}
+
+	return resp, nil
+}
+
+// SetUserPassword changes the password of a repository user.
+func SetUserPassword(ctx context.Context, c *apiclient.KopiaAPIClient, username, password string) error {
+	if err := c.Put(ctx, "users/"+url.PathEscape(username), &SetUserPasswordRequest{Password: password}, &Empty{}); err != nil {
+		return errors.Wrap(err, "SetUserPassword")
+	}
+
+	return nil
+}
+
+// DeleteUser deletes a repository user.
+func DeleteUser(ctx context.Context, c *apiclient.KopiaAPIClient, username string) error {
+	if err := c.Delete(ctx, "users/"+url.PathEscape(username), nil, nil, &Empty{}); err != nil {
+		return errors.Wrap(err, "DeleteUser")
+	}
+
+	return nil
+}
+
+// ListACLs lists ACL entries.
+func ListACLs(ctx context.Context, c *apiclient.KopiaAPIClient) (*ACLListResponse, error) {
+	resp := &ACLListResponse{}
+	if err := c.Get(ctx, "acls", nil, resp); err != nil {
+		return nil, errors.Wrap(err, "ListACLs")
+	}
+
+	return resp, nil
+}
+
+// AddACL adds an ACL entry or grants a predefined role and returns added entries.
+func AddACL(ctx context.Context, c *apiclient.KopiaAPIClient, req *AddACLRequest) (*ACLListResponse, error) {
+	resp := &ACLListResponse{}
+	if err := c.Post(ctx, "acls", req, resp); err != nil {
+		return nil, errors.Wrap(err, "AddACL")
+	}
+
+	return resp, nil
+}
+
+// UpdateACL replaces an ACL entry and returns the new entry, which has a new ID.
+func UpdateACL(ctx context.Context, c *apiclient.KopiaAPIClient, id string, req *AddACLRequest) (*ACLEntry, error) {
+	resp := &ACLEntry{}
+	if err := c.Put(ctx, "acls/"+id, req, resp); err != nil {
+		return nil, errors.Wrap(err, "UpdateACL")
+	}
+
+	return resp, nil
+}
+
+// DeleteACL deletes an ACL entry.
+func DeleteACL(ctx context.Context, c *apiclient.KopiaAPIClient, id string) error {
+	if err := c.Delete(ctx, "acls/"+id, nil, nil, &Empty{}); err != nil {
+		return errors.Wrap(err, "DeleteACL")
+	}
+
+	return nil
+}
@@ -6,10 +6,13 @@
 	"time"
 
 	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/acl"
+	"github.com/kopia/kopia/internal/auditlog"
 	"github.com/kopia/kopia/internal/uitask"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/format"
+	"github.com/kopia/kopia/repo/maintenance"
 	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
@@ -93,6 +96,7 @@ type Empty struct{
 	ErrorPathNotFound       APIErrorCode = "PATH_NOT_FOUND"
 	ErrorStorageConnection  APIErrorCode = "STORAGE_CONNECTION"
 	ErrorAccessDenied       APIErrorCode = "ACCESS_DENIED"
+	ErrorAlreadyExists      APIErrorCode = "ALREADY_EXISTS"
 )
 
 // ErrorResponse represents error response.
@@ -184,6 +188,11 @@ type SnapshotsResponse struct {
 	UniqueCount     int         `json:"uniqueCount"`
 }
 
+// StorageUsageResponse contains storage used exclusively by snapshots of each source and shared with other sources.
+type StorageUsageResponse struct {
+	Sources []*snapshotfs.SourceStorageUsage `json:"sources"`
+}
+
 // DeleteSnapshotsRequest contains request to delete a number of snapshots and optionally the
 // entire snapshot source.
 type DeleteSnapshotsRequest struct {
@@ -227,6 +236,41 @@ type CurrentUserResponse struct {
 	Hostname string `json:"hostname"`
 }
 
+// MaintenanceHistoryResponse contains the history of maintenance runs, most recent first.
+type MaintenanceHistoryResponse struct {
+	Runs []maintenance.HistoryEntry `json:"runs"`
+}
+
+// MaintenanceInfoResponse contains maintenance parameters and schedule of the repository.
+type MaintenanceInfoResponse struct {
+	Params        *maintenance.Params   `json:"params"`
+	Schedule      *maintenance.Schedule `json:"schedule"`
+	OwnedByServer bool                  `json:"ownedByServer"` // true if maintenance is run by the server
+}
+
+// SetMaintenanceRequest contains changes to maintenance parameters, omitted fields are left unchanged.
+type SetMaintenanceRequest struct {
+	Owner      string                   `json:"owner,omitempty"` // 'user@hostname' or "me" to make the server the owner
+	QuickCycle *maintenance.CycleParams `json:"quick,omitempty"`
+	FullCycle  *maintenance.CycleParams `json:"full,omitempty"`
+}
+
+// RunMaintenanceRequest contains request to start maintenance.
+type RunMaintenanceRequest struct {
+	Full bool `json:"full"`
+}
+
+// AuditLogResponse contains entries of the server audit log, oldest first.
+type AuditLogResponse struct {
+	Entries   []*auditlog.Entry `json:"entries"`
+	Malformed int               `json:"malformed,omitempty"` // number of malformed entries that were skipped
+}
+
+// RepositoryStatsResponse contains the history of repository statistics recorded by maintenance, oldest first.
+type RepositoryStatsResponse struct {
+	History []*maintenance.RepositoryStats `json:"history"`
+}
+
 // TaskListResponse contains a list of tasks.
 type TaskListResponse struct {
 	Tasks []uitask.Info `json:"tasks"`
@@ -237,6 +281,37 @@ type TaskLogResponse struct {
 	Logs []json.RawMessage `json:"logs"` // formatted as uitask.LogEntry
 }
 
+// Types of events sent by the server event stream.
+const (
+	EventTaskStarted      = "task-started"
+	EventTaskProgress     = "task-progress"
+	EventTaskLog          = "task-log"
+	EventTaskFinished     = "task-finished"
+	EventSourceStatus     = "source-status"
+	EventSnapshotFinished = "snapshot-finished"
+)
+
+// Event is a single event sent by the server event stream.
+type Event struct {
+	Type     string                 `json:"type"`
+	Time     time.Time              `json:"time"`
+	Task     *uitask.Info           `json:"task,omitempty"`     // task-started, task-progress and task-finished
+	TaskID   string                 `json:"taskID,omitempty"`   // task-log
+	LogLine  json.RawMessage        `json:"logLine,omitempty"`  // task-log, formatted as uitask.LogEntry
+	Source   *SourceStatus          `json:"source,omitempty"`   // source-status
+	Snapshot *SnapshotFinishedEvent `json:"snapshot,omitempty"` // snapshot-finished
+}
+
+// SnapshotFinishedEvent describes the outcome of a snapshot taken by the server.
+type SnapshotFinishedEvent struct {
+	Source     snapshot.SourceInfo `json:"source"`
+	SnapshotID manifest.ID         `json:"snapshotID,omitempty"`
+	StartTime  time.Time           `json:"startTime"`
+	EndTime    time.Time           `json:"endTime"`
+	Stats      *snapshot.Stats     `json:"stats,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
 // RestoreRequest contains request to restore an object (file or directory) to a given destination.
 type RestoreRequest struct {
 	Root string `json:"root"`
@@ -293,3 +368,73 @@ type UIPreferences struct {
 	Theme            string `json:"theme"`            // 'dark', 'light' or ''
 	PageSize         int    `json:"pageSize"`         // A page size; the actual possible values will only be provided by the frontend
 }
+
+// ACLEntry is an ACL entry along with its identifier.
+type ACLEntry struct {
+	ID manifest.ID `json:"id"`
+	*acl.Entry
+}
+
+// ACLCheckRequest contains request to explain the access level of a user to a target.
+type ACLCheckRequest struct {
+	User   string            `json:"user,omitempty"`   // defaults to the authenticated user
+	Groups []string          `json:"groups,omitempty"` // additional groups of other users, such as OpenID Connect groups
+	Target map[string]string `json:"target"`
+}
+
+// ACLCheckResponse contains the access level of a user to a target along with ACL entries that contributed to it.
+type ACLCheckResponse struct {
+	User       string            `json:"user"`
+	Groups     []string          `json:"groups"`
+	Target     map[string]string `json:"target"`
+	ACLEnabled bool              `json:"aclEnabled"`
+	Access     acl.AccessLevel   `json:"access"`
+	Entries    []ACLEntry        `json:"entries"` // sorted by decreasing access level
+}
+
+// ACLLintFinding describes a potential problem with an ACL entry.
+type ACLLintFinding struct {
+	Entry   ACLEntry `json:"entry"`
+	Kind    string   `json:"kind"`
+	Message string   `json:"message"`
+}
+
+// ACLLintResponse contains the results of ACL linting.
+type ACLLintResponse struct {
+	Findings []ACLLintFinding `json:"findings"`
+}
+
+// ACLListResponse contains a list of ACL entries.
+type ACLListResponse struct {
+	Entries []ACLEntry `json:"entries"`
+}
+
+// AddACLRequest contains request to add an ACL entry or to grant a predefined role, which adds multiple entries.
+type AddACLRequest struct {
+	User   string            `json:"user"`
+	Target map[string]string `json:"target,omitempty"`
+	Access acl.AccessLevel   `json:"access,omitempty"`
+	Role   string            `json:"role,omitempty"`
+}
+
+// UserInfo describes a repository user.
+type UserInfo struct {
+	Username            string `json:"username"`
+	PasswordHashVersion int    `json:"passwordHashVersion"`
+}
+
+// UsersResponse contains a list of repository users.
+type UsersResponse struct {
+	Users []UserInfo `json:"users"`
+}
+
+// AddUserRequest contains request to add a repository user.
+type AddUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// SetUserPasswordRequest contains request to change the password of a repository user.
+type SetUserPasswordRequest struct {
+	Password string `json:"password"`
+}
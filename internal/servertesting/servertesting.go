@@ -4,6 +4,8 @@
 import (
 	"context"
 	"crypto/sha256"
+	cryptotls "crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
 	"net/http/httptest"
 	"path/filepath"
@@ -36,9 +38,40 @@
 func StartServer(t *testing.T, env *repotesting.Environment, tls bool) *repo.APIServerInfo {
 	t.Helper()
 
+	return StartServerWithOptions(t, env, tls, nil)
+}
+
+// StartServerWithOptions starts a test server with options customized by the provided function and returns APIServerInfo.
+func StartServerWithOptions(t *testing.T, env *repotesting.Environment, tls bool, modify func(o *server.Options)) *repo.APIServerInfo {
+	t.Helper()
+
+	return startServer(t, env, tls, nil, modify)
+}
+
+// StartServerWithClientCertificates starts a TLS test server which verifies client certificates issued
+// by the provided certificate authorities and authenticates their users, with options customized by the
+// provided function.
+func StartServerWithClientCertificates(t *testing.T, env *repotesting.Environment, clientCAs *x509.CertPool, source auth.ClientCertificateIdentitySource, modify func(o *server.Options)) *repo.APIServerInfo {
+	t.Helper()
+
+	cc, err := auth.NewClientCertificateAuthenticator(source)
+	require.NoError(t, err)
+
+	return startServer(t, env, true, clientCAs, func(o *server.Options) {
+		o.ClientCertificates = cc
+
+		if modify != nil {
+			modify(o)
+		}
+	})
+}
+
+func startServer(t *testing.T, env *repotesting.Environment, tls bool, clientCAs *x509.CertPool, modify func(o *server.Options)) *repo.APIServerInfo {
+	t.Helper()
+
 	ctx := testlogging.Context(t)
 
-	s, err := server.New(ctx, &server.Options{
+	opt := &server.Options{
 		ConfigFile:      env.ConfigFile(),
 		PasswordPersist: passwordpersist.File(),
 		Authorizer:      auth.LegacyAuthorizer(),
@@ -49,8 +82,13 @@ func StartServer(t *testing.T, env *repotesting.Environment, tls bool) *repo.API
 		RefreshInterval:   1 * time.Minute,
 		UIUser:            TestUIUsername,
 		UIPreferencesFile: filepath.Join(testutil.TempDirectory(t), "ui-pref.json"),
-	})
+	}
+
+	if modify != nil {
+		modify(opt)
+	}
 
+	s, err := server.New(ctx, opt)
 	require.NoError(t, err)
 
 	s.SetRepository(ctx, env.Repository)
@@ -71,6 +109,14 @@ func StartServer(t *testing.T, env *repotesting.Environment, tls bool) *repo.API
 	hs := httptest.NewUnstartedServer(s.GRPCRouterHandler(m))
 	if tls {
 		hs.EnableHTTP2 = true
+
+		if clientCAs != nil {
+			hs.TLS = &cryptotls.Config{
+				ClientCAs:  clientCAs,
+				ClientAuth: cryptotls.VerifyClientCertIfGiven,
+			}
+		}
+
 		hs.StartTLS()
 		serverHash := sha256.Sum256(hs.Certificate().Raw)
 		asi.BaseURL = hs.URL
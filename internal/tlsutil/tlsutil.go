@@ -132,10 +132,7 @@ func TLSConfigTrustingSingleCertificate(sha256Fingerprint string) *tls.Config {
 // TransportTrustingSingleCertificate return http.RoundTripper which trusts exactly one TLS certificate with
 // provided SHA256 fingerprint.
 func TransportTrustingSingleCertificate(sha256Fingerprint string) http.RoundTripper {
-	t2 := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
-	t2.TLSClientConfig = TLSConfigTrustingSingleCertificate(sha256Fingerprint)
-
-	return t2
+	return TransportWithTLSConfig(TLSConfigTrustingSingleCertificate(sha256Fingerprint))
 }
 
 func verifyPeerCertificate(sha256Fingerprint string) func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
@@ -158,3 +155,50 @@ func verifyPeerCertificate(sha256Fingerprint string) func(rawCerts [][]byte, ver
 		return errors.Errorf("can't find certificate matching SHA256 fingerprint %q (server had %v)", sha256Fingerprint, serverCerts)
 	}
 }
+
+// ClientTLSConfig returns tls.Config which trusts exactly one TLS certificate with the provided SHA256 fingerprint
+// or system certificate authorities if it is empty, and presents the client certificate loaded from the provided
+// PEM files, if specified.
+func ClientTLSConfig(sha256Fingerprint, clientCertFile, clientKeyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if sha256Fingerprint != "" {
+		cfg = TLSConfigTrustingSingleCertificate(sha256Fingerprint)
+	}
+
+	if clientCertFile != "" || clientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to load client certificate")
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// TransportWithTLSConfig returns http.RoundTripper which uses the provided TLS configuration.
+func TransportWithTLSConfig(cfg *tls.Config) http.RoundTripper {
+	t2 := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	t2.TLSClientConfig = cfg
+
+	return t2
+}
+
+// LoadCertificatePool loads PEM-encoded certificates from the provided file.
+func LoadCertificatePool(fname string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(fname) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading certificates")
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.Errorf("no certificates found in %v", fname)
+	}
+
+	return pool, nil
+}
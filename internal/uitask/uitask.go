@@ -68,7 +68,8 @@ type Info struct {
 type runningTaskInfo struct {
 	Info
 
-	maxLogMessages int // +checklocksignore
+	maxLogMessages int      // +checklocksignore
+	manager        *Manager // +checklocksignore
 
 	mu sync.Mutex
 	// +checklocks:mu
@@ -111,17 +112,32 @@ func (t *runningTaskInfo) cancel() {
 // ReportProgressInfo implements the Controller interface.
 func (t *runningTaskInfo) ReportProgressInfo(pi string) {
 	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	t.ProgressInfo = pi
+	t.mu.Unlock()
+
+	t.notify(EventProgress, nil)
 }
 
 // ReportCounters implements the Controller interface.
 func (t *runningTaskInfo) ReportCounters(c map[string]CounterValue) {
 	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	t.Counters = cloneCounters(c)
+	t.mu.Unlock()
+
+	t.notify(EventProgress, nil)
+}
+
+// notify delivers the event about the task to listeners registered with the manager, if any.
+func (t *runningTaskInfo) notify(et EventType, logLine json.RawMessage) {
+	if t.manager == nil || !t.manager.hasListeners() {
+		return
+	}
+
+	t.manager.notifyListeners(Event{
+		Type:    et,
+		Task:    t.info(),
+		LogLine: logLine,
+	})
 }
 
 // info returns a copy of task information while holding a lock.
@@ -189,9 +205,9 @@ func (t *runningTaskInfo) addLogEntry(le json.RawMessage) {
 	}
 
 	t.mu.Lock()
-	defer t.mu.Unlock()
 
 	if t.Status.IsFinished() {
+		t.mu.Unlock()
 		return
 	}
 
@@ -199,6 +215,10 @@ func (t *runningTaskInfo) addLogEntry(le json.RawMessage) {
 	if len(t.LogLines) > t.maxLogMessages {
 		t.LogLines = t.LogLines[1:]
 	}
+
+	t.mu.Unlock()
+
+	t.notify(EventLog, le)
 }
 
 func (t *runningTaskInfo) log() []json.RawMessage {
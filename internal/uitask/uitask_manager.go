@@ -32,8 +32,35 @@ type Manager struct {
 
 	MaxFinishedTasks      int // +checklocksignore
 	MaxLogMessagesPerTask int // +checklocksignore
+
+	listenersMu sync.RWMutex
+	// +checklocks:listenersMu
+	nextListenerID int
+	// +checklocks:listenersMu
+	listeners map[int]Listener
+}
+
+// EventType describes the type of task event.
+type EventType string
+
+// Supported event types.
+const (
+	EventStarted  EventType = "started"
+	EventProgress EventType = "progress"
+	EventLog      EventType = "log"
+	EventFinished EventType = "finished"
+)
+
+// Event describes a change to a task, which is delivered to listeners.
+type Event struct {
+	Type    EventType
+	Task    Info
+	LogLine json.RawMessage // only set for EventLog
 }
 
+// Listener receives task events, it is invoked synchronously and must not block.
+type Listener func(ev Event)
+
 // Controller allows the task to communicate with task manager and receive signals.
 type Controller interface {
 	CurrentTaskID() string
@@ -54,18 +81,60 @@ func (m *Manager) Run(ctx context.Context, kind, description string, task TaskFu
 			Status:      StatusRunning,
 		},
 		maxLogMessages: m.MaxLogMessagesPerTask,
+		manager:        m,
 	}
 
 	ctx = logging.WithLogger(ctx, r.loggerForModule)
 
 	m.startTask(r)
+	r.notify(EventStarted, nil)
 
 	err := task(ctx, r)
 	m.completeTask(r, err)
+	r.notify(EventFinished, nil)
 
 	return err
 }
 
+// AddListener registers a listener which will receive events about all tasks and returns a function
+// which removes it.
+func (m *Manager) AddListener(l Listener) (remove func()) {
+	m.listenersMu.Lock()
+	defer m.listenersMu.Unlock()
+
+	m.nextListenerID++
+	id := m.nextListenerID
+
+	if m.listeners == nil {
+		m.listeners = map[int]Listener{}
+	}
+
+	m.listeners[id] = l
+
+	return func() {
+		m.listenersMu.Lock()
+		defer m.listenersMu.Unlock()
+
+		delete(m.listeners, id)
+	}
+}
+
+func (m *Manager) hasListeners() bool {
+	m.listenersMu.RLock()
+	defer m.listenersMu.RUnlock()
+
+	return len(m.listeners) > 0
+}
+
+func (m *Manager) notifyListeners(ev Event) {
+	m.listenersMu.RLock()
+	defer m.listenersMu.RUnlock()
+
+	for _, l := range m.listeners {
+		l(ev)
+	}
+}
+
 // ListTasks lists all running and some recently-finished tasks up to configured limits.
 func (m *Manager) ListTasks() []Info {
 	m.mu.Lock()
@@ -312,6 +312,71 @@ func TestUITaskCancel_BeforeOnCancel(t *testing.T) {
 	})
 }
 
+func TestUITaskListeners(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := uitask.NewManager()
+
+	var events []uitask.Event
+
+	remove := m.AddListener(func(ev uitask.Event) {
+		events = append(events, ev)
+	})
+
+	m.Run(ctx, "some-kind", "test-1", func(ctx context.Context, ctrl uitask.Controller) error {
+		ctrl.ReportProgressInfo("working")
+		log(ctx).Infof("hello")
+		ignoredLog(ctx).Infof("this is ignored")
+		ctrl.ReportCounters(map[string]uitask.CounterValue{
+			"foo": uitask.SimpleCounter(3),
+		})
+
+		return errors.Errorf("some error")
+	})
+
+	var types []uitask.EventType
+	for _, ev := range events {
+		types = append(types, ev.Type)
+	}
+
+	if diff := cmp.Diff(types, []uitask.EventType{
+		uitask.EventStarted,
+		uitask.EventProgress,
+		uitask.EventLog,
+		uitask.EventProgress,
+		uitask.EventFinished,
+	}); diff != "" {
+		t.Fatalf("unexpected events: %v", diff)
+	}
+
+	if got, want := events[1].Task.ProgressInfo, "working"; got != want {
+		t.Fatalf("invalid progress info %v, want %v", got, want)
+	}
+
+	if !strings.Contains(string(events[2].LogLine), "hello") {
+		t.Fatalf("invalid log line %s", events[2].LogLine)
+	}
+
+	if got, want := events[3].Task.Counters["foo"].Value, int64(3); got != want {
+		t.Fatalf("invalid counter value %v, want %v", got, want)
+	}
+
+	if got, want := events[4].Task.Status, uitask.StatusFailed; got != want {
+		t.Fatalf("invalid final status %v, want %v", got, want)
+	}
+
+	remove()
+
+	m.Run(ctx, "some-kind", "test-2", func(ctx context.Context, ctrl uitask.Controller) error {
+		return nil
+	})
+
+	if got, want := len(events), 5; got != want {
+		t.Fatalf("unexpected events after removing listener: %v, want %v", got, want)
+	}
+}
+
 func getTaskID(t *testing.T, m *uitask.Manager, desc string) string {
 	t.Helper()
 
@@ -0,0 +1,153 @@
+// Package notification sends notifications about snapshot, maintenance and error events
+// to destinations configured in notification profiles.
+package notification
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/command"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/logging"
+
+	// register supported notification senders.
+	_ "github.com/kopia/kopia/notification/sender/email"
+	_ "github.com/kopia/kopia/notification/sender/webhook"
+)
+
+var log = logging.Module("notification")
+
+// ErrCommandNotificationsDisabled is returned when sending using a command profile on a client
+// that has not enabled command notifications.
+var ErrCommandNotificationsDisabled = errors.Errorf("command notifications are not enabled on this client, reconnect with --enable-command-notifications")
+
+// SnapshotReport describes the outcome of a snapshot, passed to notifytemplate.SnapshotReport.
+type SnapshotReport struct {
+	Source         string    `json:"source"`
+	SnapshotID     string    `json:"snapshotID,omitempty"`
+	StartTime      time.Time `json:"startTime"`
+	EndTime        time.Time `json:"endTime"`
+	TotalFileCount int64     `json:"totalFileCount"`
+	TotalFileSize  int64     `json:"totalFileSize"`
+	ErrorCount     int       `json:"errorCount"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// Duration returns the duration of the snapshot.
+func (r *SnapshotReport) Duration() time.Duration {
+	return r.EndTime.Sub(r.StartTime).Truncate(time.Second)
+}
+
+// MaintenanceReport describes the outcome of a maintenance run, passed to notifytemplate.MaintenanceReport.
+type MaintenanceReport struct {
+	Mode      string    `json:"mode"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Duration returns the duration of the maintenance run.
+func (r *MaintenanceReport) Duration() time.Duration {
+	return r.EndTime.Sub(r.StartTime).Truncate(time.Second)
+}
+
+// MaintenanceOverdue describes full maintenance which has not run when due, passed to notifytemplate.MaintenanceOverdue.
+type MaintenanceOverdue struct {
+	Owner   string        `json:"owner"`
+	DueTime time.Time     `json:"dueTime"`
+	Overdue time.Duration `json:"overdue"`
+}
+
+// VerifyError describes failed snapshot verification, passed to notifytemplate.VerifyError.
+type VerifyError struct {
+	Sources []string `json:"sources,omitempty"`
+	Error   string   `json:"error"`
+}
+
+// TestNotification is passed to notifytemplate.TestNotification.
+type TestNotification struct {
+	ProfileName string `json:"profileName"`
+}
+
+// Send renders the notification template with the provided arguments and sends it using all profiles
+// accepting the provided severity. Failures are logged, but do not fail the operation being reported.
+func Send(ctx context.Context, rep repo.Repository, templateName string, args any, severity sender.Severity) {
+	profiles, err := notifyprofile.ListProfiles(ctx, rep)
+	if err != nil {
+		log(ctx).Errorf("unable to list notification profiles: %v", err)
+		return
+	}
+
+	var msg *sender.Message
+
+	for _, p := range profiles {
+		if severity < p.MinSeverity {
+			continue
+		}
+
+		if !isMethodAllowed(rep, p) {
+			log(ctx).Infof("skipping notification profile %q because command notifications are not enabled on this client", p.ProfileName)
+			continue
+		}
+
+		if msg == nil {
+			msg, err = RenderMessage(ctx, rep, templateName, args, severity)
+			if err != nil {
+				log(ctx).Errorf("unable to render notification: %v", err)
+				return
+			}
+		}
+
+		if err := SendTo(ctx, rep, p, msg); err != nil {
+			log(ctx).Errorf("unable to send notification using profile %q: %v", p.ProfileName, err)
+		}
+	}
+}
+
+// SendTo sends the message using the provided profile, regardless of its minimum severity.
+func SendTo(ctx context.Context, rep repo.Repository, p *notifyprofile.Config, msg *sender.Message) error {
+	if !isMethodAllowed(rep, p) {
+		return ErrCommandNotificationsDisabled
+	}
+
+	s, err := sender.GetSender(ctx, p.MethodConfig)
+	if err != nil {
+		return errors.Wrap(err, "unable to create sender")
+	}
+
+	log(ctx).Debugf("sending notification %q using %v", msg.Subject, s.Summary())
+
+	return errors.Wrap(s.Send(ctx, msg), "unable to send notification")
+}
+
+// isMethodAllowed determines whether the profile can be used on this client. Profiles are stored in the
+// repository, so commands are only executed on clients which explicitly opted in.
+func isMethodAllowed(rep repo.Repository, p *notifyprofile.Config) bool {
+	return p.MethodConfig.Type != command.Method || rep.ClientOptions().EnableCommandNotifications
+}
+
+// RenderMessage renders the notification template with the provided arguments.
+func RenderMessage(ctx context.Context, rep repo.Repository, templateName string, args any, severity sender.Severity) (*sender.Message, error) {
+	text, err := notifytemplate.GetTemplate(ctx, rep, templateName)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get notification template")
+	}
+
+	rendered, err := notifytemplate.Render(text, &notifytemplate.Data{
+		Hostname:  rep.ClientOptions().Hostname,
+		EventTime: clock.Now(),
+		EventArgs: args,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to render template %q", templateName)
+	}
+
+	//nolint:wrapcheck
+	return sender.ParseMessage(rendered, severity)
+}
@@ -0,0 +1,134 @@
+package notification_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/notifyprofile"
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/command"
+	"github.com/kopia/kopia/notification/sender/testsender"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+func TestSend(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	for _, p := range []*notifyprofile.Config{
+		{ProfileName: "errors", MinSeverity: sender.SeverityError, MethodConfig: sender.MethodConfig{Type: testsender.Method, Config: &testsender.Options{Name: t.Name() + "-errors"}}},
+		{ProfileName: "all", MinSeverity: sender.SeverityVerbose, MethodConfig: sender.MethodConfig{Type: testsender.Method, Config: &testsender.Options{Name: t.Name() + "-all"}}},
+		{ProfileName: "broken", MinSeverity: sender.SeverityVerbose, MethodConfig: sender.MethodConfig{Type: testsender.Method, Config: &testsender.Options{Fail: true}}},
+	} {
+		require.NoError(t, notifyprofile.SaveProfile(ctx, env.RepositoryWriter, p))
+	}
+
+	profiles, err := notifyprofile.ListProfiles(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, profiles, 3)
+	require.Equal(t, "all", profiles[0].ProfileName)
+
+	startTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	notification.Send(ctx, env.RepositoryWriter, notifytemplate.SnapshotReport, &notification.SnapshotReport{
+		Source:         "user@host:/some/path",
+		SnapshotID:     "some-snapshot-id",
+		StartTime:      startTime,
+		EndTime:        startTime.Add(time.Minute),
+		TotalFileCount: 33,
+		TotalFileSize:  12345,
+	}, sender.SeveritySuccess)
+
+	notification.Send(ctx, env.RepositoryWriter, notifytemplate.SnapshotReport, &notification.SnapshotReport{
+		Source:    "user@host:/some/path",
+		StartTime: startTime,
+		EndTime:   startTime.Add(time.Minute),
+		Error:     "some error",
+	}, sender.SeverityError)
+
+	all := testsender.MessagesSentTo(t.Name() + "-all")
+	require.Len(t, all, 2)
+	require.Equal(t, "Snapshot of user@host:/some/path completed on "+env.RepositoryWriter.ClientOptions().Hostname, all[0].Subject)
+	require.Equal(t, sender.SeveritySuccess, all[0].Severity)
+	require.Contains(t, all[0].Body, "some-snapshot-id")
+	require.Contains(t, all[0].Body, "Duration:    1m0s")
+
+	errorsOnly := testsender.MessagesSentTo(t.Name() + "-errors")
+	require.Len(t, errorsOnly, 1)
+	require.True(t, strings.HasPrefix(errorsOnly[0].Subject, "Snapshot of user@host:/some/path failed"))
+	require.Contains(t, errorsOnly[0].Body, "some error")
+
+	// customized template is used instead of the built-in one.
+	require.NoError(t, notifytemplate.SetTemplate(ctx, env.RepositoryWriter, notifytemplate.VerifyError, "Subject: custom {{ .EventArgs.Error }}\n\nbody\n"))
+	notification.Send(ctx, env.RepositoryWriter, notifytemplate.VerifyError, &notification.VerifyError{Error: "bad"}, sender.SeverityError)
+
+	errorsOnly = testsender.MessagesSentTo(t.Name() + "-errors")
+	require.Len(t, errorsOnly, 2)
+	require.Equal(t, "custom bad", errorsOnly[1].Subject)
+
+	require.NoError(t, notifyprofile.DeleteProfile(ctx, env.RepositoryWriter, "errors"))
+	require.ErrorIs(t, notifyprofile.DeleteProfile(ctx, env.RepositoryWriter, "errors"), notifyprofile.ErrNotFound)
+
+	_, err = notifyprofile.GetProfile(ctx, env.RepositoryWriter, "errors")
+	require.ErrorIs(t, err, notifyprofile.ErrNotFound)
+}
+
+func TestProfilesWithUnexpectedManifestsAreIgnored(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	p := &notifyprofile.Config{ProfileName: "p1", MethodConfig: sender.MethodConfig{Type: testsender.Method, Config: &testsender.Options{Name: t.Name()}}}
+	require.NoError(t, notifyprofile.SaveProfile(ctx, env.RepositoryWriter, p))
+
+	// profile with additional labels is ignored.
+	_, err := env.RepositoryWriter.PutManifest(ctx, map[string]string{
+		manifest.TypeLabelKey: notifyprofile.ManifestType,
+		"profile":             "p2",
+		"username":            "foo",
+	}, &notifyprofile.Config{ProfileName: "p2", MethodConfig: p.MethodConfig})
+	require.NoError(t, err)
+
+	_, err = notifyprofile.GetProfile(ctx, env.RepositoryWriter, "p2")
+	require.ErrorIs(t, err, notifyprofile.ErrNotFound)
+
+	profiles, err := notifyprofile.ListProfiles(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+
+	// duplicate manifest makes the profile ambiguous.
+	_, err = env.RepositoryWriter.PutManifest(ctx, map[string]string{
+		manifest.TypeLabelKey: notifyprofile.ManifestType,
+		"profile":             "p1",
+	}, p)
+	require.NoError(t, err)
+
+	_, err = notifyprofile.GetProfile(ctx, env.RepositoryWriter, "p1")
+	require.ErrorContains(t, err, "ambiguous")
+
+	profiles, err = notifyprofile.ListProfiles(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Empty(t, profiles)
+}
+
+func TestCommandProfilesRequireOptIn(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	require.False(t, env.RepositoryWriter.ClientOptions().EnableCommandNotifications)
+
+	p := &notifyprofile.Config{ProfileName: "cmd", MinSeverity: sender.SeverityVerbose, MethodConfig: sender.MethodConfig{Type: command.Method, Config: &command.Options{Command: "no-such-command"}}}
+	require.NoError(t, notifyprofile.SaveProfile(ctx, env.RepositoryWriter, p))
+
+	msg := &sender.Message{Subject: "test", Severity: sender.SeverityError}
+	require.ErrorIs(t, notification.SendTo(ctx, env.RepositoryWriter, p, msg), notification.ErrCommandNotificationsDisabled)
+
+	// profile is skipped and does not prevent sending using other profiles.
+	other := &notifyprofile.Config{ProfileName: "other", MinSeverity: sender.SeverityVerbose, MethodConfig: sender.MethodConfig{Type: testsender.Method, Config: &testsender.Options{Name: t.Name()}}}
+	require.NoError(t, notifyprofile.SaveProfile(ctx, env.RepositoryWriter, other))
+
+	notification.Send(ctx, env.RepositoryWriter, notifytemplate.VerifyError, &notification.VerifyError{Error: "bad"}, sender.SeverityError)
+	require.Len(t, testsender.MessagesSentTo(t.Name()), 1)
+}
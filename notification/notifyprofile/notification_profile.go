@@ -0,0 +1,156 @@
+// Package notifyprofile manages notification profiles stored in the repository.
+package notifyprofile
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+// ManifestType is the type of manifests holding notification profiles.
+const ManifestType = "notificationProfile"
+
+const profileNameKey = "profile"
+
+// ErrNotFound is returned when a profile is not found.
+var ErrNotFound = errors.Errorf("profile not found")
+
+// Config is a JSON-serializable notification profile, which routes messages of at least the minimum
+// severity to the configured sender.
+type Config struct {
+	ProfileName  string              `json:"profile"`
+	MethodConfig sender.MethodConfig `json:"method"`
+	MinSeverity  sender.Severity     `json:"minSeverity"`
+}
+
+func labelsForProfileName(name string) map[string]string {
+	return map[string]string{
+		manifest.TypeLabelKey: ManifestType,
+		profileNameKey:        name,
+	}
+}
+
+// hasExpectedLabels determines whether the manifest has exactly the labels set by SaveProfile().
+// Since profiles can run commands, manifests which were not written by SaveProfile() are never used.
+func hasExpectedLabels(e *manifest.EntryMetadata) bool {
+	return len(e.Labels) == 2 && e.Labels[manifest.TypeLabelKey] == ManifestType && e.Labels[profileNameKey] != ""
+}
+
+// loadProfile loads the profile stored in the provided manifest and verifies that it matches its labels.
+func loadProfile(ctx context.Context, rep repo.Repository, e *manifest.EntryMetadata) (*Config, error) {
+	var pc Config
+
+	if _, err := rep.GetManifest(ctx, e.ID, &pc); err != nil {
+		return nil, errors.Wrap(err, "unable to get notification profile")
+	}
+
+	if pc.ProfileName != e.Labels[profileNameKey] {
+		return nil, errors.Errorf("notification profile %v does not match its labels", e.ID)
+	}
+
+	return &pc, nil
+}
+
+// GetProfile returns the notification profile with the provided name or ErrNotFound.
+// Profiles stored in more than one manifest are rejected as ambiguous.
+func GetProfile(ctx context.Context, rep repo.Repository, name string) (*Config, error) {
+	entries, err := rep.FindManifests(ctx, labelsForProfileName(name))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find notification profile")
+	}
+
+	var valid []*manifest.EntryMetadata
+
+	for _, e := range entries {
+		if hasExpectedLabels(e) {
+			valid = append(valid, e)
+		}
+	}
+
+	switch len(valid) {
+	case 0:
+		return nil, ErrNotFound
+	case 1:
+		return loadProfile(ctx, rep, valid[0])
+	default:
+		return nil, errors.Errorf("notification profile %q is ambiguous, found %v manifests", name, len(valid))
+	}
+}
+
+// ListProfiles returns all notification profiles sorted by name. Profiles which are ambiguous or
+// have unexpected labels are skipped.
+func ListProfiles(ctx context.Context, rep repo.Repository) ([]*Config, error) {
+	entries, err := rep.FindManifests(ctx, map[string]string{
+		manifest.TypeLabelKey: ManifestType,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list notification profiles")
+	}
+
+	byName := map[string][]*manifest.EntryMetadata{}
+
+	for _, e := range entries {
+		if hasExpectedLabels(e) {
+			byName[e.Labels[profileNameKey]] = append(byName[e.Labels[profileNameKey]], e)
+		}
+	}
+
+	var profiles []*Config
+
+	for _, v := range byName {
+		if len(v) != 1 {
+			continue
+		}
+
+		pc, err := loadProfile(ctx, rep, v[0])
+		if err != nil {
+			return nil, err
+		}
+
+		profiles = append(profiles, pc)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].ProfileName < profiles[j].ProfileName
+	})
+
+	return profiles, nil
+}
+
+// SaveProfile saves the notification profile, replacing any existing profile with the same name.
+func SaveProfile(ctx context.Context, rep repo.RepositoryWriter, pc *Config) error {
+	if pc.ProfileName == "" {
+		return errors.Errorf("profile name must be provided")
+	}
+
+	if _, err := rep.ReplaceManifests(ctx, labelsForProfileName(pc.ProfileName), pc); err != nil {
+		return errors.Wrap(err, "unable to save notification profile")
+	}
+
+	return nil
+}
+
+// DeleteProfile deletes the notification profile with the provided name.
+func DeleteProfile(ctx context.Context, rep repo.RepositoryWriter, name string) error {
+	entries, err := rep.FindManifests(ctx, labelsForProfileName(name))
+	if err != nil {
+		return errors.Wrap(err, "unable to find notification profile")
+	}
+
+	if len(entries) == 0 {
+		return ErrNotFound
+	}
+
+	for _, e := range entries {
+		if err := rep.DeleteManifest(ctx, e.ID); err != nil {
+			return errors.Wrap(err, "unable to delete notification profile")
+		}
+	}
+
+	return nil
+}
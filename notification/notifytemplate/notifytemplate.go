@@ -0,0 +1,195 @@
+// Package notifytemplate provides templates of notification messages, which can be customized
+// by storing their replacements in the repository.
+package notifytemplate
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+// Names of built-in notification templates.
+const (
+	SnapshotReport     = "snapshot-report.txt"
+	MaintenanceReport  = "maintenance-report.txt"
+	MaintenanceOverdue = "maintenance-overdue.txt"
+	VerifyError        = "verify-error.txt"
+	TestNotification   = "test-notification.txt"
+)
+
+const (
+	notificationTemplateManifestType = "notificationTemplate"
+	templateNameKey                  = "template"
+)
+
+//go:embed embedded/*.txt
+var embedded embed.FS
+
+// Data is passed to notification templates.
+type Data struct {
+	Hostname  string    `json:"hostname"`
+	EventTime time.Time `json:"eventTime"`
+	EventArgs any       `json:"eventArgs"`
+}
+
+// Info describes a notification template.
+type Info struct {
+	Name         string `json:"name"`
+	IsCustomized bool   `json:"isCustomized"`
+}
+
+// templateManifest is the payload of manifests storing customized templates.
+type templateManifest struct {
+	Text string `json:"text"`
+}
+
+func labelsForTemplateName(name string) map[string]string {
+	return map[string]string{
+		manifest.TypeLabelKey: notificationTemplateManifestType,
+		templateNameKey:       name,
+	}
+}
+
+// DefaultTemplate returns the built-in text of the template with the provided name.
+func DefaultTemplate(name string) (string, error) {
+	b, err := embedded.ReadFile("embedded/" + name)
+	if err != nil {
+		return "", errors.Errorf("unknown notification template: %q", name)
+	}
+
+	return string(b), nil
+}
+
+// GetTemplate returns the text of the template with the provided name, customized or built-in.
+func GetTemplate(ctx context.Context, rep repo.Repository, name string) (string, error) {
+	entries, err := rep.FindManifests(ctx, labelsForTemplateName(name))
+	if err != nil {
+		return "", errors.Wrap(err, "unable to find notification template")
+	}
+
+	if len(entries) == 0 {
+		return DefaultTemplate(name)
+	}
+
+	var tm templateManifest
+
+	if _, err := rep.GetManifest(ctx, manifest.PickLatestID(entries), &tm); err != nil {
+		return "", errors.Wrap(err, "unable to get notification template")
+	}
+
+	return tm.Text, nil
+}
+
+// SetTemplate stores the customized text of the built-in template with the provided name.
+func SetTemplate(ctx context.Context, rep repo.RepositoryWriter, name, text string) error {
+	if _, err := DefaultTemplate(name); err != nil {
+		return err
+	}
+
+	if _, err := ParseTemplate(text); err != nil {
+		return err
+	}
+
+	if _, err := rep.ReplaceManifests(ctx, labelsForTemplateName(name), &templateManifest{Text: text}); err != nil {
+		return errors.Wrap(err, "unable to save notification template")
+	}
+
+	return nil
+}
+
+// ResetTemplate removes the customized text of the template with the provided name, restoring the built-in one.
+func ResetTemplate(ctx context.Context, rep repo.RepositoryWriter, name string) error {
+	entries, err := rep.FindManifests(ctx, labelsForTemplateName(name))
+	if err != nil {
+		return errors.Wrap(err, "unable to find notification template")
+	}
+
+	for _, e := range entries {
+		if err := rep.DeleteManifest(ctx, e.ID); err != nil {
+			return errors.Wrap(err, "unable to delete notification template")
+		}
+	}
+
+	return nil
+}
+
+// ListTemplates returns information about all built-in templates.
+func ListTemplates(ctx context.Context, rep repo.Repository) ([]*Info, error) {
+	entries, err := embedded.ReadDir("embedded")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list built-in templates")
+	}
+
+	customized, err := rep.FindManifests(ctx, map[string]string{
+		manifest.TypeLabelKey: notificationTemplateManifestType,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list notification templates")
+	}
+
+	isCustomized := map[string]bool{}
+	for _, e := range customized {
+		isCustomized[e.Labels[templateNameKey]] = true
+	}
+
+	var result []*Info
+
+	for _, e := range entries {
+		result = append(result, &Info{
+			Name:         e.Name(),
+			IsCustomized: isCustomized[e.Name()],
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// ParseTemplate parses the text of a notification template.
+func ParseTemplate(text string) (*template.Template, error) {
+	t, err := template.New("notification").Funcs(template.FuncMap{
+		"formatTime": func(t time.Time) string {
+			return t.Local().Format("Mon Jan 2 15:04:05 MST 2006")
+		},
+		"bytes": units.BytesString,
+		"join":  strings.Join,
+		"title": func(s string) string {
+			if s == "" {
+				return s
+			}
+
+			return strings.ToUpper(s[:1]) + s[1:]
+		},
+	}).Parse(text)
+
+	return t, errors.Wrap(err, "unable to parse notification template")
+}
+
+// Render renders the template text with the provided data.
+func Render(text string, data *Data) (string, error) {
+	t, err := ParseTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+
+	if err := t.Execute(&buf, data); err != nil {
+		return "", errors.Wrap(err, "unable to render notification template")
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,93 @@
+package notifytemplate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/notification/sender"
+)
+
+func TestDefaultTemplatesRender(t *testing.T) {
+	startTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := map[string][]any{
+		notifytemplate.SnapshotReport: {
+			&notification.SnapshotReport{Source: "src", StartTime: startTime, EndTime: startTime.Add(time.Hour)},
+			&notification.SnapshotReport{Source: "src", Error: "some error"},
+		},
+		notifytemplate.MaintenanceReport: {
+			&notification.MaintenanceReport{Mode: "full", StartTime: startTime, EndTime: startTime.Add(time.Hour)},
+			&notification.MaintenanceReport{Mode: "quick", Error: "some error"},
+		},
+		notifytemplate.MaintenanceOverdue: {
+			&notification.MaintenanceOverdue{Owner: "user@host", DueTime: startTime, Overdue: 48 * time.Hour},
+		},
+		notifytemplate.VerifyError: {
+			&notification.VerifyError{Error: "some error"},
+			&notification.VerifyError{Sources: []string{"a", "b"}, Error: "some error"},
+		},
+		notifytemplate.TestNotification: {
+			&notification.TestNotification{ProfileName: "some-profile"},
+		},
+	}
+
+	for name, args := range cases {
+		text, err := notifytemplate.DefaultTemplate(name)
+		require.NoError(t, err)
+
+		for _, a := range args {
+			rendered, err := notifytemplate.Render(text, &notifytemplate.Data{
+				Hostname:  "some-host",
+				EventTime: startTime,
+				EventArgs: a,
+			})
+			require.NoError(t, err, name)
+
+			msg, err := sender.ParseMessage(rendered, sender.SeverityReport)
+			require.NoError(t, err, name)
+			require.Contains(t, msg.Subject, "some-host", name)
+			require.NotContains(t, rendered, "<no value>", name)
+		}
+	}
+}
+
+func TestCustomTemplates(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	infos, err := notifytemplate.ListTemplates(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, infos, 5)
+
+	for _, i := range infos {
+		require.False(t, i.IsCustomized)
+	}
+
+	require.Error(t, notifytemplate.SetTemplate(ctx, env.RepositoryWriter, "no-such-template.txt", "Subject: x\n"))
+	require.Error(t, notifytemplate.SetTemplate(ctx, env.RepositoryWriter, notifytemplate.VerifyError, "Subject: {{ .Invalid"))
+	require.NoError(t, notifytemplate.SetTemplate(ctx, env.RepositoryWriter, notifytemplate.VerifyError, "Subject: custom\n"))
+
+	text, err := notifytemplate.GetTemplate(ctx, env.RepositoryWriter, notifytemplate.VerifyError)
+	require.NoError(t, err)
+	require.Equal(t, "Subject: custom\n", text)
+
+	infos, err = notifytemplate.ListTemplates(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+
+	for _, i := range infos {
+		require.Equal(t, i.Name == notifytemplate.VerifyError, i.IsCustomized, i.Name)
+	}
+
+	require.NoError(t, notifytemplate.ResetTemplate(ctx, env.RepositoryWriter, notifytemplate.VerifyError))
+
+	text, err = notifytemplate.GetTemplate(ctx, env.RepositoryWriter, notifytemplate.VerifyError)
+	require.NoError(t, err)
+
+	def, err := notifytemplate.DefaultTemplate(notifytemplate.VerifyError)
+	require.NoError(t, err)
+	require.Equal(t, def, text)
+}
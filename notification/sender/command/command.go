@@ -0,0 +1,87 @@
+// Package command implements notification sender which runs a command for each message.
+package command
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification/sender"
+)
+
+// Method is the notification method of command senders.
+const Method sender.Method = "command"
+
+const defaultTimeout = 60 * time.Second
+
+// Options defines command notification sender options.
+type Options struct {
+	Command   string   `json:"command"`
+	Arguments []string `json:"arguments,omitempty"`
+
+	TimeoutSeconds int `json:"timeout,omitempty"`
+}
+
+// ApplyDefaultsAndValidate applies default values and validates the options.
+func (o *Options) ApplyDefaultsAndValidate() error {
+	if o.Command == "" {
+		return errors.Errorf("command must be provided")
+	}
+
+	return nil
+}
+
+type commandSender struct {
+	opt Options
+}
+
+// Send runs the command passing the message body on standard input and the subject, severity
+// and headers in KOPIA_NOTIFICATION_* environment variables.
+func (s *commandSender) Send(ctx context.Context, msg *sender.Message) error {
+	timeout := defaultTimeout
+	if s.opt.TimeoutSeconds != 0 {
+		timeout = time.Duration(s.opt.TimeoutSeconds) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, s.opt.Command, s.opt.Arguments...) //nolint:gosec
+	c.Env = append(os.Environ(),
+		"KOPIA_NOTIFICATION_SUBJECT="+msg.Subject,
+		"KOPIA_NOTIFICATION_SEVERITY="+msg.Severity.String(),
+	)
+
+	for k, v := range msg.Headers {
+		c.Env = append(c.Env, "KOPIA_NOTIFICATION_HEADER_"+strings.ToUpper(strings.ReplaceAll(k, "-", "_"))+"="+v)
+	}
+
+	c.Stdin = strings.NewReader(msg.Body)
+
+	if out, err := c.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "error running notification command: %s", strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+func (s *commandSender) Summary() string {
+	return "Command: " + strings.Join(append([]string{s.opt.Command}, s.opt.Arguments...), " ")
+}
+
+// New returns new command notification sender.
+func New(ctx context.Context, opt *Options) (sender.Sender, error) {
+	if err := opt.ApplyDefaultsAndValidate(); err != nil {
+		return nil, errors.Wrap(err, "invalid notification configuration")
+	}
+
+	return &commandSender{opt: *opt}, nil
+}
+
+func init() {
+	sender.Register(Method, Options{}, New)
+}
@@ -0,0 +1,47 @@
+package command_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/command"
+)
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a POSIX shell")
+	}
+
+	ctx := testlogging.Context(t)
+	outFile := filepath.Join(t.TempDir(), "out.txt")
+
+	s, err := command.New(ctx, &command.Options{
+		Command:   "sh",
+		Arguments: []string{"-c", `echo "$KOPIA_NOTIFICATION_SEVERITY $KOPIA_NOTIFICATION_SUBJECT $KOPIA_NOTIFICATION_HEADER_X_SOME" > "$0"; cat >> "$0"`, outFile},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Send(ctx, &sender.Message{
+		Subject:  "some subject",
+		Headers:  map[string]string{"X-Some": "value"},
+		Severity: sender.SeverityWarning,
+		Body:     "some body\n",
+	}))
+
+	b, err := os.ReadFile(outFile)
+	require.NoError(t, err)
+	require.Equal(t, "warning some subject value\nsome body\n", string(b))
+
+	s, err = command.New(ctx, &command.Options{
+		Command:   "sh",
+		Arguments: []string{"-c", "echo some failure; exit 1"},
+	})
+	require.NoError(t, err)
+	require.ErrorContains(t, s.Send(ctx, &sender.Message{Subject: "some subject"}), "some failure")
+}
@@ -0,0 +1,135 @@
+// Package email implements notification sender which delivers messages using SMTP.
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/notification/sender"
+)
+
+// Method is the notification method of email senders.
+const Method sender.Method = "email"
+
+const defaultSMTPPort = 587
+
+// Options defines email notification sender options.
+type Options struct {
+	SMTPServer   string `json:"smtpServer"`
+	SMTPPort     int    `json:"smtpPort"`
+	SMTPIdentity string `json:"smtpIdentity,omitempty"`
+	SMTPUsername string `json:"smtpUsername,omitempty"`
+	SMTPPassword string `json:"smtpPassword,omitempty" kopia:"sensitive"`
+
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ApplyDefaultsAndValidate applies default values and validates the options.
+func (o *Options) ApplyDefaultsAndValidate() error {
+	if o.SMTPPort == 0 {
+		o.SMTPPort = defaultSMTPPort
+	}
+
+	if o.SMTPServer == "" {
+		return errors.Errorf("SMTP server must be provided")
+	}
+
+	if o.From == "" {
+		return errors.Errorf("From address must be provided")
+	}
+
+	if len(o.recipients()) == 0 {
+		return errors.Errorf("To address must be provided")
+	}
+
+	return nil
+}
+
+// recipients returns the list of comma-separated recipients.
+func (o *Options) recipients() []string {
+	var result []string
+
+	for _, r := range strings.Split(o.To, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			result = append(result, r)
+		}
+	}
+
+	return result
+}
+
+type emailSender struct {
+	opt Options
+}
+
+func (s *emailSender) Send(ctx context.Context, msg *sender.Message) error {
+	var auth smtp.Auth
+
+	if s.opt.SMTPUsername != "" {
+		auth = smtp.PlainAuth(s.opt.SMTPIdentity, s.opt.SMTPUsername, s.opt.SMTPPassword, s.opt.SMTPServer)
+	}
+
+	addr := net.JoinHostPort(s.opt.SMTPServer, strconv.Itoa(s.opt.SMTPPort))
+
+	if err := smtp.SendMail(addr, auth, s.opt.From, s.opt.recipients(), s.formatMessage(msg)); err != nil {
+		return errors.Wrap(err, "unable to send email")
+	}
+
+	return nil
+}
+
+// formatMessage formats the message as RFC 5322 plain text email.
+func (s *emailSender) formatMessage(msg *sender.Message) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %v\r\n", s.opt.From)
+	fmt.Fprintf(&buf, "To: %v\r\n", strings.Join(s.opt.recipients(), ", "))
+	fmt.Fprintf(&buf, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %v\r\n", clock.Now().Format("Mon, 02 Jan 2006 15:04:05 -0700"))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: text/plain; charset=UTF-8\r\n")
+
+	var keys []string
+	for k := range msg.Headers {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%v: %v\r\n", k, msg.Headers[k])
+	}
+
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return buf.Bytes()
+}
+
+func (s *emailSender) Summary() string {
+	return fmt.Sprintf("SMTP server: %q, Mail from: %q Mail to: %q", s.opt.SMTPServer, s.opt.From, s.opt.To)
+}
+
+// New returns new email notification sender.
+func New(ctx context.Context, opt *Options) (sender.Sender, error) {
+	if err := opt.ApplyDefaultsAndValidate(); err != nil {
+		return nil, errors.Wrap(err, "invalid notification configuration")
+	}
+
+	return &emailSender{opt: *opt}, nil
+}
+
+func init() {
+	sender.Register(Method, Options{}, New)
+}
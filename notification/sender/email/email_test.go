@@ -0,0 +1,162 @@
+package email_test
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/email"
+)
+
+// smtpStandIn is a minimal SMTP server, which accepts all messages and records them.
+type smtpStandIn struct {
+	listener net.Listener
+
+	mu         sync.Mutex
+	from       []string
+	recipients []string
+	data       []string
+}
+
+func startSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &smtpStandIn{listener: l}
+
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go s.handle(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *smtpStandIn) port() int {
+	//nolint:forcetypeassert
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStandIn) handle(conn net.Conn) {
+	defer conn.Close() //nolint:errcheck
+
+	tc := textproto.NewConn(conn)
+
+	tc.PrintfLine("220 localhost ESMTP stand-in") //nolint:errcheck
+
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			tc.PrintfLine("250 localhost") //nolint:errcheck
+
+		case "MAIL":
+			s.mu.Lock()
+			s.from = append(s.from, arg)
+			s.mu.Unlock()
+			tc.PrintfLine("250 OK") //nolint:errcheck
+
+		case "RCPT":
+			s.mu.Lock()
+			s.recipients = append(s.recipients, arg)
+			s.mu.Unlock()
+			tc.PrintfLine("250 OK") //nolint:errcheck
+
+		case "DATA":
+			tc.PrintfLine("354 Go ahead") //nolint:errcheck
+
+			b, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.data = append(s.data, string(b))
+			s.mu.Unlock()
+			tc.PrintfLine("250 OK") //nolint:errcheck
+
+		case "QUIT":
+			tc.PrintfLine("221 Bye") //nolint:errcheck
+			return
+
+		default:
+			tc.PrintfLine("250 OK") //nolint:errcheck
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	ctx := testlogging.Context(t)
+	srv := startSMTPStandIn(t)
+
+	s, err := email.New(ctx, &email.Options{
+		SMTPServer: "127.0.0.1",
+		SMTPPort:   srv.port(),
+		From:       "kopia@example.com",
+		To:         "user1@example.com, user2@example.com",
+	})
+	require.NoError(t, err)
+	require.Contains(t, s.Summary(), "user1@example.com")
+
+	require.NoError(t, s.Send(ctx, &sender.Message{
+		Subject:  "some subject",
+		Headers:  map[string]string{"X-Some": "value"},
+		Severity: sender.SeverityError,
+		Body:     "line 1\nline 2\n",
+	}))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	require.Equal(t, []string{"FROM:<kopia@example.com>"}, srv.from)
+	require.Equal(t, []string{"TO:<user1@example.com>", "TO:<user2@example.com>"}, srv.recipients)
+	require.Len(t, srv.data, 1)
+
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(srv.data[0]))).ReadMIMEHeader()
+	require.NoError(t, err)
+	require.Equal(t, "some subject", msg.Get("Subject"))
+	require.Equal(t, "value", msg.Get("X-Some"))
+	require.Equal(t, "kopia@example.com", msg.Get("From"))
+	require.Contains(t, srv.data[0], "\nline 1\nline 2\n")
+}
+
+func TestEmailOptionsValidation(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	_, err := email.New(ctx, &email.Options{From: "a@example.com", To: "b@example.com"})
+	require.ErrorContains(t, err, "SMTP server")
+
+	_, err = email.New(ctx, &email.Options{SMTPServer: "localhost", To: "b@example.com"})
+	require.ErrorContains(t, err, "From address")
+
+	_, err = email.New(ctx, &email.Options{SMTPServer: "localhost", From: "a@example.com", To: " , "})
+	require.ErrorContains(t, err, "To address")
+
+	opt := &email.Options{SMTPServer: "localhost", From: "a@example.com", To: "b@example.com"}
+	_, err = email.New(ctx, opt)
+	require.NoError(t, err)
+	require.Equal(t, 587, opt.SMTPPort)
+}
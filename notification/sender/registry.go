@@ -0,0 +1,93 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Method identifies a type of notification sender, such as "email" or "webhook".
+type Method string
+
+//nolint:gochecknoglobals
+var factories = map[Method]*senderFactory{}
+
+type senderFactory struct {
+	defaultOptionsFunc func() interface{}
+	createSenderFunc   func(ctx context.Context, options interface{}) (Sender, error)
+}
+
+// Register registers factory function to create senders of a given method.
+func Register[T any](
+	method Method,
+	defaultOptions T,
+	createSenderFunc func(ctx context.Context, options *T) (Sender, error),
+) {
+	factories[method] = &senderFactory{
+		defaultOptionsFunc: func() interface{} {
+			c := defaultOptions
+			return &c
+		},
+		createSenderFunc: func(ctx context.Context, options interface{}) (Sender, error) {
+			//nolint:forcetypeassert
+			return createSenderFunc(ctx, options.(*T))
+		},
+	}
+}
+
+// MethodConfig represents JSON-serializable configuration of a notification sender.
+//
+//nolint:musttag // we use custom JSON marshaling.
+type MethodConfig struct {
+	Type   Method
+	Config interface{}
+}
+
+// UnmarshalJSON parses the JSON-encoded data into MethodConfig.
+func (c *MethodConfig) UnmarshalJSON(b []byte) error {
+	raw := struct {
+		Type Method          `json:"type"`
+		Data json.RawMessage `json:"config"`
+	}{}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return errors.Wrap(err, "error unmarshaling method config JSON")
+	}
+
+	c.Type = raw.Type
+
+	f := factories[raw.Type]
+	if f == nil {
+		return errors.Errorf("notification method '%v' not registered", raw.Type)
+	}
+
+	c.Config = f.defaultOptionsFunc()
+	if err := json.Unmarshal(raw.Data, c.Config); err != nil {
+		return errors.Wrap(err, "unable to unmarshal config")
+	}
+
+	return nil
+}
+
+// MarshalJSON returns JSON-encoded sender configuration.
+func (c MethodConfig) MarshalJSON() ([]byte, error) {
+	//nolint:wrapcheck
+	return json.Marshal(struct {
+		Type Method      `json:"type"`
+		Data interface{} `json:"config"`
+	}{
+		Type: c.Type,
+		Data: c.Config,
+	})
+}
+
+// GetSender creates the sender based on the provided configuration.
+// The method must be previously registered using Register.
+func GetSender(ctx context.Context, c MethodConfig) (Sender, error) {
+	if f, ok := factories[c.Type]; ok {
+		return f.createSenderFunc(ctx, c.Config)
+	}
+
+	return nil, errors.Errorf("unknown notification method: %s", c.Type)
+}
@@ -0,0 +1,118 @@
+// Package sender defines the interface of notification senders and provides a registry of their types.
+package sender
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Severity represents the severity of a notification message, higher values are more severe.
+type Severity int32
+
+// Supported notification severities.
+const (
+	SeverityVerbose Severity = -100
+	SeveritySuccess Severity = -10
+	SeverityReport  Severity = 0
+	SeverityWarning Severity = 10
+	SeverityError   Severity = 20
+)
+
+// SeverityNames maps severity names to their values.
+//
+//nolint:gochecknoglobals
+var SeverityNames = map[string]Severity{
+	"verbose": SeverityVerbose,
+	"success": SeveritySuccess,
+	"report":  SeverityReport,
+	"warning": SeverityWarning,
+	"error":   SeverityError,
+}
+
+// ParseSeverity parses the severity name.
+func ParseSeverity(s string) (Severity, error) {
+	if v, ok := SeverityNames[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	return 0, errors.Errorf("unknown severity: %q", s)
+}
+
+func (s Severity) String() string {
+	for k, v := range SeverityNames {
+		if v == s {
+			return k
+		}
+	}
+
+	return fmt.Sprintf("severity-%d", int32(s))
+}
+
+// Message represents a notification message ready to be sent.
+type Message struct {
+	Subject  string            `json:"subject"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Severity Severity          `json:"severity"`
+	Body     string            `json:"body"`
+}
+
+// ParseMessage parses the rendered notification template, which consists of 'Key: Value' headers
+// followed by an empty line and the message body. The 'Subject' header becomes the message subject.
+func ParseMessage(text string, severity Severity) (*Message, error) {
+	msg := &Message{
+		Headers:  map[string]string{},
+		Severity: severity,
+	}
+
+	s := bufio.NewScanner(strings.NewReader(text))
+
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			break
+		}
+
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, errors.Errorf("invalid message header: %q", line)
+		}
+
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+
+		if strings.EqualFold(k, "Subject") {
+			msg.Subject = v
+		} else {
+			msg.Headers[k] = v
+		}
+	}
+
+	var body []string
+
+	for s.Scan() {
+		body = append(body, s.Text())
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "error parsing message")
+	}
+
+	if msg.Subject == "" {
+		return nil, errors.Errorf("message is missing a subject")
+	}
+
+	msg.Body = strings.TrimSpace(strings.Join(body, "\n")) + "\n"
+
+	return msg, nil
+}
+
+// Sender sends notification messages.
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+
+	// Summary returns a human-readable description of the destination of messages.
+	Summary() string
+}
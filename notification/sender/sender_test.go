@@ -0,0 +1,42 @@
+package sender_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/notification/sender"
+)
+
+func TestParseMessage(t *testing.T) {
+	msg, err := sender.ParseMessage("Subject: Some subject\nX-Header: some value\n\nLine 1\nLine 2\n\n", sender.SeverityWarning)
+	require.NoError(t, err)
+	require.Equal(t, &sender.Message{
+		Subject:  "Some subject",
+		Headers:  map[string]string{"X-Header": "some value"},
+		Severity: sender.SeverityWarning,
+		Body:     "Line 1\nLine 2\n",
+	}, msg)
+
+	_, err = sender.ParseMessage("X-Header: some value\n\nbody", sender.SeverityWarning)
+	require.ErrorContains(t, err, "missing a subject")
+
+	_, err = sender.ParseMessage("Subject: foo\ninvalid header\n\nbody", sender.SeverityWarning)
+	require.ErrorContains(t, err, "invalid message header")
+}
+
+func TestParseSeverity(t *testing.T) {
+	for name, sev := range sender.SeverityNames {
+		got, err := sender.ParseSeverity(name)
+		require.NoError(t, err)
+		require.Equal(t, sev, got)
+		require.Equal(t, name, sev.String())
+	}
+
+	got, err := sender.ParseSeverity("ERROR")
+	require.NoError(t, err)
+	require.Equal(t, sender.SeverityError, got)
+
+	_, err = sender.ParseSeverity("no-such-severity")
+	require.Error(t, err)
+}
@@ -0,0 +1,67 @@
+// Package testsender implements notification sender which captures messages in memory for testing.
+package testsender
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification/sender"
+)
+
+// Method is the notification method of test senders.
+const Method sender.Method = "testsender"
+
+// Options defines test notification sender options.
+type Options struct {
+	// Name identifies the list of captured messages.
+	Name string `json:"name"`
+
+	// Fail causes sending to fail.
+	Fail bool `json:"fail,omitempty"`
+}
+
+//nolint:gochecknoglobals
+var (
+	mu       sync.Mutex
+	captured = map[string][]*sender.Message{}
+)
+
+// MessagesSentTo returns messages captured by test senders with the provided name.
+func MessagesSentTo(name string) []*sender.Message {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return append([]*sender.Message(nil), captured[name]...)
+}
+
+type testSender struct {
+	opt Options
+}
+
+func (s *testSender) Send(ctx context.Context, msg *sender.Message) error {
+	if s.opt.Fail {
+		return errors.Errorf("test sender failure")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	captured[s.opt.Name] = append(captured[s.opt.Name], msg)
+
+	return nil
+}
+
+func (s *testSender) Summary() string {
+	return "Test sender " + s.opt.Name
+}
+
+// New returns new test notification sender.
+func New(ctx context.Context, opt *Options) (sender.Sender, error) {
+	return &testSender{opt: *opt}, nil
+}
+
+func init() {
+	sender.Register(Method, Options{}, New)
+}
@@ -0,0 +1,104 @@
+// Package webhook implements notification sender which delivers messages as JSON payload of HTTP requests.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/notification/sender"
+)
+
+// Method is the notification method of webhook senders.
+const Method sender.Method = "webhook"
+
+// Options defines webhook notification sender options.
+type Options struct {
+	Endpoint string `json:"endpoint"`
+	Method   string `json:"method"`
+
+	// Headers are additional HTTP headers sent with each request, such as authorization tokens.
+	Headers map[string]string `json:"headers,omitempty" kopia:"sensitive"`
+}
+
+// ApplyDefaultsAndValidate applies default values and validates the options.
+func (o *Options) ApplyDefaultsAndValidate() error {
+	if o.Method == "" {
+		o.Method = http.MethodPost
+	}
+
+	if o.Endpoint == "" {
+		return errors.Errorf("endpoint must be provided")
+	}
+
+	return nil
+}
+
+// Payload is the JSON payload of webhook requests.
+type Payload struct {
+	Subject  string            `json:"subject"`
+	Severity string            `json:"severity"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Body     string            `json:"body"`
+}
+
+type webhookSender struct {
+	opt Options
+}
+
+func (s *webhookSender) Send(ctx context.Context, msg *sender.Message) error {
+	payload, err := json.Marshal(Payload{
+		Subject:  msg.Subject,
+		Severity: msg.Severity.String(),
+		Headers:  msg.Headers,
+		Body:     msg.Body,
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal payload")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, s.opt.Method, s.opt.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrap(err, "unable to create request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range s.opt.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending webhook request")
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("webhook request failed with status %v", resp.Status)
+	}
+
+	return nil
+}
+
+func (s *webhookSender) Summary() string {
+	return fmt.Sprintf("Webhook %v %v", s.opt.Method, s.opt.Endpoint)
+}
+
+// New returns new webhook notification sender.
+func New(ctx context.Context, opt *Options) (sender.Sender, error) {
+	if err := opt.ApplyDefaultsAndValidate(); err != nil {
+		return nil, errors.Wrap(err, "invalid notification configuration")
+	}
+
+	return &webhookSender{opt: *opt}, nil
+}
+
+func init() {
+	sender.Register(Method, Options{}, New)
+}
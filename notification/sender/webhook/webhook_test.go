@@ -0,0 +1,71 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/notification/sender"
+	"github.com/kopia/kopia/notification/sender/webhook"
+)
+
+func TestWebhook(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	var (
+		requests []webhook.Payload
+		headers  []http.Header
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhook.Payload
+
+		if r.URL.Path == "/fail" {
+			http.Error(w, "failure", http.StatusInternalServerError)
+			return
+		}
+
+		if r.Method != http.MethodPut || json.NewDecoder(r.Body).Decode(&p) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		requests = append(requests, p)
+		headers = append(headers, r.Header.Clone())
+	}))
+	defer srv.Close()
+
+	s, err := webhook.New(ctx, &webhook.Options{
+		Endpoint: srv.URL + "/notify",
+		Method:   http.MethodPut,
+		Headers:  map[string]string{"Authorization": "Bearer some-token"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Send(ctx, &sender.Message{
+		Subject:  "some subject",
+		Headers:  map[string]string{"X-Some": "value"},
+		Severity: sender.SeverityError,
+		Body:     "some body",
+	}))
+
+	require.Equal(t, []webhook.Payload{{
+		Subject:  "some subject",
+		Severity: "error",
+		Headers:  map[string]string{"X-Some": "value"},
+		Body:     "some body",
+	}}, requests)
+	require.Equal(t, "Bearer some-token", headers[0].Get("Authorization"))
+	require.Equal(t, "application/json", headers[0].Get("Content-Type"))
+
+	s, err = webhook.New(ctx, &webhook.Options{Endpoint: srv.URL + "/fail"})
+	require.NoError(t, err)
+	require.ErrorContains(t, s.Send(ctx, &sender.Message{Subject: "some subject"}), "500")
+
+	_, err = webhook.New(ctx, &webhook.Options{})
+	require.ErrorContains(t, err, "endpoint must be provided")
+}
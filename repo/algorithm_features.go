@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"github.com/kopia/kopia/internal/feature"
+	"github.com/kopia/kopia/repo/format"
+)
+
+// Features required by repositories using algorithms added after the required features mechanism.
+const (
+	featureEncryptionXChaCha20Poly1305 feature.Feature = "encryption-xchacha20-poly1305"
+	featureEncryptionAES256GCMSIV      feature.Feature = "encryption-aes256-gcm-siv"
+	featureHashHMACSHA3256128          feature.Feature = "hash-hmac-sha3-256-128"
+	featureHashKMAC256                 feature.Feature = "hash-kmac256"
+)
+
+// algorithmFeatures maps hashing and encryption algorithms to features recorded in repositories using them,
+// so that older clients refuse to open such repositories with a clear message.
+//
+//nolint:gochecknoglobals
+var algorithmFeatures = map[string]feature.Feature{
+	"XCHACHA20-POLY1305-HMAC-SHA256": featureEncryptionXChaCha20Poly1305,
+	"AES256-GCM-SIV-HMAC-SHA256":     featureEncryptionAES256GCMSIV,
+	"HMAC-SHA3-256-128":              featureHashHMACSHA3256128,
+	"KMAC256":                        featureHashKMAC256,
+	"KMAC256-128":                    featureHashKMAC256,
+}
+
+// algorithmRequiredFeatures returns features required to open repositories using algorithms of the provided content format.
+func algorithmRequiredFeatures(cf *format.ContentFormat) []feature.Required {
+	var result []feature.Required
+
+	for _, algo := range []string{cf.Hash, cf.Encryption} {
+		if f, ok := algorithmFeatures[algo]; ok {
+			result = append(result, feature.Required{
+				Feature: f,
+				IfNotUnderstood: feature.IfNotUnderstood{
+					Message: "The repository uses " + algo + " algorithm, which is not supported by this version of Kopia.",
+				},
+			})
+		}
+	}
+
+	return result
+}
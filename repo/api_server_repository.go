@@ -25,6 +25,10 @@ type APIServerInfo struct {
 	BaseURL                             string `json:"url"`
 	TrustedServerCertificateFingerprint string `json:"serverCertFingerprint"`
 	DisableGRPC                         bool   `json:"disableGRPC,omitempty"`
+
+	// PEM files with client certificate and its private key presented to the server, optional.
+	ClientCertificateFile string `json:"clientCertFile,omitempty"`
+	ClientKeyFile         string `json:"clientKeyFile,omitempty"`
 }
 
 // remoteRepository is an implementation of Repository that connects to an instance of
@@ -294,6 +298,8 @@ func openRestAPIRepository(ctx context.Context, si *APIServerInfo, password stri
 	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
 		BaseURL:                             si.BaseURL,
 		TrustedServerCertificateFingerprint: si.TrustedServerCertificateFingerprint,
+		ClientCertificateFile:               si.ClientCertificateFile,
+		ClientKeyFile:                       si.ClientKeyFile,
 		Username:                            par.cliOpts.UsernameAtHost(),
 		Password:                            password,
 		LogRequests:                         true,
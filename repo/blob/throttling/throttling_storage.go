@@ -47,15 +47,17 @@ type throttlingStorage struct {
 }
 
 func (s *throttlingStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
+	th := s.throttlerFor(ctx)
+
 	acquired := length
 	if acquired < 0 {
 		acquired = unknownBlobAcquireLength
 	}
 
-	s.throttler.BeforeOperation(ctx, operationGetBlob)
-	defer s.throttler.AfterOperation(ctx, operationGetBlob)
+	th.BeforeOperation(ctx, operationGetBlob)
+	defer th.AfterOperation(ctx, operationGetBlob)
 
-	s.throttler.BeforeDownload(ctx, acquired)
+	th.BeforeDownload(ctx, acquired)
 
 	output.Reset()
 
@@ -65,10 +67,10 @@ func (s *throttlingStorage) GetBlob(ctx context.Context, id blob.ID, offset, len
 	if acquired != downloaded {
 		if downloaded > acquired {
 			// we downloaded more than initially acquired, acquire more which may pause for a bit.
-			s.throttler.BeforeDownload(ctx, downloaded-acquired)
+			th.BeforeDownload(ctx, downloaded-acquired)
 		} else {
 			// we downloaded less than initially acquired, release extra
-			s.throttler.ReturnUnusedDownloadBytes(ctx, acquired-downloaded)
+			th.ReturnUnusedDownloadBytes(ctx, acquired-downloaded)
 		}
 	}
 
@@ -76,42 +78,101 @@ func (s *throttlingStorage) GetBlob(ctx context.Context, id blob.ID, offset, len
 }
 
 func (s *throttlingStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
-	s.throttler.BeforeOperation(ctx, operationGetMetadata)
-	defer s.throttler.AfterOperation(ctx, operationGetMetadata)
+	th := s.throttlerFor(ctx)
+
+	th.BeforeOperation(ctx, operationGetMetadata)
+	defer th.AfterOperation(ctx, operationGetMetadata)
 
 	return s.Storage.GetMetadata(ctx, id) //nolint:wrapcheck
 }
 
 func (s *throttlingStorage) ListBlobs(ctx context.Context, blobIDPrefix blob.ID, cb func(bm blob.Metadata) error) error {
-	s.throttler.BeforeOperation(ctx, operationListBlobs)
-	defer s.throttler.AfterOperation(ctx, operationListBlobs)
+	th := s.throttlerFor(ctx)
+
+	th.BeforeOperation(ctx, operationListBlobs)
+	defer th.AfterOperation(ctx, operationListBlobs)
 
 	return s.Storage.ListBlobs(ctx, blobIDPrefix, cb) //nolint:wrapcheck
 }
 
 func (s *throttlingStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
-	s.throttler.BeforeOperation(ctx, operationPutBlob)
-	defer s.throttler.AfterOperation(ctx, operationPutBlob)
+	th := s.throttlerFor(ctx)
+
+	th.BeforeOperation(ctx, operationPutBlob)
+	defer th.AfterOperation(ctx, operationPutBlob)
 
-	s.throttler.BeforeUpload(ctx, int64(data.Length()))
+	th.BeforeUpload(ctx, int64(data.Length()))
 
 	return s.Storage.PutBlob(ctx, id, data, opts) //nolint:wrapcheck
 }
 
 func (s *throttlingStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
-	s.throttler.BeforeOperation(ctx, operationDeleteBlob)
-	defer s.throttler.AfterOperation(ctx, operationDeleteBlob)
+	th := s.throttlerFor(ctx)
+
+	th.BeforeOperation(ctx, operationDeleteBlob)
+	defer th.AfterOperation(ctx, operationDeleteBlob)
 
 	return s.Storage.DeleteBlob(ctx, id) //nolint:wrapcheck
 }
 
 func (s *throttlingStorage) ExtendBlobRetention(ctx context.Context, id blob.ID, opts blob.ExtendOptions) error {
-	s.throttler.BeforeOperation(ctx, operationExtendBlobRetention)
-	defer s.throttler.AfterOperation(ctx, operationExtendBlobRetention)
+	th := s.throttlerFor(ctx)
+
+	th.BeforeOperation(ctx, operationExtendBlobRetention)
+	defer th.AfterOperation(ctx, operationExtendBlobRetention)
 
 	return s.Storage.ExtendBlobRetention(ctx, id, opts) //nolint:wrapcheck
 }
 
+// throttlerFor returns the throttler used for an operation with the provided context.
+func (s *throttlingStorage) throttlerFor(ctx context.Context) Throttler {
+	if t, ok := ctx.Value(contextThrottlerKey{}).(Throttler); ok {
+		return chainedThrottler{t, s.throttler}
+	}
+
+	return s.throttler
+}
+
+type contextThrottlerKey struct{}
+
+// WithThrottler returns a context in which operations of throttling storage wrappers are additionally
+// throttled by the provided throttler, so that a long-running operation, such as a maintenance task,
+// can be limited without affecting other operations using the same storage.
+func WithThrottler(ctx context.Context, t Throttler) context.Context {
+	return context.WithValue(ctx, contextThrottlerKey{}, t)
+}
+
+// chainedThrottler applies the limits of two throttlers.
+type chainedThrottler struct {
+	first  Throttler
+	second Throttler
+}
+
+func (c chainedThrottler) BeforeOperation(ctx context.Context, op string) {
+	c.first.BeforeOperation(ctx, op)
+	c.second.BeforeOperation(ctx, op)
+}
+
+func (c chainedThrottler) AfterOperation(ctx context.Context, op string) {
+	c.second.AfterOperation(ctx, op)
+	c.first.AfterOperation(ctx, op)
+}
+
+func (c chainedThrottler) BeforeDownload(ctx context.Context, numBytes int64) {
+	c.first.BeforeDownload(ctx, numBytes)
+	c.second.BeforeDownload(ctx, numBytes)
+}
+
+func (c chainedThrottler) BeforeUpload(ctx context.Context, numBytes int64) {
+	c.first.BeforeUpload(ctx, numBytes)
+	c.second.BeforeUpload(ctx, numBytes)
+}
+
+func (c chainedThrottler) ReturnUnusedDownloadBytes(ctx context.Context, numBytes int64) {
+	c.first.ReturnUnusedDownloadBytes(ctx, numBytes)
+	c.second.ReturnUnusedDownloadBytes(ctx, numBytes)
+}
+
 // NewWrapper returns a Storage wrapper that adds retry loop around all operations of the underlying storage.
 func NewWrapper(wrapped blob.Storage, throttler Throttler) blob.Storage {
 	return &throttlingStorage{wrapped, throttler}
@@ -148,3 +148,34 @@ func TestThrottling(t *testing.T) {
 		"AfterOperation(ListBlobs)",
 	}, m.activity)
 }
+
+func TestThrottlingWithContextThrottler(t *testing.T) {
+	ctx := testlogging.Context(t)
+	m := &mockThrottler{}
+	c := &mockThrottler{}
+	st := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	wrapped := throttling.NewWrapper(st, m)
+
+	// operations using the context are throttled by both throttlers.
+	require.NoError(t, wrapped.PutBlob(throttling.WithThrottler(ctx, c), "blob1", gather.FromSlice([]byte{1, 2, 3}), blob.PutOptions{}))
+
+	want := []string{
+		"BeforeOperation(PutBlob)",
+		"BeforeUpload(3)",
+		"AfterOperation(PutBlob)",
+	}
+
+	require.Equal(t, want, m.activity)
+	require.Equal(t, want, c.activity)
+
+	// other operations are not affected.
+	m.Reset()
+	c.Reset()
+
+	require.NoError(t, wrapped.DeleteBlob(ctx, "blob1"))
+	require.Equal(t, []string{
+		"BeforeOperation(DeleteBlob)",
+		"AfterOperation(DeleteBlob)",
+	}, m.activity)
+	require.Empty(t, c.activity)
+}
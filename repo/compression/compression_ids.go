@@ -28,4 +28,8 @@
 	headerDeflateDefault         HeaderID = 0x1500
 	headerDeflateBestSpeed       HeaderID = 0x1501
 	headerDeflateBestCompression HeaderID = 0x1502
+
+	// HeaderZstdDictionary is used by zstd compression against a trained dictionary, which is not
+	// registered by name since it requires the dictionary to be provided by the caller.
+	HeaderZstdDictionary HeaderID = 0x1600
 )
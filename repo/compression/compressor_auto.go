@@ -0,0 +1,113 @@
+package compression
+
+import (
+	"bytes"
+	"math"
+)
+
+// AutoName is the name of a special compression mode, which selects the compressor for each object
+// based on estimated compressibility of its data.
+const AutoName Name = "auto"
+
+// Compressors selected by automatic compression.
+const (
+	AutoFastCompressor Name = "s2-default"
+	AutoBestCompressor Name = "zstd"
+)
+
+const (
+	// autoProbeWindowCount is the number of windows sampled from the probed data.
+	autoProbeWindowCount = 4
+
+	// autoProbeWindowSize is the size of each sampled window.
+	autoProbeWindowSize = 16 << 10
+
+	// data with entropy (in bits per byte) lower than this is considered highly compressible.
+	autoHighlyCompressibleEntropy = 6.0
+
+	// data with entropy (in bits per byte) above this may be incompressible, which is verified by test compression.
+	autoPossiblyIncompressibleEntropy = 7.5
+
+	// minimum reduction of the sample size by the fast compressor for the data to be considered compressible.
+	autoMinCompressionRatio = 0.95
+)
+
+// AutoProbeSample returns the sample of data used by SelectAutoCompressor, consisting of several
+// evenly-spaced windows read using the provided function.
+func AutoProbeSample(length int, readAt func(p []byte, off int64) (int, error)) []byte {
+	if length <= autoProbeWindowCount*autoProbeWindowSize {
+		buf := make([]byte, length)
+		n, _ := readAt(buf, 0)
+
+		return buf[0:n]
+	}
+
+	var result []byte
+
+	stride := length / autoProbeWindowCount
+	buf := make([]byte, autoProbeWindowSize)
+
+	for i := 0; i < autoProbeWindowCount; i++ {
+		n, _ := readAt(buf, int64(i*stride))
+		result = append(result, buf[0:n]...)
+	}
+
+	return result
+}
+
+// SelectAutoCompressor estimates compressibility of the provided data sample and returns the name of the
+// compressor to use: empty name for incompressible data, AutoFastCompressor for moderately compressible data
+// and AutoBestCompressor for highly compressible data.
+func SelectAutoCompressor(sample []byte) Name {
+	if len(sample) == 0 {
+		return ""
+	}
+
+	e := entropy(sample)
+
+	switch {
+	case e < autoHighlyCompressibleEntropy:
+		return AutoBestCompressor
+
+	case e < autoPossiblyIncompressibleEntropy:
+		return AutoFastCompressor
+	}
+
+	// byte histogram does not capture repetitions of high-entropy sequences, so do a quick trial
+	// compression of the sample to see if it's worth compressing.
+	var buf bytes.Buffer
+
+	if err := ByName[AutoFastCompressor].Compress(&buf, bytes.NewReader(sample)); err != nil {
+		return ""
+	}
+
+	if float64(buf.Len()-compressionHeaderSize) < autoMinCompressionRatio*float64(len(sample)) {
+		return AutoFastCompressor
+	}
+
+	return ""
+}
+
+// entropy returns Shannon entropy of the provided data in bits per byte.
+func entropy(data []byte) float64 {
+	var histogram [256]int
+
+	for _, b := range data {
+		histogram[b]++
+	}
+
+	var result float64
+
+	total := float64(len(data))
+
+	for _, cnt := range histogram {
+		if cnt == 0 {
+			continue
+		}
+
+		p := float64(cnt) / total
+		result -= p * math.Log2(p)
+	}
+
+	return result
+}
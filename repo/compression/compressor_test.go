@@ -4,7 +4,9 @@
 	"bytes"
 	"crypto/rand"
 	"fmt"
+	mathrand "math/rand"
 	"sort"
+	"sync"
 	"testing"
 
 	"github.com/kopia/kopia/internal/testutil"
@@ -164,3 +166,273 @@ func decompressionBenchmark(b *testing.B, comp Compressor, input []byte, output
 		}
 	}
 }
+
+func TestSelectAutoCompressor(t *testing.T) {
+	random := make([]byte, 100000)
+	rand.Read(random)
+
+	// random bytes restricted to 100 distinct values have entropy of ~6.6 bits per byte
+	text := make([]byte, 100000)
+	for i := range text {
+		text[i] = 32 + random[i]%100
+	}
+
+	// random block repeated many times has high entropy but is trivially compressible
+	repeated := bytes.Repeat(random[0:4096], 25)
+
+	cases := []struct {
+		desc   string
+		sample []byte
+		want   Name
+	}{
+		{"empty", nil, ""},
+		{"zeros", make([]byte, 100000), AutoBestCompressor},
+		{"text-like", text, AutoFastCompressor},
+		{"random", random, ""},
+		{"repeated-random", repeated, AutoFastCompressor},
+	}
+
+	for _, tc := range cases {
+		if got := SelectAutoCompressor(tc.sample); got != tc.want {
+			t.Errorf("invalid compressor selected for %v: %q, want %q", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestAutoProbeSample(t *testing.T) {
+	data := make([]byte, 1<<20)
+	for i := range data {
+		data[i] = byte(i / 1000)
+	}
+
+	if got, want := len(AutoProbeSample(len(data), bytes.NewReader(data).ReadAt)), autoProbeWindowCount*autoProbeWindowSize; got != want {
+		t.Errorf("invalid sample size %v, want %v", got, want)
+	}
+
+	if got, want := AutoProbeSample(100, bytes.NewReader(data[0:100]).ReadAt), data[0:100]; !bytes.Equal(got, want) {
+		t.Errorf("invalid sample of short data")
+	}
+}
+
+func TestDictionaryCompressor(t *testing.T) {
+	samples := make([][]byte, 50)
+	for i := range samples {
+		samples[i] = []byte(fmt.Sprintf(`{"id":%d,"kind":"document","owner":"user-%d","tags":["backup","snapshot","policy"],"enabled":true}`, i, i%7))
+	}
+
+	dict := TrainDictionary(samples, 4096)
+	if len(dict) == 0 || len(dict) > 4096 {
+		t.Fatalf("invalid dictionary size: %v", len(dict))
+	}
+
+	c, err := NewDictionaryCompressor([]byte{1, 2, 3}, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(`{"id":1234,"kind":"document","owner":"user-3","tags":["backup","snapshot","policy"],"enabled":false}`)
+
+	var withDict, withoutDict, decompressed bytes.Buffer
+
+	if err := c.Compress(&withDict, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ByName["zstd"].Compress(&withoutDict, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	if withDict.Len() >= withoutDict.Len() {
+		t.Errorf("dictionary did not improve compression: %v, without dictionary %v", withDict.Len(), withoutDict.Len())
+	}
+
+	ref, err := DictionaryReference(withDict.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(ref, []byte{1, 2, 3}) {
+		t.Errorf("invalid dictionary reference: %x", ref)
+	}
+
+	if err := c.Decompress(&decompressed, bytes.NewReader(withDict.Bytes()), true); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(decompressed.Bytes(), data) {
+		t.Errorf("invalid decompressed data")
+	}
+
+	other, err := NewDictionaryCompressor([]byte{4, 5, 6}, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := other.Decompress(&decompressed, bytes.NewReader(withDict.Bytes()), true); err == nil {
+		t.Errorf("expected error when decompressing with a different dictionary")
+	}
+}
+
+func TestTrainDictionary_NothingInCommon(t *testing.T) {
+	samples := make([][]byte, 10)
+	for i := range samples {
+		samples[i] = make([]byte, 1000)
+		rand.Read(samples[i])
+	}
+
+	if dict := TrainDictionary(samples, 4096); dict != nil {
+		t.Errorf("unexpected dictionary trained from random samples: %v bytes", len(dict))
+	}
+}
+
+// dictionaryTestSamples returns small files of several kinds which have structure in common,
+// but differ in details, as is typical for files for which dictionary compression is used.
+func dictionaryTestSamples(rnd *mathrand.Rand, n int) [][]byte {
+	words := []string{"alpha", "beta", "gamma", "delta", "epsilon", "zeta", "theta", "kappa", "lambda", "sigma"}
+	word := func() string { return words[rnd.Intn(len(words))] }
+
+	var samples [][]byte
+
+	for i := 0; i < n; i++ {
+		var b bytes.Buffer
+
+		switch i % 3 {
+		case 0:
+			fmt.Fprintf(&b, "{\n  \"apiVersion\": \"v1\",\n  \"kind\": \"ConfigMap\",\n  \"metadata\": {\n    \"name\": \"%v-%v\",\n    \"namespace\": \"%v\",\n    \"labels\": {\"app\": \"%v\", \"tier\": \"backend\"}\n  },\n  \"data\": {\n", word(), rnd.Intn(1000), word(), word())
+
+			for j := 0; j < 3+rnd.Intn(5); j++ {
+				fmt.Fprintf(&b, "    \"%v.%v\": \"%v\",\n", word(), word(), rnd.Intn(100000))
+			}
+
+			b.WriteString("  }\n}\n")
+
+		case 1:
+			fmt.Fprintf(&b, "package %v\n\nimport (\n\t\"context\"\n\t\"fmt\"\n\n\t\"github.com/pkg/errors\"\n)\n\n", word())
+
+			for j := 0; j < 2+rnd.Intn(3); j++ {
+				fmt.Fprintf(&b, "// %v%v returns the %v of the provided %v.\nfunc %v%v(ctx context.Context, %v string) (int, error) {\n\tif %v == \"\" {\n\t\treturn 0, errors.New(\"%v is required\")\n\t}\n\n\treturn fmt.Println(%v)\n}\n\n", word(), j, word(), word(), word(), j, word(), word(), word(), word())
+			}
+
+		default:
+			for j := 0; j < 5+rnd.Intn(10); j++ {
+				fmt.Fprintf(&b, "2023-07-%02d 12:%02d:%02d INFO  [%v] request completed method=GET path=/api/v1/%v/%v status=200 duration=%vms\n", 1+rnd.Intn(28), rnd.Intn(60), rnd.Intn(60), word(), word(), rnd.Intn(10000), rnd.Intn(500))
+			}
+		}
+
+		samples = append(samples, b.Bytes())
+	}
+
+	return samples
+}
+
+func totalCompressedSize(t *testing.T, c Compressor, files [][]byte) int {
+	t.Helper()
+
+	total := 0
+
+	for _, f := range files {
+		var buf bytes.Buffer
+
+		if err := c.Compress(&buf, bytes.NewReader(f)); err != nil {
+			t.Fatal(err)
+		}
+
+		total += buf.Len()
+	}
+
+	return total
+}
+
+func TestTrainDictionary_ImprovesCompressionRatio(t *testing.T) {
+	rnd := mathrand.New(mathrand.NewSource(1))
+
+	training := dictionaryTestSamples(rnd, 300)
+	files := dictionaryTestSamples(rnd, 150)
+
+	// samples are usually collected while walking directories, so similar files are next to each other.
+	sort.SliceStable(training, func(i, j int) bool { return training[i][0] < training[j][0] })
+
+	uncompressed := 0
+	for _, f := range files {
+		uncompressed += len(f)
+	}
+
+	dict := TrainDictionary(training, 16384)
+
+	trained, err := NewDictionaryCompressor([]byte{1}, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// dictionary of the same size consisting of training samples concatenated together.
+	var naiveDict []byte
+	for _, s := range training {
+		naiveDict = append(naiveDict, s...)
+	}
+
+	naive, err := NewDictionaryCompressor([]byte{2}, naiveDict[len(naiveDict)-len(dict):])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withoutDict := totalCompressedSize(t, ByName["zstd"], files)
+	withNaiveDict := totalCompressedSize(t, naive, files)
+	withTrainedDict := totalCompressedSize(t, trained, files)
+
+	t.Logf("uncompressed %v, zstd %v, naive dictionary %v, trained dictionary %v (%v bytes)", uncompressed, withoutDict, withNaiveDict, withTrainedDict, len(dict))
+
+	// files not used for training compress at least 30% better than without dictionary.
+	if withTrainedDict*10 > withoutDict*7 {
+		t.Errorf("trained dictionary did not improve compression enough: %v, without dictionary %v", withTrainedDict, withoutDict)
+	}
+
+	// trained dictionary covers all kinds of samples, not just the most recent ones.
+	if withTrainedDict >= withNaiveDict {
+		t.Errorf("trained dictionary is not better than naive one: %v, naive dictionary %v", withTrainedDict, withNaiveDict)
+	}
+}
+
+func TestDictionaryCompressor_Concurrent(t *testing.T) {
+	rnd := mathrand.New(mathrand.NewSource(1))
+	samples := dictionaryTestSamples(rnd, 100)
+
+	c, err := NewDictionaryCompressor([]byte{1}, TrainDictionary(samples, 4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, len(samples))
+
+	for _, s := range samples {
+		wg.Add(1)
+
+		go func(s []byte) {
+			defer wg.Done()
+
+			var compressed, decompressed bytes.Buffer
+
+			if err := c.Compress(&compressed, bytes.NewReader(s)); err != nil {
+				errs <- err
+				return
+			}
+
+			if err := c.Decompress(&decompressed, bytes.NewReader(compressed.Bytes()), true); err != nil {
+				errs <- err
+				return
+			}
+
+			if !bytes.Equal(decompressed.Bytes(), s) {
+				errs <- fmt.Errorf("invalid decompressed data")
+			}
+		}(s)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
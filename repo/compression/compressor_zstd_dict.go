@@ -0,0 +1,185 @@
+package compression
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/iocopy"
+)
+
+// DictionaryName is the name under which data compressed against a dictionary is reported.
+const DictionaryName Name = "zstd-dictionary"
+
+func init() {
+	// dictionary compressor is not registered since it can't be used without a dictionary,
+	// but its name is needed to report compressed contents.
+	HeaderIDToName[HeaderZstdDictionary] = DictionaryName
+}
+
+// maxDictionaryReferenceLength is the maximum length of dictionary reference stored in the compressed data.
+const maxDictionaryReferenceLength = 255
+
+// dictionaryCompressor implements zstd compression against a raw content dictionary.
+//
+// Compressed data consists of the compression header, followed by the length-prefixed
+// dictionary reference and zstd frame. The reference is opaque to this package and is used
+// by the caller to locate the dictionary needed for decompression.
+type dictionaryCompressor struct {
+	reference   []byte
+	encoderPool sync.Pool
+	decoderPool sync.Pool
+}
+
+// NewDictionaryCompressor returns a compressor which compresses data using zstd against
+// the provided dictionary and stores the provided dictionary reference in the compressed data.
+func NewDictionaryCompressor(reference, dictionary []byte) (Compressor, error) {
+	if len(reference) == 0 || len(reference) > maxDictionaryReferenceLength {
+		return nil, errors.Errorf("invalid dictionary reference length: %v", len(reference))
+	}
+
+	// zstd dictionary ID must be non-zero, derive it from the reference.
+	dictID := crc32.ChecksumIEEE(reference) | 1
+
+	newEncoder := func() (*zstd.Encoder, error) {
+		//nolint:wrapcheck
+		return zstd.NewWriter(nil, zstd.WithEncoderDictRaw(dictID, dictionary), zstd.WithEncoderConcurrency(1), zstd.WithEncoderCRC(false))
+	}
+
+	newDecoder := func() (*zstd.Decoder, error) {
+		//nolint:wrapcheck
+		return zstd.NewReader(nil, zstd.WithDecoderDictRaw(dictID, dictionary), zstd.WithDecoderConcurrency(1))
+	}
+
+	// create the first encoder and decoder to validate the dictionary.
+	enc, err := newEncoder()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create dictionary encoder")
+	}
+
+	dec, err := newDecoder()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create dictionary decoder")
+	}
+
+	c := &dictionaryCompressor{
+		reference: append([]byte(nil), reference...),
+	}
+
+	c.encoderPool.New = func() interface{} {
+		e, err := newEncoder()
+		mustSucceed(err)
+
+		return e
+	}
+
+	c.decoderPool.New = func() interface{} {
+		d, err := newDecoder()
+		mustSucceed(err)
+
+		return d
+	}
+
+	c.encoderPool.Put(enc)
+	c.decoderPool.Put(dec)
+
+	return c, nil
+}
+
+func (c *dictionaryCompressor) HeaderID() HeaderID {
+	return HeaderZstdDictionary
+}
+
+func (c *dictionaryCompressor) Compress(output io.Writer, input io.Reader) error {
+	var buf bytes.Buffer
+
+	if err := iocopy.JustCopy(&buf, input); err != nil {
+		return errors.Wrap(err, "unable to read input")
+	}
+
+	result := compressionHeader(HeaderZstdDictionary)
+	result = append(result, byte(len(c.reference)))
+	result = append(result, c.reference...)
+
+	//nolint:forcetypeassert
+	enc := c.encoderPool.Get().(*zstd.Encoder)
+	defer c.encoderPool.Put(enc)
+
+	result = enc.EncodeAll(buf.Bytes(), result)
+
+	if _, err := output.Write(result); err != nil {
+		return errors.Wrap(err, "unable to write compressed data")
+	}
+
+	return nil
+}
+
+func (c *dictionaryCompressor) Decompress(output io.Writer, input io.Reader, withHeader bool) error {
+	if withHeader {
+		if err := verifyCompressionHeader(input, compressionHeader(HeaderZstdDictionary)); err != nil {
+			return err
+		}
+	}
+
+	ref, err := readDictionaryReference(input)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(ref, c.reference) {
+		return errors.Errorf("data was compressed using a different dictionary")
+	}
+
+	var buf bytes.Buffer
+
+	if err := iocopy.JustCopy(&buf, input); err != nil {
+		return errors.Wrap(err, "unable to read input")
+	}
+
+	//nolint:forcetypeassert
+	dec := c.decoderPool.Get().(*zstd.Decoder)
+	defer c.decoderPool.Put(dec)
+
+	result, err := dec.DecodeAll(buf.Bytes(), nil)
+	if err != nil {
+		return errors.Wrap(err, "decompression error")
+	}
+
+	if _, err := output.Write(result); err != nil {
+		return errors.Wrap(err, "unable to write decompressed data")
+	}
+
+	return nil
+}
+
+// DictionaryReference returns the reference to the dictionary needed to decompress the provided
+// data compressed by a compressor returned by NewDictionaryCompressor.
+func DictionaryReference(compressed []byte) ([]byte, error) {
+	r := bytes.NewReader(compressed)
+
+	if err := verifyCompressionHeader(r, compressionHeader(HeaderZstdDictionary)); err != nil {
+		return nil, err
+	}
+
+	return readDictionaryReference(r)
+}
+
+func readDictionaryReference(r io.Reader) ([]byte, error) {
+	var l [1]byte
+
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, errors.Wrap(err, "error reading dictionary reference length")
+	}
+
+	ref := make([]byte, l[0])
+
+	if _, err := io.ReadFull(r, ref); err != nil {
+		return nil, errors.Wrap(err, "error reading dictionary reference")
+	}
+
+	return ref, nil
+}
@@ -0,0 +1,149 @@
+package compression
+
+import (
+	"container/heap"
+	"encoding/binary"
+)
+
+const (
+	// dictionaryKmerSize is the length of byte sequences whose frequencies are counted across samples.
+	dictionaryKmerSize = 8
+
+	// dictionarySegmentSize is the length of segments copied from samples into the dictionary.
+	dictionarySegmentSize = 64
+)
+
+// TrainDictionary builds a raw zstd dictionary of up to maxSize bytes from the provided samples.
+//
+// The dictionary consists of sample segments covering byte sequences that occur in the largest number
+// of different samples, with most valuable segments placed at the end, where they are cheapest to reference.
+// Returns nil if samples do not have enough in common for the dictionary to be useful.
+func TrainDictionary(samples [][]byte, maxSize int) []byte {
+	freq := map[uint64]int{}
+
+	for _, s := range samples {
+		seen := map[uint64]bool{}
+
+		for i := 0; i+dictionaryKmerSize <= len(s); i++ {
+			k := binary.LittleEndian.Uint64(s[i:])
+			if !seen[k] {
+				seen[k] = true
+				freq[k]++
+			}
+		}
+	}
+
+	var candidates dictionarySegmentHeap
+
+	for _, s := range samples {
+		for off := 0; off < len(s); off += dictionarySegmentSize / 2 { //nolint:gomnd
+			end := off + dictionarySegmentSize
+			if end > len(s) {
+				end = len(s)
+			}
+
+			seg := s[off:end]
+
+			if score := segmentScore(seg, freq); score > 0 {
+				candidates = append(candidates, dictionarySegment{seg, score})
+			}
+
+			if end == len(s) {
+				break
+			}
+		}
+	}
+
+	heap.Init(&candidates)
+
+	var selected [][]byte
+
+	total := 0
+
+	for candidates.Len() > 0 && total < maxSize {
+		//nolint:forcetypeassert
+		top := heap.Pop(&candidates).(dictionarySegment)
+
+		// scores only decrease as segments are selected, so re-evaluate lazily.
+		if score := segmentScore(top.data, freq); score < top.score {
+			if score > 0 {
+				heap.Push(&candidates, dictionarySegment{top.data, score})
+			}
+
+			continue
+		}
+
+		seg := top.data
+		if total+len(seg) > maxSize {
+			seg = seg[0 : maxSize-total]
+		}
+
+		selected = append(selected, seg)
+		total += len(seg)
+
+		// byte sequences present in the dictionary no longer contribute to scores of other segments.
+		for i := 0; i+dictionaryKmerSize <= len(seg); i++ {
+			delete(freq, binary.LittleEndian.Uint64(seg[i:]))
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil
+	}
+
+	result := make([]byte, 0, total)
+
+	for i := len(selected) - 1; i >= 0; i-- {
+		result = append(result, selected[i]...)
+	}
+
+	return result
+}
+
+// segmentScore returns the number of additional samples that could benefit from each distinct byte
+// sequence in the segment.
+func segmentScore(seg []byte, freq map[uint64]int) int {
+	seen := map[uint64]bool{}
+	score := 0
+
+	for i := 0; i+dictionaryKmerSize <= len(seg); i++ {
+		k := binary.LittleEndian.Uint64(seg[i:])
+		if seen[k] {
+			continue
+		}
+
+		seen[k] = true
+
+		if f := freq[k]; f > 1 {
+			score += f - 1
+		}
+	}
+
+	return score
+}
+
+type dictionarySegment struct {
+	data  []byte
+	score int
+}
+
+// dictionarySegmentHeap implements heap.Interface, ordering segments by decreasing score.
+type dictionarySegmentHeap []dictionarySegment
+
+func (h dictionarySegmentHeap) Len() int           { return len(h) }
+func (h dictionarySegmentHeap) Less(i, j int) bool { return h[i].score > h[j].score }
+func (h dictionarySegmentHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *dictionarySegmentHeap) Push(x interface{}) {
+	//nolint:forcetypeassert
+	*h = append(*h, x.(dictionarySegment))
+}
+
+func (h *dictionarySegmentHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+
+	return x
+}
@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/feature"
+)
+
+// FeatureCompressionDictionaries is required by repositories which may contain contents compressed
+// using compression dictionaries, which older clients are unable to decompress.
+const FeatureCompressionDictionaries feature.Feature = "compression-dictionaries"
+
+// EnableCompressionDictionaries records FeatureCompressionDictionaries as required to open the repository,
+// which must happen before the first compression dictionary is written.
+func EnableCompressionDictionaries(ctx context.Context, rep DirectRepositoryWriter) error {
+	fm := rep.FormatManager()
+
+	required, err := fm.RequiredFeatures()
+	if err != nil {
+		return errors.Wrap(err, "unable to get required features")
+	}
+
+	for _, r := range required {
+		if r.Feature == FeatureCompressionDictionaries {
+			return nil
+		}
+	}
+
+	mp, err := fm.GetMutableParameters()
+	if err != nil {
+		return errors.Wrap(err, "mutable parameters")
+	}
+
+	blobcfg, err := fm.BlobCfgBlob()
+	if err != nil {
+		return errors.Wrap(err, "blob configuration")
+	}
+
+	required = append(required, feature.Required{
+		Feature: FeatureCompressionDictionaries,
+		IfNotUnderstood: feature.IfNotUnderstood{
+			Message: "The repository contains contents compressed using compression dictionaries, which are not supported by this version of Kopia.",
+		},
+	})
+
+	return errors.Wrap(fm.SetParameters(ctx, mp, blobcfg, required), "unable to set required features")
+}
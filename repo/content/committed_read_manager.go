@@ -25,6 +25,7 @@
 	"github.com/kopia/kopia/repo/blob/sharded"
 	"github.com/kopia/kopia/repo/compression"
 	"github.com/kopia/kopia/repo/content/indexblob"
+	"github.com/kopia/kopia/repo/ecc"
 	"github.com/kopia/kopia/repo/format"
 	"github.com/kopia/kopia/repo/hashing"
 	"github.com/kopia/kopia/repo/logging"
@@ -109,6 +110,10 @@ type SharedManager struct {
 	repoLogManager *repolog.LogManager
 	internalLogger *zap.SugaredLogger // backing logger for 'sharedBaseLogger'
 
+	dictionaryCompressorsMutex sync.Mutex
+	// +checklocks:dictionaryCompressorsMutex
+	dictionaryCompressors map[ID]compression.Compressor // compressors for dictionaries loaded so far
+
 	metricsStruct
 }
 
@@ -276,7 +281,7 @@ func (sm *SharedManager) indexBlobManager() (indexblob.Manager, error) {
 	return q, nil
 }
 
-func (sm *SharedManager) decryptContentAndVerify(payload gather.Bytes, bi Info, output *gather.WriteBuffer) error {
+func (sm *SharedManager) decryptContentAndVerify(ctx context.Context, payload gather.Bytes, bi Info, output *gather.WriteBuffer) error {
 	sm.Stats.readContent(payload.Length())
 
 	var hashBuf [hashing.MaxHashSize]byte
@@ -302,6 +307,10 @@ func (sm *SharedManager) decryptContentAndVerify(payload gather.Bytes, bi Info,
 		return errors.Wrapf(err, "invalid checksum at %v offset %v length %v/%v", bi.GetPackBlobID(), bi.GetPackOffset(), bi.GetPackedLength(), payload.Length())
 	}
 
+	if h == compression.HeaderZstdDictionary {
+		return errors.Wrapf(sm.decompressWithDictionary(ctx, tmp.ToByteSlice(), output), "error decompressing %v", bi.GetContentID())
+	}
+
 	c := compression.ByHeaderID[h]
 	if c == nil {
 		return errors.Errorf("unsupported compressor %x", h)
@@ -318,6 +327,19 @@ func (sm *SharedManager) decryptContentAndVerify(payload gather.Bytes, bi Info,
 	return nil
 }
 
+// VerifyPackedContent verifies the payload of the content read directly from its pack blob
+// and returns the number of damaged shards that had to be corrected using error correction.
+func (sm *SharedManager) VerifyPackedContent(ctx context.Context, payload gather.Bytes, bi Info) (damagedShards int, err error) {
+	if d, ok := sm.format.Encryptor().(ecc.DamageDetector); ok {
+		damagedShards = d.DamagedShards(payload)
+	}
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	return damagedShards, sm.decryptContentAndVerify(ctx, payload, bi, &tmp)
+}
+
 func (sm *SharedManager) decryptAndVerify(encrypted gather.Bytes, iv []byte, output *gather.WriteBuffer) error {
 	t0 := timetrack.StartTimer()
 
@@ -88,6 +88,8 @@ type WriteManager struct {
 	failedPacks []*pendingPackInfo // list of packs that failed to write, will be retried
 	// +checklocks:mu
 	packIndexBuilder index.Builder // contents that are in index currently being built (all packs saved but not committed)
+	// +checklocks:mu
+	pendingDictionaryReferences map[ID][]ID // contents written using each compression dictionary, not yet recorded
 
 	// +checklocks:mu
 	disableIndexFlushCount int
@@ -254,7 +256,7 @@ func (bm *WriteManager) maybeRetryWritingFailedPacksUnlocked(ctx context.Context
 	return nil
 }
 
-func (bm *WriteManager) addToPackUnlocked(ctx context.Context, contentID ID, data gather.Bytes, isDeleted bool, comp compression.HeaderID, previousWriteTime int64, mp format.MutableParameters) error {
+func (bm *WriteManager) addToPackUnlocked(ctx context.Context, contentID ID, data gather.Bytes, isDeleted bool, comp compression.HeaderID, dict compression.Compressor, previousWriteTime int64, mp format.MutableParameters) error {
 	// see if the current index is old enough to cause automatic flush.
 	if err := bm.maybeFlushBasedOnTimeUnlocked(ctx); err != nil {
 		return errors.Wrap(err, "unable to flush old pending writes")
@@ -266,7 +268,7 @@ func (bm *WriteManager) addToPackUnlocked(ctx context.Context, contentID ID, dat
 	defer compressedAndEncrypted.Close()
 
 	// encrypt and compress before taking lock
-	actualComp, err := bm.maybeCompressAndEncryptDataForPacking(data, contentID, comp, &compressedAndEncrypted, mp)
+	actualComp, err := bm.maybeCompressAndEncryptDataForPacking(data, contentID, comp, dict, &compressedAndEncrypted, mp)
 	if err != nil {
 		return errors.Wrapf(err, "unable to encrypt %q", contentID)
 	}
@@ -467,6 +469,11 @@ func (bm *WriteManager) flushPackIndexesLocked(ctx context.Context, mp format.Mu
 		bm.indexesLock.RLock()
 		defer bm.indexesLock.RUnlock()
 
+		// dictionary references must be recorded before the contents become visible to garbage collection.
+		if err := bm.writeDictionaryReferencesLocked(ctx); err != nil {
+			return errors.Wrap(err, "error writing compression dictionary references")
+		}
+
 		indexBlobMDs, err := bm.writeIndexBlobs(ctx, dataShards, bm.currentSessionInfo.ID)
 		if err != nil {
 			return errors.Wrap(err, "error writing index blob")
@@ -704,6 +711,20 @@ func (bm *WriteManager) rewriteContent(ctx context.Context, contentID ID, onlyRe
 		return errors.Wrap(err, "unable to get content data and info")
 	}
 
+	var dict compression.Compressor
+
+	if bi.GetCompressionHeaderID() == compression.HeaderZstdDictionary {
+		// preserve compression using the same dictionary.
+		dictionaryID, err := bm.ContentCompressionDictionary(ctx, contentID)
+		if err != nil {
+			return errors.Wrap(err, "unable to get compression dictionary")
+		}
+
+		if dict, err = bm.dictionaryCompressor(ctx, dictionaryID); err != nil {
+			return err
+		}
+	}
+
 	isDeleted := bi.GetDeleted()
 
 	if onlyRewriteDeleted {
@@ -714,7 +735,7 @@ func (bm *WriteManager) rewriteContent(ctx context.Context, contentID ID, onlyRe
 		isDeleted = false
 	}
 
-	return bm.addToPackUnlocked(ctx, contentID, data.Bytes(), isDeleted, bi.GetCompressionHeaderID(), bi.GetTimestampSeconds(), mp)
+	return bm.addToPackUnlocked(ctx, contentID, data.Bytes(), isDeleted, bi.GetCompressionHeaderID(), dict, bi.GetTimestampSeconds(), mp)
 }
 
 func packPrefixForContentID(contentID ID) blob.ID {
@@ -780,6 +801,14 @@ func (bm *WriteManager) SupportsContentCompression() (bool, error) {
 // WriteContent saves a given content of data to a pack group with a provided name and returns a contentID
 // that's based on the contents of data written.
 func (bm *WriteManager) WriteContent(ctx context.Context, data gather.Bytes, prefix index.IDPrefix, comp compression.HeaderID) (ID, error) {
+	if err := prefix.ValidateSingle(); err != nil {
+		return EmptyID, errors.Wrap(err, "invalid prefix")
+	}
+
+	return bm.writeContent(ctx, data, prefix, comp, EmptyID)
+}
+
+func (bm *WriteManager) writeContent(ctx context.Context, data gather.Bytes, prefix index.IDPrefix, comp compression.HeaderID, dictionaryID ID) (ID, error) {
 	t0 := timetrack.StartTimer()
 	defer func() {
 		bm.writeContentBytes.Observe(int64(data.Length()), t0.Elapsed())
@@ -794,10 +823,6 @@ func (bm *WriteManager) WriteContent(ctx context.Context, data gather.Bytes, pre
 		return EmptyID, err
 	}
 
-	if err := prefix.ValidateSingle(); err != nil {
-		return EmptyID, errors.Wrap(err, "invalid prefix")
-	}
-
 	var hashOutput [hashing.MaxHashSize]byte
 
 	contentID, err := IDFromHash(prefix, bm.hashData(hashOutput[:0], data))
@@ -834,7 +859,22 @@ func (bm *WriteManager) WriteContent(ctx context.Context, data gather.Bytes, pre
 
 	bm.log.Debugf(logbuf.String())
 
-	return contentID, bm.addToPackUnlocked(ctx, contentID, data, false, comp, previousWriteTime, mp)
+	if dictionaryID == EmptyID {
+		return contentID, bm.addToPackUnlocked(ctx, contentID, data, false, comp, nil, previousWriteTime, mp)
+	}
+
+	dict, err := bm.dictionaryCompressor(ctx, dictionaryID)
+	if err != nil {
+		return EmptyID, err
+	}
+
+	if err := bm.addToPackUnlocked(ctx, contentID, data, false, comp, dict, previousWriteTime, mp); err != nil {
+		return contentID, err
+	}
+
+	bm.addDictionaryReference(dictionaryID, contentID)
+
+	return contentID, nil
 }
 
 // GetContent gets the contents of a given content. If the content is not found returns ErrContentNotFound.
@@ -0,0 +1,361 @@
+package content
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/blobcrypto"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/timetrack"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/compression"
+	"github.com/kopia/kopia/repo/content/index"
+	"github.com/kopia/kopia/repo/hashing"
+)
+
+// CompressionDictionaryPrefix is the reserved prefix of contents holding compression dictionaries,
+// which can't be used with WriteContent().
+const CompressionDictionaryPrefix = index.CompressionDictionaryPrefix
+
+// BlobIDPrefixDictionaryReferences is the prefix of blobs recording which contents were compressed
+// using each compression dictionary.
+const BlobIDPrefixDictionaryReferences blob.ID = "kopia.dictionaryrefs."
+
+// WriteCompressionDictionary saves the provided compression dictionary and returns the ID of the content
+// that can be subsequently passed to WriteContentWithDictionary().
+func (bm *WriteManager) WriteCompressionDictionary(ctx context.Context, dict gather.Bytes) (ID, error) {
+	contentID, err := bm.writeContent(ctx, dict, CompressionDictionaryPrefix, NoCompression, EmptyID)
+	if err != nil {
+		return EmptyID, err
+	}
+
+	c, err := compression.NewDictionaryCompressor(contentID.Hash(), dict.ToByteSlice())
+	if err != nil {
+		return EmptyID, errors.Wrap(err, "invalid compression dictionary")
+	}
+
+	// the dictionary may not be committed yet, make sure contents compressed using it can be read
+	// before that happens.
+	bm.addDictionaryCompressor(contentID, c)
+
+	return contentID, nil
+}
+
+// WriteContentWithDictionary saves a given content compressed using zstd against the compression dictionary
+// stored in the provided content and returns a contentID that's based on the contents of data written.
+//
+// The reference from the content to the dictionary is recorded in a dictionary references blob when
+// the index is flushed, so that garbage collection can find dictionaries in use without reading contents.
+func (bm *WriteManager) WriteContentWithDictionary(ctx context.Context, data gather.Bytes, prefix IDPrefix, dictionaryID ID) (ID, error) {
+	if err := prefix.ValidateSingle(); err != nil {
+		return EmptyID, errors.Wrap(err, "invalid prefix")
+	}
+
+	// fail on invalid dictionaries even if the content turns out to be a duplicate.
+	if _, err := bm.dictionaryCompressor(ctx, dictionaryID); err != nil {
+		return EmptyID, err
+	}
+
+	return bm.writeContent(ctx, data, prefix, compression.HeaderZstdDictionary, dictionaryID)
+}
+
+func (bm *WriteManager) addDictionaryReference(dictionaryID, contentID ID) {
+	bm.lock()
+	defer bm.unlock()
+
+	if bm.pendingDictionaryReferences == nil {
+		bm.pendingDictionaryReferences = map[ID][]ID{}
+	}
+
+	bm.pendingDictionaryReferences[dictionaryID] = append(bm.pendingDictionaryReferences[dictionaryID], contentID)
+}
+
+// writeDictionaryReferencesLocked records references of contents in packs that have been written,
+// references of contents in pending packs are recorded when their index is flushed.
+//
+// +checklocks:bm.mu
+func (bm *WriteManager) writeDictionaryReferencesLocked(ctx context.Context) error {
+	written := map[ID][]ID{}
+	pending := map[ID][]ID{}
+
+	for dictionaryID, contentIDs := range bm.pendingDictionaryReferences {
+		for _, cid := range contentIDs {
+			if _, ok := bm.packIndexBuilder[cid]; ok {
+				written[dictionaryID] = append(written[dictionaryID], cid)
+			} else {
+				pending[dictionaryID] = append(pending[dictionaryID], cid)
+			}
+		}
+	}
+
+	if len(written) == 0 {
+		return nil
+	}
+
+	if _, err := bm.writeDictionaryReferences(ctx, written); err != nil {
+		return err
+	}
+
+	bm.pendingDictionaryReferences = pending
+
+	return nil
+}
+
+// dictionaryReferences is the payload of dictionary references blobs.
+type dictionaryReferences struct {
+	Dictionaries []dictionaryReferencesEntry `json:"dictionaries"`
+}
+
+type dictionaryReferencesEntry struct {
+	DictionaryID ID   `json:"dictionary"`
+	ContentIDs   []ID `json:"contents"`
+}
+
+func (bm *WriteManager) writeDictionaryReferences(ctx context.Context, refs map[ID][]ID) (blob.ID, error) {
+	var payload dictionaryReferences
+
+	for dictionaryID, contentIDs := range refs {
+		payload.Dictionaries = append(payload.Dictionaries, dictionaryReferencesEntry{dictionaryID, contentIDs})
+	}
+
+	sort.Slice(payload.Dictionaries, func(i, j int) bool {
+		return payload.Dictionaries[i].DictionaryID.String() < payload.Dictionaries[j].DictionaryID.String()
+	})
+
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to serialize dictionary references")
+	}
+
+	var encrypted gather.WriteBuffer
+	defer encrypted.Close()
+
+	blobID, err := blobcrypto.Encrypt(bm.format, gather.FromSlice(js), BlobIDPrefixDictionaryReferences, "", &encrypted)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to encrypt dictionary references")
+	}
+
+	bm.onUpload(int64(encrypted.Length()))
+
+	if err := bm.st.PutBlob(ctx, blobID, encrypted.Bytes(), blob.PutOptions{}); err != nil {
+		return "", errors.Wrapf(err, "unable to write dictionary references: %v", blobID)
+	}
+
+	return blobID, nil
+}
+
+// DictionaryReferences describes contents compressed using compression dictionaries, as recorded
+// in a single blob when the contents were written.
+type DictionaryReferences struct {
+	BlobID    blob.ID
+	Timestamp time.Time
+	Contents  map[ID][]ID // IDs of contents keyed by dictionary ID, which may no longer exist
+}
+
+// CompressionDictionaryReferences returns all recorded references of contents to compression dictionaries.
+func (bm *WriteManager) CompressionDictionaryReferences(ctx context.Context) ([]DictionaryReferences, error) {
+	blobs, err := blob.ListAllBlobs(ctx, bm.st, BlobIDPrefixDictionaryReferences)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list dictionary references")
+	}
+
+	var (
+		result    []DictionaryReferences
+		payload   gather.WriteBuffer
+		decrypted gather.WriteBuffer
+	)
+
+	defer payload.Close()
+	defer decrypted.Close()
+
+	for _, b := range blobs {
+		if err := bm.st.GetBlob(ctx, b.BlobID, 0, -1, &payload); err != nil {
+			if errors.Is(err, blob.ErrBlobNotFound) {
+				continue
+			}
+
+			return nil, errors.Wrapf(err, "error loading dictionary references: %v", b.BlobID)
+		}
+
+		if err := blobcrypto.Decrypt(bm.format, payload.Bytes(), b.BlobID, &decrypted); err != nil {
+			return nil, errors.Wrapf(err, "error decrypting dictionary references: %v", b.BlobID)
+		}
+
+		var dr dictionaryReferences
+
+		if err := json.NewDecoder(decrypted.Bytes().Reader()).Decode(&dr); err != nil {
+			return nil, errors.Wrapf(err, "error parsing dictionary references: %v", b.BlobID)
+		}
+
+		refs := DictionaryReferences{
+			BlobID:    b.BlobID,
+			Timestamp: b.Timestamp,
+			Contents:  map[ID][]ID{},
+		}
+
+		for _, e := range dr.Dictionaries {
+			refs.Contents[e.DictionaryID] = append(refs.Contents[e.DictionaryID], e.ContentIDs...)
+		}
+
+		result = append(result, refs)
+	}
+
+	return result, nil
+}
+
+// ReplaceCompressionDictionaryReferences records the provided dictionary references in a single blob and
+// deletes the provided blobs holding references returned by CompressionDictionaryReferences().
+func (bm *WriteManager) ReplaceCompressionDictionaryReferences(ctx context.Context, refs map[ID][]ID, oldBlobIDs []blob.ID) error {
+	var newBlobID blob.ID
+
+	if len(refs) > 0 {
+		var err error
+
+		if newBlobID, err = bm.writeDictionaryReferences(ctx, refs); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range oldBlobIDs {
+		// identical references produce the same blob ID.
+		if b == newBlobID {
+			continue
+		}
+
+		if err := bm.st.DeleteBlob(ctx, b); err != nil && !errors.Is(err, blob.ErrBlobNotFound) {
+			return errors.Wrapf(err, "unable to delete dictionary references: %v", b)
+		}
+	}
+
+	return nil
+}
+
+// ContentCompressionDictionary returns the ID of the compression dictionary used to compress the provided
+// content or EmptyID if the content is not compressed using a dictionary.
+func (bm *WriteManager) ContentCompressionDictionary(ctx context.Context, contentID ID) (ID, error) {
+	var payload gather.WriteBuffer
+	defer payload.Close()
+
+	bm.mu.RLock()
+	pp, bi, err := bm.getContentInfoReadLocked(ctx, contentID)
+
+	if err == nil && bi.GetCompressionHeaderID() == compression.HeaderZstdDictionary {
+		err = bm.getContentPayloadReadLocked(ctx, pp, bi, &payload)
+	}
+	bm.mu.RUnlock()
+
+	if err != nil {
+		return EmptyID, err
+	}
+
+	if bi.GetCompressionHeaderID() != compression.HeaderZstdDictionary {
+		return EmptyID, nil
+	}
+
+	var (
+		hashBuf    [hashing.MaxHashSize]byte
+		compressed gather.WriteBuffer
+	)
+
+	defer compressed.Close()
+
+	if err := bm.decryptAndVerify(payload.Bytes(), getPackedContentIV(hashBuf[:0], contentID), &compressed); err != nil {
+		return EmptyID, errors.Wrapf(err, "invalid checksum of %v", contentID)
+	}
+
+	return dictionaryIDFromCompressedData(compressed.ToByteSlice())
+}
+
+// decompressWithDictionary decompresses the provided data compressed using a compression dictionary.
+func (sm *SharedManager) decompressWithDictionary(ctx context.Context, compressed []byte, output *gather.WriteBuffer) error {
+	dictionaryID, err := dictionaryIDFromCompressedData(compressed)
+	if err != nil {
+		return err
+	}
+
+	dict, err := sm.dictionaryCompressor(ctx, dictionaryID)
+	if err != nil {
+		return err
+	}
+
+	t0 := timetrack.StartTimer()
+
+	if err := dict.Decompress(output, bytes.NewReader(compressed), true); err != nil {
+		return errors.Wrap(err, "error decompressing")
+	}
+
+	sm.decompressedBytes.Observe(int64(len(compressed)), t0.Elapsed())
+
+	return nil
+}
+
+func dictionaryIDFromCompressedData(compressed []byte) (ID, error) {
+	ref, err := compression.DictionaryReference(compressed)
+	if err != nil {
+		return EmptyID, errors.Wrap(err, "invalid dictionary reference")
+	}
+
+	// dictionaries always have the same prefix, so the hash is sufficient to reference them.
+	dictionaryID, err := IDFromHash(CompressionDictionaryPrefix, ref)
+	if err != nil {
+		return EmptyID, errors.Wrap(err, "invalid dictionary content ID")
+	}
+
+	return dictionaryID, nil
+}
+
+// dictionaryCompressor returns the compressor for the dictionary stored in the given content.
+//
+// Dictionaries are only loaded from committed contents, since this is invoked while reading contents
+// with the write manager lock held. Dictionaries written by this process are added to the cache by
+// WriteCompressionDictionary().
+func (sm *SharedManager) dictionaryCompressor(ctx context.Context, dictionaryID ID) (compression.Compressor, error) {
+	if dictionaryID.Prefix() != CompressionDictionaryPrefix {
+		return nil, errors.Errorf("%v is not a compression dictionary", dictionaryID)
+	}
+
+	sm.dictionaryCompressorsMutex.Lock()
+	c := sm.dictionaryCompressors[dictionaryID]
+	sm.dictionaryCompressorsMutex.Unlock()
+
+	if c != nil {
+		return c, nil
+	}
+
+	bi, err := sm.committedContents.getContent(dictionaryID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to find compression dictionary %v", dictionaryID)
+	}
+
+	var dict gather.WriteBuffer
+	defer dict.Close()
+
+	if err := sm.getContentDataReadLocked(ctx, nil, bi, &dict); err != nil {
+		return nil, errors.Wrapf(err, "unable to load compression dictionary %v", dictionaryID)
+	}
+
+	c, err = compression.NewDictionaryCompressor(dictionaryID.Hash(), dict.ToByteSlice())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid compression dictionary")
+	}
+
+	sm.addDictionaryCompressor(dictionaryID, c)
+
+	return c, nil
+}
+
+func (sm *SharedManager) addDictionaryCompressor(dictionaryID ID, c compression.Compressor) {
+	sm.dictionaryCompressorsMutex.Lock()
+	defer sm.dictionaryCompressorsMutex.Unlock()
+
+	if sm.dictionaryCompressors == nil {
+		sm.dictionaryCompressors = map[ID]compression.Compressor{}
+	}
+
+	sm.dictionaryCompressors[dictionaryID] = c
+}
@@ -24,7 +24,7 @@
 
 const indexBlobCompactionWarningThreshold = 1000
 
-func (sm *SharedManager) maybeCompressAndEncryptDataForPacking(data gather.Bytes, contentID ID, comp compression.HeaderID, output *gather.WriteBuffer, mp format.MutableParameters) (compression.HeaderID, error) {
+func (sm *SharedManager) maybeCompressAndEncryptDataForPacking(data gather.Bytes, contentID ID, comp compression.HeaderID, dict compression.Compressor, output *gather.WriteBuffer, mp format.MutableParameters) (compression.HeaderID, error) {
 	var hashOutput [hashing.MaxHashSize]byte
 
 	iv := getPackedContentIV(hashOutput[:0], contentID)
@@ -47,6 +47,10 @@ func (sm *SharedManager) maybeCompressAndEncryptDataForPacking(data gather.Bytes
 
 		// allocate temporary buffer to hold the compressed bytes.
 		c := compression.ByHeaderID[comp]
+		if comp == compression.HeaderZstdDictionary {
+			c = dict
+		}
+
 		if c == nil {
 			return NoCompression, errors.Errorf("unsupported compressor %x", comp)
 		}
@@ -108,17 +112,28 @@ func (sm *SharedManager) getContentDataReadLocked(ctx context.Context, pp *pendi
 	var payload gather.WriteBuffer
 	defer payload.Close()
 
+	if err := sm.getContentPayloadReadLocked(ctx, pp, bi, &payload); err != nil {
+		return err
+	}
+
+	return sm.decryptContentAndVerify(ctx, payload.Bytes(), bi, output)
+}
+
+// getContentPayloadReadLocked gets the encrypted payload of the content from the pending pack or the pack blob.
+func (sm *SharedManager) getContentPayloadReadLocked(ctx context.Context, pp *pendingPackInfo, bi Info, payload *gather.WriteBuffer) error {
 	if pp != nil && pp.packBlobID == bi.GetPackBlobID() {
 		// we need to use a lock here in case somebody else writes to the pack at the same time.
-		if err := pp.currentPackData.AppendSectionTo(&payload, int(bi.GetPackOffset()), int(bi.GetPackedLength())); err != nil {
+		if err := pp.currentPackData.AppendSectionTo(payload, int(bi.GetPackOffset()), int(bi.GetPackedLength())); err != nil {
 			// should never happen
 			return errors.Wrap(err, "error appending pending content data to buffer")
 		}
-	} else if err := sm.getCacheForContentID(bi.GetContentID()).GetContent(ctx, contentCacheKeyForInfo(bi), bi.GetPackBlobID(), int64(bi.GetPackOffset()), int64(bi.GetPackedLength()), &payload); err != nil {
-		return errors.Wrap(err, "error getting cached content")
+
+		return nil
 	}
 
-	return sm.decryptContentAndVerify(payload.Bytes(), bi, output)
+	return errors.Wrap(
+		sm.getCacheForContentID(bi.GetContentID()).GetContent(ctx, contentCacheKeyForInfo(bi), bi.GetPackBlobID(), int64(bi.GetPackOffset()), int64(bi.GetPackedLength()), payload),
+		"error getting cached content")
 }
 
 func (sm *SharedManager) preparePackDataContent(pp *pendingPackInfo) (index.Builder, error) {
@@ -2107,6 +2107,86 @@ func (s *contentManagerSuite) TestCompression_NonCompressibleData(t *testing.T)
 	verifyContent(ctx, t, bm2, cid, nonCompressibleData)
 }
 
+func (s *contentManagerSuite) TestCompression_Dictionary(t *testing.T) {
+	data := blobtesting.DataMap{}
+	st := blobtesting.NewMapStorage(data, nil, nil)
+	bm := s.newTestContentManagerWithTweaks(t, st, &contentManagerTestTweaks{
+		indexVersion: index.Version2,
+	})
+
+	ctx := testlogging.Context(t)
+	dict := []byte(`{"name":"kopia","description":"fast and secure backup","type":"config","enabled":true,"retention":{"keepLatest":10,"keepDaily":7,"keepWeekly":4}}`)
+	contentData := []byte(`{"name":"kopia","description":"fast and secure backup","type":"config","enabled":false,"retention":{"keepLatest":5,"keepDaily":7,"keepWeekly":4}}`)
+
+	// dictionary prefix is reserved.
+	_, err := bm.WriteContent(ctx, gather.FromSlice(dict), CompressionDictionaryPrefix, NoCompression)
+	require.ErrorContains(t, err, "invalid prefix")
+
+	dictID, err := bm.WriteCompressionDictionary(ctx, gather.FromSlice(dict))
+	require.NoError(t, err)
+	require.Equal(t, CompressionDictionaryPrefix, dictID.Prefix())
+
+	// dictionary is usable before it's committed.
+	cid, err := bm.WriteContentWithDictionary(ctx, gather.FromSlice(contentData), "", dictID)
+	require.NoError(t, err)
+
+	gotDictID, err := bm.ContentCompressionDictionary(ctx, cid)
+	require.NoError(t, err)
+	require.Equal(t, dictID, gotDictID)
+
+	ci, err := bm.ContentInfo(ctx, cid)
+	require.NoError(t, err)
+	require.Equal(t, compression.HeaderZstdDictionary, ci.GetCompressionHeaderID())
+	require.Less(t, ci.GetPackedLength(), uint32(len(contentData)))
+
+	verifyContent(ctx, t, bm, cid, contentData)
+	require.NoError(t, bm.Flush(ctx))
+
+	// references to dictionaries are recorded when contents are flushed.
+	refs, err := bm.CompressionDictionaryReferences(ctx)
+	require.NoError(t, err)
+	require.Len(t, refs, 1)
+	require.Equal(t, map[ID][]ID{dictID: {cid}}, refs[0].Contents)
+
+	// rewritten content remains compressed using the dictionary.
+	require.NoError(t, bm.RewriteContent(ctx, cid))
+	require.NoError(t, bm.Flush(ctx))
+
+	ci, err = bm.ContentInfo(ctx, cid)
+	require.NoError(t, err)
+	require.Equal(t, compression.HeaderZstdDictionary, ci.GetCompressionHeaderID())
+
+	// duplicate and rewritten contents don't record new references.
+	_, err = bm.WriteContentWithDictionary(ctx, gather.FromSlice(contentData), "", dictID)
+	require.NoError(t, err)
+	require.NoError(t, bm.Flush(ctx))
+
+	refs2, err := bm.CompressionDictionaryReferences(ctx)
+	require.NoError(t, err)
+	require.Equal(t, refs, refs2)
+
+	// replacing references removes the previous records.
+	require.NoError(t, bm.ReplaceCompressionDictionaryReferences(ctx, nil, []blob.ID{refs[0].BlobID}))
+
+	refs, err = bm.CompressionDictionaryReferences(ctx)
+	require.NoError(t, err)
+	require.Empty(t, refs)
+
+	// new manager loads the dictionary from the repository when reading the content.
+	bm2 := s.newTestContentManagerWithTweaks(t, st, &contentManagerTestTweaks{
+		indexVersion: index.Version2,
+	})
+	verifyContent(ctx, t, bm2, cid, contentData)
+
+	gotDictID, err = bm2.ContentCompressionDictionary(ctx, dictID)
+	require.NoError(t, err)
+	require.Equal(t, EmptyID, gotDictID)
+
+	// only contents with dictionary prefix can be used as dictionaries.
+	_, err = bm.WriteContentWithDictionary(ctx, gather.FromSlice(contentData), "", cid)
+	require.ErrorContains(t, err, "is not a compression dictionary")
+}
+
 func (s *contentManagerSuite) TestContentCachingByFormat(t *testing.T) {
 	data := blobtesting.DataMap{}
 	st := blobtesting.NewMapStorage(data, nil, nil)
@@ -15,6 +15,11 @@
 // IDPrefix represents a content ID prefix (empty string or single character between 'g' and 'z').
 type IDPrefix string
 
+// CompressionDictionaryPrefix is the prefix of contents holding compression dictionaries.
+// It is outside of the range of prefixes accepted by ValidateSingle(), so that contents
+// written by users can never be mistaken for dictionaries.
+const CompressionDictionaryPrefix IDPrefix = "~"
+
 // ValidateSingle returns an error if a given prefix is invalid.
 func (p IDPrefix) ValidateSingle() error {
 	switch len(p) {
@@ -29,6 +34,12 @@ func (p IDPrefix) ValidateSingle() error {
 	return errors.Errorf("invalid prefix, must be empty or a single letter between 'g' and 'z'")
 }
 
+// isValidPrefixCharacter returns true if the provided character is a valid prefix of an existing content ID,
+// including reserved prefixes.
+func isValidPrefixCharacter(ch byte) bool {
+	return (ch >= 'g' && ch <= 'z') || ch == CompressionDictionaryPrefix[0]
+}
+
 // ID is an identifier of content in content-addressable storage.
 type ID struct {
 	data [hashing.MaxHashSize]byte
@@ -72,7 +83,7 @@ func (i ID) Hash() []byte {
 // EmptyID represents empty content ID.
 var EmptyID = ID{} //nolint:gochecknoglobals
 
-// prefixStrings contains precomputed single-character strings for all valid prefixes 'g'..'z'
+// prefixStrings contains precomputed single-character strings for all valid prefixes 'g'..'z' and reserved prefixes.
 //
 //nolint:gochecknoglobals
 var prefixStrings [256]IDPrefix
@@ -81,6 +92,8 @@ func init() {
 	for i := 'g'; i <= 'z'; i++ {
 		prefixStrings[i] = IDPrefix([]byte{byte(i)})
 	}
+
+	prefixStrings[CompressionDictionaryPrefix[0]] = CompressionDictionaryPrefix
 }
 
 func (i ID) less(other ID) bool {
@@ -165,8 +178,10 @@ func IDFromHash(prefix IDPrefix, hash []byte) (ID, error) {
 		return EmptyID, errors.Errorf("hash too short")
 	}
 
-	if err := prefix.ValidateSingle(); err != nil {
-		return EmptyID, errors.Wrap(err, "invalid prefix")
+	if prefix != CompressionDictionaryPrefix {
+		if err := prefix.ValidateSingle(); err != nil {
+			return EmptyID, errors.Wrap(err, "invalid prefix")
+		}
 	}
 
 	if len(prefix) > 0 {
@@ -192,7 +207,7 @@ func ParseID(s string) (ID, error) {
 	if len(s)%2 == 1 {
 		id.prefix = s[0]
 
-		if id.prefix < 'g' || id.prefix > 'z' {
+		if !isValidPrefixCharacter(id.prefix) {
 			return id, errors.Errorf("invalid content prefix")
 		}
 
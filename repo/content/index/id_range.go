@@ -11,7 +11,8 @@ func (r IDRange) Contains(id ID) bool {
 	return id.comparePrefix(r.StartID) >= 0 && id.comparePrefix(r.EndID) < 0
 }
 
-const maxIDCharacterPlus1 = "\x7B"
+// maxIDCharacterPlus1 is greater than all characters used in content IDs, including the reserved '~' prefix.
+const maxIDCharacterPlus1 = "\x7F"
 
 // PrefixRange returns ID range that contains all IDs with a given prefix.
 func PrefixRange(prefix IDPrefix) IDRange {
@@ -23,7 +24,7 @@ func PrefixRange(prefix IDPrefix) IDRange {
 //nolint:gochecknoglobals
 var AllIDs = IDRange{"", maxIDCharacterPlus1}
 
-// AllPrefixedIDs is an IDRange that contains all valid IDs prefixed IDs ('g' .. 'z').
+// AllPrefixedIDs is an IDRange that contains all valid IDs prefixed IDs ('g' .. 'z' and reserved prefixes).
 //
 //nolint:gochecknoglobals
 var AllPrefixedIDs = IDRange{"g", maxIDCharacterPlus1}
@@ -22,6 +22,8 @@ func TestIDValid(t *testing.T) {
 		"z00000000",
 		"z00000001",
 		"zffffffff",
+		"~00000000",
+		"~ffffffff",
 	}
 
 	var validContentIDsOrdered []ID
@@ -108,6 +110,17 @@ func TestIDPrefix(t *testing.T) {
 	require.NoError(t, IDPrefix("x").ValidateSingle())
 	require.ErrorContains(t, IDPrefix("@").ValidateSingle(), "invalid prefix, must be empty or a single letter between 'g' and 'z'")
 	require.ErrorContains(t, IDPrefix("x12").ValidateSingle(), "invalid prefix, must be empty or a single letter between 'g' and 'z'")
+
+	// reserved prefix can't be used by callers, but is valid in IDs.
+	require.ErrorContains(t, CompressionDictionaryPrefix.ValidateSingle(), "invalid prefix, must be empty or a single letter between 'g' and 'z'")
+
+	cid, err := IDFromHash(CompressionDictionaryPrefix, []byte{0x12, 0x34})
+	require.NoError(t, err)
+	require.Equal(t, "~1234", cid.String())
+	require.Equal(t, CompressionDictionaryPrefix, cid.Prefix())
+	require.True(t, AllPrefixedIDs.Contains(cid))
+	require.True(t, PrefixRange(CompressionDictionaryPrefix).Contains(cid))
+	require.False(t, AllNonPrefixedIDs.Contains(cid))
 }
 
 func TestIDHash(t *testing.T) {
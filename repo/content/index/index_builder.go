@@ -43,7 +43,7 @@ func (b Builder) Add(i Info) {
 }
 
 // base36Value stores a base-36 reverse lookup such that ASCII character corresponds to its
-// base-36 value ('0'=0..'9'=9, 'a'=10, 'b'=11, .., 'z'=35).
+// base-36 value ('0'=0..'9'=9, 'a'=10, 'b'=11, .., 'z'=35), with the reserved prefix sorted last (36).
 //
 //nolint:gochecknoglobals
 var base36Value [256]byte
@@ -57,21 +57,23 @@ func init() {
 		base36Value['a'+i] = byte(i + 10) //nolint:gomnd
 		base36Value['A'+i] = byte(i + 10) //nolint:gomnd
 	}
+
+	base36Value[CompressionDictionaryPrefix[0]] = 36 //nolint:gomnd
 }
 
 // sortedContents returns the list of []Info sorted lexicographically using bucket sort
 // sorting is optimized based on the format of content IDs (optional single-character
-// alphanumeric prefix (0-9a-z), followed by hexadecimal digits (0-9a-f).
+// alphanumeric or reserved prefix (0-9a-z~), followed by hexadecimal digits (0-9a-f).
 func (b Builder) sortedContents() []Info {
-	var buckets [36 * 16][]Info
+	var buckets [37 * 16][]Info
 
-	// phase 1 - bucketize into 576 (36 *16) separate lists
-	// by first [0-9a-z] and second character [0-9a-f].
+	// phase 1 - bucketize into 592 (37 *16) separate lists
+	// by first [0-9a-z~] and second character [0-9a-f].
 	for cid, v := range b {
 		first := int(base36Value[cid.prefix])
 		second := int(cid.data[0] >> 4) //nolint:gomnd
 
-		// first: 0..35, second: 0..15
+		// first: 0..36, second: 0..15
 		buck := first<<4 + second //nolint:gomnd
 
 		buckets[buck] = append(buckets[buck], v)
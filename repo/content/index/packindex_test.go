@@ -352,6 +352,12 @@ func TestSortedContents2(t *testing.T) {
 	b.Add(&InfoStruct{
 		ContentID: mustParseID(t, "h1023"),
 	})
+	b.Add(&InfoStruct{
+		ContentID: mustParseID(t, "~0123"),
+	})
+	b.Add(&InfoStruct{
+		ContentID: mustParseID(t, "z1023"),
+	})
 
 	got := b.sortedContents()
 
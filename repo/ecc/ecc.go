@@ -6,6 +6,7 @@
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/repo/encryption"
 )
 
@@ -58,3 +59,10 @@ func CreateEncryptor(p Parameters) (encryption.Encryptor, error) {
 		OverheadPercent: p.GetECCOverheadPercent(),
 	})
 }
+
+// DamageDetector is implemented by error correcting encryptors, which can detect
+// damaged parts of the input that must be reconstructed when decrypting.
+type DamageDetector interface {
+	// DamagedShards returns the number of damaged shards in the input.
+	DamagedShards(input gather.Bytes) int
+}
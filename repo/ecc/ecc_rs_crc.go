@@ -343,6 +343,38 @@ func (r *ReedSolomonCrcECC) Decrypt(input gather.Bytes, _ []byte, output *gather
 	return nil
 }
 
+// DamagedShards implements DamageDetector by counting data and parity shards whose CRC does not match.
+// See Encrypt comments for a description of the layout.
+func (r *ReedSolomonCrcECC) DamagedShards(input gather.Bytes) int {
+	sizes := r.computeSizesFromStored(input.Length())
+	shardPlusCrcSize := crcSize + sizes.ShardSize
+
+	// Allocate space for the input + padding
+	var inputBuffer gather.WriteBuffer
+	defer inputBuffer.Close()
+	inputBytes := inputBuffer.MakeContiguous((sizes.DataShards + sizes.ParityShards) * shardPlusCrcSize * sizes.Blocks)
+
+	copied := input.AppendToSlice(inputBytes[:0])
+
+	// WriteBuffer does not clear the data, so we must clear the padding
+	if len(copied) < len(inputBytes) {
+		clear(inputBytes[len(copied):])
+	}
+
+	damaged := 0
+
+	// Parity and data shards are all stored with their CRC, we don't need to check shards inside the padding.
+	for pos := 0; pos < len(copied); pos += shardPlusCrcSize {
+		crc := binary.BigEndian.Uint32(inputBytes[pos : pos+crcSize])
+
+		if crc != crc32.ChecksumIEEE(inputBytes[pos+crcSize:pos+shardPlusCrcSize]) {
+			damaged++
+		}
+	}
+
+	return damaged
+}
+
 func readLength(shards [][]byte, sizes *sizesInfo) (originalSize, startShard, startByte int) {
 	var lengthBuffer [lengthSize]byte
 
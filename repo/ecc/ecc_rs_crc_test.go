@@ -5,6 +5,7 @@
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/repo/encryption"
 )
 
@@ -175,3 +176,42 @@ func computeFinalFileSizeWithoutPadding(inputSize, parityShards, shardSize, bloc
 	sizePlusLength := lengthSize + inputSize
 	return parityShards*(crcSize+shardSize)*blocks + sizePlusLength + ceilInt(sizePlusLength, shardSize)*crcSize
 }
+
+func Test_RsCrc32_DamagedShards(t *testing.T) {
+	t.Parallel()
+
+	impl, err := newReedSolomonCrcECC(&Options{
+		Algorithm:       AlgorithmReedSolomonWithCrc32,
+		OverheadPercent: 2,
+		MaxShardSize:    1024,
+	})
+	require.NoError(t, err)
+
+	for _, originalSize := range []int{1, 10 * 1024, 1024 * 1024} {
+		original := make([]byte, originalSize)
+		for i := range original {
+			original[i] = byte(i%255) + 1
+		}
+
+		var output gather.WriteBuffer
+		defer output.Close()
+
+		require.NoError(t, impl.Encrypt(gather.FromSlice(original), nil, &output))
+
+		data := output.ToByteSlice()
+		require.Equal(t, 0, impl.DamagedShards(gather.FromSlice(data)))
+
+		sizes := impl.computeSizesFromStored(len(data))
+
+		// damage one parity shard and the last data shard.
+		flipByte(data, crcSize)
+		flipByte(data, len(data)-1)
+		require.Equal(t, 2, impl.DamagedShards(gather.FromSlice(data)), "size %v, shard size %v", originalSize, sizes.ShardSize)
+
+		var decrypted gather.WriteBuffer
+		defer decrypted.Close()
+
+		require.NoError(t, impl.Decrypt(gather.FromSlice(data), nil, &decrypted))
+		require.Equal(t, original, decrypted.ToByteSlice())
+	}
+}
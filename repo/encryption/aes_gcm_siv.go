@@ -0,0 +1,283 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	gcmSIVNonceSize = 12
+	gcmSIVTagSize   = 16
+	gcmSIVBlockSize = 16
+)
+
+// aesGCMSIV implements AEAD_AES_256_GCM_SIV as specified in RFC 8452, which is resistant
+// to nonce reuse.
+type aesGCMSIV struct {
+	keyGen cipher.Block
+}
+
+// newAESGCMSIV returns cipher.AEAD implementing AES-256-GCM-SIV with the provided 32-byte key.
+func newAESGCMSIV(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 { //nolint:gomnd
+		return nil, errors.Errorf("invalid AES-256-GCM-SIV key size: %v", len(key))
+	}
+
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create AES-256 cipher")
+	}
+
+	return &aesGCMSIV{b}, nil
+}
+
+func (a *aesGCMSIV) NonceSize() int {
+	return gcmSIVNonceSize
+}
+
+func (a *aesGCMSIV) Overhead() int {
+	return gcmSIVTagSize
+}
+
+// deriveKeys derives per-nonce message authentication and encryption keys.
+func (a *aesGCMSIV) deriveKeys(nonce []byte) (authKey [16]byte, encBlock cipher.Block) {
+	var (
+		in, out [gcmSIVBlockSize]byte
+		encKey  [32]byte
+	)
+
+	copy(in[4:], nonce)
+
+	for i := uint32(0); i < 6; i++ { //nolint:gomnd
+		binary.LittleEndian.PutUint32(in[0:4], i)
+		a.keyGen.Encrypt(out[:], in[:])
+
+		if i < 2 { //nolint:gomnd
+			copy(authKey[i*8:], out[0:8])
+		} else {
+			copy(encKey[(i-2)*8:], out[0:8])
+		}
+	}
+
+	// key length is always valid.
+	encBlock, _ = aes.NewCipher(encKey[:])
+
+	return authKey, encBlock
+}
+
+func (a *aesGCMSIV) computeTag(authKey [16]byte, encBlock cipher.Block, nonce, plaintext, additionalData []byte) [gcmSIVTagSize]byte {
+	p := newPolyval(authKey[:])
+
+	p.update(additionalData)
+	p.update(plaintext)
+
+	var lengthBlock [gcmSIVBlockSize]byte
+
+	binary.LittleEndian.PutUint64(lengthBlock[0:8], uint64(len(additionalData))*8) //nolint:gomnd
+	binary.LittleEndian.PutUint64(lengthBlock[8:16], uint64(len(plaintext))*8)     //nolint:gomnd
+	p.update(lengthBlock[:])
+
+	s := p.sum()
+
+	for i := 0; i < gcmSIVNonceSize; i++ {
+		s[i] ^= nonce[i]
+	}
+
+	s[15] &= 0x7f
+
+	var tag [gcmSIVTagSize]byte
+
+	encBlock.Encrypt(tag[:], s[:])
+
+	return tag
+}
+
+// ctr applies AES-CTR keystream in GCM-SIV mode, where the counter is the little-endian first word of the block.
+func ctr(encBlock cipher.Block, tag [gcmSIVTagSize]byte, dst, src []byte) {
+	counter := tag
+	counter[15] |= 0x80
+
+	var keyStream [gcmSIVBlockSize]byte
+
+	for len(src) > 0 {
+		encBlock.Encrypt(keyStream[:], counter[:])
+		binary.LittleEndian.PutUint32(counter[0:4], binary.LittleEndian.Uint32(counter[0:4])+1)
+
+		n := subtle.XORBytes(dst, src, keyStream[:])
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+func (a *aesGCMSIV) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("invalid nonce size")
+	}
+
+	authKey, encBlock := a.deriveKeys(nonce)
+	tag := a.computeTag(authKey, encBlock, nonce, plaintext, additionalData)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+gcmSIVTagSize)
+
+	ctr(encBlock, tag, out, plaintext)
+	copy(out[len(plaintext):], tag[:])
+
+	return ret
+}
+
+func (a *aesGCMSIV) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != gcmSIVNonceSize {
+		return nil, errors.Errorf("invalid nonce size")
+	}
+
+	if len(ciphertext) < gcmSIVTagSize {
+		return nil, errors.Errorf("ciphertext too short")
+	}
+
+	var tag [gcmSIVTagSize]byte
+
+	copy(tag[:], ciphertext[len(ciphertext)-gcmSIVTagSize:])
+	ciphertext = ciphertext[0 : len(ciphertext)-gcmSIVTagSize]
+
+	authKey, encBlock := a.deriveKeys(nonce)
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	ctr(encBlock, tag, out, ciphertext)
+
+	expected := a.computeTag(authKey, encBlock, nonce, out, additionalData)
+	if subtle.ConstantTimeCompare(expected[:], tag[:]) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+
+		return nil, errors.Errorf("message authentication failed")
+	}
+
+	return ret, nil
+}
+
+// sliceForAppend extends the provided slice by n bytes, returning the extended slice and the appended part.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+
+	tail = head[len(in):]
+
+	return head, tail
+}
+
+// polyval implements POLYVAL universal hash from RFC 8452.
+//
+// Field elements are represented as 128-bit little-endian integers, where bit i is the coefficient of x^i.
+// Multiplication only uses integer multiplication, XORs and shifts, so that the execution time does not
+// depend on the hash key or the data, unlike implementations based on table lookups.
+type polyval struct {
+	h, y polyvalFieldElement
+}
+
+type polyvalFieldElement struct {
+	lo, hi uint64
+}
+
+func newPolyval(h []byte) *polyval {
+	return &polyval{h: loadPolyvalFieldElement(h)}
+}
+
+func loadPolyvalFieldElement(b []byte) polyvalFieldElement {
+	return polyvalFieldElement{binary.LittleEndian.Uint64(b[0:8]), binary.LittleEndian.Uint64(b[8:16])}
+}
+
+// update processes the provided data, padding it with zeroes to the block size.
+func (p *polyval) update(data []byte) {
+	var block [gcmSIVBlockSize]byte
+
+	for len(data) > 0 {
+		n := copy(block[:], data)
+		for i := n; i < gcmSIVBlockSize; i++ {
+			block[i] = 0
+		}
+
+		data = data[n:]
+
+		x := loadPolyvalFieldElement(block[:])
+
+		p.y.lo ^= x.lo
+		p.y.hi ^= x.hi
+		p.y = polyvalDot(p.y, p.h)
+	}
+}
+
+func (p *polyval) sum() [gcmSIVBlockSize]byte {
+	var result [gcmSIVBlockSize]byte
+
+	binary.LittleEndian.PutUint64(result[0:8], p.y.lo)
+	binary.LittleEndian.PutUint64(result[8:16], p.y.hi)
+
+	return result
+}
+
+// polyvalDot returns a*b*x^-128 modulo x^128 + x^127 + x^126 + x^121 + 1.
+func polyvalDot(a, b polyvalFieldElement) polyvalFieldElement {
+	// 256-bit product using Karatsuba multiplication, least significant word first.
+	l := clmul64(a.lo, b.lo)
+	h := clmul64(a.hi, b.hi)
+	m := clmul64(a.lo^a.hi, b.lo^b.hi)
+
+	m.lo ^= l.lo ^ h.lo
+	m.hi ^= l.hi ^ h.hi
+
+	x0, x1, x2, x3 := l.lo, l.hi^m.lo, h.lo^m.hi, h.hi
+
+	// Montgomery reduction: add multiples of the modulus to clear the two low words, one at a time,
+	// and divide by x^128 by taking the two high words. For each word q, adding q*(x^128 + x^127 +
+	// x^126 + x^121 + 1) clears q and adds q*(x^121 + x^126 + x^127 + x^128) to the words above.
+	x1 ^= x0<<57 ^ x0<<62 ^ x0<<63
+	x2 ^= x0 ^ x0>>7 ^ x0>>2 ^ x0>>1
+
+	x2 ^= x1<<57 ^ x1<<62 ^ x1<<63
+	x3 ^= x1 ^ x1>>7 ^ x1>>2 ^ x1>>1
+
+	return polyvalFieldElement{x2, x3}
+}
+
+// clmul64 returns the 128-bit carry-less product of a and b.
+func clmul64(a, b uint64) polyvalFieldElement {
+	a0, a1 := uint32(a), uint32(a>>32) //nolint:gomnd
+	b0, b1 := uint32(b), uint32(b>>32) //nolint:gomnd
+
+	lo := clmul32(a0, b0)
+	hi := clmul32(a1, b1)
+	mid := clmul32(a0^a1, b0^b1) ^ lo ^ hi
+
+	return polyvalFieldElement{lo ^ mid<<32, hi ^ mid>>32}
+}
+
+// clmul32 returns the 64-bit carry-less product of a and b in constant time.
+//
+// Integer multiplication is used on operands with only every fourth bit set, in which at most 8 partial
+// products overlap in any bit position, so carries never reach the next bit position that is kept.
+func clmul32(a, b uint32) uint64 {
+	const (
+		m0 = 0x1111111111111111
+		m1 = 0x2222222222222222
+		m2 = 0x4444444444444444
+		m3 = 0x8888888888888888
+	)
+
+	a0, a1, a2, a3 := uint64(a)&m0, uint64(a)&m1, uint64(a)&m2, uint64(a)&m3
+	b0, b1, b2, b3 := uint64(b)&m0, uint64(b)&m1, uint64(b)&m2, uint64(b)&m3
+
+	c0 := (a0 * b0) ^ (a1 * b3) ^ (a2 * b2) ^ (a3 * b1)
+	c1 := (a0 * b1) ^ (a1 * b0) ^ (a2 * b3) ^ (a3 * b2)
+	c2 := (a0 * b2) ^ (a1 * b1) ^ (a2 * b0) ^ (a3 * b3)
+	c3 := (a0 * b3) ^ (a1 * b2) ^ (a2 * b1) ^ (a3 * b0)
+
+	return c0&m0 | c1&m1 | c2&m2 | c3&m3
+}
@@ -109,8 +109,10 @@ func TestCiphertextSamples(t *testing.T) {
 
 			// samples of base16-encoded ciphertexts of payload encrypted with masterKey & contentID
 			samples: map[string]string{
-				"AES256-GCM-HMAC-SHA256":        "e43ba07f85a6d70c5f1102ca06cf19c597e5f91e527b21f00fb76e8bec3fd1",
-				"CHACHA20-POLY1305-HMAC-SHA256": "118359f3d4d589d939efbbc3168ae4c77c51bcebce6845fe6ef5d11342faa6",
+				"AES256-GCM-HMAC-SHA256":         "e43ba07f85a6d70c5f1102ca06cf19c597e5f91e527b21f00fb76e8bec3fd1",
+				"AES256-GCM-SIV-HMAC-SHA256":     "d607f95ce5f1fcaef47318f396431996672b16893a5a8632862446416cadd5",
+				"CHACHA20-POLY1305-HMAC-SHA256":  "118359f3d4d589d939efbbc3168ae4c77c51bcebce6845fe6ef5d11342faa6",
+				"XCHACHA20-POLY1305-HMAC-SHA256": "6288ab0e956793bc735444dd3d3c4508baaeebd1fb840032e738e34e8d2cf86cc48430ff1a2853352e057a",
 			},
 		},
 		{
@@ -120,8 +122,10 @@ func TestCiphertextSamples(t *testing.T) {
 
 			// samples of base16-encoded ciphertexts of payload encrypted with masterKey & contentID
 			samples: map[string]string{
-				"AES256-GCM-HMAC-SHA256":        "eaad755a238f1daa4052db2e5ccddd934790b6cca415b3ccfd46ac5746af33d9d30f4400ffa9eb3a64fb1ce21b888c12c043bf6787d4a5c15ad10f21f6a6027ee3afe0",
-				"CHACHA20-POLY1305-HMAC-SHA256": "836d2ba87892711077adbdbe1452d3b2c590bbfdf6fd3387dc6810220a32ec19de862e1a4f865575e328424b5f178afac1b7eeff11494f719d119b7ebb924d1d0846a3",
+				"AES256-GCM-HMAC-SHA256":         "eaad755a238f1daa4052db2e5ccddd934790b6cca415b3ccfd46ac5746af33d9d30f4400ffa9eb3a64fb1ce21b888c12c043bf6787d4a5c15ad10f21f6a6027ee3afe0",
+				"AES256-GCM-SIV-HMAC-SHA256":     "91e844b06a56e0298600d1bdf080e592e9afeb2bbfd97eb793809c17b5da9e8516173a112683dabeccd2b01ade1f0dd004f9c4287d2f49b1b1f9ff95773d6e37808de5",
+				"CHACHA20-POLY1305-HMAC-SHA256":  "836d2ba87892711077adbdbe1452d3b2c590bbfdf6fd3387dc6810220a32ec19de862e1a4f865575e328424b5f178afac1b7eeff11494f719d119b7ebb924d1d0846a3",
+				"XCHACHA20-POLY1305-HMAC-SHA256": "33a1c00b1355a147743b2e1c97af6044feec9bccea755ffd1b37ae35587d1249db97de763a570f5986a17b9fe482cb38e72623c30a52127d0aa5cde220bef600d0de6c2cbab82a6d2ee040247adf70",
 			},
 		},
 	}
@@ -2,6 +2,7 @@
 
 import (
 	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/ecc"
 	"github.com/kopia/kopia/repo/encryption"
 )
 
@@ -36,6 +37,15 @@ func (p *encryptorWrapper) Decrypt(cipherText gather.Bytes, contentID []byte, ou
 	return p.impl.Decrypt(tmp.Bytes(), contentID, output)
 }
 
+// DamagedShards implements ecc.DamageDetector.
+func (p *encryptorWrapper) DamagedShards(cipherText gather.Bytes) int {
+	if d, ok := p.next.(ecc.DamageDetector); ok {
+		return d.DamagedShards(cipherText)
+	}
+
+	return 0
+}
+
 func (p *encryptorWrapper) Overhead() int {
 	panic("Should not be called")
 }
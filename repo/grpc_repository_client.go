@@ -795,8 +795,13 @@ func (c grpcCreds) RequireTransportSecurity() bool {
 func openGRPCAPIRepository(ctx context.Context, si *APIServerInfo, password string, par *immutableServerRepositoryParameters) (Repository, error) {
 	var transportCreds credentials.TransportCredentials
 
-	if si.TrustedServerCertificateFingerprint != "" {
-		transportCreds = credentials.NewTLS(tlsutil.TLSConfigTrustingSingleCertificate(si.TrustedServerCertificateFingerprint))
+	if si.TrustedServerCertificateFingerprint != "" || si.ClientCertificateFile != "" {
+		tc, err := tlsutil.ClientTLSConfig(si.TrustedServerCertificateFingerprint, si.ClientCertificateFile, si.ClientKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to set up TLS")
+		}
+
+		transportCreds = credentials.NewTLS(tc)
 	} else {
 		transportCreds = credentials.NewClientTLSFromCert(nil, "")
 	}
@@ -0,0 +1,114 @@
+package hashing
+
+import (
+	"encoding/binary"
+	"hash"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// kmac256Rate is the rate (block size) of KMAC256 in bytes.
+const kmac256Rate = 136
+
+// kmac implements KMAC256 keyed hash as specified in NIST SP 800-185.
+type kmac struct {
+	sha3.ShakeHash
+
+	key       []byte
+	outputLen int
+}
+
+func (k *kmac) Reset() {
+	k.ShakeHash.Reset()
+	k.ShakeHash.Write(bytepad(encodeString(k.key), kmac256Rate)) //nolint:errcheck
+}
+
+func (k *kmac) Sum(b []byte) []byte {
+	c := k.ShakeHash.Clone()
+	c.Write(rightEncode(uint64(k.outputLen) * 8)) //nolint:errcheck,gomnd
+
+	out := make([]byte, k.outputLen)
+	c.Read(out) //nolint:errcheck
+
+	return append(b, out...)
+}
+
+func (k *kmac) Size() int {
+	return k.outputLen
+}
+
+func (k *kmac) BlockSize() int {
+	return kmac256Rate
+}
+
+// newKMAC256 returns KMAC256 with the provided key, customization string and output length in bytes.
+func newKMAC256(key, customization []byte, outputLen int) hash.Hash {
+	k := &kmac{
+		ShakeHash: sha3.NewCShake256([]byte("KMAC"), customization),
+		key:       append([]byte(nil), key...),
+		outputLen: outputLen,
+	}
+
+	k.Reset()
+
+	return k
+}
+
+func kmac256Factory(outputLen int) func(key []byte) (hash.Hash, error) {
+	return func(key []byte) (hash.Hash, error) {
+		return newKMAC256(key, nil, outputLen), nil
+	}
+}
+
+// leftEncode implements left_encode() from NIST SP 800-185.
+func leftEncode(v uint64) []byte {
+	var b [9]byte
+
+	binary.BigEndian.PutUint64(b[1:], v)
+
+	i := 1
+	for i < 8 && b[i] == 0 {
+		i++
+	}
+
+	b[i-1] = byte(9 - i) //nolint:gomnd
+
+	return b[i-1:]
+}
+
+// rightEncode implements right_encode() from NIST SP 800-185.
+func rightEncode(v uint64) []byte {
+	var b [9]byte
+
+	binary.BigEndian.PutUint64(b[:8], v)
+
+	i := 0
+	for i < 7 && b[i] == 0 {
+		i++
+	}
+
+	b[8] = byte(8 - i) //nolint:gomnd
+
+	return b[i:]
+}
+
+// encodeString implements encode_string() from NIST SP 800-185.
+func encodeString(s []byte) []byte {
+	return append(leftEncode(uint64(len(s))*8), s...) //nolint:gomnd
+}
+
+// bytepad implements bytepad() from NIST SP 800-185.
+func bytepad(x []byte, w int) []byte {
+	result := append(leftEncode(uint64(w)), x...)
+
+	if pad := len(result) % w; pad != 0 {
+		result = append(result, make([]byte, w-pad)...)
+	}
+
+	return result
+}
+
+func init() {
+	Register("KMAC256", truncatedKeyedHashFuncFactory(kmac256Factory(32), 32))     //nolint:gomnd
+	Register("KMAC256-128", truncatedKeyedHashFuncFactory(kmac256Factory(16), 16)) //nolint:gomnd
+}
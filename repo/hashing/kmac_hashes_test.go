@@ -0,0 +1,48 @@
+package hashing
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKMAC256_TestVectors(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(0x40 + i)
+	}
+
+	data := make([]byte, 200)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// samples #4 and #5 from NIST SP 800-185 KMAC examples.
+	cases := []struct {
+		data          []byte
+		customization string
+		want          string
+	}{
+		{
+			data:          data[0:4],
+			customization: "My Tagged Application",
+			want:          "20c570c31346f703c9ac36c61c03cb64c3970d0cfc787e9b79599d273a68d2f7f69d4cc3de9d104a351689f27cf6f5951f0103f33f4f24871024d9c27773a8dd",
+		},
+		{
+			data: data,
+			want: "75358cf39e41494e949707927cee0af20a3ff553904c86b08f21cc414bcfd691589d27cf5e15369cbbff8b9a4c2eb17800855d0235ff635da82533ec6b759b69",
+		},
+	}
+
+	for _, tc := range cases {
+		h := newKMAC256(key, []byte(tc.customization), 64)
+		h.Write(tc.data)
+		require.Equal(t, tc.want, hex.EncodeToString(h.Sum(nil)))
+
+		// hash must be reusable after reset.
+		h.Reset()
+		h.Write(tc.data)
+		require.Equal(t, tc.want, hex.EncodeToString(h.Sum(nil)))
+	}
+}
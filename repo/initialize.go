@@ -119,6 +119,8 @@ func repositoryObjectFormatFromOptions(opt *NewRepositoryOptions) (*format.Repos
 		f.HMACSecret = nil
 	}
 
+	f.RequiredFeatures = algorithmRequiredFeatures(&f.ContentFormat)
+
 	if fv == format.FormatVersion1 || f.ContentFormat.ECCOverheadPercent == 0 {
 		f.ContentFormat.ECC = ""
 		f.ContentFormat.ECCOverheadPercent = 0
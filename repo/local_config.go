@@ -36,6 +36,9 @@ type ClientOptions struct {
 
 	EnableActions bool `json:"enableActions"`
 
+	// EnableCommandNotifications allows notification profiles stored in the repository to run commands on this client.
+	EnableCommandNotifications bool `json:"enableCommandNotifications,omitempty"`
+
 	FormatBlobCacheDuration time.Duration `json:"formatBlobCacheDuration,omitempty"`
 
 	Throttling *throttling.Limits `json:"throttlingLimits,omitempty"`
@@ -23,9 +23,17 @@ type DeleteUnreferencedBlobsOptions struct {
 }
 
 // DeleteUnreferencedBlobs deletes o was created after maintenance startederenced by index entries.
+func DeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWriter, opt DeleteUnreferencedBlobsOptions, safety SafetyParameters) (int, error) {
+	cnt, _, err := deleteUnreferencedBlobs(ctx, rep, opt, safety)
+
+	return cnt, err
+}
+
+// deleteUnreferencedBlobs deletes unreferenced blobs and returns their number and total size.
+// In dry-run mode the blobs that would be deleted are returned.
 //
 //nolint:gocyclo,funlen
-func DeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWriter, opt DeleteUnreferencedBlobsOptions, safety SafetyParameters) (int, error) {
+func deleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWriter, opt DeleteUnreferencedBlobsOptions, safety SafetyParameters) (int, int64, error) {
 	if opt.Parallel == 0 {
 		opt.Parallel = 16
 	}
@@ -69,7 +77,7 @@ func DeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWrite
 
 	activeSessions, err := rep.ContentManager().ListActiveSessions(ctx)
 	if err != nil {
-		return 0, errors.Wrap(err, "unable to load active sessions")
+		return 0, 0, errors.Wrap(err, "unable to load active sessions")
 	}
 
 	cutoffTime := opt.NotAfterTime
@@ -113,7 +121,7 @@ func DeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWrite
 
 		return nil
 	}); err != nil {
-		return 0, errors.Wrap(err, "error looking for unreferenced blobs")
+		return 0, 0, errors.Wrap(err, "error looking for unreferenced blobs")
 	}
 
 	close(unused)
@@ -123,16 +131,16 @@ func DeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWrite
 
 	// wait for all delete workers to finish.
 	if err := eg.Wait(); err != nil {
-		return 0, errors.Wrap(err, "worker error")
+		return 0, 0, errors.Wrap(err, "worker error")
 	}
 
 	if opt.DryRun {
-		return int(unreferencedCount), nil
+		return int(unreferencedCount), unreferencedSize, nil
 	}
 
 	del, cnt := deleted.Approximate()
 
 	log(ctx).Infof("Deleted total %v unreferenced blobs (%v)", del, units.BytesString(cnt))
 
-	return int(del), nil
+	return int(del), cnt, nil
 }
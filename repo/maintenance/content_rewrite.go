@@ -6,13 +6,16 @@
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/stats"
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
 )
 
 const parallelContentRewritesCPUMultiplier = 2
@@ -26,6 +29,15 @@ type RewriteContentsOptions struct {
 	ShortPacks     bool
 	FormatVersion  int
 	DryRun         bool
+
+	// LiveDataThresholdPercent, if set, selects live contents of packs whose percentage of live data
+	// is below the threshold, up to MaxRewriteBytes (if set) of live data per run.
+	LiveDataThresholdPercent int
+	MaxRewriteBytes          int64
+
+	// Deadline, if set, stops rewriting of further contents once passed.
+	// Contents rewritten so far are kept and the remaining ones can be rewritten later.
+	Deadline time.Time
 }
 
 const shortPackThresholdPercent = 60 // blocks below 60% of max block size are considered to be 'short
@@ -38,8 +50,18 @@ type contentInfoOrError struct {
 // RewriteContents rewrites contents according to provided criteria and creates new
 // blobs and index entries to point at the.
 func RewriteContents(ctx context.Context, rep repo.DirectRepositoryWriter, opt *RewriteContentsOptions, safety SafetyParameters) error {
+	_, err := rewriteContents(ctx, rep, opt, safety)
+
+	return err
+}
+
+// rewriteContents rewrites contents according to provided criteria and returns the number and total packed size
+// of contents that have been rewritten or would be rewritten in dry-run mode.
+//
+//nolint:funlen
+func rewriteContents(ctx context.Context, rep repo.DirectRepositoryWriter, opt *RewriteContentsOptions, safety SafetyParameters) (*stats.CountSum, error) {
 	if opt == nil {
-		return errors.Errorf("missing options")
+		return nil, errors.Errorf("missing options")
 	}
 
 	if opt.ShortPacks {
@@ -48,12 +70,17 @@ func RewriteContents(ctx context.Context, rep repo.DirectRepositoryWriter, opt *
 		log(ctx).Infof("Rewriting contents...")
 	}
 
+	if opt.ContentIDRange == (content.IDRange{}) {
+		opt.ContentIDRange = index.AllIDs
+	}
+
 	cnt := getContentToRewrite(ctx, rep, opt)
 
 	var (
 		mu          sync.Mutex
-		totalBytes  int64
+		rewritten   stats.CountSum
 		failedCount int
+		interrupted bool
 	)
 
 	if opt.Parallel == 0 {
@@ -82,6 +109,14 @@ func RewriteContents(ctx context.Context, rep repo.DirectRepositoryWriter, opt *
 					optDeleted = " (deleted)"
 				}
 
+				if !opt.Deadline.IsZero() && !rep.Time().Before(opt.Deadline) {
+					mu.Lock()
+					interrupted = true
+					mu.Unlock()
+
+					continue
+				}
+
 				age := rep.Time().Sub(c.Timestamp())
 				if age < safety.RewriteMinAge {
 					log(ctx).Debugf("Not rewriting content %v (%v bytes) from pack %v%v %v, because it's too new.", c.GetContentID(), c.GetPackedLength(), c.GetPackBlobID(), optDeleted, age)
@@ -89,9 +124,7 @@ func RewriteContents(ctx context.Context, rep repo.DirectRepositoryWriter, opt *
 				}
 
 				log(ctx).Debugf("Rewriting content %v (%v bytes) from pack %v%v %v", c.GetContentID(), c.GetPackedLength(), c.GetPackBlobID(), optDeleted, age)
-				mu.Lock()
-				totalBytes += int64(c.GetPackedLength())
-				mu.Unlock()
+				rewritten.Add(int64(c.GetPackedLength()))
 
 				if opt.DryRun {
 					continue
@@ -115,14 +148,24 @@ func RewriteContents(ctx context.Context, rep repo.DirectRepositoryWriter, opt *
 
 	wg.Wait()
 
-	log(ctx).Infof("Total bytes rewritten %v", units.BytesString(totalBytes))
+	if interrupted {
+		log(ctx).Infof("Maintenance time limit reached, remaining contents will be rewritten later.")
+	}
+
+	_, totalBytes := rewritten.Approximate()
+
+	if opt.DryRun {
+		log(ctx).Infof("Total bytes that would be rewritten %v", units.BytesString(totalBytes))
+	} else {
+		log(ctx).Infof("Total bytes rewritten %v", units.BytesString(totalBytes))
+	}
 
 	if failedCount == 0 {
 		//nolint:wrapcheck
-		return rep.ContentManager().Flush(ctx)
+		return &rewritten, rep.ContentManager().Flush(ctx)
 	}
 
-	return errors.Errorf("failed to rewrite %v contents", failedCount)
+	return &rewritten, errors.Errorf("failed to rewrite %v contents", failedCount)
 }
 
 func getContentToRewrite(ctx context.Context, rep repo.DirectRepository, opt *RewriteContentsOptions) <-chan contentInfoOrError {
@@ -143,6 +186,11 @@ func getContentToRewrite(ctx context.Context, rep repo.DirectRepository, opt *Re
 			}
 		}
 
+		// add live contents from packs with low ratio of live data
+		if opt.LiveDataThresholdPercent > 0 {
+			findContentInSparsePacks(ctx, rep, ch, opt)
+		}
+
 		// add all blocks with given format version
 		if opt.FormatVersion != 0 {
 			findContentWithFormatVersion(ctx, rep, ch, opt)
@@ -216,14 +264,18 @@ func(pi content.PackInfo) error {
 			if packNumberByPrefix[prefix] == 2 {
 				// when we encounter the 2nd pack, emit contents from the first one too.
 				for _, ci := range firstPackByPrefix[prefix].ContentInfos {
-					ch <- contentInfoOrError{Info: ci}
+					if opt.ContentIDRange.Contains(ci.GetContentID()) {
+						ch <- contentInfoOrError{Info: ci}
+					}
 				}
 
 				firstPackByPrefix[prefix] = content.PackInfo{}
 			}
 
 			for _, ci := range pi.ContentInfos {
-				ch <- contentInfoOrError{Info: ci}
+				if opt.ContentIDRange.Contains(ci.GetContentID()) {
+					ch <- contentInfoOrError{Info: ci}
+				}
 			}
 
 			return nil
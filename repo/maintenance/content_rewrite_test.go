@@ -11,6 +11,7 @@
 	"github.com/kopia/kopia/internal/repotesting"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content/index"
 	"github.com/kopia/kopia/repo/maintenance"
 	"github.com/kopia/kopia/repo/object"
 )
@@ -52,6 +53,16 @@ func (s *formatSpecificTestSuite) TestContentRewrite(t *testing.T) {
 			wantPDelta: 1,
 			wantQDelta: 0,
 		},
+		{
+			numPContents: 2,
+			numQContents: 3,
+			opt: &maintenance.RewriteContentsOptions{
+				ShortPacks:     true,
+				ContentIDRange: index.AllPrefixedIDs,
+			},
+			wantPDelta: 0,
+			wantQDelta: 1,
+		},
 		{
 			numPContents: 1,
 			numQContents: 0,
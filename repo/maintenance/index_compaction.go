@@ -8,7 +8,7 @@
 
 // runTaskIndexCompactionQuick rewrites index blobs to reduce their count but does not drop any contents.
 func runTaskIndexCompactionQuick(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
-	return ReportRun(ctx, runParams.rep, TaskIndexCompaction, s, func() error {
+	return runParams.runTaskWithoutStats(ctx, TaskIndexCompaction, s, func(ctx context.Context) error {
 		log(ctx).Infof("Compacting indexes...")
 
 		const maxSmallBlobsForIndexCompaction = 8
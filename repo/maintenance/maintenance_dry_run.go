@@ -0,0 +1,306 @@
+package maintenance
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/stats"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
+)
+
+// Actions performed by maintenance tasks, as reported by DryRun.
+const (
+	ActionMarkDeleted = "mark-deleted"
+	ActionRewrite     = "rewrite"
+	ActionDrop        = "drop"
+	ActionDelete      = "delete"
+)
+
+// TaskReport describes changes that a maintenance task would make to the repository.
+type TaskReport struct {
+	Task       TaskType `json:"task"`
+	Action     string   `json:"action"`
+	SkipReason string   `json:"skipReason,omitempty"`
+
+	Contents     int64 `json:"contents"`
+	ContentBytes int64 `json:"contentBytes"`
+	Blobs        int64 `json:"blobs"`
+	BlobBytes    int64 `json:"blobBytes"`
+
+	// Details contains additional task-specific statistics.
+	Details map[string]int64 `json:"details,omitempty"`
+}
+
+// DryRunReport describes changes that maintenance would make to the repository.
+type DryRunReport struct {
+	Mode      Mode         `json:"mode"`
+	StartTime time.Time    `json:"startTime"`
+	Tasks     []TaskReport `json:"tasks"`
+
+	// ProjectedReclaimedBytes is the total size of contents that would be marked as deleted,
+	// which is reclaimed by subsequent maintenance cycles, and blobs that would be deleted.
+	ProjectedReclaimedBytes int64 `json:"projectedReclaimedBytes"`
+}
+
+// DryRun simulates maintenance in the provided mode and returns the report of changes it would make
+// without modifying the repository or the maintenance schedule.
+// The provided callback can simulate additional tasks, whose reports are included first.
+func DryRun(ctx context.Context, rep repo.DirectRepositoryWriter, mode Mode, safety SafetyParameters, cb func(ctx context.Context, startTime time.Time) ([]TaskReport, error)) (*DryRunReport, error) {
+	report := &DryRunReport{
+		Mode:      mode,
+		StartTime: rep.Time(),
+	}
+
+	if cb != nil {
+		tasks, err := cb(ctx, report.StartTime)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Tasks = append(report.Tasks, tasks...)
+	}
+
+	s, err := GetSchedule(ctx, rep)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get schedule")
+	}
+
+	var tasks []TaskReport
+
+	switch mode {
+	case ModeQuick:
+		tasks, err = dryRunQuickMaintenance(ctx, rep, report.StartTime, s, safety)
+
+	case ModeFull:
+		tasks, err = dryRunFullMaintenance(ctx, rep, report.StartTime, s, safety)
+
+	default:
+		return nil, errors.Errorf("unknown mode %q", mode)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	report.Tasks = append(report.Tasks, tasks...)
+
+	for _, t := range report.Tasks {
+		switch t.Action {
+		case ActionMarkDeleted:
+			report.ProjectedReclaimedBytes += t.ContentBytes
+		case ActionDelete:
+			report.ProjectedReclaimedBytes += t.BlobBytes
+		}
+	}
+
+	return report, nil
+}
+
+func dryRunQuickMaintenance(ctx context.Context, rep repo.DirectRepositoryWriter, startTime time.Time, s *Schedule, safety SafetyParameters) ([]TaskReport, error) {
+	_, ok, emerr := rep.ContentManager().EpochManager()
+	if ok {
+		log(ctx).Debugf("quick maintenance not required for epoch manager")
+		return nil, nil
+	}
+
+	if emerr != nil {
+		return nil, errors.Wrap(emerr, "epoch manager")
+	}
+
+	var result []TaskReport
+
+	if shouldQuickRewriteContents(s, safety) {
+		t, err := dryRunRewriteContents(ctx, rep, TaskRewriteContentsQuick, &RewriteContentsOptions{
+			ContentIDRange: index.AllPrefixedIDs,
+			PackPrefix:     content.PackBlobIDPrefixSpecial,
+			ShortPacks:     true,
+		}, safety)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, t)
+	} else {
+		result = append(result, skippedRewrite(TaskRewriteContentsQuick))
+	}
+
+	switch {
+	case !shouldDeleteOrphanedPacks(rep.Time(), s, safety):
+		result = append(result, skippedBlobDeletion(TaskDeleteOrphanedBlobsQuick, s, safety))
+
+	case hadRecentFullRewrite(s):
+		t, err := dryRunDeleteUnreferencedBlobs(ctx, rep, TaskDeleteOrphanedBlobsFull, DeleteUnreferencedBlobsOptions{
+			NotAfterTime: startTime,
+		}, safety)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, t)
+
+	default:
+		t, err := dryRunDeleteUnreferencedBlobs(ctx, rep, TaskDeleteOrphanedBlobsQuick, DeleteUnreferencedBlobsOptions{
+			NotAfterTime: startTime,
+			Prefix:       content.PackBlobIDPrefixSpecial,
+		}, safety)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func dryRunFullMaintenance(ctx context.Context, rep repo.DirectRepositoryWriter, startTime time.Time, s *Schedule, safety SafetyParameters) ([]TaskReport, error) {
+	var result []TaskReport
+
+	p, err := GetParams(ctx, rep)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get maintenance params")
+	}
+
+	if shouldFullRewriteContents(s, safety) {
+		t, err := dryRunRewriteContents(ctx, rep, TaskRewriteContentsFull, &RewriteContentsOptions{
+			ContentIDRange: index.AllIDs,
+			ShortPacks:     true,
+		}, safety)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, t)
+
+		if pc := p.PackCompaction; pc.LiveDataThresholdPercent > 0 {
+			t, err := dryRunRewriteContents(ctx, rep, TaskCompactPacks, &RewriteContentsOptions{
+				ContentIDRange:           index.AllIDs,
+				LiveDataThresholdPercent: pc.LiveDataThresholdPercent,
+				MaxRewriteBytes:          pc.MaxRewriteBytes,
+			}, safety)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, t)
+		}
+	} else {
+		result = append(result, skippedRewrite(TaskRewriteContentsFull))
+	}
+
+	t, err := dryRunDropDeletedContents(ctx, rep, s, safety)
+	if err != nil {
+		return nil, err
+	}
+
+	result = append(result, t)
+
+	if shouldDeleteOrphanedPacks(rep.Time(), s, safety) {
+		t, err := dryRunDeleteUnreferencedBlobs(ctx, rep, TaskDeleteOrphanedBlobsFull, DeleteUnreferencedBlobsOptions{
+			NotAfterTime: startTime,
+		}, safety)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, t)
+	} else {
+		result = append(result, skippedBlobDeletion(TaskDeleteOrphanedBlobsFull, s, safety))
+	}
+
+	return result, nil
+}
+
+func skippedRewrite(task TaskType) TaskReport {
+	return TaskReport{
+		Task:       task,
+		Action:     ActionRewrite,
+		SkipReason: "previous content rewrite has not been finalized yet",
+	}
+}
+
+func skippedBlobDeletion(task TaskType, s *Schedule, safety SafetyParameters) TaskReport {
+	return TaskReport{
+		Task:       task,
+		Action:     ActionDelete,
+		SkipReason: "not enough time has passed yet, next deletion at " + nextBlobDeleteTime(s, safety).Format(time.RFC3339),
+	}
+}
+
+func dryRunRewriteContents(ctx context.Context, rep repo.DirectRepositoryWriter, task TaskType, opt *RewriteContentsOptions, safety SafetyParameters) (TaskReport, error) {
+	opt.DryRun = true
+
+	rewritten, err := rewriteContents(ctx, rep, opt, safety)
+	if err != nil {
+		return TaskReport{}, errors.Wrap(err, "error simulating content rewrite")
+	}
+
+	cnt, size := rewritten.Approximate()
+
+	return TaskReport{
+		Task:         task,
+		Action:       ActionRewrite,
+		Contents:     int64(cnt),
+		ContentBytes: size,
+	}, nil
+}
+
+func dryRunDeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWriter, task TaskType, opt DeleteUnreferencedBlobsOptions, safety SafetyParameters) (TaskReport, error) {
+	opt.DryRun = true
+
+	cnt, size, err := deleteUnreferencedBlobs(ctx, rep, opt, safety)
+	if err != nil {
+		return TaskReport{}, errors.Wrap(err, "error simulating blob deletion")
+	}
+
+	return TaskReport{
+		Task:      task,
+		Action:    ActionDelete,
+		Blobs:     int64(cnt),
+		BlobBytes: size,
+	}, nil
+}
+
+// dryRunDropDeletedContents finds contents whose index entries would be dropped by DropDeletedContents.
+func dryRunDropDeletedContents(ctx context.Context, rep repo.DirectRepositoryWriter, s *Schedule, safety SafetyParameters) (TaskReport, error) {
+	report := TaskReport{
+		Task:   TaskDropDeletedContentsFull,
+		Action: ActionDrop,
+	}
+
+	var safeDropTime time.Time
+
+	if safety.RequireTwoGCCycles {
+		safeDropTime = findSafeDropTime(s.Runs[TaskSnapshotGarbageCollection], safety)
+	} else {
+		safeDropTime = rep.Time()
+	}
+
+	if safeDropTime.IsZero() {
+		report.SkipReason = "not enough time has passed since previous successful snapshot GC"
+		return report, nil
+	}
+
+	var dropped stats.CountSum
+
+	if err := rep.ContentReader().IterateContents(ctx, content.IterateOptions{IncludeDeleted: true}, func(ci content.Info) error {
+		if ci.GetDeleted() && ci.Timestamp().Before(safeDropTime) {
+			dropped.Add(int64(ci.GetPackedLength()))
+		}
+
+		return nil
+	}); err != nil {
+		return TaskReport{}, errors.Wrap(err, "error iterating contents")
+	}
+
+	cnt, size := dropped.Approximate()
+
+	report.Contents = int64(cnt)
+	report.ContentBytes = size
+
+	return report, nil
+}
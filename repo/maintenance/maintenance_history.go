@@ -0,0 +1,87 @@
+package maintenance
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo"
+)
+
+// HistoryRetentionOptions specifies how many runs of each maintenance task are kept in the history.
+// The most recent runs required to determine maintenance safety are always kept.
+type HistoryRetentionOptions struct {
+	MaxCount int           `json:"maxCount"`
+	MaxAge   time.Duration `json:"maxAge"`
+}
+
+// OrDefault returns default HistoryRetentionOptions.
+func (o HistoryRetentionOptions) OrDefault() HistoryRetentionOptions {
+	if o.MaxCount == 0 && o.MaxAge == 0 {
+		return defaultHistoryRetention()
+	}
+
+	return o
+}
+
+func defaultHistoryRetention() HistoryRetentionOptions {
+	//nolint:gomnd
+	return HistoryRetentionOptions{
+		MaxCount: 50,                  // no more than 50 runs of each task
+		MaxAge:   90 * 24 * time.Hour, // no more than 90 days of history
+	}
+}
+
+// HistoryEntry describes a single run of a maintenance task.
+type HistoryEntry struct {
+	Task TaskType `json:"task"`
+	RunInfo
+}
+
+// History returns the history of maintenance runs of the provided task (or all tasks if empty),
+// most recent first.
+func History(ctx context.Context, rep repo.DirectRepository, taskType TaskType) ([]HistoryEntry, error) {
+	s, err := GetSchedule(ctx, rep)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get maintenance schedule")
+	}
+
+	var result []HistoryEntry
+
+	for t, runs := range s.Runs {
+		if taskType != "" && t != taskType {
+			continue
+		}
+
+		for _, ri := range runs {
+			result = append(result, HistoryEntry{t, ri})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].Start.Equal(result[j].Start) {
+			return result[i].Start.After(result[j].Start)
+		}
+
+		return result[i].Task < result[j].Task
+	})
+
+	return result, nil
+}
+
+// trimHistory discards runs beyond retention, while always keeping minRetainedRunInfoPerRunType most recent runs.
+func trimHistory(history []RunInfo, opt HistoryRetentionOptions, now time.Time) []RunInfo {
+	for i := minRetainedRunInfoPerRunType; i < len(history); i++ {
+		if opt.MaxCount > 0 && i >= opt.MaxCount {
+			return history[0:i]
+		}
+
+		if opt.MaxAge > 0 && now.Sub(history[i].End) > opt.MaxAge {
+			return history[0:i]
+		}
+	}
+
+	return history
+}
@@ -7,6 +7,7 @@
 	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob/throttling"
 	"github.com/kopia/kopia/repo/manifest"
 )
 
@@ -25,6 +26,28 @@ type Params struct {
 	LogRetention LogRetentionOptions `json:"logRetention"`
 
 	ExtendObjectLocks bool `json:"extendObjectLocks"`
+
+	// Partitions is the number of content ID partitions of full content rewrite and snapshot GC,
+	// which can be processed in parallel by maintenance workers. Values below 2 disable partitioning.
+	Partitions int `json:"partitions,omitempty"`
+
+	// Windows is a list of crontab expressions matching the minutes (in local time) during which
+	// automatic maintenance is allowed to run. When empty, maintenance can run at any time.
+	Windows []string `json:"windows,omitempty"`
+
+	// MaxRuntime limits the duration of a single maintenance run. Tasks which have not completed
+	// by then are resumed during the next run.
+	MaxRuntime time.Duration `json:"maxRuntime,omitempty"`
+
+	// TaskBudgets limits the storage bandwidth and operations used by individual maintenance tasks.
+	TaskBudgets map[TaskType]throttling.Limits `json:"taskBudgets,omitempty"`
+
+	// Tasks overrides scheduling of individual maintenance tasks.
+	Tasks map[TaskType]TaskParams `json:"tasks,omitempty"`
+
+	HistoryRetention HistoryRetentionOptions `json:"historyRetention"`
+
+	PackCompaction PackCompactionParams `json:"packCompaction"`
 }
 
 func (p *Params) isOwnedByByThisUser(rep repo.Repository) bool {
@@ -57,6 +80,44 @@ type CycleParams struct {
 	Interval time.Duration `json:"interval"`
 }
 
+// PackCompactionParams specifies parameters of compaction of packs with low ratio of live data,
+// which runs as part of full maintenance.
+type PackCompactionParams struct {
+	// LiveDataThresholdPercent selects packs with lower percentage of live data for compaction, zero disables compaction.
+	LiveDataThresholdPercent int `json:"liveDataThresholdPercent,omitempty"`
+
+	// MaxRewriteBytes limits the amount of live data rewritten by a single compaction, zero means unlimited.
+	MaxRewriteBytes int64 `json:"maxRewriteBytes,omitempty"`
+}
+
+// TaskParams specifies scheduling parameters of an individual maintenance task, which otherwise runs
+// as part of every quick or full maintenance cycle.
+type TaskParams struct {
+	Disabled bool          `json:"disabled,omitempty"`
+	Interval time.Duration `json:"interval,omitempty"`
+}
+
+// isTaskDue determines whether the provided task is enabled and enough time has passed since its last successful run.
+func (p *Params) isTaskDue(ctx context.Context, taskType TaskType, s *Schedule, now time.Time) bool {
+	tp := p.Tasks[taskType]
+
+	if tp.Disabled {
+		log(ctx).Infof("Skipping %v, because it is disabled.", taskType)
+		return false
+	}
+
+	if tp.Interval <= 0 {
+		return true
+	}
+
+	if next := maxEndTime(s.Runs[taskType]).Add(tp.Interval); now.Before(next) {
+		log(ctx).Infof("Skipping %v, because it's not due until %v.", taskType, next.Format(time.RFC3339))
+		return false
+	}
+
+	return true
+}
+
 // HasParams determines whether repository-wide maintenance parameters have been set.
 func HasParams(ctx context.Context, rep repo.Repository) (bool, error) {
 	md, err := manifestIDs(ctx, rep)
@@ -0,0 +1,583 @@
+package maintenance
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
+)
+
+const (
+	partitionedJobBlobID     = "kopia.maintenance.job"
+	partitionLeaseBlobPrefix = "kopia.maintenance.lease."
+	partitionDataBlobPrefix  = "kopia.maintenance.data."
+
+	// maxPartitions is the maximum number of partitions, partition boundaries are two-character
+	// prefixes of non-prefixed content IDs.
+	maxPartitions = 256
+
+	// leaseDuration is the time after which partitions and jobs which are not renewed are considered abandoned.
+	// It must be well above maxClockSkew, since leases are compared against clocks of other clients.
+	leaseDuration = 15 * time.Minute
+
+	// leaseRenewInterval is the interval between renewals of leases and jobs being processed.
+	leaseRenewInterval = leaseDuration / 5
+
+	// leaseSettleTime is the time a worker waits after claiming a partition before verifying
+	// that its claim was not overwritten by a competing worker.
+	leaseSettleTime = 5 * time.Second
+)
+
+//nolint:gochecknoglobals
+var (
+	// partitionPollInterval is the interval at which the owner checks for partitions processed by other workers.
+	partitionPollInterval = 10 * time.Second
+
+	// builtinPartitionFuncs are partition functions of tasks implemented in this package.
+	builtinPartitionFuncs = map[TaskType]PartitionFunc{
+		TaskRewriteContentsFull: rewriteContentsPartition,
+	}
+)
+
+// PartitionedJob describes a maintenance task whose work is split into content ID partitions,
+// which are claimed and processed by cooperating maintenance workers.
+type PartitionedJob struct {
+	ID         string            `json:"id"`
+	Task       TaskType          `json:"task"`
+	Owner      string            `json:"owner"`
+	StartTime  time.Time         `json:"startTime"`
+	UpdateTime time.Time         `json:"updateTime"`
+	Safety     SafetyParameters  `json:"safety"`
+	Partitions []content.IDRange `json:"partitions"`
+}
+
+// partitionLease records the worker processing a partition of a job and the result of processing.
+type partitionLease struct {
+	JobID     string           `json:"jobID"`
+	Partition int              `json:"partition"`
+	Worker    string           `json:"worker"`
+	Expires   time.Time        `json:"expires"`
+	Completed bool             `json:"completed,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	Counters  map[string]int64 `json:"counters,omitempty"`
+}
+
+// PartitionFunc processes contents in the provided range as part of a partitioned maintenance task,
+// it must flush all writes before returning. Returned counters are summed across all partitions.
+type PartitionFunc func(ctx context.Context, rep repo.DirectRepositoryWriter, job *PartitionedJob, r content.IDRange) (map[string]int64, error)
+
+// PartitionPrepareFunc is invoked by the owner of a partitioned job before any of its partitions are processed,
+// it can store data shared with workers processing individual partitions using PutPartitionData.
+type PartitionPrepareFunc func(ctx context.Context, rep repo.DirectRepositoryWriter, job *PartitionedJob) error
+
+// ContentIDPartitions splits the space of content IDs into n contiguous ranges of roughly equal size.
+// Non-prefixed content IDs are hashes and are split evenly, prefixed content IDs all belong to the last range.
+func ContentIDPartitions(n int) []content.IDRange {
+	if n < 1 {
+		n = 1
+	}
+
+	if n > maxPartitions {
+		n = maxPartitions
+	}
+
+	var result []content.IDRange
+
+	start := index.AllIDs.StartID
+
+	for i := 1; i < n; i++ {
+		end := content.IDPrefix(fmt.Sprintf("%02x", i*maxPartitions/n))
+
+		result = append(result, content.IDRange{StartID: start, EndID: end})
+		start = end
+	}
+
+	return append(result, content.IDRange{StartID: start, EndID: index.AllIDs.EndID})
+}
+
+// RunPartitioned runs the provided task split into content ID partitions according to maintenance parameters.
+// Partitions are processed by the maintenance owner and by any clients running maintenance workers,
+// the owner waits until all partitions are complete and returns the sum of their counters.
+func RunPartitioned(ctx context.Context, runParams RunParameters, task TaskType, safety SafetyParameters, fn PartitionFunc) (map[string]int64, error) {
+	return RunPartitionedWithData(ctx, runParams, task, safety, nil, fn)
+}
+
+// RunPartitionedWithData is like RunPartitioned, but invokes the provided function to prepare data of partitions
+// before they are made available to workers.
+func RunPartitionedWithData(ctx context.Context, runParams RunParameters, task TaskType, safety SafetyParameters, prepare PartitionPrepareFunc, fn PartitionFunc) (map[string]int64, error) {
+	rep := runParams.rep
+
+	job := &PartitionedJob{
+		ID:         uuid.NewString(),
+		Task:       task,
+		Owner:      rep.ClientOptions().UsernameAtHost(),
+		StartTime:  runParams.MaintenanceStartTime,
+		UpdateTime: rep.Time(),
+		Safety:     safety,
+		Partitions: ContentIDPartitions(runParams.Params.Partitions),
+	}
+
+	// leases and data of jobs interrupted by a crash of the owner are never going to be used.
+	if err := deletePartitionBlobs(ctx, rep); err != nil {
+		return nil, err
+	}
+
+	if prepare != nil {
+		if err := prepare(ctx, rep, job); err != nil {
+			if cerr := deletePartitionBlobs(ctx, rep); cerr != nil {
+				log(ctx).Errorf("unable to delete partition data: %v", cerr)
+			}
+
+			return nil, errors.Wrap(err, "unable to prepare partitions")
+		}
+	}
+
+	if err := putEncryptedJSON(ctx, rep, partitionedJobBlobID, job); err != nil {
+		return nil, errors.Wrap(err, "unable to write partitioned job")
+	}
+
+	log(ctx).Infof("Running %v in %v partitions...", task, len(job.Partitions))
+
+	stopRenewing := keepRenewing(ctx, func() {
+		job.UpdateTime = rep.Time()
+
+		if err := putEncryptedJSON(ctx, rep, partitionedJobBlobID, job); err != nil {
+			log(ctx).Errorf("unable to renew partitioned job: %v", err)
+		}
+	})
+
+	counters, err := runAllPartitions(ctx, rep, job, fn)
+
+	stopRenewing()
+
+	if cerr := deletePartitionedJob(ctx, rep); cerr != nil && err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// pick up index entries written by other workers.
+	return counters, errors.Wrap(rep.Refresh(ctx), "error refreshing indexes")
+}
+
+// runAllPartitions processes partitions of the provided job until all of them are complete.
+func runAllPartitions(ctx context.Context, rep repo.DirectRepositoryWriter, job *PartitionedJob, fn PartitionFunc) (map[string]int64, error) {
+	workerID := newWorkerID(rep)
+
+	for {
+		if _, err := processPartitions(ctx, rep, job, workerID, fn); err != nil {
+			return nil, err
+		}
+
+		counters, pending, err := mergePartitionResults(ctx, rep, job)
+		if err != nil {
+			return nil, err
+		}
+
+		if pending == 0 {
+			return counters, nil
+		}
+
+		log(ctx).Infof("Waiting for %v partitions being processed by other workers...", pending)
+
+		if !clock.SleepInterruptibly(ctx, partitionPollInterval) {
+			return nil, errors.Wrap(ctx.Err(), "interrupted while waiting for partitions")
+		}
+	}
+}
+
+// mergePartitionResults sums counters of all completed partitions and returns the number of partitions which are not complete yet.
+func mergePartitionResults(ctx context.Context, rep repo.DirectRepository, job *PartitionedJob) (map[string]int64, int, error) {
+	counters := map[string]int64{}
+	pending := 0
+
+	for i := range job.Partitions {
+		l, err := getPartitionLease(ctx, rep, job, i)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if l == nil || !l.Completed {
+			pending++
+			continue
+		}
+
+		for k, v := range l.Counters {
+			counters[k] += v
+		}
+	}
+
+	return counters, pending, nil
+}
+
+// RunWorker processes partitions of the maintenance job currently in progress, which are not claimed by other workers.
+// Functions of partitioned tasks implemented outside of this package must be provided.
+// It returns the number of processed partitions.
+func RunWorker(ctx context.Context, rep repo.DirectRepositoryWriter, funcs map[TaskType]PartitionFunc) (int, error) {
+	job := &PartitionedJob{}
+
+	err := getEncryptedJSON(ctx, rep, partitionedJobBlobID, job)
+	if errors.Is(err, blob.ErrBlobNotFound) {
+		log(ctx).Debugf("no partitioned maintenance job in progress")
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to read partitioned job")
+	}
+
+	if rep.Time().Sub(job.UpdateTime) > leaseDuration {
+		log(ctx).Debugf("ignoring abandoned partitioned job %v", job.ID)
+		return 0, nil
+	}
+
+	fn := funcs[job.Task]
+	if fn == nil {
+		fn = builtinPartitionFuncs[job.Task]
+	}
+
+	if fn == nil {
+		return 0, errors.Errorf("unsupported partitioned task %q", job.Task)
+	}
+
+	// make sure contents written by the owner before starting the job are visible.
+	if err := rep.Refresh(ctx); err != nil {
+		return 0, errors.Wrap(err, "error refreshing indexes")
+	}
+
+	return processPartitions(ctx, rep, job, newWorkerID(rep), fn)
+}
+
+// processPartitions claims and processes available partitions of the job, returns the number of processed partitions.
+func processPartitions(ctx context.Context, rep repo.DirectRepositoryWriter, job *PartitionedJob, workerID string, fn PartitionFunc) (int, error) {
+	processed := 0
+
+	for i, r := range job.Partitions {
+		claimed, err := claimPartition(ctx, rep, job, i, workerID)
+		if err != nil {
+			return processed, err
+		}
+
+		if !claimed {
+			continue
+		}
+
+		log(ctx).Infof("Processing partition %v/%v of %v...", i+1, len(job.Partitions), job.Task)
+		log(ctx).Debugf("partition %v covers content IDs from %q to %q", i, r.StartID, r.EndID)
+
+		if err := processPartition(ctx, rep, job, i, workerID, fn); err != nil {
+			return processed, errors.Wrapf(err, "error processing partition %v of %v", i, job.Task)
+		}
+
+		processed++
+	}
+
+	return processed, nil
+}
+
+func processPartition(ctx context.Context, rep repo.DirectRepositoryWriter, job *PartitionedJob, i int, workerID string, fn PartitionFunc) error {
+	pctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stopRenewing := keepRenewing(ctx, func() {
+		l, err := getPartitionLease(ctx, rep, job, i)
+		if err == nil && (l == nil || l.Worker != workerID) {
+			log(ctx).Errorf("lease of partition %v has been taken over by another worker", i)
+			cancel()
+
+			return
+		}
+
+		if err := putPartitionLease(ctx, rep, &partitionLease{
+			JobID:     job.ID,
+			Partition: i,
+			Worker:    workerID,
+			Expires:   rep.Time().Add(leaseDuration),
+		}); err != nil {
+			log(ctx).Errorf("unable to renew lease of partition %v: %v", i, err)
+		}
+	})
+
+	counters, err := fn(pctx, rep, job, job.Partitions[i])
+
+	stopRenewing()
+
+	if err != nil {
+		// release the partition so that it can be retried.
+		if perr := putPartitionLease(ctx, rep, &partitionLease{
+			JobID:     job.ID,
+			Partition: i,
+			Worker:    workerID,
+			Expires:   rep.Time(),
+			Error:     err.Error(),
+		}); perr != nil {
+			log(ctx).Errorf("unable to release lease of partition %v: %v", i, perr)
+		}
+
+		return err
+	}
+
+	return putPartitionLease(ctx, rep, &partitionLease{
+		JobID:     job.ID,
+		Partition: i,
+		Worker:    workerID,
+		Expires:   rep.Time(),
+		Completed: true,
+		Counters:  counters,
+	})
+}
+
+// claimPartition attempts to acquire the lease of a partition which is neither complete nor leased by another worker.
+// Since blob storage does not support conditional writes, the lease is written and read back after a while
+// to detect competing workers which claimed the same partition at the same time, in which case the last writer wins.
+func claimPartition(ctx context.Context, rep repo.DirectRepositoryWriter, job *PartitionedJob, i int, workerID string) (bool, error) {
+	l, err := getPartitionLease(ctx, rep, job, i)
+	if err != nil {
+		return false, err
+	}
+
+	if l != nil && (l.Completed || rep.Time().Before(l.Expires)) {
+		return false, nil
+	}
+
+	if err := putPartitionLease(ctx, rep, &partitionLease{
+		JobID:     job.ID,
+		Partition: i,
+		Worker:    workerID,
+		Expires:   rep.Time().Add(leaseDuration),
+	}); err != nil {
+		return false, err
+	}
+
+	if !job.Safety.DisableEventualConsistencySafety && !clock.SleepInterruptibly(ctx, leaseSettleTime) {
+		return false, errors.Wrap(ctx.Err(), "interrupted while claiming partition")
+	}
+
+	l, err = getPartitionLease(ctx, rep, job, i)
+	if err != nil {
+		return false, err
+	}
+
+	return l != nil && l.Worker == workerID, nil
+}
+
+// keepRenewing invokes the provided function periodically until the returned function is called.
+func keepRenewing(ctx context.Context, renew func()) func() {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		t := time.NewTicker(leaseRenewInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-t.C:
+				renew()
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+func partitionLeaseBlobID(job *PartitionedJob, i int) blob.ID {
+	return blob.ID(fmt.Sprintf("%v%v.%v", partitionLeaseBlobPrefix, job.ID, i))
+}
+
+// getPartitionLease returns the lease of the provided partition or nil if the partition has not been claimed yet.
+func getPartitionLease(ctx context.Context, rep repo.DirectRepository, job *PartitionedJob, i int) (*partitionLease, error) {
+	l := &partitionLease{}
+
+	err := getEncryptedJSON(ctx, rep, partitionLeaseBlobID(job, i), l)
+	if errors.Is(err, blob.ErrBlobNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read lease of partition %v", i)
+	}
+
+	return l, nil
+}
+
+func putPartitionLease(ctx context.Context, rep repo.DirectRepositoryWriter, l *partitionLease) error {
+	return errors.Wrapf(
+		putEncryptedJSON(ctx, rep, partitionLeaseBlobID(&PartitionedJob{ID: l.JobID}, l.Partition), l),
+		"unable to write lease of partition %v", l.Partition)
+}
+
+func deletePartitionedJob(ctx context.Context, rep repo.DirectRepositoryWriter) error {
+	if err := rep.BlobStorage().DeleteBlob(ctx, partitionedJobBlobID); err != nil && !errors.Is(err, blob.ErrBlobNotFound) {
+		return errors.Wrap(err, "unable to delete partitioned job")
+	}
+
+	return deletePartitionBlobs(ctx, rep)
+}
+
+// deletePartitionBlobs deletes leases and data of all partitions.
+func deletePartitionBlobs(ctx context.Context, rep repo.DirectRepositoryWriter) error {
+	var ids []blob.ID
+
+	for _, prefix := range []blob.ID{partitionLeaseBlobPrefix, partitionDataBlobPrefix} {
+		if err := rep.BlobStorage().ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+			ids = append(ids, bm.BlobID)
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "unable to list partition blobs")
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		if err := rep.BlobStorage().DeleteBlob(ctx, id); err != nil && !errors.Is(err, blob.ErrBlobNotFound) {
+			return errors.Wrap(err, "unable to delete partition blob")
+		}
+	}
+
+	return nil
+}
+
+func partitionDataBlobID(job *PartitionedJob, r content.IDRange) (blob.ID, error) {
+	for i, pr := range job.Partitions {
+		if pr == r {
+			return blob.ID(fmt.Sprintf("%v%v.%v", partitionDataBlobPrefix, job.ID, i)), nil
+		}
+	}
+
+	return "", errors.Errorf("partition %q..%q not found in job %v", r.StartID, r.EndID, job.ID)
+}
+
+// PutPartitionData stores data of the provided partition of the job, which is deleted along with the job.
+func PutPartitionData(ctx context.Context, rep repo.DirectRepositoryWriter, job *PartitionedJob, r content.IDRange, data []byte) error {
+	blobID, err := partitionDataBlobID(job, r)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(putEncrypted(ctx, rep, blobID, data), "unable to write partition data")
+}
+
+// GetPartitionData returns data of the provided partition of the job stored using PutPartitionData.
+func GetPartitionData(ctx context.Context, rep repo.DirectRepository, job *PartitionedJob, r content.IDRange) ([]byte, error) {
+	blobID, err := partitionDataBlobID(job, r)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := getEncrypted(ctx, rep, blobID)
+
+	return b, errors.Wrap(err, "unable to read partition data")
+}
+
+// getEncryptedJSON reads the blob written by putEncryptedJSON and decodes it into the provided value.
+func getEncryptedJSON(ctx context.Context, rep repo.DirectRepository, blobID blob.ID, v interface{}) error {
+	j, err := getEncrypted(ctx, rep, blobID)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrapf(json.Unmarshal(j, v), "malformed blob %v", blobID)
+}
+
+// getEncrypted reads and decrypts the blob written by putEncrypted.
+func getEncrypted(ctx context.Context, rep repo.DirectRepository, blobID blob.ID) ([]byte, error) {
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	if err := rep.BlobReader().GetBlob(ctx, blobID, 0, -1, &tmp); err != nil {
+		return nil, errors.Wrapf(err, "error reading %v", blobID)
+	}
+
+	c, err := getAES256GCM(rep)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get cipher")
+	}
+
+	b := tmp.ToByteSlice()
+
+	if len(b) < c.NonceSize() {
+		return nil, errors.Errorf("invalid blob %v", blobID)
+	}
+
+	d, err := c.Open(nil, b[0:c.NonceSize()], b[c.NonceSize():], maintenanceScheduleAEADExtraData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to decrypt %v", blobID)
+	}
+
+	return d, nil
+}
+
+// putEncryptedJSON writes the JSON representation of the provided value encrypted the same way as the schedule blob.
+func putEncryptedJSON(ctx context.Context, rep repo.DirectRepositoryWriter, blobID blob.ID, v interface{}) error {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "unable to serialize JSON")
+	}
+
+	return putEncrypted(ctx, rep, blobID, j)
+}
+
+// putEncrypted writes the provided data encrypted the same way as the schedule blob.
+func putEncrypted(ctx context.Context, rep repo.DirectRepositoryWriter, blobID blob.ID, data []byte) error {
+	c, err := getAES256GCM(rep)
+	if err != nil {
+		return errors.Wrap(err, "unable to get cipher")
+	}
+
+	nonce := make([]byte, c.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return errors.Wrap(err, "unable to initialize nonce")
+	}
+
+	result := append([]byte(nil), nonce...)
+	ciphertext := c.Seal(result, nonce, data, maintenanceScheduleAEADExtraData)
+
+	//nolint:wrapcheck
+	return rep.BlobStorage().PutBlob(ctx, blobID, gather.FromSlice(ciphertext), blob.PutOptions{})
+}
+
+func newWorkerID(rep repo.DirectRepository) string {
+	return rep.ClientOptions().UsernameAtHost() + "/" + uuid.NewString()
+}
+
+func rewriteContentsPartition(ctx context.Context, rep repo.DirectRepositoryWriter, job *PartitionedJob, r content.IDRange) (map[string]int64, error) {
+	rewritten, err := rewriteContents(ctx, rep, &RewriteContentsOptions{
+		ContentIDRange: r,
+		ShortPacks:     true,
+	}, job.Safety)
+	if err != nil {
+		return nil, err
+	}
+
+	cnt, size := rewritten.Approximate()
+
+	return map[string]int64{
+		"contents": int64(cnt),
+		"bytes":    size,
+	}, nil
+}
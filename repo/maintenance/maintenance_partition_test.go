@@ -0,0 +1,165 @@
+package maintenance_test
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
+	"github.com/kopia/kopia/repo/maintenance"
+)
+
+func TestContentIDPartitions(t *testing.T) {
+	require.Equal(t, []content.IDRange{index.AllIDs}, maintenance.ContentIDPartitions(0))
+	require.Equal(t, []content.IDRange{index.AllIDs}, maintenance.ContentIDPartitions(1))
+	require.Len(t, maintenance.ContentIDPartitions(1000), 256)
+
+	parts := maintenance.ContentIDPartitions(3)
+	require.Equal(t, []content.IDRange{
+		{StartID: index.AllIDs.StartID, EndID: "55"},
+		{StartID: "55", EndID: "aa"},
+		{StartID: "aa", EndID: index.AllIDs.EndID},
+	}, parts)
+
+	var ids []content.ID
+
+	for i := 0; i < 1000; i++ {
+		var h [16]byte
+
+		rand.Read(h[:])
+
+		cid, err := content.IDFromHash("", h[:])
+		require.NoError(t, err)
+
+		ids = append(ids, cid)
+
+		cid, err = content.IDFromHash("k", h[:])
+		require.NoError(t, err)
+
+		ids = append(ids, cid)
+	}
+
+	// each content ID belongs to exactly one partition.
+	for _, cid := range ids {
+		n := 0
+
+		for _, r := range parts {
+			if r.Contains(cid) {
+				n++
+			}
+		}
+
+		require.Equal(t, 1, n, cid)
+	}
+}
+
+func (s *formatSpecificTestSuite) TestPartitionedMaintenance(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, s.formatVersion)
+
+	anotherClient := env.MustConnectOpenAnother(t)
+
+	const (
+		testTask   maintenance.TaskType = "test-task"
+		partitions                      = 4
+	)
+
+	require.NoError(t, repo.WriteSession(ctx, env.Repository, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.RepositoryWriter) error {
+		p := maintenance.DefaultParams()
+		p.Owner = env.Repository.ClientOptions().UsernameAtHost()
+		p.Partitions = partitions
+
+		return maintenance.SetParams(ctx, w, &p)
+	}))
+
+	// no job in progress.
+	require.NoError(t, repo.DirectWriteSession(ctx, anotherClient.(repo.DirectRepository), repo.WriteSessionOptions{}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
+		n, err := maintenance.RunWorker(ctx, w, nil)
+		require.Zero(t, n)
+
+		return err
+	}))
+
+	var (
+		mu        sync.Mutex
+		processed = map[content.IDRange]string{}
+	)
+
+	partitionFunc := func(worker string) maintenance.PartitionFunc {
+		return func(ctx context.Context, rep repo.DirectRepositoryWriter, job *maintenance.PartitionedJob, r content.IDRange) (map[string]int64, error) {
+			require.Equal(t, testTask, job.Task)
+
+			// data stored by the owner before partitions were processed.
+			data, err := maintenance.GetPartitionData(ctx, rep, job, r)
+			require.NoError(t, err)
+			require.Equal(t, "data-"+string(r.StartID), string(data))
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			require.NotContains(t, processed, r)
+			processed[r] = worker
+
+			return map[string]int64{"partitions": 1}, nil
+		}
+	}
+
+	workerFunc := partitionFunc("worker")
+	ownerFunc := partitionFunc("owner")
+
+	var workerProcessed int
+
+	require.NoError(t, maintenance.RunExclusive(ctx, env.RepositoryWriter, maintenance.ModeFull, true, func(ctx context.Context, runParams maintenance.RunParameters) error {
+		counters, err := maintenance.RunPartitionedWithData(ctx, runParams, testTask, maintenance.SafetyNone,
+			func(ctx context.Context, rep repo.DirectRepositoryWriter, job *maintenance.PartitionedJob) error {
+				for _, r := range job.Partitions {
+					if err := maintenance.PutPartitionData(ctx, rep, job, r, []byte("data-"+string(r.StartID))); err != nil {
+						return err
+					}
+				}
+
+				return nil
+			},
+			func(ctx context.Context, rep repo.DirectRepositoryWriter, job *maintenance.PartitionedJob, r content.IDRange) (map[string]int64, error) {
+				// while the owner processes the first partition, another worker claims the remaining ones.
+				if workerProcessed == 0 {
+					require.NoError(t, repo.DirectWriteSession(ctx, anotherClient.(repo.DirectRepository), repo.WriteSessionOptions{}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
+						var werr error
+
+						workerProcessed, werr = maintenance.RunWorker(ctx, w, map[maintenance.TaskType]maintenance.PartitionFunc{
+							testTask: workerFunc,
+						})
+
+						return werr
+					}))
+				}
+
+				return ownerFunc(ctx, rep, job, r)
+			})
+		if err != nil {
+			return err
+		}
+
+		require.Equal(t, map[string]int64{"partitions": partitions}, counters)
+
+		return nil
+	}))
+
+	require.Equal(t, partitions-1, workerProcessed)
+	require.Len(t, processed, partitions)
+
+	for _, r := range maintenance.ContentIDPartitions(partitions)[1:] {
+		require.Equal(t, "worker", processed[r])
+	}
+
+	// job, leases and data have been cleaned up.
+	blobs, err := blob.ListAllBlobs(ctx, env.RepositoryWriter.BlobStorage(), "kopia.maintenance.")
+	require.NoError(t, err)
+	require.Empty(t, blobs)
+}
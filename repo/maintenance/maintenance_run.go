@@ -7,9 +7,12 @@
 	"time"
 
 	"github.com/gofrs/flock"
+	"github.com/hashicorp/cronexpr"
 	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/stats"
+	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/content"
 	"github.com/kopia/kopia/repo/content/index"
@@ -36,16 +39,21 @@
 
 // Task IDs.
 const (
-	TaskSnapshotGarbageCollection   = "snapshot-gc"
-	TaskDeleteOrphanedBlobsQuick    = "quick-delete-blobs"
-	TaskDeleteOrphanedBlobsFull     = "full-delete-blobs"
-	TaskRewriteContentsQuick        = "quick-rewrite-contents"
-	TaskRewriteContentsFull         = "full-rewrite-contents"
-	TaskDropDeletedContentsFull     = "full-drop-deleted-content"
-	TaskIndexCompaction             = "index-compaction"
-	TaskExtendBlobRetentionTimeFull = "extend-blob-retention-time"
-	TaskCleanupLogs                 = "cleanup-logs"
-	TaskCleanupEpochManager         = "cleanup-epoch-manager"
+	TaskSnapshotGarbageCollection    = "snapshot-gc"
+	TaskTrainCompressionDictionaries = "train-compression-dictionaries"
+	TaskDeleteOrphanedBlobsQuick     = "quick-delete-blobs"
+	TaskDeleteOrphanedBlobsFull      = "full-delete-blobs"
+	TaskRewriteContentsQuick         = "quick-rewrite-contents"
+	TaskRewriteContentsFull          = "full-rewrite-contents"
+	TaskCompactPacks                 = "compact-packs"
+	TaskDropDeletedContentsFull      = "full-drop-deleted-content"
+	TaskIndexCompaction              = "index-compaction"
+	TaskExtendBlobRetentionTimeFull  = "extend-blob-retention-time"
+	TaskCleanupLogs                  = "cleanup-logs"
+	TaskCleanupEpochManager          = "cleanup-epoch-manager"
+	TaskScrub                        = "scrub"
+	TaskRepositoryStats              = "repository-stats"
+	TaskSourceStorageUsage           = "source-storage-usage"
 )
 
 // shouldRun returns Mode if repository is due for periodic maintenance.
@@ -60,6 +68,11 @@ func shouldRun(ctx context.Context, rep repo.DirectRepository, p *Params) (Mode,
 		return ModeNone, errors.Wrap(err, "error getting status")
 	}
 
+	if s.InterruptedRun != nil {
+		log(ctx).Debugf("resuming interrupted %v maintenance cycle", s.InterruptedRun.Mode)
+		return s.InterruptedRun.Mode, nil
+	}
+
 	// check full cycle first, as it does more than the quick cycle
 	if p.FullCycle.Enabled {
 		if !rep.Time().Before(s.NextFullMaintenanceTime) {
@@ -87,7 +100,7 @@ func shouldRun(ctx context.Context, rep repo.DirectRepository, p *Params) (Mode,
 	return ModeNone, nil
 }
 
-func updateSchedule(ctx context.Context, runParams RunParameters) error {
+func updateSchedule(ctx context.Context, runParams *RunParameters) error {
 	rep := runParams.rep
 	p := runParams.Params
 
@@ -96,6 +109,21 @@ func updateSchedule(ctx context.Context, runParams RunParameters) error {
 		return errors.Wrap(err, "error getting schedule")
 	}
 
+	// full cycle supersedes interrupted quick cycle.
+	if ir := s.InterruptedRun; ir != nil && (ir.Mode == runParams.Mode || runParams.Mode == ModeFull) {
+		s.InterruptedRun = nil
+
+		if ir.Mode == runParams.Mode {
+			// resuming interrupted cycle, which has already been scheduled when it started.
+			log(ctx).Infof("Resuming %v maintenance interrupted at %v.", ir.Mode, ir.Start.Format(time.RFC3339))
+
+			runParams.cycleStart = ir.Start
+			runParams.resumed = true
+
+			return SetSchedule(ctx, rep, s)
+		}
+	}
+
 	switch runParams.Mode {
 	case ModeFull:
 		// on full cycle, also update the quick cycle
@@ -129,6 +157,96 @@ type RunParameters struct {
 
 	// timestamp of the last update of maintenance schedule blob
 	MaintenanceStartTime time.Time
+
+	// Deadline after which no new maintenance tasks are started, zero if unlimited.
+	Deadline time.Time
+
+	// start time of the maintenance cycle, which may have been started by an earlier, interrupted run.
+	cycleStart time.Time
+	resumed    bool
+}
+
+// ErrDeadlineExceeded is returned when maintenance tasks are not started because the maintenance
+// run has exceeded its deadline. The remaining tasks are resumed during the next run.
+var ErrDeadlineExceeded = errors.New("maintenance deadline exceeded")
+
+// DeadlineExceeded returns true if maintenance run has exceeded its deadline and must not start new tasks.
+func (r RunParameters) DeadlineExceeded() bool {
+	return !r.Deadline.IsZero() && !r.rep.Time().Before(r.Deadline)
+}
+
+// TaskCompleted returns true if the provided task has already successfully completed as part of
+// the interrupted maintenance cycle, which is being resumed.
+func (r RunParameters) TaskCompleted(ctx context.Context, taskType TaskType) (bool, error) {
+	if !r.resumed {
+		return false, nil
+	}
+
+	s, err := GetSchedule(ctx, r.rep)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to get schedule")
+	}
+
+	return r.completedInSchedule(s, taskType), nil
+}
+
+// completedInSchedule returns true if the schedule records successful completion of the task as part of
+// the interrupted maintenance cycle, which is being resumed.
+func (r RunParameters) completedInSchedule(s *Schedule, taskType TaskType) bool {
+	if !r.resumed {
+		return false
+	}
+
+	for _, ri := range s.Runs[taskType] {
+		if ri.Success && !ri.Start.Before(r.cycleStart) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runTask runs maintenance task of the cycle using ReportRunAndStats, unless the task has already
+// completed in the interrupted cycle being resumed, so that resumed cycles make progress.
+func (r RunParameters) runTask(ctx context.Context, taskType TaskType, s *Schedule, run func(ctx context.Context) (map[string]int64, error)) error {
+	if r.completedInSchedule(s, taskType) {
+		log(ctx).Infof("Skipping %v, which has already completed in the interrupted maintenance cycle.", taskType)
+		return nil
+	}
+
+	return ReportRunAndStats(ctx, r.rep, taskType, s, run)
+}
+
+// runTaskWithoutStats is like runTask for tasks which don't report statistics.
+func (r RunParameters) runTaskWithoutStats(ctx context.Context, taskType TaskType, s *Schedule, run func(ctx context.Context) error) error {
+	return r.runTask(ctx, taskType, s, func(ctx context.Context) (map[string]int64, error) {
+		return nil, run(ctx)
+	})
+}
+
+func (r RunParameters) checkDeadline() error {
+	if r.DeadlineExceeded() {
+		return ErrDeadlineExceeded
+	}
+
+	return nil
+}
+
+// maintenanceDeadline returns the deadline of maintenance run starting at the provided time.
+func maintenanceDeadline(p *Params, windows []*cronexpr.Expression, now time.Time) time.Time {
+	var deadline time.Time
+
+	if p.MaxRuntime > 0 {
+		deadline = now.Add(p.MaxRuntime)
+	}
+
+	if inWindow(windows, now) {
+		if we := windowEnd(windows, now); !we.IsZero() && (deadline.IsZero() || we.Before(deadline)) {
+			deadline = we
+		}
+	}
+
+	return deadline
 }
 
 // NotOwnedError is returned when maintenance cannot run because it is owned by another user.
@@ -157,6 +275,17 @@ func RunExclusive(ctx context.Context, rep repo.DirectRepositoryWriter, mode Mod
 		return NotOwnedError{p.Owner}
 	}
 
+	windows := parseWindows(p.Windows)
+
+	if !inWindow(windows, rep.Time()) {
+		if mode == ModeAuto {
+			log(ctx).Debugf("outside of maintenance window")
+			return nil
+		}
+
+		log(ctx).Infof("Running maintenance outside of maintenance window.")
+	}
+
 	if mode == ModeAuto {
 		mode, err = shouldRun(ctx, rep, p)
 		if err != nil {
@@ -169,15 +298,9 @@ func RunExclusive(ctx context.Context, rep repo.DirectRepositoryWriter, mode Mod
 		return nil
 	}
 
-	lockFile := rep.ConfigFilename() + ".mlock"
-	log(ctx).Debugf("Acquiring maintenance lock in file %v", lockFile)
-
-	// acquire local lock on a config file
-	l := flock.New(lockFile)
-
-	ok, err := l.TryLock()
+	l, ok, err := tryLockMaintenance(ctx, rep)
 	if err != nil {
-		return errors.Wrap(err, "error acquiring maintenance lock")
+		return err
 	}
 
 	if !ok {
@@ -187,11 +310,17 @@ func RunExclusive(ctx context.Context, rep repo.DirectRepositoryWriter, mode Mod
 
 	defer l.Unlock() //nolint:errcheck
 
-	runParams := RunParameters{rep, mode, p, time.Time{}}
+	runParams := RunParameters{
+		rep:        rep,
+		Mode:       mode,
+		Params:     p,
+		Deadline:   maintenanceDeadline(p, windows, rep.Time()),
+		cycleStart: rep.Time(),
+	}
 
 	// update schedule so that we don't run the maintenance again immediately if
 	// this process crashes.
-	if err = updateSchedule(ctx, runParams); err != nil {
+	if err = updateSchedule(ctx, &runParams); err != nil {
 		return errors.Wrap(err, "error updating maintenance schedule")
 	}
 
@@ -209,11 +338,57 @@ func RunExclusive(ctx context.Context, rep repo.DirectRepositoryWriter, mode Mod
 	log(ctx).Infof("Running %v maintenance...", runParams.Mode)
 	defer log(ctx).Infof("Finished %v maintenance.", runParams.Mode)
 
+	if !runParams.Deadline.IsZero() {
+		log(ctx).Infof("Maintenance will not start new tasks after %v.", runParams.Deadline.Format(time.RFC3339))
+	}
+
 	if err := runParams.rep.Refresh(ctx); err != nil {
 		return errors.Wrap(err, "error refreshing indexes before maintenance")
 	}
 
-	return cb(ctx, runParams)
+	if err := cb(ctx, runParams); !errors.Is(err, ErrDeadlineExceeded) {
+		return err
+	}
+
+	return interruptMaintenance(ctx, runParams)
+}
+
+// tryLockMaintenance acquires local maintenance lock on the repository config file, which prevents
+// maintenance tasks from running concurrently. Returns false if the lock is already held.
+func tryLockMaintenance(ctx context.Context, rep repo.DirectRepositoryWriter) (*flock.Flock, bool, error) {
+	lockFile := rep.ConfigFilename() + ".mlock"
+	log(ctx).Debugf("Acquiring maintenance lock in file %v", lockFile)
+
+	l := flock.New(lockFile)
+
+	ok, err := l.TryLock()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "error acquiring maintenance lock")
+	}
+
+	return l, ok, nil
+}
+
+// interruptMaintenance records maintenance cycle which has exceeded its deadline so that
+// the remaining tasks are resumed during the next run.
+func interruptMaintenance(ctx context.Context, runParams RunParameters) error {
+	s, err := GetSchedule(ctx, runParams.rep)
+	if err != nil {
+		return errors.Wrap(err, "unable to get schedule")
+	}
+
+	s.InterruptedRun = &InterruptedRun{
+		Mode:  runParams.Mode,
+		Start: runParams.cycleStart,
+	}
+
+	if err := SetSchedule(ctx, runParams.rep, s); err != nil {
+		return errors.Wrap(err, "unable to set schedule")
+	}
+
+	log(ctx).Infof("Maintenance time limit reached, remaining %v maintenance tasks will be resumed during the next run.", runParams.Mode)
+
+	return nil
 }
 
 func checkClockSkewBounds(rp RunParameters) error {
@@ -262,6 +437,10 @@ func runQuickMaintenance(ctx context.Context, runParams RunParameters, safety Sa
 		return errors.Wrap(err, "unable to get schedule")
 	}
 
+	if err := runParams.checkDeadline(); err != nil {
+		return err
+	}
+
 	if shouldQuickRewriteContents(s, safety) {
 		// find 'q' packs that are less than 80% full and rewrite contents in them into
 		// new consolidated packs, orphaning old packs in the process.
@@ -272,6 +451,10 @@ func runQuickMaintenance(ctx context.Context, runParams RunParameters, safety Sa
 		notRewritingContents(ctx)
 	}
 
+	if err := runParams.checkDeadline(); err != nil {
+		return err
+	}
+
 	if shouldDeleteOrphanedPacks(runParams.rep.Time(), s, safety) {
 		var err error
 
@@ -294,11 +477,19 @@ func runQuickMaintenance(ctx context.Context, runParams RunParameters, safety Sa
 		notDeletingOrphanedBlobs(ctx, s, safety)
 	}
 
+	if err := runParams.checkDeadline(); err != nil {
+		return err
+	}
+
 	// consolidate many smaller indexes into fewer larger ones.
 	if err := runTaskIndexCompactionQuick(ctx, runParams, s, safety); err != nil {
 		return errors.Wrap(err, "error performing index compaction")
 	}
 
+	if err := runParams.checkDeadline(); err != nil {
+		return err
+	}
+
 	if err := runTaskCleanupLogs(ctx, runParams, s); err != nil {
 		return errors.Wrap(err, "error cleaning up logs")
 	}
@@ -317,12 +508,21 @@ func notDeletingOrphanedBlobs(ctx context.Context, s *Schedule, safety SafetyPar
 }
 
 func runTaskCleanupLogs(ctx context.Context, runParams RunParameters, s *Schedule) error {
-	return ReportRun(ctx, runParams.rep, TaskCleanupLogs, s, func() error {
+	return runParams.runTask(ctx, TaskCleanupLogs, s, func(ctx context.Context) (map[string]int64, error) {
 		deleted, err := CleanupLogs(ctx, runParams.rep, runParams.Params.LogRetention.OrDefault())
 
 		log(ctx).Infof("Cleaned up %v logs.", len(deleted))
 
-		return err
+		var deletedBytes int64
+
+		for _, bm := range deleted {
+			deletedBytes += bm.Length
+		}
+
+		return map[string]int64{
+			"deletedLogs":  int64(len(deleted)),
+			"deletedBytes": deletedBytes,
+		}, err
 	})
 }
 
@@ -336,7 +536,7 @@ func runTaskCleanupEpochManager(ctx context.Context, runParams RunParameters, s
 		return nil
 	}
 
-	return ReportRun(ctx, runParams.rep, TaskCleanupEpochManager, s, func() error {
+	return runParams.runTaskWithoutStats(ctx, TaskCleanupEpochManager, s, func(ctx context.Context) error {
 		log(ctx).Infof("Cleaning up old index blobs which have already been compacted...")
 		return errors.Wrap(em.CleanupSupersededIndexes(ctx), "error cleaning up superseded index blobs")
 	})
@@ -358,78 +558,142 @@ func runTaskDropDeletedContentsFull(ctx context.Context, runParams RunParameters
 
 	log(ctx).Infof("Found safe time to drop indexes: %v", safeDropTime)
 
-	return ReportRun(ctx, runParams.rep, TaskDropDeletedContentsFull, s, func() error {
+	return runParams.runTaskWithoutStats(ctx, TaskDropDeletedContentsFull, s, func(ctx context.Context) error {
 		return DropDeletedContents(ctx, runParams.rep, safeDropTime, safety)
 	})
 }
 
 func runTaskRewriteContentsQuick(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
-	return ReportRun(ctx, runParams.rep, TaskRewriteContentsQuick, s, func() error {
-		return RewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
+	return runParams.runTask(ctx, TaskRewriteContentsQuick, s, func(ctx context.Context) (map[string]int64, error) {
+		rewritten, err := rewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
 			ContentIDRange: index.AllPrefixedIDs,
 			PackPrefix:     content.PackBlobIDPrefixSpecial,
 			ShortPacks:     true,
+			Deadline:       runParams.Deadline,
 		}, safety)
+
+		return rewriteStats(rewritten), err
 	})
 }
 
 func runTaskRewriteContentsFull(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
-	return ReportRun(ctx, runParams.rep, TaskRewriteContentsFull, s, func() error {
-		return RewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
+	return runParams.runTask(ctx, TaskRewriteContentsFull, s, func(ctx context.Context) (map[string]int64, error) {
+		if runParams.Params.Partitions > 1 {
+			counters, err := RunPartitioned(ctx, runParams, TaskRewriteContentsFull, safety, rewriteContentsPartition)
+			if err != nil {
+				return nil, err
+			}
+
+			log(ctx).Infof("Total bytes rewritten by all partitions %v", units.BytesString(counters["bytes"]))
+
+			return map[string]int64{
+				"rewrittenContents": counters["contents"],
+				"rewrittenBytes":    counters["bytes"],
+			}, nil
+		}
+
+		rewritten, err := rewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
 			ContentIDRange: index.AllIDs,
 			ShortPacks:     true,
+			Deadline:       runParams.Deadline,
 		}, safety)
+
+		return rewriteStats(rewritten), err
 	})
 }
 
 func runTaskDeleteOrphanedBlobsFull(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
-	return ReportRun(ctx, runParams.rep, TaskDeleteOrphanedBlobsFull, s, func() error {
-		_, err := DeleteUnreferencedBlobs(ctx, runParams.rep, DeleteUnreferencedBlobsOptions{
+	return runParams.runTask(ctx, TaskDeleteOrphanedBlobsFull, s, func(ctx context.Context) (map[string]int64, error) {
+		return deleteBlobsStats(deleteUnreferencedBlobs(ctx, runParams.rep, DeleteUnreferencedBlobsOptions{
 			NotAfterTime: runParams.MaintenanceStartTime,
-		}, safety)
-		return err
+		}, safety))
 	})
 }
 
 func runTaskDeleteOrphanedBlobsQuick(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
-	return ReportRun(ctx, runParams.rep, TaskDeleteOrphanedBlobsQuick, s, func() error {
-		_, err := DeleteUnreferencedBlobs(ctx, runParams.rep, DeleteUnreferencedBlobsOptions{
+	return runParams.runTask(ctx, TaskDeleteOrphanedBlobsQuick, s, func(ctx context.Context) (map[string]int64, error) {
+		return deleteBlobsStats(deleteUnreferencedBlobs(ctx, runParams.rep, DeleteUnreferencedBlobsOptions{
 			NotAfterTime: runParams.MaintenanceStartTime,
 			Prefix:       content.PackBlobIDPrefixSpecial,
-		}, safety)
-		return err
+		}, safety))
 	})
 }
 
 func runTaskExtendBlobRetentionTimeFull(ctx context.Context, runParams RunParameters, s *Schedule) error {
-	return ReportRun(ctx, runParams.rep, TaskExtendBlobRetentionTimeFull, s, func() error {
-		_, err := ExtendBlobRetentionTime(ctx, runParams.rep, ExtendBlobRetentionTimeOptions{})
-		return err
+	return runParams.runTask(ctx, TaskExtendBlobRetentionTimeFull, s, func(ctx context.Context) (map[string]int64, error) {
+		cnt, err := ExtendBlobRetentionTime(ctx, runParams.rep, ExtendBlobRetentionTimeOptions{})
+
+		return map[string]int64{
+			"extendedBlobs": int64(cnt),
+		}, err
 	})
 }
 
+func rewriteStats(rewritten *stats.CountSum) map[string]int64 {
+	if rewritten == nil {
+		return nil
+	}
+
+	cnt, size := rewritten.Approximate()
+
+	return map[string]int64{
+		"rewrittenContents": int64(cnt),
+		"rewrittenBytes":    size,
+	}
+}
+
+func deleteBlobsStats(cnt int, size int64, err error) (map[string]int64, error) {
+	return map[string]int64{
+		"deletedBlobs": int64(cnt),
+		"deletedBytes": size,
+	}, err
+}
+
 func runFullMaintenance(ctx context.Context, runParams RunParameters, safety SafetyParameters) error {
 	s, err := GetSchedule(ctx, runParams.rep)
 	if err != nil {
 		return errors.Wrap(err, "unable to get schedule")
 	}
 
+	if err := runParams.checkDeadline(); err != nil {
+		return err
+	}
+
 	if shouldFullRewriteContents(s, safety) {
 		// find packs that are less than 80% full and rewrite contents in them into
 		// new consolidated packs, orphaning old packs in the process.
 		if err := runTaskRewriteContentsFull(ctx, runParams, s, safety); err != nil {
 			return errors.Wrap(err, "error rewriting contents in short packs")
 		}
+
+		if runParams.Params.PackCompaction.LiveDataThresholdPercent > 0 {
+			if err := runParams.checkDeadline(); err != nil {
+				return err
+			}
+
+			// rewrite live contents of packs which mostly contain deleted contents.
+			if err := runTaskCompactPacks(ctx, runParams, s, safety); err != nil {
+				return errors.Wrap(err, "error compacting packs")
+			}
+		}
 	} else {
 		notRewritingContents(ctx)
 	}
 
+	if err := runParams.checkDeadline(); err != nil {
+		return err
+	}
+
 	// rewrite indexes by dropping content entries that have been marked
 	// as deleted for a long time
 	if err := runTaskDropDeletedContentsFull(ctx, runParams, s, safety); err != nil {
 		return errors.Wrap(err, "error dropping deleted contents")
 	}
 
+	if err := runParams.checkDeadline(); err != nil {
+		return err
+	}
+
 	if shouldDeleteOrphanedPacks(runParams.rep.Time(), s, safety) {
 		// delete orphaned packs after some time.
 		if err := runTaskDeleteOrphanedBlobsFull(ctx, runParams, s, safety); err != nil {
@@ -439,6 +703,10 @@ func runFullMaintenance(ctx context.Context, runParams RunParameters, safety Saf
 		notDeletingOrphanedBlobs(ctx, s, safety)
 	}
 
+	if err := runParams.checkDeadline(); err != nil {
+		return err
+	}
+
 	// extend retention-time on supported storage.
 	if runParams.Params.ExtendObjectLocks {
 		if err := runTaskExtendBlobRetentionTimeFull(ctx, runParams, s); err != nil {
@@ -448,10 +716,18 @@ func runFullMaintenance(ctx context.Context, runParams RunParameters, safety Saf
 		log(ctx).Debug("Extending object lock retention-period is disabled.")
 	}
 
+	if err := runParams.checkDeadline(); err != nil {
+		return err
+	}
+
 	if err := runTaskCleanupLogs(ctx, runParams, s); err != nil {
 		return errors.Wrap(err, "error cleaning up logs")
 	}
 
+	if err := runParams.checkDeadline(); err != nil {
+		return err
+	}
+
 	if err := runTaskCleanupEpochManager(ctx, runParams, s); err != nil {
 		return errors.Wrap(err, "error cleaning up epoch manager")
 	}
@@ -463,7 +739,7 @@ func runFullMaintenance(ctx context.Context, runParams RunParameters, safety Saf
 // since each content rewrite will require deleting of orphaned blobs after some time passes,
 // we don't want to starve blob deletion by constantly doing rewrites.
 func shouldQuickRewriteContents(s *Schedule, safety SafetyParameters) bool {
-	latestContentRewriteEndTime := maxEndTime(s.Runs[TaskRewriteContentsFull], s.Runs[TaskRewriteContentsQuick])
+	latestContentRewriteEndTime := maxEndTime(s.Runs[TaskRewriteContentsFull], s.Runs[TaskCompactPacks], s.Runs[TaskRewriteContentsQuick])
 	latestBlobDeleteTime := maxEndTime(s.Runs[TaskDeleteOrphanedBlobsFull], s.Runs[TaskDeleteOrphanedBlobsQuick])
 
 	// never did rewrite - safe to do so.
@@ -480,7 +756,7 @@ func shouldQuickRewriteContents(s *Schedule, safety SafetyParameters) bool {
 func shouldFullRewriteContents(s *Schedule, safety SafetyParameters) bool {
 	// NOTE - we're not looking at TaskRewriteContentsQuick here, this allows full rewrite to sometimes
 	// follow quick rewrite.
-	latestContentRewriteEndTime := maxEndTime(s.Runs[TaskRewriteContentsFull])
+	latestContentRewriteEndTime := maxEndTime(s.Runs[TaskRewriteContentsFull], s.Runs[TaskCompactPacks])
 	latestBlobDeleteTime := maxEndTime(s.Runs[TaskDeleteOrphanedBlobsFull], s.Runs[TaskDeleteOrphanedBlobsQuick])
 
 	// never did rewrite - safe to do so.
@@ -501,7 +777,7 @@ func shouldDeleteOrphanedPacks(now time.Time, s *Schedule, safety SafetyParamete
 }
 
 func nextBlobDeleteTime(s *Schedule, safety SafetyParameters) time.Time {
-	latestContentRewriteEndTime := maxEndTime(s.Runs[TaskRewriteContentsFull], s.Runs[TaskRewriteContentsQuick])
+	latestContentRewriteEndTime := maxEndTime(s.Runs[TaskRewriteContentsFull], s.Runs[TaskCompactPacks], s.Runs[TaskRewriteContentsQuick], scrubRewriteRuns(s.Runs[TaskScrub]))
 	if latestContentRewriteEndTime.IsZero() {
 		return time.Time{}
 	}
@@ -510,7 +786,7 @@ func nextBlobDeleteTime(s *Schedule, safety SafetyParameters) time.Time {
 }
 
 func hadRecentFullRewrite(s *Schedule) bool {
-	return !maxEndTime(s.Runs[TaskRewriteContentsFull]).Before(maxEndTime(s.Runs[TaskRewriteContentsQuick]))
+	return !maxEndTime(s.Runs[TaskRewriteContentsFull], s.Runs[TaskCompactPacks]).Before(maxEndTime(s.Runs[TaskRewriteContentsQuick]))
 }
 
 func maxEndTime(taskRuns ...[]RunInfo) time.Time {
@@ -13,6 +13,7 @@
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/throttling"
 )
 
 const (
@@ -26,8 +27,17 @@
 	maintenanceScheduleAEADExtraData = []byte("maintenance")
 )
 
-// maxRetainedRunInfoPerRunType the maximum number of retained RunInfo entries per run type.
-const maxRetainedRunInfoPerRunType = 5
+// minRetainedRunInfoPerRunType the minimum number of retained RunInfo entries per run type,
+// regardless of history retention, since they are used to determine maintenance safety.
+const minRetainedRunInfoPerRunType = 5
+
+const (
+	// taskBudgetThrottlingWindow is the duration window during which the token buckets of task budgets fully replenish.
+	taskBudgetThrottlingWindow = 10 * time.Second
+
+	// taskBudgetInitialFill is the fraction of task budget available immediately when the task starts.
+	taskBudgetInitialFill = 0.1
+)
 
 // RunInfo represents information about a single run of a maintenance task.
 type RunInfo struct {
@@ -35,6 +45,9 @@ type RunInfo struct {
 	End     time.Time `json:"end"`
 	Success bool      `json:"success,omitempty"`
 	Error   string    `json:"error,omitempty"`
+
+	// Stats contains task-specific statistics, such as the number of bytes freed.
+	Stats map[string]int64 `json:"stats,omitempty"`
 }
 
 // Schedule keeps track of scheduled maintenance times.
@@ -43,10 +56,27 @@ type Schedule struct {
 	NextQuickMaintenanceTime time.Time `json:"nextQuickMaintenance"`
 
 	Runs map[TaskType][]RunInfo `json:"runs"`
+
+	// InterruptedRun describes maintenance run which has exceeded its time limit and
+	// will be resumed during the next run.
+	InterruptedRun *InterruptedRun `json:"interruptedRun,omitempty"`
+
+	// Scrub records the progress of incremental repository scrub.
+	Scrub *ScrubProgress `json:"scrub,omitempty"`
 }
 
-// ReportRun adds the provided run information to the history and discards oldest entried.
+// InterruptedRun describes maintenance run which has been interrupted before all of its tasks completed.
+type InterruptedRun struct {
+	Mode  Mode      `json:"mode"`
+	Start time.Time `json:"start"`
+}
+
+// ReportRun adds the provided run information to the history and discards entries beyond default history retention.
 func (s *Schedule) ReportRun(taskType TaskType, info RunInfo) {
+	s.reportRun(taskType, info, defaultHistoryRetention())
+}
+
+func (s *Schedule) reportRun(taskType TaskType, info RunInfo, retention HistoryRetentionOptions) {
 	if s.Runs == nil {
 		s.Runs = map[TaskType][]RunInfo{}
 	}
@@ -54,11 +84,7 @@ func (s *Schedule) ReportRun(taskType TaskType, info RunInfo) {
 	// insert as first item
 	history := append([]RunInfo{info}, s.Runs[taskType]...)
 
-	if len(history) > maxRetainedRunInfoPerRunType {
-		history = history[0:maxRetainedRunInfoPerRunType]
-	}
-
-	s.Runs[taskType] = history
+	s.Runs[taskType] = trimHistory(history, retention, info.End)
 }
 
 func getAES256GCM(rep repo.DirectRepository) (cipher.AEAD, error) {
@@ -91,6 +117,10 @@ func TimeToAttemptNextMaintenance(ctx context.Context, rep repo.DirectRepository
 
 	nextMaintenanceTime := max
 
+	if ms.InterruptedRun != nil {
+		nextMaintenanceTime = rep.Time()
+	}
+
 	if mp.FullCycle.Enabled {
 		if ms.NextFullMaintenanceTime.Before(nextMaintenanceTime) {
 			nextMaintenanceTime = ms.NextFullMaintenanceTime
@@ -103,6 +133,18 @@ func TimeToAttemptNextMaintenance(ctx context.Context, rep repo.DirectRepository
 		}
 	}
 
+	// postpone maintenance until the next window, unless it's already open.
+	if windows := parseWindows(mp.Windows); len(windows) > 0 {
+		if now := rep.Time(); nextMaintenanceTime.Before(now) {
+			nextMaintenanceTime = now
+		}
+
+		nextMaintenanceTime = nextWindowStart(windows, nextMaintenanceTime)
+		if nextMaintenanceTime.IsZero() || nextMaintenanceTime.After(max) {
+			nextMaintenanceTime = max
+		}
+	}
+
 	return nextMaintenanceTime, nil
 }
 
@@ -174,8 +216,48 @@ func SetSchedule(ctx context.Context, rep repo.DirectRepositoryWriter, s *Schedu
 	return rep.BlobStorage().PutBlob(ctx, maintenanceScheduleBlobID, gather.FromSlice(ciphertext), blob.PutOptions{})
 }
 
+// withTaskBudget returns a context in which storage operations are additionally throttled according to
+// the budget of the provided task. The repository throttler is not modified, so operations which are not
+// part of the task are not affected.
+func withTaskBudget(ctx context.Context, p *Params, taskType TaskType) context.Context {
+	budget, ok := p.TaskBudgets[taskType]
+	if !ok {
+		return ctx
+	}
+
+	log(ctx).Debugf("applying %v budget: %+v", taskType, budget)
+
+	th, err := throttling.NewThrottler(budget, taskBudgetThrottlingWindow, taskBudgetInitialFill)
+	if err != nil {
+		log(ctx).Errorf("unable to apply %v budget: %v", taskType, err)
+		return ctx
+	}
+
+	return throttling.WithThrottler(ctx, th)
+}
+
 // ReportRun reports timing of a maintenance run and persists it in repository.
-func ReportRun(ctx context.Context, rep repo.DirectRepositoryWriter, taskType TaskType, s *Schedule, run func() error) error {
+func ReportRun(ctx context.Context, rep repo.DirectRepositoryWriter, taskType TaskType, s *Schedule, run func(ctx context.Context) error) error {
+	return ReportRunAndStats(ctx, rep, taskType, s, func(ctx context.Context) (map[string]int64, error) {
+		return nil, run(ctx)
+	})
+}
+
+// ReportRunAndStats reports timing and statistics of a maintenance run and persists it in repository.
+// The run is skipped if the task has been disabled or it's not due yet according to its interval.
+// The context passed to run is throttled according to the budget of the task.
+func ReportRunAndStats(ctx context.Context, rep repo.DirectRepositoryWriter, taskType TaskType, s *Schedule, run func(ctx context.Context) (map[string]int64, error)) error {
+	return reportRunAndStats(ctx, rep, taskType, s, true, run)
+}
+
+// reportRunAndStats implements ReportRunAndStats, explicitly requested runs pass skipIfNotDue=false,
+// so that they are only subject to the budget of the task.
+func reportRunAndStats(ctx context.Context, rep repo.DirectRepositoryWriter, taskType TaskType, s *Schedule, skipIfNotDue bool, run func(ctx context.Context) (map[string]int64, error)) error {
+	p, err := GetParams(ctx, rep)
+	if err != nil {
+		return errors.Wrap(err, "unable to get maintenance params")
+	}
+
 	if s == nil {
 		var err error
 
@@ -185,13 +267,18 @@ func ReportRun(ctx context.Context, rep repo.DirectRepositoryWriter, taskType Ta
 		}
 	}
 
+	if skipIfNotDue && !p.isTaskDue(ctx, taskType, s, rep.Time()) {
+		return nil
+	}
+
 	ri := RunInfo{
 		Start: rep.Time(),
 	}
 
-	runErr := run()
+	stats, runErr := run(withTaskBudget(ctx, p, taskType))
 
 	ri.End = rep.Time()
+	ri.Stats = stats
 
 	if runErr != nil {
 		ri.Error = runErr.Error()
@@ -199,7 +286,7 @@ func ReportRun(ctx context.Context, rep repo.DirectRepositoryWriter, taskType Ta
 		ri.Success = true
 	}
 
-	s.ReportRun(taskType, ri)
+	s.reportRun(taskType, ri, p.HistoryRetention.OrDefault())
 
 	if err := SetSchedule(ctx, rep, s); err != nil {
 		log(ctx).Errorf("unable to report run: %v", err)
@@ -1,7 +1,9 @@
 package maintenance_test
 
 import (
+	"context"
 	"encoding/json"
+	"sort"
 	"testing"
 	"time"
 
@@ -10,6 +12,9 @@
 
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/timetrack"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/throttling"
 	"github.com/kopia/kopia/repo/maintenance"
 )
 
@@ -51,6 +56,278 @@ func (s *formatSpecificTestSuite) TestMaintenanceSchedule(t *testing.T) {
 	}
 }
 
+func (s *formatSpecificTestSuite) TestMaintenanceInterruption(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, s.formatVersion)
+
+	const testTask maintenance.TaskType = "test-task"
+
+	setParams := func(p maintenance.Params) {
+		t.Helper()
+
+		p.Owner = env.Repository.ClientOptions().UsernameAtHost()
+		p.FullCycle = maintenance.CycleParams{Enabled: true, Interval: 24 * time.Hour}
+		p.QuickCycle = maintenance.CycleParams{Enabled: true, Interval: time.Hour}
+
+		require.NoError(t, maintenance.SetParams(ctx, env.RepositoryWriter, &p))
+	}
+
+	// outside of the maintenance window automatic maintenance does not run.
+	setParams(maintenance.Params{Windows: []string{"* * 31 2 *"}})
+	require.NoError(t, maintenance.RunExclusive(ctx, env.RepositoryWriter, maintenance.ModeAuto, false, func(ctx context.Context, runParams maintenance.RunParameters) error {
+		t.Fatal("unexpected maintenance run")
+		return nil
+	}))
+
+	// full maintenance exceeding its runtime.
+	setParams(maintenance.Params{MaxRuntime: time.Nanosecond})
+	require.NoError(t, maintenance.RunExclusive(ctx, env.RepositoryWriter, maintenance.ModeFull, false, func(ctx context.Context, runParams maintenance.RunParameters) error {
+		require.False(t, runParams.Deadline.IsZero())
+		require.NoError(t, maintenance.ReportRun(ctx, env.RepositoryWriter, testTask, nil, func(ctx context.Context) error { return nil }))
+
+		return maintenance.Run(ctx, runParams, maintenance.SafetyFull)
+	}))
+
+	sch, err := maintenance.GetSchedule(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.NotNil(t, sch.InterruptedRun)
+	require.Equal(t, maintenance.ModeFull, sch.InterruptedRun.Mode)
+
+	nmt, err := maintenance.TimeToAttemptNextMaintenance(ctx, env.RepositoryWriter, clock.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.False(t, nmt.After(clock.Now()))
+
+	// next automatic run resumes the interrupted cycle, skipping completed tasks.
+	setParams(maintenance.Params{})
+
+	resumed := false
+
+	require.NoError(t, maintenance.RunExclusive(ctx, env.RepositoryWriter, maintenance.ModeAuto, false, func(ctx context.Context, runParams maintenance.RunParameters) error {
+		resumed = true
+
+		require.Equal(t, maintenance.ModeFull, runParams.Mode)
+		require.True(t, runParams.Deadline.IsZero())
+
+		completed, err := runParams.TaskCompleted(ctx, testTask)
+		require.NoError(t, err)
+		require.True(t, completed)
+
+		completed, err = runParams.TaskCompleted(ctx, maintenance.TaskSnapshotGarbageCollection)
+		require.NoError(t, err)
+		require.False(t, completed)
+
+		return maintenance.Run(ctx, runParams, maintenance.SafetyFull)
+	}))
+
+	require.True(t, resumed)
+
+	sch, err = maintenance.GetSchedule(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Nil(t, sch.InterruptedRun)
+	require.NotEmpty(t, sch.Runs[maintenance.TaskCleanupLogs])
+
+	// no longer due for maintenance.
+	require.NoError(t, maintenance.RunExclusive(ctx, env.RepositoryWriter, maintenance.ModeAuto, false, func(ctx context.Context, runParams maintenance.RunParameters) error {
+		t.Fatal("unexpected maintenance run")
+		return nil
+	}))
+}
+
+func (s *formatSpecificTestSuite) TestMaintenanceResumeSkipsCompletedTasks(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, s.formatVersion)
+
+	p := maintenance.DefaultParams()
+	p.Owner = env.Repository.ClientOptions().UsernameAtHost()
+	require.NoError(t, maintenance.SetParams(ctx, env.RepositoryWriter, &p))
+
+	runFull := func() {
+		t.Helper()
+
+		require.NoError(t, maintenance.RunExclusive(ctx, env.RepositoryWriter, maintenance.ModeFull, false, func(ctx context.Context, runParams maintenance.RunParameters) error {
+			return maintenance.Run(ctx, runParams, maintenance.SafetyNone)
+		}))
+	}
+
+	// find tasks of the full cycle in the order in which they run.
+	runFull()
+
+	sch, err := maintenance.GetSchedule(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+
+	var tasks []maintenance.TaskType
+
+	for tt := range sch.Runs {
+		tasks = append(tasks, tt)
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return sch.Runs[tasks[i]][0].Start.Before(sch.Runs[tasks[j]][0].Start)
+	})
+
+	require.Contains(t, tasks, maintenance.TaskType(maintenance.TaskDeleteOrphanedBlobsFull))
+	require.Contains(t, tasks, maintenance.TaskType(maintenance.TaskCleanupLogs))
+
+	// resume cycle interrupted after each task, completed tasks must not run again.
+	for completed := range tasks {
+		sch, err := maintenance.GetSchedule(ctx, env.RepositoryWriter)
+		require.NoError(t, err)
+
+		cycleStart := env.RepositoryWriter.Time()
+		marker := maintenance.RunInfo{Start: cycleStart, End: cycleStart, Success: true, Stats: map[string]int64{"marker": int64(completed)}}
+
+		sch.InterruptedRun = &maintenance.InterruptedRun{Mode: maintenance.ModeFull, Start: cycleStart}
+
+		for _, tt := range tasks[:completed] {
+			sch.ReportRun(tt, marker)
+		}
+
+		require.NoError(t, maintenance.SetSchedule(ctx, env.RepositoryWriter, sch))
+
+		runFull()
+
+		sch, err = maintenance.GetSchedule(ctx, env.RepositoryWriter)
+		require.NoError(t, err)
+		require.Nil(t, sch.InterruptedRun)
+
+		for i, tt := range tasks {
+			if i < completed {
+				require.Equal(t, marker.Stats, sch.Runs[tt][0].Stats, "task %v completed before interruption after %v tasks was run again", tt, completed)
+			} else {
+				require.NotEqual(t, marker.Stats, sch.Runs[tt][0].Stats, "task %v not run after interruption after %v tasks", tt, completed)
+				require.True(t, sch.Runs[tt][0].Start.After(cycleStart) || sch.Runs[tt][0].Start.Equal(cycleStart))
+			}
+		}
+	}
+}
+
+func (s *formatSpecificTestSuite) TestPerTaskScheduling(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, s.formatVersion)
+
+	const (
+		disabledTask maintenance.TaskType = "disabled-task"
+		hourlyTask   maintenance.TaskType = "hourly-task"
+		otherTask    maintenance.TaskType = "other-task"
+	)
+
+	p := maintenance.DefaultParams()
+	p.Tasks = map[maintenance.TaskType]maintenance.TaskParams{
+		disabledTask: {Disabled: true},
+		hourlyTask:   {Interval: time.Hour},
+	}
+	p.HistoryRetention = maintenance.HistoryRetentionOptions{MaxCount: 7}
+
+	require.NoError(t, maintenance.SetParams(ctx, env.RepositoryWriter, &p))
+
+	runCount := map[maintenance.TaskType]int{}
+
+	run := func(task maintenance.TaskType) {
+		t.Helper()
+
+		require.NoError(t, maintenance.ReportRunAndStats(ctx, env.RepositoryWriter, task, nil, func(ctx context.Context) (map[string]int64, error) {
+			runCount[task]++
+
+			return map[string]int64{"runs": int64(runCount[task])}, nil
+		}))
+	}
+
+	for i := 0; i < 10; i++ {
+		run(disabledTask)
+		run(hourlyTask)
+		run(otherTask)
+	}
+
+	require.Equal(t, map[maintenance.TaskType]int{
+		hourlyTask: 1,
+		otherTask:  10,
+	}, runCount)
+
+	history, err := maintenance.History(ctx, env.RepositoryWriter, "")
+	require.NoError(t, err)
+	require.Len(t, history, 8)
+
+	history, err = maintenance.History(ctx, env.RepositoryWriter, otherTask)
+	require.NoError(t, err)
+	require.Len(t, history, 7)
+
+	// most recent first
+	require.Equal(t, map[string]int64{"runs": 10}, history[0].Stats)
+	require.Equal(t, map[string]int64{"runs": 4}, history[6].Stats)
+
+	// most recent runs required for maintenance safety are always kept.
+	p.HistoryRetention = maintenance.HistoryRetentionOptions{MaxCount: 1}
+	require.NoError(t, maintenance.SetParams(ctx, env.RepositoryWriter, &p))
+
+	run(otherTask)
+
+	history, err = maintenance.History(ctx, env.RepositoryWriter, otherTask)
+	require.NoError(t, err)
+	require.Len(t, history, 5)
+}
+
+func (s *formatSpecificTestSuite) TestTaskBudget(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, s.formatVersion)
+
+	const budgetedTask maintenance.TaskType = "budgeted-task"
+
+	p := maintenance.DefaultParams()
+	p.TaskBudgets = map[maintenance.TaskType]throttling.Limits{
+		budgetedTask: {ListsPerSecond: 1},
+	}
+
+	require.NoError(t, maintenance.SetParams(ctx, env.RepositoryWriter, &p))
+
+	st := env.RepositoryWriter.BlobStorage()
+
+	listBlobs := func(ctx context.Context) (time.Duration, error) {
+		timer := timetrack.StartTimer()
+
+		err := st.ListBlobs(ctx, "", func(bm blob.Metadata) error { return nil })
+
+		return timer.Elapsed(), err
+	}
+
+	require.NoError(t, maintenance.ReportRun(ctx, env.RepositoryWriter, budgetedTask, nil, func(taskCtx context.Context) error {
+		var (
+			concurrentElapsed time.Duration
+			concurrentErr     error
+			done              = make(chan struct{})
+		)
+
+		// operations which are not part of the task are not throttled while it's running.
+		go func() {
+			defer close(done)
+
+			for i := 0; i < 5 && concurrentErr == nil; i++ {
+				var elapsed time.Duration
+
+				elapsed, concurrentErr = listBlobs(ctx)
+				concurrentElapsed += elapsed
+			}
+		}()
+
+		var taskElapsed time.Duration
+
+		for i := 0; i < 2; i++ {
+			elapsed, err := listBlobs(taskCtx)
+			require.NoError(t, err)
+
+			taskElapsed += elapsed
+		}
+
+		<-done
+
+		require.NoError(t, concurrentErr)
+		require.Greater(t, taskElapsed, 500*time.Millisecond)
+		require.Less(t, concurrentElapsed, 500*time.Millisecond)
+
+		return nil
+	}))
+
+	// budget does not apply after the task has finished.
+	elapsed, err := listBlobs(ctx)
+	require.NoError(t, err)
+	require.Less(t, elapsed, 500*time.Millisecond)
+}
+
 func TestTimeToAttemptNextMaintenance(t *testing.T) {
 	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
 
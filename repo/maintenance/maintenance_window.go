@@ -0,0 +1,105 @@
+package maintenance
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hashicorp/cronexpr"
+	"github.com/pkg/errors"
+)
+
+// maxWindowLookahead is the maximum time we look ahead when searching for the end of maintenance window.
+const maxWindowLookahead = 7 * 24 * time.Hour
+
+// ValidateWindows returns an error if any of the provided maintenance windows is not a valid crontab expression.
+func ValidateWindows(windows []string) error {
+	for _, w := range windows {
+		e := stripWindowComment(w)
+		if e == "" {
+			return errors.Errorf("empty maintenance window %q", w)
+		}
+
+		if _, err := cronexpr.Parse(e); err != nil {
+			return errors.Errorf("invalid maintenance window %q", w)
+		}
+	}
+
+	return nil
+}
+
+func stripWindowComment(s string) string {
+	return strings.TrimSpace(strings.SplitN(s, "#", 2)[0]) //nolint:gomnd
+}
+
+func parseWindows(windows []string) []*cronexpr.Expression {
+	var result []*cronexpr.Expression
+
+	for _, w := range windows {
+		ce, err := cronexpr.Parse(stripWindowComment(w))
+		if err != nil {
+			// ignore invalid windows, they have been validated when set.
+			continue
+		}
+
+		result = append(result, ce)
+	}
+
+	return result
+}
+
+// inWindow determines whether the minute containing the provided time is matched by any of the windows.
+// Empty list of windows matches any time.
+func inWindow(windows []*cronexpr.Expression, t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+
+	m := t.In(time.Local).Truncate(time.Minute)
+
+	for _, ce := range windows {
+		if ce.Next(m.Add(-time.Nanosecond)).Equal(m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// windowEnd returns the time when the maintenance window containing the provided time ends
+// or zero time if it does not end within maxWindowLookahead.
+func windowEnd(windows []*cronexpr.Expression, t time.Time) time.Time {
+	if len(windows) == 0 {
+		return time.Time{}
+	}
+
+	for m := t.Truncate(time.Minute); m.Before(t.Add(maxWindowLookahead)); m = m.Add(time.Minute) {
+		if !inWindow(windows, m) {
+			return m
+		}
+	}
+
+	return time.Time{}
+}
+
+// nextWindowStart returns the earliest time not before the provided time which is within
+// a maintenance window or zero time if there's no such time.
+func nextWindowStart(windows []*cronexpr.Expression, t time.Time) time.Time {
+	if inWindow(windows, t) {
+		return t
+	}
+
+	var result time.Time
+
+	for _, ce := range windows {
+		nt := ce.Next(t.In(time.Local))
+		if nt.IsZero() {
+			continue
+		}
+
+		if result.IsZero() || nt.Before(result) {
+			result = nt
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,49 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceWindows(t *testing.T) {
+	require.NoError(t, ValidateWindows(nil))
+	require.NoError(t, ValidateWindows([]string{"* 1-4 * * *", "30-59 22 * * 6 # saturday night"}))
+	require.Error(t, ValidateWindows([]string{"* 1-4 * * *", "bad"}))
+	require.Error(t, ValidateWindows([]string{"# just a comment"}))
+
+	// 2020-01-01 is a Wednesday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2020, 1, day, hour, minute, 0, 0, time.Local)
+	}
+
+	windows := parseWindows([]string{"* 1-4 * * *", "30-59 22 * * 6 # saturday night"})
+
+	require.True(t, inWindow(nil, at(1, 12, 0)))
+	require.True(t, inWindow(windows, at(1, 1, 0)))
+	require.True(t, inWindow(windows, at(1, 4, 59).Add(30*time.Second)))
+	require.False(t, inWindow(windows, at(1, 5, 0)))
+	require.False(t, inWindow(windows, at(4, 22, 29)))
+	require.True(t, inWindow(windows, at(4, 22, 30)))
+
+	require.True(t, windowEnd(nil, at(1, 2, 0)).IsZero())
+	require.True(t, windowEnd(windows, at(1, 2, 15)).Equal(at(1, 5, 0)))
+	require.True(t, windowEnd(windows, at(4, 22, 45)).Equal(at(4, 23, 0)))
+	require.True(t, windowEnd(parseWindows([]string{"* * * * *"}), at(1, 2, 15)).IsZero())
+
+	require.True(t, nextWindowStart(windows, at(1, 2, 0)).Equal(at(1, 2, 0)))
+	require.True(t, nextWindowStart(windows, at(1, 12, 0)).Equal(at(2, 1, 0)))
+	require.True(t, nextWindowStart(windows, at(4, 12, 0)).Equal(at(4, 22, 30)))
+
+	p := &Params{MaxRuntime: 2 * time.Hour}
+
+	require.True(t, maintenanceDeadline(&Params{}, nil, at(1, 2, 0)).IsZero())
+	require.True(t, maintenanceDeadline(p, nil, at(1, 2, 0)).Equal(at(1, 4, 0)))
+	require.True(t, maintenanceDeadline(p, windows, at(1, 1, 0)).Equal(at(1, 3, 0)))
+	require.True(t, maintenanceDeadline(p, windows, at(1, 4, 0)).Equal(at(1, 5, 0)))
+	require.True(t, maintenanceDeadline(&Params{}, windows, at(1, 4, 0)).Equal(at(1, 5, 0)))
+
+	// explicit run outside of the window is only limited by max runtime.
+	require.True(t, maintenanceDeadline(p, windows, at(1, 12, 0)).Equal(at(1, 14, 0)))
+}
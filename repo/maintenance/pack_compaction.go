@@ -0,0 +1,173 @@
+package maintenance
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
+)
+
+// packLiveData describes the amount of live data in a pack.
+type packLiveData struct {
+	packID blob.ID
+	size   int64 // size of the pack blob in storage
+	live   int64 // size of contents which have not been deleted, as determined from the index
+}
+
+// liveDataPercent returns the percentage of live data in the pack.
+func (p *packLiveData) liveDataPercent() int64 {
+	if p.size == 0 {
+		return 0
+	}
+
+	return p.live * 100 / p.size //nolint:gomnd
+}
+
+// findContentInSparsePacks emits live contents of packs whose live data ratio is below
+// opt.LiveDataThresholdPercent, starting with packs with the lowest ratio.
+func findContentInSparsePacks(ctx context.Context, rep repo.DirectRepository, ch chan contentInfoOrError, opt *RewriteContentsOptions) {
+	packs := map[blob.ID]*packLiveData{}
+
+	if err := rep.ContentReader().IterateContents(
+		ctx,
+		content.IterateOptions{
+			Range:          index.AllIDs,
+			IncludeDeleted: true,
+		},
+		func(ci content.Info) error {
+			if !strings.HasPrefix(string(ci.GetPackBlobID()), string(opt.PackPrefix)) {
+				return nil
+			}
+
+			p := packs[ci.GetPackBlobID()]
+			if p == nil {
+				p = &packLiveData{packID: ci.GetPackBlobID()}
+				packs[ci.GetPackBlobID()] = p
+			}
+
+			if !ci.GetDeleted() {
+				p.live += int64(ci.GetPackedLength())
+			}
+
+			return nil
+		}); err != nil {
+		ch <- contentInfoOrError{err: err}
+		return
+	}
+
+	// pack sizes are taken from storage, since the index does not account for pack headers,
+	// padding and local indexes, nor for contents that have been dropped from the index.
+	if err := getPackSizes(ctx, rep, packs, opt.PackPrefix); err != nil {
+		ch <- contentInfoOrError{err: err}
+		return
+	}
+
+	selected := selectSparsePacks(ctx, packs, opt.LiveDataThresholdPercent, opt.MaxRewriteBytes)
+	if len(selected) == 0 {
+		return
+	}
+
+	if err := rep.ContentReader().IterateContents(
+		ctx,
+		content.IterateOptions{
+			Range: opt.ContentIDRange,
+		},
+		func(ci content.Info) error {
+			if selected[ci.GetPackBlobID()] {
+				ch <- contentInfoOrError{Info: ci}
+			}
+
+			return nil
+		}); err != nil {
+		ch <- contentInfoOrError{err: err}
+		return
+	}
+}
+
+// getPackSizes sets sizes of the provided packs to the lengths of their blobs.
+func getPackSizes(ctx context.Context, rep repo.DirectRepository, packs map[blob.ID]*packLiveData, prefix blob.ID) error {
+	prefixes := content.PackBlobIDPrefixes
+	if prefix != "" {
+		prefixes = []blob.ID{prefix}
+	}
+
+	for _, prefix := range prefixes {
+		if err := rep.BlobReader().ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+			if p := packs[bm.BlobID]; p != nil {
+				p.size = bm.Length
+			}
+
+			return nil
+		}); err != nil {
+			return errors.Wrapf(err, "error listing %v blobs", prefix)
+		}
+	}
+
+	return nil
+}
+
+// selectSparsePacks selects packs with live data ratio below the threshold, which have some live data.
+// Packs with the lowest ratio, which free the most space per byte rewritten, are selected first
+// until the total size of live data reaches maxRewriteBytes (if non-zero).
+func selectSparsePacks(ctx context.Context, packs map[blob.ID]*packLiveData, thresholdPercent int, maxRewriteBytes int64) map[blob.ID]bool {
+	var candidates []*packLiveData
+
+	for _, p := range packs {
+		// packs missing from storage have unknown size.
+		if p.size > 0 && p.live > 0 && p.liveDataPercent() < int64(thresholdPercent) {
+			candidates = append(candidates, p)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		// compare live[i]/size[i] < live[j]/size[j] without division
+		if l, r := candidates[i].live*candidates[j].size, candidates[j].live*candidates[i].size; l != r {
+			return l < r
+		}
+
+		return candidates[i].packID < candidates[j].packID
+	})
+
+	var (
+		selected                  = map[blob.ID]bool{}
+		rewriteBytes, reclaimable int64
+	)
+
+	for _, p := range candidates {
+		// always select at least one pack to make progress.
+		if maxRewriteBytes > 0 && len(selected) > 0 && rewriteBytes+p.live > maxRewriteBytes {
+			break
+		}
+
+		selected[p.packID] = true
+		rewriteBytes += p.live
+		reclaimable += p.size - p.live
+	}
+
+	log(ctx).Infof("Found %v packs with less than %v%% of live data, compacting %v of them by rewriting %v to reclaim %v.",
+		len(candidates), thresholdPercent, len(selected), units.BytesString(rewriteBytes), units.BytesString(reclaimable))
+
+	return selected
+}
+
+func runTaskCompactPacks(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
+	pc := runParams.Params.PackCompaction
+
+	return runParams.runTask(ctx, TaskCompactPacks, s, func(ctx context.Context) (map[string]int64, error) {
+		rewritten, err := rewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
+			ContentIDRange:           index.AllIDs,
+			LiveDataThresholdPercent: pc.LiveDataThresholdPercent,
+			MaxRewriteBytes:          pc.MaxRewriteBytes,
+			Deadline:                 runParams.Deadline,
+		}, safety)
+
+		return rewriteStats(rewritten), err
+	})
+}
@@ -0,0 +1,130 @@
+package maintenance_test
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/maintenance"
+)
+
+func (s *formatSpecificTestSuite) TestPackCompaction(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, s.formatVersion)
+
+	// writePack writes a single pack with n contents, deletes the first numDeleted of them
+	// and returns content IDs.
+	writePack := func(n, numDeleted int) []content.ID {
+		t.Helper()
+
+		var cids []content.ID
+
+		require.NoError(t, repo.DirectWriteSession(ctx, env.RepositoryWriter, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
+			for i := 0; i < n; i++ {
+				var b [1000]byte
+
+				rand.Read(b[:])
+
+				cid, err := w.ContentManager().WriteContent(ctx, gather.FromSlice(b[:]), "", content.NoCompression)
+				require.NoError(t, err)
+
+				cids = append(cids, cid)
+			}
+
+			return nil
+		}))
+
+		require.NoError(t, repo.DirectWriteSession(ctx, env.RepositoryWriter, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
+			for _, cid := range cids[0:numDeleted] {
+				require.NoError(t, w.ContentManager().DeleteContent(ctx, cid))
+			}
+
+			return nil
+		}))
+
+		return cids
+	}
+
+	packOf := func(cid content.ID) blob.ID {
+		t.Helper()
+
+		ci, err := env.RepositoryWriter.ContentInfo(ctx, cid)
+		require.NoError(t, err)
+
+		return ci.GetPackBlobID()
+	}
+
+	sparse := writePack(10, 9)     // 10% live
+	lessSparse := writePack(10, 7) // 30% live
+	dense := writePack(10, 2)      // 80% live
+	deleted := writePack(10, 10)   // no live data
+	sparsePack := packOf(sparse[9])
+	lessSparsePack := packOf(lessSparse[9])
+	densePack := packOf(dense[9])
+	deletedPack := packOf(deleted[9])
+
+	require.NoError(t, env.RepositoryWriter.Refresh(ctx))
+
+	// limit rewrite to 1 content, which only allows compacting the most sparse pack.
+	require.NoError(t, repo.DirectWriteSession(ctx, env.RepositoryWriter, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
+		return maintenance.RewriteContents(ctx, w, &maintenance.RewriteContentsOptions{
+			LiveDataThresholdPercent: 50,
+			MaxRewriteBytes:          1500,
+		}, maintenance.SafetyNone)
+	}))
+
+	require.NotEqual(t, sparsePack, packOf(sparse[9]))
+	require.Equal(t, lessSparsePack, packOf(lessSparse[9]))
+	require.Equal(t, densePack, packOf(dense[9]))
+	require.Equal(t, deletedPack, packOf(deleted[9]))
+
+	// deleted contents are not rewritten.
+	require.Equal(t, sparsePack, packOf(sparse[0]))
+
+	// without the limit, remaining packs below the threshold are compacted.
+	require.NoError(t, repo.DirectWriteSession(ctx, env.RepositoryWriter, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
+		return maintenance.RewriteContents(ctx, w, &maintenance.RewriteContentsOptions{
+			LiveDataThresholdPercent: 50,
+		}, maintenance.SafetyNone)
+	}))
+
+	for _, cid := range lessSparse[7:] {
+		require.NotEqual(t, lessSparsePack, packOf(cid))
+	}
+
+	require.Equal(t, densePack, packOf(dense[9]))
+	require.Equal(t, deletedPack, packOf(deleted[9]))
+
+	// live data ratio is computed using pack blob size, which accounts for contents whose
+	// index entries have been dropped.
+	truncated := writePack(10, 0)
+	truncatedPack := packOf(truncated[0])
+
+	require.NoError(t, repo.DirectWriteSession(ctx, env.RepositoryWriter, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
+		for _, cid := range truncated[2:] {
+			require.NoError(t, w.ContentManager().DeleteContent(ctx, cid))
+		}
+
+		return nil
+	}))
+
+	require.NoError(t, env.RepositoryWriter.Refresh(ctx))
+	require.NoError(t, maintenance.DropDeletedContents(ctx, env.RepositoryWriter, clock.Now().Add(time.Hour), maintenance.SafetyNone))
+	require.NoError(t, env.RepositoryWriter.Refresh(ctx))
+
+	require.NoError(t, repo.DirectWriteSession(ctx, env.RepositoryWriter, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
+		return maintenance.RewriteContents(ctx, w, &maintenance.RewriteContentsOptions{
+			LiveDataThresholdPercent: 50,
+		}, maintenance.SafetyNone)
+	}))
+
+	require.NotEqual(t, truncatedPack, packOf(truncated[0]))
+}
@@ -0,0 +1,370 @@
+package maintenance
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
+)
+
+// ScrubOptions provides options for Scrub.
+type ScrubOptions struct {
+	// Mirror is an optional copy of the repository storage (such as the destination of
+	// 'kopia repository sync-to'), from which missing or damaged pack blobs are restored.
+	Mirror blob.Storage
+
+	// MaxBytes limits the total size of pack blobs read in a single run, 0 means unlimited.
+	MaxBytes int64
+
+	// Deadline, if not zero, stops the scrub once reached. The remaining pack blobs are scrubbed during the next run.
+	Deadline time.Time
+
+	// DryRun only reports problems without repairing them or recording progress.
+	DryRun bool
+
+	// Force allows the scrub to run even if maintenance is not owned by the local user.
+	Force bool
+}
+
+// ScrubProgress records the progress of a scrub cycle which can span multiple runs.
+type ScrubProgress struct {
+	CycleStart   time.Time `json:"cycleStart,omitempty"`
+	LastBlobID   blob.ID   `json:"lastBlobID,omitempty"` // last pack blob scrubbed in the current cycle
+	LastCycleEnd time.Time `json:"lastCycleEnd,omitempty"`
+}
+
+// ScrubStats contains statistics about a scrub run.
+type ScrubStats struct {
+	ScrubbedBlobs     int   `json:"scrubbedBlobs"`
+	ScrubbedBytes     int64 `json:"scrubbedBytes"`
+	VerifiedContents  int   `json:"verifiedContents"`
+	CorrectedContents int   `json:"correctedContents"` // contents rewritten after correcting them using error correction
+	RestoredBlobs     int   `json:"restoredBlobs"`     // pack blobs restored from the mirror
+	MissingBlobs      int   `json:"missingBlobs"`      // pack blobs which are missing and could not be restored
+	InvalidContents   int   `json:"invalidContents"`   // contents which are invalid and could not be repaired
+	CycleCompleted    bool  `json:"cycleCompleted"`
+}
+
+// counters returns statistics as counters of a maintenance run.
+func (s *ScrubStats) counters() map[string]int64 {
+	return map[string]int64{
+		"scrubbedBlobs":     int64(s.ScrubbedBlobs),
+		"scrubbedBytes":     s.ScrubbedBytes,
+		"verifiedContents":  int64(s.VerifiedContents),
+		"correctedContents": int64(s.CorrectedContents),
+		"restoredBlobs":     int64(s.RestoredBlobs),
+		"missingBlobs":      int64(s.MissingBlobs),
+		"invalidContents":   int64(s.InvalidContents),
+	}
+}
+
+// Scrub reads pack blobs and verifies all contents stored in them, continuing where the previous run left off.
+// Contents that needed error correction are rewritten into new packs and missing or damaged pack blobs
+// are restored from the mirror, if provided.
+//
+// Since repairs orphan pack blobs and the progress is stored in the maintenance schedule, scrub must
+// be run by the maintenance owner while holding the maintenance lock, like other maintenance tasks.
+// Scrub is always run when requested, regardless of the interval of the scrub task.
+func Scrub(ctx context.Context, rep repo.DirectRepositoryWriter, opt *ScrubOptions) (*ScrubStats, error) {
+	if !opt.DryRun {
+		p, err := GetParams(ctx, rep)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get maintenance params")
+		}
+
+		if !opt.Force && !p.isOwnedByByThisUser(rep) {
+			return nil, NotOwnedError{p.Owner}
+		}
+
+		l, ok, err := tryLockMaintenance(ctx, rep)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return nil, errors.Errorf("maintenance is already in progress")
+		}
+
+		defer l.Unlock() //nolint:errcheck
+
+		if err := rep.Refresh(ctx); err != nil {
+			return nil, errors.Wrap(err, "error refreshing indexes before scrub")
+		}
+	}
+
+	s, err := GetSchedule(ctx, rep)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get schedule")
+	}
+
+	progress := ScrubProgress{}
+	if s.Scrub != nil {
+		progress = *s.Scrub
+	}
+
+	var st ScrubStats
+
+	if opt.DryRun {
+		err = scrubPacks(ctx, rep, opt, &progress, &st)
+	} else {
+		err = reportRunAndStats(ctx, rep, TaskScrub, s, false, func(ctx context.Context) (map[string]int64, error) {
+			err := scrubPacks(ctx, rep, opt, &progress, &st)
+			s.Scrub = &progress
+
+			return st.counters(), err
+		})
+	}
+
+	if err != nil {
+		return &st, errors.Wrap(err, "error running scrub")
+	}
+
+	if st.MissingBlobs > 0 || st.InvalidContents > 0 {
+		return &st, errors.Errorf("found %v missing pack blobs and %v invalid contents that could not be repaired", st.MissingBlobs, st.InvalidContents)
+	}
+
+	return &st, nil
+}
+
+func scrubPacks(ctx context.Context, rep repo.DirectRepositoryWriter, opt *ScrubOptions, progress *ScrubProgress, st *ScrubStats) error {
+	if progress.CycleStart.IsZero() {
+		progress.CycleStart = rep.Time()
+	}
+
+	packContents, err := packContentsAfter(ctx, rep, progress.LastBlobID)
+	if err != nil {
+		return err
+	}
+
+	existing := map[blob.ID]blob.Metadata{}
+
+	for _, prefix := range content.PackBlobIDPrefixes {
+		if err := rep.BlobReader().ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+			existing[bm.BlobID] = bm
+			return nil
+		}); err != nil {
+			return errors.Wrapf(err, "unable to list %v blobs", prefix)
+		}
+	}
+
+	var packIDs []blob.ID
+
+	for packID := range packContents {
+		packIDs = append(packIDs, packID)
+	}
+
+	sort.Slice(packIDs, func(i, j int) bool {
+		return packIDs[i] < packIDs[j]
+	})
+
+	if progress.LastBlobID != "" {
+		log(ctx).Infof("Resuming scrub started at %v after %v, %v pack blobs remaining.", progress.CycleStart.Format(time.RFC3339), progress.LastBlobID, len(packIDs))
+	} else {
+		log(ctx).Infof("Scrubbing %v pack blobs...", len(packIDs))
+	}
+
+	for _, packID := range packIDs {
+		if st.ScrubbedBlobs > 0 {
+			if !opt.Deadline.IsZero() && !rep.Time().Before(opt.Deadline) {
+				log(ctx).Infof("Scrub time limit reached, will continue after %v during the next run.", progress.LastBlobID)
+				return flushScrubRepairs(ctx, rep, st)
+			}
+
+			if opt.MaxBytes > 0 && st.ScrubbedBytes+existing[packID].Length > opt.MaxBytes {
+				log(ctx).Infof("Scrubbed %v, will continue after %v during the next run.", units.BytesString(st.ScrubbedBytes), progress.LastBlobID)
+				return flushScrubRepairs(ctx, rep, st)
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "scrub canceled")
+		}
+
+		bm, ok := existing[packID]
+		if err := scrubPack(ctx, rep, opt, packID, bm.Length, ok, packContents[packID], st); err != nil {
+			return err
+		}
+
+		progress.LastBlobID = packID
+	}
+
+	st.CycleCompleted = true
+	*progress = ScrubProgress{LastCycleEnd: rep.Time()}
+
+	log(ctx).Infof("Scrub cycle completed, scrubbed %v pack blobs (%v) in this run.", st.ScrubbedBlobs, units.BytesString(st.ScrubbedBytes))
+
+	return flushScrubRepairs(ctx, rep, st)
+}
+
+// packContentsAfter returns live contents of pack blobs with IDs greater than the provided one, grouped by pack blob.
+func packContentsAfter(ctx context.Context, rep repo.DirectRepository, after blob.ID) (map[blob.ID][]content.Info, error) {
+	result := map[blob.ID][]content.Info{}
+
+	if err := rep.ContentReader().IterateContents(ctx, content.IterateOptions{
+		Range: index.AllIDs,
+	}, func(ci content.Info) error {
+		if ci.GetPackBlobID() > after {
+			result[ci.GetPackBlobID()] = append(result[ci.GetPackBlobID()], ci)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "unable to iterate contents")
+	}
+
+	return result, nil
+}
+
+func scrubPack(ctx context.Context, rep repo.DirectRepositoryWriter, opt *ScrubOptions, packID blob.ID, length int64, exists bool, infos []content.Info, st *ScrubStats) error {
+	var data gather.WriteBuffer
+	defer data.Close()
+
+	if !exists {
+		log(ctx).Errorf("pack blob %v is missing", packID)
+
+		if !restoreFromMirror(ctx, rep, opt, packID, infos, st) {
+			st.MissingBlobs++
+		}
+
+		return nil
+	}
+
+	if err := rep.BlobReader().GetBlob(ctx, packID, 0, -1, &data); err != nil {
+		if errors.Is(err, blob.ErrBlobNotFound) {
+			return scrubPack(ctx, rep, opt, packID, 0, false, infos, st)
+		}
+
+		return errors.Wrapf(err, "unable to read pack blob %v", packID)
+	}
+
+	st.ScrubbedBlobs++
+	st.ScrubbedBytes += length
+
+	corrected, invalid := verifyPackContents(ctx, rep, packID, data.Bytes(), infos)
+	st.VerifiedContents += len(infos)
+
+	if len(invalid) > 0 {
+		if restoreFromMirror(ctx, rep, opt, packID, infos, st) {
+			return nil
+		}
+
+		st.InvalidContents += len(invalid)
+	}
+
+	for _, ci := range corrected {
+		if opt.DryRun {
+			log(ctx).Infof("Would rewrite content %v corrected using error correction.", ci.GetContentID())
+			continue
+		}
+
+		if err := rep.ContentManager().RewriteContent(ctx, ci.GetContentID()); err != nil {
+			log(ctx).Errorf("unable to rewrite content %v: %v", ci.GetContentID(), err)
+			st.InvalidContents++
+
+			continue
+		}
+
+		st.CorrectedContents++
+	}
+
+	return nil
+}
+
+// verifyPackContents verifies contents stored in the provided pack data and returns the contents which
+// have been corrected using error correction and the ones which are invalid.
+func verifyPackContents(ctx context.Context, rep repo.DirectRepositoryWriter, packID blob.ID, data gather.Bytes, infos []content.Info) (corrected, invalid []content.Info) {
+	b := data.ToByteSlice()
+
+	for _, ci := range infos {
+		start, end := int64(ci.GetPackOffset()), int64(ci.GetPackOffset())+int64(ci.GetPackedLength())
+		if end > int64(len(b)) {
+			log(ctx).Errorf("content %v out of bounds of its pack blob %v", ci.GetContentID(), packID)
+
+			invalid = append(invalid, ci)
+
+			continue
+		}
+
+		damaged, err := rep.ContentManager().VerifyPackedContent(ctx, gather.FromSlice(b[start:end]), ci)
+		if err != nil {
+			log(ctx).Errorf("content %v is invalid: %v", ci.GetContentID(), err)
+
+			invalid = append(invalid, ci)
+
+			continue
+		}
+
+		if damaged > 0 {
+			log(ctx).Infof("Content %v in %v had %v damaged shards corrected using error correction.", ci.GetContentID(), packID, damaged)
+
+			corrected = append(corrected, ci)
+		}
+	}
+
+	return corrected, invalid
+}
+
+// restoreFromMirror replaces the pack blob with its copy from the mirror, if the copy is valid.
+func restoreFromMirror(ctx context.Context, rep repo.DirectRepositoryWriter, opt *ScrubOptions, packID blob.ID, infos []content.Info, st *ScrubStats) bool {
+	if opt.Mirror == nil {
+		return false
+	}
+
+	var data gather.WriteBuffer
+	defer data.Close()
+
+	if err := opt.Mirror.GetBlob(ctx, packID, 0, -1, &data); err != nil {
+		log(ctx).Errorf("unable to read pack blob %v from mirror: %v", packID, err)
+		return false
+	}
+
+	if corrected, invalid := verifyPackContents(ctx, rep, packID, data.Bytes(), infos); len(corrected)+len(invalid) > 0 {
+		log(ctx).Errorf("mirror copy of pack blob %v is damaged, not restoring", packID)
+		return false
+	}
+
+	if opt.DryRun {
+		log(ctx).Infof("Would restore pack blob %v from mirror.", packID)
+	} else {
+		if err := rep.BlobStorage().PutBlob(ctx, packID, data.Bytes(), blob.PutOptions{}); err != nil {
+			log(ctx).Errorf("unable to restore pack blob %v from mirror: %v", packID, err)
+			return false
+		}
+
+		log(ctx).Infof("Restored pack blob %v from mirror.", packID)
+	}
+
+	st.RestoredBlobs++
+
+	return true
+}
+
+// flushScrubRepairs ensures contents rewritten by the scrub are persisted before the progress is recorded.
+func flushScrubRepairs(ctx context.Context, rep repo.DirectRepositoryWriter, st *ScrubStats) error {
+	if st.CorrectedContents == 0 {
+		return nil
+	}
+
+	return errors.Wrap(rep.Flush(ctx), "error flushing rewritten contents")
+}
+
+// scrubRewriteRuns returns scrub runs which have rewritten contents.
+func scrubRewriteRuns(runs []RunInfo) []RunInfo {
+	var result []RunInfo
+
+	for _, r := range runs {
+		if r.Stats["correctedContents"] > 0 {
+			result = append(result, r)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,196 @@
+package maintenance_test
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/ecc"
+	"github.com/kopia/kopia/repo/format"
+	"github.com/kopia/kopia/repo/maintenance"
+)
+
+func TestScrub(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, format.MaxFormatVersion, repotesting.Options{
+		NewRepositoryOptions: func(nro *repo.NewRepositoryOptions) {
+			nro.BlockFormat.ECC = ecc.AlgorithmReedSolomonWithCrc32
+			nro.BlockFormat.ECCOverheadPercent = 10
+		},
+	})
+
+	p, err := maintenance.GetParams(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+
+	p.Owner = env.Repository.ClientOptions().UsernameAtHost()
+	require.NoError(t, maintenance.SetParams(ctx, env.RepositoryWriter, p))
+
+	writePack := func() []content.ID {
+		t.Helper()
+
+		var cids []content.ID
+
+		require.NoError(t, repo.DirectWriteSession(ctx, env.RepositoryWriter, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
+			for i := 0; i < 5; i++ {
+				var b [10000]byte
+
+				rand.Read(b[:])
+
+				cid, err := w.ContentManager().WriteContent(ctx, gather.FromSlice(b[:]), "", content.NoCompression)
+				require.NoError(t, err)
+
+				cids = append(cids, cid)
+			}
+
+			return nil
+		}))
+
+		return cids
+	}
+
+	contentInfo := func(cid content.ID) content.Info {
+		t.Helper()
+
+		ci, err := env.RepositoryWriter.ContentInfo(ctx, cid)
+		require.NoError(t, err)
+
+		return ci
+	}
+
+	// modifyPack applies the provided modification to the pack blob containing the content.
+	modifyPack := func(cid content.ID, modify func(ci content.Info, b []byte)) {
+		t.Helper()
+
+		ci := contentInfo(cid)
+
+		var tmp gather.WriteBuffer
+		defer tmp.Close()
+
+		require.NoError(t, env.RootStorage().GetBlob(ctx, ci.GetPackBlobID(), 0, -1, &tmp))
+
+		b := tmp.ToByteSlice()
+		modify(ci, b)
+
+		require.NoError(t, env.RootStorage().PutBlob(ctx, ci.GetPackBlobID(), gather.FromSlice(b), blob.PutOptions{}))
+	}
+
+	correctable := writePack()
+	missing := writePack()
+	damaged := writePack()
+
+	require.NoError(t, env.RepositoryWriter.Refresh(ctx))
+
+	mirror := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+
+	require.NoError(t, env.RootStorage().ListBlobs(ctx, "", func(bm blob.Metadata) error {
+		var tmp gather.WriteBuffer
+		defer tmp.Close()
+
+		require.NoError(t, env.RootStorage().GetBlob(ctx, bm.BlobID, 0, -1, &tmp))
+
+		return mirror.PutBlob(ctx, bm.BlobID, tmp.Bytes(), blob.PutOptions{})
+	}))
+
+	// scrub a single pack at a time, recording progress.
+	st, err := maintenance.Scrub(ctx, env.RepositoryWriter, &maintenance.ScrubOptions{MaxBytes: 1})
+	require.NoError(t, err)
+	require.Equal(t, 1, st.ScrubbedBlobs)
+	require.False(t, st.CycleCompleted)
+
+	sched, err := maintenance.GetSchedule(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.NotEmpty(t, sched.Scrub.LastBlobID)
+
+	st, err = maintenance.Scrub(ctx, env.RepositoryWriter, &maintenance.ScrubOptions{})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, st.ScrubbedBlobs, 2)
+	require.True(t, st.CycleCompleted)
+	require.Zero(t, st.CorrectedContents)
+
+	sched, err = maintenance.GetSchedule(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Empty(t, sched.Scrub.LastBlobID)
+	require.False(t, sched.Scrub.LastCycleEnd.IsZero())
+	require.Len(t, sched.Runs[maintenance.TaskScrub], 2)
+
+	// damage a single byte, which can be corrected using error correction.
+	correctablePack := contentInfo(correctable[0]).GetPackBlobID()
+
+	modifyPack(correctable[0], func(ci content.Info, b []byte) {
+		b[ci.GetPackOffset()+ci.GetPackedLength()/2] ^= 0xff
+	})
+
+	// damage the entire content, which can't be corrected.
+	modifyPack(damaged[0], func(ci content.Info, b []byte) {
+		for i := ci.GetPackOffset(); i < ci.GetPackOffset()+ci.GetPackedLength(); i++ {
+			b[i] = 0
+		}
+	})
+
+	require.NoError(t, env.RootStorage().DeleteBlob(ctx, contentInfo(missing[0]).GetPackBlobID()))
+
+	// dry run does not repair anything.
+	st, err = maintenance.Scrub(ctx, env.RepositoryWriter, &maintenance.ScrubOptions{DryRun: true, Mirror: mirror})
+	require.NoError(t, err)
+	require.Equal(t, 2, st.RestoredBlobs)
+	require.Equal(t, correctablePack, contentInfo(correctable[0]).GetPackBlobID())
+
+	st, err = maintenance.Scrub(ctx, env.RepositoryWriter, &maintenance.ScrubOptions{})
+	require.Error(t, err)
+	require.Equal(t, 1, st.CorrectedContents)
+	require.Equal(t, 1, st.MissingBlobs)
+	require.Equal(t, 1, st.InvalidContents)
+	require.NotEqual(t, correctablePack, contentInfo(correctable[0]).GetPackBlobID())
+
+	st, err = maintenance.Scrub(ctx, env.RepositoryWriter, &maintenance.ScrubOptions{Mirror: mirror})
+	require.NoError(t, err)
+	require.Zero(t, st.CorrectedContents)
+	require.Equal(t, 2, st.RestoredBlobs)
+
+	for _, cids := range [][]content.ID{correctable, missing, damaged} {
+		for _, cid := range cids {
+			_, err := env.RepositoryWriter.ContentManager().GetContent(ctx, cid)
+			require.NoError(t, err)
+		}
+	}
+}
+
+func TestScrubExplicitRun(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, format.MaxFormatVersion)
+
+	p, err := maintenance.GetParams(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+
+	p.Owner = env.Repository.ClientOptions().UsernameAtHost()
+
+	// scrub requested explicitly runs even if the task is disabled or not due.
+	p.Tasks = map[maintenance.TaskType]maintenance.TaskParams{
+		maintenance.TaskScrub: {Disabled: true},
+	}
+	require.NoError(t, maintenance.SetParams(ctx, env.RepositoryWriter, p))
+
+	st, err := maintenance.Scrub(ctx, env.RepositoryWriter, &maintenance.ScrubOptions{})
+	require.NoError(t, err)
+	require.True(t, st.CycleCompleted)
+
+	sched, err := maintenance.GetSchedule(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, sched.Runs[maintenance.TaskScrub], 1)
+
+	// scrub must be run by the maintenance owner, unless forced.
+	p.Owner = "another-user@host"
+	require.NoError(t, maintenance.SetParams(ctx, env.RepositoryWriter, p))
+
+	_, err = maintenance.Scrub(ctx, env.RepositoryWriter, &maintenance.ScrubOptions{})
+	require.ErrorAs(t, err, &maintenance.NotOwnedError{})
+
+	_, err = maintenance.Scrub(ctx, env.RepositoryWriter, &maintenance.ScrubOptions{Force: true})
+	require.NoError(t, err)
+}
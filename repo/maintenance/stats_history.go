@@ -0,0 +1,208 @@
+package maintenance
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+)
+
+const (
+	statsHistoryBlobID = "kopia.stats"
+
+	// statsHistoryMinInterval is the minimum interval between samples of the statistics history,
+	// more frequent samples replace the most recent one.
+	statsHistoryMinInterval = 20 * time.Hour
+
+	// maxStatsHistorySamples limits the number of retained samples, which at one sample per day
+	// is about three years of history.
+	maxStatsHistorySamples = 1100
+)
+
+// BlobStats describes blobs with a common prefix.
+type BlobStats struct {
+	Count int64 `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+// SourceStats describes storage used by snapshots of a single source.
+type SourceStats struct {
+	Snapshots int `json:"snapshots"`
+
+	// UniqueBytes is the packed size of contents referenced only by snapshots of the source.
+	UniqueBytes int64 `json:"uniqueBytes"`
+
+	// SharedBytes is the packed size of contents referenced by snapshots of the source and other sources.
+	SharedBytes int64 `json:"sharedBytes"`
+}
+
+// RepositoryStats is a sample of repository-wide statistics.
+type RepositoryStats struct {
+	Time time.Time `json:"time"`
+
+	// Blobs contains statistics of blobs by prefix.
+	Blobs map[blob.ID]BlobStats `json:"blobs"`
+
+	ContentCount int64 `json:"contentCount"`
+
+	// LogicalBytes is the original size of all contents, PhysicalBytes is their size after compression and encryption.
+	LogicalBytes  int64 `json:"logicalBytes"`
+	PhysicalBytes int64 `json:"physicalBytes"`
+
+	// CompressionSavedBytes is the number of bytes saved by compression of contents.
+	CompressionSavedBytes int64 `json:"compressionSavedBytes"`
+
+	// SnapshotBytes is the total size of files in all snapshots, before deduplication.
+	Snapshots     int   `json:"snapshots"`
+	SnapshotBytes int64 `json:"snapshotBytes"`
+
+	// DedupeRatio is the ratio of SnapshotBytes to LogicalBytes.
+	DedupeRatio float64 `json:"dedupeRatio"`
+
+	// Sources contains statistics of individual snapshot sources.
+	Sources map[string]SourceStats `json:"sources,omitempty"`
+}
+
+// TotalBlobBytes returns the total size of all blobs.
+func (s *RepositoryStats) TotalBlobBytes() int64 {
+	var total int64
+
+	for _, b := range s.Blobs {
+		total += b.Bytes
+	}
+
+	return total
+}
+
+func (s *RepositoryStats) counters() map[string]int64 {
+	return map[string]int64{
+		"contents":      s.ContentCount,
+		"logicalBytes":  s.LogicalBytes,
+		"physicalBytes": s.PhysicalBytes,
+		"blobBytes":     s.TotalBlobBytes(),
+		"snapshots":     int64(s.Snapshots),
+	}
+}
+
+// SnapshotStatsFunc fills in statistics of snapshots, which are computed outside of the repository layer.
+type SnapshotStatsFunc func(ctx context.Context, rep repo.DirectRepository, st *RepositoryStats) error
+
+// CollectStats computes current statistics of the repository.
+func CollectStats(ctx context.Context, rep repo.DirectRepository, snapshotStats SnapshotStatsFunc) (*RepositoryStats, error) {
+	st := &RepositoryStats{
+		Time:  rep.Time(),
+		Blobs: map[blob.ID]BlobStats{},
+	}
+
+	log(ctx).Infof("Collecting blob statistics...")
+
+	if err := rep.BlobReader().ListBlobs(ctx, "", func(bm blob.Metadata) error {
+		prefix := statsBlobPrefix(bm.BlobID)
+
+		bs := st.Blobs[prefix]
+		bs.Count++
+		bs.Bytes += bm.Length
+		st.Blobs[prefix] = bs
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "error listing blobs")
+	}
+
+	log(ctx).Infof("Collecting content statistics...")
+
+	if err := rep.ContentReader().IterateContents(ctx, content.IterateOptions{}, func(ci content.Info) error {
+		orig, packed := int64(ci.GetOriginalLength()), int64(ci.GetPackedLength())
+
+		st.ContentCount++
+		st.LogicalBytes += orig
+		st.PhysicalBytes += packed
+
+		if ci.GetCompressionHeaderID() != content.NoCompression && orig > packed {
+			st.CompressionSavedBytes += orig - packed
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "error iterating contents")
+	}
+
+	if snapshotStats != nil {
+		if err := snapshotStats(ctx, rep, st); err != nil {
+			return nil, errors.Wrap(err, "error collecting snapshot statistics")
+		}
+	}
+
+	if st.LogicalBytes > 0 {
+		st.DedupeRatio = float64(st.SnapshotBytes) / float64(st.LogicalBytes)
+	}
+
+	return st, nil
+}
+
+// RecordStats collects current statistics of the repository and appends them to the statistics history.
+func RecordStats(ctx context.Context, rep repo.DirectRepositoryWriter, s *Schedule, snapshotStats SnapshotStatsFunc) error {
+	return ReportRunAndStats(ctx, rep, TaskRepositoryStats, s, func(ctx context.Context) (map[string]int64, error) {
+		st, err := CollectStats(ctx, rep, snapshotStats)
+		if err != nil {
+			return nil, err
+		}
+
+		history, err := StatsHistory(ctx, rep)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := putEncryptedJSON(ctx, rep, statsHistoryBlobID, appendStatsHistory(history, st)); err != nil {
+			return nil, errors.Wrap(err, "unable to write statistics history")
+		}
+
+		return st.counters(), nil
+	})
+}
+
+// StatsHistory returns the history of repository statistics, oldest first.
+func StatsHistory(ctx context.Context, rep repo.DirectRepository) ([]*RepositoryStats, error) {
+	var history []*RepositoryStats
+
+	err := getEncryptedJSON(ctx, rep, statsHistoryBlobID, &history)
+	if errors.Is(err, blob.ErrBlobNotFound) {
+		return nil, nil
+	}
+
+	return history, errors.Wrap(err, "unable to read statistics history")
+}
+
+// appendStatsHistory appends the sample to the history, replacing the most recent sample if it's too recent
+// and discarding the oldest samples beyond maxStatsHistorySamples.
+func appendStatsHistory(history []*RepositoryStats, st *RepositoryStats) []*RepositoryStats {
+	if n := len(history); n > 0 && st.Time.Sub(history[n-1].Time) < statsHistoryMinInterval {
+		history = history[0 : n-1]
+	}
+
+	history = append(history, st)
+
+	if len(history) > maxStatsHistorySamples {
+		history = history[len(history)-maxStatsHistorySamples:]
+	}
+
+	return history
+}
+
+// statsBlobPrefix returns the prefix under which the blob is counted in statistics.
+func statsBlobPrefix(id blob.ID) blob.ID {
+	if strings.HasPrefix(string(id), "kopia.") {
+		return "kopia."
+	}
+
+	if id == "" {
+		return ""
+	}
+
+	return id[0:1]
+}
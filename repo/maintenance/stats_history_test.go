@@ -0,0 +1,93 @@
+package maintenance_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/faketime"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/maintenance"
+)
+
+func TestRecordStats(t *testing.T) {
+	ta := faketime.NewClockTimeWithOffset(0)
+
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant, repotesting.Options{
+		OpenOptions: func(o *repo.Options) {
+			o.TimeNowFunc = ta.NowFunc()
+		},
+	})
+
+	history, err := maintenance.StatsHistory(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	writeContents := func(n int) {
+		t.Helper()
+
+		require.NoError(t, repo.DirectWriteSession(ctx, env.RepositoryWriter, repo.WriteSessionOptions{}, func(ctx context.Context, w repo.DirectRepositoryWriter) error {
+			for i := 0; i < n; i++ {
+				_, err := w.ContentManager().WriteContent(ctx, gather.FromSlice([]byte{byte(n), byte(i), 1, 2, 3}), "", content.NoCompression)
+				require.NoError(t, err)
+			}
+
+			return nil
+		}))
+	}
+
+	snapshotStats := func(ctx context.Context, rep repo.DirectRepository, st *maintenance.RepositoryStats) error {
+		st.Snapshots = 1
+		st.SnapshotBytes = 3 * st.LogicalBytes
+		st.Sources = map[string]maintenance.SourceStats{
+			"user@host:/path": {Snapshots: 1, UniqueBytes: st.PhysicalBytes},
+		}
+
+		return nil
+	}
+
+	writeContents(3)
+	require.NoError(t, maintenance.RecordStats(ctx, env.RepositoryWriter, nil, snapshotStats))
+
+	history, err = maintenance.StatsHistory(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, int64(3), history[0].ContentCount)
+	require.Equal(t, int64(15), history[0].LogicalBytes)
+	require.Positive(t, history[0].PhysicalBytes)
+	require.Positive(t, history[0].Blobs["p"].Count)
+	require.Positive(t, history[0].TotalBlobBytes())
+	require.InDelta(t, 3.0, history[0].DedupeRatio, 0.001)
+	require.Equal(t, 1, history[0].Sources["user@host:/path"].Snapshots)
+
+	// samples recorded shortly after each other replace the most recent one.
+	ta.Advance(time.Hour)
+	writeContents(2)
+	require.NoError(t, maintenance.RecordStats(ctx, env.RepositoryWriter, nil, snapshotStats))
+
+	history, err = maintenance.StatsHistory(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, int64(5), history[0].ContentCount)
+
+	ta.Advance(24 * time.Hour)
+	writeContents(4)
+	require.NoError(t, maintenance.RecordStats(ctx, env.RepositoryWriter, nil, snapshotStats))
+
+	history, err = maintenance.StatsHistory(ctx, env.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, int64(5), history[0].ContentCount)
+	require.Equal(t, int64(9), history[1].ContentCount)
+	require.True(t, history[0].Time.Before(history[1].Time))
+
+	mh, err := maintenance.History(ctx, env.RepositoryWriter, maintenance.TaskRepositoryStats)
+	require.NoError(t, err)
+	require.Len(t, mh, 3)
+	require.Equal(t, int64(9), mh[0].Stats["contents"])
+}
@@ -39,6 +39,12 @@ type contentManager interface {
 	WriteContent(ctx context.Context, data gather.Bytes, prefix content.IDPrefix, comp compression.HeaderID) (content.ID, error)
 }
 
+// dictionaryContentManager is implemented by content managers which support compression against
+// compression dictionaries.
+type dictionaryContentManager interface {
+	WriteContentWithDictionary(ctx context.Context, data gather.Bytes, prefix content.IDPrefix, dictionaryID content.ID) (content.ID, error)
+}
+
 // Manager implements a content-addressable storage on top of blob storage.
 type Manager struct {
 	Format format.ObjectFormat
@@ -46,6 +52,10 @@ type Manager struct {
 	contentMgr  contentManager
 	newSplitter splitter.Factory
 	writerPool  sync.Pool
+
+	splitterFactoriesMutex sync.Mutex
+	// +checklocks:splitterFactoriesMutex
+	splitterFactories map[string]splitter.Factory // pooled factories of non-default splitters
 }
 
 // NewWriter creates an ObjectWriter for writing to the repository.
@@ -53,10 +63,18 @@ func (om *Manager) NewWriter(ctx context.Context, opt WriterOptions) Writer {
 	w, _ := om.writerPool.Get().(*objectWriter)
 	w.ctx = ctx
 	w.om = om
-	w.splitter = om.newSplitter()
+	w.splitter = om.splitterFactory(ctx, opt.Splitter)()
 	w.description = opt.Description
 	w.prefix = opt.Prefix
 	w.compressor = compression.ByName[opt.Compressor]
+
+	// pooled writer may still be queried for its selected compressor.
+	w.mu.Lock()
+	w.autoCompression = opt.Compressor == compression.AutoName
+	w.selectedCompressor = opt.Compressor
+	w.mu.Unlock()
+
+	w.dictionary = opt.CompressionDictionary
 	w.totalLength = 0
 	w.currentPosition = 0
 
@@ -77,6 +95,37 @@ func (om *Manager) NewWriter(ctx context.Context, opt WriterOptions) Writer {
 	return w
 }
 
+// splitterFactory returns the factory for the splitter selected by the splitter policy or the default
+// splitter factory if the name is empty or not supported.
+func (om *Manager) splitterFactory(ctx context.Context, name string) splitter.Factory {
+	if name == "" || name == om.Format.Splitter {
+		return om.newSplitter
+	}
+
+	om.splitterFactoriesMutex.Lock()
+	defer om.splitterFactoriesMutex.Unlock()
+
+	if f := om.splitterFactories[name]; f != nil {
+		return f
+	}
+
+	f := splitter.GetPolicyFactory(name)
+	if f == nil {
+		log(ctx).Warnf("unsupported splitter %q, using default", name)
+
+		return om.newSplitter
+	}
+
+	if om.splitterFactories == nil {
+		om.splitterFactories = map[string]splitter.Factory{}
+	}
+
+	pooled := splitter.Pooled(f)
+	om.splitterFactories[name] = pooled
+
+	return pooled
+}
+
 func (om *Manager) closedWriter(ow *objectWriter) {
 	om.writerPool.Put(ow)
 }
@@ -168,6 +168,46 @@ func objectIDsEqual(o1, o2 ID) bool {
 	return o1 == o2
 }
 
+func TestWriterSplitterOverride(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	cases := []struct {
+		splitter     string
+		wantSegments int
+	}{
+		{"", 1},                 // repository default - FIXED-1M
+		{"FIXED-128K", 4},       // 3 full segments + 1 partial
+		{"no-such-splitter", 1}, // falls back to repository default
+	}
+
+	for _, tc := range cases {
+		_, _, om := setupTest(t, nil)
+
+		w := om.NewWriter(ctx, WriterOptions{
+			Splitter: tc.splitter,
+		})
+
+		w.Write(make([]byte, 400<<10))
+
+		oid, err := w.Result()
+		require.NoError(t, err)
+
+		var segments int
+
+		if indexObjectID, ok := oid.IndexObjectID(); ok {
+			ndx, err := LoadIndexObject(ctx, om.contentMgr, indexObjectID)
+			require.NoError(t, err)
+
+			segments = len(ndx)
+		} else {
+			segments = 1
+		}
+
+		require.Equal(t, tc.wantSegments, segments, tc.splitter)
+		require.NoError(t, w.Close())
+	}
+}
+
 func TestCompression_ContentCompressionEnabled(t *testing.T) {
 	ctx := testlogging.Context(t)
 
@@ -187,6 +227,48 @@ func TestCompression_ContentCompressionEnabled(t *testing.T) {
 	require.Equal(t, compression.ByName["gzip"].HeaderID(), cmap[cid])
 }
 
+func TestCompression_Auto(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	random := make([]byte, 100000)
+	cryptorand.Read(random)
+
+	cases := []struct {
+		data []byte
+		want compression.Name
+	}{
+		{bytes.Repeat([]byte{1, 2, 3, 4}, 1000), compression.AutoBestCompressor},
+		{random, ""},
+	}
+
+	for _, tc := range cases {
+		cmap := map[content.ID]compression.HeaderID{}
+		_, _, om := setupTest(t, cmap)
+
+		w := om.NewWriter(ctx, WriterOptions{
+			Compressor: compression.AutoName,
+		})
+
+		require.Equal(t, compression.AutoName, w.(CompressorSelector).SelectedCompressor())
+
+		w.Write(tc.data)
+		oid, err := w.Result()
+		require.NoError(t, err)
+		require.Equal(t, tc.want, w.(CompressorSelector).SelectedCompressor())
+
+		cid, _, ok := oid.ContentID()
+		require.True(t, ok)
+
+		if tc.want == "" {
+			require.Equal(t, content.NoCompression, cmap[cid])
+		} else {
+			require.Equal(t, compression.ByName[tc.want].HeaderID(), cmap[cid])
+		}
+
+		require.NoError(t, w.Close())
+	}
+}
+
 func TestCompression_ContentCompressionDisabled(t *testing.T) {
 	ctx := testlogging.Context(t)
 
@@ -19,6 +19,10 @@
 
 const indirectContentPrefix = "x"
 
+// maxDictionaryCompressedChunkSize is the maximum size of a chunk compressed using a compression dictionary.
+// Larger chunks have enough context of their own for the dictionary to make little difference.
+const maxDictionaryCompressedChunkSize = 64 << 10
+
 // Writer allows writing content to the storage and supports automatic deduplication and encryption
 // of written data.
 type Writer interface {
@@ -33,6 +37,12 @@ type Writer interface {
 	Result() (ID, error)
 }
 
+// CompressorSelector is implemented by writers that can report the name of the compressor
+// used for the object, which may have been selected automatically.
+type CompressorSelector interface {
+	SelectedCompressor() compression.Name
+}
+
 type contentIDTracker struct {
 	mu sync.Mutex
 	// +checklocks:mu
@@ -70,6 +80,12 @@ type objectWriter struct {
 
 	compressor compression.Compressor
 
+	// +checklocks:mu
+	autoCompression bool // select compressor based on the data of the first chunk
+	// +checklocks:mu
+	selectedCompressor compression.Name // name of the compressor used for the object
+	dictionary         content.ID       // compression dictionary for small chunks or empty
+
 	prefix      content.IDPrefix
 	buffer      gather.WriteBuffer
 	totalLength int64
@@ -137,9 +153,28 @@ func (w *objectWriter) Write(data []byte) (n int, err error) {
 	return dataLen, nil
 }
 
+// SelectedCompressor returns the name of the compressor used for the object or "auto" if compressor
+// has not been selected yet.
+func (w *objectWriter) SelectedCompressor() compression.Name {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.selectedCompressor
+}
+
+// flushBuffer writes the buffered chunk, the caller must hold w.mu.
+//
+// +checklocks:w.mu
 func (w *objectWriter) flushBuffer() error {
 	length := w.buffer.Length()
 
+	if w.autoCompression {
+		// probe the first chunk to determine compressor for the entire object.
+		w.autoCompression = false
+		w.selectedCompressor = compression.SelectAutoCompressor(compression.AutoProbeSample(length, w.buffer.Bytes().ReadAt))
+		w.compressor = compression.ByName[w.selectedCompressor]
+	}
+
 	// hold a lock as we may grow the index
 	w.indirectIndexGrowMutex.Lock()
 	chunkID := len(w.indirectIndex)
@@ -205,7 +240,14 @@ func (w *objectWriter) prepareAndWriteContentChunk(chunkID int, data gather.Byte
 		return errors.Wrap(err, "unable to prepare content bytes")
 	}
 
-	contentID, err := w.om.contentMgr.WriteContent(w.ctx, contentBytes, w.prefix, comp)
+	var contentID content.ID
+
+	if dcm, ok := w.om.contentMgr.(dictionaryContentManager); ok && w.useDictionary(comp, data.Length()) {
+		contentID, err = dcm.WriteContentWithDictionary(w.ctx, contentBytes, w.prefix, w.dictionary)
+	} else {
+		contentID, err = w.om.contentMgr.WriteContent(w.ctx, contentBytes, w.prefix, comp)
+	}
+
 	if err != nil {
 		return errors.Wrapf(err, "unable to write content chunk %v of %v: %v", chunkID, w.description, err)
 	}
@@ -218,6 +260,12 @@ func (w *objectWriter) prepareAndWriteContentChunk(chunkID int, data gather.Byte
 	return nil
 }
 
+// useDictionary returns true if a chunk of the given length should be compressed using
+// the compression dictionary instead of the selected compressor.
+func (w *objectWriter) useDictionary(comp compression.HeaderID, length int) bool {
+	return w.dictionary != content.EmptyID && comp != content.NoCompression && length <= maxDictionaryCompressedChunkSize
+}
+
 func (w *objectWriter) saveError(err error) error {
 	if err != nil {
 		// store write error so that we fail at Result() later.
@@ -338,6 +386,11 @@ func writeIndirectObject(w io.Writer, entries []IndirectObjectEntry) error {
 type WriterOptions struct {
 	Description string
 	Prefix      content.IDPrefix // empty string or a single-character ('g'..'z')
-	Compressor  compression.Name
-	AsyncWrites int // allow up to N content writes to be asynchronous
+	Compressor  compression.Name // compressor name or compression.AutoName
+	Splitter    string           // use particular splitter instead of the repository default
+	AsyncWrites int              // allow up to N content writes to be asynchronous
+
+	// CompressionDictionary is the ID of content holding the compression dictionary used instead of
+	// the compressor for small chunks, if supported by the repository.
+	CompressionDictionary content.ID
 }
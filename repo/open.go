@@ -45,6 +45,11 @@
 var supportedFeatures = []feature.Feature{
 	"index-v1",
 	"index-v2",
+	featureEncryptionXChaCha20Poly1305,
+	featureEncryptionAES256GCMSIV,
+	featureHashHMACSHA3256128,
+	featureHashKMAC256,
+	FeatureCompressionDictionaries,
 }
 
 // throttlingWindow is the duration window during which the throttling token bucket fully replenishes.
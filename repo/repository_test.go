@@ -16,6 +16,7 @@
 
 	"github.com/kopia/kopia/internal/cache"
 	"github.com/kopia/kopia/internal/epoch"
+	"github.com/kopia/kopia/internal/feature"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/metricid"
 	"github.com/kopia/kopia/internal/repotesting"
@@ -546,6 +547,47 @@ func TestInitializeWithNoRetention(t *testing.T) {
 	require.NoError(t, env.RepositoryWriter.BlobStorage().GetBlob(ctx, format.KopiaBlobCfgBlobID, 0, -1, &b))
 }
 
+func TestInitializeWithNewAlgorithmsRequiresFeatures(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant, repotesting.Options{
+		NewRepositoryOptions: func(n *repo.NewRepositoryOptions) {
+			n.BlockFormat.Hash = "KMAC256-128"
+			n.BlockFormat.Encryption = "AES256-GCM-SIV-HMAC-SHA256"
+		},
+	})
+
+	w := env.RepositoryWriter.NewObjectWriter(ctx, object.WriterOptions{})
+	w.Write([]byte("The quick brown fox jumps over the lazy dog"))
+
+	oid, err := w.Result()
+	require.NoError(t, err)
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	env.MustReopen(t)
+
+	//nolint:forcetypeassert
+	required, err := env.Repository.(repo.DirectRepository).FormatManager().RequiredFeatures()
+	require.NoError(t, err)
+
+	var features []feature.Feature
+	for _, r := range required {
+		features = append(features, r.Feature)
+	}
+
+	require.ElementsMatch(t, []feature.Feature{"hash-kmac256", "encryption-aes256-gcm-siv"}, features)
+
+	// clients which don't understand the algorithms refuse to open the repository.
+	require.Len(t, feature.GetUnsupportedFeatures(required, []feature.Feature{"index-v1", "index-v2"}), 2)
+
+	rc, err := env.Repository.OpenObject(ctx, oid)
+	require.NoError(t, err)
+
+	defer rc.Close()
+
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.Equal(t, "The quick brown fox jumps over the lazy dog", string(b))
+}
+
 func TestObjectWritesWithRetention(t *testing.T) {
 	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant, repotesting.Options{
 		NewRepositoryOptions: func(n *repo.NewRepositoryOptions) {
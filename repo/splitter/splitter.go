@@ -72,6 +72,14 @@ func SupportedAlgorithms() []string {
 	"DYNAMIC-4M-RABINKARP":   newRabinKarp64SplitterFactory(splitterSize4MB),
 	"DYNAMIC-8M-RABINKARP":   newRabinKarp64SplitterFactory(splitterSize8MB),
 
+	"DYNAMIC-128K-FASTCDC": newFastCDCSplitterFactory(splitterSize128KB),
+	"DYNAMIC-256K-FASTCDC": newFastCDCSplitterFactory(splitterSize256KB),
+	"DYNAMIC-512K-FASTCDC": newFastCDCSplitterFactory(splitterSize512KB),
+	"DYNAMIC-1M-FASTCDC":   newFastCDCSplitterFactory(splitterSize1MB),
+	"DYNAMIC-2M-FASTCDC":   newFastCDCSplitterFactory(splitterSize2MB),
+	"DYNAMIC-4M-FASTCDC":   newFastCDCSplitterFactory(splitterSize4MB),
+	"DYNAMIC-8M-FASTCDC":   newFastCDCSplitterFactory(splitterSize8MB),
+
 	// handle deprecated legacy names to splitters of arbitrary size
 	"FIXED": Fixed(splitterSize4MB),
 
@@ -80,10 +88,51 @@ func SupportedAlgorithms() []string {
 	"DYNAMIC": newBuzHash32SplitterFactory(splitterSize4MB),
 }
 
+// policySplitterFactories is a map of registered splitter factories, which are only meaningful
+// for particular file formats and can only be selected for individual files by the splitter policy.
+//
+//nolint:gochecknoglobals
+var policySplitterFactories = map[string]Factory{
+	// split ZIP-like archives at member boundaries and large members using dynamic splitter
+	"ZIP-1M-BUZHASH": newZipMemberSplitterFactory(newBuzHash32SplitterFactory(splitterSize1MB)),
+	"ZIP-2M-BUZHASH": newZipMemberSplitterFactory(newBuzHash32SplitterFactory(splitterSize2MB)),
+	"ZIP-4M-BUZHASH": newZipMemberSplitterFactory(newBuzHash32SplitterFactory(splitterSize4MB)),
+	"ZIP-8M-BUZHASH": newZipMemberSplitterFactory(newBuzHash32SplitterFactory(splitterSize8MB)),
+
+	"ZIP-1M-FASTCDC": newZipMemberSplitterFactory(newFastCDCSplitterFactory(splitterSize1MB)),
+	"ZIP-2M-FASTCDC": newZipMemberSplitterFactory(newFastCDCSplitterFactory(splitterSize2MB)),
+	"ZIP-4M-FASTCDC": newZipMemberSplitterFactory(newFastCDCSplitterFactory(splitterSize4MB)),
+	"ZIP-8M-FASTCDC": newZipMemberSplitterFactory(newFastCDCSplitterFactory(splitterSize8MB)),
+}
+
 // GetFactory gets splitter factory with a specified name or nil if not found.
 func GetFactory(name string) Factory {
 	return splitterFactories[name]
 }
 
+// SupportedPolicyAlgorithms returns the list of splitters that can only be selected for individual
+// files by the splitter policy.
+func SupportedPolicyAlgorithms() []string {
+	var supportedSplitters []string
+
+	for k := range policySplitterFactories {
+		supportedSplitters = append(supportedSplitters, k)
+	}
+
+	sort.Strings(supportedSplitters)
+
+	return supportedSplitters
+}
+
+// GetPolicyFactory gets factory of a splitter selected by the splitter policy, which can be either
+// a regular splitter or one that can only be selected by the policy, or nil if not found.
+func GetPolicyFactory(name string) Factory {
+	if f := splitterFactories[name]; f != nil {
+		return f
+	}
+
+	return policySplitterFactories[name]
+}
+
 // DefaultAlgorithm is the name of the splitter used by default for new repositories.
 const DefaultAlgorithm = "DYNAMIC-4M-BUZHASH"
@@ -0,0 +1,156 @@
+package splitter
+
+import (
+	"math/bits"
+)
+
+// fastCDCGearSeed is the seed used to deterministically generate gear table.
+// Changing it will change all split points and break deduplication against existing data.
+const fastCDCGearSeed = 0x6b6f706961464344 // "kopiaFCD"
+
+// fastCDCNormalizationLevel determines how many mask bits are added before and removed after
+// the average chunk size is reached, which makes chunk size distribution narrower.
+const fastCDCNormalizationLevel = 2
+
+//nolint:gochecknoglobals
+var fastCDCGear = generateGearTable(fastCDCGearSeed)
+
+// generateGearTable returns a table of 256 pseudo-random values generated using splitmix64.
+func generateGearTable(seed uint64) [256]uint64 {
+	var result [256]uint64
+
+	x := seed
+
+	for i := range result {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9 //nolint:gomnd
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb //nolint:gomnd
+		result[i] = z ^ (z >> 31)                //nolint:gomnd
+	}
+
+	return result
+}
+
+// fastCDCSplitter implements FastCDC content-defined chunking with normalized chunking
+// as described in "FastCDC: a Fast and Efficient Content-Defined Chunking Approach
+// for Data Deduplication" (Xia et al., USENIX ATC 2016).
+type fastCDCSplitter struct {
+	fp         uint64
+	count      int
+	minSize    int
+	normalSize int
+	maxSize    int
+	maskSmall  uint64 // more bits, used before reaching normalSize
+	maskLarge  uint64 // fewer bits, used after reaching normalSize
+	gear       *[256]uint64
+}
+
+func (rs *fastCDCSplitter) Close() {
+}
+
+func (rs *fastCDCSplitter) Reset() {
+	rs.fp = 0
+	rs.count = 0
+}
+
+func (rs *fastCDCSplitter) NextSplitPoint(b []byte) int {
+	var consumed int
+
+	// until minSize, there's no need to hash anything since the gear hash starts from scratch
+	// at the minimum size boundary.
+	if left := rs.minSize - rs.count; left > 0 {
+		if left > len(b) {
+			left = len(b)
+		}
+
+		rs.count += left
+		consumed += left
+		b = b[left:]
+	}
+
+	// until normalSize, use the stricter mask which makes split points less likely.
+	if left := rs.normalSize - rs.count; left > 0 {
+		if left > len(b) {
+			left = len(b)
+		}
+
+		if n := rs.scan(b[0:left], rs.maskSmall); n > 0 {
+			return consumed + n
+		}
+
+		consumed += left
+		b = b[left:]
+	}
+
+	// until maxSize, use the more relaxed mask which makes split points more likely.
+	if left := rs.maxSize - rs.count; left > 0 {
+		if left > len(b) {
+			left = len(b)
+		}
+
+		if n := rs.scan(b[0:left], rs.maskLarge); n > 0 {
+			return consumed + n
+		}
+
+		consumed += left
+	}
+
+	// if we're at the max size, split
+	if rs.count >= rs.maxSize {
+		rs.Reset()
+		return consumed
+	}
+
+	return -1
+}
+
+// scan feeds the provided bytes to the gear hash and returns the number of bytes consumed
+// up to and including the split point or 0 if there was no split point.
+func (rs *fastCDCSplitter) scan(b []byte, mask uint64) int {
+	fp := rs.fp
+	gear := rs.gear
+
+	for i, c := range b {
+		fp = (fp << 1) + gear[c]
+
+		if fp&mask == 0 {
+			rs.Reset()
+			return i + 1
+		}
+	}
+
+	rs.fp = fp
+	rs.count += len(b)
+
+	return 0
+}
+
+func (rs *fastCDCSplitter) MaxSegmentSize() int {
+	return rs.maxSize
+}
+
+// fastCDCMask returns a mask with the provided number of bits set, using the most significant
+// bits of the gear hash, which depend on the largest number of preceding bytes.
+func fastCDCMask(numBits int) uint64 {
+	return ^uint64(0) << (64 - numBits) //nolint:gomnd
+}
+
+func newFastCDCSplitterFactory(avgSize int) Factory {
+	// avgSize must be a power of two, so the number of mask bits is the number of trailing zeroes.
+	avgBits := bits.TrailingZeros(uint(avgSize))
+	maskSmall := fastCDCMask(avgBits + fastCDCNormalizationLevel)
+	maskLarge := fastCDCMask(avgBits - fastCDCNormalizationLevel)
+	minSize, maxSize := avgSize/2, avgSize*2 //nolint:gomnd
+
+	return func() Splitter {
+		return &fastCDCSplitter{
+			minSize:    minSize,
+			normalSize: avgSize,
+			maxSize:    maxSize,
+			maskSmall:  maskSmall,
+			maskLarge:  maskLarge,
+			gear:       &fastCDCGear,
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package splitter
 
 import (
+	"archive/zip"
+	"bytes"
 	"fmt"
 	"math"
 	"math/rand"
@@ -37,6 +39,12 @@ func TestSplitterStability(t *testing.T) {
 		{newRabinKarp64SplitterFactory(2048), 1887, 2649, 1028, 4096},
 		{newRabinKarp64SplitterFactory(32768), 121, 41322, 16896, 65536},
 		{newRabinKarp64SplitterFactory(65536), 53, 94339, 35875, 131072},
+		{newFastCDCSplitterFactory(32), 131305, 38, 17, 64},
+		{newFastCDCSplitterFactory(1024), 4175, 1197, 514, 2048},
+		{newFastCDCSplitterFactory(2048), 2062, 2424, 1028, 4096},
+		{newFastCDCSplitterFactory(32768), 131, 38167, 16693, 65536},
+		{newFastCDCSplitterFactory(65536), 67, 74626, 33786, 131072},
+		{newZipMemberSplitterFactory(newBuzHash32SplitterFactory(32768)), 112, 44642, 16413, 65536},
 
 		{Pooled(Fixed(1000)), 5000, 1000, 1000, 1000},
 
@@ -50,6 +58,12 @@ func TestSplitterStability(t *testing.T) {
 		{Pooled(newRabinKarp64SplitterFactory(2048)), 1887, 2649, 1028, 4096},
 		{Pooled(newRabinKarp64SplitterFactory(32768)), 121, 41322, 16896, 65536},
 		{Pooled(newRabinKarp64SplitterFactory(65536)), 53, 94339, 35875, 131072},
+		{Pooled(newFastCDCSplitterFactory(32)), 131305, 38, 17, 64},
+		{Pooled(newFastCDCSplitterFactory(1024)), 4175, 1197, 514, 2048},
+		{Pooled(newFastCDCSplitterFactory(2048)), 2062, 2424, 1028, 4096},
+		{Pooled(newFastCDCSplitterFactory(32768)), 131, 38167, 16693, 65536},
+		{Pooled(newFastCDCSplitterFactory(65536)), 67, 74626, 33786, 131072},
+		{Pooled(newZipMemberSplitterFactory(newBuzHash32SplitterFactory(32768))), 112, 44642, 16413, 65536},
 	}
 
 	// run each test twice to rule out the possibility of some state leaking through splitter reuse
@@ -119,6 +133,108 @@ func TestSplitterStability(t *testing.T) {
 	}
 }
 
+func TestPolicySplitterFactories(t *testing.T) {
+	for _, name := range SupportedPolicyAlgorithms() {
+		if GetFactory(name) != nil {
+			t.Errorf("policy splitter %v must not be usable as repository splitter", name)
+		}
+
+		if GetPolicyFactory(name) == nil {
+			t.Errorf("policy splitter %v not found", name)
+		}
+	}
+
+	for _, name := range SupportedAlgorithms() {
+		if GetPolicyFactory(name) == nil {
+			t.Errorf("splitter %v can't be selected by policy", name)
+		}
+	}
+
+	if GetPolicyFactory("ZIP-4M-BUZHASH") == nil {
+		t.Errorf("ZIP-4M-BUZHASH not found")
+	}
+}
+
+func TestZipMemberSplitter(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+
+	members := make([][]byte, 10)
+	for i := range members {
+		members[i] = make([]byte, 100000+r.Intn(200000))
+		r.Read(members[i])
+	}
+
+	original := chunkSet(t, makeZip(t, members))
+
+	// replace one member in the middle of the archive
+	members[4] = make([]byte, 150000)
+	r.Read(members[4])
+
+	modified := chunkSet(t, makeZip(t, members))
+
+	var unchanged int
+
+	for k := range modified {
+		if original[k] {
+			unchanged++
+		}
+	}
+
+	// all members except the modified one and the central directory should be in identical chunks.
+	if got, want := unchanged, len(members)-2; got < want {
+		t.Errorf("unexpected number of unchanged chunks: %v, want at least %v", got, want)
+	}
+}
+
+func makeZip(t *testing.T, members [][]byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	for i, m := range members {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:   fmt.Sprintf("member-%v", i),
+			Method: zip.Store,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func chunkSet(t *testing.T, data []byte) map[string]bool {
+	t.Helper()
+
+	s := GetPolicyFactory("ZIP-1M-BUZHASH")()
+	defer s.Close()
+
+	result := map[string]bool{}
+
+	for len(data) > 0 {
+		n := s.NextSplitPoint(data)
+		if n < 0 {
+			n = len(data)
+		}
+
+		result[string(data[0:n])] = true
+		data = data[n:]
+	}
+
+	return result
+}
+
 func getSplitPoints(data []byte, s Splitter) (minSplit, maxSplit, count int) {
 	maxSplit = 0
 	minSplit = int(math.MaxInt32)
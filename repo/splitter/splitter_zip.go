@@ -0,0 +1,96 @@
+package splitter
+
+// zipLocalFileHeaderSignature is the signature of ZIP local file header ("PK\x03\x04")
+// which precedes each member of ZIP-like archives (zip, jar, docx, odt, epub, etc.)
+const zipLocalFileHeaderSignature = 0x504b0304
+
+// zipMemberMinSize is the minimum size of a chunk that can be produced by splitting at member boundary,
+// which prevents archives consisting of many small members from being split into many tiny chunks.
+const zipMemberMinSize = 64 << 10
+
+// zipMemberSplitter splits ZIP-like archives at member boundaries, so that unchanged members
+// of modified archives end up in identical chunks. Members larger than the maximum segment size
+// are further split using the inner splitter.
+//
+// Split points are placed immediately after each local file header signature.
+type zipMemberSplitter struct {
+	inner   Splitter
+	window  uint32 // last 4 bytes consumed
+	count   int    // number of bytes consumed since the last split point
+	minSize int
+}
+
+func (s *zipMemberSplitter) Close() {
+	s.inner.Close()
+}
+
+func (s *zipMemberSplitter) Reset() {
+	s.inner.Reset()
+	s.window = 0
+	s.count = 0
+}
+
+func (s *zipMemberSplitter) NextSplitPoint(b []byte) int {
+	// find the first member boundary, without consuming any bytes yet.
+	boundary := -1
+	w, cnt := s.window, s.count
+
+	for i, c := range b {
+		w = w<<8 | uint32(c) //nolint:gomnd
+		cnt++
+
+		if w == zipLocalFileHeaderSignature && cnt >= s.minSize {
+			boundary = i + 1
+			break
+		}
+	}
+
+	prefix := b
+	if boundary >= 0 {
+		prefix = b[0:boundary]
+	}
+
+	// the inner splitter may find a split point before the member boundary.
+	if n := s.inner.NextSplitPoint(prefix); n >= 0 {
+		s.consume(prefix[0:n])
+		s.count = 0
+
+		return n
+	}
+
+	s.consume(prefix)
+
+	if boundary < 0 {
+		return -1
+	}
+
+	s.inner.Reset()
+	s.count = 0
+
+	return boundary
+}
+
+func (s *zipMemberSplitter) consume(b []byte) {
+	s.count += len(b)
+
+	if len(b) > 4 { //nolint:gomnd
+		b = b[len(b)-4:]
+	}
+
+	for _, c := range b {
+		s.window = s.window<<8 | uint32(c) //nolint:gomnd
+	}
+}
+
+func (s *zipMemberSplitter) MaxSegmentSize() int {
+	return s.inner.MaxSegmentSize()
+}
+
+func newZipMemberSplitterFactory(inner Factory) Factory {
+	return func() Splitter {
+		return &zipMemberSplitter{
+			inner:   inner(),
+			minSize: zipMemberMinSize,
+		}
+	}
+}
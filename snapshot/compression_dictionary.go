@@ -0,0 +1,87 @@
+package snapshot
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+// CompressionDictionaryManifestType is the value of the "type" label for compression dictionary manifests.
+const CompressionDictionaryManifestType = "compressionDictionary"
+
+// CompressionDictionary describes the compression dictionary trained for a snapshot source.
+type CompressionDictionary struct {
+	Source      SourceInfo      `json:"source"`
+	ContentID   content.ID      `json:"contentID"`
+	SnapshotID  manifest.ID     `json:"snapshotID"`
+	TrainedTime fs.UTCTimestamp `json:"trainedTime"`
+	SampleCount int             `json:"sampleCount"`
+	SampleBytes int64           `json:"sampleBytes"`
+}
+
+func compressionDictionaryLabels(si SourceInfo) map[string]string {
+	labels := sourceInfoToLabels(si)
+	labels[typeKey] = CompressionDictionaryManifestType
+
+	return labels
+}
+
+// FindCompressionDictionary returns the compression dictionary trained for the provided source or nil if none exists.
+func FindCompressionDictionary(ctx context.Context, rep repo.Repository, si SourceInfo) (*CompressionDictionary, error) {
+	entries, err := rep.FindManifests(ctx, compressionDictionaryLabels(si))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find compression dictionary manifests")
+	}
+
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	d := &CompressionDictionary{}
+
+	if _, err := rep.GetManifest(ctx, manifest.PickLatestID(entries), d); err != nil {
+		return nil, errors.Wrap(err, "unable to load compression dictionary manifest")
+	}
+
+	return d, nil
+}
+
+// ListCompressionDictionaries returns the current compression dictionaries of all sources.
+func ListCompressionDictionaries(ctx context.Context, rep repo.Repository) ([]*CompressionDictionary, error) {
+	entries, err := rep.FindManifests(ctx, map[string]string{
+		typeKey: CompressionDictionaryManifestType,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find compression dictionary manifests")
+	}
+
+	var result []*CompressionDictionary
+
+	for _, e := range entries {
+		d := &CompressionDictionary{}
+
+		if _, err := rep.GetManifest(ctx, e.ID, d); err != nil {
+			return nil, errors.Wrap(err, "unable to load compression dictionary manifest")
+		}
+
+		result = append(result, d)
+	}
+
+	return result, nil
+}
+
+// SaveCompressionDictionary saves the provided compression dictionary, replacing the previous dictionary
+// of the same source. The previous dictionary content is retained until garbage collection finds that no contents
+// reference it.
+func SaveCompressionDictionary(ctx context.Context, rep repo.RepositoryWriter, d *CompressionDictionary) error {
+	if _, err := rep.ReplaceManifests(ctx, compressionDictionaryLabels(d.Source), d); err != nil {
+		return errors.Wrap(err, "unable to save compression dictionary manifest")
+	}
+
+	return nil
+}
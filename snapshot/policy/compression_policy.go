@@ -18,6 +18,7 @@ type CompressionPolicy struct {
 	NoParentNeverCompress bool             `json:"noParentNeverCompress,omitempty"`
 	MinSize               int64            `json:"minSize,omitempty"`
 	MaxSize               int64            `json:"maxSize,omitempty"`
+	UseDictionary         *OptionalBool    `json:"useDictionary,omitempty"`
 }
 
 // CompressionPolicyDefinition specifies which policy definition provided the value of a particular field.
@@ -27,6 +28,7 @@ type CompressionPolicyDefinition struct {
 	NeverCompress  snapshot.SourceInfo `json:"neverCompress,omitempty"`
 	MinSize        snapshot.SourceInfo `json:"minSize,omitempty"`
 	MaxSize        snapshot.SourceInfo `json:"maxSize,omitempty"`
+	UseDictionary  snapshot.SourceInfo `json:"useDictionary,omitempty"`
 }
 
 // CompressorForFile returns compression name to be used for compressing a given file according to policy, using attributes such as name or size.
@@ -62,6 +64,7 @@ func (p *CompressionPolicy) Merge(src CompressionPolicy, def *CompressionPolicyD
 	mergeCompressionName(&p.CompressorName, src.CompressorName, &def.CompressorName, si)
 	mergeInt64(&p.MinSize, src.MinSize, &def.MinSize, si)
 	mergeInt64(&p.MaxSize, src.MaxSize, &def.MaxSize, si)
+	mergeOptionalBool(&p.UseDictionary, src.UseDictionary, &def.UseDictionary, si)
 
 	mergeStrings(&p.OnlyCompress, &p.NoParentOnlyCompress, src.OnlyCompress, src.NoParentOnlyCompress, &def.OnlyCompress, si)
 	mergeStrings(&p.NeverCompress, &p.NoParentNeverCompress, src.NeverCompress, src.NoParentNeverCompress, &def.NeverCompress, si)
@@ -27,6 +27,7 @@ type Policy struct {
 	ErrorHandlingPolicy ErrorHandlingPolicy `json:"errorHandling,omitempty"`
 	SchedulingPolicy    SchedulingPolicy    `json:"scheduling,omitempty"`
 	CompressionPolicy   CompressionPolicy   `json:"compression,omitempty"`
+	SplitterPolicy      SplitterPolicy      `json:"splitter,omitempty"`
 	Actions             ActionsPolicy       `json:"actions,omitempty"`
 	LoggingPolicy       LoggingPolicy       `json:"logging,omitempty"`
 	UploadPolicy        UploadPolicy        `json:"upload,omitempty"`
@@ -41,6 +42,7 @@ type Definition struct {
 	ErrorHandlingPolicy ErrorHandlingPolicyDefinition `json:"errorHandling,omitempty"`
 	SchedulingPolicy    SchedulingPolicyDefinition    `json:"scheduling,omitempty"`
 	CompressionPolicy   CompressionPolicyDefinition   `json:"compression,omitempty"`
+	SplitterPolicy      SplitterPolicyDefinition      `json:"splitter,omitempty"`
 	Actions             ActionsPolicyDefinition       `json:"actions,omitempty"`
 	LoggingPolicy       LoggingPolicyDefinition       `json:"logging,omitempty"`
 	UploadPolicy        UploadPolicyDefinition        `json:"upload,omitempty"`
@@ -74,7 +76,7 @@ func (p *Policy) Target() snapshot.SourceInfo {
 }
 
 // ValidatePolicy returns error if the given policy is invalid.
-// Currently, only SchedulingPolicy is validated.
+// Currently, only SchedulingPolicy, UploadPolicy and SplitterPolicy are validated.
 func ValidatePolicy(si snapshot.SourceInfo, pol *Policy) error {
 	if err := ValidateSchedulingPolicy(pol.SchedulingPolicy); err != nil {
 		return errors.Wrap(err, "invalid scheduling policy")
@@ -84,6 +86,10 @@ func ValidatePolicy(si snapshot.SourceInfo, pol *Policy) error {
 		return errors.Wrap(err, "invalid upload policy")
 	}
 
+	if err := ValidateSplitterPolicy(pol.SplitterPolicy); err != nil {
+		return errors.Wrap(err, "invalid splitter policy")
+	}
+
 	return nil
 }
 
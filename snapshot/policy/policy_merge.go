@@ -24,6 +24,7 @@ func MergePolicies(policies []*Policy, si snapshot.SourceInfo) (*Policy, *Defini
 		merged.SchedulingPolicy.Merge(p.SchedulingPolicy, &def.SchedulingPolicy, p.Target())
 		merged.UploadPolicy.Merge(p.UploadPolicy, &def.UploadPolicy, p.Target())
 		merged.CompressionPolicy.Merge(p.CompressionPolicy, &def.CompressionPolicy, p.Target())
+		merged.SplitterPolicy.Merge(p.SplitterPolicy, &def.SplitterPolicy, p.Target())
 		merged.Actions.Merge(p.Actions, &def.Actions, p.Target())
 		merged.LoggingPolicy.Merge(p.LoggingPolicy, &def.LoggingPolicy, p.Target())
 
@@ -39,6 +40,7 @@ func MergePolicies(policies []*Policy, si snapshot.SourceInfo) (*Policy, *Defini
 	merged.SchedulingPolicy.Merge(defaultSchedulingPolicy, &def.SchedulingPolicy, GlobalPolicySourceInfo)
 	merged.UploadPolicy.Merge(defaultUploadPolicy, &def.UploadPolicy, GlobalPolicySourceInfo)
 	merged.CompressionPolicy.Merge(defaultCompressionPolicy, &def.CompressionPolicy, GlobalPolicySourceInfo)
+	merged.SplitterPolicy.Merge(defaultSplitterPolicy, &def.SplitterPolicy, GlobalPolicySourceInfo)
 	merged.Actions.Merge(defaultActionsPolicy, &def.Actions, GlobalPolicySourceInfo)
 	merged.LoggingPolicy.Merge(defaultLoggingPolicy, &def.LoggingPolicy, GlobalPolicySourceInfo)
 
@@ -83,6 +85,21 @@ func mergeStringsReplace(target *[]string, src []string, def *snapshot.SourceInf
 	}
 }
 
+// mergeStringsReplaceNoParent is like mergeStringsReplace, but an empty list is not inherited
+// from the parent when noParent is set.
+func mergeStringsReplaceNoParent(target *[]string, targetNoParent *bool, src []string, noParent bool, def *snapshot.SourceInfo, si snapshot.SourceInfo) {
+	if *targetNoParent || len(*target) > 0 {
+		// already defined by a more specific policy.
+		return
+	}
+
+	if len(src) > 0 || noParent {
+		*target = src
+		*targetNoParent = noParent
+		*def = si
+	}
+}
+
 func mergeStrings(target *[]string, targetNoParent *bool, src []string, noParent bool, def *snapshot.SourceInfo, si snapshot.SourceInfo) {
 	if *targetNoParent {
 		// merges prevented
@@ -122,6 +139,13 @@ func mergeCompressionName(target *compression.Name, src compression.Name, def *s
 	}
 }
 
+func mergeString(target *string, src string, def *snapshot.SourceInfo, si snapshot.SourceInfo) {
+	if *target == "" && src != "" {
+		*target = src
+		*def = si
+	}
+}
+
 func mergeInt64(target *int64, src int64, def *snapshot.SourceInfo, si snapshot.SourceInfo) {
 	if *target == 0 && src != 0 {
 		*target = src
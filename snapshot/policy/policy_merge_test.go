@@ -20,6 +20,8 @@
 	"SchedulingPolicyDefinition.NoParentTimesOfDay":     true, // special
 	"CompressionPolicyDefinition.NoParentOnlyCompress":  true,
 	"CompressionPolicyDefinition.NoParentNeverCompress": true,
+	"SplitterPolicyDefinition.NoParentBlockAligned":     true,
+	"SplitterPolicyDefinition.NoParentArchiveMembers":   true,
 }
 
 func TestPolicyDefinition(t *testing.T) {
@@ -148,6 +150,10 @@ func testPolicyMergeSingleField(t *testing.T, fieldName string, typ reflect.Type
 		v0 = reflect.ValueOf([]policy.TimeOfDay{})
 		v1 = reflect.ValueOf([]policy.TimeOfDay{{Hour: 10}})
 		v2 = reflect.ValueOf([]policy.TimeOfDay{{Hour: 11}})
+	case "string":
+		v0 = reflect.ValueOf("")
+		v1 = reflect.ValueOf("foo")
+		v2 = reflect.ValueOf("bar")
 	case "compression.Name":
 		v0 = reflect.ValueOf(compression.Name(""))
 		v1 = reflect.ValueOf(compression.Name("foo"))
@@ -13,6 +13,27 @@
 		CompressorName: "none",
 	}
 
+	// defaultSplitterPolicy does not select per-file splitters for any extensions, so that files in existing
+	// repositories keep being split using the repository splitter unless the lists are configured explicitly.
+	defaultSplitterPolicy = SplitterPolicy{
+		BlockAlignedAlgorithm:   "FIXED-1M",
+		ArchiveMembersAlgorithm: "ZIP-4M-BUZHASH",
+	}
+
+	// newRepositorySplitterPolicy uses block-aligned fixed splitting for VM disk images and splits
+	// ZIP-like archives at member boundaries. It is only written to the global policy of new repositories,
+	// since changing splitters of existing files would prevent their deduplication with previous snapshots.
+	newRepositorySplitterPolicy = SplitterPolicy{
+		BlockAligned: []string{
+			".img", ".qcow2", ".raw", ".vdi", ".vhd", ".vhdx", ".vmdk",
+		},
+		BlockAlignedAlgorithm: defaultSplitterPolicy.BlockAlignedAlgorithm,
+		ArchiveMembers: []string{
+			".apk", ".docx", ".ear", ".epub", ".jar", ".odp", ".ods", ".odt", ".pptx", ".war", ".whl", ".xlsx", ".zip",
+		},
+		ArchiveMembersAlgorithm: defaultSplitterPolicy.ArchiveMembersAlgorithm,
+	}
+
 	// defaultErrorHandlingPolicy is the default error handling policy.
 	defaultErrorHandlingPolicy = ErrorHandlingPolicy{
 		IgnoreFileErrors:      newOptionalBool(false),
@@ -64,6 +85,7 @@
 		FilesPolicy:         defaultFilesPolicy,
 		RetentionPolicy:     defaultRetentionPolicy,
 		CompressionPolicy:   defaultCompressionPolicy,
+		SplitterPolicy:      defaultSplitterPolicy,
 		ErrorHandlingPolicy: defaultErrorHandlingPolicy,
 		SchedulingPolicy:    defaultSchedulingPolicy,
 		LoggingPolicy:       defaultLoggingPolicy,
@@ -71,10 +93,21 @@
 		UploadPolicy:        defaultUploadPolicy,
 	}
 
+	// NewRepositoryGlobalPolicy is the global policy written to new repositories, which is the default policy
+	// extended with settings that only apply to newly created repositories.
+	NewRepositoryGlobalPolicy = newRepositoryGlobalPolicy()
+
 	// DefaultDefinition provides the Definition for the default policy.
 	DefaultDefinition = &Definition{}
 )
 
+func newRepositoryGlobalPolicy() *Policy {
+	p := *DefaultPolicy
+	p.SplitterPolicy = newRepositorySplitterPolicy
+
+	return &p
+}
+
 // Tree represents a node in the policy tree, where a policy can be
 // defined. A nil tree is a valid tree with default policy.
 type Tree struct {
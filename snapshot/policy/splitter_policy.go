@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/repo/splitter"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// SplitterPolicy specifies the policy for choosing the splitter used to break file contents into chunks.
+// Extension lists are not merged with lists of parent policies, but replace them. Empty lists are inherited
+// from parent policies, unless the corresponding NoParent flag is set.
+type SplitterPolicy struct {
+	Algorithm               string   `json:"algorithm,omitempty"`
+	BlockAligned            []string `json:"blockAligned,omitempty"`
+	NoParentBlockAligned    bool     `json:"noParentBlockAligned,omitempty"`
+	BlockAlignedAlgorithm   string   `json:"blockAlignedAlgorithm,omitempty"`
+	ArchiveMembers          []string `json:"archiveMembers,omitempty"`
+	NoParentArchiveMembers  bool     `json:"noParentArchiveMembers,omitempty"`
+	ArchiveMembersAlgorithm string   `json:"archiveMembersAlgorithm,omitempty"`
+}
+
+// SplitterPolicyDefinition specifies which policy definition provided the value of a particular field.
+type SplitterPolicyDefinition struct {
+	Algorithm               snapshot.SourceInfo `json:"algorithm,omitempty"`
+	BlockAligned            snapshot.SourceInfo `json:"blockAligned,omitempty"`
+	BlockAlignedAlgorithm   snapshot.SourceInfo `json:"blockAlignedAlgorithm,omitempty"`
+	ArchiveMembers          snapshot.SourceInfo `json:"archiveMembers,omitempty"`
+	ArchiveMembersAlgorithm snapshot.SourceInfo `json:"archiveMembersAlgorithm,omitempty"`
+}
+
+// SplitterForFile returns the name of the splitter to be used for a given file according to policy,
+// using attributes such as name. Extensions are matched case-insensitively.
+// Empty string means repository default splitter.
+func (p *SplitterPolicy) SplitterForFile(e fs.Entry) string {
+	ext := filepath.Ext(e.Name())
+
+	if containsExtension(p.BlockAligned, ext) && p.BlockAlignedAlgorithm != "" {
+		return p.BlockAlignedAlgorithm
+	}
+
+	if containsExtension(p.ArchiveMembers, ext) && p.ArchiveMembersAlgorithm != "" {
+		return p.ArchiveMembersAlgorithm
+	}
+
+	return p.Algorithm
+}
+
+// Merge applies default values from the provided policy.
+func (p *SplitterPolicy) Merge(src SplitterPolicy, def *SplitterPolicyDefinition, si snapshot.SourceInfo) {
+	mergeString(&p.Algorithm, src.Algorithm, &def.Algorithm, si)
+	mergeString(&p.BlockAlignedAlgorithm, src.BlockAlignedAlgorithm, &def.BlockAlignedAlgorithm, si)
+	mergeString(&p.ArchiveMembersAlgorithm, src.ArchiveMembersAlgorithm, &def.ArchiveMembersAlgorithm, si)
+
+	mergeStringsReplaceNoParent(&p.BlockAligned, &p.NoParentBlockAligned, src.BlockAligned, src.NoParentBlockAligned, &def.BlockAligned, si)
+	mergeStringsReplaceNoParent(&p.ArchiveMembers, &p.NoParentArchiveMembers, src.ArchiveMembers, src.NoParentArchiveMembers, &def.ArchiveMembers, si)
+}
+
+func containsExtension(slice []string, ext string) bool {
+	if ext == "" {
+		return false
+	}
+
+	for _, v := range slice {
+		if strings.EqualFold(v, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateSplitterPolicy returns an error if the policy references unsupported splitters.
+// Splitters which can only be selected by the policy are only allowed for archive members.
+func ValidateSplitterPolicy(p SplitterPolicy) error {
+	for _, name := range []string{p.Algorithm, p.BlockAlignedAlgorithm} {
+		if name != "" && splitter.GetFactory(name) == nil {
+			return errors.Errorf("unsupported splitter %q", name)
+		}
+	}
+
+	if name := p.ArchiveMembersAlgorithm; name != "" && splitter.GetPolicyFactory(name) == nil {
+		return errors.Errorf("unsupported archive splitter %q", name)
+	}
+
+	return nil
+}
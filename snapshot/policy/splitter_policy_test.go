@@ -0,0 +1,103 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+func TestSplitterForFile(t *testing.T) {
+	dir := mockfs.NewDirectory()
+
+	global := &policy.Policy{
+		SplitterPolicy: policy.SplitterPolicy{
+			Algorithm:      "DYNAMIC-1M-FASTCDC",
+			BlockAligned:   []string{".bin", ".vmdk"},
+			ArchiveMembers: []string{".jar", ".zip"},
+		},
+	}
+
+	p, _ := policy.MergePolicies([]*policy.Policy{global, policy.DefaultPolicy}, snapshot.SourceInfo{})
+
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"disk.vmdk", "FIXED-1M"},
+		{"DISK.VMDK", "FIXED-1M"},
+		{"firmware.bin", "FIXED-1M"},
+		{"disk.qcow2", "DYNAMIC-1M-FASTCDC"},
+		{"archive.zip", "ZIP-4M-BUZHASH"},
+		{"app.jar", "ZIP-4M-BUZHASH"},
+		{"Archive.Zip", "ZIP-4M-BUZHASH"},
+		{"notes.txt", "DYNAMIC-1M-FASTCDC"},
+		{"no-extension", "DYNAMIC-1M-FASTCDC"},
+	}
+
+	for _, tc := range cases {
+		require.Equal(t, tc.want, p.SplitterPolicy.SplitterForFile(dir.AddFile(tc.name, nil, 0o644)), tc.name)
+	}
+
+	// lists of child policies replace lists of their parents, so extensions can be removed.
+	child := &policy.Policy{
+		SplitterPolicy: policy.SplitterPolicy{
+			ArchiveMembers: []string{".zip"},
+		},
+	}
+
+	p, def := policy.MergePolicies([]*policy.Policy{child, global, policy.DefaultPolicy}, snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/child"})
+	require.Equal(t, []string{".zip"}, p.SplitterPolicy.ArchiveMembers)
+	require.Equal(t, []string{".bin", ".vmdk"}, p.SplitterPolicy.BlockAligned)
+	require.Equal(t, "ZIP-4M-BUZHASH", p.SplitterPolicy.SplitterForFile(dir.AddFile("archive.zip", nil, 0o644)))
+	require.Equal(t, "DYNAMIC-1M-FASTCDC", p.SplitterPolicy.SplitterForFile(dir.AddFile("app.jar", nil, 0o644)))
+	require.Equal(t, policy.GlobalPolicySourceInfo, def.SplitterPolicy.ArchiveMembersAlgorithm)
+
+	// lists can be disabled without inheriting them from the parent.
+	noArchives := &policy.Policy{
+		SplitterPolicy: policy.SplitterPolicy{
+			NoParentArchiveMembers: true,
+		},
+	}
+
+	p, _ = policy.MergePolicies([]*policy.Policy{noArchives, global, policy.DefaultPolicy}, snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/child"})
+	require.Empty(t, p.SplitterPolicy.ArchiveMembers)
+	require.Equal(t, "DYNAMIC-1M-FASTCDC", p.SplitterPolicy.SplitterForFile(dir.AddFile("archive.zip", nil, 0o644)))
+	require.Equal(t, "FIXED-1M", p.SplitterPolicy.SplitterForFile(dir.AddFile("disk.vmdk", nil, 0o644)))
+
+	// default policy does not use per-file splitters until extensions are added.
+	for _, name := range []string{"other.txt", "no-ext", "disk.vmdk", "disk.qcow2", "disk.img", "archive.zip", "report.docx"} {
+		require.Equal(t, "", policy.DefaultPolicy.SplitterPolicy.SplitterForFile(dir.AddFile(name, nil, 0o644)), name)
+	}
+
+	// global policy of new repositories uses block-aligned splitting for disk images and member splitting for archives.
+	for name, want := range map[string]string{
+		"other.txt":   "",
+		"no-ext":      "",
+		"disk.vmdk":   "FIXED-1M",
+		"disk.QCOW2":  "FIXED-1M",
+		"archive.zip": "ZIP-4M-BUZHASH",
+		"report.docx": "ZIP-4M-BUZHASH",
+	} {
+		require.Equal(t, want, policy.NewRepositoryGlobalPolicy.SplitterPolicy.SplitterForFile(dir.AddFile(name, nil, 0o644)), name)
+	}
+}
+
+func TestValidateSplitterPolicy(t *testing.T) {
+	require.NoError(t, policy.ValidateSplitterPolicy(policy.SplitterPolicy{}))
+	require.NoError(t, policy.ValidateSplitterPolicy(policy.SplitterPolicy{
+		Algorithm:               "DYNAMIC-4M-FASTCDC",
+		BlockAlignedAlgorithm:   "FIXED-4M",
+		ArchiveMembersAlgorithm: "ZIP-8M-FASTCDC",
+	}))
+	require.Error(t, policy.ValidateSplitterPolicy(policy.SplitterPolicy{Algorithm: "no-such-splitter"}))
+	require.Error(t, policy.ValidateSplitterPolicy(policy.SplitterPolicy{BlockAlignedAlgorithm: "no-such-splitter"}))
+	require.Error(t, policy.ValidateSplitterPolicy(policy.SplitterPolicy{ArchiveMembersAlgorithm: "no-such-splitter"}))
+
+	// archive member splitters can't be used for all files.
+	require.Error(t, policy.ValidateSplitterPolicy(policy.SplitterPolicy{Algorithm: "ZIP-4M-BUZHASH"}))
+	require.Error(t, policy.ValidateSplitterPolicy(policy.SplitterPolicy{BlockAlignedAlgorithm: "ZIP-4M-BUZHASH"}))
+}
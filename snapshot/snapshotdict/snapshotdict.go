@@ -0,0 +1,280 @@
+// Package snapshotdict implements training of per-source compression dictionaries from snapshot contents.
+package snapshotdict
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/compression"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
+	"github.com/kopia/kopia/repo/logging"
+	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+var log = logging.Module("snapshotdict")
+
+const (
+	// MaxDictionarySize is the maximum size of a trained dictionary.
+	MaxDictionarySize = 64 << 10
+
+	// maxSampleFileSize is the maximum size of a file sampled for training, larger files
+	// are not compressed using dictionaries.
+	maxSampleFileSize = 64 << 10
+
+	// maxSampleSize is the maximum number of bytes sampled from a single file.
+	maxSampleSize = 16 << 10
+
+	// maxSampleCount is the maximum number of files sampled for training.
+	maxSampleCount = 2000
+
+	// minSampleCount is the minimum number of sampled files required to train a dictionary.
+	minSampleCount = 10
+
+	// RetrainInterval is the minimum time between training dictionaries for the same source.
+	RetrainInterval = 7 * 24 * time.Hour
+
+	// maxDictionariesPerSource is the average number of dictionaries per source retained in the repository
+	// above which retraining is suspended until garbage collection deletes dictionaries that are no longer
+	// referenced by any contents.
+	maxDictionariesPerSource = 3
+)
+
+// errEnoughSamples is used to stop iteration once enough samples have been collected.
+var errEnoughSamples = errors.New("enough samples")
+
+// Run trains compression dictionaries for all sources with dictionary compression enabled
+// whose dictionaries are missing or out of date.
+func Run(ctx context.Context, rep repo.DirectRepositoryWriter, force bool) error {
+	//nolint:wrapcheck
+	return maintenance.ReportRun(ctx, rep, maintenance.TaskTrainCompressionDictionaries, nil, func(ctx context.Context) error {
+		return runInternal(ctx, rep, force)
+	})
+}
+
+func runInternal(ctx context.Context, rep repo.DirectRepositoryWriter, force bool) error {
+	sources, err := snapshot.ListSources(ctx, rep)
+	if err != nil {
+		return errors.Wrap(err, "unable to list sources")
+	}
+
+	canRetrain, err := canRetrainDictionaries(ctx, rep)
+	if err != nil {
+		return err
+	}
+
+	for _, si := range sources {
+		pol, _, _, err := policy.GetEffectivePolicy(ctx, rep, si)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get effective policy for %v", si)
+		}
+
+		if !pol.CompressionPolicy.UseDictionary.OrDefault(false) {
+			continue
+		}
+
+		if _, err := trainForSource(ctx, rep, si, force, canRetrain); err != nil {
+			return errors.Wrapf(err, "unable to train compression dictionary for %v", si)
+		}
+	}
+
+	return nil
+}
+
+// TrainForSource trains and saves compression dictionary for the provided source using files from its latest
+// snapshot, unless the current dictionary is recent or was trained using the same snapshot.
+// Returns nil if no dictionary has been trained.
+func TrainForSource(ctx context.Context, rep repo.DirectRepositoryWriter, si snapshot.SourceInfo, force bool) (*snapshot.CompressionDictionary, error) {
+	canRetrain, err := canRetrainDictionaries(ctx, rep)
+	if err != nil {
+		return nil, err
+	}
+
+	return trainForSource(ctx, rep, si, force, canRetrain)
+}
+
+func trainForSource(ctx context.Context, rep repo.DirectRepositoryWriter, si snapshot.SourceInfo, force, canRetrain bool) (*snapshot.CompressionDictionary, error) {
+	man, err := latestSnapshot(ctx, rep, si)
+	if err != nil || man == nil {
+		return nil, err
+	}
+
+	existing, err := snapshot.FindCompressionDictionary(ctx, rep, si)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find existing dictionary")
+	}
+
+	if existing != nil && !force {
+		if existing.SnapshotID == man.ID || rep.Time().Sub(existing.TrainedTime.ToTime()) < RetrainInterval {
+			log(ctx).Debugf("compression dictionary for %v is up to date", si)
+			return nil, nil
+		}
+	}
+
+	if existing != nil && !canRetrain {
+		log(ctx).Infof("Not retraining compression dictionary for %v until unused dictionaries are garbage-collected.", si)
+		return nil, nil
+	}
+
+	root, err := snapshotfs.SnapshotRoot(rep, man)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get snapshot root")
+	}
+
+	samples, sampleBytes, err := collectSamples(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(samples) < minSampleCount {
+		log(ctx).Debugf("not enough small files to train compression dictionary for %v", si)
+		return nil, nil
+	}
+
+	dict := compression.TrainDictionary(samples, MaxDictionarySize)
+	if dict == nil {
+		log(ctx).Debugf("files of %v have too little in common to train compression dictionary", si)
+		return nil, nil
+	}
+
+	if err := repo.EnableCompressionDictionaries(ctx, rep); err != nil {
+		return nil, errors.Wrap(err, "unable to enable compression dictionaries")
+	}
+
+	cid, err := rep.ContentManager().WriteCompressionDictionary(ctx, gather.FromSlice(dict))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to write dictionary content")
+	}
+
+	d := &snapshot.CompressionDictionary{
+		Source:      si,
+		ContentID:   cid,
+		SnapshotID:  man.ID,
+		TrainedTime: fs.UTCTimestampFromTime(rep.Time()),
+		SampleCount: len(samples),
+		SampleBytes: sampleBytes,
+	}
+
+	if err := snapshot.SaveCompressionDictionary(ctx, rep, d); err != nil {
+		return nil, errors.Wrap(err, "unable to save dictionary")
+	}
+
+	log(ctx).Infof("Trained compression dictionary for %v (%v) from %v files.", si, units.BytesString(int64(len(dict))), len(samples))
+
+	return d, nil
+}
+
+// canRetrainDictionaries returns true unless the repository holds too many dictionaries compared
+// to the number of sources that use them.
+func canRetrainDictionaries(ctx context.Context, rep repo.DirectRepositoryWriter) (bool, error) {
+	current, err := snapshot.ListCompressionDictionaries(ctx, rep)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to list compression dictionaries")
+	}
+
+	var count int
+
+	if err := rep.ContentReader().IterateContents(ctx, content.IterateOptions{
+		Range: index.PrefixRange(content.CompressionDictionaryPrefix),
+	}, func(content.Info) error {
+		count++
+		return nil
+	}); err != nil {
+		return false, errors.Wrap(err, "unable to count compression dictionaries")
+	}
+
+	return count < maxDictionariesPerSource*len(current), nil
+}
+
+func latestSnapshot(ctx context.Context, rep repo.Repository, si snapshot.SourceInfo) (*snapshot.Manifest, error) {
+	mans, err := snapshot.ListSnapshots(ctx, rep, si)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list snapshots")
+	}
+
+	var latest *snapshot.Manifest
+
+	for _, m := range mans {
+		if m.IncompleteReason != "" {
+			continue
+		}
+
+		if latest == nil || m.StartTime.After(latest.StartTime) {
+			latest = m
+		}
+	}
+
+	return latest, nil
+}
+
+// collectSamples returns samples of small files found in the provided directory tree, visiting
+// directories breadth-first.
+func collectSamples(ctx context.Context, root fs.Entry) (samples [][]byte, sampleBytes int64, err error) {
+	dirs := []fs.Directory{}
+
+	if d, ok := root.(fs.Directory); ok {
+		dirs = append(dirs, d)
+	}
+
+	for len(dirs) > 0 && len(samples) < maxSampleCount {
+		dir := dirs[0]
+		dirs = dirs[1:]
+
+		err := dir.IterateEntries(ctx, func(ctx context.Context, e fs.Entry) error {
+			switch e := e.(type) {
+			case fs.Directory:
+				dirs = append(dirs, e)
+
+			case fs.File:
+				if e.Size() == 0 || e.Size() > maxSampleFileSize {
+					return nil
+				}
+
+				s, err := readSample(ctx, e)
+				if err != nil {
+					return err
+				}
+
+				samples = append(samples, s)
+				sampleBytes += int64(len(s))
+
+				if len(samples) >= maxSampleCount {
+					return errEnoughSamples
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil && !errors.Is(err, errEnoughSamples) {
+			return nil, 0, errors.Wrap(err, "error reading directory")
+		}
+	}
+
+	return samples, sampleBytes, nil
+}
+
+func readSample(ctx context.Context, f fs.File) ([]byte, error) {
+	r, err := f.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open %v", f.Name())
+	}
+	defer r.Close() //nolint:errcheck
+
+	b, err := io.ReadAll(io.LimitReader(r, maxSampleSize))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read %v", f.Name())
+	}
+
+	return b, nil
+}
@@ -0,0 +1,267 @@
+package snapshotfs
+
+import (
+	"context"
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/bigmap"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// ExclusiveStorageUsage describes contents referenced by a snapshot or a source, split into contents
+// referenced exclusively by it, which would be freed by deleting it, and contents shared with others.
+type ExclusiveStorageUsage struct {
+	// number of contents and their packed size referenced only by the snapshot or source.
+	// +checkatomic
+	UniqueContentCount int64 `json:"uniqueContentCount"`
+	// +checkatomic
+	UniqueBytes int64 `json:"uniqueBytes"`
+
+	// number of contents and their packed size also referenced by other snapshots or sources.
+	// +checkatomic
+	SharedContentCount int64 `json:"sharedContentCount"`
+	// +checkatomic
+	SharedBytes int64 `json:"sharedBytes"`
+}
+
+// SnapshotStorageUsage describes storage used by a single snapshot, compared to all other snapshots.
+type SnapshotStorageUsage struct {
+	ID        manifest.ID     `json:"id"`
+	StartTime fs.UTCTimestamp `json:"startTime"`
+	ExclusiveStorageUsage
+}
+
+// SourceStorageUsage describes storage used by all snapshots of a single source, compared to other sources.
+type SourceStorageUsage struct {
+	Source        snapshot.SourceInfo `json:"source"`
+	SnapshotCount int                 `json:"snapshotCount"`
+	ExclusiveStorageUsage
+
+	// Snapshots contains usage of individual snapshots of the source, oldest first.
+	Snapshots []*SnapshotStorageUsage `json:"snapshots,omitempty"`
+}
+
+// CalculateSourceStorageUsage walks the provided snapshots and determines for each of their sources
+// the amount of storage referenced only by snapshots of that source and shared with other sources.
+// When includeSnapshots is true, the same is determined for each individual snapshot, which requires
+// walking each snapshot separately and is therefore slower.
+func CalculateSourceStorageUsage(ctx context.Context, rep repo.Repository, manifests []*snapshot.Manifest, includeSnapshots bool) ([]*SourceStorageUsage, error) {
+	sources, err := newUsageTracker(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer sources.close(ctx)
+
+	snapshots, err := newUsageTracker(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer snapshots.close(ctx)
+
+	result := []*SourceStorageUsage{}
+
+	for _, group := range snapshot.GroupBySource(manifests) {
+		u := &SourceStorageUsage{
+			Source:        group[0].Source,
+			SnapshotCount: len(group),
+		}
+
+		result = append(result, u)
+		sourceOwner := sources.addOwner(&u.ExclusiveStorageUsage)
+
+		if !includeSnapshots {
+			if err := walkUniqueContents(ctx, rep, group, func(cid []byte, packedLength int64) error {
+				return sources.reference(ctx, sourceOwner, cid, packedLength)
+			}); err != nil {
+				return nil, errors.Wrapf(err, "error processing snapshots of %v", u.Source)
+			}
+
+			continue
+		}
+
+		if err := calculateSnapshotStorageUsage(ctx, rep, u, group, sources, sourceOwner, snapshots); err != nil {
+			return nil, errors.Wrapf(err, "error processing snapshots of %v", u.Source)
+		}
+	}
+
+	return result, nil
+}
+
+// calculateSnapshotStorageUsage walks snapshots of a single source one by one, accounting their contents
+// both to the individual snapshots and to the source.
+func calculateSnapshotStorageUsage(ctx context.Context, rep repo.Repository, u *SourceStorageUsage, group []*snapshot.Manifest, sources *usageTracker, sourceOwner uint32, snapshots *usageTracker) error {
+	// contents already accounted to the source by its previous snapshots.
+	sourceSeen, err := bigmap.NewSet(ctx)
+	if err != nil {
+		return errors.Wrap(err, "NewSet")
+	}
+
+	defer sourceSeen.Close(ctx)
+
+	for _, m := range snapshot.SortByTime(group, false) {
+		su := &SnapshotStorageUsage{
+			ID:        m.ID,
+			StartTime: m.StartTime,
+		}
+
+		u.Snapshots = append(u.Snapshots, su)
+		snapshotOwner := snapshots.addOwner(&su.ExclusiveStorageUsage)
+
+		if err := walkUniqueContents(ctx, rep, []*snapshot.Manifest{m}, func(cid []byte, packedLength int64) error {
+			if err := snapshots.reference(ctx, snapshotOwner, cid, packedLength); err != nil {
+				return err
+			}
+
+			if !sourceSeen.Put(ctx, cid) {
+				return nil
+			}
+
+			return sources.reference(ctx, sourceOwner, cid, packedLength)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// usageTracker determines which contents are referenced by only one of its owners, by remembering the first owner
+// referencing each content and the set of contents referenced by more than one owner.
+type usageTracker struct {
+	owners *bigmap.Map
+	shared *bigmap.Set
+	usage  []*ExclusiveStorageUsage
+}
+
+func newUsageTracker(ctx context.Context) (*usageTracker, error) {
+	owners, err := bigmap.NewMap(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewMap")
+	}
+
+	shared, err := bigmap.NewSet(ctx)
+	if err != nil {
+		owners.Close(ctx)
+		return nil, errors.Wrap(err, "NewSet")
+	}
+
+	return &usageTracker{owners: owners, shared: shared}, nil
+}
+
+func (t *usageTracker) close(ctx context.Context) {
+	t.owners.Close(ctx)
+	t.shared.Close(ctx)
+}
+
+// addOwner registers a new owner, whose usage will be updated as contents are referenced.
+func (t *usageTracker) addOwner(u *ExclusiveStorageUsage) uint32 {
+	t.usage = append(t.usage, u)
+
+	return uint32(len(t.usage) - 1)
+}
+
+// reference records that the owner references the content, which must be reported at most once per owner.
+// Owners must be registered before their contents are referenced.
+func (t *usageTracker) reference(ctx context.Context, owner uint32, cid []byte, packedLength int64) error {
+	var ownerBytes [4]byte
+
+	binary.BigEndian.PutUint32(ownerBytes[:], owner)
+
+	u := t.usage[owner]
+
+	if t.owners.PutIfAbsent(ctx, cid, ownerBytes[:]) {
+		atomic.AddInt64(&u.UniqueContentCount, 1)
+		atomic.AddInt64(&u.UniqueBytes, packedLength)
+
+		return nil
+	}
+
+	atomic.AddInt64(&u.SharedContentCount, 1)
+	atomic.AddInt64(&u.SharedBytes, packedLength)
+
+	if !t.shared.Put(ctx, cid) {
+		return nil
+	}
+
+	// first time the content is found to be shared, move it from unique to shared usage of its first owner.
+	v, ok, err := t.owners.Get(ctx, ownerBytes[:0], cid)
+	if err != nil || !ok || len(v) != len(ownerBytes) {
+		return errors.Errorf("unable to get owner of content %x", cid)
+	}
+
+	o := t.usage[binary.BigEndian.Uint32(v)]
+
+	atomic.AddInt64(&o.UniqueContentCount, -1)
+	atomic.AddInt64(&o.UniqueBytes, -packedLength)
+	atomic.AddInt64(&o.SharedContentCount, 1)
+	atomic.AddInt64(&o.SharedBytes, packedLength)
+
+	return nil
+}
+
+// walkUniqueContents invokes the provided callback exactly once for each content referenced by the provided snapshots.
+func walkUniqueContents(ctx context.Context, rep repo.Repository, snapshots []*snapshot.Manifest, callback func(cid []byte, packedLength int64) error) error {
+	seen, err := bigmap.NewSet(ctx)
+	if err != nil {
+		return errors.Wrap(err, "NewSet")
+	}
+
+	defer seen.Close(ctx)
+
+	tw, twerr := NewTreeWalker(ctx, TreeWalkerOptions{
+		EntryCallback: func(ctx context.Context, entry fs.Entry, oid object.ID, entryPath string) error {
+			contentIDs, err := rep.VerifyObject(ctx, oid)
+			if err != nil {
+				return errors.Wrapf(err, "error verifying object %v", oid)
+			}
+
+			var cidbuf [128]byte
+
+			for _, cid := range contentIDs {
+				key := cid.Append(cidbuf[:0])
+
+				if !seen.Put(ctx, key) {
+					continue
+				}
+
+				info, err := rep.ContentInfo(ctx, cid)
+				if err != nil {
+					return errors.Wrapf(err, "error getting content info for %v", cid)
+				}
+
+				if err := callback(key, int64(info.GetPackedLength())); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	})
+	if twerr != nil {
+		return errors.Wrap(twerr, "tree walker")
+	}
+
+	defer tw.Close(ctx)
+
+	for _, snap := range snapshots {
+		root, err := SnapshotRoot(rep, snap)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get snapshot root of %v", snap.ID)
+		}
+
+		if err := tw.Process(ctx, root, ""); err != nil {
+			return errors.Wrapf(err, "error processing snapshot %v", snap.ID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package snapshotfs_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+func TestCalculateSourceStorageUsage(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	// each root directory has one unique file and one file shared with the other source.
+	root1 := mockfs.NewDirectory()
+	root1.AddFile("file1", []byte{1, 2, 3}, 0o644)
+	root1.AddFile("shared", []byte{4, 5, 6, 7}, 0o644)
+
+	root2 := mockfs.NewDirectory()
+	root2.AddFile("file2", []byte{8, 9}, 0o644)
+	root2.AddFile("shared", []byte{4, 5, 6, 7}, 0o644)
+
+	src1 := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/src1"}
+	src2 := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/src2"}
+
+	u := snapshotfs.NewUploader(env.RepositoryWriter)
+
+	man1, err := u.Upload(ctx, root1, nil, src1)
+	require.NoError(t, err)
+
+	// second snapshot of the same source with an additional file.
+	root1.AddFile("file3", []byte{10, 11, 12, 13, 14}, 0o644)
+
+	man2, err := u.Upload(ctx, root1, nil, src1)
+	require.NoError(t, err)
+
+	man3, err := u.Upload(ctx, root2, nil, src2)
+	require.NoError(t, err)
+
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	for _, includeSnapshots := range []bool{false, true} {
+		usage, err := snapshotfs.CalculateSourceStorageUsage(ctx, env.RepositoryWriter, []*snapshot.Manifest{man3, man1, man2}, includeSnapshots)
+		require.NoError(t, err)
+		require.Len(t, usage, 2)
+
+		u1, u2 := usage[0], usage[1]
+
+		require.Equal(t, src1, u1.Source)
+		require.Equal(t, 2, u1.SnapshotCount)
+		// 2 root directories, file1 and file3
+		require.Equal(t, int64(4), u1.UniqueContentCount)
+		require.Equal(t, int64(1), u1.SharedContentCount)
+
+		require.Equal(t, src2, u2.Source)
+		require.Equal(t, 1, u2.SnapshotCount)
+		// root directory and file2
+		require.Equal(t, int64(2), u2.UniqueContentCount)
+		require.Equal(t, int64(1), u2.SharedContentCount)
+
+		require.Positive(t, u1.UniqueBytes)
+		require.Positive(t, u2.UniqueBytes)
+		require.Positive(t, u1.SharedBytes)
+		require.Equal(t, u1.SharedBytes, u2.SharedBytes)
+
+		if !includeSnapshots {
+			require.Empty(t, u1.Snapshots)
+			continue
+		}
+
+		require.Len(t, u1.Snapshots, 2)
+		require.Len(t, u2.Snapshots, 1)
+
+		s1, s2, s3 := u1.Snapshots[0], u1.Snapshots[1], u2.Snapshots[0]
+
+		require.Equal(t, man1.ID, s1.ID)
+		require.Equal(t, man2.ID, s2.ID)
+		require.Equal(t, man3.ID, s3.ID)
+
+		// root directory, file1 and shared file are shared with the second snapshot
+		require.Equal(t, int64(1), s1.UniqueContentCount)
+		require.Equal(t, int64(2), s1.SharedContentCount)
+
+		// root directory and file3
+		require.Equal(t, int64(2), s2.UniqueContentCount)
+		require.Equal(t, int64(2), s2.SharedContentCount)
+
+		// root directory and file2
+		require.Equal(t, int64(2), s3.UniqueContentCount)
+		require.Equal(t, int64(1), s3.SharedContentCount)
+		require.Equal(t, u2.UniqueBytes, s3.UniqueBytes)
+	}
+}
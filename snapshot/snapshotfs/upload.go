@@ -27,6 +27,7 @@
 	"github.com/kopia/kopia/internal/workshare"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/compression"
+	"github.com/kopia/kopia/repo/content"
 	"github.com/kopia/kopia/repo/logging"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
@@ -55,6 +56,9 @@
 
 var errCanceled = errors.New("canceled")
 
+// autoCompressionNone is recorded in entries of files stored uncompressed by automatic compression.
+const autoCompressionNone compression.Name = "none"
+
 // reasons why a snapshot is incomplete.
 const (
 	IncompleteReasonCheckpoint   = "checkpoint"
@@ -116,6 +120,9 @@ type Uploader struct {
 	workerPool *workshare.Pool[*uploadWorkItem]
 
 	traceEnabled bool
+
+	// compression dictionary trained for the source being uploaded or empty.
+	compressionDictionary content.ID
 }
 
 // IsCanceled returns true if the upload is canceled.
@@ -161,11 +168,13 @@ func (u *Uploader) uploadFileInternal(ctx context.Context, parentCheckpointRegis
 	}
 
 	comp := pol.CompressionPolicy.CompressorForFile(f)
+	splitterName := pol.SplitterPolicy.SplitterForFile(f)
+	dictionary := u.compressionDictionaryForPolicy(pol)
 
 	chunkSize := pol.UploadPolicy.ParallelUploadAboveSize.OrDefault(-1)
 	if chunkSize < 0 || f.Size() <= chunkSize {
 		// all data fits in 1 full chunks, upload directly
-		return u.uploadFileData(ctx, parentCheckpointRegistry, f, f.Name(), 0, -1, comp)
+		return u.uploadFileData(ctx, parentCheckpointRegistry, f, f.Name(), 0, -1, comp, splitterName, dictionary)
 	}
 
 	// we always have N+1 parts, first N are exactly chunkSize, last one has undetermined length
@@ -191,11 +200,11 @@ func (u *Uploader) uploadFileInternal(ctx context.Context, parentCheckpointRegis
 		if wg.CanShareWork(u.workerPool) {
 			// another goroutine is available, delegate to them
 			wg.RunAsync(u.workerPool, func(c *workshare.Pool[*uploadWorkItem], request *uploadWorkItem) {
-				parts[i], partErrors[i] = u.uploadFileData(ctx, parentCheckpointRegistry, f, uuid.NewString(), offset, length, comp)
+				parts[i], partErrors[i] = u.uploadFileData(ctx, parentCheckpointRegistry, f, uuid.NewString(), offset, length, comp, splitterName, dictionary)
 			}, nil)
 		} else {
 			// just do the work in the current goroutine
-			parts[i], partErrors[i] = u.uploadFileData(ctx, parentCheckpointRegistry, f, uuid.NewString(), offset, length, comp)
+			parts[i], partErrors[i] = u.uploadFileData(ctx, parentCheckpointRegistry, f, uuid.NewString(), offset, length, comp, splitterName, dictionary)
 		}
 	}
 
@@ -234,7 +243,7 @@ func concatenateParts(ctx context.Context, rep repo.RepositoryWriter, name strin
 	return de, nil
 }
 
-func (u *Uploader) uploadFileData(ctx context.Context, parentCheckpointRegistry *checkpointRegistry, f fs.File, fname string, offset, length int64, compressor compression.Name) (*snapshot.DirEntry, error) {
+func (u *Uploader) uploadFileData(ctx context.Context, parentCheckpointRegistry *checkpointRegistry, f fs.File, fname string, offset, length int64, compressor compression.Name, splitterName string, dictionary content.ID) (*snapshot.DirEntry, error) {
 	file, err := f.Open(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to open file")
@@ -244,7 +253,10 @@ func (u *Uploader) uploadFileData(ctx context.Context, parentCheckpointRegistry
 	writer := u.repo.NewObjectWriter(ctx, object.WriterOptions{
 		Description: "FILE:" + fname,
 		Compressor:  compressor,
+		Splitter:    splitterName,
 		AsyncWrites: 1, // upload chunk in parallel to writing another chunk
+
+		CompressionDictionary: dictionary,
 	})
 	defer writer.Close() //nolint:errcheck
 
@@ -295,9 +307,44 @@ func (u *Uploader) uploadFileData(ctx context.Context, parentCheckpointRegistry
 	atomic.AddInt32(&u.stats.TotalFileCount, 1)
 	atomic.AddInt64(&u.stats.TotalFileSize, de.FileSize)
 
+	if compressor == compression.AutoName {
+		u.addAutoCompressionStats(de, writer)
+	}
+
 	return de, nil
 }
 
+// addAutoCompressionStats records the compressor selected automatically for the file in its entry,
+// so that it can be accounted for when the file is cached by subsequent snapshots.
+func (u *Uploader) addAutoCompressionStats(de *snapshot.DirEntry, w object.Writer) {
+	cs, ok := w.(object.CompressorSelector)
+	if !ok {
+		return
+	}
+
+	de.AutoCompressor = cs.SelectedCompressor()
+
+	switch de.AutoCompressor {
+	case "", compression.AutoName:
+		// incompressible or empty file.
+		de.AutoCompressor = autoCompressionNone
+	}
+
+	u.stats.AddAutoCompressed(de.AutoCompressor, de.FileSize)
+}
+
+// addCachedAutoCompressionStats carries the compressor selected automatically for the cached file forward.
+func (u *Uploader) addCachedAutoCompressionStats(de *snapshot.DirEntry, cached fs.Entry) {
+	hde, ok := cached.(snapshot.HasDirEntry)
+	if !ok || hde.DirEntry().AutoCompressor == "" {
+		return
+	}
+
+	de.AutoCompressor = hde.DirEntry().AutoCompressor
+
+	u.stats.AddAutoCompressed(de.AutoCompressor, de.FileSize)
+}
+
 func (u *Uploader) uploadSymlinkInternal(ctx context.Context, relativePath string, f fs.Symlink) (dirEntry *snapshot.DirEntry, ret error) {
 	u.Progress.HashingFile(relativePath)
 
@@ -357,6 +404,9 @@ func (u *Uploader) uploadStreamingFileInternal(ctx context.Context, relativePath
 	writer := u.repo.NewObjectWriter(ctx, object.WriterOptions{
 		Description: "STREAMFILE:" + f.Name(),
 		Compressor:  comp,
+		Splitter:    pol.SplitterPolicy.SplitterForFile(f),
+
+		CompressionDictionary: u.compressionDictionaryForPolicy(pol),
 	})
 
 	defer writer.Close() //nolint:errcheck
@@ -382,6 +432,10 @@ func (u *Uploader) uploadStreamingFileInternal(ctx context.Context, relativePath
 	atomic.AddInt32(&u.stats.TotalFileCount, 1)
 	atomic.AddInt64(&u.stats.TotalFileSize, de.FileSize)
 
+	if comp == compression.AutoName {
+		u.addAutoCompressionStats(de, writer)
+	}
+
 	return de, nil
 }
 
@@ -841,6 +895,8 @@ func (u *Uploader) processSingle(
 				return errors.Wrap(err, "unable to create dir entry")
 			}
 
+			u.addCachedAutoCompressionStats(cachedDirEntry, cachedEntry)
+
 			return u.processEntryUploadResult(ctx, cachedDirEntry, nil, entryRelativePath, parentDirBuilder,
 				false,
 				u.OverrideEntryLogDetail.OrDefault(policyTree.EffectivePolicy().LoggingPolicy.Entries.CacheHit.OrDefault(policy.LogDetailNone)),
@@ -1212,6 +1268,36 @@ func (u *Uploader) maybeOpenDirectoryFromManifest(ctx context.Context, man *snap
 	return dir
 }
 
+// findCompressionDictionary returns the ID of the compression dictionary trained for the source
+// if the policy of the source enables dictionary compression.
+func (u *Uploader) findCompressionDictionary(ctx context.Context, policyTree *policy.Tree, sourceInfo snapshot.SourceInfo) content.ID {
+	if !policyTree.EffectivePolicy().CompressionPolicy.UseDictionary.OrDefault(false) {
+		return content.EmptyID
+	}
+
+	d, err := snapshot.FindCompressionDictionary(ctx, u.repo, sourceInfo)
+	if err != nil {
+		uploadLog(ctx).Errorf("unable to find compression dictionary, compressing without dictionary: %v", err)
+		return content.EmptyID
+	}
+
+	if d == nil {
+		// dictionary is trained during maintenance after the first snapshot of the source.
+		return content.EmptyID
+	}
+
+	return d.ContentID
+}
+
+// compressionDictionaryForPolicy returns the compression dictionary to use for files with the provided policy.
+func (u *Uploader) compressionDictionaryForPolicy(pol *policy.Policy) content.ID {
+	if !pol.CompressionPolicy.UseDictionary.OrDefault(false) {
+		return content.EmptyID
+	}
+
+	return u.compressionDictionary
+}
+
 // Upload uploads contents of the specified filesystem entry (file or directory) to the repository and returns snapshot.Manifest with statistics.
 // Old snapshot manifest, when provided can be used to speed up uploads by utilizing hash cache.
 func (u *Uploader) Upload(
@@ -1246,6 +1332,7 @@ func (u *Uploader) Upload(
 
 	u.stats = &snapshot.Stats{}
 	u.totalWrittenBytes.Store(0)
+	u.compressionDictionary = u.findCompressionDictionary(ctx, policyTree, sourceInfo)
 
 	var err error
 
@@ -38,6 +38,7 @@
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob/filesystem"
 	bloblogging "github.com/kopia/kopia/repo/blob/logging"
+	"github.com/kopia/kopia/repo/compression"
 	"github.com/kopia/kopia/repo/logging"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
@@ -909,6 +910,45 @@ func TestUpload_VirtualDirectoryWithStreamingFile_WithCompression(t *testing.T)
 	assert.Less(t, testutil.MustGetTotalDirSize(t, th.repoDir), int64(14000))
 }
 
+func TestUpload_AutoCompression(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	defer th.cleanup()
+
+	u := NewUploader(th.repo)
+
+	pol := *policy.DefaultPolicy
+	pol.CompressionPolicy.CompressorName = compression.AutoName
+
+	policyTree := policy.BuildTree(nil, &pol)
+
+	compressible := []byte(strings.Repeat("a", 4096))
+	incompressible := make([]byte, 8192)
+	rand.Read(incompressible)
+
+	sourceDir := mockfs.NewDirectory()
+	sourceDir.AddFile("compressible", compressible, defaultPermissions)
+	sourceDir.AddFile("incompressible", incompressible, defaultPermissions)
+
+	man, err := u.Upload(ctx, sourceDir, policyTree, snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(len(compressible)), atomic.LoadInt64(&man.Stats.AutoCompressionBestSize))
+	assert.Equal(t, int64(len(incompressible)), atomic.LoadInt64(&man.Stats.AutoCompressionNoneSize))
+	assert.Equal(t, int64(0), atomic.LoadInt64(&man.Stats.AutoCompressionFastSize))
+
+	// compressors selected for cached files are carried forward from the previous snapshot.
+	man2, err := u.Upload(ctx, sourceDir, policyTree, snapshot.SourceInfo{}, man)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&man2.Stats.CachedFiles))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&man2.Stats.NonCachedFiles))
+	assert.Equal(t, int64(len(compressible)), atomic.LoadInt64(&man2.Stats.AutoCompressionBestSize))
+	assert.Equal(t, int64(len(incompressible)), atomic.LoadInt64(&man2.Stats.AutoCompressionNoneSize))
+	assert.Equal(t, int64(0), atomic.LoadInt64(&man2.Stats.AutoCompressionFastSize))
+}
+
 func TestUpload_VirtualDirectoryWithStreamingFileWithModTime(t *testing.T) {
 	content := []byte("Streaming Temporary file content")
 	mt := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
@@ -0,0 +1,117 @@
+package snapshotgc
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/bigmap"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// mayContainDictionaries returns true if the provided range may contain compression dictionaries.
+func mayContainDictionaries(r content.IDRange) bool {
+	dictionaries := index.PrefixRange(content.CompressionDictionaryPrefix)
+
+	return r.StartID < dictionaries.EndID && r.EndID > dictionaries.StartID
+}
+
+// findInUseCompressionDictionaries adds to the provided set compression dictionaries which are current
+// dictionaries of snapshot sources or are referenced by any content, including deleted contents which may
+// still be undeleted. References are recorded by writers, so no contents need to be read.
+//
+// References recorded after refsCutoff may be of contents that are not yet visible, so they are kept
+// as-is. When compactReferences is true, each blob holding older references of contents which no longer
+// exist, or which are recorded in another blob, is rewritten separately, so that the number of references
+// held in memory is bounded by the size of a single blob.
+func findInUseCompressionDictionaries(ctx context.Context, rep repo.DirectRepositoryWriter, used *bigmap.Set, refsCutoff time.Time, compactReferences bool) error {
+	current, err := snapshot.ListCompressionDictionaries(ctx, rep)
+	if err != nil {
+		return errors.Wrap(err, "unable to list compression dictionaries")
+	}
+
+	var cidbuf [128]byte
+
+	for _, d := range current {
+		used.Put(ctx, d.ContentID.Append(cidbuf[:0]))
+	}
+
+	allRefs, err := rep.ContentManager().CompressionDictionaryReferences(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to get compression dictionary references")
+	}
+
+	seen, err := bigmap.NewSet(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to create new set")
+	}
+	defer seen.Close(ctx)
+
+	for _, refs := range allRefs {
+		if refs.Timestamp.After(refsCutoff) {
+			for dictionaryID := range refs.Contents {
+				used.Put(ctx, dictionaryID.Append(cidbuf[:0]))
+			}
+
+			continue
+		}
+
+		existing, removed, err := existingDictionaryReferences(ctx, rep, refs, seen)
+		if err != nil {
+			return err
+		}
+
+		for dictionaryID := range existing {
+			used.Put(ctx, dictionaryID.Append(cidbuf[:0]))
+		}
+
+		if !compactReferences || removed == 0 {
+			continue
+		}
+
+		log(ctx).Debugf("Compacting compression dictionary references in %v, removing %v references.", refs.BlobID, removed)
+
+		if err := rep.ContentManager().ReplaceCompressionDictionaryReferences(ctx, existing, []blob.ID{refs.BlobID}); err != nil {
+			return errors.Wrap(err, "unable to compact compression dictionary references")
+		}
+	}
+
+	return nil
+}
+
+// existingDictionaryReferences returns references from the provided blob of contents which exist and have not
+// been seen in previously processed blobs, and the number of references that can be removed.
+func existingDictionaryReferences(ctx context.Context, rep repo.DirectRepository, refs content.DictionaryReferences, seen *bigmap.Set) (existing map[content.ID][]content.ID, removed int, err error) {
+	var keybuf [256]byte
+
+	existing = map[content.ID][]content.ID{}
+
+	for dictionaryID, contentIDs := range refs.Contents {
+		for _, cid := range contentIDs {
+			key := cid.Append(append(dictionaryID.Append(keybuf[:0]), '/'))
+
+			if !seen.Put(ctx, key) {
+				removed++
+				continue
+			}
+
+			if _, err := rep.ContentInfo(ctx, cid); err != nil {
+				if errors.Is(err, content.ErrContentNotFound) {
+					removed++
+					continue
+				}
+
+				return nil, 0, errors.Wrapf(err, "unable to get content info of %v", cid)
+			}
+
+			existing[dictionaryID] = append(existing[dictionaryID], cid)
+		}
+	}
+
+	return existing, removed, nil
+}
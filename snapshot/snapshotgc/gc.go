@@ -3,6 +3,8 @@
 
 import (
 	"context"
+	"encoding/binary"
+	"sort"
 	"time"
 
 	"github.com/pkg/errors"
@@ -13,6 +15,7 @@
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
 	"github.com/kopia/kopia/repo/logging"
 	"github.com/kopia/kopia/repo/maintenance"
 	"github.com/kopia/kopia/repo/manifest"
@@ -23,7 +26,9 @@
 
 var log = logging.Module("snapshotgc")
 
-func findInUseContentIDs(ctx context.Context, rep repo.Repository, used *bigmap.Set) error {
+// findInUseContentIDs adds contents reachable from all snapshots to the provided set, using reachability
+// summaries where available and walking the remaining snapshots.
+func findInUseContentIDs(ctx context.Context, rep repo.DirectRepositoryWriter, used *bigmap.Set, opt gcOptions, summaryCutoff time.Time) error {
 	ids, err := snapshot.ListSnapshotManifests(ctx, rep, nil, nil)
 	if err != nil {
 		return errors.Wrap(err, "unable to list snapshot manifest IDs")
@@ -34,6 +39,69 @@ func findInUseContentIDs(ctx context.Context, rep repo.Repository, used *bigmap.
 		return errors.Wrap(err, "unable to load manifest IDs")
 	}
 
+	ri, err := loadReachabilityIndex(ctx, rep)
+	if err != nil {
+		return err
+	}
+
+	defer ri.close(ctx)
+
+	// process oldest snapshots first, so that directories they share with newer snapshots
+	// are summarized once and summaries of newer snapshots only describe what changed.
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].StartTime.Before(manifests[j].StartTime)
+	})
+
+	log(ctx).Infof("Looking for active contents...")
+
+	computed := 0
+
+	for _, m := range manifests {
+		if !opt.computeSummaries {
+			break
+		}
+
+		// snapshots which are not summarized before the deadline are walked and summarized during next run.
+		if !opt.summaryDeadline.IsZero() && !rep.Time().Before(opt.summaryDeadline) {
+			log(ctx).Infof("Maintenance deadline reached, not computing further reachability summaries.")
+			break
+		}
+
+		// recent snapshots are walked instead, since they are frequently expired by retention policies
+		// before long, which would leave behind summaries describing directories no other snapshot has.
+		if !m.StartTime.ToTime().Before(summaryCutoff) || ri.contains(m.RootObjectID()) {
+			continue
+		}
+
+		if err := ri.computeReachabilitySummary(ctx, rep, m); err != nil {
+			return errors.Wrapf(err, "unable to compute reachability summary of %v", m.RootObjectID())
+		}
+
+		computed++
+	}
+
+	if computed > 0 {
+		log(ctx).Infof("Computed %v reachability summaries.", computed)
+	}
+
+	toWalk, err := ri.addReachableContents(ctx, rep, manifests, used)
+	if err != nil {
+		return err
+	}
+
+	if err := walkInUseContentIDs(ctx, rep, toWalk, used); err != nil {
+		return err
+	}
+
+	return ri.addSummaryContents(ctx, rep, used, opt.deleteSummaries, summaryCutoff)
+}
+
+// walkInUseContentIDs adds contents reachable from the provided entries to the set by walking their trees.
+func walkInUseContentIDs(ctx context.Context, rep repo.Repository, entries []fs.Entry, used *bigmap.Set) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
 	w, twerr := snapshotfs.NewTreeWalker(ctx, snapshotfs.TreeWalkerOptions{
 		EntryCallback: func(ctx context.Context, entry fs.Entry, oid object.ID, entryPath string) error {
 			contentIDs, verr := rep.VerifyObject(ctx, oid)
@@ -51,21 +119,16 @@ func findInUseContentIDs(ctx context.Context, rep repo.Repository, used *bigmap.
 		},
 	})
 	if twerr != nil {
-		return errors.Wrap(err, "unable to create tree walker")
+		return errors.Wrap(twerr, "unable to create tree walker")
 	}
 
 	defer w.Close(ctx)
 
-	log(ctx).Infof("Looking for active contents...")
-
-	for _, m := range manifests {
-		root, err := snapshotfs.SnapshotRoot(rep, m)
-		if err != nil {
-			return errors.Wrap(err, "unable to get snapshot root")
-		}
+	log(ctx).Infof("Walking %v trees without reachability summaries...", len(entries))
 
-		if err := w.Process(ctx, root, ""); err != nil {
-			return errors.Wrap(err, "error processing snapshot root")
+	for _, e := range entries {
+		if err := w.Process(ctx, e, ""); err != nil {
+			return errors.Wrap(err, "error processing snapshot tree")
 		}
 	}
 
@@ -73,49 +136,263 @@ func findInUseContentIDs(ctx context.Context, rep repo.Repository, used *bigmap.
 }
 
 // Run performs garbage collection on all the snapshots in the repository.
-func Run(ctx context.Context, rep repo.DirectRepositoryWriter, gcDelete bool, safety maintenance.SafetyParameters, maintenanceStartTime time.Time) (Stats, error) {
+func Run(ctx context.Context, rep repo.DirectRepositoryWriter, gcDelete bool, runParams maintenance.RunParameters, safety maintenance.SafetyParameters) (Stats, error) {
 	var st Stats
 
-	err := maintenance.ReportRun(ctx, rep, maintenance.TaskSnapshotGarbageCollection, nil, func() error {
-		if err := runInternal(ctx, rep, gcDelete, safety, maintenanceStartTime, &st); err != nil {
-			return err
+	err := maintenance.ReportRunAndStats(ctx, rep, maintenance.TaskSnapshotGarbageCollection, nil, func(ctx context.Context) (map[string]int64, error) {
+		if err := runInternal(ctx, rep, gcOptions{
+			gcDelete:         gcDelete,
+			computeSummaries: true,
+			summaryDeadline:  runParams.Deadline,
+			deleteSummaries:  gcDelete,
+			contentRange:     index.AllIDs,
+		}, safety, runParams.MaintenanceStartTime, &st); err != nil {
+			return nil, err
 		}
 
-		l := log(ctx)
-
-		l.Infof("GC found %v unused contents (%v)", st.UnusedCount, units.BytesString(st.UnusedBytes))
-		l.Infof("GC found %v unused contents that are too recent to delete (%v)", st.TooRecentCount, units.BytesString(st.TooRecentBytes))
-		l.Infof("GC found %v in-use contents (%v)", st.InUseCount, units.BytesString(st.InUseBytes))
-		l.Infof("GC found %v in-use system-contents (%v)", st.SystemCount, units.BytesString(st.SystemBytes))
+		logStats(ctx, &st)
 
 		if st.UnusedCount > 0 && !gcDelete {
-			return errors.Errorf("Not deleting because 'gcDelete' was not set")
+			return st.counters(), errors.Errorf("Not deleting because 'gcDelete' was not set")
 		}
 
-		return nil
+		return st.counters(), nil
 	})
 
 	return st, errors.Wrap(err, "error running snapshot gc")
 }
 
-func runInternal(ctx context.Context, rep repo.DirectRepositoryWriter, gcDelete bool, safety maintenance.SafetyParameters, maintenanceStartTime time.Time, st *Stats) error {
-	var unused, inUse, system, tooRecent, undeleted stats.CountSum
+// DryRun finds contents that garbage collection would mark as deleted, without making any changes to the repository.
+func DryRun(ctx context.Context, rep repo.DirectRepositoryWriter, safety maintenance.SafetyParameters, maintenanceStartTime time.Time) (maintenance.TaskReport, error) {
+	var st Stats
+
+	if err := runInternal(ctx, rep, gcOptions{
+		dryRun:       true,
+		contentRange: index.AllIDs,
+	}, safety, maintenanceStartTime, &st); err != nil {
+		return maintenance.TaskReport{}, err
+	}
+
+	return maintenance.TaskReport{
+		Task:         maintenance.TaskSnapshotGarbageCollection,
+		Action:       maintenance.ActionMarkDeleted,
+		Contents:     int64(st.UnusedCount),
+		ContentBytes: st.UnusedBytes,
+		Details: map[string]int64{
+			"inUseContents":     int64(st.InUseCount),
+			"inUseBytes":        st.InUseBytes,
+			"systemContents":    int64(st.SystemCount),
+			"systemBytes":       st.SystemBytes,
+			"tooRecentContents": int64(st.TooRecentCount),
+			"tooRecentBytes":    st.TooRecentBytes,
+			"undeletedContents": int64(st.UndeletedCount),
+			"undeletedBytes":    st.UndeletedBytes,
+		},
+	}, nil
+}
+
+// RunPartitioned performs garbage collection of contents split into content ID partitions, which are processed
+// by the maintenance owner and by other clients running maintenance workers.
+// The owner finds in-use contents once and stores the in-use contents of each partition along with the job,
+// so that workers don't need to walk snapshots.
+func RunPartitioned(ctx context.Context, rep repo.DirectRepositoryWriter, runParams maintenance.RunParameters, safety maintenance.SafetyParameters) (Stats, error) {
+	var st Stats
+
+	err := maintenance.ReportRunAndStats(ctx, rep, maintenance.TaskSnapshotGarbageCollection, nil, func(ctx context.Context) (map[string]int64, error) {
+		used, serr := bigmap.NewSet(ctx)
+		if serr != nil {
+			return nil, errors.Wrap(serr, "unable to create new set")
+		}
+		defer used.Close(ctx)
+
+		if err := findInUseContentIDs(ctx, rep, used, gcOptions{
+			computeSummaries: true,
+			summaryDeadline:  runParams.Deadline,
+			deleteSummaries:  true,
+		}, runParams.MaintenanceStartTime.Add(-safety.MinContentAgeSubjectToGC)); err != nil {
+			return nil, errors.Wrap(err, "unable to find in-use content ID")
+		}
+
+		if err := findInUseCompressionDictionaries(ctx, rep, used, runParams.MaintenanceStartTime.Add(-safety.MinContentAgeSubjectToGC), true); err != nil {
+			return nil, err
+		}
+
+		if err := rep.Flush(ctx); err != nil {
+			return nil, errors.Wrap(err, "flush error")
+		}
+
+		counters, err := maintenance.RunPartitionedWithData(ctx, runParams, maintenance.TaskSnapshotGarbageCollection, safety,
+			func(ctx context.Context, rep repo.DirectRepositoryWriter, job *maintenance.PartitionedJob) error {
+				return putInUseContentIDs(ctx, rep, job, used)
+			},
+			RunPartition)
+		if err != nil {
+			return nil, errors.Wrap(err, "partitioned snapshot GC failure")
+		}
+
+		st.addCounters(counters)
+
+		logStats(ctx, &st)
+
+		return st.counters(), nil
+	})
+
+	return st, errors.Wrap(err, "error running snapshot gc")
+}
 
-	used, serr := bigmap.NewSet(ctx)
-	if serr != nil {
-		return errors.Wrap(serr, "unable to create new set")
+// RunPartition performs garbage collection of contents in the provided range, as part of partitioned maintenance,
+// using in-use contents of the partition found by the owner of the job.
+func RunPartition(ctx context.Context, rep repo.DirectRepositoryWriter, job *maintenance.PartitionedJob, r content.IDRange) (map[string]int64, error) {
+	var st Stats
+
+	used, err := getInUseContentIDs(ctx, rep, job, r)
+	if err != nil {
+		return nil, err
 	}
+
 	defer used.Close(ctx)
 
-	if err := findInUseContentIDs(ctx, rep, used); err != nil {
-		return errors.Wrap(err, "unable to find in-use content ID")
+	if err := runInternal(ctx, rep, gcOptions{
+		gcDelete:     true,
+		contentRange: r,
+		used:         used,
+	}, job.Safety, job.StartTime, &st); err != nil {
+		return nil, err
+	}
+
+	return st.counters(), nil
+}
+
+// putInUseContentIDs stores IDs of existing contents of each partition of the job which are in the provided set.
+func putInUseContentIDs(ctx context.Context, rep repo.DirectRepositoryWriter, job *maintenance.PartitionedJob, used *bigmap.Set) error {
+	for _, r := range job.Partitions {
+		var (
+			data   []byte
+			cidbuf [128]byte
+		)
+
+		if err := rep.ContentReader().IterateContents(ctx, content.IterateOptions{Range: r, IncludeDeleted: true}, func(ci content.Info) error {
+			cid := ci.GetContentID().Append(cidbuf[:0])
+
+			if used.Contains(cid) {
+				data = binary.AppendUvarint(data, uint64(len(cid)))
+				data = append(data, cid...)
+			}
+
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "error iterating contents")
+		}
+
+		if err := maintenance.PutPartitionData(ctx, rep, job, r, data); err != nil {
+			return errors.Wrap(err, "unable to store in-use contents")
+		}
+	}
+
+	return nil
+}
+
+// getInUseContentIDs returns the set of in-use contents of the partition stored by putInUseContentIDs.
+func getInUseContentIDs(ctx context.Context, rep repo.DirectRepository, job *maintenance.PartitionedJob, r content.IDRange) (*bigmap.Set, error) {
+	data, err := maintenance.GetPartitionData(ctx, rep, job, r)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get in-use contents")
+	}
+
+	used, err := bigmap.NewSet(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create new set")
+	}
+
+	for len(data) > 0 {
+		l, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < l {
+			used.Close(ctx)
+			return nil, errors.Errorf("malformed in-use contents of partition")
+		}
+
+		used.Put(ctx, data[n:n+int(l)])
+		data = data[n+int(l):]
+	}
+
+	return used, nil
+}
+
+func logStats(ctx context.Context, st *Stats) {
+	l := log(ctx)
+
+	l.Infof("GC found %v unused contents (%v)", st.UnusedCount, units.BytesString(st.UnusedBytes))
+	l.Infof("GC found %v unused contents that are too recent to delete (%v)", st.TooRecentCount, units.BytesString(st.TooRecentBytes))
+	l.Infof("GC found %v in-use contents (%v)", st.InUseCount, units.BytesString(st.InUseBytes))
+	l.Infof("GC found %v in-use system-contents (%v)", st.SystemCount, units.BytesString(st.SystemBytes))
+}
+
+// gcOptions controls the behavior of runInternal.
+type gcOptions struct {
+	gcDelete bool
+
+	// in dry-run mode no changes are made to the repository.
+	dryRun bool
+
+	// compute missing reachability summaries of snapshots, until summaryDeadline passes (if set).
+	computeSummaries bool
+	summaryDeadline  time.Time
+
+	// delete reachability summaries of snapshots that no longer exist and compact
+	// compression dictionary references.
+	deleteSummaries bool
+
+	// range of contents subject to garbage collection.
+	contentRange content.IDRange
+
+	// contents known to be in use, found by walking snapshots if nil.
+	used *bigmap.Set
+}
+
+// findInUseContents returns the set of contents reachable from snapshots and compression dictionaries in use.
+func findInUseContents(ctx context.Context, rep repo.DirectRepositoryWriter, opt gcOptions, safety maintenance.SafetyParameters, maintenanceStartTime time.Time) (*bigmap.Set, error) {
+	used, err := bigmap.NewSet(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create new set")
+	}
+
+	if err := findInUseContentIDs(ctx, rep, used, opt, maintenanceStartTime.Add(-safety.MinContentAgeSubjectToGC)); err != nil {
+		used.Close(ctx)
+		return nil, errors.Wrap(err, "unable to find in-use content ID")
+	}
+
+	if mayContainDictionaries(opt.contentRange) {
+		if err := findInUseCompressionDictionaries(ctx, rep, used, maintenanceStartTime.Add(-safety.MinContentAgeSubjectToGC), opt.deleteSummaries); err != nil {
+			used.Close(ctx)
+			return nil, err
+		}
+	}
+
+	return used, nil
+}
+
+// runInternal performs garbage collection of contents in the range specified by options.
+func runInternal(ctx context.Context, rep repo.DirectRepositoryWriter, opt gcOptions, safety maintenance.SafetyParameters, maintenanceStartTime time.Time, st *Stats) error {
+	var unused, inUse, system, tooRecent, undeleted stats.CountSum
+
+	gcDelete, dryRun := opt.gcDelete, opt.dryRun
+
+	used := opt.used
+	if used == nil {
+		var err error
+
+		if used, err = findInUseContents(ctx, rep, opt, safety, maintenanceStartTime); err != nil {
+			return err
+		}
+
+		defer used.Close(ctx)
 	}
 
 	log(ctx).Infof("Looking for unreferenced contents...")
 
 	// Ensure that the iteration includes deleted contents, so those can be
 	// undeleted (recovered).
-	err := rep.ContentReader().IterateContents(ctx, content.IterateOptions{IncludeDeleted: true}, func(ci content.Info) error {
+	err := rep.ContentReader().IterateContents(ctx, content.IterateOptions{Range: opt.contentRange, IncludeDeleted: true}, func(ci content.Info) error {
 		if manifest.ContentPrefix == ci.GetContentID().Prefix() {
 			system.Add(int64(ci.GetPackedLength()))
 			return nil
@@ -125,6 +402,13 @@ func runInternal(ctx context.Context, rep repo.DirectRepositoryWriter, gcDelete
 
 		if used.Contains(ci.GetContentID().Append(cidbuf[:0])) {
 			if ci.GetDeleted() {
+				if dryRun {
+					undeleted.Add(int64(ci.GetPackedLength()))
+					inUse.Add(int64(ci.GetPackedLength()))
+
+					return nil
+				}
+
 				if err := rep.ContentManager().UndeleteContent(ctx, ci.GetContentID()); err != nil {
 					return errors.Wrapf(err, "Could not undelete referenced content: %v", ci)
 				}
@@ -174,5 +458,9 @@ func runInternal(ctx context.Context, rep repo.DirectRepositoryWriter, gcDelete
 		return errors.Wrap(err, "error iterating contents")
 	}
 
+	if dryRun {
+		return nil
+	}
+
 	return errors.Wrap(rep.Flush(ctx), "flush error")
 }
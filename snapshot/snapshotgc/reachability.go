@@ -0,0 +1,622 @@
+package snapshotgc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/bigmap"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/compression"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+// ReachabilityManifestType is the value of the "type" label for manifests describing reachability summaries.
+const ReachabilityManifestType = "reachability"
+
+const (
+	reachabilityRootLabel    = "root"
+	reachabilitySummaryLabel = "summary"
+
+	// reachabilitySummaryPrefix is the prefix of objects storing reachability summaries.
+	reachabilitySummaryPrefix content.IDPrefix = "r"
+
+	// reachabilitySummaryFormatVersion is the version of the encoding of reachability summary objects.
+	reachabilitySummaryFormatVersion = 1
+)
+
+// ReachabilitySummary describes a reachability summary object, which lists contents directly referenced
+// by directories of a snapshot that were not summarized before, along with their subdirectories.
+//
+// Summaries are not written when snapshots are created, but computed by snapshot GC for snapshots older
+// than MinContentAgeSubjectToGC, since recent snapshots are often expired by retention policies soon after.
+// GC computes summaries of all such snapshots in a single run, unless the maintenance deadline passes.
+// BenchmarkFindInUseContentIDs compares finding in-use contents with and without summaries.
+//
+// Since snapshot trees are content-addressed, a directory which is unchanged between snapshots is only
+// summarized once, so the summary of a snapshot is proportional to the number of directories which changed
+// since the snapshots summarized before it. The set of contents reachable from a snapshot is the union of
+// records of directories reachable from its root, which may be stored in summaries of older snapshots.
+type ReachabilitySummary struct {
+	RootObjectID    object.ID       `json:"rootObjectID"`
+	SummaryObjectID object.ID       `json:"summaryObjectID"`
+	DirectoryCount  int             `json:"directoryCount"`
+	CreatedTime     fs.UTCTimestamp `json:"createdTime"`
+}
+
+// reachabilityRecord describes contents directly referenced by a directory (or a snapshot root
+// which is a file), which are the contents of the object itself and of all its files and symlinks.
+type reachabilityRecord struct {
+	objectID object.ID
+	contents []content.ID
+	subdirs  []object.ID
+}
+
+// reachabilitySummaryInfo describes a reachability summary loaded by GC.
+type reachabilitySummaryInfo struct {
+	manifestID manifest.ID
+	objectID   object.ID
+	modTime    time.Time
+	loaded     bool // false if the summary could not be read
+	used       bool // true if any of its records is reachable from a live snapshot
+}
+
+// reachabilityIndex provides access to records from all reachability summaries.
+type reachabilityIndex struct {
+	// records keyed by object ID, the value is the index of the summary followed by the encoded record.
+	records   *bigmap.Map
+	summaries []*reachabilitySummaryInfo
+}
+
+func newReachabilityIndex(ctx context.Context) (*reachabilityIndex, error) {
+	m, err := bigmap.NewMap(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create map")
+	}
+
+	return &reachabilityIndex{records: m}, nil
+}
+
+func (ri *reachabilityIndex) close(ctx context.Context) {
+	ri.records.Close(ctx)
+}
+
+func (ri *reachabilityIndex) contains(oid object.ID) bool {
+	var oidbuf [128]byte
+
+	return ri.records.Contains(oid.Append(oidbuf[:0]))
+}
+
+// get returns the record of the provided object and marks the summary it came from as used.
+func (ri *reachabilityIndex) get(ctx context.Context, oid object.ID) (*reachabilityRecord, bool, error) {
+	var oidbuf [128]byte
+
+	v, ok, err := ri.records.Get(ctx, nil, oid.Append(oidbuf[:0]))
+	if err != nil || !ok {
+		return nil, false, errors.Wrap(err, "unable to get reachability record")
+	}
+
+	summaryIndex, n := binary.Uvarint(v)
+	if n <= 0 || summaryIndex >= uint64(len(ri.summaries)) {
+		return nil, false, errors.Errorf("invalid reachability record of %v", oid)
+	}
+
+	rec := &reachabilityRecord{objectID: oid}
+
+	if err := decodeReachabilityRecordBody(bytes.NewReader(v[n:]), rec); err != nil {
+		return nil, false, errors.Wrapf(err, "invalid reachability record of %v", oid)
+	}
+
+	ri.summaries[summaryIndex].used = true
+
+	return rec, true, nil
+}
+
+// add adds records of a summary to the index, records of objects which are already known are ignored.
+func (ri *reachabilityIndex) add(ctx context.Context, si *reachabilitySummaryInfo, records []*reachabilityRecord) {
+	summaryIndex := ri.addSummary(si)
+
+	for _, rec := range records {
+		ri.addRecord(ctx, summaryIndex, rec)
+	}
+}
+
+// addSummary adds the provided summary to the index and returns its index.
+func (ri *reachabilityIndex) addSummary(si *reachabilitySummaryInfo) int {
+	ri.summaries = append(ri.summaries, si)
+
+	return len(ri.summaries) - 1
+}
+
+// addRecord adds a record of the summary with a given index, unless the object is already known.
+func (ri *reachabilityIndex) addRecord(ctx context.Context, summaryIndex int, rec *reachabilityRecord) {
+	var oidbuf [128]byte
+
+	v := binary.AppendUvarint(nil, uint64(summaryIndex))
+
+	ri.records.PutIfAbsent(ctx, rec.objectID.Append(oidbuf[:0]), appendReachabilityRecordBody(v, rec))
+}
+
+// loadReachabilityIndex loads all reachability summaries stored in the repository. Summaries which
+// can't be read are logged and skipped, so that the objects they describe are walked instead.
+func loadReachabilityIndex(ctx context.Context, rep repo.Repository) (*reachabilityIndex, error) {
+	entries, err := rep.FindManifests(ctx, map[string]string{
+		manifest.TypeLabelKey: ReachabilityManifestType,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find reachability summaries")
+	}
+
+	// load summaries deterministically, oldest first.
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].ModTime.Equal(entries[j].ModTime) {
+			return entries[i].ModTime.Before(entries[j].ModTime)
+		}
+
+		return entries[i].ID < entries[j].ID
+	})
+
+	ri, err := newReachabilityIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		si := &reachabilitySummaryInfo{
+			manifestID: e.ID,
+			modTime:    e.ModTime,
+		}
+
+		records, err := readReachabilitySummary(ctx, rep, e, si)
+		if err != nil {
+			log(ctx).Warnf("unable to use reachability summary %v: %v", e.ID, err)
+		}
+
+		si.loaded = err == nil
+
+		ri.add(ctx, si, records)
+	}
+
+	return ri, nil
+}
+
+func readReachabilitySummary(ctx context.Context, rep repo.Repository, e *manifest.EntryMetadata, si *reachabilitySummaryInfo) ([]*reachabilityRecord, error) {
+	oid, err := object.ParseID(e.Labels[reachabilitySummaryLabel])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid summary object ID")
+	}
+
+	si.objectID = oid
+
+	r, err := rep.OpenObject(ctx, oid)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open reachability summary")
+	}
+	defer r.Close() //nolint:errcheck
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read reachability summary")
+	}
+
+	// validate the entire summary before using any of it.
+	return decodeReachabilitySummary(data)
+}
+
+// computeReachabilitySummary saves records of all objects reachable from the root of the provided snapshot
+// which are not in the index yet and adds them to the index.
+//
+// Records are written to the summary object and added to the index as soon as each directory is summarized,
+// so memory usage does not depend on the size of the snapshot. If this fails, the entire GC run fails
+// and the index is discarded, so records of a summary which was never saved are never used.
+func (ri *reachabilityIndex) computeReachabilitySummary(ctx context.Context, rep repo.RepositoryWriter, man *snapshot.Manifest) error {
+	rootEntry, err := snapshotfs.SnapshotRoot(rep, man)
+	if err != nil {
+		return errors.Wrap(err, "unable to get snapshot root")
+	}
+
+	visited, err := bigmap.NewSet(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to create new set")
+	}
+	defer visited.Close(ctx)
+
+	si := &reachabilitySummaryInfo{
+		modTime: rep.Time(),
+		loaded:  true,
+	}
+
+	sw := &reachabilitySummaryWriter{
+		ri:           ri,
+		summaryIndex: ri.addSummary(si),
+		w: rep.NewObjectWriter(ctx, object.WriterOptions{
+			Description: "REACHABILITY SUMMARY",
+			Prefix:      reachabilitySummaryPrefix,
+			Compressor:  compression.Name("zstd-fastest"),
+		}),
+	}
+	defer sw.w.Close() //nolint:errcheck
+
+	if err := sw.writeHeader(); err != nil {
+		return err
+	}
+
+	if err := ri.summarize(ctx, rep, rootEntry, visited, sw); err != nil {
+		return err
+	}
+
+	summaryOID, err := sw.w.Result()
+	if err != nil {
+		return errors.Wrap(err, "unable to write reachability summary")
+	}
+
+	root := man.RootObjectID()
+
+	labels := map[string]string{
+		manifest.TypeLabelKey:    ReachabilityManifestType,
+		reachabilityRootLabel:    root.String(),
+		reachabilitySummaryLabel: summaryOID.String(),
+	}
+
+	manifestID, err := rep.PutManifest(ctx, labels, &ReachabilitySummary{
+		RootObjectID:    root,
+		SummaryObjectID: summaryOID,
+		DirectoryCount:  sw.count,
+		CreatedTime:     fs.UTCTimestampFromTime(si.modTime),
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to save reachability summary manifest")
+	}
+
+	si.manifestID = manifestID
+	si.objectID = summaryOID
+
+	return nil
+}
+
+// summarize writes records of the provided entry and its subdirectories, skipping the ones which are
+// already in the index, since all objects reachable from them are in the index as well.
+func (ri *reachabilityIndex) summarize(ctx context.Context, rep repo.Repository, e fs.Entry, visited *bigmap.Set, sw *reachabilitySummaryWriter) error {
+	var oidbuf [128]byte
+
+	oid := entryObjectID(e)
+
+	if ri.contains(oid) || !visited.Put(ctx, oid.Append(oidbuf[:0])) {
+		return nil
+	}
+
+	rec := &reachabilityRecord{objectID: oid}
+
+	cids, err := rep.VerifyObject(ctx, oid)
+	if err != nil {
+		return errors.Wrapf(err, "error verifying %v", oid)
+	}
+
+	rec.contents = append(rec.contents, cids...)
+
+	if dir, ok := e.(fs.Directory); ok {
+		if err := dir.IterateEntries(ctx, func(ctx context.Context, child fs.Entry) error {
+			childOID := entryObjectID(child)
+
+			if _, isDir := child.(fs.Directory); isDir {
+				rec.subdirs = append(rec.subdirs, childOID)
+
+				return ri.summarize(ctx, rep, child, visited, sw)
+			}
+
+			cids, err := rep.VerifyObject(ctx, childOID)
+			if err != nil {
+				return errors.Wrapf(err, "error verifying %v", childOID)
+			}
+
+			rec.contents = append(rec.contents, cids...)
+
+			return nil
+		}); err != nil {
+			return errors.Wrapf(err, "error reading directory %v", oid)
+		}
+	}
+
+	rec.contents = sortedUniqueContentIDs(rec.contents)
+
+	return sw.writeRecord(ctx, rec)
+}
+
+func entryObjectID(e fs.Entry) object.ID {
+	if h, ok := e.(object.HasObjectID); ok {
+		return h.ObjectID()
+	}
+
+	return object.EmptyID
+}
+
+func sortedUniqueContentIDs(ids []content.ID) []content.ID {
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+
+	n := 0
+
+	for i, id := range ids {
+		if i > 0 && ids[n-1] == id {
+			continue
+		}
+
+		ids[n] = id
+		n++
+	}
+
+	return ids[:n]
+}
+
+// addReachableContents adds contents reachable from the provided snapshots to the set using the records
+// in the index and returns entries which need to be walked because their records are missing.
+func (ri *reachabilityIndex) addReachableContents(ctx context.Context, rep repo.Repository, manifests []*snapshot.Manifest, used *bigmap.Set) ([]fs.Entry, error) {
+	visited, err := bigmap.NewSet(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create new set")
+	}
+	defer visited.Close(ctx)
+
+	var (
+		toWalk  []fs.Entry
+		pending []object.ID
+		oidbuf  [128]byte
+		cidbuf  [128]byte
+	)
+
+	for _, m := range manifests {
+		root := m.RootObjectID()
+
+		if !visited.Put(ctx, root.Append(oidbuf[:0])) {
+			continue
+		}
+
+		if ri.contains(root) {
+			pending = append(pending, root)
+			continue
+		}
+
+		rootEntry, err := snapshotfs.SnapshotRoot(rep, m)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get snapshot root")
+		}
+
+		toWalk = append(toWalk, rootEntry)
+	}
+
+	for len(pending) > 0 {
+		oid := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		rec, ok, err := ri.get(ctx, oid)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			// records of subdirectories can be missing if the summary holding them was deleted
+			// after the directory stopped being reachable and it was reintroduced later.
+			log(ctx).Debugf("missing reachability record of %v", oid)
+
+			toWalk = append(toWalk, snapshotfs.DirectoryEntry(rep, oid, nil))
+
+			continue
+		}
+
+		for _, cid := range rec.contents {
+			used.Put(ctx, cid.Append(cidbuf[:0]))
+		}
+
+		for _, sub := range rec.subdirs {
+			if visited.Put(ctx, sub.Append(oidbuf[:0])) {
+				pending = append(pending, sub)
+			}
+		}
+	}
+
+	return toWalk, nil
+}
+
+// addSummaryContents adds contents storing reachability summaries to the set. When deleteUnused is set,
+// manifests of summaries which don't hold records of any live snapshot, including unreadable ones, are deleted
+// instead, which makes their contents eligible for garbage collection. Summaries created after the cutoff
+// are retained, since they may belong to snapshots which are being created.
+func (ri *reachabilityIndex) addSummaryContents(ctx context.Context, rep repo.RepositoryWriter, used *bigmap.Set, deleteUnused bool, cutoff time.Time) error {
+	var (
+		deleted int
+		cidbuf  [128]byte
+	)
+
+	for _, si := range ri.summaries {
+		if deleteUnused && !si.used && si.modTime.Before(cutoff) {
+			if err := rep.DeleteManifest(ctx, si.manifestID); err != nil {
+				return errors.Wrap(err, "unable to delete reachability summary")
+			}
+
+			deleted++
+
+			continue
+		}
+
+		if !si.loaded {
+			continue
+		}
+
+		cids, err := rep.VerifyObject(ctx, si.objectID)
+		if err != nil {
+			return errors.Wrapf(err, "unable to verify reachability summary %v", si.manifestID)
+		}
+
+		for _, cid := range cids {
+			used.Put(ctx, cid.Append(cidbuf[:0]))
+		}
+	}
+
+	if deleted > 0 {
+		log(ctx).Infof("Deleted %v unused reachability summaries.", deleted)
+	}
+
+	return nil
+}
+
+// reachabilitySummaryWriter streams records of a reachability summary to an object and adds them to the index.
+type reachabilitySummaryWriter struct {
+	ri           *reachabilityIndex
+	summaryIndex int
+	w            object.Writer
+	buf          []byte
+	count        int
+}
+
+func (sw *reachabilitySummaryWriter) writeHeader() error {
+	_, err := sw.w.Write(binary.AppendUvarint(nil, reachabilitySummaryFormatVersion))
+
+	return errors.Wrap(err, "unable to write reachability summary")
+}
+
+func (sw *reachabilitySummaryWriter) writeRecord(ctx context.Context, rec *reachabilityRecord) error {
+	sw.buf = appendLengthPrefixed(sw.buf[:0], rec.objectID.String())
+	sw.buf = appendReachabilityRecordBody(sw.buf, rec)
+
+	if _, err := sw.w.Write(sw.buf); err != nil {
+		return errors.Wrap(err, "unable to write reachability summary")
+	}
+
+	sw.ri.addRecord(ctx, sw.summaryIndex, rec)
+	sw.count++
+
+	return nil
+}
+
+func appendLengthPrefixed(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendReachabilityRecordBody(buf []byte, rec *reachabilityRecord) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(rec.contents)))
+	for _, cid := range rec.contents {
+		buf = appendLengthPrefixed(buf, cid.String())
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(rec.subdirs)))
+	for _, sub := range rec.subdirs {
+		buf = appendLengthPrefixed(buf, sub.String())
+	}
+
+	return buf
+}
+
+func decodeReachabilitySummary(data []byte) ([]*reachabilityRecord, error) {
+	r := bytes.NewReader(data)
+
+	v, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid reachability summary")
+	}
+
+	if v != reachabilitySummaryFormatVersion {
+		return nil, errors.Errorf("unsupported reachability summary version %v", v)
+	}
+
+	var records []*reachabilityRecord
+
+	for r.Len() > 0 {
+		s, err := readLengthPrefixed(r)
+		if err != nil {
+			return nil, err
+		}
+
+		oid, err := object.ParseID(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid object ID")
+		}
+
+		rec := &reachabilityRecord{objectID: oid}
+
+		if err := decodeReachabilityRecordBody(r, rec); err != nil {
+			return nil, err
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+func decodeReachabilityRecordBody(r *bytes.Reader, rec *reachabilityRecord) error {
+	n, err := readCount(r)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		s, err := readLengthPrefixed(r)
+		if err != nil {
+			return err
+		}
+
+		cid, err := content.ParseID(s)
+		if err != nil {
+			return errors.Wrap(err, "invalid content ID")
+		}
+
+		rec.contents = append(rec.contents, cid)
+	}
+
+	n, err = readCount(r)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		s, err := readLengthPrefixed(r)
+		if err != nil {
+			return err
+		}
+
+		oid, err := object.ParseID(s)
+		if err != nil {
+			return errors.Wrap(err, "invalid object ID")
+		}
+
+		rec.subdirs = append(rec.subdirs, oid)
+	}
+
+	return nil
+}
+
+// readCount reads the number of elements which follow, each of which takes at least one byte.
+func readCount(r *bytes.Reader) (int, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return 0, errors.Errorf("invalid reachability summary")
+	}
+
+	return int(n), nil
+}
+
+func readLengthPrefixed(r *bytes.Reader) (string, error) {
+	n, err := readCount(r)
+	if err != nil {
+		return "", err
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", errors.Wrap(err, "invalid reachability summary")
+	}
+
+	return string(b), nil
+}
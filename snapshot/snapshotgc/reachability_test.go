@@ -0,0 +1,101 @@
+package snapshotgc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/bigmap"
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+const (
+	benchmarkSnapshots      = 20
+	benchmarkDirectories    = 100
+	benchmarkFilesPerDir    = 20
+	benchmarkFilePermission = 0o644
+)
+
+// BenchmarkFindInUseContentIDs measures the time to find contents reachable from snapshots which share most
+// of their directories by walking all of them and by reading reachability summaries computed by a previous run.
+func BenchmarkFindInUseContentIDs(b *testing.B) {
+	b.Run("WithoutSummaries", func(b *testing.B) {
+		benchmarkFindInUseContentIDs(b, false)
+	})
+
+	b.Run("WithSummaries", func(b *testing.B) {
+		benchmarkFindInUseContentIDs(b, true)
+	})
+}
+
+func benchmarkFindInUseContentIDs(b *testing.B, summaries bool) {
+	ctx, env := repotesting.NewEnvironment(b, repotesting.FormatNotImportant)
+	rep := env.RepositoryWriter
+
+	createBenchmarkSnapshots(ctx, b, rep)
+
+	// all snapshots are old enough to be summarized.
+	cutoff := rep.Time().Add(time.Hour)
+	opt := gcOptions{computeSummaries: summaries}
+
+	if summaries {
+		findBenchmarkInUseContentIDs(ctx, b, rep, opt, cutoff)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		findBenchmarkInUseContentIDs(ctx, b, rep, opt, cutoff)
+	}
+}
+
+func findBenchmarkInUseContentIDs(ctx context.Context, b *testing.B, rep repo.DirectRepositoryWriter, opt gcOptions, cutoff time.Time) {
+	b.Helper()
+
+	used, err := bigmap.NewSet(ctx)
+	require.NoError(b, err)
+
+	defer used.Close(ctx)
+
+	require.NoError(b, findInUseContentIDs(ctx, rep, used, opt, cutoff))
+}
+
+// createBenchmarkSnapshots creates snapshots of a directory tree which gets a new file in one of its
+// directories before each snapshot.
+func createBenchmarkSnapshots(ctx context.Context, b *testing.B, rep repo.RepositoryWriter) {
+	b.Helper()
+
+	si := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/bench"}
+	root := mockfs.NewDirectory()
+
+	for d := 0; d < benchmarkDirectories; d++ {
+		dir := root.AddDir(fmt.Sprintf("dir-%v", d), 0o755)
+
+		for f := 0; f < benchmarkFilesPerDir; f++ {
+			dir.AddFile(fmt.Sprintf("file-%v", f), []byte(fmt.Sprintf("contents of file %v in directory %v", f, d)), benchmarkFilePermission)
+		}
+	}
+
+	policyTree, err := policy.TreeForSource(ctx, rep, si)
+	require.NoError(b, err)
+
+	for i := 0; i < benchmarkSnapshots; i++ {
+		root.AddFile(fmt.Sprintf("dir-%v/new-%v", i%benchmarkDirectories, i), []byte(fmt.Sprintf("new file %v", i)), benchmarkFilePermission)
+
+		man, err := snapshotfs.NewUploader(rep).Upload(ctx, root, policyTree, si)
+		require.NoError(b, err)
+
+		_, err = snapshot.SaveSnapshot(ctx, rep, man)
+		require.NoError(b, err)
+	}
+
+	require.NoError(b, rep.Flush(ctx))
+}
@@ -8,3 +8,33 @@ type Stats struct {
 	UnusedBytes, InUseBytes, SystemBytes, TooRecentBytes, UndeletedBytes int64
 	UnusedCount, InUseCount, SystemCount, TooRecentCount, UndeletedCount uint32
 }
+
+// counters returns statistics as counters of a maintenance partition.
+func (s *Stats) counters() map[string]int64 {
+	return map[string]int64{
+		"unusedContents":    int64(s.UnusedCount),
+		"unusedBytes":       s.UnusedBytes,
+		"inUseContents":     int64(s.InUseCount),
+		"inUseBytes":        s.InUseBytes,
+		"systemContents":    int64(s.SystemCount),
+		"systemBytes":       s.SystemBytes,
+		"tooRecentContents": int64(s.TooRecentCount),
+		"tooRecentBytes":    s.TooRecentBytes,
+		"undeletedContents": int64(s.UndeletedCount),
+		"undeletedBytes":    s.UndeletedBytes,
+	}
+}
+
+// addCounters adds counters of a maintenance partition to statistics.
+func (s *Stats) addCounters(c map[string]int64) {
+	s.UnusedCount += uint32(c["unusedContents"])
+	s.UnusedBytes += c["unusedBytes"]
+	s.InUseCount += uint32(c["inUseContents"])
+	s.InUseBytes += c["inUseBytes"]
+	s.SystemCount += uint32(c["systemContents"])
+	s.SystemBytes += c["systemBytes"]
+	s.TooRecentCount += uint32(c["tooRecentContents"])
+	s.TooRecentBytes += c["tooRecentBytes"]
+	s.UndeletedCount += uint32(c["undeletedContents"])
+	s.UndeletedBytes += c["undeletedBytes"]
+}
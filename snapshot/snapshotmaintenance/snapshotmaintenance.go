@@ -3,27 +3,222 @@
 
 import (
 	"context"
+	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/notification"
+	"github.com/kopia/kopia/notification/notifytemplate"
+	"github.com/kopia/kopia/notification/sender"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/logging"
 	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotdict"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
 	"github.com/kopia/kopia/snapshot/snapshotgc"
 )
 
+var log = logging.Module("snapshotmaintenance")
+
 // Run runs the complete snapshot and repository maintenance.
 func Run(ctx context.Context, dr repo.DirectRepositoryWriter, mode maintenance.Mode, force bool, safety maintenance.SafetyParameters) error {
 	//nolint:wrapcheck
 	return maintenance.RunExclusive(ctx, dr, mode, force,
 		func(ctx context.Context, runParams maintenance.RunParameters) error {
-			// run snapshot GC before full maintenance
-			if runParams.Mode == maintenance.ModeFull {
-				if _, err := snapshotgc.Run(ctx, dr, true, safety, runParams.MaintenanceStartTime); err != nil {
-					return errors.Wrap(err, "snapshot GC failure")
-				}
+			startTime := clock.Now()
+			err := runMaintenance(ctx, dr, runParams, safety)
+
+			sendMaintenanceNotification(ctx, dr, runParams.Mode, startTime, err)
+
+			return err
+		})
+}
+
+func runMaintenance(ctx context.Context, dr repo.DirectRepositoryWriter, runParams maintenance.RunParameters, safety maintenance.SafetyParameters) error {
+	// run snapshot GC before full maintenance
+	if runParams.Mode == maintenance.ModeFull {
+		if err := runTaskIfNotCompleted(ctx, runParams, maintenance.TaskSnapshotGarbageCollection, func() error {
+			return errors.Wrap(runSnapshotGC(ctx, dr, runParams, safety), "snapshot GC failure")
+		}); err != nil {
+			return err
+		}
+
+		if err := runTaskIfNotCompleted(ctx, runParams, maintenance.TaskTrainCompressionDictionaries, func() error {
+			return errors.Wrap(snapshotdict.Run(ctx, dr, false), "compression dictionary training failure")
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := maintenance.Run(ctx, runParams, safety); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if runParams.Mode == maintenance.ModeFull && !runParams.DeadlineExceeded() {
+		// statistics are informational, don't fail maintenance because of them.
+		if err := recordStats(ctx, dr, runParams); err != nil {
+			log(ctx).Errorf("unable to record repository statistics: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// sendMaintenanceNotification reports failed maintenance runs as errors and completed ones as successes.
+func sendMaintenanceNotification(ctx context.Context, rep repo.Repository, mode maintenance.Mode, startTime time.Time, err error) {
+	r := &notification.MaintenanceReport{
+		Mode:      string(mode),
+		StartTime: startTime,
+		EndTime:   clock.Now(),
+	}
+
+	severity := sender.SeveritySuccess
+
+	// maintenance which has reached its time limit will be resumed during the next run, which is not a failure.
+	if err != nil && !errors.Is(err, maintenance.ErrDeadlineExceeded) {
+		r.Error = err.Error()
+		severity = sender.SeverityError
+	}
+
+	notification.Send(ctx, rep, notifytemplate.MaintenanceReport, r, severity)
+}
+
+// recordStats records repository statistics. Storage usage of sources requires walking all snapshots,
+// so it's only included when enabled by setting the interval of its maintenance task.
+func recordStats(ctx context.Context, dr repo.DirectRepositoryWriter, runParams maintenance.RunParameters) error {
+	s, err := maintenance.GetSchedule(ctx, dr)
+	if err != nil {
+		return errors.Wrap(err, "unable to get maintenance schedule")
+	}
+
+	//nolint:wrapcheck
+	return maintenance.RecordStats(ctx, dr, s, func(ctx context.Context, rep repo.DirectRepository, st *maintenance.RepositoryStats) error {
+		manifests, err := snapshotCountStats(ctx, rep, st)
+		if err != nil {
+			return err
+		}
+
+		if runParams.Params.Tasks[maintenance.TaskSourceStorageUsage].Interval <= 0 {
+			return nil
+		}
+
+		//nolint:wrapcheck
+		return maintenance.ReportRun(ctx, dr, maintenance.TaskSourceStorageUsage, s, func(ctx context.Context) error {
+			return sourceStats(ctx, rep, manifests, st)
+		})
+	})
+}
+
+// SnapshotStats fills in statistics of snapshots and their sources.
+func SnapshotStats(ctx context.Context, rep repo.DirectRepository, st *maintenance.RepositoryStats) error {
+	manifests, err := snapshotCountStats(ctx, rep, st)
+	if err != nil {
+		return err
+	}
+
+	return sourceStats(ctx, rep, manifests, st)
+}
+
+// snapshotCountStats fills in the number and total size of snapshots and returns their manifests.
+func snapshotCountStats(ctx context.Context, rep repo.DirectRepository, st *maintenance.RepositoryStats) ([]*snapshot.Manifest, error) {
+	ids, err := snapshot.ListSnapshotManifests(ctx, rep, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list snapshot manifest IDs")
+	}
+
+	manifests, err := snapshot.LoadSnapshots(ctx, rep, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load snapshot manifests")
+	}
+
+	for _, m := range manifests {
+		st.Snapshots++
+		st.SnapshotBytes += m.Stats.TotalFileSize
+	}
+
+	return manifests, nil
+}
+
+// sourceStats fills in storage usage of snapshot sources, which requires walking all snapshots.
+func sourceStats(ctx context.Context, rep repo.DirectRepository, manifests []*snapshot.Manifest, st *maintenance.RepositoryStats) error {
+	log(ctx).Infof("Calculating storage usage of snapshot sources...")
+
+	usage, err := snapshotfs.CalculateSourceStorageUsage(ctx, rep, manifests, false)
+	if err != nil {
+		return errors.Wrap(err, "unable to calculate storage usage of sources")
+	}
+
+	st.Sources = map[string]maintenance.SourceStats{}
+
+	for _, u := range usage {
+		st.Sources[u.Source.String()] = maintenance.SourceStats{
+			Snapshots:   u.SnapshotCount,
+			UniqueBytes: u.UniqueBytes,
+			SharedBytes: u.SharedBytes,
+		}
+	}
+
+	return nil
+}
+
+// runTaskIfNotCompleted runs the provided task unless the maintenance run has exceeded its deadline or
+// the task has already completed during the interrupted maintenance cycle being resumed.
+func runTaskIfNotCompleted(ctx context.Context, runParams maintenance.RunParameters, taskType maintenance.TaskType, run func() error) error {
+	if runParams.DeadlineExceeded() {
+		return maintenance.ErrDeadlineExceeded
+	}
+
+	completed, err := runParams.TaskCompleted(ctx, taskType)
+	if err != nil {
+		return errors.Wrap(err, "unable to determine task status")
+	}
+
+	if completed {
+		log(ctx).Infof("Skipping %v, which has already completed in the interrupted maintenance cycle.", taskType)
+		return nil
+	}
+
+	return run()
+}
+
+func runSnapshotGC(ctx context.Context, dr repo.DirectRepositoryWriter, runParams maintenance.RunParameters, safety maintenance.SafetyParameters) error {
+	if runParams.Params.Partitions > 1 {
+		_, err := snapshotgc.RunPartitioned(ctx, dr, runParams, safety)
+
+		return err //nolint:wrapcheck
+	}
+
+	_, err := snapshotgc.Run(ctx, dr, true, runParams, safety)
+
+	return err //nolint:wrapcheck
+}
+
+// RunWorker processes partitions of partitioned maintenance in progress which are not claimed by other workers
+// and returns the number of processed partitions.
+func RunWorker(ctx context.Context, dr repo.DirectRepositoryWriter) (int, error) {
+	//nolint:wrapcheck
+	return maintenance.RunWorker(ctx, dr, map[maintenance.TaskType]maintenance.PartitionFunc{
+		maintenance.TaskSnapshotGarbageCollection: snapshotgc.RunPartition,
+	})
+}
+
+// DryRun simulates the complete snapshot and repository maintenance and returns the report of changes
+// it would make, without modifying the repository.
+func DryRun(ctx context.Context, dr repo.DirectRepositoryWriter, mode maintenance.Mode, safety maintenance.SafetyParameters) (*maintenance.DryRunReport, error) {
+	//nolint:wrapcheck
+	return maintenance.DryRun(ctx, dr, mode, safety,
+		func(ctx context.Context, startTime time.Time) ([]maintenance.TaskReport, error) {
+			if mode != maintenance.ModeFull {
+				return nil, nil
+			}
+
+			t, err := snapshotgc.DryRun(ctx, dr, safety, startTime)
+			if err != nil {
+				return nil, errors.Wrap(err, "snapshot GC failure")
 			}
 
-			//nolint:wrapcheck
-			return maintenance.Run(ctx, runParams, safety)
+			return []maintenance.TaskReport{t}, nil
 		})
 }
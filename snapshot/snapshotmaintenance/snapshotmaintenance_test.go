@@ -1,8 +1,11 @@
 package snapshotmaintenance_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
+	"fmt"
+	"io"
 	"testing"
 	"time"
 
@@ -12,15 +15,23 @@
 
 	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/internal/faketime"
+	"github.com/kopia/kopia/internal/feature"
+	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/mockfs"
 	"github.com/kopia/kopia/internal/repotesting"
 	"github.com/kopia/kopia/internal/testlogging"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/compression"
 	"github.com/kopia/kopia/repo/content"
 	"github.com/kopia/kopia/repo/format"
 	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotdict"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+	"github.com/kopia/kopia/snapshot/snapshotgc"
 	"github.com/kopia/kopia/snapshot/snapshotmaintenance"
 )
 
@@ -231,6 +242,401 @@ func (s *formatSpecificTestSuite) TestSnapshotGCMinContentAgeSafety(t *testing.T
 	checkContentDeletion(t, th.Repository, cids, false)
 }
 
+func (s *formatSpecificTestSuite) TestSnapshotGCReachabilitySummaries(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newTestHarness(t, s.formatVersion)
+
+	si := snapshot.SourceInfo{
+		Host:     "host",
+		UserName: "user",
+		Path:     "/foo",
+	}
+
+	th.sourceDir.AddFile("f1", []byte{1, 2, 3, 4}, defaultPermissions)
+	th.sourceDir.AddDir("d1", defaultPermissions)
+	th.sourceDir.AddFile("d1/f3", []byte{9, 10, 11, 12}, defaultPermissions)
+
+	s1 := mustSnapshot(t, th.RepositoryWriter, th.sourceDir, si)
+	mustFlush(t, th.RepositoryWriter)
+
+	// summaries are not computed for recent snapshots.
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull))
+	require.Empty(t, findReachabilitySummaries(t, th.RepositoryWriter))
+
+	th.fakeTime.Advance(maintenance.SafetyFull.MinContentAgeSubjectToGC + time.Hour)
+
+	// GC computes the summary of the snapshot which does not have one.
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull))
+	require.Len(t, findReachabilitySummaries(t, th.RepositoryWriter), 1)
+
+	th.sourceDir.AddFile("f2", []byte{5, 6, 7, 8}, defaultPermissions)
+
+	s2 := mustSnapshot(t, th.RepositoryWriter, th.sourceDir, si)
+	mustFlush(t, th.RepositoryWriter)
+
+	th.fakeTime.Advance(maintenance.SafetyFull.MinContentAgeSubjectToGC + time.Hour)
+
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull))
+	mustFlush(t, th.RepositoryWriter)
+
+	// the summary of the new snapshot only describes its root directory, since d1 did not change.
+	summaries := findReachabilitySummaries(t, th.RepositoryWriter)
+	require.Len(t, summaries, 2)
+	require.Equal(t, 1, mustGetReachabilitySummary(t, th.RepositoryWriter, s2.RootObjectID()).DirectoryCount)
+
+	require.NoError(t, th.RepositoryWriter.DeleteManifest(ctx, s1.ID))
+	mustFlush(t, th.RepositoryWriter)
+
+	th.fakeTime.Advance(maintenance.SafetyFull.MinContentAgeSubjectToGC + time.Hour)
+
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull))
+	mustFlush(t, th.RepositoryWriter)
+
+	// contents only reachable from the deleted snapshot are deleted, but its summary is retained
+	// since it describes d1, which is still reachable.
+	require.Len(t, findReachabilitySummaries(t, th.RepositoryWriter), 2)
+
+	checkContentDeletion(t, th.RepositoryWriter, []content.ID{mustGetContentID(t, s1.RootObjectID())}, true)
+	checkContentDeletion(t, th.RepositoryWriter, []content.ID{mustGetContentID(t, s2.RootObjectID())}, false)
+
+	// contents of the remaining snapshot are intact.
+	require.Equal(t, []byte{5, 6, 7, 8}, mustReadSnapshotFile(t, th.RepositoryWriter, s2, "f2"))
+	require.Equal(t, []byte{9, 10, 11, 12}, mustReadSnapshotFile(t, th.RepositoryWriter, s2, "d1", "f3"))
+
+	require.NoError(t, th.RepositoryWriter.DeleteManifest(ctx, s2.ID))
+	mustFlush(t, th.RepositoryWriter)
+
+	th.fakeTime.Advance(maintenance.SafetyFull.MinContentAgeSubjectToGC + time.Hour)
+
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull))
+	mustFlush(t, th.RepositoryWriter)
+
+	// summaries no longer describing any snapshot are removed.
+	require.Empty(t, findReachabilitySummaries(t, th.RepositoryWriter))
+	checkContentDeletion(t, th.RepositoryWriter, []content.ID{mustGetContentID(t, s2.RootObjectID())}, true)
+}
+
+func mustGetReachabilitySummary(t *testing.T, rep repo.Repository, root object.ID) *snapshotgc.ReachabilitySummary {
+	t.Helper()
+
+	entries, err := rep.FindManifests(testlogging.Context(t), map[string]string{
+		manifest.TypeLabelKey: snapshotgc.ReachabilityManifestType,
+		"root":                root.String(),
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	var rs snapshotgc.ReachabilitySummary
+
+	_, err = rep.GetManifest(testlogging.Context(t), entries[0].ID, &rs)
+	require.NoError(t, err)
+
+	return &rs
+}
+
+func mustReadSnapshotFile(t *testing.T, rep repo.Repository, man *snapshot.Manifest, path ...string) []byte {
+	t.Helper()
+
+	ctx := testlogging.Context(t)
+
+	e, err := snapshotfs.SnapshotRoot(rep, man)
+	require.NoError(t, err)
+
+	for _, name := range path {
+		//nolint:forcetypeassert
+		e, err = e.(fs.Directory).Child(ctx, name)
+		require.NoError(t, err)
+	}
+
+	//nolint:forcetypeassert
+	r, err := e.(fs.File).Open(ctx)
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return data
+}
+
+func findReachabilitySummaries(t *testing.T, rep repo.Repository) []*manifest.EntryMetadata {
+	t.Helper()
+
+	entries, err := rep.FindManifests(testlogging.Context(t), map[string]string{
+		manifest.TypeLabelKey: snapshotgc.ReachabilityManifestType,
+	})
+	require.NoError(t, err)
+
+	return entries
+}
+
+func (s *formatSpecificTestSuite) TestMaintenanceDryRun(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newTestHarness(t, s.formatVersion)
+
+	th.sourceDir.AddDir("d1", defaultPermissions)
+	th.sourceDir.AddFile("d1/f2", []byte{1, 2, 3, 4}, defaultPermissions)
+
+	si := snapshot.SourceInfo{
+		Host:     "host",
+		UserName: "user",
+		Path:     "/foo",
+	}
+
+	s1 := mustSnapshot(t, th.RepositoryWriter, th.sourceDir, si)
+	mustFlush(t, th.RepositoryWriter)
+
+	require.NoError(t, th.RepositoryWriter.DeleteManifest(ctx, s1.ID))
+	mustFlush(t, th.RepositoryWriter)
+
+	th.fakeTime.Advance(maintenance.SafetyFull.MinContentAgeSubjectToGC + time.Hour)
+
+	schedBefore, err := maintenance.GetSchedule(ctx, th.RepositoryWriter)
+	require.NoError(t, err)
+
+	report, err := snapshotmaintenance.DryRun(ctx, th.RepositoryWriter, maintenance.ModeFull, maintenance.SafetyFull)
+	require.NoError(t, err)
+	mustFlush(t, th.RepositoryWriter)
+
+	require.Equal(t, maintenance.ModeFull, report.Mode)
+	require.NotEmpty(t, report.Tasks)
+
+	gc := report.Tasks[0]
+	require.EqualValues(t, maintenance.TaskSnapshotGarbageCollection, gc.Task)
+	require.Equal(t, maintenance.ActionMarkDeleted, gc.Action)
+	require.Positive(t, gc.Contents)
+	require.Positive(t, gc.ContentBytes)
+	require.GreaterOrEqual(t, report.ProjectedReclaimedBytes, gc.ContentBytes)
+
+	// the repository and maintenance schedule are unchanged.
+	checkContentDeletion(t, th.RepositoryWriter, []content.ID{mustGetContentID(t, s1.RootObjectID())}, false)
+
+	schedAfter, err := maintenance.GetSchedule(ctx, th.RepositoryWriter)
+	require.NoError(t, err)
+	require.Equal(t, schedBefore.Runs, schedAfter.Runs)
+
+	// actual maintenance marks contents reported by the dry run as deleted.
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull))
+	mustFlush(t, th.RepositoryWriter)
+
+	checkContentDeletion(t, th.RepositoryWriter, []content.ID{mustGetContentID(t, s1.RootObjectID())}, true)
+}
+
+func (s *formatSpecificTestSuite) TestPartitionedSnapshotGC(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newTestHarness(t, s.formatVersion)
+
+	th.sourceDir.AddDir("d1", defaultPermissions)
+	th.sourceDir.AddFile("d1/f2", []byte{1, 2, 3, 4}, defaultPermissions)
+	th.sourceDir.AddFile("f3", []byte{5, 6, 7, 8}, defaultPermissions)
+
+	si := snapshot.SourceInfo{
+		Host:     "host",
+		UserName: "user",
+		Path:     "/foo",
+	}
+
+	p := maintenance.DefaultParams()
+	p.Owner = th.RepositoryWriter.ClientOptions().UsernameAtHost()
+	p.Partitions = 4
+	require.NoError(t, maintenance.SetParams(ctx, th.RepositoryWriter, &p))
+
+	s1 := mustSnapshot(t, th.RepositoryWriter, th.sourceDir, si)
+	mustFlush(t, th.RepositoryWriter)
+
+	th.sourceDir.Remove("f3")
+
+	s2 := mustSnapshot(t, th.RepositoryWriter, th.sourceDir, si)
+	mustFlush(t, th.RepositoryWriter)
+
+	th.fakeTime.Advance(maintenance.SafetyFull.MinContentAgeSubjectToGC + time.Hour)
+
+	require.NoError(t, th.RepositoryWriter.DeleteManifest(ctx, s1.ID))
+	mustFlush(t, th.RepositoryWriter)
+
+	th.fakeTime.Advance(maintenance.SafetyFull.MinContentAgeSubjectToGC + time.Hour)
+
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, false, maintenance.SafetyNone))
+	mustFlush(t, th.RepositoryWriter)
+
+	// without safety, contents marked as deleted are immediately dropped from indexes.
+	_, err := th.RepositoryWriter.ContentInfo(ctx, mustGetContentID(t, s1.RootObjectID()))
+	require.ErrorIs(t, err, content.ErrContentNotFound)
+
+	checkContentDeletion(t, th.RepositoryWriter, []content.ID{mustGetContentID(t, s2.RootObjectID())}, false)
+
+	sched, err := maintenance.GetSchedule(ctx, th.RepositoryWriter)
+	require.NoError(t, err)
+	require.NotEmpty(t, sched.Runs[maintenance.TaskSnapshotGarbageCollection])
+	require.True(t, sched.Runs[maintenance.TaskSnapshotGarbageCollection][0].Success)
+	require.True(t, sched.Runs[maintenance.TaskRewriteContentsFull][0].Success)
+}
+
+func (s *formatSpecificTestSuite) TestSourceStorageUsageStatsAreOptIn(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newTestHarness(t, s.formatVersion)
+
+	th.sourceDir.AddFile("f1", []byte{1, 2, 3, 4}, defaultPermissions)
+
+	si := snapshot.SourceInfo{
+		Host:     "host",
+		UserName: "user",
+		Path:     "/foo",
+	}
+
+	mustSnapshot(t, th.RepositoryWriter, th.sourceDir, si)
+	mustFlush(t, th.RepositoryWriter)
+
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull))
+	mustFlush(t, th.RepositoryWriter)
+
+	history, err := maintenance.StatsHistory(ctx, th.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, 1, history[0].Snapshots)
+	require.Empty(t, history[0].Sources)
+
+	p, err := maintenance.GetParams(ctx, th.RepositoryWriter)
+	require.NoError(t, err)
+
+	p.Tasks = map[maintenance.TaskType]maintenance.TaskParams{
+		maintenance.TaskSourceStorageUsage: {Interval: 7 * 24 * time.Hour},
+	}
+	require.NoError(t, maintenance.SetParams(ctx, th.RepositoryWriter, p))
+
+	th.fakeTime.Advance(24 * time.Hour)
+
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull))
+	mustFlush(t, th.RepositoryWriter)
+
+	history, err = maintenance.StatsHistory(ctx, th.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, 1, history[1].Sources[si.String()].Snapshots)
+
+	// not due again until the interval elapses.
+	th.fakeTime.Advance(24 * time.Hour)
+
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull))
+	mustFlush(t, th.RepositoryWriter)
+
+	history, err = maintenance.StatsHistory(ctx, th.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.Empty(t, history[2].Sources)
+
+	sched, err := maintenance.GetSchedule(ctx, th.RepositoryWriter)
+	require.NoError(t, err)
+	require.Len(t, sched.Runs[maintenance.TaskSourceStorageUsage], 1)
+	require.Len(t, sched.Runs[maintenance.TaskRepositoryStats], 3)
+}
+
+func (s *formatSpecificTestSuite) TestCompressionDictionaryTraining(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newTestHarness(t, s.formatVersion)
+
+	si := snapshot.SourceInfo{
+		Host:     "host",
+		UserName: "user",
+		Path:     "/foo",
+	}
+
+	useDictionary := policy.OptionalBool(true)
+
+	require.NoError(t, policy.SetPolicy(ctx, th.RepositoryWriter, si, &policy.Policy{
+		CompressionPolicy: policy.CompressionPolicy{
+			CompressorName: "zstd",
+			UseDictionary:  &useDictionary,
+		},
+	}))
+
+	for i := 0; i < 30; i++ {
+		th.sourceDir.AddFile(fmt.Sprintf("config-%v.json", i), []byte(fmt.Sprintf(`{"id":%v,"kind":"document","owner":"user-%v","tags":["backup","snapshot","policy"],"enabled":true}`, i, i%3)), defaultPermissions)
+	}
+
+	mustSnapshot(t, th.RepositoryWriter, th.sourceDir, si)
+	mustFlush(t, th.RepositoryWriter)
+
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull))
+	mustFlush(t, th.RepositoryWriter)
+
+	d, err := snapshot.FindCompressionDictionary(ctx, th.RepositoryWriter, si)
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	require.Equal(t, content.CompressionDictionaryPrefix, d.ContentID.Prefix())
+	require.Equal(t, 30, d.SampleCount)
+
+	newFileData := []byte(`{"id":1000,"kind":"document","owner":"user-5","tags":["backup","snapshot","policy"],"enabled":false}`)
+	th.sourceDir.AddFile("config-new.json", newFileData, defaultPermissions)
+
+	s2 := mustSnapshot(t, th.RepositoryWriter, th.sourceDir, si)
+	mustFlush(t, th.RepositoryWriter)
+
+	dir, err := snapshotfs.SnapshotRoot(th.RepositoryWriter, s2)
+	require.NoError(t, err)
+
+	f, err := fs.IterateEntriesAndFindChild(ctx, dir.(fs.Directory), "config-new.json")
+	require.NoError(t, err)
+
+	info, err := th.RepositoryWriter.ContentInfo(ctx, mustGetContentID(t, f.(object.HasObjectID).ObjectID()))
+	require.NoError(t, err)
+
+	scc, err := th.RepositoryWriter.ContentManager().SupportsContentCompression()
+	require.NoError(t, err)
+
+	if scc {
+		require.Equal(t, compression.HeaderZstdDictionary, info.GetCompressionHeaderID())
+	}
+
+	required, err := th.RepositoryWriter.FormatManager().RequiredFeatures()
+	require.NoError(t, err)
+	require.Contains(t, required, feature.Required{
+		Feature: repo.FeatureCompressionDictionaries,
+		IfNotUnderstood: feature.IfNotUnderstood{
+			Message: "The repository contains contents compressed using compression dictionaries, which are not supported by this version of Kopia.",
+		},
+	})
+
+	// retraining replaces the current dictionary of the source.
+	th.fakeTime.Advance(snapshotdict.RetrainInterval)
+
+	d2, err := snapshotdict.TrainForSource(ctx, th.RepositoryWriter, si, false)
+	require.NoError(t, err)
+	require.NotNil(t, d2)
+	require.NotEqual(t, d.ContentID, d2.ContentID)
+
+	unusedDictionaryID, err := th.RepositoryWriter.ContentManager().WriteCompressionDictionary(ctx, gather.FromSlice(bytes.Repeat([]byte("unused dictionary,"), 100)))
+	require.NoError(t, err)
+	mustFlush(t, th.RepositoryWriter)
+
+	// previous dictionary is retained as long as contents reference it, unreferenced dictionaries are deleted.
+	th.fakeTime.Advance(maintenance.SafetyFull.MinContentAgeSubjectToGC + time.Hour)
+	require.NoError(t, snapshotmaintenance.Run(ctx, th.RepositoryWriter, maintenance.ModeFull, true, maintenance.SafetyFull))
+	mustFlush(t, th.RepositoryWriter)
+
+	require.NoError(t, th.RepositoryWriter.Refresh(ctx))
+
+	isLive := func(cid content.ID) bool {
+		ci, err := th.RepositoryWriter.ContentInfo(ctx, cid)
+
+		return err == nil && !ci.GetDeleted()
+	}
+
+	require.True(t, isLive(d2.ContentID))
+	require.Equal(t, scc, isLive(d.ContentID))
+	require.False(t, isLive(unusedDictionaryID))
+
+	r, err := f.(fs.File).Open(ctx)
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, newFileData, got)
+}
+
 func newTestHarness(t *testing.T, formatVersion format.Version) *testHarness {
 	t.Helper()
 
@@ -0,0 +1,346 @@
+// Package snapshotrecovery implements recovery of snapshots from repositories which have lost index blobs or snapshot manifests.
+package snapshotrecovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/timetrack"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
+	"github.com/kopia/kopia/repo/logging"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+)
+
+var log = logging.Module("snapshotrecovery")
+
+const (
+	directoryContentPrefix = "k"
+	directoryStreamType    = "kopia:directory"
+	indirectStreamType     = "kopia:indirect"
+
+	// RecoveredPin is the pin added to recovered snapshots to prevent their expiration.
+	RecoveredPin = "recovered"
+)
+
+// Options provides options for Run.
+type Options struct {
+	// RecoverIndexes rebuilds index entries from all pack blobs before looking for snapshot roots.
+	RecoverIndexes bool
+	Parallel       int
+
+	// Source of the recovered snapshots.
+	Source snapshot.SourceInfo
+
+	// Commit writes recovered index entries and snapshot manifests, otherwise recovery only reports what it found.
+	Commit bool
+}
+
+// Stats contains statistics about recovery.
+type Stats struct {
+	ProcessedPackBlobs int `json:"processedPackBlobs"`
+	FailedPackBlobs    int `json:"failedPackBlobs"`
+	RecoveredContents  int `json:"recoveredContents"`
+	Directories        int `json:"directories"`
+
+	// Snapshots contains recovered snapshot manifests.
+	Snapshots []*snapshot.Manifest `json:"snapshots"`
+}
+
+// Run rebuilds indexes from pack blobs, finds directories which are not reachable from any snapshot
+// and creates snapshot manifests for them, so that their contents can be browsed and restored.
+func Run(ctx context.Context, rep repo.DirectRepositoryWriter, opt Options) (*Stats, error) {
+	st := &Stats{}
+
+	if opt.RecoverIndexes {
+		if err := recoverIndexes(ctx, rep, opt, st); err != nil {
+			return st, err
+		}
+	}
+
+	roots, err := findOrphanedRoots(ctx, rep, st)
+	if err != nil {
+		return st, err
+	}
+
+	for _, r := range roots {
+		man := &snapshot.Manifest{
+			Source:      opt.Source,
+			Description: "Recovered from directory " + r.oid.String(),
+			StartTime:   fs.UTCTimestampFromTime(r.timestamp),
+			EndTime:     fs.UTCTimestampFromTime(r.timestamp),
+			RootEntry: &snapshot.DirEntry{
+				Name:       r.oid.String(),
+				Type:       snapshot.EntryTypeDirectory,
+				ObjectID:   r.oid,
+				DirSummary: r.summary,
+			},
+			Pins: []string{RecoveredPin},
+		}
+
+		if s := r.summary; s != nil {
+			man.RootEntry.FileSize = s.TotalFileSize
+			man.RootEntry.ModTime = s.MaxModTime
+			man.Stats.TotalFileSize = s.TotalFileSize
+			man.Stats.TotalFileCount = int32(s.TotalFileCount)
+			man.Stats.TotalDirectoryCount = int32(s.TotalDirCount)
+		}
+
+		if opt.Commit {
+			if _, err := snapshot.SaveSnapshot(ctx, rep, man); err != nil {
+				return st, errors.Wrapf(err, "unable to save recovered snapshot of %v", r.oid)
+			}
+		}
+
+		st.Snapshots = append(st.Snapshots, man)
+	}
+
+	if opt.Commit {
+		return st, errors.Wrap(rep.Flush(ctx), "flush")
+	}
+
+	return st, nil
+}
+
+// recoverIndexes recovers index entries from local indexes stored in all pack blobs.
+func recoverIndexes(ctx context.Context, rep repo.DirectRepositoryWriter, opt Options, st *Stats) error {
+	var (
+		processed, failed, recovered atomic.Int32
+		tt                           timetrack.Throttle
+	)
+
+	blobCh := make(chan blob.Metadata)
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		defer close(blobCh)
+
+		for _, prefix := range content.PackBlobIDPrefixes {
+			if err := rep.BlobReader().ListBlobs(egCtx, prefix, func(bm blob.Metadata) error {
+				select {
+				case blobCh <- bm:
+					return nil
+				case <-egCtx.Done():
+					return egCtx.Err()
+				}
+			}); err != nil {
+				return errors.Wrapf(err, "error listing blobs with prefix %q", prefix)
+			}
+		}
+
+		return nil
+	})
+
+	parallel := opt.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	for i := 0; i < parallel; i++ {
+		eg.Go(func() error {
+			for bm := range blobCh {
+				infos, err := rep.ContentManager().RecoverIndexFromPackBlob(egCtx, bm.BlobID, bm.Length, opt.Commit)
+				if err != nil {
+					// the repository is likely damaged, keep going.
+					log(ctx).Errorf("unable to recover index from %v: %v", bm.BlobID, err)
+					failed.Add(1)
+
+					continue
+				}
+
+				recovered.Add(int32(len(infos)))
+
+				if n := processed.Add(1); tt.ShouldOutput(time.Second) {
+					log(ctx).Infof("Recovered %v index entries from %v pack blobs...", recovered.Load(), n)
+				}
+			}
+
+			return nil
+		})
+	}
+
+	err := eg.Wait()
+
+	st.ProcessedPackBlobs = int(processed.Load())
+	st.FailedPackBlobs = int(failed.Load())
+	st.RecoveredContents = int(recovered.Load())
+
+	log(ctx).Infof("Recovered %v index entries from %v pack blobs, %v pack blobs could not be read.", st.RecoveredContents, st.ProcessedPackBlobs, st.FailedPackBlobs)
+
+	if err != nil {
+		return errors.Wrap(err, "error recovering indexes")
+	}
+
+	if !opt.Commit {
+		return nil
+	}
+
+	return errors.Wrap(rep.Flush(ctx), "error committing recovered indexes")
+}
+
+type orphanedRoot struct {
+	oid       object.ID
+	timestamp time.Time
+	summary   *fs.DirectorySummary
+}
+
+// findOrphanedRoots returns directories which are not referenced by any other directory or snapshot, oldest first.
+func findOrphanedRoots(ctx context.Context, rep repo.DirectRepositoryWriter, st *Stats) ([]orphanedRoot, error) {
+	var (
+		directories = map[content.ID]orphanedRoot{}
+		referenced  = map[content.ID]bool{}
+	)
+
+	markReferenced := func(oid object.ID) {
+		if cid, ok := indexContentID(oid); ok {
+			referenced[cid] = true
+		}
+	}
+
+	log(ctx).Infof("Looking for directories...")
+
+	if err := rep.ContentReader().IterateContents(ctx, content.IterateOptions{
+		Range: index.PrefixRange(directoryContentPrefix),
+	}, func(ci content.Info) error {
+		oid, dir, err := readDirectoryContent(ctx, rep, ci.GetContentID(), markReferenced)
+		if err != nil {
+			log(ctx).Debugf("skipping %v: %v", ci.GetContentID(), err)
+			return nil
+		}
+
+		for _, e := range dir.Entries {
+			if e.Type == snapshot.EntryTypeDirectory {
+				markReferenced(e.ObjectID)
+			}
+		}
+
+		directories[ci.GetContentID()] = orphanedRoot{oid, ci.Timestamp(), dir.Summary}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "error iterating directory contents")
+	}
+
+	st.Directories = len(directories)
+
+	manifests, err := snapshot.ListSnapshotManifests(ctx, rep, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list snapshots")
+	}
+
+	snapshots, err := snapshot.LoadSnapshots(ctx, rep, manifests)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load snapshots")
+	}
+
+	for _, s := range snapshots {
+		markReferenced(s.RootObjectID())
+	}
+
+	var roots []orphanedRoot
+
+	for cid, d := range directories {
+		if !referenced[cid] {
+			roots = append(roots, d)
+		}
+	}
+
+	sort.Slice(roots, func(i, j int) bool {
+		return roots[i].timestamp.Before(roots[j].timestamp)
+	})
+
+	log(ctx).Infof("Found %v directories, %v of them are not reachable from any of %v snapshots.", len(directories), len(roots), len(snapshots))
+
+	return roots, nil
+}
+
+// readDirectoryContent parses the provided content as a directory or an index of a large directory.
+// Objects referenced by the index are passed to markReferenced.
+func readDirectoryContent(ctx context.Context, rep repo.Repository, cid content.ID, markReferenced func(oid object.ID)) (object.ID, *snapshot.DirManifest, error) {
+	oid := object.DirectObjectID(cid)
+
+	b, err := rep.(repo.DirectRepository).ContentReader().GetContent(ctx, cid)
+	if err != nil {
+		return oid, nil, errors.Wrap(err, "unable to read content")
+	}
+
+	if !bytes.HasPrefix(b, []byte("{")) {
+		// directories may be compressed.
+		oid = object.Compressed(oid)
+
+		if b, err = readObject(ctx, rep, oid); err != nil {
+			return oid, nil, err
+		}
+	}
+
+	var hdr struct {
+		StreamType string                       `json:"stream"`
+		Entries    []object.IndirectObjectEntry `json:"entries"`
+	}
+
+	// indexes of large directories only contain object IDs, which is not valid for directory entries.
+	if json.Unmarshal(b, &hdr) == nil && hdr.StreamType == indirectStreamType {
+		for _, e := range hdr.Entries {
+			markReferenced(e.Object)
+		}
+
+		oid = object.IndirectObjectID(oid)
+
+		if b, err = readObject(ctx, rep, oid); err != nil {
+			return oid, nil, err
+		}
+	}
+
+	var dir snapshot.DirManifest
+
+	if err := json.Unmarshal(b, &dir); err != nil {
+		return oid, nil, errors.Wrap(err, "not a directory")
+	}
+
+	if dir.StreamType != directoryStreamType {
+		return oid, nil, errors.Errorf("unexpected stream type %q", dir.StreamType)
+	}
+
+	return oid, &dir, nil
+}
+
+func readObject(ctx context.Context, rep repo.Repository, oid object.ID) ([]byte, error) {
+	r, err := rep.OpenObject(ctx, oid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open object %v", oid)
+	}
+
+	defer r.Close() //nolint:errcheck
+
+	b, err := io.ReadAll(r)
+
+	return b, errors.Wrapf(err, "unable to read object %v", oid)
+}
+
+// indexContentID returns the ID of the content holding the object or its top-level index.
+func indexContentID(oid object.ID) (content.ID, bool) {
+	for {
+		ind, ok := oid.IndexObjectID()
+		if !ok {
+			break
+		}
+
+		oid = ind
+	}
+
+	cid, _, ok := oid.ContentID()
+
+	return cid, ok
+}
@@ -0,0 +1,99 @@
+package snapshotrecovery_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/format"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+	"github.com/kopia/kopia/snapshot/snapshotrecovery"
+)
+
+func TestRecovery(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, format.MaxFormatVersion)
+
+	dir := mockfs.NewDirectory()
+	dir.AddFile("f1", []byte{1, 2, 3}, 0o644)
+	dir.AddDir("d1", 0o755)
+	dir.AddFile("d1/f2", []byte{4, 5, 6}, 0o644)
+	dir.AddDir("d1/d2", 0o755)
+	dir.AddFile("d1/d2/f3", []byte{7, 8, 9}, 0o644)
+
+	si := snapshot.SourceInfo{Host: "host", UserName: "user", Path: "/foo"}
+	recoveredSource := snapshot.SourceInfo{Host: "recovered", UserName: "user", Path: "/recovered"}
+
+	policyTree, err := policy.TreeForSource(ctx, env.RepositoryWriter, si)
+	require.NoError(t, err)
+
+	// upload the directory but lose the snapshot manifest.
+	man, err := snapshotfs.NewUploader(env.RepositoryWriter).Upload(ctx, dir, policyTree, si)
+	require.NoError(t, err)
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	st, err := snapshotrecovery.Run(ctx, env.RepositoryWriter, snapshotrecovery.Options{Source: recoveredSource})
+	require.NoError(t, err)
+	require.Equal(t, 3, st.Directories)
+	require.Len(t, st.Snapshots, 1)
+	require.Equal(t, man.RootObjectID(), st.Snapshots[0].RootObjectID())
+
+	snaps, err := snapshot.ListSnapshots(ctx, env.RepositoryWriter, recoveredSource)
+	require.NoError(t, err)
+	require.Empty(t, snaps)
+
+	_, err = snapshotrecovery.Run(ctx, env.RepositoryWriter, snapshotrecovery.Options{Source: recoveredSource, Commit: true})
+	require.NoError(t, err)
+
+	snaps, err = snapshot.ListSnapshots(ctx, env.RepositoryWriter, recoveredSource)
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+	require.Equal(t, man.RootObjectID(), snaps[0].RootObjectID())
+	require.Equal(t, []string{snapshotrecovery.RecoveredPin}, snaps[0].Pins)
+	require.Equal(t, int32(3), snaps[0].Stats.TotalFileCount)
+
+	// recovered root is now reachable from a snapshot.
+	st, err = snapshotrecovery.Run(ctx, env.RepositoryWriter, snapshotrecovery.Options{Source: recoveredSource, Commit: true})
+	require.NoError(t, err)
+	require.Empty(t, st.Snapshots)
+
+	// lose all indexes.
+	require.NoError(t, env.RootStorage().ListBlobs(ctx, "x", func(bm blob.Metadata) error {
+		return env.RootStorage().DeleteBlob(ctx, bm.BlobID)
+	}))
+
+	env.MustReopen(t)
+
+	snaps, err = snapshot.ListSnapshots(ctx, env.RepositoryWriter, recoveredSource)
+	require.NoError(t, err)
+	require.Empty(t, snaps)
+
+	st, err = snapshotrecovery.Run(ctx, env.RepositoryWriter, snapshotrecovery.Options{
+		Source:         recoveredSource,
+		RecoverIndexes: true,
+		Parallel:       4,
+		Commit:         true,
+	})
+	require.NoError(t, err)
+	require.Positive(t, st.RecoveredContents)
+	require.Zero(t, st.FailedPackBlobs)
+
+	// snapshot manifest was recovered together with the indexes, so no new snapshots are created.
+	require.Empty(t, st.Snapshots)
+
+	snaps, err = snapshot.ListSnapshots(ctx, env.RepositoryWriter, recoveredSource)
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+
+	root, err := snapshotfs.SnapshotRoot(env.RepositoryWriter, snaps[0])
+	require.NoError(t, err)
+
+	ents, err := fs.GetAllEntries(ctx, root.(fs.Directory))
+	require.NoError(t, err)
+	require.Len(t, ents, 2)
+}
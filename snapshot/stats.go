@@ -4,6 +4,7 @@
 	"sync/atomic"
 
 	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/repo/compression"
 )
 
 // Stats keeps track of snapshot generation statistics.
@@ -14,6 +15,14 @@ type Stats struct {
 	// +checkatomic
 	ExcludedTotalFileSize int64 `json:"excludedTotalSize"`
 
+	// sizes of files stored using automatic compression, including cached files, by selected compressor
+	// +checkatomic
+	AutoCompressionNoneSize int64 `json:"autoCompressionNoneSize,omitempty"`
+	// +checkatomic
+	AutoCompressionFastSize int64 `json:"autoCompressionFastSize,omitempty"`
+	// +checkatomic
+	AutoCompressionBestSize int64 `json:"autoCompressionBestSize,omitempty"`
+
 	// keep all int32 aligned because they will be atomically updated
 	// +checkatomic
 	TotalFileCount int32 `json:"fileCount"`
@@ -36,6 +45,18 @@ type Stats struct {
 	ErrorCount int32 `json:"errorCount"`
 }
 
+// AddAutoCompressed adds the information about data uploaded using automatic compression to the statistics.
+func (s *Stats) AddAutoCompressed(comp compression.Name, size int64) {
+	switch comp {
+	case compression.AutoFastCompressor:
+		atomic.AddInt64(&s.AutoCompressionFastSize, size)
+	case compression.AutoBestCompressor:
+		atomic.AddInt64(&s.AutoCompressionBestSize, size)
+	default:
+		atomic.AddInt64(&s.AutoCompressionNoneSize, size)
+	}
+}
+
 // AddExcluded adds the information about excluded file to the statistics.
 func (s *Stats) AddExcluded(md fs.Entry) {
 	if md.IsDir() {